@@ -0,0 +1,142 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// SelectUsersByEmail returns the users with an email address, ignoring case.
+func (db Query) SelectUsersByEmail(ctx context.Context, email string) ([]ResponseUser, error) {
+	const query = `SELECT ` + userColumns + ` FROM users WHERE LOWER(email) = LOWER($1) ORDER BY username;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resp := []ResponseUser{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		resp = append(resp, user)
+	}
+	return resp, rows.Err()
+}
+
+// InsertUserToken stores a single-use token sent to email. The user's
+// earlier tokens for the same purpose stop working, so only the newest link
+// does.
+func (db Query) InsertUserToken(ctx context.Context, tokenHash string, username string, purpose string, email string, expiresAt time.Time) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM user_tokens WHERE username = $1 AND purpose = $2;`, username, purpose)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO user_tokens (token_hash, username, purpose, email, expires_at) VALUES ($1, $2, $3, $4, $5);`,
+		tokenHash, username, purpose, email, expiresAt)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SelectUserToken returns the user an unused, unexpired token was sent to.
+func (db Query) SelectUserToken(ctx context.Context, tokenHash string, purpose string) (ResponseUser, error) {
+	const query = `SELECT ` + userColumns + ` FROM users
+	WHERE username = (
+		SELECT username FROM user_tokens
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+	);`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return ResponseUser{}, err
+	}
+	defer stmt.Close()
+
+	return scanUser(stmt.QueryRowContext(ctx, tokenHash, purpose))
+}
+
+// useUserToken marks an unused, unexpired token as used and returns who it
+// was sent to. It returns sql.ErrNoRows for any other token.
+func useUserToken(tx *sql.Tx, tokenHash string, purpose string) (string, string, error) {
+	const query = `UPDATE user_tokens SET used_at = NOW()
+	WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+	RETURNING username, email;`
+
+	var username, email string
+	err := tx.QueryRow(query, tokenHash, purpose).Scan(&username, &email)
+	return username, email, err
+}
+
+// VerifyEmail uses a verification token and marks the address it was sent
+// to as verified. A token sent to an address the user has since changed
+// returns sql.ErrNoRows.
+func (db Query) VerifyEmail(ctx context.Context, tokenHash string) (string, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	username, email, err := useUserToken(tx, tokenHash, tokenPurposeVerifyEmail)
+	if err != nil {
+		return "", err
+	}
+	res, err := tx.Exec(`UPDATE users SET email_verified_at = NOW() WHERE username = $1 AND email = $2;`, username, email)
+	if err != nil {
+		return "", err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return "", err
+	}
+	if n == 0 {
+		return "", sql.ErrNoRows
+	}
+	return username, tx.Commit()
+}
+
+// ResetPassword uses a reset token to set a new password. The user's
+// sessions and other reset tokens are removed, so a stolen session or link
+// stops working too.
+func (db Query) ResetPassword(ctx context.Context, tokenHash string, hashedPassword string) (string, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	username, _, err := useUserToken(tx, tokenHash, tokenPurposeResetPassword)
+	if err != nil {
+		return "", err
+	}
+	_, err = tx.Exec(`UPDATE users SET hashed_password = $1 WHERE username = $2;`, hashedPassword, username)
+	if err != nil {
+		return "", err
+	}
+	_, err = tx.Exec(`DELETE FROM sessions WHERE username = $1;`, username)
+	if err != nil {
+		return "", err
+	}
+	_, err = tx.Exec(`DELETE FROM user_tokens WHERE username = $1 AND purpose = $2 AND used_at IS NULL;`, username, tokenPurposeResetPassword)
+	if err != nil {
+		return "", err
+	}
+	return username, tx.Commit()
+}
@@ -0,0 +1,228 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+const useUserTokenQuery = `UPDATE user_tokens SET used_at = NOW() WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW() RETURNING username, email;`
+
+func TestSelectUsersByEmail(t *testing.T) {
+	t.Run("TestSelectUsersByEmailShouldIgnoreCase", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		mock.ExpectPrepare(regexp.QuoteMeta(`FROM users WHERE LOWER(email) = LOWER($1) ORDER BY username;`)).
+			ExpectQuery().
+			WithArgs("Tester@Email.com").
+			WillReturnRows(sqlmock.NewRows([]string{"username", "email", "fullname", "hashed_password", "created_at", "role", "email_verified_at", "mfa_enabled_at"}).
+				AddRow("tester", "tester@email.com", "tester testing", "hashed", createdAt, UserRoleCustomer, nil, nil))
+
+		query := NewDB(db)
+
+		// Act
+		users, err := query.SelectUsersByEmail(context.Background(), "Tester@Email.com")
+
+		// Assert
+		assert.NoError(t, err)
+		if assert.Len(t, users, 1) {
+			assert.Equal(t, "tester", users[0].Username)
+			assert.Nil(t, users[0].EmailVerifiedAt)
+		}
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestInsertUserToken(t *testing.T) {
+	t.Run("TestInsertUserTokenShouldReplaceEarlierTokens", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		expiresAt := time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM user_tokens WHERE username = $1 AND purpose = $2;`)).
+			WithArgs("tester", tokenPurposeVerifyEmail).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO user_tokens (token_hash, username, purpose, email, expires_at) VALUES ($1, $2, $3, $4, $5);`)).
+			WithArgs("hash", "tester", tokenPurposeVerifyEmail, "tester@email.com", expiresAt).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		query := NewDB(db)
+
+		// Act
+		err = query.InsertUserToken(context.Background(), "hash", "tester", tokenPurposeVerifyEmail, "tester@email.com", expiresAt)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestSelectUserToken(t *testing.T) {
+	t.Run("TestSelectUserTokenShouldReturnUserOfValidToken", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		mock.ExpectPrepare(regexp.QuoteMeta(`SELECT username, email, fullname, hashed_password, created_at, role, email_verified_at, mfa_enabled_at FROM users WHERE username = ( SELECT username FROM user_tokens WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW() );`)).
+			ExpectQuery().
+			WithArgs("hash", tokenPurposeResetPassword).
+			WillReturnRows(sqlmock.NewRows([]string{"username", "email", "fullname", "hashed_password", "created_at", "role", "email_verified_at", "mfa_enabled_at"}).
+				AddRow("tester", "tester@email.com", "tester testing", "hashed", createdAt, UserRoleCustomer, createdAt, nil))
+
+		query := NewDB(db)
+
+		// Act
+		user, err := query.SelectUserToken(context.Background(), "hash", tokenPurposeResetPassword)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "tester@email.com", user.Email)
+		assert.Equal(t, &createdAt, user.EmailVerifiedAt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestVerifyEmailQuery(t *testing.T) {
+	t.Run("TestVerifyEmailShouldMarkTheTokenEmailVerified", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(useUserTokenQuery)).
+			WithArgs("hash", tokenPurposeVerifyEmail).
+			WillReturnRows(sqlmock.NewRows([]string{"username", "email"}).AddRow("tester", "tester@email.com"))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET email_verified_at = NOW() WHERE username = $1 AND email = $2;`)).
+			WithArgs("tester", "tester@email.com").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		query := NewDB(db)
+
+		// Act
+		username, err := query.VerifyEmail(context.Background(), "hash")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "tester", username)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("TestVerifyEmailShouldRejectTokenForChangedEmail", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(useUserTokenQuery)).
+			WithArgs("hash", tokenPurposeVerifyEmail).
+			WillReturnRows(sqlmock.NewRows([]string{"username", "email"}).AddRow("tester", "old@email.com"))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET email_verified_at = NOW()`)).
+			WithArgs("tester", "old@email.com").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		query := NewDB(db)
+
+		// Act
+		_, err = query.VerifyEmail(context.Background(), "hash")
+
+		// Assert
+		assert.Equal(t, sql.ErrNoRows, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("TestVerifyEmailShouldReturnErrNoRowsForUsedToken", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(useUserTokenQuery)).
+			WithArgs("hash", tokenPurposeVerifyEmail).
+			WillReturnRows(sqlmock.NewRows([]string{"username", "email"}))
+		mock.ExpectRollback()
+
+		query := NewDB(db)
+
+		// Act
+		_, err = query.VerifyEmail(context.Background(), "hash")
+
+		// Assert
+		assert.Equal(t, sql.ErrNoRows, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestResetPasswordQuery(t *testing.T) {
+	t.Run("TestResetPasswordShouldSetPasswordAndEndSessions", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(useUserTokenQuery)).
+			WithArgs("hash", tokenPurposeResetPassword).
+			WillReturnRows(sqlmock.NewRows([]string{"username", "email"}).AddRow("tester", "tester@email.com"))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET hashed_password = $1 WHERE username = $2;`)).
+			WithArgs("hashed", "tester").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM sessions WHERE username = $1;`)).
+			WithArgs("tester").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM user_tokens WHERE username = $1 AND purpose = $2 AND used_at IS NULL;`)).
+			WithArgs("tester", tokenPurposeResetPassword).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		query := NewDB(db)
+
+		// Act
+		username, err := query.ResetPassword(context.Background(), "hash", "hashed")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "tester", username)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("TestResetPasswordShouldReturnErrNoRowsForExpiredToken", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(useUserTokenQuery)).
+			WithArgs("hash", tokenPurposeResetPassword).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		query := NewDB(db)
+
+		// Act
+		_, err = query.ResetPassword(context.Background(), "hash", "hashed")
+
+		// Assert
+		assert.Equal(t, sql.ErrNoRows, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	c "github.com/paquesqueue/bookstore/common"
+)
+
+type AccountHandlrQueries interface {
+	ResendVerification(ctx context.Context, username string) error
+	VerifyEmail(ctx context.Context, req RequestEmailVerification) error
+	ForgotPassword(ctx context.Context, req RequestForgotPassword) error
+	ResetPassword(ctx context.Context, req RequestResetPassword) error
+}
+
+type AccountHandlr struct {
+	handler AccountHandlrQueries
+	log     c.Log
+}
+
+func NewAccountHandlr(h AccountHandlrQueries, l c.Log) AccountHandlr {
+	return AccountHandlr{h, l}
+}
+
+func (h AccountHandlr) VerifyEmail(ctx echo.Context) error {
+	req := RequestEmailVerification{}
+	if err := ctx.Bind(&req); err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	if err := h.handler.VerifyEmail(ctx.Request().Context(), req); err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error VerifyEmail Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, "Email Verified")
+}
+
+// ResendVerification sends the signed in user a new verification link.
+func (h AccountHandlr) ResendVerification(ctx echo.Context) error {
+	user, ok := CurrentUser(ctx)
+	if !ok {
+		return ctx.NoContent(http.StatusUnauthorized)
+	}
+
+	if err := h.handler.ResendVerification(ctx.Request().Context(), user.Username); err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error ResendVerification Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.NoContent(http.StatusAccepted)
+}
+
+// ForgotPassword answers 202 for known and unknown addresses alike.
+func (h AccountHandlr) ForgotPassword(ctx echo.Context) error {
+	req := RequestForgotPassword{}
+	if err := ctx.Bind(&req); err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	if err := h.handler.ForgotPassword(ctx.Request().Context(), req); err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error ForgotPassword Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.NoContent(http.StatusAccepted)
+}
+
+func (h AccountHandlr) ResetPassword(ctx echo.Context) error {
+	req := RequestResetPassword{}
+	if err := ctx.Bind(&req); err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	if err := h.handler.ResetPassword(ctx.Request().Context(), req); err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error ResetPassword Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, "Password Reset")
+}
@@ -0,0 +1,169 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type AccountHandlrStub struct {
+	username string
+	verify   RequestEmailVerification
+	forgot   RequestForgotPassword
+	reset    RequestResetPassword
+	err      error
+}
+
+func (h *AccountHandlrStub) ResendVerification(ctx context.Context, username string) error {
+	h.username = username
+	return h.err
+}
+
+func (h *AccountHandlrStub) VerifyEmail(ctx context.Context, req RequestEmailVerification) error {
+	h.verify = req
+	return h.err
+}
+
+func (h *AccountHandlrStub) ForgotPassword(ctx context.Context, req RequestForgotPassword) error {
+	h.forgot = req
+	return h.err
+}
+
+func (h *AccountHandlrStub) ResetPassword(ctx context.Context, req RequestResetPassword) error {
+	h.reset = req
+	return h.err
+}
+
+func newAccountContext(body string) (echo.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	return echo.New().NewContext(req, rec), rec
+}
+
+func TestVerifyEmailHandler(t *testing.T) {
+	t.Run("TestVerifyEmailHandlerShouldReturnHTTPStatus200", func(t *testing.T) {
+		// Arrange
+		stub := &AccountHandlrStub{}
+		handler := NewAccountHandlr(stub, logrus.New())
+		ctx, rec := newAccountContext(`{"token":"abc"}`)
+
+		// Act
+		err := handler.VerifyEmail(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "abc", stub.verify.Token)
+	})
+	t.Run("TestVerifyEmailHandlerShouldReturnServiceErrorCode", func(t *testing.T) {
+		// Arrange
+		handler := NewAccountHandlr(&AccountHandlrStub{err: &c.Err{Code: http.StatusBadRequest}}, logrus.New())
+		ctx, rec := newAccountContext(`{"token":"used"}`)
+
+		// Act
+		err := handler.VerifyEmail(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestResendVerificationHandler(t *testing.T) {
+	t.Run("TestResendVerificationHandlerShouldSendToCurrentUser", func(t *testing.T) {
+		// Arrange
+		stub := &AccountHandlrStub{}
+		handler := NewAccountHandlr(stub, logrus.New())
+		ctx, rec := newAccountContext(``)
+		ctx.Set(ctxUserKey, ResponseUser{Username: "tester"})
+
+		// Act
+		err := handler.ResendVerification(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+		assert.Equal(t, "tester", stub.username)
+	})
+	t.Run("TestResendVerificationHandlerShouldReturnHTTPStatus401WithoutUser", func(t *testing.T) {
+		// Arrange
+		handler := NewAccountHandlr(&AccountHandlrStub{}, logrus.New())
+		ctx, rec := newAccountContext(``)
+
+		// Act
+		err := handler.ResendVerification(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
+
+func TestForgotPasswordHandler(t *testing.T) {
+	t.Run("TestForgotPasswordHandlerShouldReturnHTTPStatus202", func(t *testing.T) {
+		// Arrange
+		stub := &AccountHandlrStub{}
+		handler := NewAccountHandlr(stub, logrus.New())
+		ctx, rec := newAccountContext(`{"email":"tester@email.com"}`)
+
+		// Act
+		err := handler.ForgotPassword(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+		assert.Equal(t, "tester@email.com", stub.forgot.Email)
+	})
+	t.Run("TestForgotPasswordHandlerShouldReturnHTTPStatus400ForBadBody", func(t *testing.T) {
+		// Arrange
+		handler := NewAccountHandlr(&AccountHandlrStub{}, logrus.New())
+		ctx, rec := newAccountContext(`{"email":`)
+
+		// Act
+		err := handler.ForgotPassword(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestResetPasswordHandler(t *testing.T) {
+	t.Run("TestResetPasswordHandlerShouldReturnHTTPStatus200", func(t *testing.T) {
+		// Arrange
+		stub := &AccountHandlrStub{}
+		handler := NewAccountHandlr(stub, logrus.New())
+		ctx, rec := newAccountContext(`{"token":"abc","password":"new password"}`)
+
+		// Act
+		err := handler.ResetPassword(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, RequestResetPassword{Token: "abc", Password: "new password"}, stub.reset)
+	})
+	t.Run("TestResetPasswordHandlerShouldReturnHTTPStatus500", func(t *testing.T) {
+		// Arrange
+		handler := NewAccountHandlr(&AccountHandlrStub{err: errors.New("boom")}, logrus.New())
+		ctx, rec := newAccountContext(`{"token":"abc","password":"new password"}`)
+
+		// Act
+		err := handler.ResetPassword(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
@@ -0,0 +1,228 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/paquesqueue/bookstore/mail"
+	"github.com/paquesqueue/bookstore/tracing"
+	"github.com/paquesqueue/bookstore/utils"
+)
+
+// A user token is a single-use link sent by email, or the challenge that
+// finishes a two-factor login; only its hash is stored.
+const (
+	tokenPurposeVerifyEmail   = "verify_email"
+	tokenPurposeResetPassword = "reset_password"
+	tokenPurposeMFALogin      = "mfa_login"
+)
+
+type AccountQueries interface {
+	SelectUser(ctx context.Context, username string) (ResponseUser, error)
+	SelectUsersByEmail(ctx context.Context, email string) ([]ResponseUser, error)
+	InsertUserToken(ctx context.Context, tokenHash string, username string, purpose string, email string, expiresAt time.Time) error
+	VerifyEmail(ctx context.Context, tokenHash string) (string, error)
+	SelectUserToken(ctx context.Context, tokenHash string, purpose string) (ResponseUser, error)
+	ResetPassword(ctx context.Context, tokenHash string, hashedPassword string) (string, error)
+}
+
+// accountEmail is the data the account email templates are rendered with.
+type accountEmail struct {
+	Username string
+	Fullname string
+	Link     string
+	ValidFor string
+}
+
+type AccountServices struct {
+	query  AccountQueries
+	mailer mail.Mailer
+	policy PasswordPolicy
+	conf   c.AuthConfig
+	log    c.Log
+	now    func() time.Time
+	// background runs work the response does not wait for.
+	background func(func())
+}
+
+func NewAccountService(q AccountQueries, m mail.Mailer, p PasswordPolicy, conf c.AuthConfig, l c.Log) AccountServices {
+	return AccountServices{q, m, p, conf, l, time.Now, func(f func()) { go f() }}
+}
+
+// detachedContext keeps a request's values, such as its log fields and
+// trace, for work that goes on after the response is sent.
+type detachedContext struct{ context.Context }
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+// SendVerification emails user a link confirming their current address.
+// Sending again replaces the earlier link.
+func (s AccountServices) SendVerification(ctx context.Context, user ResponseUser) error {
+	ctx, span := tracing.Start(ctx, "AccountServices.SendVerification")
+	defer span.End()
+
+	if user.Email == "" {
+		return &c.Err{Code: http.StatusBadRequest, Remark: "Error User Has No Email"}
+	}
+	return s.sendToken(ctx, user, tokenPurposeVerifyEmail, s.conf.VerifyEmailTTL, "/verify-email", "verify_email", "Error SendVerification Service")
+}
+
+// ResendVerification sends username a new verification link, unless their
+// address is verified already.
+func (s AccountServices) ResendVerification(ctx context.Context, username string) error {
+	ctx, span := tracing.Start(ctx, "AccountServices.ResendVerification")
+	defer span.End()
+
+	user, err := s.query.SelectUser(ctx, username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return &c.Err{Code: http.StatusNotFound, Remark: "Error User Not Found", Original: err}
+		}
+		c.LogFrom(ctx, s.log).Errorf("Error SelectUser : %v", err)
+		return &c.Err{Code: http.StatusInternalServerError, Remark: "Error ResendVerification Service", Original: err}
+	}
+	if user.EmailVerifiedAt != nil {
+		return &c.Err{Code: http.StatusConflict, Remark: "Error Email Already Verified"}
+	}
+	return s.SendVerification(ctx, user)
+}
+
+func (s AccountServices) VerifyEmail(ctx context.Context, req RequestEmailVerification) error {
+	ctx, span := tracing.Start(ctx, "AccountServices.VerifyEmail")
+	defer span.End()
+
+	if req.Token == "" {
+		return &c.Err{Code: http.StatusBadRequest, Remark: "Error Token Required"}
+	}
+	if _, err := s.query.VerifyEmail(ctx, utils.HashToken(req.Token)); err != nil {
+		return s.tokenErr(ctx, err, "VerifyEmail", "Error VerifyEmail Service")
+	}
+	return nil
+}
+
+// ForgotPassword emails a reset link to every user with the address. It
+// succeeds whether or not the address is known, so it cannot be used to
+// find out who has an account. The lookup and the emails happen after it
+// returns, so neither does the response time; failures are only logged.
+func (s AccountServices) ForgotPassword(ctx context.Context, req RequestForgotPassword) error {
+	ctx, span := tracing.Start(ctx, "AccountServices.ForgotPassword")
+	defer span.End()
+
+	email := strings.TrimSpace(req.Email)
+	if email == "" {
+		return &c.Err{Code: http.StatusBadRequest, Remark: "Error Email Required"}
+	}
+	ctx = detachedContext{ctx}
+	s.background(func() { s.sendPasswordResets(ctx, email) })
+	return nil
+}
+
+func (s AccountServices) sendPasswordResets(ctx context.Context, email string) {
+	ctx, span := tracing.Start(ctx, "AccountServices.sendPasswordResets")
+	defer span.End()
+
+	users, err := s.query.SelectUsersByEmail(ctx, email)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error SelectUsersByEmail : %v", err)
+		return
+	}
+	for _, user := range users {
+		// Errors are logged by sendToken.
+		_ = s.sendToken(ctx, user, tokenPurposeResetPassword, s.conf.PasswordResetTTL, "/reset-password", "password_reset", "Error ForgotPassword Service")
+	}
+}
+
+// ResetPassword sets a new password with a token from ForgotPassword and
+// signs the user out everywhere.
+func (s AccountServices) ResetPassword(ctx context.Context, req RequestResetPassword) error {
+	ctx, span := tracing.Start(ctx, "AccountServices.ResetPassword")
+	defer span.End()
+
+	if req.Token == "" || req.Password == "" {
+		return &c.Err{Code: http.StatusBadRequest, Remark: "Error Token And Password Required"}
+	}
+	tokenHash := utils.HashToken(req.Token)
+	user, err := s.query.SelectUserToken(ctx, tokenHash, tokenPurposeResetPassword)
+	if err != nil {
+		return s.tokenErr(ctx, err, "SelectUserToken", "Error ResetPassword Service")
+	}
+	if err := checkPassword(ctx, s.policy, s.log, req.Password, user.Username, user.Email, "Error ResetPassword Service"); err != nil {
+		return err
+	}
+	hashedPassword, err := utils.HashPassword(req.Password)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error ResetPassword Hash Password : %v", err)
+		return &c.Err{Code: http.StatusInternalServerError, Remark: "Error ResetPassword Service", Original: err}
+	}
+	if _, err := s.query.ResetPassword(ctx, tokenHash, hashedPassword); err != nil {
+		return s.tokenErr(ctx, err, "ResetPassword", "Error ResetPassword Service")
+	}
+	return nil
+}
+
+// sendToken stores a new token for user and emails them a link to path on
+// the app carrying it.
+func (s AccountServices) sendToken(ctx context.Context, user ResponseUser, purpose string, ttl time.Duration, path string, template string, remark string) error {
+	token, err := utils.NewToken()
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error %v New Token : %v", purpose, err)
+		return &c.Err{Code: http.StatusInternalServerError, Remark: remark, Original: err}
+	}
+	if err := s.query.InsertUserToken(ctx, utils.HashToken(token), user.Username, purpose, user.Email, s.now().Add(ttl)); err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error InsertUserToken : %v", err)
+		return &c.Err{Code: http.StatusInternalServerError, Remark: remark, Original: err}
+	}
+
+	msg, err := mail.Render(template, accountEmail{
+		Username: user.Username,
+		Fullname: user.Fullname,
+		Link:     strings.TrimSuffix(s.conf.AppURL, "/") + path + "?token=" + url.QueryEscape(token),
+		ValidFor: humanDuration(ttl),
+	})
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error Render %v Email : %v", template, err)
+		return &c.Err{Code: http.StatusInternalServerError, Remark: remark, Original: err}
+	}
+	msg.To = []string{user.Email}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error Send %v Email : %v", template, err)
+		return &c.Err{Code: http.StatusBadGateway, Remark: "Error Sending Email", Original: err}
+	}
+	return nil
+}
+
+func (s AccountServices) tokenErr(ctx context.Context, err error, query string, remark string) error {
+	if err == sql.ErrNoRows {
+		return &c.Err{Code: http.StatusBadRequest, Remark: "Error Invalid Or Expired Token", Original: err}
+	}
+	c.LogFrom(ctx, s.log).Errorf("Error %v : %v", query, err)
+	return &c.Err{Code: http.StatusInternalServerError, Remark: remark, Original: err}
+}
+
+// humanDuration writes d in the largest whole unit, like "2 days".
+func humanDuration(d time.Duration) string {
+	unit := func(n int64, name string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %v", name)
+		}
+		return fmt.Sprintf("%d %vs", n, name)
+	}
+	switch {
+	case d >= 24*time.Hour && d%(24*time.Hour) == 0:
+		return unit(int64(d/(24*time.Hour)), "day")
+	case d >= time.Hour && d%time.Hour == 0:
+		return unit(int64(d/time.Hour), "hour")
+	case d >= time.Minute:
+		return unit(int64(d/time.Minute), "minute")
+	default:
+		return d.String()
+	}
+}
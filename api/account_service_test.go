@@ -0,0 +1,341 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/paquesqueue/bookstore/mail"
+	"github.com/paquesqueue/bookstore/passwords"
+	"github.com/paquesqueue/bookstore/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type userToken struct {
+	tokenHash string
+	username  string
+	purpose   string
+	email     string
+	expiresAt time.Time
+}
+
+type AccountQueriesStub struct {
+	user           ResponseUser
+	users          []ResponseUser
+	tokens         []userToken
+	tokenHash      string
+	hashedPassword string
+	err            error
+}
+
+func (q *AccountQueriesStub) SelectUser(ctx context.Context, username string) (ResponseUser, error) {
+	return q.user, q.err
+}
+
+func (q *AccountQueriesStub) SelectUsersByEmail(ctx context.Context, email string) ([]ResponseUser, error) {
+	return q.users, q.err
+}
+
+func (q *AccountQueriesStub) InsertUserToken(ctx context.Context, tokenHash string, username string, purpose string, email string, expiresAt time.Time) error {
+	q.tokens = append(q.tokens, userToken{tokenHash, username, purpose, email, expiresAt})
+	return q.err
+}
+
+func (q *AccountQueriesStub) VerifyEmail(ctx context.Context, tokenHash string) (string, error) {
+	q.tokenHash = tokenHash
+	return q.user.Username, q.err
+}
+
+func (q *AccountQueriesStub) SelectUserToken(ctx context.Context, tokenHash string, purpose string) (ResponseUser, error) {
+	q.tokenHash = tokenHash
+	return q.user, q.err
+}
+
+func (q *AccountQueriesStub) ResetPassword(ctx context.Context, tokenHash string, hashedPassword string) (string, error) {
+	q.tokenHash, q.hashedPassword = tokenHash, hashedPassword
+	return q.user.Username, q.err
+}
+
+type MailerStub struct {
+	sent []mail.Message
+	err  error
+}
+
+func (m *MailerStub) Send(ctx context.Context, msg mail.Message) error {
+	m.sent = append(m.sent, msg)
+	return m.err
+}
+
+var accountNow = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newAccountServices(q AccountQueries, m mail.Mailer) AccountServices {
+	s := NewAccountService(q, m, passwords.Policy{MinLength: 8, RejectSimilar: true}, c.AuthConfig{
+		AppURL:           "https://shop.example/",
+		VerifyEmailTTL:   48 * time.Hour,
+		PasswordResetTTL: time.Hour,
+	}, logrus.New())
+	s.now = func() time.Time { return accountNow }
+	s.background = func(f func()) { f() }
+	return s
+}
+
+// mailedToken returns the token in the link of msg.
+func mailedToken(t *testing.T, msg mail.Message, path string) string {
+	start := strings.Index(msg.Text, "https://shop.example"+path+"?token=")
+	if !assert.GreaterOrEqual(t, start, 0) {
+		return ""
+	}
+	link, err := url.Parse(strings.Fields(msg.Text[start:])[0])
+	assert.NoError(t, err)
+	return link.Query().Get("token")
+}
+
+func TestSendVerification(t *testing.T) {
+	t.Run("TestSendVerificationShouldStoreHashAndMailLink", func(t *testing.T) {
+		// Arrange
+		query := &AccountQueriesStub{}
+		mailer := &MailerStub{}
+		services := newAccountServices(query, mailer)
+
+		// Act
+		err := services.SendVerification(context.Background(), ResponseUser{Username: "tester", Fullname: "Tes Ter", Email: "tester@email.com"})
+
+		// Assert
+		assert.NoError(t, err)
+		if assert.Len(t, query.tokens, 1) && assert.Len(t, mailer.sent, 1) {
+			msg := mailer.sent[0]
+			token := mailedToken(t, msg, "/verify-email")
+			assert.NotEmpty(t, token)
+			assert.Equal(t, userToken{utils.HashToken(token), "tester", tokenPurposeVerifyEmail, "tester@email.com", accountNow.Add(48 * time.Hour)}, query.tokens[0])
+			assert.Equal(t, []string{"tester@email.com"}, msg.To)
+			assert.Contains(t, msg.Text, "2 days")
+			assert.Contains(t, msg.HTML, "Tes Ter")
+		}
+	})
+	t.Run("TestSendVerificationShouldReturnBadGatewayWhenMailFails", func(t *testing.T) {
+		// Arrange
+		services := newAccountServices(&AccountQueriesStub{}, &MailerStub{err: errors.New("connection refused")})
+
+		// Act
+		err := services.SendVerification(context.Background(), ResponseUser{Username: "tester", Email: "tester@email.com"})
+
+		// Assert
+		if assert.IsType(t, &c.Err{}, err) {
+			assert.Equal(t, http.StatusBadGateway, err.(*c.Err).Code)
+		}
+	})
+}
+
+func TestResendVerification(t *testing.T) {
+	t.Run("TestResendVerificationShouldReturnConflictWhenVerified", func(t *testing.T) {
+		// Arrange
+		mailer := &MailerStub{}
+		services := newAccountServices(&AccountQueriesStub{user: ResponseUser{Username: "tester", Email: "tester@email.com", EmailVerifiedAt: &accountNow}}, mailer)
+
+		// Act
+		err := services.ResendVerification(context.Background(), "tester")
+
+		// Assert
+		if assert.IsType(t, &c.Err{}, err) {
+			assert.Equal(t, http.StatusConflict, err.(*c.Err).Code)
+		}
+		assert.Empty(t, mailer.sent)
+	})
+	t.Run("TestResendVerificationShouldSendToUnverifiedUser", func(t *testing.T) {
+		// Arrange
+		mailer := &MailerStub{}
+		services := newAccountServices(&AccountQueriesStub{user: ResponseUser{Username: "tester", Email: "tester@email.com"}}, mailer)
+
+		// Act
+		err := services.ResendVerification(context.Background(), "tester")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Len(t, mailer.sent, 1)
+	})
+}
+
+func TestVerifyEmail(t *testing.T) {
+	t.Run("TestVerifyEmailShouldLookUpTokenHash", func(t *testing.T) {
+		// Arrange
+		query := &AccountQueriesStub{user: ResponseUser{Username: "tester"}}
+		services := newAccountServices(query, &MailerStub{})
+
+		// Act
+		err := services.VerifyEmail(context.Background(), RequestEmailVerification{Token: "token"})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, utils.HashToken("token"), query.tokenHash)
+	})
+	t.Run("TestVerifyEmailShouldReturnBadRequestForUnknownToken", func(t *testing.T) {
+		for _, req := range []RequestEmailVerification{{}, {Token: "used"}} {
+			// Arrange
+			services := newAccountServices(&AccountQueriesStub{err: sql.ErrNoRows}, &MailerStub{})
+
+			// Act
+			err := services.VerifyEmail(context.Background(), req)
+
+			// Assert
+			if assert.IsType(t, &c.Err{}, err) {
+				assert.Equal(t, http.StatusBadRequest, err.(*c.Err).Code)
+			}
+		}
+	})
+}
+
+func TestForgotPassword(t *testing.T) {
+	t.Run("TestForgotPasswordShouldMailEveryUserWithTheEmail", func(t *testing.T) {
+		// Arrange
+		query := &AccountQueriesStub{users: []ResponseUser{
+			{Username: "tester", Email: "tester@email.com"},
+			{Username: "tester2", Email: "Tester@email.com"},
+		}}
+		mailer := &MailerStub{}
+		services := newAccountServices(query, mailer)
+
+		// Act
+		err := services.ForgotPassword(context.Background(), RequestForgotPassword{Email: " tester@email.com "})
+
+		// Assert
+		assert.NoError(t, err)
+		if assert.Len(t, mailer.sent, 2) && assert.Len(t, query.tokens, 2) {
+			token := mailedToken(t, mailer.sent[1], "/reset-password")
+			assert.Equal(t, userToken{utils.HashToken(token), "tester2", tokenPurposeResetPassword, "Tester@email.com", accountNow.Add(time.Hour)}, query.tokens[1])
+			assert.Contains(t, mailer.sent[1].Text, "1 hour")
+		}
+	})
+	t.Run("TestForgotPasswordShouldSucceedForUnknownEmail", func(t *testing.T) {
+		// Arrange
+		mailer := &MailerStub{}
+		services := newAccountServices(&AccountQueriesStub{users: []ResponseUser{}}, mailer)
+
+		// Act
+		err := services.ForgotPassword(context.Background(), RequestForgotPassword{Email: "nobody@email.com"})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Empty(t, mailer.sent)
+	})
+	t.Run("TestForgotPasswordShouldHideMailFailures", func(t *testing.T) {
+		// Arrange
+		query := &AccountQueriesStub{users: []ResponseUser{{Username: "tester", Email: "tester@email.com"}}}
+		services := newAccountServices(query, &MailerStub{err: errors.New("connection refused")})
+
+		// Act
+		err := services.ForgotPassword(context.Background(), RequestForgotPassword{Email: "tester@email.com"})
+
+		// Assert
+		assert.NoError(t, err)
+	})
+	t.Run("TestForgotPasswordShouldReturnBeforeLookingUpTheEmail", func(t *testing.T) {
+		// Arrange
+		query := &AccountQueriesStub{users: []ResponseUser{{Username: "tester", Email: "tester@email.com"}}}
+		mailer := &MailerStub{}
+		services := newAccountServices(query, mailer)
+		var pending func()
+		services.background = func(f func()) { pending = f }
+		ctx, cancel := context.WithCancel(context.Background())
+
+		// Act
+		err := services.ForgotPassword(ctx, RequestForgotPassword{Email: "tester@email.com"})
+		cancel()
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Empty(t, query.tokens)
+		assert.Empty(t, mailer.sent)
+		if assert.NotNil(t, pending) {
+			pending()
+			assert.Len(t, query.tokens, 1)
+			assert.Len(t, mailer.sent, 1)
+		}
+	})
+	t.Run("TestForgotPasswordShouldRequireEmail", func(t *testing.T) {
+		// Arrange
+		services := newAccountServices(&AccountQueriesStub{}, &MailerStub{})
+
+		// Act
+		err := services.ForgotPassword(context.Background(), RequestForgotPassword{Email: " "})
+
+		// Assert
+		if assert.IsType(t, &c.Err{}, err) {
+			assert.Equal(t, http.StatusBadRequest, err.(*c.Err).Code)
+		}
+	})
+}
+
+func TestResetPassword(t *testing.T) {
+	t.Run("TestResetPasswordShouldStoreHashedPassword", func(t *testing.T) {
+		// Arrange
+		query := &AccountQueriesStub{user: ResponseUser{Username: "tester"}}
+		services := newAccountServices(query, &MailerStub{})
+
+		// Act
+		err := services.ResetPassword(context.Background(), RequestResetPassword{Token: "token", Password: "new password"})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, utils.HashToken("token"), query.tokenHash)
+		assert.NoError(t, utils.CheckPassword(query.hashedPassword, "new password"))
+	})
+	t.Run("TestResetPasswordShouldApplyPolicyToTokenUser", func(t *testing.T) {
+		// Arrange
+		query := &AccountQueriesStub{user: ResponseUser{Username: "tester", Email: "tester@email.com"}}
+		services := newAccountServices(query, &MailerStub{})
+
+		// Act
+		err := services.ResetPassword(context.Background(), RequestResetPassword{Token: "token", Password: "tester2024"})
+
+		// Assert
+		if assert.IsType(t, &c.Err{}, err) {
+			assert.Equal(t, http.StatusBadRequest, err.(*c.Err).Code)
+			assert.Equal(t, &passwords.Rejection{Reasons: []string{passwords.ReasonSimilar}}, err.(*c.Err).Original)
+		}
+		assert.Empty(t, query.hashedPassword)
+	})
+	t.Run("TestResetPasswordShouldReturnBadRequest", func(t *testing.T) {
+		for _, tc := range []struct {
+			req RequestResetPassword
+			err error
+		}{
+			{RequestResetPassword{Token: "token"}, nil},
+			{RequestResetPassword{Password: "new password"}, nil},
+			{RequestResetPassword{Token: "expired", Password: "new password"}, sql.ErrNoRows},
+		} {
+			// Arrange
+			services := newAccountServices(&AccountQueriesStub{err: tc.err}, &MailerStub{})
+
+			// Act
+			err := services.ResetPassword(context.Background(), tc.req)
+
+			// Assert
+			if assert.IsType(t, &c.Err{}, err) {
+				assert.Equal(t, http.StatusBadRequest, err.(*c.Err).Code)
+			}
+		}
+	})
+}
+
+func TestHumanDuration(t *testing.T) {
+	t.Run("TestHumanDurationShouldUseLargestWholeUnit", func(t *testing.T) {
+		for d, want := range map[time.Duration]string{
+			48 * time.Hour:   "2 days",
+			36 * time.Hour:   "36 hours",
+			time.Hour:        "1 hour",
+			90 * time.Minute: "90 minutes",
+			30 * time.Second: "30s",
+		} {
+			assert.Equal(t, want, humanDuration(d))
+		}
+	})
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const apiKeyColumns = `k.id, k.username, k.name, k.prefix, k.scopes, k.created_at, k.expires_at, k.last_used_at, k.rotated_at, k.revoked_at`
+
+func scanAPIKey(row rowScanner, dest ...interface{}) (ResponseAPIKey, error) {
+	resp := ResponseAPIKey{}
+	var expiresAt, lastUsedAt, rotatedAt, revokedAt sql.NullTime
+	err := row.Scan(append([]interface{}{&resp.Id, &resp.Username, &resp.Name, &resp.Prefix, pq.Array(&resp.Scopes),
+		&resp.CreatedAt, &expiresAt, &lastUsedAt, &rotatedAt, &revokedAt}, dest...)...)
+	if err != nil {
+		return ResponseAPIKey{}, err
+	}
+	resp.ExpiresAt = nullTime(expiresAt)
+	resp.LastUsedAt = nullTime(lastUsedAt)
+	resp.RotatedAt = nullTime(rotatedAt)
+	resp.RevokedAt = nullTime(revokedAt)
+	return resp, nil
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func (db Query) InsertAPIKey(ctx context.Context, req RequestAPIKey) (ResponseAPIKey, error) {
+	const query = `INSERT INTO api_keys AS k (username, name, prefix, key_hash, scopes, expires_at)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING ` + apiKeyColumns + `;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return ResponseAPIKey{}, err
+	}
+	defer stmt.Close()
+
+	row := stmt.QueryRowContext(ctx, req.Username, req.Name, req.Prefix, req.KeyHash, pq.Array(req.Scopes), req.ExpiresAt)
+	return scanAPIKey(row)
+}
+
+// SelectAPIKeys returns every key of a user, revoked and expired ones
+// included, newest first.
+func (db Query) SelectAPIKeys(ctx context.Context, username string) ([]ResponseAPIKey, error) {
+	const query = `SELECT ` + apiKeyColumns + `
+	FROM api_keys k
+	WHERE k.username = $1
+	ORDER BY k.created_at DESC, k.id DESC;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resp := []ResponseAPIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		resp = append(resp, key)
+	}
+	return resp, rows.Err()
+}
+
+// RotateAPIKey replaces the secret of a user's unrevoked key. The old secret
+// stops working at once.
+func (db Query) RotateAPIKey(ctx context.Context, username string, id uint64, prefix string, keyHash string) (ResponseAPIKey, error) {
+	const query = `UPDATE api_keys AS k SET prefix = $3, key_hash = $4, rotated_at = NOW()
+	WHERE k.id = $1 AND k.username = $2 AND k.revoked_at IS NULL
+	RETURNING ` + apiKeyColumns + `;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return ResponseAPIKey{}, err
+	}
+	defer stmt.Close()
+
+	return scanAPIKey(stmt.QueryRowContext(ctx, id, username, prefix, keyHash))
+}
+
+// RevokeAPIKey disables a user's key for good. Revoking a key twice returns
+// sql.ErrNoRows, like revoking a key that does not exist.
+func (db Query) RevokeAPIKey(ctx context.Context, username string, id uint64) error {
+	const query = `UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND username = $2 AND revoked_at IS NULL;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, id, username)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// SelectAPIKeyUser returns an unrevoked, unexpired key and its owner.
+func (db Query) SelectAPIKeyUser(ctx context.Context, keyHash string) (ResponseAPIKey, ResponseUser, error) {
+	const query = `SELECT ` + apiKeyColumns + `, u.username, u.email, u.fullname, u.hashed_password, u.created_at, u.role, u.mfa_enabled_at
+	FROM api_keys k
+	JOIN users u ON u.username = k.username
+	WHERE k.key_hash = $1 AND k.revoked_at IS NULL AND (k.expires_at IS NULL OR k.expires_at > NOW());`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return ResponseAPIKey{}, ResponseUser{}, err
+	}
+	defer stmt.Close()
+
+	user := ResponseUser{}
+	var mfaEnabledAt sql.NullTime
+	key, err := scanAPIKey(stmt.QueryRowContext(ctx, keyHash),
+		&user.Username, &user.Email, &user.Fullname, &user.HashedPassword, &user.CreatedAt, &user.Role, &mfaEnabledAt)
+	if err != nil {
+		return ResponseAPIKey{}, ResponseUser{}, err
+	}
+	user.MFAEnabledAt = nullTime(mfaEnabledAt)
+	return key, user, nil
+}
+
+// TouchAPIKey records that a key was used. It writes at most once a minute
+// per key so busy integrations do not turn every request into an update.
+func (db Query) TouchAPIKey(ctx context.Context, id uint64) error {
+	const query = `UPDATE api_keys SET last_used_at = NOW()
+	WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute');`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, id)
+	return err
+}
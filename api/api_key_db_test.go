@@ -0,0 +1,95 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var apiKeyRowColumns = []string{"id", "username", "name", "prefix", "scopes", "created_at", "expires_at", "last_used_at", "rotated_at", "revoked_at"}
+
+func TestSelectAPIKeyUser(t *testing.T) {
+	t.Run("TestSelectAPIKeyUserShouldReturnActiveKeyAndOwner", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		expiresAt := createdAt.AddDate(1, 0, 0)
+		mock.ExpectPrepare(regexp.QuoteMeta(`WHERE k.key_hash = $1 AND k.revoked_at IS NULL AND (k.expires_at IS NULL OR k.expires_at > NOW());`)).
+			ExpectQuery().
+			WithArgs("hash").
+			WillReturnRows(sqlmock.NewRows(append(apiKeyRowColumns, "username", "email", "fullname", "hashed_password", "created_at", "role", "mfa_enabled_at")).
+				AddRow(4, "reader", "Shop sync", "bk_abcdefgh", "{books:read,lists:write}", createdAt, expiresAt, nil, nil, nil,
+					"reader", "reader@email.com", "Re Ader", "hashed", createdAt, UserRoleCustomer, nil))
+
+		query := NewDB(db)
+
+		// Act
+		key, user, err := query.SelectAPIKeyUser(context.Background(), "hash")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(4), key.Id)
+		assert.Equal(t, []string{"books:read", "lists:write"}, key.Scopes)
+		assert.Equal(t, &expiresAt, key.ExpiresAt)
+		assert.Nil(t, key.LastUsedAt)
+		assert.Equal(t, "reader", user.Username)
+		assert.Equal(t, UserRoleCustomer, user.Role)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRevokeAPIKeyQuery(t *testing.T) {
+	t.Run("TestRevokeAPIKeyShouldReturnErrNoRowsWhenNothingRevoked", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectPrepare(regexp.QuoteMeta(`UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND username = $2 AND revoked_at IS NULL;`)).
+			ExpectExec().
+			WithArgs(3, "intruder").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		query := NewDB(db)
+
+		// Act
+		err = query.RevokeAPIKey(context.Background(), "intruder", 3)
+
+		// Assert
+		assert.Equal(t, sql.ErrNoRows, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestTouchAPIKey(t *testing.T) {
+	t.Run("TestTouchAPIKeyShouldUpdateAtMostOnceAMinute", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectPrepare(regexp.QuoteMeta(`WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute');`)).
+			ExpectExec().
+			WithArgs(4).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		query := NewDB(db)
+
+		// Act
+		err = query.TouchAPIKey(context.Background(), 4)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
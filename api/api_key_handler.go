@@ -0,0 +1,109 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	c "github.com/paquesqueue/bookstore/common"
+)
+
+type APIKeyHandlrQueries interface {
+	AddAPIKey(ctx context.Context, username string, role string, req RequestAPIKey) (ResponseAPIKey, error)
+	GetAPIKeys(ctx context.Context, username string) ([]ResponseAPIKey, error)
+	RotateAPIKey(ctx context.Context, username string, id uint64) (ResponseAPIKey, error)
+	RevokeAPIKey(ctx context.Context, username string, id uint64) error
+}
+
+type APIKeyHandlr struct {
+	handler APIKeyHandlrQueries
+	log     c.Log
+}
+
+func NewAPIKeyHandlr(h APIKeyHandlrQueries, l c.Log) APIKeyHandlr {
+	return APIKeyHandlr{h, l}
+}
+
+func (h APIKeyHandlr) AddAPIKey(ctx echo.Context) error {
+	username, ok := sessionOwner(ctx)
+	if !ok {
+		return ctx.NoContent(http.StatusForbidden)
+	}
+
+	req := RequestAPIKey{}
+	err := ctx.Bind(&req)
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	user, _ := CurrentUser(ctx)
+	res, err := h.handler.AddAPIKey(ctx.Request().Context(), username, user.Role, req)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error AddAPIKey Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusCreated, res)
+}
+
+func (h APIKeyHandlr) GetAPIKeys(ctx echo.Context) error {
+	username, ok := sessionOwner(ctx)
+	if !ok {
+		return ctx.NoContent(http.StatusForbidden)
+	}
+
+	res, err := h.handler.GetAPIKeys(ctx.Request().Context(), username)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error GetAPIKeys Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h APIKeyHandlr) RotateAPIKey(ctx echo.Context) error {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+	username, ok := sessionOwner(ctx)
+	if !ok {
+		return ctx.NoContent(http.StatusForbidden)
+	}
+
+	res, err := h.handler.RotateAPIKey(ctx.Request().Context(), username, uint64(id))
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error RotateAPIKey Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h APIKeyHandlr) RevokeAPIKey(ctx echo.Context) error {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+	username, ok := sessionOwner(ctx)
+	if !ok {
+		return ctx.NoContent(http.StatusForbidden)
+	}
+
+	err = h.handler.RevokeAPIKey(ctx.Request().Context(), username, uint64(id))
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error RevokeAPIKey Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, "Revoked Successfully")
+}
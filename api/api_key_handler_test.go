@@ -0,0 +1,192 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type APIKeyHandlrStub struct {
+	username string
+	role     string
+	id       uint64
+	req      RequestAPIKey
+	err      error
+}
+
+func (h *APIKeyHandlrStub) AddAPIKey(ctx context.Context, username string, role string, req RequestAPIKey) (ResponseAPIKey, error) {
+	h.username, h.role, h.req = username, role, req
+	return ResponseAPIKey{Id: 1, Username: username, Name: req.Name, Scopes: req.Scopes, Key: "bk_secret"}, h.err
+}
+
+func (h *APIKeyHandlrStub) GetAPIKeys(ctx context.Context, username string) ([]ResponseAPIKey, error) {
+	h.username = username
+	return []ResponseAPIKey{{Id: 1, Username: username}}, h.err
+}
+
+func (h *APIKeyHandlrStub) RotateAPIKey(ctx context.Context, username string, id uint64) (ResponseAPIKey, error) {
+	h.username, h.id = username, id
+	return ResponseAPIKey{Id: id, Username: username, Key: "bk_rotated"}, h.err
+}
+
+func (h *APIKeyHandlrStub) RevokeAPIKey(ctx context.Context, username string, id uint64) error {
+	h.username, h.id = username, id
+	return h.err
+}
+
+func newAPIKeyContext(method string, body string, user *ResponseUser) (echo.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(method, "/users/reader/api-keys/3", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	ctx := echo.New().NewContext(req, rec)
+	ctx.SetParamNames("username", "id")
+	ctx.SetParamValues("reader", "3")
+	if user != nil {
+		ctx.Set(ctxUserKey, *user)
+	}
+	return ctx, rec
+}
+
+func TestAddAPIKeyHandler(t *testing.T) {
+	t.Run("TestAddAPIKeyHandlerShouldReturnHTTPStatus201WithKey", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newAPIKeyContext(http.MethodPost, `{"name":"Shop sync","scopes":["books:read"]}`, &ResponseUser{Username: "reader", Role: UserRoleCustomer})
+		stub := &APIKeyHandlrStub{}
+		handler := NewAPIKeyHandlr(stub, logrus.New())
+
+		// Act
+		err := handler.AddAPIKey(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, "reader", stub.username)
+		assert.Equal(t, UserRoleCustomer, stub.role)
+		assert.Equal(t, []string{"books:read"}, stub.req.Scopes)
+
+		res := ResponseAPIKey{}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		assert.Equal(t, "bk_secret", res.Key)
+	})
+
+	t.Run("TestAddAPIKeyHandlerShouldReturnHTTPStatus403ForOtherUser", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newAPIKeyContext(http.MethodPost, `{"name":"Shop sync"}`, &ResponseUser{Username: "intruder"})
+		stub := &APIKeyHandlrStub{}
+		handler := NewAPIKeyHandlr(stub, logrus.New())
+
+		// Act
+		err := handler.AddAPIKey(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Empty(t, stub.username)
+	})
+
+	t.Run("TestAddAPIKeyHandlerShouldReturnHTTPStatus403WhenCalledWithAPIKey", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newAPIKeyContext(http.MethodPost, `{"name":"Wider","scopes":["books:write"]}`, &ResponseUser{Username: "reader"})
+		ctx.Set(ctxAPIKeyKey, ResponseAPIKey{Id: 1, Username: "reader", Scopes: []string{"users:write"}})
+		stub := &APIKeyHandlrStub{}
+		handler := NewAPIKeyHandlr(stub, logrus.New())
+
+		// Act
+		err := handler.AddAPIKey(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Empty(t, stub.username)
+	})
+
+	t.Run("TestAddAPIKeyHandlerShouldReturnServiceStatus", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newAPIKeyContext(http.MethodPost, `{"name":""}`, &ResponseUser{Username: "reader"})
+		stub := &APIKeyHandlrStub{err: &c.Err{Code: http.StatusBadRequest}}
+		handler := NewAPIKeyHandlr(stub, logrus.New())
+
+		// Act
+		err := handler.AddAPIKey(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestGetAPIKeysHandler(t *testing.T) {
+	t.Run("TestGetAPIKeysHandlerShouldReturnHTTPStatus200", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newAPIKeyContext(http.MethodGet, "", &ResponseUser{Username: "reader"})
+		stub := &APIKeyHandlrStub{}
+		handler := NewAPIKeyHandlr(stub, logrus.New())
+
+		// Act
+		err := handler.GetAPIKeys(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "reader", stub.username)
+	})
+}
+
+func TestRotateAPIKeyHandler(t *testing.T) {
+	t.Run("TestRotateAPIKeyHandlerShouldReturnNewKey", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newAPIKeyContext(http.MethodPost, "", &ResponseUser{Username: "reader"})
+		stub := &APIKeyHandlrStub{}
+		handler := NewAPIKeyHandlr(stub, logrus.New())
+
+		// Act
+		err := handler.RotateAPIKey(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, uint64(3), stub.id)
+		assert.Contains(t, rec.Body.String(), "bk_rotated")
+	})
+}
+
+func TestRevokeAPIKeyHandler(t *testing.T) {
+	t.Run("TestRevokeAPIKeyHandlerShouldReturnHTTPStatus200", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newAPIKeyContext(http.MethodDelete, "", &ResponseUser{Username: "reader"})
+		stub := &APIKeyHandlrStub{}
+		handler := NewAPIKeyHandlr(stub, logrus.New())
+
+		// Act
+		err := handler.RevokeAPIKey(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, uint64(3), stub.id)
+	})
+
+	t.Run("TestRevokeAPIKeyHandlerShouldReturnHTTPStatus404ForUnknownKey", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newAPIKeyContext(http.MethodDelete, "", &ResponseUser{Username: "reader"})
+		stub := &APIKeyHandlrStub{err: &c.Err{Code: http.StatusNotFound}}
+		handler := NewAPIKeyHandlr(stub, logrus.New())
+
+		// Act
+		err := handler.RevokeAPIKey(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
@@ -0,0 +1,262 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/paquesqueue/bookstore/tracing"
+	"github.com/paquesqueue/bookstore/utils"
+)
+
+const (
+	// APIKeyPrefix starts every API key, so keys are told apart from the
+	// service access token and are easy to spot when leaked.
+	APIKeyPrefix = "bk_"
+
+	// apiKeyShownLen is how much of a key is stored in the clear to help
+	// its owner recognise it.
+	apiKeyShownLen   = len(APIKeyPrefix) + 8
+	apiKeyMaxNameLen = 100
+)
+
+// An API key scope is a resource and an access level, like books:read.
+// Write access includes read access.
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+)
+
+// apiKeyResources are the resources an API key can be scoped to; a resource
+// is the first segment of a route's path, except for lists which live under
+// their owner.
+var apiKeyResources = []string{"authors", "books", "categories", "feeds", "lists", "publishers", "reviews", "tags", "users", "works"}
+
+// apiKeyCustomerWrites are the resources a customer's key may write; the
+// rest of the catalog is written by staff. Only admins may scope a key to
+// users.
+var apiKeyCustomerWrites = []string{"lists", "reviews"}
+
+// APIKeyScope is the scope a request to route needs, like books:write for
+// PUT /books/:id.
+func APIKeyScope(method string, route string) string {
+	segments := strings.Split(strings.Trim(route, "/"), "/")
+	resource := segments[0]
+	if resource == "users" && len(segments) > 2 && segments[2] == "lists" {
+		resource = "lists"
+	}
+
+	access := ScopeWrite
+	if method == http.MethodGet || method == http.MethodHead {
+		access = ScopeRead
+	}
+	return resource + ":" + access
+}
+
+// AllowsScope reports whether key may make requests needing scope.
+func (key ResponseAPIKey) AllowsScope(scope string) bool {
+	resource, access, _ := strings.Cut(scope, ":")
+	for _, s := range key.Scopes {
+		if s == scope || (access == ScopeRead && s == resource+":"+ScopeWrite) {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKeyFromHeader returns the API key in an Authorization header, sent
+// either bare or as a bearer token.
+func APIKeyFromHeader(header string) (string, bool) {
+	key := header
+	if len(key) > len("Bearer ") && strings.EqualFold(key[:len("Bearer ")], "Bearer ") {
+		key = key[len("Bearer "):]
+	}
+	if !strings.HasPrefix(key, APIKeyPrefix) || len(key) == len(APIKeyPrefix) {
+		return "", false
+	}
+	return key, true
+}
+
+type APIKeyQueries interface {
+	InsertAPIKey(ctx context.Context, req RequestAPIKey) (ResponseAPIKey, error)
+	SelectAPIKeys(ctx context.Context, username string) ([]ResponseAPIKey, error)
+	RotateAPIKey(ctx context.Context, username string, id uint64, prefix string, keyHash string) (ResponseAPIKey, error)
+	RevokeAPIKey(ctx context.Context, username string, id uint64) error
+}
+
+type APIKeyServices struct {
+	query APIKeyQueries
+	log   c.Log
+	now   func() time.Time
+}
+
+func NewAPIKeyService(q APIKeyQueries, l c.Log) APIKeyServices {
+	return APIKeyServices{q, l, time.Now}
+}
+
+// AddAPIKey creates a key for username, whose role limits the scopes it may
+// have. The returned key is the only time it is shown; only its hash is
+// stored.
+func (s APIKeyServices) AddAPIKey(ctx context.Context, username string, role string, req RequestAPIKey) (ResponseAPIKey, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyServices.AddAPIKey")
+	defer span.End()
+
+	req, err := s.validAPIKey(req)
+	if err != nil {
+		return ResponseAPIKey{}, err
+	}
+	for _, scope := range req.Scopes {
+		if !roleGrantsScope(role, scope) {
+			return ResponseAPIKey{}, &c.Err{Code: http.StatusForbidden, Remark: "Error API Key Scope Not Allowed For Role"}
+		}
+	}
+	key, err := newAPIKey()
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error APIKey New Token : %v", err)
+		return ResponseAPIKey{}, &c.Err{Code: http.StatusInternalServerError, Remark: "Error AddAPIKey Service", Original: err}
+	}
+	req.Username = username
+	req.Prefix = key[:apiKeyShownLen]
+	req.KeyHash = utils.HashToken(key)
+
+	resp, err := s.query.InsertAPIKey(ctx, req)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error InsertAPIKey : %v", err)
+		return ResponseAPIKey{}, apiKeyErr(err, "Error AddAPIKey Service")
+	}
+	resp.Key = key
+	return resp, nil
+}
+
+func (s APIKeyServices) GetAPIKeys(ctx context.Context, username string) ([]ResponseAPIKey, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyServices.GetAPIKeys")
+	defer span.End()
+
+	resp, err := s.query.SelectAPIKeys(ctx, username)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error SelectAPIKeys : %v", err)
+		return nil, apiKeyErr(err, "Error GetAPIKeys Service")
+	}
+	return resp, nil
+}
+
+// RotateAPIKey gives a key a new secret, keeping its name, scopes and
+// expiry. Integrations must switch to the returned key right away.
+func (s APIKeyServices) RotateAPIKey(ctx context.Context, username string, id uint64) (ResponseAPIKey, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyServices.RotateAPIKey")
+	defer span.End()
+
+	key, err := newAPIKey()
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error APIKey New Token : %v", err)
+		return ResponseAPIKey{}, &c.Err{Code: http.StatusInternalServerError, Remark: "Error RotateAPIKey Service", Original: err}
+	}
+
+	resp, err := s.query.RotateAPIKey(ctx, username, id, key[:apiKeyShownLen], utils.HashToken(key))
+	if err != nil {
+		if err != sql.ErrNoRows {
+			c.LogFrom(ctx, s.log).Errorf("Error RotateAPIKey : %v", err)
+		}
+		return ResponseAPIKey{}, apiKeyErr(err, "Error RotateAPIKey Service")
+	}
+	resp.Key = key
+	return resp, nil
+}
+
+func (s APIKeyServices) RevokeAPIKey(ctx context.Context, username string, id uint64) error {
+	ctx, span := tracing.Start(ctx, "APIKeyServices.RevokeAPIKey")
+	defer span.End()
+
+	if err := s.query.RevokeAPIKey(ctx, username, id); err != nil {
+		if err != sql.ErrNoRows {
+			c.LogFrom(ctx, s.log).Errorf("Error RevokeAPIKey : %v", err)
+		}
+		return apiKeyErr(err, "Error RevokeAPIKey Service")
+	}
+	return nil
+}
+
+// validAPIKey trims the name and sorts and de-duplicates the scopes.
+func (s APIKeyServices) validAPIKey(req RequestAPIKey) (RequestAPIKey, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || utf8.RuneCountInString(req.Name) > apiKeyMaxNameLen {
+		return req, &c.Err{Code: http.StatusBadRequest, Remark: "Error API Key Name Required"}
+	}
+	if len(req.Scopes) == 0 {
+		return req, &c.Err{Code: http.StatusBadRequest, Remark: "Error API Key Scopes Required"}
+	}
+
+	scopes := map[string]bool{}
+	for _, scope := range req.Scopes {
+		if !validScope(scope) {
+			return req, &c.Err{Code: http.StatusBadRequest, Remark: "Error Unknown API Key Scope"}
+		}
+		scopes[scope] = true
+	}
+	req.Scopes = make([]string, 0, len(scopes))
+	for scope := range scopes {
+		req.Scopes = append(req.Scopes, scope)
+	}
+	sort.Strings(req.Scopes)
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(s.now()) {
+		return req, &c.Err{Code: http.StatusBadRequest, Remark: "Error API Key Expiry Must Be In The Future"}
+	}
+	return req, nil
+}
+
+func validScope(scope string) bool {
+	resource, access, _ := strings.Cut(scope, ":")
+	if access != ScopeRead && access != ScopeWrite {
+		return false
+	}
+	for _, r := range apiKeyResources {
+		if r == resource {
+			return true
+		}
+	}
+	return false
+}
+
+// roleGrantsScope reports whether a user with role may give a key scope.
+func roleGrantsScope(role string, scope string) bool {
+	resource, access, _ := strings.Cut(scope, ":")
+	switch {
+	case role == UserRoleAdmin:
+		return true
+	case resource == "users":
+		return false
+	case role == UserRoleStaff, access == ScopeRead:
+		return true
+	}
+	for _, r := range apiKeyCustomerWrites {
+		if r == resource {
+			return true
+		}
+	}
+	return false
+}
+
+func newAPIKey() (string, error) {
+	token, err := utils.NewToken()
+	if err != nil {
+		return "", err
+	}
+	return APIKeyPrefix + token, nil
+}
+
+func apiKeyErr(err error, remark string) *c.Err {
+	switch {
+	case err == sql.ErrNoRows:
+		return &c.Err{Code: http.StatusNotFound, Remark: "Error API Key Not Found", Original: err}
+	case isPqError(err, pqForeignKeyViolation):
+		return &c.Err{Code: http.StatusNotFound, Remark: "Error User Not Found", Original: err}
+	default:
+		return &c.Err{Code: http.StatusInternalServerError, Remark: remark, Original: err}
+	}
+}
@@ -0,0 +1,221 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/paquesqueue/bookstore/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type APIKeyQueriesStub struct {
+	inserted RequestAPIKey
+	prefix   string
+	keyHash  string
+	err      error
+}
+
+func (q *APIKeyQueriesStub) InsertAPIKey(ctx context.Context, req RequestAPIKey) (ResponseAPIKey, error) {
+	q.inserted = req
+	return ResponseAPIKey{Id: 1, Username: req.Username, Name: req.Name, Prefix: req.Prefix, Scopes: req.Scopes, ExpiresAt: req.ExpiresAt}, q.err
+}
+
+func (q *APIKeyQueriesStub) SelectAPIKeys(ctx context.Context, username string) ([]ResponseAPIKey, error) {
+	return []ResponseAPIKey{}, q.err
+}
+
+func (q *APIKeyQueriesStub) RotateAPIKey(ctx context.Context, username string, id uint64, prefix string, keyHash string) (ResponseAPIKey, error) {
+	q.prefix, q.keyHash = prefix, keyHash
+	return ResponseAPIKey{Id: id, Username: username, Prefix: prefix}, q.err
+}
+
+func (q *APIKeyQueriesStub) RevokeAPIKey(ctx context.Context, username string, id uint64) error {
+	return q.err
+}
+
+func newAPIKeyServices(q APIKeyQueries) APIKeyServices {
+	s := NewAPIKeyService(q, logrus.New())
+	s.now = func() time.Time { return time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC) }
+	return s
+}
+
+func TestAddAPIKey(t *testing.T) {
+	t.Run("TestAddAPIKeyShouldStoreHashAndReturnKeyOnce", func(t *testing.T) {
+		// Arrange
+		query := &APIKeyQueriesStub{}
+		services := newAPIKeyServices(query)
+		expiresAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		// Act
+		res, err := services.AddAPIKey(context.Background(), "reader", UserRoleCustomer, RequestAPIKey{
+			Name:      " Shop sync ",
+			Scopes:    []string{"lists:write", "books:read", "books:read"},
+			ExpiresAt: &expiresAt,
+		})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(res.Key, APIKeyPrefix))
+		assert.Equal(t, res.Key[:apiKeyShownLen], res.Prefix)
+		assert.Equal(t, utils.HashToken(res.Key), query.inserted.KeyHash)
+		assert.Equal(t, "reader", query.inserted.Username)
+		assert.Equal(t, "Shop sync", query.inserted.Name)
+		assert.Equal(t, []string{"books:read", "lists:write"}, query.inserted.Scopes)
+	})
+
+	t.Run("TestAddAPIKeyShouldRejectInvalidRequests", func(t *testing.T) {
+		past := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		for _, req := range []RequestAPIKey{
+			{Name: "", Scopes: []string{"books:read"}},
+			{Name: "No scopes"},
+			{Name: "Unknown resource", Scopes: []string{"metrics:read"}},
+			{Name: "Unknown access", Scopes: []string{"books:admin"}},
+			{Name: "Expired", Scopes: []string{"books:read"}, ExpiresAt: &past},
+		} {
+			// Arrange
+			query := &APIKeyQueriesStub{}
+			services := newAPIKeyServices(query)
+
+			// Act
+			_, err := services.AddAPIKey(context.Background(), "reader", UserRoleCustomer, req)
+
+			// Assert
+			if assert.Error(t, err, req.Name) {
+				assert.Equal(t, http.StatusBadRequest, err.(*c.Err).Code, req.Name)
+			}
+			assert.Empty(t, query.inserted.KeyHash, req.Name)
+		}
+	})
+
+	t.Run("TestAddAPIKeyShouldLimitScopesToRole", func(t *testing.T) {
+		for _, tc := range []struct {
+			role    string
+			scope   string
+			allowed bool
+		}{
+			{UserRoleCustomer, "books:read", true},
+			{UserRoleCustomer, "reviews:write", true},
+			{UserRoleCustomer, "books:write", false},
+			{UserRoleCustomer, "feeds:write", false},
+			{UserRoleCustomer, "users:read", false},
+			{UserRoleStaff, "books:write", true},
+			{UserRoleStaff, "feeds:write", true},
+			{UserRoleStaff, "users:read", false},
+			{UserRoleAdmin, "users:write", true},
+		} {
+			// Arrange
+			query := &APIKeyQueriesStub{}
+			services := newAPIKeyServices(query)
+
+			// Act
+			_, err := services.AddAPIKey(context.Background(), "reader", tc.role, RequestAPIKey{Name: "Sync", Scopes: []string{tc.scope}})
+
+			// Assert
+			if tc.allowed {
+				assert.NoError(t, err, tc.role+" "+tc.scope)
+				assert.NotEmpty(t, query.inserted.KeyHash, tc.role+" "+tc.scope)
+			} else if assert.Error(t, err, tc.role+" "+tc.scope) {
+				assert.Equal(t, http.StatusForbidden, err.(*c.Err).Code, tc.role+" "+tc.scope)
+				assert.Empty(t, query.inserted.KeyHash, tc.role+" "+tc.scope)
+			}
+		}
+	})
+}
+
+func TestRotateAPIKey(t *testing.T) {
+	t.Run("TestRotateAPIKeyShouldStoreNewHash", func(t *testing.T) {
+		// Arrange
+		query := &APIKeyQueriesStub{}
+		services := newAPIKeyServices(query)
+
+		// Act
+		res, err := services.RotateAPIKey(context.Background(), "reader", 3)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, utils.HashToken(res.Key), query.keyHash)
+		assert.Equal(t, res.Key[:apiKeyShownLen], query.prefix)
+	})
+
+	t.Run("TestRotateAPIKeyShouldReturnHTTPStatus404ForRevokedKey", func(t *testing.T) {
+		// Arrange
+		services := newAPIKeyServices(&APIKeyQueriesStub{err: sql.ErrNoRows})
+
+		// Act
+		_, err := services.RotateAPIKey(context.Background(), "reader", 3)
+
+		// Assert
+		if assert.Error(t, err) {
+			assert.Equal(t, http.StatusNotFound, err.(*c.Err).Code)
+		}
+	})
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	t.Run("TestRevokeAPIKeyShouldReturnHTTPStatus404ForUnknownKey", func(t *testing.T) {
+		// Arrange
+		services := newAPIKeyServices(&APIKeyQueriesStub{err: sql.ErrNoRows})
+
+		// Act
+		err := services.RevokeAPIKey(context.Background(), "reader", 3)
+
+		// Assert
+		if assert.Error(t, err) {
+			assert.Equal(t, http.StatusNotFound, err.(*c.Err).Code)
+		}
+	})
+}
+
+func TestAPIKeyScope(t *testing.T) {
+	t.Run("TestAPIKeyScopeShouldUseResourceAndMethod", func(t *testing.T) {
+		// Assert
+		assert.Equal(t, "books:read", APIKeyScope(http.MethodGet, "/books/:id"))
+		assert.Equal(t, "books:write", APIKeyScope(http.MethodPut, "/books/:id/cover"))
+		assert.Equal(t, "lists:write", APIKeyScope(http.MethodPost, "/users/:username/lists/:id/books"))
+		assert.Equal(t, "users:read", APIKeyScope(http.MethodGet, "/users/:username/recommendations"))
+	})
+
+	t.Run("TestAllowsScopeShouldLetWriteIncludeRead", func(t *testing.T) {
+		// Arrange
+		key := ResponseAPIKey{Scopes: []string{"books:write", "tags:read"}}
+
+		// Assert
+		assert.True(t, key.AllowsScope("books:read"))
+		assert.True(t, key.AllowsScope("books:write"))
+		assert.True(t, key.AllowsScope("tags:read"))
+		assert.False(t, key.AllowsScope("tags:write"))
+		assert.False(t, key.AllowsScope("reviews:read"))
+	})
+}
+
+func TestAPIKeyFromHeader(t *testing.T) {
+	t.Run("TestAPIKeyFromHeaderShouldAcceptBareAndBearerKeys", func(t *testing.T) {
+		// Act
+		bare, bareOk := APIKeyFromHeader("bk_abc")
+		bearer, bearerOk := APIKeyFromHeader("Bearer bk_abc")
+
+		// Assert
+		assert.True(t, bareOk)
+		assert.Equal(t, "bk_abc", bare)
+		assert.True(t, bearerOk)
+		assert.Equal(t, "bk_abc", bearer)
+	})
+
+	t.Run("TestAPIKeyFromHeaderShouldIgnoreOtherCredentials", func(t *testing.T) {
+		for _, header := range []string{"", "token", "Bearer token", "bk_"} {
+			// Act
+			_, ok := APIKeyFromHeader(header)
+
+			// Assert
+			assert.False(t, ok, header)
+		}
+	})
+}
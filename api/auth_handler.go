@@ -0,0 +1,250 @@
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	c "github.com/paquesqueue/bookstore/common"
+)
+
+const (
+	ctxUserKey   = "user"
+	ctxAPIKeyKey = "api_key"
+)
+
+type AuthHandlrQueries interface {
+	Login(ctx context.Context, req RequestLogin) (ResponseSession, error)
+	LoginMFA(ctx context.Context, req RequestLoginMFA) (ResponseSession, error)
+	Logout(ctx context.Context, token string) error
+	Authenticate(ctx context.Context, token string) (ResponseUser, error)
+	AuthenticateAPIKey(ctx context.Context, key string) (ResponseAPIKey, ResponseUser, error)
+	MFAMissing(user ResponseUser) bool
+}
+
+type AuthHandlr struct {
+	handler AuthHandlrQueries
+	log     c.Log
+}
+
+func NewAuthHandlr(h AuthHandlrQueries, l c.Log) AuthHandlr {
+	return AuthHandlr{h, l}
+}
+
+func (h AuthHandlr) Login(ctx echo.Context) error {
+	req := RequestLogin{}
+	err := ctx.Bind(&req)
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+	req.IP = ctx.RealIP()
+
+	res, err := h.handler.Login(ctx.Request().Context(), req)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error Login Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, res)
+}
+
+// LoginMFA finishes a login that needed a second factor.
+func (h AuthHandlr) LoginMFA(ctx echo.Context) error {
+	req := RequestLoginMFA{}
+	err := ctx.Bind(&req)
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+	req.IP = ctx.RealIP()
+
+	res, err := h.handler.LoginMFA(ctx.Request().Context(), req)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error LoginMFA Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h AuthHandlr) Logout(ctx echo.Context) error {
+	err := h.handler.Logout(ctx.Request().Context(), ctx.Request().Header.Get(SessionHeader))
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error Logout Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, "Logged Out Successfully")
+}
+
+// RequireUser rejects requests without a valid session and makes the
+// session's user available through CurrentUser.
+func (h AuthHandlr) RequireUser(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		if _, ok := CurrentUser(ctx); ok {
+			return next(ctx)
+		}
+		user, err := h.handler.Authenticate(ctx.Request().Context(), ctx.Request().Header.Get(SessionHeader))
+		if err != nil {
+			if cmErr, ok := err.(*c.Err); ok {
+				return ctx.NoContent(cmErr.Code)
+			}
+			c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error RequireUser Handler : %v", err)
+			return ctx.NoContent(http.StatusInternalServerError)
+		}
+		setCurrentUser(ctx, user)
+		return next(ctx)
+	}
+}
+
+// APIKeyAuth authenticates requests carrying an API key in the
+// Authorization header as the key's owner, so RequireUser and RequireRole
+// accept them, and rejects those outside the key's scopes. Requests without
+// an API key pass through.
+func (h AuthHandlr) APIKeyAuth(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		key, ok := APIKeyFromHeader(ctx.Request().Header.Get(echo.HeaderAuthorization))
+		if !ok {
+			return next(ctx)
+		}
+		apiKey, user, err := h.handler.AuthenticateAPIKey(ctx.Request().Context(), key)
+		if err != nil {
+			if cmErr, ok := err.(*c.Err); ok {
+				return ctx.NoContent(cmErr.Code)
+			}
+			c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error APIKeyAuth Handler : %v", err)
+			return ctx.NoContent(http.StatusInternalServerError)
+		}
+
+		ctx.Set(ctxAPIKeyKey, apiKey)
+		setCurrentUser(ctx, user)
+		req := ctx.Request()
+		ctx.SetRequest(req.WithContext(c.WithLogFields(req.Context(), c.Fields{"api_key": apiKey.Id})))
+		if !apiKey.AllowsScope(APIKeyScope(req.Method, ctx.Path())) {
+			return ctx.NoContent(http.StatusForbidden)
+		}
+		return next(ctx)
+	}
+}
+
+// CurrentAPIKey returns the key set by APIKeyAuth.
+func CurrentAPIKey(ctx echo.Context) (ResponseAPIKey, bool) {
+	key, ok := ctx.Get(ctxAPIKeyKey).(ResponseAPIKey)
+	return key, ok
+}
+
+// ClientKey identifies who sent a request, for rate limiting: the API key,
+// the user of a valid session, otherwise the IP address. A user found here
+// is kept for RequireUser, so the session is looked up once.
+func (h AuthHandlr) ClientKey(ctx echo.Context) string {
+	if key, ok := CurrentAPIKey(ctx); ok {
+		return "key:" + strconv.FormatUint(key.Id, 10)
+	}
+	if user, ok := CurrentUser(ctx); ok {
+		return "user:" + user.Username
+	}
+	if token := ctx.Request().Header.Get(SessionHeader); token != "" {
+		user, err := h.handler.Authenticate(ctx.Request().Context(), token)
+		if err == nil {
+			setCurrentUser(ctx, user)
+			return "user:" + user.Username
+		}
+	}
+	return "ip:" + ctx.RealIP()
+}
+
+// ClientIPExtractor returns how ctx.RealIP() reads the client address, which
+// lockouts and rate limits count against. X-Forwarded-For and X-Real-IP are
+// whatever the client sends, so without trustedProxies the address is the
+// connection's. With them, X-Forwarded-For is read back to the first
+// address that is not one of those proxies.
+func ClientIPExtractor(trustedProxies []string) echo.IPExtractor {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, proxy := range trustedProxies {
+		if _, ipRange, err := net.ParseCIDR(proxy); err == nil {
+			options = append(options, echo.TrustIPRange(ipRange))
+		}
+	}
+	return echo.ExtractIPFromXFFHeader(options...)
+}
+
+func setCurrentUser(ctx echo.Context, user ResponseUser) {
+	ctx.Set(ctxUserKey, user)
+	req := ctx.Request()
+	ctx.SetRequest(req.WithContext(c.WithLogFields(req.Context(), c.Fields{"user": user.Username})))
+}
+
+// OptionalUser is RequireUser for routes that anonymous visitors may also
+// use; only a session token that is sent must be valid.
+func (h AuthHandlr) OptionalUser(next echo.HandlerFunc) echo.HandlerFunc {
+	withUser := h.RequireUser(next)
+	return func(ctx echo.Context) error {
+		if ctx.Request().Header.Get(SessionHeader) == "" {
+			return next(ctx)
+		}
+		return withUser(ctx)
+	}
+}
+
+// RequireRole is RequireUser limited to users holding one of roles. Users
+// whose role must use two-factor authentication are refused until they
+// turn it on.
+func (h AuthHandlr) RequireRole(roles ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return h.RequireUser(func(ctx echo.Context) error {
+			user, _ := CurrentUser(ctx)
+			for _, role := range roles {
+				if user.Role == role {
+					if h.handler.MFAMissing(user) {
+						return ctx.NoContent(http.StatusForbidden)
+					}
+					return next(ctx)
+				}
+			}
+			return ctx.NoContent(http.StatusForbidden)
+		})
+	}
+}
+
+// CurrentUser returns the user set by RequireUser.
+func CurrentUser(ctx echo.Context) (ResponseUser, bool) {
+	user, ok := ctx.Get(ctxUserKey).(ResponseUser)
+	return user, ok
+}
+
+// sessionOwner returns the username in the path when the session belongs to
+// it. Keys and two-factor settings are managed with a session only, so a
+// key cannot mint another key with wider scopes.
+func sessionOwner(ctx echo.Context) (string, bool) {
+	if _, ok := CurrentAPIKey(ctx); ok {
+		return "", false
+	}
+	user, ok := CurrentUser(ctx)
+	username := ctx.Param("username")
+	return username, ok && user.Username == username
+}
+
+// accountManager returns the username in the path and who is signed in,
+// when that is its owner or an admin, both with a session. Admins, who may
+// turn off a user's two-factor authentication or erase their data, must
+// have two-factor authentication on themselves.
+func accountManager(ctx echo.Context) (string, string, bool) {
+	if username, ok := sessionOwner(ctx); ok {
+		return username, username, true
+	}
+	if _, ok := CurrentAPIKey(ctx); ok {
+		return "", "", false
+	}
+	user, ok := CurrentUser(ctx)
+	return ctx.Param("username"), user.Username, ok && user.Role == UserRoleAdmin && user.MFAEnabledAt != nil
+}
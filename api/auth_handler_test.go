@@ -0,0 +1,376 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type AuthHandlrStub struct {
+	tokens   map[string]ResponseUser
+	apiKeys  map[string]ResponseAPIKey
+	mfaRoles []string
+	mfaLogin RequestLoginMFA
+	loginIPs []string
+}
+
+func (h *AuthHandlrStub) Login(ctx context.Context, req RequestLogin) (ResponseSession, error) {
+	h.loginIPs = append(h.loginIPs, req.IP)
+	return ResponseSession{Token: "token", Username: req.Username}, nil
+}
+
+func (h *AuthHandlrStub) LoginMFA(ctx context.Context, req RequestLoginMFA) (ResponseSession, error) {
+	h.mfaLogin = req
+	if req.Code != "123456" {
+		return ResponseSession{}, &c.Err{Code: http.StatusUnauthorized}
+	}
+	return ResponseSession{Token: "token", Username: "mod"}, nil
+}
+
+func (h *AuthHandlrStub) MFAMissing(user ResponseUser) bool {
+	return user.MFAEnabledAt == nil && mfaRequired(c.AuthConfig{MFARequiredRoles: h.mfaRoles}, user.Role)
+}
+
+func (h *AuthHandlrStub) Logout(ctx context.Context, token string) error {
+	delete(h.tokens, token)
+	return nil
+}
+
+func (h *AuthHandlrStub) Authenticate(ctx context.Context, token string) (ResponseUser, error) {
+	user, ok := h.tokens[token]
+	if !ok {
+		return ResponseUser{}, &c.Err{Code: http.StatusUnauthorized}
+	}
+	return user, nil
+}
+
+func (h *AuthHandlrStub) AuthenticateAPIKey(ctx context.Context, key string) (ResponseAPIKey, ResponseUser, error) {
+	apiKey, ok := h.apiKeys[key]
+	if !ok {
+		return ResponseAPIKey{}, ResponseUser{}, &c.Err{Code: http.StatusUnauthorized}
+	}
+	return apiKey, ResponseUser{Username: apiKey.Username, Role: UserRoleCustomer}, nil
+}
+
+func newAuthHandlrStub() *AuthHandlrStub {
+	return &AuthHandlrStub{tokens: map[string]ResponseUser{
+		"customer-token": {Username: "reader", Role: UserRoleCustomer},
+		"staff-token":    {Username: "mod", Role: UserRoleStaff},
+		"mfa-token":      {Username: "admin", Role: UserRoleAdmin, MFAEnabledAt: &time.Time{}},
+	}, apiKeys: map[string]ResponseAPIKey{
+		"bk_reader": {Id: 9, Username: "reader", Scopes: []string{"books:read", "lists:write"}},
+	}}
+}
+
+func serveWithSession(mw echo.MiddlewareFunc, token string) (*httptest.ResponseRecorder, string) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if token != "" {
+		req.Header.Set(SessionHeader, token)
+	}
+	rec := httptest.NewRecorder()
+	ctx := echo.New().NewContext(req, rec)
+
+	var username string
+	mw(func(ctx echo.Context) error {
+		user, _ := CurrentUser(ctx)
+		username = user.Username
+		return ctx.NoContent(http.StatusOK)
+	})(ctx)
+	return rec, username
+}
+
+func TestRequireUser(t *testing.T) {
+	handler := NewAuthHandlr(newAuthHandlrStub(), logrus.New())
+
+	t.Run("TestRequireUserShouldSetCurrentUser", func(t *testing.T) {
+		// Act
+		rec, username := serveWithSession(handler.RequireUser, "customer-token")
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "reader", username)
+	})
+
+	t.Run("TestRequireUserShouldAddUserToLogFields", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(SessionHeader, "customer-token")
+		ctx := echo.New().NewContext(req, httptest.NewRecorder())
+
+		// Act
+		var fields c.Fields
+		err := handler.RequireUser(func(ctx echo.Context) error {
+			fields = c.LogFields(ctx.Request().Context())
+			return nil
+		})(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "reader", fields["user"])
+	})
+
+	t.Run("TestRequireUserShouldReturnHTTPStatus401WithoutSession", func(t *testing.T) {
+		// Act
+		rec, username := serveWithSession(handler.RequireUser, "")
+
+		// Assert
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Empty(t, username)
+	})
+}
+
+func TestRequireRole(t *testing.T) {
+	handler := NewAuthHandlr(newAuthHandlrStub(), logrus.New())
+	staffOnly := handler.RequireRole(UserRoleStaff, UserRoleAdmin)
+
+	t.Run("TestRequireRoleShouldAllowStaff", func(t *testing.T) {
+		// Act
+		rec, username := serveWithSession(staffOnly, "staff-token")
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "mod", username)
+	})
+
+	t.Run("TestRequireRoleShouldReturnHTTPStatus403ForCustomer", func(t *testing.T) {
+		// Act
+		rec, _ := serveWithSession(staffOnly, "customer-token")
+
+		// Assert
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("TestRequireRoleShouldReturnHTTPStatus401WithoutSession", func(t *testing.T) {
+		// Act
+		rec, _ := serveWithSession(staffOnly, "unknown-token")
+
+		// Assert
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("TestRequireRoleShouldReturnHTTPStatus403UntilMFAIsOn", func(t *testing.T) {
+		// Arrange
+		stub := newAuthHandlrStub()
+		stub.mfaRoles = []string{UserRoleStaff, UserRoleAdmin}
+		staffOnly := NewAuthHandlr(stub, logrus.New()).RequireRole(UserRoleStaff, UserRoleAdmin)
+
+		// Act
+		withoutMFA, _ := serveWithSession(staffOnly, "staff-token")
+		withMFA, username := serveWithSession(staffOnly, "mfa-token")
+
+		// Assert
+		assert.Equal(t, http.StatusForbidden, withoutMFA.Code)
+		assert.Equal(t, http.StatusOK, withMFA.Code)
+		assert.Equal(t, "admin", username)
+	})
+}
+
+func TestLoginHandler(t *testing.T) {
+	t.Run("TestLoginHandlerShouldCountSpoofedForwardedForAgainstPeer", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		e.IPExtractor = ClientIPExtractor(nil)
+		stub := newAuthHandlrStub()
+		handler := NewAuthHandlr(stub, logrus.New())
+
+		// Act
+		for _, spoofed := range []string{"198.51.100.1", "198.51.100.2"} {
+			req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"username":"reader","password":"wrong"}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(echo.HeaderXForwardedFor, spoofed)
+			req.RemoteAddr = "203.0.113.7:51234"
+			err := handler.Login(e.NewContext(req, httptest.NewRecorder()))
+			assert.NoError(t, err)
+		}
+
+		// Assert
+		assert.Equal(t, []string{"203.0.113.7", "203.0.113.7"}, stub.loginIPs)
+	})
+}
+
+func TestLoginMFAHandler(t *testing.T) {
+	t.Run("TestLoginMFAHandlerShouldPassCodeAndIP", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPost, "/auth/login/mfa", strings.NewReader(`{"mfa_token":"challenge","code":"123456"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.RemoteAddr = "10.0.0.1:1234"
+		rec := httptest.NewRecorder()
+		ctx := echo.New().NewContext(req, rec)
+		stub := newAuthHandlrStub()
+		handler := NewAuthHandlr(stub, logrus.New())
+
+		// Act
+		err := handler.LoginMFA(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, RequestLoginMFA{Token: "challenge", RequestMFACode: RequestMFACode{Code: "123456", IP: "10.0.0.1"}}, stub.mfaLogin)
+		}
+	})
+
+	t.Run("TestLoginMFAHandlerShouldReturnHTTPStatus401ForWrongCode", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPost, "/auth/login/mfa", strings.NewReader(`{"mfa_token":"challenge","code":"000000"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		ctx := echo.New().NewContext(req, rec)
+		handler := NewAuthHandlr(newAuthHandlrStub(), logrus.New())
+
+		// Act
+		err := handler.LoginMFA(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		}
+	})
+}
+
+func serveWithAPIKey(handler AuthHandlr, method string, route string, authorization string) (*httptest.ResponseRecorder, echo.Context) {
+	req := httptest.NewRequest(method, "/", nil)
+	req.Header.Set(echo.HeaderAuthorization, authorization)
+	rec := httptest.NewRecorder()
+	ctx := echo.New().NewContext(req, rec)
+	ctx.SetPath(route)
+
+	handler.APIKeyAuth(func(ctx echo.Context) error {
+		return ctx.NoContent(http.StatusOK)
+	})(ctx)
+	return rec, ctx
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	handler := NewAuthHandlr(newAuthHandlrStub(), logrus.New())
+
+	t.Run("TestAPIKeyAuthShouldActAsKeyOwner", func(t *testing.T) {
+		// Act
+		rec, ctx := serveWithAPIKey(handler, http.MethodPost, "/users/:username/lists", "Bearer bk_reader")
+
+		// Assert
+		user, _ := CurrentUser(ctx)
+		key, _ := CurrentAPIKey(ctx)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "reader", user.Username)
+		assert.Equal(t, uint64(9), key.Id)
+		assert.Equal(t, uint64(9), c.LogFields(ctx.Request().Context())["api_key"])
+	})
+
+	t.Run("TestAPIKeyAuthShouldReturnHTTPStatus403OutsideScopes", func(t *testing.T) {
+		// Act
+		rec, _ := serveWithAPIKey(handler, http.MethodPut, "/books/:id", "bk_reader")
+
+		// Assert
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("TestAPIKeyAuthShouldReturnHTTPStatus401ForUnknownKey", func(t *testing.T) {
+		// Act
+		rec, ctx := serveWithAPIKey(handler, http.MethodGet, "/books", "bk_revoked")
+
+		// Assert
+		_, ok := CurrentUser(ctx)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.False(t, ok)
+	})
+
+	t.Run("TestAPIKeyAuthShouldIgnoreOtherCredentials", func(t *testing.T) {
+		// Act
+		rec, ctx := serveWithAPIKey(handler, http.MethodPut, "/books/:id", "token")
+
+		// Assert
+		_, ok := CurrentUser(ctx)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.False(t, ok)
+	})
+}
+
+func TestClientKey(t *testing.T) {
+	handler := NewAuthHandlr(newAuthHandlrStub(), logrus.New())
+
+	t.Run("TestClientKeyShouldUseSessionUser", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(SessionHeader, "customer-token")
+		ctx := echo.New().NewContext(req, httptest.NewRecorder())
+
+		// Act
+		key := handler.ClientKey(ctx)
+
+		// Assert
+		user, ok := CurrentUser(ctx)
+		assert.Equal(t, "user:reader", key)
+		assert.True(t, ok)
+		assert.Equal(t, "reader", user.Username)
+	})
+
+	t.Run("TestClientKeyShouldPreferAPIKey", func(t *testing.T) {
+		// Arrange
+		_, ctx := serveWithAPIKey(handler, http.MethodGet, "/books", "bk_reader")
+
+		// Act
+		key := handler.ClientKey(ctx)
+
+		// Assert
+		assert.Equal(t, "key:9", key)
+	})
+
+	t.Run("TestClientKeyShouldFallBackToIPAddress", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(SessionHeader, "unknown-token")
+		req.RemoteAddr = "203.0.113.7:51234"
+		ctx := echo.New().NewContext(req, httptest.NewRecorder())
+
+		// Act
+		key := handler.ClientKey(ctx)
+
+		// Assert
+		_, ok := CurrentUser(ctx)
+		assert.Equal(t, "ip:203.0.113.7", key)
+		assert.False(t, ok)
+	})
+
+	t.Run("TestClientKeyShouldIgnoreSpoofedForwardingHeaders", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		e.IPExtractor = ClientIPExtractor(nil)
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(echo.HeaderXForwardedFor, "198.51.100.1")
+		req.Header.Set(echo.HeaderXRealIP, "198.51.100.2")
+		req.RemoteAddr = "203.0.113.7:51234"
+		ctx := e.NewContext(req, httptest.NewRecorder())
+
+		// Act
+		key := handler.ClientKey(ctx)
+
+		// Assert
+		assert.Equal(t, "ip:203.0.113.7", key)
+	})
+
+	t.Run("TestClientKeyShouldReadForwardedForFromTrustedProxy", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		e.IPExtractor = ClientIPExtractor([]string{"10.0.0.0/8"})
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(echo.HeaderXForwardedFor, "192.0.2.66, 198.51.100.1")
+		req.RemoteAddr = "10.0.0.5:51234"
+		ctx := e.NewContext(req, httptest.NewRecorder())
+
+		// Act
+		key := handler.ClientKey(ctx)
+
+		// Assert
+		assert.Equal(t, "ip:198.51.100.1", key)
+	})
+}
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/paquesqueue/bookstore/tracing"
+	"github.com/paquesqueue/bookstore/utils"
+)
+
+const (
+	// SessionHeader carries the session token. Authorization is taken by the
+	// service access token checked for every request.
+	SessionHeader = "X-Session-Token"
+
+	sessionTTL = 24 * time.Hour
+)
+
+type AuthQueries interface {
+	InsertSession(ctx context.Context, tokenHash string, username string, expiresAt time.Time) error
+	SelectSessionUser(ctx context.Context, tokenHash string) (ResponseUser, error)
+	DeleteSession(ctx context.Context, tokenHash string) error
+	SelectAPIKeyUser(ctx context.Context, keyHash string) (ResponseAPIKey, ResponseUser, error)
+	TouchAPIKey(ctx context.Context, id uint64) error
+	InsertUserToken(ctx context.Context, tokenHash string, username string, purpose string, email string, expiresAt time.Time) error
+	SelectUserToken(ctx context.Context, tokenHash string, purpose string) (ResponseUser, error)
+	UseMFAChallenge(ctx context.Context, tokenHash string) (string, error)
+}
+
+// CredentialVerifier checks a login's username and password, returning
+// the user they belong to, and then the second factor of users with
+// two-factor authentication on.
+type CredentialVerifier interface {
+	VerifyCredentials(ctx context.Context, req RequestLogin) (ResponseUser, error)
+	VerifySecondFactor(ctx context.Context, user ResponseUser, req RequestLoginMFA) error
+}
+
+type AuthServices struct {
+	query    AuthQueries
+	verifier CredentialVerifier
+	conf     c.AuthConfig
+	log      c.Log
+	now      func() time.Time
+}
+
+func NewAuthService(q AuthQueries, v CredentialVerifier, conf c.AuthConfig, l c.Log) AuthServices {
+	return AuthServices{q, v, conf, l, time.Now}
+}
+
+// Login checks a user's password and opens a session. Only a hash of the
+// returned token is stored. Users with two-factor authentication on get an
+// MFA token instead, to finish the login with LoginMFA.
+func (s AuthServices) Login(ctx context.Context, req RequestLogin) (ResponseSession, error) {
+	ctx, span := tracing.Start(ctx, "AuthServices.Login")
+	defer span.End()
+
+	user, err := s.verifier.VerifyCredentials(ctx, req)
+	if err != nil {
+		return ResponseSession{}, err
+	}
+	if user.MFAEnabledAt == nil {
+		return s.openSession(ctx, user, "Error Login Service")
+	}
+
+	token, err := utils.NewToken()
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error Login New Token : %v", err)
+		return ResponseSession{}, &c.Err{Code: http.StatusInternalServerError, Remark: "Error Login Service", Original: err}
+	}
+	expiresAt := s.now().Add(s.conf.MFAChallengeTTL)
+	if err := s.query.InsertUserToken(ctx, utils.HashToken(token), user.Username, tokenPurposeMFALogin, user.Email, expiresAt); err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error InsertUserToken : %v", err)
+		return ResponseSession{}, &c.Err{Code: http.StatusInternalServerError, Remark: "Error Login Service", Original: err}
+	}
+	return ResponseSession{MFARequired: true, MFAToken: token, Username: user.Username, Role: user.Role, ExpiresAt: expiresAt}, nil
+}
+
+// LoginMFA finishes a login with the MFA token Login returned and a code
+// from the user's authenticator or a recovery code, and opens a session.
+// A wrong code leaves the MFA token usable until it expires.
+func (s AuthServices) LoginMFA(ctx context.Context, req RequestLoginMFA) (ResponseSession, error) {
+	ctx, span := tracing.Start(ctx, "AuthServices.LoginMFA")
+	defer span.End()
+
+	if req.Token == "" {
+		return ResponseSession{}, &c.Err{Code: http.StatusBadRequest, Remark: "Error MFA Token Required"}
+	}
+	tokenHash := utils.HashToken(req.Token)
+	user, err := s.query.SelectUserToken(ctx, tokenHash, tokenPurposeMFALogin)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ResponseSession{}, &c.Err{Code: http.StatusUnauthorized, Remark: "Error Invalid Or Expired MFA Token", Original: err}
+		}
+		c.LogFrom(ctx, s.log).Errorf("Error SelectUserToken : %v", err)
+		return ResponseSession{}, &c.Err{Code: http.StatusInternalServerError, Remark: "Error LoginMFA Service", Original: err}
+	}
+	if err := s.verifier.VerifySecondFactor(ctx, user, req); err != nil {
+		return ResponseSession{}, err
+	}
+	if _, err := s.query.UseMFAChallenge(ctx, tokenHash); err != nil {
+		if err == sql.ErrNoRows {
+			return ResponseSession{}, &c.Err{Code: http.StatusUnauthorized, Remark: "Error Invalid Or Expired MFA Token", Original: err}
+		}
+		c.LogFrom(ctx, s.log).Errorf("Error UseMFAChallenge : %v", err)
+		return ResponseSession{}, &c.Err{Code: http.StatusInternalServerError, Remark: "Error LoginMFA Service", Original: err}
+	}
+	return s.openSession(ctx, user, "Error LoginMFA Service")
+}
+
+// MFAMissing reports whether user's role must use two-factor
+// authentication but the user has not turned it on.
+func (s AuthServices) MFAMissing(user ResponseUser) bool {
+	return user.MFAEnabledAt == nil && mfaRequired(s.conf, user.Role)
+}
+
+func (s AuthServices) openSession(ctx context.Context, user ResponseUser, remark string) (ResponseSession, error) {
+	token, err := utils.NewToken()
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error Login New Token : %v", err)
+		return ResponseSession{}, &c.Err{Code: http.StatusInternalServerError, Remark: remark, Original: err}
+	}
+	expiresAt := s.now().Add(sessionTTL)
+	if err := s.query.InsertSession(ctx, utils.HashToken(token), user.Username, expiresAt); err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error InsertSession : %v", err)
+		return ResponseSession{}, &c.Err{Code: http.StatusInternalServerError, Remark: remark, Original: err}
+	}
+	return ResponseSession{Token: token, Username: user.Username, Role: user.Role, ExpiresAt: expiresAt}, nil
+}
+
+func (s AuthServices) Logout(ctx context.Context, token string) error {
+	ctx, span := tracing.Start(ctx, "AuthServices.Logout")
+	defer span.End()
+
+	if err := s.query.DeleteSession(ctx, utils.HashToken(token)); err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error DeleteSession : %v", err)
+		return &c.Err{Code: http.StatusInternalServerError, Remark: "Error Logout Service", Original: err}
+	}
+	return nil
+}
+
+// Authenticate resolves a session token to its user.
+func (s AuthServices) Authenticate(ctx context.Context, token string) (ResponseUser, error) {
+	ctx, span := tracing.Start(ctx, "AuthServices.Authenticate")
+	defer span.End()
+
+	if token == "" {
+		return ResponseUser{}, &c.Err{Code: http.StatusUnauthorized, Remark: "Error Session Required"}
+	}
+	user, err := s.query.SelectSessionUser(ctx, utils.HashToken(token))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ResponseUser{}, &c.Err{Code: http.StatusUnauthorized, Remark: "Error Session Expired", Original: err}
+		}
+		c.LogFrom(ctx, s.log).Errorf("Error SelectSessionUser : %v", err)
+		return ResponseUser{}, &c.Err{Code: http.StatusInternalServerError, Remark: "Error Authenticate Service", Original: err}
+	}
+	return user, nil
+}
+
+// AuthenticateAPIKey resolves an API key to the key and its owner and
+// records that the key was used.
+func (s AuthServices) AuthenticateAPIKey(ctx context.Context, key string) (ResponseAPIKey, ResponseUser, error) {
+	ctx, span := tracing.Start(ctx, "AuthServices.AuthenticateAPIKey")
+	defer span.End()
+
+	apiKey, user, err := s.query.SelectAPIKeyUser(ctx, utils.HashToken(key))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ResponseAPIKey{}, ResponseUser{}, &c.Err{Code: http.StatusUnauthorized, Remark: "Error Invalid API Key", Original: err}
+		}
+		c.LogFrom(ctx, s.log).Errorf("Error SelectAPIKeyUser : %v", err)
+		return ResponseAPIKey{}, ResponseUser{}, &c.Err{Code: http.StatusInternalServerError, Remark: "Error AuthenticateAPIKey Service", Original: err}
+	}
+	// A missed update only makes last_used_at stale, so the request goes on.
+	if err := s.query.TouchAPIKey(ctx, apiKey.Id); err != nil {
+		c.LogFrom(ctx, s.log).Warnf("Error TouchAPIKey : %v", err)
+	}
+	return apiKey, user, nil
+}
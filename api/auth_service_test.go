@@ -0,0 +1,335 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"testing"
+	"time"
+
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/paquesqueue/bookstore/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type AuthQueriesStub struct {
+	users    map[string]ResponseUser
+	sessions map[string]string
+	apiKeys  map[string]ResponseAPIKey
+	touched  []uint64
+	touchErr error
+	// challenges are unused MFA tokens by hash.
+	challenges map[string]string
+}
+
+func newAuthQueriesStub(t *testing.T) *AuthQueriesStub {
+	hashed, err := utils.HashPassword("123456")
+	assert.NoError(t, err)
+	return &AuthQueriesStub{
+		users:      map[string]ResponseUser{"tester": {Username: "tester", HashedPassword: hashed, Role: UserRoleStaff}},
+		sessions:   map[string]string{},
+		challenges: map[string]string{},
+		apiKeys: map[string]ResponseAPIKey{
+			utils.HashToken("bk_valid"): {Id: 4, Username: "tester", Scopes: []string{"books:read"}},
+		},
+	}
+}
+
+func (q *AuthQueriesStub) InsertSession(ctx context.Context, tokenHash string, username string, expiresAt time.Time) error {
+	q.sessions[tokenHash] = username
+	return nil
+}
+
+func (q *AuthQueriesStub) SelectSessionUser(ctx context.Context, tokenHash string) (ResponseUser, error) {
+	username, ok := q.sessions[tokenHash]
+	if !ok {
+		return ResponseUser{}, sql.ErrNoRows
+	}
+	return q.users[username], nil
+}
+
+func (q *AuthQueriesStub) DeleteSession(ctx context.Context, tokenHash string) error {
+	delete(q.sessions, tokenHash)
+	return nil
+}
+
+func (q *AuthQueriesStub) SelectAPIKeyUser(ctx context.Context, keyHash string) (ResponseAPIKey, ResponseUser, error) {
+	key, ok := q.apiKeys[keyHash]
+	if !ok {
+		return ResponseAPIKey{}, ResponseUser{}, sql.ErrNoRows
+	}
+	return key, q.users[key.Username], nil
+}
+
+func (q *AuthQueriesStub) TouchAPIKey(ctx context.Context, id uint64) error {
+	q.touched = append(q.touched, id)
+	return q.touchErr
+}
+
+func (q *AuthQueriesStub) InsertUserToken(ctx context.Context, tokenHash string, username string, purpose string, email string, expiresAt time.Time) error {
+	q.challenges[tokenHash] = username
+	return nil
+}
+
+func (q *AuthQueriesStub) SelectUserToken(ctx context.Context, tokenHash string, purpose string) (ResponseUser, error) {
+	username, ok := q.challenges[tokenHash]
+	if !ok {
+		return ResponseUser{}, sql.ErrNoRows
+	}
+	return q.users[username], nil
+}
+
+func (q *AuthQueriesStub) UseMFAChallenge(ctx context.Context, tokenHash string) (string, error) {
+	username, ok := q.challenges[tokenHash]
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+	delete(q.challenges, tokenHash)
+	return username, nil
+}
+
+// CredentialVerifierStub accepts the passwords of the users in query.
+type CredentialVerifierStub struct {
+	query *AuthQueriesStub
+	err   error
+}
+
+func (v CredentialVerifierStub) VerifyCredentials(ctx context.Context, req RequestLogin) (ResponseUser, error) {
+	if v.err != nil {
+		return ResponseUser{}, v.err
+	}
+	user, ok := v.query.users[req.Username]
+	if !ok || utils.CheckPassword(user.HashedPassword, req.Password) != nil {
+		return ResponseUser{}, &c.Err{Code: http.StatusUnauthorized, Remark: "Error Invalid Credentials"}
+	}
+	return user, nil
+}
+
+func (v CredentialVerifierStub) VerifySecondFactor(ctx context.Context, user ResponseUser, req RequestLoginMFA) error {
+	if req.Code != "123456" {
+		return &c.Err{Code: http.StatusUnauthorized, Remark: "Error Invalid Code"}
+	}
+	return nil
+}
+
+var authConf = c.AuthConfig{MFARequiredRoles: []string{UserRoleStaff}, MFAChallengeTTL: 5 * time.Minute}
+
+func newAuthServices(query *AuthQueriesStub) AuthServices {
+	return NewAuthService(query, CredentialVerifierStub{query: query}, authConf, logrus.New())
+}
+
+func TestLogin(t *testing.T) {
+	t.Run("TestLoginShouldOpenSessionUsableForAuthenticate", func(t *testing.T) {
+		// Arrange
+		query := newAuthQueriesStub(t)
+		services := newAuthServices(query)
+
+		// Act
+		session, err := services.Login(context.Background(), RequestLogin{Username: "tester", Password: "123456"})
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.NotEmpty(t, session.Token)
+			assert.Equal(t, UserRoleStaff, session.Role)
+			assert.NotContains(t, query.sessions, session.Token)
+
+			user, err := services.Authenticate(context.Background(), session.Token)
+			assert.NoError(t, err)
+			assert.Equal(t, "tester", user.Username)
+		}
+	})
+
+	t.Run("TestLoginShouldReturnHTTPStatus401ForWrongPassword", func(t *testing.T) {
+		// Arrange
+		services := newAuthServices(newAuthQueriesStub(t))
+
+		// Act
+		_, err := services.Login(context.Background(), RequestLogin{Username: "tester", Password: "654321"})
+
+		// Assert
+		if assert.Error(t, err) {
+			assert.Equal(t, http.StatusUnauthorized, err.(*c.Err).Code)
+		}
+	})
+
+	t.Run("TestLoginShouldReturnHTTPStatus401ForUnknownUser", func(t *testing.T) {
+		// Arrange
+		services := newAuthServices(newAuthQueriesStub(t))
+
+		// Act
+		_, err := services.Login(context.Background(), RequestLogin{Username: "nobody", Password: "123456"})
+
+		// Assert
+		if assert.Error(t, err) {
+			assert.Equal(t, http.StatusUnauthorized, err.(*c.Err).Code)
+		}
+	})
+
+	t.Run("TestLoginShouldReturnLockoutWithoutSession", func(t *testing.T) {
+		// Arrange
+		query := newAuthQueriesStub(t)
+		services := NewAuthService(query, CredentialVerifierStub{query: query, err: &c.Err{Code: http.StatusTooManyRequests}}, authConf, logrus.New())
+
+		// Act
+		_, err := services.Login(context.Background(), RequestLogin{Username: "tester", Password: "123456"})
+
+		// Assert
+		if assert.Error(t, err) {
+			assert.Equal(t, http.StatusTooManyRequests, err.(*c.Err).Code)
+		}
+		assert.Empty(t, query.sessions)
+	})
+}
+
+func TestLoginMFA(t *testing.T) {
+	mfaUser := func(t *testing.T) *AuthQueriesStub {
+		query := newAuthQueriesStub(t)
+		user := query.users["tester"]
+		user.MFAEnabledAt = &time.Time{}
+		query.users["tester"] = user
+		return query
+	}
+
+	t.Run("TestLoginShouldAskForSecondFactor", func(t *testing.T) {
+		// Arrange
+		query := mfaUser(t)
+		services := newAuthServices(query)
+
+		// Act
+		session, err := services.Login(context.Background(), RequestLogin{Username: "tester", Password: "123456"})
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.True(t, session.MFARequired)
+			assert.NotEmpty(t, session.MFAToken)
+			assert.Empty(t, session.Token)
+			assert.Empty(t, query.sessions)
+			assert.Contains(t, query.challenges, utils.HashToken(session.MFAToken))
+		}
+	})
+
+	t.Run("TestLoginMFAShouldOpenSessionOnce", func(t *testing.T) {
+		// Arrange
+		query := mfaUser(t)
+		services := newAuthServices(query)
+		challenge, err := services.Login(context.Background(), RequestLogin{Username: "tester", Password: "123456"})
+		assert.NoError(t, err)
+		req := RequestLoginMFA{Token: challenge.MFAToken, RequestMFACode: RequestMFACode{Code: "123456"}}
+
+		// Act
+		session, err := services.LoginMFA(context.Background(), req)
+		_, again := services.LoginMFA(context.Background(), req)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.NotEmpty(t, session.Token)
+			assert.Equal(t, "tester", query.sessions[utils.HashToken(session.Token)])
+		}
+		if assert.Error(t, again) {
+			assert.Equal(t, http.StatusUnauthorized, again.(*c.Err).Code)
+		}
+	})
+
+	t.Run("TestLoginMFAShouldKeepTokenAfterWrongCode", func(t *testing.T) {
+		// Arrange
+		query := mfaUser(t)
+		services := newAuthServices(query)
+		challenge, err := services.Login(context.Background(), RequestLogin{Username: "tester", Password: "123456"})
+		assert.NoError(t, err)
+
+		// Act
+		_, err = services.LoginMFA(context.Background(), RequestLoginMFA{Token: challenge.MFAToken, RequestMFACode: RequestMFACode{Code: "000000"}})
+
+		// Assert
+		if assert.Error(t, err) {
+			assert.Equal(t, http.StatusUnauthorized, err.(*c.Err).Code)
+		}
+		assert.Len(t, query.challenges, 1)
+		assert.Empty(t, query.sessions)
+	})
+}
+
+func TestMFAMissing(t *testing.T) {
+	t.Run("TestMFAMissingShouldOnlyFlagRequiredRolesWithoutMFA", func(t *testing.T) {
+		// Arrange
+		services := newAuthServices(newAuthQueriesStub(t))
+
+		// Act
+		staff := services.MFAMissing(ResponseUser{Role: UserRoleStaff})
+		staffWithMFA := services.MFAMissing(ResponseUser{Role: UserRoleStaff, MFAEnabledAt: &time.Time{}})
+		customer := services.MFAMissing(ResponseUser{Role: UserRoleCustomer})
+
+		// Assert
+		assert.True(t, staff)
+		assert.False(t, staffWithMFA)
+		assert.False(t, customer)
+	})
+}
+
+func TestLogout(t *testing.T) {
+	t.Run("TestLogoutShouldEndSession", func(t *testing.T) {
+		// Arrange
+		services := newAuthServices(newAuthQueriesStub(t))
+		session, err := services.Login(context.Background(), RequestLogin{Username: "tester", Password: "123456"})
+		assert.NoError(t, err)
+
+		// Act
+		err = services.Logout(context.Background(), session.Token)
+
+		// Assert
+		assert.NoError(t, err)
+		_, err = services.Authenticate(context.Background(), session.Token)
+		if assert.Error(t, err) {
+			assert.Equal(t, http.StatusUnauthorized, err.(*c.Err).Code)
+		}
+	})
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	t.Run("TestAuthenticateAPIKeyShouldReturnKeyAndOwner", func(t *testing.T) {
+		// Arrange
+		query := newAuthQueriesStub(t)
+		services := newAuthServices(query)
+
+		// Act
+		key, user, err := services.AuthenticateAPIKey(context.Background(), "bk_valid")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(4), key.Id)
+		assert.Equal(t, "tester", user.Username)
+		assert.Equal(t, []uint64{4}, query.touched)
+	})
+
+	t.Run("TestAuthenticateAPIKeyShouldIgnoreFailedTouch", func(t *testing.T) {
+		// Arrange
+		query := newAuthQueriesStub(t)
+		query.touchErr = sql.ErrConnDone
+		services := newAuthServices(query)
+
+		// Act
+		_, user, err := services.AuthenticateAPIKey(context.Background(), "bk_valid")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "tester", user.Username)
+	})
+
+	t.Run("TestAuthenticateAPIKeyShouldReturnHTTPStatus401ForUnknownKey", func(t *testing.T) {
+		// Arrange
+		query := newAuthQueriesStub(t)
+		services := newAuthServices(query)
+
+		// Act
+		_, _, err := services.AuthenticateAPIKey(context.Background(), "bk_revoked")
+
+		// Assert
+		if assert.Error(t, err) {
+			assert.Equal(t, http.StatusUnauthorized, err.(*c.Err).Code)
+		}
+		assert.Empty(t, query.touched)
+	})
+}
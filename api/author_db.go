@@ -0,0 +1,267 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"unicode"
+
+	"github.com/lib/pq"
+)
+
+const RoleAuthor = "author"
+
+// nameKey normalises a person or organisation name for de-duplication so that
+// "J.K. Rowling" and "j. k. rowling" resolve to the same entity.
+func nameKey(name string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+func (db Query) InsertAuthor(ctx context.Context, req RequestAuthor) (ResponseAuthor, error) {
+	const query = `INSERT INTO authors
+	(name, name_key)
+	VALUES ($1, $2)
+	RETURNING id, name, created_at;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return ResponseAuthor{}, err
+	}
+	defer stmt.Close()
+
+	row := stmt.QueryRowContext(ctx, strings.TrimSpace(req.Name), nameKey(req.Name))
+	resp := ResponseAuthor{}
+	err = row.Scan(&resp.Id, &resp.Name, &resp.CreatedAt)
+	if err != nil {
+		return ResponseAuthor{}, err
+	}
+	return resp, nil
+}
+
+func (db Query) SelectAllAuthors(ctx context.Context, params GetAllParams) ([]ResponseAuthor, error) {
+	const query = `SELECT id, name, created_at
+	FROM authors
+	ORDER BY name, id
+	LIMIT $1
+	OFFSET $2;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, params.Limit, params.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resp := []ResponseAuthor{}
+	for rows.Next() {
+		result := ResponseAuthor{}
+		err = rows.Scan(&result.Id, &result.Name, &result.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		resp = append(resp, result)
+	}
+	return resp, rows.Err()
+}
+
+func (db Query) SelectAuthor(ctx context.Context, id uint64) (ResponseAuthor, error) {
+	const query = `SELECT id, name, created_at FROM authors WHERE id = $1;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return ResponseAuthor{}, err
+	}
+	defer stmt.Close()
+
+	resp := ResponseAuthor{}
+	err = stmt.QueryRowContext(ctx, id).Scan(&resp.Id, &resp.Name, &resp.CreatedAt)
+	if err != nil {
+		return ResponseAuthor{}, err
+	}
+	return resp, nil
+}
+
+// UpdateAuthor renames an author and re-renders the authors array of every
+// book it is linked to, so ResponseBook keeps showing the canonical name.
+// The works of those books take the new array too, or the next edit of an
+// edition would copy the old name back through syncWorkEditions.
+func (db Query) UpdateAuthor(ctx context.Context, id uint64, req RequestAuthor) (ResponseAuthor, error) {
+	const query = `UPDATE authors
+	SET name = $1, name_key = $2
+	WHERE id = $3
+	RETURNING id, name, created_at;`
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return ResponseAuthor{}, err
+	}
+	defer tx.Rollback()
+
+	resp := ResponseAuthor{}
+	err = tx.QueryRow(query, strings.TrimSpace(req.Name), nameKey(req.Name), id).Scan(&resp.Id, &resp.Name, &resp.CreatedAt)
+	if err != nil {
+		return ResponseAuthor{}, err
+	}
+
+	_, err = tx.Exec(`UPDATE books b
+	SET authors = ARRAY(SELECT a.name FROM book_authors ba JOIN authors a ON a.id = ba.author_id WHERE ba.book_id = b.id AND ba.role = $2 ORDER BY ba.position)
+	WHERE b.id IN (SELECT book_id FROM book_authors WHERE author_id = $1 AND role = $2);`, id, RoleAuthor)
+	if err != nil {
+		return ResponseAuthor{}, err
+	}
+	_, err = tx.Exec(`UPDATE works w
+	SET authors = b.authors
+	FROM books b
+	WHERE b.work_id = w.id AND b.id IN (SELECT book_id FROM book_authors WHERE author_id = $1 AND role = $2);`, id, RoleAuthor)
+	if err != nil {
+		return ResponseAuthor{}, err
+	}
+	return resp, tx.Commit()
+}
+
+func (db Query) DeleteAuthor(ctx context.Context, id uint64) error {
+	const query = `DELETE FROM authors WHERE id = $1;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (db Query) SelectBooksByAuthor(ctx context.Context, id uint64, params GetAllParams) ([]ResponseAuthorBook, error) {
+	const query = `SELECT b.id, b.title, b.authors, b.publisher, b.isbn, b.price, b.quantity, b.created_by, b.created_at, ba.role, ba.position
+	FROM book_authors ba
+	JOIN books b ON b.id = ba.book_id
+	WHERE ba.author_id = $1
+	ORDER BY b.id, ba.position
+	LIMIT $2
+	OFFSET $3;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, id, params.Limit, params.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resp := []ResponseAuthorBook{}
+	for rows.Next() {
+		r := ResponseAuthorBook{}
+		err = rows.Scan(&r.Id, &r.Title, pq.Array(&r.Authors), &r.Publisher, &r.Isbn, &r.Price, &r.Quantity, &r.Created_by, &r.Created_at, &r.Role, &r.Position)
+		if err != nil {
+			return nil, err
+		}
+		resp = append(resp, r)
+	}
+	return resp, rows.Err()
+}
+
+// linkBookContributors resolves the author and publisher names of a book to
+// their normalised entities, creating any that do not exist yet.
+func linkBookContributors(tx *sql.Tx, bookId uint64, authors []string, publisher string) error {
+	const upsertAuthor = `INSERT INTO authors (name, name_key) VALUES ($1, $2)
+	ON CONFLICT (name_key) DO UPDATE SET name_key = EXCLUDED.name_key
+	RETURNING id;`
+	const upsertPublisher = `INSERT INTO publishers (name, name_key) VALUES ($1, $2)
+	ON CONFLICT (name_key) DO UPDATE SET name_key = EXCLUDED.name_key
+	RETURNING id;`
+
+	_, err := tx.Exec(`DELETE FROM book_authors WHERE book_id = $1 AND role = $2;`, bookId, RoleAuthor)
+	if err != nil {
+		return err
+	}
+
+	for i, name := range authors {
+		key := nameKey(name)
+		if key == "" {
+			continue
+		}
+		var authorId uint64
+		err = tx.QueryRow(upsertAuthor, strings.TrimSpace(name), key).Scan(&authorId)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO book_authors (book_id, author_id, role, position) VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING;`, bookId, authorId, RoleAuthor, i+1)
+		if err != nil {
+			return err
+		}
+	}
+
+	var publisherId sql.NullInt64
+	if key := nameKey(publisher); key != "" {
+		err = tx.QueryRow(upsertPublisher, strings.TrimSpace(publisher), key).Scan(&publisherId)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec(`UPDATE books SET publisher_id = $1 WHERE id = $2;`, publisherId, bookId)
+	return err
+}
+
+// migrateBookContributors links every existing book to de-duplicated author
+// and publisher entities built from its authors array and publisher column.
+// The first spelling seen for a name becomes the canonical one.
+func migrateBookContributors(tx *sql.Tx) error {
+	type bookContributors struct {
+		id        uint64
+		authors   []string
+		publisher string
+	}
+
+	rows, err := tx.Query(`SELECT id, authors, publisher FROM books ORDER BY id;`)
+	if err != nil {
+		return err
+	}
+	books := []bookContributors{}
+	for rows.Next() {
+		b := bookContributors{}
+		if err := rows.Scan(&b.id, pq.Array(&b.authors), &b.publisher); err != nil {
+			rows.Close()
+			return err
+		}
+		books = append(books, b)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, b := range books {
+		if err := linkBookContributors(tx, b.id, b.authors, b.publisher); err != nil {
+			return err
+		}
+	}
+	return nil
+}
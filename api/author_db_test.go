@@ -0,0 +1,335 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNameKey(t *testing.T) {
+	t.Run("TestNameKeyShouldIgnoreCasePunctuationAndSpaces", func(t *testing.T) {
+		// Arrange
+		names := []string{"J.K. Rowling", "J. K. Rowling", " j k rowling ", "J.-K. ROWLING"}
+
+		// Act & Assert
+		for _, name := range names {
+			assert.Equal(t, "jkrowling", nameKey(name))
+		}
+		assert.NotEqual(t, nameKey("J.K. Rowling"), nameKey("J.R. Rowling"))
+		assert.Equal(t, "", nameKey(" . "))
+	})
+}
+
+func TestInsertAuthor(t *testing.T) {
+	t.Run("TestInsertAuthorShouldReturnNoError", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		row := sqlmock.NewRows([]string{"id", "name", "created_at"}).AddRow(1, "J.K. Rowling", time.Now())
+		get := mock.ExpectPrepare(regexp.QuoteMeta(`INSERT INTO authors (name, name_key) VALUES ($1, $2) RETURNING id, name, created_at;`))
+		get.ExpectQuery().
+			WithArgs("J.K. Rowling", "jkrowling").
+			WillReturnRows(row)
+
+		query := NewDB(db)
+
+		// Act
+		result, err := query.InsertAuthor(context.Background(), RequestAuthor{Name: " J.K. Rowling "})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(1), result.Id)
+		assert.Equal(t, "J.K. Rowling", result.Name)
+	})
+
+	t.Run("TestInsertAuthorShouldReturnError", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		get := mock.ExpectPrepare(regexp.QuoteMeta(`INSERT INTO authors`))
+		get.ExpectQuery().
+			WillReturnError(&pq.Error{Code: pqUniqueViolation})
+
+		query := NewDB(db)
+
+		// Act
+		_, err = query.InsertAuthor(context.Background(), RequestAuthor{Name: "J.K. Rowling"})
+
+		// Assert
+		assert.True(t, isPqError(err, pqUniqueViolation))
+	})
+}
+
+func TestSelectAllAuthors(t *testing.T) {
+	t.Run("TestSelectAllAuthorsShouldReturnNoError", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		rows := sqlmock.NewRows([]string{"id", "name", "created_at"}).
+			AddRow(2, "Jane Writer", time.Now()).
+			AddRow(1, "John Author", time.Now())
+		get := mock.ExpectPrepare(regexp.QuoteMeta(`SELECT id, name, created_at FROM authors ORDER BY name, id LIMIT $1 OFFSET $2;`))
+		get.ExpectQuery().
+			WithArgs(10, 0).
+			WillReturnRows(rows)
+
+		query := NewDB(db)
+
+		// Act
+		results, err := query.SelectAllAuthors(context.Background(), GetAllParams{Limit: 10, Offset: 0})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Len(t, results, 2)
+	})
+}
+
+func TestSelectAuthor(t *testing.T) {
+	t.Run("TestSelectAuthorShouldReturnErrNoRows", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		get := mock.ExpectPrepare(regexp.QuoteMeta(`SELECT id, name, created_at FROM authors WHERE id = $1;`))
+		get.ExpectQuery().
+			WithArgs(7).
+			WillReturnError(sql.ErrNoRows)
+
+		query := NewDB(db)
+
+		// Act
+		_, err = query.SelectAuthor(context.Background(), 7)
+
+		// Assert
+		assert.Equal(t, sql.ErrNoRows, err)
+	})
+}
+
+func TestUpdateAuthor(t *testing.T) {
+	t.Run("TestUpdateAuthorShouldRerenderBooks", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE authors SET name = $1, name_key = $2 WHERE id = $3`)).
+			WithArgs("Joanne Rowling", "joannerowling", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "created_at"}).AddRow(1, "Joanne Rowling", time.Now()))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE books b SET authors = ARRAY(`)).
+			WithArgs(1, RoleAuthor).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE works w SET authors = b.authors FROM books b WHERE b.work_id = w.id AND b.id IN (SELECT book_id FROM book_authors WHERE author_id = $1 AND role = $2);`)).
+			WithArgs(1, RoleAuthor).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		query := NewDB(db)
+
+		// Act
+		result, err := query.UpdateAuthor(context.Background(), 1, RequestAuthor{Name: "Joanne Rowling"})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "Joanne Rowling", result.Name)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestUpdateAuthorShouldRollbackWhenWorksFail", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE authors`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "created_at"}).AddRow(1, "Joanne Rowling", time.Now()))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE books b SET authors = ARRAY(`)).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE works w SET authors = b.authors`)).
+			WillReturnError(sql.ErrConnDone)
+		mock.ExpectRollback()
+
+		query := NewDB(db)
+
+		// Act
+		_, err = query.UpdateAuthor(context.Background(), 1, RequestAuthor{Name: "Joanne Rowling"})
+
+		// Assert
+		assert.Equal(t, sql.ErrConnDone, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestUpdateAuthorShouldRollbackOnError", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE authors`)).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		query := NewDB(db)
+
+		// Act
+		_, err = query.UpdateAuthor(context.Background(), 1, RequestAuthor{Name: "Joanne Rowling"})
+
+		// Assert
+		assert.Equal(t, sql.ErrNoRows, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDeleteAuthor(t *testing.T) {
+	t.Run("TestDeleteAuthorShouldReturnErrNoRowsWhenMissing", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		get := mock.ExpectPrepare(regexp.QuoteMeta(`DELETE FROM authors WHERE id = $1;`))
+		get.ExpectExec().
+			WithArgs(1).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		query := NewDB(db)
+
+		// Act
+		err = query.DeleteAuthor(context.Background(), 1)
+
+		// Assert
+		assert.Equal(t, sql.ErrNoRows, err)
+	})
+
+	t.Run("TestDeleteAuthorShouldReturnForeignKeyError", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		get := mock.ExpectPrepare(regexp.QuoteMeta(`DELETE FROM authors WHERE id = $1;`))
+		get.ExpectExec().
+			WithArgs(1).
+			WillReturnError(&pq.Error{Code: pqForeignKeyViolation})
+
+		query := NewDB(db)
+
+		// Act
+		err = query.DeleteAuthor(context.Background(), 1)
+
+		// Assert
+		assert.True(t, isPqError(err, pqForeignKeyViolation))
+	})
+}
+
+func TestSelectBooksByAuthor(t *testing.T) {
+	t.Run("TestSelectBooksByAuthorShouldReturnNoError", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		rows := sqlmock.NewRows([]string{"id", "title", "authors", "publisher", "isbn", "price", "quantity", "created_by", "created_at", "role", "position"}).
+			AddRow(1, "mockTitle", pq.Array([]string{"John Author", "Jane Writer"}), "mockPublisher", "1234567890", 1000, 10, "Admin", time.Now(), RoleAuthor, 2)
+		get := mock.ExpectPrepare(regexp.QuoteMeta(`FROM book_authors ba JOIN books b ON b.id = ba.book_id WHERE ba.author_id = $1`))
+		get.ExpectQuery().
+			WithArgs(2, 10, 0).
+			WillReturnRows(rows)
+
+		query := NewDB(db)
+
+		// Act
+		results, err := query.SelectBooksByAuthor(context.Background(), 2, GetAllParams{Limit: 10})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Len(t, results, 1)
+		assert.Equal(t, RoleAuthor, results[0].Role)
+		assert.Equal(t, int64(2), results[0].Position)
+		assert.Equal(t, []string{"John Author", "Jane Writer"}, results[0].Authors)
+	})
+}
+
+func TestLinkBookContributors(t *testing.T) {
+	t.Run("TestLinkBookContributorsShouldDeduplicateAuthors", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM book_authors WHERE book_id = $1 AND role = $2;`)).
+			WithArgs(5, RoleAuthor).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		for i, name := range []string{"J.K. Rowling", "J. K. Rowling"} {
+			mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO authors (name, name_key) VALUES ($1, $2) ON CONFLICT (name_key)`)).
+				WithArgs(name, "jkrowling").
+				WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+			mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO book_authors (book_id, author_id, role, position)`)).
+				WithArgs(5, 9, RoleAuthor, i+1).
+				WillReturnResult(sqlmock.NewResult(0, int64(1-i)))
+		}
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO publishers (name, name_key) VALUES ($1, $2) ON CONFLICT (name_key)`)).
+			WithArgs("Bloomsbury", "bloomsbury").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE books SET publisher_id = $1 WHERE id = $2;`)).
+			WithArgs(3, 5).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		tx, err := db.Begin()
+		assert.NoError(t, err)
+
+		// Act
+		err = linkBookContributors(tx, 5, []string{"J.K. Rowling", "J. K. Rowling", " "}, "Bloomsbury")
+		assert.NoError(t, tx.Commit())
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestLinkBookContributorsShouldClearMissingPublisher", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM book_authors`)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE books SET publisher_id = $1 WHERE id = $2;`)).
+			WithArgs(driver.Value(nil), 5).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		tx, err := db.Begin()
+		assert.NoError(t, err)
+
+		// Act
+		err = linkBookContributors(tx, 5, nil, "")
+		assert.NoError(t, tx.Commit())
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
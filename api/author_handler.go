@@ -0,0 +1,154 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	c "github.com/paquesqueue/bookstore/common"
+)
+
+type AuthorHandlrQueries interface {
+	AddAuthor(ctx context.Context, req RequestAuthor) (ResponseAuthor, error)
+	ListAuthors(ctx context.Context, params GetAllParams) ([]ResponseAuthor, error)
+	GetAuthor(ctx context.Context, id uint64) (ResponseAuthor, error)
+	PutAuthor(ctx context.Context, id uint64, req RequestAuthor) (ResponseAuthor, error)
+	DeleteAuthor(ctx context.Context, id uint64) error
+	ListAuthorBooks(ctx context.Context, id uint64, params GetAllParams) ([]ResponseAuthorBook, error)
+}
+
+type AuthorHandlr struct {
+	handler AuthorHandlrQueries
+	log     c.Log
+}
+
+func NewAuthorHandlr(h AuthorHandlrQueries, l c.Log) AuthorHandlr {
+	return AuthorHandlr{h, l}
+}
+
+func (h AuthorHandlr) AddAuthor(ctx echo.Context) error {
+	req := RequestAuthor{}
+	err := ctx.Bind(&req)
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	res, err := h.handler.AddAuthor(ctx.Request().Context(), req)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error AddAuthor Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusCreated, res)
+}
+
+func (h AuthorHandlr) ListAuthors(ctx echo.Context) error {
+	var req RequestGetAll
+	err := ctx.Bind(&req)
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	params := GetAllParams{
+		Limit:  req.PageSize,
+		Offset: (req.PageId - 1) * req.PageSize,
+	}
+
+	res, err := h.handler.ListAuthors(ctx.Request().Context(), params)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error ListAuthors Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h AuthorHandlr) GetAuthor(ctx echo.Context) error {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	res, err := h.handler.GetAuthor(ctx.Request().Context(), uint64(id))
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error GetAuthor Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h AuthorHandlr) PutAuthor(ctx echo.Context) error {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	req := RequestAuthor{}
+	err = ctx.Bind(&req)
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	res, err := h.handler.PutAuthor(ctx.Request().Context(), uint64(id), req)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error PutAuthor Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h AuthorHandlr) DeleteAuthor(ctx echo.Context) error {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	err = h.handler.DeleteAuthor(ctx.Request().Context(), uint64(id))
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error DeleteAuthor Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, "Deleted Successfully")
+}
+
+func (h AuthorHandlr) ListAuthorBooks(ctx echo.Context) error {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	var req RequestGetAll
+	err = ctx.Bind(&req)
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	params := GetAllParams{
+		Limit:  req.PageSize,
+		Offset: (req.PageId - 1) * req.PageSize,
+	}
+
+	res, err := h.handler.ListAuthorBooks(ctx.Request().Context(), uint64(id), params)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error ListAuthorBooks Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,230 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type AuthorHandlrSuccess struct {
+	addAuthorCalled       bool
+	listAuthorsCalled     bool
+	getAuthorCalled       bool
+	putAuthorCalled       bool
+	deleteAuthorCalled    bool
+	listAuthorBooksCalled bool
+	params                GetAllParams
+}
+
+func (h *AuthorHandlrSuccess) AddAuthor(ctx context.Context, req RequestAuthor) (ResponseAuthor, error) {
+	h.addAuthorCalled = true
+	return ResponseAuthor{Id: 1, Name: req.Name, CreatedAt: time.Now()}, nil
+}
+
+func (h *AuthorHandlrSuccess) ListAuthors(ctx context.Context, params GetAllParams) ([]ResponseAuthor, error) {
+	h.listAuthorsCalled = true
+	h.params = params
+	return []ResponseAuthor{{Id: 1, Name: "John Author"}, {Id: 2, Name: "Jane Writer"}}, nil
+}
+
+func (h *AuthorHandlrSuccess) GetAuthor(ctx context.Context, id uint64) (ResponseAuthor, error) {
+	h.getAuthorCalled = true
+	return ResponseAuthor{Id: id, Name: "John Author"}, nil
+}
+
+func (h *AuthorHandlrSuccess) PutAuthor(ctx context.Context, id uint64, req RequestAuthor) (ResponseAuthor, error) {
+	h.putAuthorCalled = true
+	return ResponseAuthor{Id: id, Name: req.Name}, nil
+}
+
+func (h *AuthorHandlrSuccess) DeleteAuthor(ctx context.Context, id uint64) error {
+	h.deleteAuthorCalled = true
+	return nil
+}
+
+func (h *AuthorHandlrSuccess) ListAuthorBooks(ctx context.Context, id uint64, params GetAllParams) ([]ResponseAuthorBook, error) {
+	h.listAuthorBooksCalled = true
+	h.params = params
+	return []ResponseAuthorBook{{ResponseBook: ResponseBook{Id: 1, Title: "mockTitle"}, Role: RoleAuthor, Position: 1}}, nil
+}
+
+type AuthorHandlrError struct {
+	statusCodeError int
+}
+
+func (h *AuthorHandlrError) AddAuthor(ctx context.Context, req RequestAuthor) (ResponseAuthor, error) {
+	return ResponseAuthor{}, &c.Err{Code: h.statusCodeError}
+}
+
+func (h *AuthorHandlrError) ListAuthors(ctx context.Context, params GetAllParams) ([]ResponseAuthor, error) {
+	return nil, &c.Err{Code: h.statusCodeError}
+}
+
+func (h *AuthorHandlrError) GetAuthor(ctx context.Context, id uint64) (ResponseAuthor, error) {
+	return ResponseAuthor{}, &c.Err{Code: h.statusCodeError}
+}
+
+func (h *AuthorHandlrError) PutAuthor(ctx context.Context, id uint64, req RequestAuthor) (ResponseAuthor, error) {
+	return ResponseAuthor{}, &c.Err{Code: h.statusCodeError}
+}
+
+func (h *AuthorHandlrError) DeleteAuthor(ctx context.Context, id uint64) error {
+	return &c.Err{Code: h.statusCodeError}
+}
+
+func (h *AuthorHandlrError) ListAuthorBooks(ctx context.Context, id uint64, params GetAllParams) ([]ResponseAuthorBook, error) {
+	return nil, &c.Err{Code: h.statusCodeError}
+}
+
+func TestAddAuthorHandler(t *testing.T) {
+	t.Run("TestAddAuthorHandlerShouldReturnHTTPStatus201", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPost, "/authors", strings.NewReader(`{"name":"John Author"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		ctx := echo.New().NewContext(req, rec)
+
+		handlrServ := &AuthorHandlrSuccess{}
+		handler := NewAuthorHandlr(handlrServ, logrus.New())
+
+		// Act
+		err := handler.AddAuthor(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.True(t, handlrServ.addAuthorCalled)
+			assert.Equal(t, http.StatusCreated, rec.Code)
+
+			res := ResponseAuthor{}
+			json.Unmarshal(rec.Body.Bytes(), &res)
+			assert.Equal(t, "John Author", res.Name)
+		}
+	})
+
+	t.Run("TestAddAuthorHandlerShouldReturnHTTPStatus409", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPost, "/authors", strings.NewReader(`{"name":"John Author"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		ctx := echo.New().NewContext(req, rec)
+
+		handler := NewAuthorHandlr(&AuthorHandlrError{statusCodeError: http.StatusConflict}, logrus.New())
+
+		// Act
+		err := handler.AddAuthor(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusConflict, rec.Code)
+		}
+	})
+}
+
+func TestGetAuthorHandler(t *testing.T) {
+	t.Run("TestGetAuthorHandlerShouldReturnHTTPStatus200", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		ctx := echo.New().NewContext(req, rec)
+		ctx.SetPath("/authors/:id")
+		ctx.SetParamNames("id")
+		ctx.SetParamValues("1")
+
+		handlrServ := &AuthorHandlrSuccess{}
+		handler := NewAuthorHandlr(handlrServ, logrus.New())
+
+		// Act
+		err := handler.GetAuthor(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.True(t, handlrServ.getAuthorCalled)
+			assert.Equal(t, http.StatusOK, rec.Code)
+		}
+	})
+
+	t.Run("TestGetAuthorHandlerShouldReturnHTTPStatus400", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		ctx := echo.New().NewContext(req, rec)
+		ctx.SetPath("/authors/:id")
+		ctx.SetParamNames("id")
+		ctx.SetParamValues("abc")
+
+		handlrServ := &AuthorHandlrSuccess{}
+		handler := NewAuthorHandlr(handlrServ, logrus.New())
+
+		// Act
+		err := handler.GetAuthor(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.False(t, handlrServ.getAuthorCalled)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		}
+	})
+}
+
+func TestDeleteAuthorHandler(t *testing.T) {
+	t.Run("TestDeleteAuthorHandlerShouldReturnHTTPStatus409", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodDelete, "/", nil)
+		rec := httptest.NewRecorder()
+		ctx := echo.New().NewContext(req, rec)
+		ctx.SetPath("/authors/:id")
+		ctx.SetParamNames("id")
+		ctx.SetParamValues("1")
+
+		handler := NewAuthorHandlr(&AuthorHandlrError{statusCodeError: http.StatusConflict}, logrus.New())
+
+		// Act
+		err := handler.DeleteAuthor(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusConflict, rec.Code)
+		}
+	})
+}
+
+func TestListAuthorBooksHandler(t *testing.T) {
+	t.Run("TestListAuthorBooksHandlerShouldReturnHTTPStatus200", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/", strings.NewReader(`{"page_id":2,"page_size":5}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		ctx := echo.New().NewContext(req, rec)
+		ctx.SetPath("/authors/:id/books")
+		ctx.SetParamNames("id")
+		ctx.SetParamValues("1")
+
+		handlrServ := &AuthorHandlrSuccess{}
+		handler := NewAuthorHandlr(handlrServ, logrus.New())
+
+		// Act
+		err := handler.ListAuthorBooks(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.True(t, handlrServ.listAuthorBooksCalled)
+			assert.Equal(t, GetAllParams{Limit: 5, Offset: 5}, handlrServ.params)
+			assert.Equal(t, http.StatusOK, rec.Code)
+
+			res := []ResponseAuthorBook{}
+			json.Unmarshal(rec.Body.Bytes(), &res)
+			assert.Equal(t, RoleAuthor, res[0].Role)
+		}
+	})
+}
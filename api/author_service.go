@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/paquesqueue/bookstore/tracing"
+)
+
+type AuthorQueries interface {
+	InsertAuthor(ctx context.Context, req RequestAuthor) (ResponseAuthor, error)
+	SelectAllAuthors(ctx context.Context, params GetAllParams) ([]ResponseAuthor, error)
+	SelectAuthor(ctx context.Context, id uint64) (ResponseAuthor, error)
+	UpdateAuthor(ctx context.Context, id uint64, req RequestAuthor) (ResponseAuthor, error)
+	DeleteAuthor(ctx context.Context, id uint64) error
+	SelectBooksByAuthor(ctx context.Context, id uint64, params GetAllParams) ([]ResponseAuthorBook, error)
+}
+
+type AuthorServices struct {
+	query AuthorQueries
+	log   c.Log
+}
+
+func NewAuthorService(q AuthorQueries, l c.Log) AuthorServices {
+	return AuthorServices{q, l}
+}
+
+func (s AuthorServices) AddAuthor(ctx context.Context, req RequestAuthor) (ResponseAuthor, error) {
+	ctx, span := tracing.Start(ctx, "AuthorServices.AddAuthor")
+	defer span.End()
+
+	if nameKey(req.Name) == "" {
+		return ResponseAuthor{}, &c.Err{Code: http.StatusBadRequest, Remark: "Error Author Name Required"}
+	}
+
+	resp, err := s.query.InsertAuthor(ctx, req)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error InsertAuthor : %v", err)
+		return ResponseAuthor{}, authorErr(err, "Error AddAuthor Service")
+	}
+	return resp, nil
+}
+
+func (s AuthorServices) ListAuthors(ctx context.Context, params GetAllParams) ([]ResponseAuthor, error) {
+	ctx, span := tracing.Start(ctx, "AuthorServices.ListAuthors")
+	defer span.End()
+
+	resp, err := s.query.SelectAllAuthors(ctx, params)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error SelectAllAuthors : %v", err)
+		return nil, &c.Err{Code: http.StatusInternalServerError, Remark: "Error ListAuthors Service", Original: err}
+	}
+	return resp, nil
+}
+
+func (s AuthorServices) GetAuthor(ctx context.Context, id uint64) (ResponseAuthor, error) {
+	ctx, span := tracing.Start(ctx, "AuthorServices.GetAuthor")
+	defer span.End()
+
+	resp, err := s.query.SelectAuthor(ctx, id)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error SelectAuthor : %v", err)
+		return ResponseAuthor{}, authorErr(err, "Error GetAuthor Service")
+	}
+	return resp, nil
+}
+
+func (s AuthorServices) PutAuthor(ctx context.Context, id uint64, req RequestAuthor) (ResponseAuthor, error) {
+	ctx, span := tracing.Start(ctx, "AuthorServices.PutAuthor")
+	defer span.End()
+
+	if nameKey(req.Name) == "" {
+		return ResponseAuthor{}, &c.Err{Code: http.StatusBadRequest, Remark: "Error Author Name Required"}
+	}
+
+	resp, err := s.query.UpdateAuthor(ctx, id, req)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error UpdateAuthor : %v", err)
+		return ResponseAuthor{}, authorErr(err, "Error PutAuthor Service")
+	}
+	return resp, nil
+}
+
+func (s AuthorServices) DeleteAuthor(ctx context.Context, id uint64) error {
+	ctx, span := tracing.Start(ctx, "AuthorServices.DeleteAuthor")
+	defer span.End()
+
+	err := s.query.DeleteAuthor(ctx, id)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error DeleteAuthor : %v", err)
+		return authorErr(err, "Error DeleteAuthor Service")
+	}
+	return nil
+}
+
+func (s AuthorServices) ListAuthorBooks(ctx context.Context, id uint64, params GetAllParams) ([]ResponseAuthorBook, error) {
+	ctx, span := tracing.Start(ctx, "AuthorServices.ListAuthorBooks")
+	defer span.End()
+
+	_, err := s.GetAuthor(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.query.SelectBooksByAuthor(ctx, id, params)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error SelectBooksByAuthor : %v", err)
+		return nil, &c.Err{Code: http.StatusInternalServerError, Remark: "Error ListAuthorBooks Service", Original: err}
+	}
+	return resp, nil
+}
+
+func authorErr(err error, remark string) *c.Err {
+	switch {
+	case err == sql.ErrNoRows:
+		return &c.Err{Code: http.StatusNotFound, Remark: "Error Author Not Found", Original: err}
+	case isPqError(err, pqUniqueViolation):
+		return &c.Err{Code: http.StatusConflict, Remark: "Error Author Already Exists", Original: err}
+	case isPqError(err, pqForeignKeyViolation):
+		return &c.Err{Code: http.StatusConflict, Remark: "Error Author Still Has Books", Original: err}
+	default:
+		return &c.Err{Code: http.StatusInternalServerError, Remark: remark, Original: err}
+	}
+}
@@ -0,0 +1,229 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type AuthorQueriesSuccess struct {
+	insertAuthorCalled        bool
+	selectAllAuthorsCalled    bool
+	selectAuthorCalled        bool
+	updateAuthorCalled        bool
+	deleteAuthorCalled        bool
+	selectBooksByAuthorCalled bool
+}
+
+func (s *AuthorQueriesSuccess) InsertAuthor(ctx context.Context, req RequestAuthor) (ResponseAuthor, error) {
+	s.insertAuthorCalled = true
+	return ResponseAuthor{Id: 1, Name: req.Name, CreatedAt: time.Now()}, nil
+}
+
+func (s *AuthorQueriesSuccess) SelectAllAuthors(ctx context.Context, params GetAllParams) ([]ResponseAuthor, error) {
+	s.selectAllAuthorsCalled = true
+	return []ResponseAuthor{
+		{Id: 1, Name: "John Author", CreatedAt: time.Now()},
+		{Id: 2, Name: "Jane Writer", CreatedAt: time.Now()},
+	}, nil
+}
+
+func (s *AuthorQueriesSuccess) SelectAuthor(ctx context.Context, id uint64) (ResponseAuthor, error) {
+	s.selectAuthorCalled = true
+	return ResponseAuthor{Id: id, Name: "John Author", CreatedAt: time.Now()}, nil
+}
+
+func (s *AuthorQueriesSuccess) UpdateAuthor(ctx context.Context, id uint64, req RequestAuthor) (ResponseAuthor, error) {
+	s.updateAuthorCalled = true
+	return ResponseAuthor{Id: id, Name: req.Name, CreatedAt: time.Now()}, nil
+}
+
+func (s *AuthorQueriesSuccess) DeleteAuthor(ctx context.Context, id uint64) error {
+	s.deleteAuthorCalled = true
+	return nil
+}
+
+func (s *AuthorQueriesSuccess) SelectBooksByAuthor(ctx context.Context, id uint64, params GetAllParams) ([]ResponseAuthorBook, error) {
+	s.selectBooksByAuthorCalled = true
+	return []ResponseAuthorBook{
+		{ResponseBook: ResponseBook{Id: 1, Title: "mockTitle", Authors: []string{"John Author"}}, Role: RoleAuthor, Position: 1},
+	}, nil
+}
+
+type AuthorQueriesError struct {
+	err error
+}
+
+func (s *AuthorQueriesError) InsertAuthor(ctx context.Context, req RequestAuthor) (ResponseAuthor, error) {
+	return ResponseAuthor{}, s.err
+}
+
+func (s *AuthorQueriesError) SelectAllAuthors(ctx context.Context, params GetAllParams) ([]ResponseAuthor, error) {
+	return nil, s.err
+}
+
+func (s *AuthorQueriesError) SelectAuthor(ctx context.Context, id uint64) (ResponseAuthor, error) {
+	return ResponseAuthor{}, s.err
+}
+
+func (s *AuthorQueriesError) UpdateAuthor(ctx context.Context, id uint64, req RequestAuthor) (ResponseAuthor, error) {
+	return ResponseAuthor{}, s.err
+}
+
+func (s *AuthorQueriesError) DeleteAuthor(ctx context.Context, id uint64) error {
+	return s.err
+}
+
+func (s *AuthorQueriesError) SelectBooksByAuthor(ctx context.Context, id uint64, params GetAllParams) ([]ResponseAuthorBook, error) {
+	return nil, s.err
+}
+
+func TestAddAuthorService(t *testing.T) {
+	t.Run("TestAddAuthorServiceShouldReturnNoError", func(t *testing.T) {
+		// Arrange
+		query := &AuthorQueriesSuccess{}
+		service := NewAuthorService(query, logrus.New())
+
+		// Act
+		resp, err := service.AddAuthor(context.Background(), RequestAuthor{Name: "John Author"})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.True(t, query.insertAuthorCalled)
+		assert.Equal(t, "John Author", resp.Name)
+	})
+
+	t.Run("TestAddAuthorServiceShouldReturnBadRequestOnEmptyName", func(t *testing.T) {
+		// Arrange
+		query := &AuthorQueriesSuccess{}
+		service := NewAuthorService(query, logrus.New())
+
+		// Act
+		_, err := service.AddAuthor(context.Background(), RequestAuthor{Name: " . "})
+
+		// Assert
+		assert.False(t, query.insertAuthorCalled)
+		assert.Equal(t, http.StatusBadRequest, err.(*c.Err).Code)
+	})
+
+	t.Run("TestAddAuthorServiceShouldReturnConflict", func(t *testing.T) {
+		// Arrange
+		query := &AuthorQueriesError{err: &pq.Error{Code: pqUniqueViolation}}
+		service := NewAuthorService(query, logrus.New())
+
+		// Act
+		_, err := service.AddAuthor(context.Background(), RequestAuthor{Name: "John Author"})
+
+		// Assert
+		assert.Equal(t, http.StatusConflict, err.(*c.Err).Code)
+	})
+}
+
+func TestGetAuthorService(t *testing.T) {
+	t.Run("TestGetAuthorServiceShouldReturnNoError", func(t *testing.T) {
+		// Arrange
+		query := &AuthorQueriesSuccess{}
+		service := NewAuthorService(query, logrus.New())
+
+		// Act
+		resp, err := service.GetAuthor(context.Background(), 1)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.True(t, query.selectAuthorCalled)
+		assert.Equal(t, uint64(1), resp.Id)
+	})
+
+	t.Run("TestGetAuthorServiceShouldReturnNotFound", func(t *testing.T) {
+		// Arrange
+		query := &AuthorQueriesError{err: sql.ErrNoRows}
+		service := NewAuthorService(query, logrus.New())
+
+		// Act
+		_, err := service.GetAuthor(context.Background(), 1)
+
+		// Assert
+		assert.Equal(t, http.StatusNotFound, err.(*c.Err).Code)
+	})
+}
+
+func TestPutAuthorService(t *testing.T) {
+	t.Run("TestPutAuthorServiceShouldReturnNoError", func(t *testing.T) {
+		// Arrange
+		query := &AuthorQueriesSuccess{}
+		service := NewAuthorService(query, logrus.New())
+
+		// Act
+		resp, err := service.PutAuthor(context.Background(), 1, RequestAuthor{Name: "Joanne Rowling"})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.True(t, query.updateAuthorCalled)
+		assert.Equal(t, "Joanne Rowling", resp.Name)
+	})
+}
+
+func TestDeleteAuthorService(t *testing.T) {
+	t.Run("TestDeleteAuthorServiceShouldReturnNoError", func(t *testing.T) {
+		// Arrange
+		query := &AuthorQueriesSuccess{}
+		service := NewAuthorService(query, logrus.New())
+
+		// Act
+		err := service.DeleteAuthor(context.Background(), 1)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.True(t, query.deleteAuthorCalled)
+	})
+
+	t.Run("TestDeleteAuthorServiceShouldReturnConflictWhenLinked", func(t *testing.T) {
+		// Arrange
+		query := &AuthorQueriesError{err: &pq.Error{Code: pqForeignKeyViolation}}
+		service := NewAuthorService(query, logrus.New())
+
+		// Act
+		err := service.DeleteAuthor(context.Background(), 1)
+
+		// Assert
+		assert.Equal(t, http.StatusConflict, err.(*c.Err).Code)
+	})
+}
+
+func TestListAuthorBooksService(t *testing.T) {
+	t.Run("TestListAuthorBooksServiceShouldReturnNoError", func(t *testing.T) {
+		// Arrange
+		query := &AuthorQueriesSuccess{}
+		service := NewAuthorService(query, logrus.New())
+
+		// Act
+		resp, err := service.ListAuthorBooks(context.Background(), 1, GetAllParams{Limit: 10})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.True(t, query.selectAuthorCalled)
+		assert.True(t, query.selectBooksByAuthorCalled)
+		assert.Len(t, resp, 1)
+	})
+
+	t.Run("TestListAuthorBooksServiceShouldReturnNotFound", func(t *testing.T) {
+		// Arrange
+		query := &AuthorQueriesError{err: sql.ErrNoRows}
+		service := NewAuthorService(query, logrus.New())
+
+		// Act
+		_, err := service.ListAuthorBooks(context.Background(), 1, GetAllParams{Limit: 10})
+
+		// Assert
+		assert.Equal(t, http.StatusNotFound, err.(*c.Err).Code)
+	})
+}
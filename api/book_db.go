@@ -1,45 +1,101 @@
 package api
 
 import (
+	"context"
+	"database/sql"
+	"math"
+
 	"github.com/lib/pq"
 )
 
-func (db Query) InsertBook(req RequestBook) (*ResponseBook, error) {
-	const query = `INSERT INTO books 
-	(title, authors, publisher, isbn, price, quantity, created_by) 
-	VALUES ($1, $2, $3, $4, $5, $6, $7) 
-	RETURNING id, title, authors, publisher, isbn, price, quantity, created_by, created_at;`
+// bookColumns lists the edition-level view of a book. Title and authors are
+// copies of the work's, kept in step by syncWorkEditions. The rating totals
+// are kept up to date as reviews are moderated, see adjustBookRating.
+const bookColumns = `id, title, authors, publisher, isbn, price, quantity, created_by, created_at,
+	work_id, format, COALESCE(to_char(publication_date, 'YYYY-MM-DD'), ''), rating_count, rating_sum`
 
-	stmt, err := db.Prepare(query)
+func scanBook(row rowScanner) (*ResponseBook, error) {
+	resp := &ResponseBook{}
+	var ratingSum int64
+	err := row.Scan(&resp.Id, &resp.Title, pq.Array(&resp.Authors), &resp.Publisher, &resp.Isbn, &resp.Price, &resp.Quantity, &resp.Created_by, &resp.Created_at,
+		&resp.WorkId, &resp.Format, &resp.PublicationDate, &resp.RatingCount, &ratingSum)
 	if err != nil {
 		return nil, err
 	}
-	defer stmt.Close()
+	resp.RatingAvg = ratingAverage(ratingSum, resp.RatingCount)
+	return resp, nil
+}
 
-	row := stmt.QueryRow(req.Title, pq.Array(req.Authors), req.Publisher, req.Isbn, req.Price, req.Quantity, req.Created_by)
+// ratingAverage rounds to two decimals, which is all a star rating needs.
+func ratingAverage(sum int64, count int64) float64 {
+	if count == 0 {
+		return 0
+	}
+	return math.Round(float64(sum)/float64(count)*100) / 100
+}
 
-	resp := &ResponseBook{}
-	err = row.Scan(&resp.Id, &resp.Title, pq.Array(&resp.Authors), &resp.Publisher, &resp.Isbn, &resp.Price, &resp.Quantity, &resp.Created_by, &resp.Created_at)
+// InsertBook adds an edition and links its contributors. Without a work id
+// a new work is created from the book's title and authors; with one, the
+// edition takes the title and authors of that work.
+func (db Query) InsertBook(ctx context.Context, req RequestBook) (*ResponseBook, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	resp, err := insertBook(tx, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp, tx.Commit()
+}
+
+func insertBook(tx *sql.Tx, req RequestBook) (*ResponseBook, error) {
+	const query = `INSERT INTO books
+	(title, authors, publisher, isbn, price, quantity, created_by, work_id, format, publication_date)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, '')::date)
+	RETURNING ` + bookColumns + `;`
+
+	var workId uint64
+	var err error
+	if req.WorkId != nil {
+		workId = *req.WorkId
+		req.Title, req.Authors, err = selectWorkTitle(tx, workId)
+	} else {
+		workId, err = insertWork(tx, RequestWork{Title: req.Title, Authors: req.Authors})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	row := tx.QueryRow(query, req.Title, pq.Array(req.Authors), req.Publisher, req.Isbn, req.Price, req.Quantity, req.Created_by,
+		workId, req.Format, req.PublicationDate)
+	resp, err := scanBook(row)
+	if err != nil {
+		return nil, err
+	}
+	err = linkBookContributors(tx, resp.Id, resp.Authors, resp.Publisher)
 	if err != nil {
 		return nil, err
 	}
 	return resp, nil
 }
 
-func (db Query) SelectAllBooks(params GetAllParams) ([]ResponseBook, error) {
-	const query = `SELECT id, title, authors, publisher, isbn, price, quantity, created_by, created_at 
+func (db Query) SelectAllBooks(ctx context.Context, params GetAllParams) ([]ResponseBook, error) {
+	const query = `SELECT ` + bookColumns + `
 	FROM books
 	ORDER BY id
 	LIMIT $1
 	OFFSET $2;`
 
-	stmt, err := db.Prepare(query)
+	stmt, err := db.PrepareContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
 	defer stmt.Close()
 
-	rows, err := stmt.Query(params.Limit, params.Offset)
+	rows, err := stmt.QueryContext(ctx, params.Limit, params.Offset)
 	if err != nil {
 		return nil, err
 	}
@@ -47,8 +103,7 @@ func (db Query) SelectAllBooks(params GetAllParams) ([]ResponseBook, error) {
 
 	resp := []ResponseBook{}
 	for rows.Next() {
-		result := &ResponseBook{}
-		err = rows.Scan(&result.Id, &result.Title, pq.Array(&result.Authors), &result.Publisher, &result.Isbn, &result.Price, &result.Quantity, &result.Created_by, &result.Created_at)
+		result, err := scanBook(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -58,57 +113,155 @@ func (db Query) SelectAllBooks(params GetAllParams) ([]ResponseBook, error) {
 
 }
 
-func (db Query) SelectBookByID(id uint64) (*ResponseBook, error) {
-	const query = `SELECT * FROM books WHERE id = $1;`
+func (db Query) SelectBookByID(ctx context.Context, id uint64) (*ResponseBook, error) {
+	const query = `SELECT ` + bookColumns + `
+	FROM books
+	WHERE id = $1;`
 
-	stmt, err := db.Prepare(query)
+	stmt, err := db.PrepareContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
 	defer stmt.Close()
 
-	row := stmt.QueryRow(id)
-	resp := &ResponseBook{}
-	err = row.Scan(&resp.Id, &resp.Title, pq.Array(&resp.Authors), &resp.Publisher, &resp.Isbn, &resp.Price, &resp.Quantity, &resp.Created_by, &resp.Created_at)
+	return scanBook(stmt.QueryRowContext(ctx, id))
+}
 
+// UpdateBook changes an edition and relinks its contributors. Giving a work
+// id moves the edition to that work and it takes the work's title and
+// authors; otherwise the new title and authors are written to the current
+// work and all of its other editions.
+func (db Query) UpdateBook(ctx context.Context, id uint64, req RequestBook) (*ResponseBook, error) {
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
-	return resp, nil
+	defer tx.Rollback()
+
+	resp, err := updateBook(tx, id, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp, tx.Commit()
 }
 
-func (db Query) UpdateBook(id uint64, req RequestBook) (*ResponseBook, error) {
-	const query = `UPDATE books 
-	SET title = $1, authors = $2, publisher = $3, isbn = $4, price = $5, quantity = $6, created_by = $7 
-	WHERE id = $8 
-	RETURNING id, title, authors, publisher, isbn, price, quantity, created_by, created_at;`
+func updateBook(tx *sql.Tx, id uint64, req RequestBook) (*ResponseBook, error) {
+	const query = `UPDATE books
+	SET title = $1, authors = $2, publisher = $3, isbn = $4, price = $5, quantity = $6, created_by = $7,
+		work_id = COALESCE($8, work_id), format = $9, publication_date = NULLIF($10, '')::date
+	WHERE id = $11
+	RETURNING ` + bookColumns + `;`
+
+	var err error
+	if req.WorkId != nil {
+		req.Title, req.Authors, err = selectWorkTitle(tx, *req.WorkId)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	stmt, err := db.Prepare(query)
+	row := tx.QueryRow(query, req.Title, pq.Array(req.Authors), req.Publisher, req.Isbn, req.Price, req.Quantity, req.Created_by,
+		req.WorkId, req.Format, req.PublicationDate, id)
+	resp, err := scanBook(row)
 	if err != nil {
 		return nil, err
 	}
-	defer stmt.Close()
-
-	row := stmt.QueryRow(req.Title, pq.Array(req.Authors), req.Publisher, req.Isbn, req.Price, req.Quantity, req.Created_by, id)
-	resp := &ResponseBook{}
-	err = row.Scan(&resp.Id, &resp.Title, pq.Array(&resp.Authors), &resp.Publisher, &resp.Isbn, &resp.Price, &resp.Quantity, &resp.Created_by, &resp.Created_at)
+	err = linkBookContributors(tx, resp.Id, resp.Authors, resp.Publisher)
 	if err != nil {
 		return nil, err
 	}
+
+	if req.WorkId == nil {
+		err = syncWorkEditions(tx, resp.WorkId, req.Title, req.Authors, resp.Id)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return resp, nil
 }
 
-func (db Query) DeleteBook(id uint64) error {
-	const query = `DELETE FROM books WHERE id = $1;`
-	stmt, err := db.Prepare(query)
+// DeleteBook removes an edition, and its work when no edition is left.
+func (db Query) DeleteBook(ctx context.Context, id uint64) error {
+	const query = `DELETE FROM books WHERE id = $1 RETURNING work_id;`
+	const deleteWork = `DELETE FROM works WHERE id = $1 AND NOT EXISTS (SELECT 1 FROM books WHERE work_id = $1);`
+
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
-	defer stmt.Close()
+	defer tx.Rollback()
 
-	_, err = stmt.Exec(id)
+	var workId sql.NullInt64
+	err = tx.QueryRowContext(ctx, query, id).Scan(&workId)
+	if err == sql.ErrNoRows {
+		return nil
+	}
 	if err != nil {
 		return err
 	}
-	return nil
+	if workId.Valid {
+		_, err = tx.ExecContext(ctx, deleteWork, workId.Int64)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (db Query) SelectBookByIsbn(ctx context.Context, isbn string) (*ResponseBook, error) {
+	const query = `SELECT ` + bookColumns + `
+	FROM books
+	WHERE isbn = $1
+	ORDER BY id
+	LIMIT 1;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	return scanBook(stmt.QueryRowContext(ctx, isbn))
+}
+
+// SelectFilteredBooks lists the books assigned to a category or any of its
+// descendants and/or carrying a tag. Zero-valued filter fields are ignored.
+func (db Query) SelectFilteredBooks(ctx context.Context, filter RequestBookFilter, params GetAllParams) ([]ResponseBook, error) {
+	const query = `SELECT ` + bookColumns + `
+	FROM books b
+	WHERE ($1 = 0 OR EXISTS (
+		SELECT 1 FROM book_categories bc
+		JOIN categories c ON c.id = bc.category_id
+		WHERE bc.book_id = b.id
+		AND c.path LIKE (SELECT path FROM categories WHERE id = $1) || '%'))
+	AND ($2 = '' OR EXISTS (
+		SELECT 1 FROM book_tags bt
+		JOIN tags t ON t.id = bt.tag_id
+		WHERE bt.book_id = b.id
+		AND t.name_key = $2))
+	ORDER BY b.id
+	LIMIT $3
+	OFFSET $4;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, filter.CategoryId, tagKey(filter.Tag), params.Limit, params.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resp := []ResponseBook{}
+	for rows.Next() {
+		r, err := scanBook(rows)
+		if err != nil {
+			return nil, err
+		}
+		resp = append(resp, *r)
+	}
+	return resp, rows.Err()
 }
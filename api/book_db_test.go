@@ -3,6 +3,8 @@
 package api
 
 import (
+	"context"
+	"database/sql"
 	"regexp"
 	"testing"
 	"time"
@@ -12,6 +14,26 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+// expectLinkBookContributors expects the authors of a book to be linked in
+// order, each to a new author row, and its publisher to "mockPublisher".
+func expectLinkBookContributors(mock sqlmock.Sqlmock, bookId uint64, authors []string) {
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM book_authors WHERE book_id = $1 AND role = $2;`)).
+		WithArgs(bookId, RoleAuthor).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	for i := range authors {
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO authors (name, name_key) VALUES ($1, $2) ON CONFLICT (name_key)`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(i + 1))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO book_authors (book_id, author_id, role, position)`)).
+			WithArgs(bookId, i+1, RoleAuthor, i+1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO publishers (name, name_key) VALUES ($1, $2) ON CONFLICT (name_key)`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE books SET publisher_id = $1 WHERE id = $2;`)).
+		WithArgs(1, bookId).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
 func TestInsertBook(t *testing.T) {
 	t.Run("TestInsertShouldReturnNoError", func(t *testing.T) {
 		// Arrange
@@ -30,18 +52,23 @@ func TestInsertBook(t *testing.T) {
 		defer db.Close()
 
 		mockCreated_at := time.Now()
-		row := sqlmock.NewRows([]string{"id", "title", "authors", "publisher", "isbn", "price", "quantity", "created_by", "created_at"}).
-			AddRow(1, mockData.Title, pq.Array(mockData.Authors), mockData.Publisher, mockData.Isbn, mockData.Price, mockData.Quantity, mockData.Created_by, mockCreated_at)
-
-		get := mock.ExpectPrepare(regexp.QuoteMeta(`INSERT INTO books (title, authors, publisher, isbn, price, quantity, created_by) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, title, authors, publisher, isbn, price, quantity, created_by, created_at;`))
-		get.ExpectQuery().
-			WithArgs(mockData.Title, pq.Array(mockData.Authors), mockData.Publisher, mockData.Isbn, mockData.Price, mockData.Quantity, mockData.Created_by).
+		row := sqlmock.NewRows([]string{"id", "title", "authors", "publisher", "isbn", "price", "quantity", "created_by", "created_at", "work_id", "format", "publication_date", "rating_count", "rating_sum"}).
+			AddRow(1, mockData.Title, pq.Array(mockData.Authors), mockData.Publisher, mockData.Isbn, mockData.Price, mockData.Quantity, mockData.Created_by, mockCreated_at, 1, "", "", 0, 0)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO works (title, authors) VALUES ($1, $2) RETURNING id;`)).
+			WithArgs(mockData.Title, pq.Array(mockData.Authors)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO books (title, authors, publisher, isbn, price, quantity, created_by, work_id, format, publication_date) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, '')::date) RETURNING id, title, authors, publisher, isbn, price, quantity, created_by, created_at, work_id`)).
+			WithArgs(mockData.Title, pq.Array(mockData.Authors), mockData.Publisher, mockData.Isbn, mockData.Price, mockData.Quantity, mockData.Created_by, 1, "", "").
 			WillReturnRows(row)
+		expectLinkBookContributors(mock, 1, mockData.Authors)
+		mock.ExpectCommit()
 
 		query := NewDB(db)
 
 		// Act
-		result, err := query.InsertBook(mockData)
+		result, err := query.InsertBook(context.Background(), mockData)
 
 		// Assert
 		assert.Nil(t, err)
@@ -55,6 +82,103 @@ func TestInsertBook(t *testing.T) {
 		assert.Equal(t, mockCreated_at, result.Created_at)
 	})
 
+	t.Run("TestInsertShouldAddEditionToExistingWork", func(t *testing.T) {
+		// Arrange
+		workId := uint64(7)
+		mockData := RequestBook{
+			Title:           "ignored",
+			Publisher:       "mockPublisher",
+			Isbn:            "9780000000024",
+			Price:           500,
+			Quantity:        0,
+			Created_by:      "mockAdmin",
+			WorkId:          &workId,
+			Format:          EditionEbook,
+			PublicationDate: "2023-01-15",
+		}
+
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT title, authors FROM works WHERE id = $1;`)).
+			WithArgs(workId).
+			WillReturnRows(sqlmock.NewRows([]string{"title", "authors"}).AddRow("mockTitle", pq.Array([]string{"mockAuthor"})))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO books`)).
+			WithArgs("mockTitle", pq.Array([]string{"mockAuthor"}), mockData.Publisher, mockData.Isbn, mockData.Price, mockData.Quantity, mockData.Created_by, workId, EditionEbook, "2023-01-15").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "title", "authors", "publisher", "isbn", "price", "quantity", "created_by", "created_at", "work_id", "format", "publication_date", "rating_count", "rating_sum"}).
+				AddRow(2, "mockTitle", pq.Array([]string{"mockAuthor"}), mockData.Publisher, mockData.Isbn, mockData.Price, mockData.Quantity, mockData.Created_by, time.Now(), workId, EditionEbook, "2023-01-15", 0, 0))
+		expectLinkBookContributors(mock, 2, []string{"mockAuthor"})
+		mock.ExpectCommit()
+
+		query := NewDB(db)
+
+		// Act
+		result, err := query.InsertBook(context.Background(), mockData)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "mockTitle", result.Title)
+		assert.Equal(t, workId, result.WorkId)
+		assert.Equal(t, EditionEbook, result.Format)
+		assert.Equal(t, "2023-01-15", result.PublicationDate)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestInsertShouldReturnErrWorkNotFound", func(t *testing.T) {
+		// Arrange
+		workId := uint64(7)
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT title, authors FROM works WHERE id = $1;`)).
+			WithArgs(workId).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		query := NewDB(db)
+
+		// Act
+		result, err := query.InsertBook(context.Background(), RequestBook{WorkId: &workId})
+
+		// Assert
+		assert.Equal(t, errWorkNotFound, err)
+		assert.Nil(t, result)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestInsertShouldRollbackWhenLinkingFails", func(t *testing.T) {
+		// Arrange
+		mockData := RequestBook{Title: "mockTitle", Authors: []string{"mockAuthor"}, Publisher: "mockPublisher", Created_by: "mockAdmin"}
+
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO works (title, authors) VALUES ($1, $2) RETURNING id;`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO books`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "title", "authors", "publisher", "isbn", "price", "quantity", "created_by", "created_at", "work_id", "format", "publication_date", "rating_count", "rating_sum"}).
+				AddRow(1, mockData.Title, pq.Array(mockData.Authors), mockData.Publisher, "", 0, 0, mockData.Created_by, time.Now(), 1, "", "", 0, 0))
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM book_authors WHERE book_id = $1 AND role = $2;`)).
+			WillReturnError(&pq.Error{Message: "db connection error"})
+		mock.ExpectRollback()
+
+		query := NewDB(db)
+
+		// Act
+		result, err := query.InsertBook(context.Background(), mockData)
+
+		// Assert
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
 	t.Run("TestInsertShouldReturnError", func(t *testing.T) {
 		// Arrange
 		mockData := RequestBook{}
@@ -63,15 +187,15 @@ func TestInsertBook(t *testing.T) {
 		assert.NoError(t, err)
 		defer db.Close()
 
-		get := mock.ExpectPrepare(regexp.QuoteMeta(`INSERT INTO books (title, authors, publisher, isbn, price, quantity, created_by) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, title, authors, publisher, isbn, price, quantity, created_by, created_at;`))
-		get.ExpectQuery().
-			WithArgs(mockData).
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO works (title, authors) VALUES ($1, $2) RETURNING id;`)).
 			WillReturnError(&pq.Error{Message: "db connection error"})
+		mock.ExpectRollback()
 
 		query := NewDB(db)
 
 		// Act
-		result, err := query.InsertBook(mockData)
+		result, err := query.InsertBook(context.Background(), mockData)
 
 		// Assert
 		assert.NotNil(t, err)
@@ -112,13 +236,13 @@ func TestSelectAllBooks(t *testing.T) {
 		defer db.Close()
 
 		mockCreated_at := time.Now()
-		row := sqlmock.NewRows([]string{"id", "title", "authors", "publisher", "isbn", "price", "quantity", "created_by", "created_at"})
+		row := sqlmock.NewRows([]string{"id", "title", "authors", "publisher", "isbn", "price", "quantity", "created_by", "created_at", "work_id", "format", "publication_date", "rating_count", "rating_sum"})
 		n := 2
 		for i := 0; i < 2; i++ {
-			row.AddRow(i+1, mockData[i].Title, pq.Array(mockData[i].Authors), mockData[i].Publisher, mockData[i].Isbn, mockData[0].Price, mockData[0].Quantity, mockData[i].Created_by, mockCreated_at)
+			row.AddRow(i+1, mockData[i].Title, pq.Array(mockData[i].Authors), mockData[i].Publisher, mockData[i].Isbn, mockData[0].Price, mockData[0].Quantity, mockData[i].Created_by, mockCreated_at, 1, "", "", 0, 0)
 		}
 
-		get := mock.ExpectPrepare(regexp.QuoteMeta(`SELECT id, title, authors, publisher, isbn, price, quantity, created_by, created_at FROM books ORDER BY id LIMIT $1 OFFSET $2;`))
+		get := mock.ExpectPrepare(regexp.QuoteMeta(`SELECT id, title, authors, publisher, isbn, price, quantity, created_by, created_at, work_id, format, COALESCE(to_char(publication_date, 'YYYY-MM-DD'), ''), rating_count, rating_sum FROM books ORDER BY id LIMIT $1 OFFSET $2;`))
 		get.ExpectQuery().
 			WithArgs().
 			WillReturnRows(row)
@@ -126,7 +250,7 @@ func TestSelectAllBooks(t *testing.T) {
 		query := NewDB(db)
 
 		// Act
-		results, err := query.SelectAllBooks(params)
+		results, err := query.SelectAllBooks(context.Background(), params)
 
 		// Assert
 		assert.Nil(t, err)
@@ -145,7 +269,7 @@ func TestSelectAllBooks(t *testing.T) {
 		assert.NoError(t, err)
 		defer db.Close()
 
-		get := mock.ExpectPrepare(regexp.QuoteMeta(`SELECT id, title, authors, publisher, isbn, price, quantity, created_by, created_at FROM books ORDER BY id LIMIT $1 OFFSET $2;`))
+		get := mock.ExpectPrepare(regexp.QuoteMeta(`SELECT id, title, authors, publisher, isbn, price, quantity, created_by, created_at, work_id, format, COALESCE(to_char(publication_date, 'YYYY-MM-DD'), ''), rating_count, rating_sum FROM books ORDER BY id LIMIT $1 OFFSET $2;`))
 		get.ExpectQuery().
 			WithArgs().
 			WillReturnError(&pq.Error{Message: "db connection error"})
@@ -153,7 +277,7 @@ func TestSelectAllBooks(t *testing.T) {
 		query := NewDB(db)
 
 		// Act
-		results, err := query.SelectAllBooks(params)
+		results, err := query.SelectAllBooks(context.Background(), params)
 
 		// Assert
 		assert.NotNil(t, err)
@@ -180,11 +304,11 @@ func TestSelectBookByID(t *testing.T) {
 		assert.NoError(t, err)
 		defer db.Close()
 
-		row := sqlmock.NewRows([]string{"id", "title", "authors", "publisher", "isbn", "price", "quantity", "created_by", "created_at"})
+		row := sqlmock.NewRows([]string{"id", "title", "authors", "publisher", "isbn", "price", "quantity", "created_by", "created_at", "work_id", "format", "publication_date", "rating_count", "rating_sum"})
 		mockCreated_at := time.Now()
-		row.AddRow(1, mockData.Title, pq.Array(mockData.Authors), mockData.Publisher, mockData.Isbn, mockData.Price, mockData.Quantity, mockData.Created_by, mockCreated_at)
+		row.AddRow(1, mockData.Title, pq.Array(mockData.Authors), mockData.Publisher, mockData.Isbn, mockData.Price, mockData.Quantity, mockData.Created_by, mockCreated_at, 1, "", "", 0, 0)
 
-		get := mock.ExpectPrepare(regexp.QuoteMeta(`SELECT * FROM books WHERE id = $1;`))
+		get := mock.ExpectPrepare(regexp.QuoteMeta(`SELECT id, title, authors, publisher, isbn, price, quantity, created_by, created_at, work_id, format, COALESCE(to_char(publication_date, 'YYYY-MM-DD'), ''), rating_count, rating_sum FROM books WHERE id = $1;`))
 		get.ExpectQuery().
 			WithArgs(1).
 			WillReturnRows(row)
@@ -192,7 +316,7 @@ func TestSelectBookByID(t *testing.T) {
 		query := NewDB(db)
 
 		// Act
-		result, err := query.SelectBookByID(id)
+		result, err := query.SelectBookByID(context.Background(), id)
 
 		// Assert
 		assert.Nil(t, err)
@@ -214,13 +338,13 @@ func TestSelectBookByID(t *testing.T) {
 		defer db.Close()
 
 		mockData := RequestBook{}
-		row := sqlmock.NewRows([]string{"id", "title", "authors", "publisher", "isbn", "price", "quantity", "created_by", "created_at"})
+		row := sqlmock.NewRows([]string{"id", "title", "authors", "publisher", "isbn", "price", "quantity", "created_by", "created_at", "work_id", "format", "publication_date", "rating_count", "rating_sum"})
 
 		id := uint64(1)
 		mockCreated_at := time.Now()
-		row.AddRow(1, mockData.Title, pq.Array(mockData.Authors), mockData.Publisher, mockData.Isbn, mockData.Price, mockData.Quantity, mockData.Created_by, mockCreated_at)
+		row.AddRow(1, mockData.Title, pq.Array(mockData.Authors), mockData.Publisher, mockData.Isbn, mockData.Price, mockData.Quantity, mockData.Created_by, mockCreated_at, 1, "", "", 0, 0)
 
-		get := mock.ExpectPrepare(regexp.QuoteMeta(`SELECT * FROM books WHERE id = $1;`))
+		get := mock.ExpectPrepare(regexp.QuoteMeta(`SELECT id, title, authors, publisher, isbn, price, quantity, created_by, created_at, work_id, format, COALESCE(to_char(publication_date, 'YYYY-MM-DD'), ''), rating_count, rating_sum FROM books WHERE id = $1;`))
 		get.ExpectQuery().
 			WithArgs(id).
 			WillReturnError(&pq.Error{Message: "db connection error"})
@@ -228,7 +352,7 @@ func TestSelectBookByID(t *testing.T) {
 		query := NewDB(db)
 
 		// Act
-		result, err := query.SelectBookByID(id)
+		result, err := query.SelectBookByID(context.Background(), id)
 
 		// Assert
 		assert.NotNil(t, err)
@@ -255,18 +379,26 @@ func TestUpdateBook(t *testing.T) {
 		defer db.Close()
 
 		mockCreated_at := time.Now()
-		row := sqlmock.NewRows([]string{"id", "title", "authors", "publisher", "isbn", "price", "quantity", "created_by", "created_at"})
-		row.AddRow(id, mockData.Title, pq.Array(mockData.Authors), mockData.Publisher, mockData.Isbn, mockData.Price, mockData.Quantity, mockData.Created_by, mockCreated_at)
+		row := sqlmock.NewRows([]string{"id", "title", "authors", "publisher", "isbn", "price", "quantity", "created_by", "created_at", "work_id", "format", "publication_date", "rating_count", "rating_sum"})
+		row.AddRow(id, mockData.Title, pq.Array(mockData.Authors), mockData.Publisher, mockData.Isbn, mockData.Price, mockData.Quantity, mockData.Created_by, mockCreated_at, 1, "", "", 0, 0)
 
-		get := mock.ExpectPrepare(regexp.QuoteMeta(`UPDATE books SET title = $1, authors = $2, publisher = $3, isbn = $4, price = $5, quantity = $6, created_by = $7 WHERE id = $8 RETURNING id, title, authors, publisher, isbn, price, quantity, created_by, created_at;`))
-		get.ExpectQuery().
-			WithArgs(mockData.Title, pq.Array(mockData.Authors), mockData.Publisher, mockData.Isbn, mockData.Price, mockData.Quantity, mockData.Created_by, id).
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE books SET title = $1, authors = $2, publisher = $3, isbn = $4, price = $5, quantity = $6, created_by = $7, work_id = COALESCE($8, work_id), format = $9, publication_date = NULLIF($10, '')::date WHERE id = $11 RETURNING id, title`)).
+			WithArgs(mockData.Title, pq.Array(mockData.Authors), mockData.Publisher, mockData.Isbn, mockData.Price, mockData.Quantity, mockData.Created_by, nil, "", "", id).
 			WillReturnRows(row)
+		expectLinkBookContributors(mock, id, mockData.Authors)
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE works SET title = $1, authors = $2 WHERE id = $3;`)).
+			WithArgs(mockData.Title, pq.Array(mockData.Authors), 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE books SET title = $1, authors = $2 WHERE work_id = $3 AND id <> $4 RETURNING id, publisher;`)).
+			WithArgs(mockData.Title, pq.Array(mockData.Authors), 1, id).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "publisher"}))
+		mock.ExpectCommit()
 
 		query := NewDB(db)
 
 		// Act
-		result, err := query.UpdateBook(id, mockData)
+		result, err := query.UpdateBook(context.Background(), id, mockData)
 
 		// Assert
 		assert.Nil(t, err)
@@ -298,15 +430,15 @@ func TestUpdateBook(t *testing.T) {
 		assert.NoError(t, err)
 		defer db.Close()
 
-		get := mock.ExpectPrepare(regexp.QuoteMeta(`UPDATE books SET title = $1, authors = $2, publisher = $3, isbn = $4, price = $5, quantity = $6, created_by = $7 WHERE id = $8 RETURNING id, title, authors, publisher, isbn, price, quantity, created_by, created_at;`))
-		get.ExpectQuery().
-			WithArgs(id, mockData).
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE books SET title = $1, authors = $2`)).
 			WillReturnError(&pq.Error{Message: "db connection error"})
+		mock.ExpectRollback()
 
 		query := NewDB(db)
 
 		// Act
-		result, err := query.UpdateBook(id, mockData)
+		result, err := query.UpdateBook(context.Background(), id, mockData)
 
 		// Assert j
 		assert.NotNil(t, err)
@@ -315,7 +447,7 @@ func TestUpdateBook(t *testing.T) {
 }
 
 func TestDeleteBook(t *testing.T) {
-	t.Run("TestDeleteBookShouldReturnNoError", func(t *testing.T) {
+	t.Run("TestDeleteBookShouldDeleteWorkWithoutEditions", func(t *testing.T) {
 		// Arrange
 		db, mock, err := sqlmock.New()
 		assert.NoError(t, err)
@@ -323,18 +455,45 @@ func TestDeleteBook(t *testing.T) {
 
 		id := uint64(1)
 
-		get := mock.ExpectPrepare(regexp.QuoteMeta(`DELETE FROM books WHERE id = $1;`))
-		get.ExpectExec().
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`DELETE FROM books WHERE id = $1 RETURNING work_id;`)).
 			WithArgs(id).
-			WillReturnResult(sqlmock.NewResult(1, 1))
+			WillReturnRows(sqlmock.NewRows([]string{"work_id"}).AddRow(7))
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM works WHERE id = $1 AND NOT EXISTS (SELECT 1 FROM books WHERE work_id = $1);`)).
+			WithArgs(7).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		query := NewDB(db)
+
+		// Act
+		err = query.DeleteBook(context.Background(), id)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestDeleteBookShouldIgnoreMissingBook", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`DELETE FROM books WHERE id = $1 RETURNING work_id;`)).
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"work_id"}))
+		mock.ExpectRollback()
 
 		query := NewDB(db)
 
 		// Act
-		err = query.DeleteBook(id)
+		err = query.DeleteBook(context.Background(), 1)
 
 		// Assert
 		assert.Nil(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
 	t.Run("TestDeleteBookShouldReturnError", func(t *testing.T) {
@@ -345,17 +504,104 @@ func TestDeleteBook(t *testing.T) {
 
 		id := uint64(1)
 
-		get := mock.ExpectPrepare(regexp.QuoteMeta(`DELETE FROM books WHERE id = $1;`))
-		get.ExpectExec().
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`DELETE FROM books WHERE id = $1 RETURNING work_id;`)).
 			WithArgs(id).
 			WillReturnError(&pq.Error{Message: "invalid id"})
+		mock.ExpectRollback()
 
 		query := NewDB(db)
 
 		// Act
-		err = query.DeleteBook(id)
+		err = query.DeleteBook(context.Background(), id)
 
 		// Assert
 		assert.NotNil(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestSelectBookByIsbn(t *testing.T) {
+	t.Run("TestSelectBookByIsbnShouldReturnNoError", func(t *testing.T) {
+		// Arrange
+		mockData := RequestBook{
+			Title:      "mockData",
+			Authors:    []string{"Author A", "Author B"},
+			Publisher:  "mockPublisher",
+			Isbn:       "9780000000017",
+			Price:      1000,
+			Quantity:   10,
+			Created_by: "mockAdmin",
+		}
+
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		row := sqlmock.NewRows([]string{"id", "title", "authors", "publisher", "isbn", "price", "quantity", "created_by", "created_at", "work_id", "format", "publication_date", "rating_count", "rating_sum"}).
+			AddRow(1, mockData.Title, pq.Array(mockData.Authors), mockData.Publisher, mockData.Isbn, mockData.Price, mockData.Quantity, mockData.Created_by, time.Now(), 1, "", "", 0, 0)
+
+		get := mock.ExpectPrepare(regexp.QuoteMeta(`SELECT id, title, authors, publisher, isbn, price, quantity, created_by, created_at, work_id, format, COALESCE(to_char(publication_date, 'YYYY-MM-DD'), ''), rating_count, rating_sum FROM books WHERE isbn = $1 ORDER BY id LIMIT 1;`))
+		get.ExpectQuery().
+			WithArgs(mockData.Isbn).
+			WillReturnRows(row)
+
+		query := NewDB(db)
+
+		// Act
+		result, err := query.SelectBookByIsbn(context.Background(), mockData.Isbn)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(1), result.Id)
+		assert.Equal(t, mockData.Isbn, result.Isbn)
+		assert.Equal(t, mockData.Authors, result.Authors)
+	})
+
+	t.Run("TestSelectBookByIsbnShouldReturnError", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		get := mock.ExpectPrepare(regexp.QuoteMeta(`SELECT id, title, authors, publisher, isbn, price, quantity, created_by, created_at, work_id, format, COALESCE(to_char(publication_date, 'YYYY-MM-DD'), ''), rating_count, rating_sum FROM books WHERE isbn = $1 ORDER BY id LIMIT 1;`))
+		get.ExpectQuery().
+			WithArgs("9780000000017").
+			WillReturnError(sql.ErrNoRows)
+
+		query := NewDB(db)
+
+		// Act
+		result, err := query.SelectBookByIsbn(context.Background(), "9780000000017")
+
+		// Assert
+		assert.Equal(t, sql.ErrNoRows, err)
+		assert.Nil(t, result)
+	})
+}
+
+func TestSelectFilteredBooks(t *testing.T) {
+	t.Run("TestSelectFilteredBooksShouldMatchCategoryDescendantsAndTag", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		rows := sqlmock.NewRows([]string{"id", "title", "authors", "publisher", "isbn", "price", "quantity", "created_by", "created_at", "work_id", "format", "publication_date", "rating_count", "rating_sum"}).
+			AddRow(4, "mockTitle", pq.Array([]string{"mockAuthor"}), "mockPublisher", "1234567890", 1000, 10, "Admin", time.Now(), 1, "", "", 0, 0)
+		get := mock.ExpectPrepare(regexp.QuoteMeta(`AND c.path LIKE (SELECT path FROM categories WHERE id = $1) || '%'`))
+		get.ExpectQuery().
+			WithArgs(3, "science fiction", 10, 0).
+			WillReturnRows(rows)
+
+		query := NewDB(db)
+
+		// Act
+		results, err := query.SelectFilteredBooks(context.Background(), RequestBookFilter{CategoryId: 3, Tag: " Science  Fiction"}, GetAllParams{Limit: 10})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Len(t, results, 1)
+		assert.Equal(t, uint64(4), results[0].Id)
 	})
 }
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"net/http"
 	"strconv"
 
@@ -9,11 +10,13 @@ import (
 )
 
 type BookHandlrQueries interface {
-	AddBook(req RequestBook) (*ResponseBook, error)
-	ListAllBooks(params GetAllParams) ([]ResponseBook, error)
-	GetBookByID(id uint64) (*ResponseBook, error)
-	PutBook(id uint64, req RequestBook) (*ResponseBook, error)
-	DelBook(id uint64) error
+	AddBook(ctx context.Context, req RequestBook) (*ResponseBook, error)
+	ListAllBooks(ctx context.Context, params GetAllParams) ([]ResponseBook, error)
+	FilterBooks(ctx context.Context, filter RequestBookFilter, params GetAllParams) ([]ResponseBook, error)
+	ListBooksByWork(ctx context.Context, params GetAllParams) ([]ResponseWork, error)
+	GetBookByID(ctx context.Context, id uint64) (*ResponseBook, error)
+	PutBook(ctx context.Context, id uint64, req RequestBook) (*ResponseBook, error)
+	DelBook(ctx context.Context, id uint64) error
 }
 
 type BookHandlr struct {
@@ -32,12 +35,12 @@ func (h BookHandlr) AddBook(ctx echo.Context) error {
 		return ctx.NoContent(http.StatusBadRequest)
 	}
 
-	res, err := h.handler.AddBook(req)
+	res, err := h.handler.AddBook(ctx.Request().Context(), req)
 	if err != nil {
 		if cmErr, ok := err.(*c.Err); ok {
 			return ctx.NoContent(cmErr.Code)
 		}
-		h.log.Errorf("Error AddBook Handler : %v", err)
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error AddBook Handler : %v", err)
 		return ctx.NoContent(http.StatusInternalServerError)
 	}
 	return ctx.JSON(http.StatusCreated, res)
@@ -50,17 +53,49 @@ func (h BookHandlr) ListAllBooks(ctx echo.Context) error {
 		return ctx.NoContent(http.StatusBadRequest)
 	}
 
+	filter := RequestBookFilter{}
+	err = (&echo.DefaultBinder{}).BindQueryParams(ctx, &filter)
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
 	params := GetAllParams{
 		Limit:  req.PageSize,
 		Offset: (req.PageId - 1) * req.PageSize,
 	}
 
-	res, err := h.handler.ListAllBooks(params)
+	// ?group=work pages through works instead of editions; it cannot be
+	// combined with the edition filters.
+	switch ctx.QueryParam("group") {
+	case "":
+	case GroupByWork:
+		if filter.CategoryId != 0 || filter.Tag != "" {
+			return ctx.NoContent(http.StatusBadRequest)
+		}
+		works, err := h.handler.ListBooksByWork(ctx.Request().Context(), params)
+		if err != nil {
+			if cmErr, ok := err.(*c.Err); ok {
+				return ctx.NoContent(cmErr.Code)
+			}
+			c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error ListAllBooks Handler : %v", err)
+			return ctx.NoContent(http.StatusInternalServerError)
+		}
+		return ctx.JSON(http.StatusOK, works)
+	default:
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	var res []ResponseBook
+	if filter.CategoryId == 0 && filter.Tag == "" {
+		res, err = h.handler.ListAllBooks(ctx.Request().Context(), params)
+	} else {
+		res, err = h.handler.FilterBooks(ctx.Request().Context(), filter, params)
+	}
 	if err != nil {
 		if cmErr, ok := err.(*c.Err); ok {
 			return ctx.NoContent(cmErr.Code)
 		}
-		h.log.Errorf("Error ListAllBooks Handler : %v", err)
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error ListAllBooks Handler : %v", err)
 		return ctx.NoContent(http.StatusInternalServerError)
 	}
 	return ctx.JSON(http.StatusOK, res)
@@ -73,12 +108,12 @@ func (h BookHandlr) GetBookByID(ctx echo.Context) error {
 		return ctx.NoContent(http.StatusBadRequest)
 	}
 
-	res, err := h.handler.GetBookByID(uint64(id))
+	res, err := h.handler.GetBookByID(ctx.Request().Context(), uint64(id))
 	if err != nil {
 		if cmErr, ok := err.(*c.Err); ok {
 			return ctx.NoContent(cmErr.Code)
 		}
-		h.log.Errorf("Error GetBookByID Handler : %v", err)
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error GetBookByID Handler : %v", err)
 		return ctx.NoContent(http.StatusInternalServerError)
 	}
 	return ctx.JSON(http.StatusOK, res)
@@ -97,12 +132,12 @@ func (h BookHandlr) PutBook(ctx echo.Context) error {
 		return ctx.NoContent(http.StatusBadRequest)
 	}
 
-	res, err := h.handler.PutBook(uint64(id), req)
+	res, err := h.handler.PutBook(ctx.Request().Context(), uint64(id), req)
 	if err != nil {
 		if cmErrm, ok := err.(*c.Err); ok {
 			return ctx.NoContent(cmErrm.Code)
 		}
-		h.log.Errorf("Error PutBook Handler : %v", err)
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error PutBook Handler : %v", err)
 		return ctx.NoContent(http.StatusInternalServerError)
 	}
 	return ctx.JSON(http.StatusOK, res)
@@ -115,12 +150,12 @@ func (h BookHandlr) DelBook(ctx echo.Context) error {
 		return ctx.NoContent(http.StatusBadRequest)
 	}
 
-	err = h.handler.DelBook(uint64(id))
+	err = h.handler.DelBook(ctx.Request().Context(), uint64(id))
 	if err != nil {
 		if cmErr, ok := err.(*c.Err); ok {
 			return ctx.NoContent(cmErr.Code)
 		}
-		h.log.Errorf("Error DelBook Handler : %v", err)
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error DelBook Handler : %v", err)
 		return ctx.NoContent(http.StatusInternalServerError)
 	}
 	return ctx.JSON(http.StatusOK, "Deleted Successfully")
@@ -1,7 +1,9 @@
 //go:build unit
+
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -17,14 +19,17 @@ import (
 )
 
 type BookHandlrSuccess struct {
-	addBookCalled   bool
-	getBookByIDCalled   bool
-	listAllBooksCalled bool
-	putBookCalled   bool
-	delBookCalled   bool
+	addBookCalled         bool
+	getBookByIDCalled     bool
+	listAllBooksCalled    bool
+	putBookCalled         bool
+	delBookCalled         bool
+	filterBooksCalled     bool
+	listBooksByWorkCalled bool
+	filter                RequestBookFilter
 }
 
-func (h *BookHandlrSuccess) AddBook(req RequestBook) (*ResponseBook, error) {
+func (h *BookHandlrSuccess) AddBook(ctx context.Context, req RequestBook) (*ResponseBook, error) {
 	h.addBookCalled = true
 	res := &ResponseBook{
 		Id:         uint64(1),
@@ -40,7 +45,7 @@ func (h *BookHandlrSuccess) AddBook(req RequestBook) (*ResponseBook, error) {
 	return res, nil
 }
 
-func (h *BookHandlrSuccess) GetBookByID(id uint64) (*ResponseBook, error) {
+func (h *BookHandlrSuccess) GetBookByID(ctx context.Context, id uint64) (*ResponseBook, error) {
 	h.getBookByIDCalled = true
 	res := &ResponseBook{
 		Id:         1,
@@ -56,7 +61,7 @@ func (h *BookHandlrSuccess) GetBookByID(id uint64) (*ResponseBook, error) {
 	return res, nil
 }
 
-func (h *BookHandlrSuccess) ListAllBooks(params GetAllParams) ([]ResponseBook, error) {
+func (h *BookHandlrSuccess) ListAllBooks(ctx context.Context, params GetAllParams) ([]ResponseBook, error) {
 	h.listAllBooksCalled = true
 	res := []ResponseBook{
 		{Id: 1,
@@ -84,7 +89,7 @@ func (h *BookHandlrSuccess) ListAllBooks(params GetAllParams) ([]ResponseBook, e
 	return res, nil
 }
 
-func (h *BookHandlrSuccess) PutBook(id uint64, req RequestBook) (*ResponseBook, error) {
+func (h *BookHandlrSuccess) PutBook(ctx context.Context, id uint64, req RequestBook) (*ResponseBook, error) {
 	h.putBookCalled = true
 	res := &ResponseBook{
 		Id:         id,
@@ -100,41 +105,60 @@ func (h *BookHandlrSuccess) PutBook(id uint64, req RequestBook) (*ResponseBook,
 	return res, nil
 }
 
-func (h *BookHandlrSuccess) DelBook(id uint64) error {
+func (h *BookHandlrSuccess) FilterBooks(ctx context.Context, filter RequestBookFilter, params GetAllParams) ([]ResponseBook, error) {
+	h.filterBooksCalled = true
+	h.filter = filter
+	return []ResponseBook{{Id: 1, Title: "mockTitle"}}, nil
+}
+
+func (h *BookHandlrSuccess) ListBooksByWork(ctx context.Context, params GetAllParams) ([]ResponseWork, error) {
+	h.listBooksByWorkCalled = true
+	return []ResponseWork{{Id: 1, Title: "mockTitle", Editions: []ResponseBook{{Id: 1, WorkId: 1}, {Id: 2, WorkId: 1}}}}, nil
+}
+
+func (h *BookHandlrSuccess) DelBook(ctx context.Context, id uint64) error {
 	h.delBookCalled = true
 	return nil
 }
 
 type BookHandlrError struct {
-	addBookCalled   bool
-	getBookByIDCalled   bool
+	addBookCalled      bool
+	getBookByIDCalled  bool
 	listAllBooksCalled bool
-	putBookCalled   bool
-	delBookCalled   bool
-	statusCodeError int
+	putBookCalled      bool
+	delBookCalled      bool
+	statusCodeError    int
 }
 
-func (h *BookHandlrError) AddBook(req RequestBook) (*ResponseBook, error) {
+func (h *BookHandlrError) AddBook(ctx context.Context, req RequestBook) (*ResponseBook, error) {
 	h.addBookCalled = true
 	return nil, &c.Err{Code: h.statusCodeError}
 }
 
-func (h *BookHandlrError) GetBookByID(id uint64) (*ResponseBook, error) {
+func (h *BookHandlrError) GetBookByID(ctx context.Context, id uint64) (*ResponseBook, error) {
 	h.getBookByIDCalled = true
 	return nil, &c.Err{Code: h.statusCodeError}
 }
 
-func (h *BookHandlrError) ListAllBooks(params GetAllParams) ([]ResponseBook, error) {
+func (h *BookHandlrError) ListAllBooks(ctx context.Context, params GetAllParams) ([]ResponseBook, error) {
 	h.listAllBooksCalled = true
 	return nil, &c.Err{Code: h.statusCodeError}
 }
 
-func (h *BookHandlrError) PutBook(id uint64, req RequestBook) (*ResponseBook, error) {
+func (h *BookHandlrError) PutBook(ctx context.Context, id uint64, req RequestBook) (*ResponseBook, error) {
 	h.putBookCalled = true
 	return nil, &c.Err{Code: h.statusCodeError}
 }
 
-func (h *BookHandlrError) DelBook(uid uint64) error {
+func (h *BookHandlrError) FilterBooks(ctx context.Context, filter RequestBookFilter, params GetAllParams) ([]ResponseBook, error) {
+	return nil, &c.Err{Code: h.statusCodeError}
+}
+
+func (h *BookHandlrError) ListBooksByWork(ctx context.Context, params GetAllParams) ([]ResponseWork, error) {
+	return nil, &c.Err{Code: h.statusCodeError}
+}
+
+func (h *BookHandlrError) DelBook(ctx context.Context, uid uint64) error {
 	h.delBookCalled = true
 	return &c.Err{Code: h.statusCodeError}
 }
@@ -244,6 +268,101 @@ func TestListAllBooksHandler(t *testing.T) {
 		}
 	})
 
+	t.Run("TestListAllBooksHandlerShouldFilterByCategoryAndTag", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/books?category=3&tag=Golang&page_id=1&page_size=10", nil)
+		rec := httptest.NewRecorder()
+
+		e := echo.New()
+		ctx := e.NewContext(req, rec)
+
+		handlrServ := &BookHandlrSuccess{}
+		log := logrus.New()
+		handler := NewBookHandlr(handlrServ, log)
+
+		// Act
+		err := handler.ListAllBooks(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.True(t, handlrServ.filterBooksCalled)
+			assert.False(t, handlrServ.listAllBooksCalled)
+			assert.Equal(t, RequestBookFilter{CategoryId: 3, Tag: "Golang"}, handlrServ.filter)
+			assert.Equal(t, http.StatusOK, rec.Code)
+		}
+	})
+
+	t.Run("TestListAllBooksHandlerShouldGroupByWork", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/books?group=work&page_id=1&page_size=10", nil)
+		rec := httptest.NewRecorder()
+
+		e := echo.New()
+		ctx := e.NewContext(req, rec)
+
+		handlrServ := &BookHandlrSuccess{}
+		log := logrus.New()
+		handler := NewBookHandlr(handlrServ, log)
+
+		// Act
+		err := handler.ListAllBooks(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.True(t, handlrServ.listBooksByWorkCalled)
+			assert.False(t, handlrServ.listAllBooksCalled)
+			assert.Equal(t, http.StatusOK, rec.Code)
+
+			res := []ResponseWork{}
+			json.Unmarshal(rec.Body.Bytes(), &res)
+			assert.Len(t, res, 1)
+			assert.Len(t, res[0].Editions, 2)
+		}
+	})
+
+	t.Run("TestListAllBooksHandlerShouldReturnHTTPStatus400OnGroupWithFilter", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/books?group=work&tag=Golang", nil)
+		rec := httptest.NewRecorder()
+
+		e := echo.New()
+		ctx := e.NewContext(req, rec)
+
+		handlrServ := &BookHandlrSuccess{}
+		log := logrus.New()
+		handler := NewBookHandlr(handlrServ, log)
+
+		// Act
+		err := handler.ListAllBooks(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.False(t, handlrServ.listBooksByWorkCalled)
+		}
+	})
+
+	t.Run("TestListAllBooksHandlerShouldReturnHTTPStatus400OnInvalidCategory", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/books?category=fiction", nil)
+		rec := httptest.NewRecorder()
+
+		e := echo.New()
+		ctx := e.NewContext(req, rec)
+
+		handlrServ := &BookHandlrSuccess{}
+		log := logrus.New()
+		handler := NewBookHandlr(handlrServ, log)
+
+		// Act
+		err := handler.ListAllBooks(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		}
+	})
+
 	t.Run("TestListAllBooksHandlerShouldReturnHTTPStatus500", func(t *testing.T) {
 		// Arrange
 		req := httptest.NewRequest(http.MethodGet, "/books", nil)
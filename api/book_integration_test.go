@@ -35,7 +35,7 @@ func setupServerBook(t *testing.T) (*sql.DB, func()) {
 	go func(e *echo.Echo, db *sql.DB) {
 		log := logrus.New()
 		storage := NewDB(db)
-		service := NewBookService(storage, log)
+		service := NewBookService(storage, nil, log)
 		handler := NewBookHandlr(service, log)
 
 		e.POST("/books", handler.AddBook)
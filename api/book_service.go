@@ -1,51 +1,116 @@
 package api
 
 import (
+	"context"
 	"database/sql"
 	"net/http"
 
 	c "github.com/paquesqueue/bookstore/common"
+	"github.com/paquesqueue/bookstore/tracing"
 )
 
 type BookQueries interface {
-	InsertBook(req RequestBook) (*ResponseBook, error)
-	SelectAllBooks(params GetAllParams) ([]ResponseBook, error)
-	SelectBookByID(id uint64) (*ResponseBook, error)
-	UpdateBook(id uint64, req RequestBook) (*ResponseBook, error)
-	DeleteBook(id uint64) error
+	InsertBook(ctx context.Context, req RequestBook) (*ResponseBook, error)
+	SelectAllBooks(ctx context.Context, params GetAllParams) ([]ResponseBook, error)
+	SelectFilteredBooks(ctx context.Context, filter RequestBookFilter, params GetAllParams) ([]ResponseBook, error)
+	SelectBookByID(ctx context.Context, id uint64) (*ResponseBook, error)
+	UpdateBook(ctx context.Context, id uint64, req RequestBook) (*ResponseBook, error)
+	DeleteBook(ctx context.Context, id uint64) error
+	SelectCoverVersions(ctx context.Context, bookIds []uint64) (map[uint64]string, error)
+	SelectAllWorks(ctx context.Context, params GetAllParams) ([]ResponseWork, error)
+	SelectWorkEditions(ctx context.Context, workIds []uint64) (map[uint64][]ResponseBook, error)
+	SelectBookWatchers(ctx context.Context, bookId uint64) ([]string, error)
 }
 
 type BookServices struct {
-	query BookQueries
-	log   c.Log
+	query    BookQueries
+	notifier ListNotifier
+	log      c.Log
 }
 
-func NewBookService(s BookQueries, l c.Log) BookServices {
-	return BookServices{s, l}
+func NewBookService(s BookQueries, n ListNotifier, l c.Log) BookServices {
+	return BookServices{s, n, l}
 }
 
-func (s BookServices) AddBook(req RequestBook) (*ResponseBook, error) {
-	res, err := s.query.InsertBook(req)
+func (s BookServices) AddBook(ctx context.Context, req RequestBook) (*ResponseBook, error) {
+	ctx, span := tracing.Start(ctx, "BookServices.AddBook")
+	defer span.End()
+
+	if err := validEdition(req); err != nil {
+		return nil, err
+	}
+
+	res, err := s.query.InsertBook(ctx, req)
 	if err != nil {
-		s.log.Errorf("Error InsertBook : %v", err)
+		c.LogFrom(ctx, s.log).Errorf("Error InsertBook : %v", err)
+		if err == errWorkNotFound {
+			return nil, &c.Err{Code: http.StatusBadRequest, Remark: "Error Work Not Found", Original: err}
+		}
 		return nil, &c.Err{Code: http.StatusInternalServerError, Remark: "Error AddBook Service", Original: err}
 	}
 	return res, nil
 }
 
-func (s BookServices) ListAllBooks(params GetAllParams) ([]ResponseBook, error) {
-	res, err := s.query.SelectAllBooks(params)
+func (s BookServices) ListAllBooks(ctx context.Context, params GetAllParams) ([]ResponseBook, error) {
+	ctx, span := tracing.Start(ctx, "BookServices.ListAllBooks")
+	defer span.End()
+
+	res, err := s.query.SelectAllBooks(ctx, params)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error SelectAllBooks : %v", err)
+		return nil, &c.Err{Code: http.StatusInternalServerError, Remark: "Error ListBooks Service", Original: err}
+	}
+
+	err = s.addCoverUrls(ctx, res)
+	if err != nil {
+		return nil, &c.Err{Code: http.StatusInternalServerError, Remark: "Error ListBooks Service", Original: err}
+	}
+	return res, nil
+}
+
+func (s BookServices) FilterBooks(ctx context.Context, filter RequestBookFilter, params GetAllParams) ([]ResponseBook, error) {
+	ctx, span := tracing.Start(ctx, "BookServices.FilterBooks")
+	defer span.End()
+
+	res, err := s.query.SelectFilteredBooks(ctx, filter, params)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error SelectFilteredBooks : %v", err)
+		return nil, &c.Err{Code: http.StatusInternalServerError, Remark: "Error ListBooks Service", Original: err}
+	}
+
+	err = s.addCoverUrls(ctx, res)
+	if err != nil {
+		return nil, &c.Err{Code: http.StatusInternalServerError, Remark: "Error ListBooks Service", Original: err}
+	}
+	return res, nil
+}
+
+// ListBooksByWork lists works with their editions, paginated by work.
+func (s BookServices) ListBooksByWork(ctx context.Context, params GetAllParams) ([]ResponseWork, error) {
+	ctx, span := tracing.Start(ctx, "BookServices.ListBooksByWork")
+	defer span.End()
+
+	res, err := s.query.SelectAllWorks(ctx, params)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error SelectAllWorks : %v", err)
+		return nil, &c.Err{Code: http.StatusInternalServerError, Remark: "Error ListBooks Service", Original: err}
+	}
+
+	err = addWorkEditions(ctx, s.query, res)
 	if err != nil {
-		s.log.Errorf("Error SelectAllBooks : %v", err)
+		c.LogFrom(ctx, s.log).Errorf("Error addWorkEditions : %v", err)
 		return nil, &c.Err{Code: http.StatusInternalServerError, Remark: "Error ListBooks Service", Original: err}
 	}
 	return res, nil
 }
 
-func (s BookServices) GetBookByID(id uint64) (*ResponseBook, error) {
-	res, err := s.query.SelectBookByID(id)
+func (s BookServices) GetBookByID(ctx context.Context, id uint64) (*ResponseBook, error) {
+	ctx, span := tracing.Start(ctx, "BookServices.GetBookByID")
+	defer span.End()
+
+	res, err := s.query.SelectBookByID(ctx, id)
 	if err != nil {
-		s.log.Errorf("Error SelectBookByID : %v", err)
+		c.LogFrom(ctx, s.log).Errorf("Error SelectBookByID : %v", err)
 		switch err {
 		case sql.ErrNoRows:
 			return nil, &c.Err{Code: http.StatusNotFound, Remark: "Error Book Not Found", Original: err}
@@ -53,23 +118,89 @@ func (s BookServices) GetBookByID(id uint64) (*ResponseBook, error) {
 			return nil, &c.Err{Code: http.StatusInternalServerError, Remark: "Error GetBook Service", Original: err}
 		}
 	}
-	return res, nil
+
+	books := []ResponseBook{*res}
+	err = s.addCoverUrls(ctx, books)
+	if err != nil {
+		return nil, &c.Err{Code: http.StatusInternalServerError, Remark: "Error GetBook Service", Original: err}
+	}
+	return &books[0], nil
 }
 
-func (s BookServices) PutBook(id uint64, req RequestBook) (*ResponseBook, error) {
-	res, err := s.query.UpdateBook(id, req)
+func (s BookServices) PutBook(ctx context.Context, id uint64, req RequestBook) (*ResponseBook, error) {
+	ctx, span := tracing.Start(ctx, "BookServices.PutBook")
+	defer span.End()
+
+	if err := validEdition(req); err != nil {
+		return nil, err
+	}
+
+	before, err := s.query.SelectBookByID(ctx, id)
 	if err != nil {
-		s.log.Errorf("Error UpdateBook : %v", err)
+		c.LogFrom(ctx, s.log).Errorf("Error SelectBookByID : %v", err)
+		if err == sql.ErrNoRows {
+			return nil, &c.Err{Code: http.StatusNotFound, Remark: "Error Book Not Found", Original: err}
+		}
 		return nil, &c.Err{Code: http.StatusInternalServerError, Remark: "Error UpdateBook Service", Original: err}
 	}
-	return res, nil
+
+	res, err := s.query.UpdateBook(ctx, id, req)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error UpdateBook : %v", err)
+		if err == errWorkNotFound {
+			return nil, &c.Err{Code: http.StatusBadRequest, Remark: "Error Work Not Found", Original: err}
+		}
+		return nil, &c.Err{Code: http.StatusInternalServerError, Remark: "Error UpdateBook Service", Original: err}
+	}
+	notifyListWatchers(ctx, s.query, s.notifier, s.log, *before, *res)
+
+	books := []ResponseBook{*res}
+	err = s.addCoverUrls(ctx, books)
+	if err != nil {
+		return nil, &c.Err{Code: http.StatusInternalServerError, Remark: "Error UpdateBook Service", Original: err}
+	}
+	return &books[0], nil
 }
 
-func (s BookServices) DelBook(id uint64) error {
-	err := s.query.DeleteBook(id)
+func (s BookServices) DelBook(ctx context.Context, id uint64) error {
+	ctx, span := tracing.Start(ctx, "BookServices.DelBook")
+	defer span.End()
+
+	err := s.query.DeleteBook(ctx, id)
 	if err != nil {
-		s.log.Errorf("Error DeleteBook : %v", err)
+		c.LogFrom(ctx, s.log).Errorf("Error DeleteBook : %v", err)
 		return &c.Err{Code: http.StatusInternalServerError, Remark: "Error DeleteBook Service", Original: err}
 	}
 	return nil
 }
+
+// addCoverUrls fills in the cover URLs of the books that have a cover.
+func (s BookServices) addCoverUrls(ctx context.Context, books []ResponseBook) error {
+	err := addCoverUrls(ctx, s.query, books)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error SelectCoverVersions : %v", err)
+	}
+	return err
+}
+
+type coverVersionQueries interface {
+	SelectCoverVersions(ctx context.Context, bookIds []uint64) (map[uint64]string, error)
+}
+
+func addCoverUrls(ctx context.Context, q coverVersionQueries, books []ResponseBook) error {
+	ids := make([]uint64, len(books))
+	for i, b := range books {
+		ids[i] = b.Id
+	}
+
+	versions, err := q.SelectCoverVersions(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for i, b := range books {
+		if version, ok := versions[b.Id]; ok {
+			books[i].CoverUrls = coverUrls(b.Id, version)
+		}
+	}
+	return nil
+}
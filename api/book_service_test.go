@@ -1,7 +1,11 @@
 //go:build unit
+
 package api
 
 import (
+	"context"
+	"database/sql"
+	"net/http"
 	"testing"
 	"time"
 
@@ -11,14 +15,14 @@ import (
 )
 
 type BookQueriesSuccess struct {
-	insertBookCalled  bool
+	insertBookCalled     bool
 	selectAllBooksCalled bool
-	selectBookByIDCalled  bool
-	updateBookCalled  bool
-	deleteBookCallled bool
+	selectBookByIDCalled bool
+	updateBookCalled     bool
+	deleteBookCallled    bool
 }
 
-func (s *BookQueriesSuccess) InsertBook(req RequestBook) (*ResponseBook, error) {
+func (s *BookQueriesSuccess) InsertBook(ctx context.Context, req RequestBook) (*ResponseBook, error) {
 	s.insertBookCalled = true
 	resp := &ResponseBook{
 		Id:         1,
@@ -34,7 +38,7 @@ func (s *BookQueriesSuccess) InsertBook(req RequestBook) (*ResponseBook, error)
 	return resp, nil
 }
 
-func (s *BookQueriesSuccess) SelectAllBooks(params GetAllParams) ([]ResponseBook, error) {
+func (s *BookQueriesSuccess) SelectAllBooks(ctx context.Context, params GetAllParams) ([]ResponseBook, error) {
 	s.selectAllBooksCalled = true
 	resp := []ResponseBook{
 		{
@@ -63,7 +67,7 @@ func (s *BookQueriesSuccess) SelectAllBooks(params GetAllParams) ([]ResponseBook
 	return resp, nil
 }
 
-func (s *BookQueriesSuccess) SelectBookByID(id uint64) (*ResponseBook, error) {
+func (s *BookQueriesSuccess) SelectBookByID(ctx context.Context, id uint64) (*ResponseBook, error) {
 	s.selectBookByIDCalled = true
 	resp := &ResponseBook{
 		Id:         id,
@@ -79,7 +83,7 @@ func (s *BookQueriesSuccess) SelectBookByID(id uint64) (*ResponseBook, error) {
 	return resp, nil
 }
 
-func (s *BookQueriesSuccess) UpdateBook(id uint64, req RequestBook) (*ResponseBook, error) {
+func (s *BookQueriesSuccess) UpdateBook(ctx context.Context, id uint64, req RequestBook) (*ResponseBook, error) {
 	s.updateBookCalled = true
 	resp := &ResponseBook{
 		Id:         id,
@@ -95,50 +99,95 @@ func (s *BookQueriesSuccess) UpdateBook(id uint64, req RequestBook) (*ResponseBo
 	return resp, nil
 }
 
-func (s *BookQueriesSuccess) DeleteBook(id uint64) error {
+func (s *BookQueriesSuccess) SelectFilteredBooks(ctx context.Context, filter RequestBookFilter, params GetAllParams) ([]ResponseBook, error) {
+	return []ResponseBook{{Id: 1, Title: "mockTitle"}}, nil
+}
+
+func (s *BookQueriesSuccess) DeleteBook(ctx context.Context, id uint64) error {
 	s.deleteBookCallled = true
 	return nil
 }
 
+func (s *BookQueriesSuccess) SelectCoverVersions(ctx context.Context, bookIds []uint64) (map[uint64]string, error) {
+	return map[uint64]string{1: "5f2b9c0d1e3a4b6c"}, nil
+}
+
+func (s *BookQueriesSuccess) SelectAllWorks(ctx context.Context, params GetAllParams) ([]ResponseWork, error) {
+	return []ResponseWork{{Id: 1, Title: "mockTitle"}, {Id: 2, Title: "mockOther"}}, nil
+}
+
+func (s *BookQueriesSuccess) SelectWorkEditions(ctx context.Context, workIds []uint64) (map[uint64][]ResponseBook, error) {
+	return map[uint64][]ResponseBook{
+		1: {
+			{Id: 1, WorkId: 1, Format: EditionHardcover, Price: 1500, Quantity: 3},
+			{Id: 2, WorkId: 1, Format: EditionEbook, Price: 500, Quantity: 0},
+		},
+	}, nil
+}
+
+func (s *BookQueriesSuccess) SelectBookWatchers(ctx context.Context, bookId uint64) ([]string, error) {
+	return []string{"tester"}, nil
+}
+
 type BookQueriesError struct {
-	insertBookCalled  bool
+	insertBookCalled     bool
 	selectAllBooksCalled bool
-	selectBookByIDCalled  bool
-	updateBookCalled  bool
-	deleteBookCallled bool
+	selectBookByIDCalled bool
+	updateBookCalled     bool
+	deleteBookCallled    bool
 }
 
-func (s *BookQueriesError) InsertBook(req RequestBook) (*ResponseBook, error) {
+func (s *BookQueriesError) InsertBook(ctx context.Context, req RequestBook) (*ResponseBook, error) {
 	s.insertBookCalled = true
 	return nil, &c.Err{}
 }
 
-func (s *BookQueriesError) SelectAllBooks(params GetAllParams) ([]ResponseBook, error) {
+func (s *BookQueriesError) SelectAllBooks(ctx context.Context, params GetAllParams) ([]ResponseBook, error) {
 	s.selectAllBooksCalled = true
 	return nil, &c.Err{}
 }
 
-func (s *BookQueriesError) SelectBookByID(id uint64) (*ResponseBook, error) {
+func (s *BookQueriesError) SelectBookByID(ctx context.Context, id uint64) (*ResponseBook, error) {
 	s.selectBookByIDCalled = true
 	return nil, &c.Err{}
 }
 
-func (s *BookQueriesError) UpdateBook(id uint64, req RequestBook) (*ResponseBook, error) {
+func (s *BookQueriesError) UpdateBook(ctx context.Context, id uint64, req RequestBook) (*ResponseBook, error) {
 	s.updateBookCalled = true
 	return nil, &c.Err{}
 }
 
-func (s *BookQueriesError) DeleteBook(id uint64) error {
+func (s *BookQueriesError) SelectFilteredBooks(ctx context.Context, filter RequestBookFilter, params GetAllParams) ([]ResponseBook, error) {
+	return nil, &c.Err{}
+}
+
+func (s *BookQueriesError) DeleteBook(ctx context.Context, id uint64) error {
 	s.deleteBookCallled = true
 	return &c.Err{}
 }
 
+func (s *BookQueriesError) SelectCoverVersions(ctx context.Context, bookIds []uint64) (map[uint64]string, error) {
+	return nil, &c.Err{}
+}
+
+func (s *BookQueriesError) SelectAllWorks(ctx context.Context, params GetAllParams) ([]ResponseWork, error) {
+	return nil, &c.Err{}
+}
+
+func (s *BookQueriesError) SelectWorkEditions(ctx context.Context, workIds []uint64) (map[uint64][]ResponseBook, error) {
+	return nil, &c.Err{}
+}
+
+func (s *BookQueriesError) SelectBookWatchers(ctx context.Context, bookId uint64) ([]string, error) {
+	return nil, &c.Err{}
+}
+
 func TestAddBook(t *testing.T) {
 	t.Run("TestAddBookServiceShouldReturnNoError", func(t *testing.T) {
 		// Arrange
 		query := &BookQueriesSuccess{}
 		log := logrus.New()
-		services := NewBookService(query, log)
+		services := NewBookService(query, nil, log)
 
 		mockData := RequestBook{
 			Title:      "mockTitle",
@@ -151,7 +200,7 @@ func TestAddBook(t *testing.T) {
 		}
 
 		// Act
-		res, err := services.AddBook(mockData)
+		res, err := services.AddBook(context.Background(), mockData)
 
 		// Assert
 		assert.Equal(t, true, query.insertBookCalled)
@@ -173,12 +222,12 @@ func TestAddBook(t *testing.T) {
 		// Arrange
 		query := &BookQueriesError{}
 		log := logrus.New()
-		services := NewBookService(query, log)
+		services := NewBookService(query, nil, log)
 
 		mockData := RequestBook{}
 
 		// Act
-		res, err := services.AddBook(mockData)
+		res, err := services.AddBook(context.Background(), mockData)
 
 		// Assert
 		assert.Equal(t, true, query.insertBookCalled)
@@ -197,10 +246,10 @@ func TestListAllBooks(t *testing.T) {
 		}
 		query := &BookQueriesSuccess{}
 		log := logrus.New()
-		services := NewBookService(query, log)
+		services := NewBookService(query, nil, log)
 
 		// Act
-		res, err := services.ListAllBooks(params)
+		res, err := services.ListAllBooks(context.Background(), params)
 
 		// Assert
 		assert.Equal(t, true, query.selectAllBooksCalled)
@@ -217,10 +266,10 @@ func TestListAllBooks(t *testing.T) {
 		}
 		query := &BookQueriesError{}
 		log := logrus.New()
-		services := NewBookService(query, log)
+		services := NewBookService(query, nil, log)
 
 		// Act
-		res, err := services.ListAllBooks(params)
+		res, err := services.ListAllBooks(context.Background(), params)
 
 		// Assert
 		assert.Equal(t, true, query.selectAllBooksCalled)
@@ -229,35 +278,83 @@ func TestListAllBooks(t *testing.T) {
 	})
 }
 
+func TestAddBookEdition(t *testing.T) {
+	t.Run("TestAddBookServiceShouldRejectUnknownFormat", func(t *testing.T) {
+		// Arrange
+		query := &BookQueriesSuccess{}
+		services := NewBookService(query, nil, logrus.New())
+
+		// Act
+		res, err := services.AddBook(context.Background(), RequestBook{Title: "mockTitle", Format: "scroll"})
+
+		// Assert
+		assert.Nil(t, res)
+		assert.Equal(t, http.StatusBadRequest, err.(*c.Err).Code)
+		assert.False(t, query.insertBookCalled)
+	})
+}
+
+func TestListBooksByWork(t *testing.T) {
+	t.Run("TestListBooksByWorkShouldAttachEditions", func(t *testing.T) {
+		// Arrange
+		services := NewBookService(&BookQueriesSuccess{}, nil, logrus.New())
+
+		// Act
+		res, err := services.ListBooksByWork(context.Background(), GetAllParams{Limit: 10})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Len(t, res, 2)
+		assert.Len(t, res[0].Editions, 2)
+		assert.Equal(t, []string{EditionHardcover, EditionEbook}, res[0].Formats)
+		assert.Equal(t, int64(3), res[0].Quantity)
+		assert.NotEmpty(t, res[0].Editions[0].CoverUrls)
+		assert.Equal(t, []ResponseBook{}, res[1].Editions)
+	})
+
+	t.Run("TestListBooksByWorkShouldReturnError", func(t *testing.T) {
+		// Arrange
+		services := NewBookService(&BookQueriesError{}, nil, logrus.New())
+
+		// Act
+		res, err := services.ListBooksByWork(context.Background(), GetAllParams{Limit: 10})
+
+		// Assert
+		assert.Nil(t, res)
+		assert.Equal(t, http.StatusInternalServerError, err.(*c.Err).Code)
+	})
+}
+
 func TestGetBookByID(t *testing.T) {
 	t.Run("TestGetBookByIDShouldReturnNoError", func(t *testing.T) {
 		// Arrange
 		query := &BookQueriesSuccess{}
 		log := logrus.New()
-		services := NewBookService(query, log)
+		services := NewBookService(query, nil, log)
 
 		id := uint64(1)
 
 		// Act
-		res, err := services.GetBookByID(id)
+		res, err := services.GetBookByID(context.Background(), id)
 
 		// Assert
 		assert.Equal(t, true, query.selectBookByIDCalled)
 		assert.Nil(t, err)
 		assert.NotNil(t, res)
 		assert.Equal(t, id, res.Id)
+		assert.Equal(t, "/books/1/cover/small?v=5f2b9c0d1e3a4b6c", res.CoverUrls[CoverSmall])
 	})
 
 	t.Run("TestGetBookByIDShouldReturnError", func(t *testing.T) {
 		// Arrange
 		query := &BookQueriesError{}
 		log := logrus.New()
-		services := NewBookService(query, log)
+		services := NewBookService(query, nil, log)
 
 		id := uint64(0)
 
 		// Act
-		res, err := services.GetBookByID(id)
+		res, err := services.GetBookByID(context.Background(), id)
 
 		// Assert
 		assert.Equal(t, true, query.selectBookByIDCalled)
@@ -266,12 +363,23 @@ func TestGetBookByID(t *testing.T) {
 	})
 }
 
+// BookQueriesPriceDrop stores the requested price so PutBook sees a change.
+type BookQueriesPriceDrop struct {
+	BookQueriesSuccess
+}
+
+func (s *BookQueriesPriceDrop) UpdateBook(ctx context.Context, id uint64, req RequestBook) (*ResponseBook, error) {
+	resp, err := s.BookQueriesSuccess.UpdateBook(context.Background(), id, req)
+	resp.Price = req.Price
+	return resp, err
+}
+
 func TestPutBook(t *testing.T) {
 	t.Run("TestUpdateBookShouldReturnNoError", func(t *testing.T) {
 		// Arrange
 		query := &BookQueriesSuccess{}
 		log := logrus.New()
-		services := NewBookService(query, log)
+		services := NewBookService(query, nil, log)
 
 		id := uint64(1)
 		mockData := RequestBook{
@@ -285,7 +393,7 @@ func TestPutBook(t *testing.T) {
 		}
 
 		// Act
-		res, err := services.PutBook(id, mockData)
+		res, err := services.PutBook(context.Background(), id, mockData)
 
 		// Assert
 		assert.Equal(t, true, query.updateBookCalled)
@@ -306,7 +414,7 @@ func TestPutBook(t *testing.T) {
 		// Arrange
 		query := &BookQueriesError{}
 		log := logrus.New()
-		services := NewBookService(query, log)
+		services := NewBookService(query, nil, log)
 
 		id := uint64(1)
 		mockData := RequestBook{
@@ -320,13 +428,30 @@ func TestPutBook(t *testing.T) {
 		}
 
 		// Act
-		res, err := services.PutBook(id, mockData)
+		res, err := services.PutBook(context.Background(), id, mockData)
 
 		// Assert
-		assert.Equal(t, true, query.updateBookCalled)
+		assert.Equal(t, true, query.selectBookByIDCalled)
+		assert.Equal(t, false, query.updateBookCalled)
 		assert.NotNil(t, err)
 		assert.Nil(t, res)
 	})
+	t.Run("TestPutBookShouldNotifyWatchersOfPriceDrop", func(t *testing.T) {
+		// Arrange
+		query := &BookQueriesPriceDrop{}
+		notifier := &ListNotifierStub{}
+		services := NewBookService(query, notifier, logrus.New())
+
+		// Act
+		_, err := services.PutBook(context.Background(), 1, RequestBook{Title: "mockTitle", Price: 800, Quantity: 100})
+
+		// Assert
+		assert.Nil(t, err)
+		if assert.Len(t, notifier.events, 1) {
+			assert.Equal(t, ListEventPriceDrop, notifier.events[0].Kind)
+			assert.Equal(t, []string{"tester"}, notifier.events[0].Usernames)
+		}
+	})
 }
 
 func TestDelBook(t *testing.T) {
@@ -334,12 +459,12 @@ func TestDelBook(t *testing.T) {
 		// Arrange
 		query := &BookQueriesSuccess{}
 		log := logrus.New()
-		services := NewBookService(query, log)
+		services := NewBookService(query, nil, log)
 
 		id := uint64(1)
 
 		// Act
-		err := services.DelBook(id)
+		err := services.DelBook(context.Background(), id)
 
 		// Assert
 		assert.Equal(t, true, query.deleteBookCallled)
@@ -351,15 +476,140 @@ func TestDelBook(t *testing.T) {
 		query := &BookQueriesError{}
 		log := logrus.New()
 
-		services := NewBookService(query, log)
+		services := NewBookService(query, nil, log)
 
 		id := uint64(1)
 
 		// Act
-		err := services.DelBook(id)
+		err := services.DelBook(context.Background(), id)
 
 		// Assert
 		assert.Equal(t, true, query.deleteBookCallled)
 		assert.Error(t, err)
 	})
 }
+
+// BookQueriesMemory is an in-memory catalog for services that need to read
+// back what they wrote.
+type BookQueriesMemory struct {
+	books     map[uint64]ResponseBook
+	details   map[uint64]RequestOnixDetail
+	subjects  map[uint64][]RequestCategoryImport
+	linkedIds []uint64
+	watchers  map[uint64][]string
+	nextId    uint64
+}
+
+func NewBookQueriesMemory() *BookQueriesMemory {
+	return &BookQueriesMemory{
+		books:    map[uint64]ResponseBook{},
+		details:  map[uint64]RequestOnixDetail{},
+		subjects: map[uint64][]RequestCategoryImport{},
+	}
+}
+
+func (q *BookQueriesMemory) InsertBook(ctx context.Context, req RequestBook) (*ResponseBook, error) {
+	q.nextId++
+	book := ResponseBook{Id: q.nextId, Title: req.Title, Authors: req.Authors, Publisher: req.Publisher, Isbn: req.Isbn,
+		Price: req.Price, Quantity: req.Quantity, Created_by: req.Created_by, Created_at: time.Now(),
+		WorkId: q.nextId, Format: req.Format, PublicationDate: req.PublicationDate}
+	q.books[book.Id] = book
+	q.linkedIds = append(q.linkedIds, book.Id)
+	return &book, nil
+}
+
+func (q *BookQueriesMemory) SelectAllBooks(ctx context.Context, params GetAllParams) ([]ResponseBook, error) {
+	resp := []ResponseBook{}
+	for id := uint64(1); id <= q.nextId; id++ {
+		if book, ok := q.books[id]; ok {
+			resp = append(resp, book)
+		}
+	}
+	if params.Offset >= int64(len(resp)) {
+		return []ResponseBook{}, nil
+	}
+	end := params.Offset + params.Limit
+	if end > int64(len(resp)) {
+		end = int64(len(resp))
+	}
+	return resp[params.Offset:end], nil
+}
+
+func (q *BookQueriesMemory) SelectBookByID(ctx context.Context, id uint64) (*ResponseBook, error) {
+	book, ok := q.books[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &book, nil
+}
+
+func (q *BookQueriesMemory) UpdateBook(ctx context.Context, id uint64, req RequestBook) (*ResponseBook, error) {
+	book, ok := q.books[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	book.Title, book.Authors, book.Publisher, book.Isbn = req.Title, req.Authors, req.Publisher, req.Isbn
+	book.Price, book.Quantity, book.Created_by = req.Price, req.Quantity, req.Created_by
+	book.Format, book.PublicationDate = req.Format, req.PublicationDate
+	q.books[id] = book
+	q.linkedIds = append(q.linkedIds, id)
+	return &book, nil
+}
+
+func (q *BookQueriesMemory) DeleteBook(ctx context.Context, id uint64) error {
+	delete(q.books, id)
+	delete(q.details, id)
+	return nil
+}
+
+func (q *BookQueriesMemory) SelectBookWatchers(ctx context.Context, bookId uint64) ([]string, error) {
+	return q.watchers[bookId], nil
+}
+
+func (q *BookQueriesMemory) SelectBookByIsbn(ctx context.Context, isbn string) (*ResponseBook, error) {
+	for id := uint64(1); id <= q.nextId; id++ {
+		if book, ok := q.books[id]; ok && book.Isbn == isbn {
+			return &book, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (q *BookQueriesMemory) IngestOnixBook(ctx context.Context, id uint64, req RequestBook, detail RequestOnixDetail, subjects []RequestCategoryImport) (*ResponseBook, error) {
+	var book *ResponseBook
+	var err error
+	if id == 0 {
+		book, err = q.InsertBook(ctx, req)
+	} else {
+		book, err = q.UpdateBook(ctx, id, req)
+	}
+	if err != nil {
+		return nil, err
+	}
+	detail.BookId = book.Id
+	q.details[book.Id] = detail
+	q.subjects[book.Id] = subjects
+	return book, nil
+}
+
+func (q *BookQueriesMemory) SelectOnixBooks(ctx context.Context, params GetAllParams) ([]ResponseOnixBook, error) {
+	resp := []ResponseOnixBook{}
+	for id := uint64(1); id <= q.nextId; id++ {
+		book, ok := q.books[id]
+		if !ok {
+			continue
+		}
+		d := q.details[id]
+		resp = append(resp, ResponseOnixBook{ResponseBook: book, RecordReference: d.RecordReference, ProductForm: d.ProductForm,
+			Subtitle: d.Subtitle, LanguageCode: d.LanguageCode, PageCount: d.PageCount, PublicationDate: d.PublicationDate,
+			Description: d.Description, CurrencyCode: d.CurrencyCode})
+	}
+	if params.Offset >= int64(len(resp)) {
+		return []ResponseOnixBook{}, nil
+	}
+	end := params.Offset + params.Limit
+	if end > int64(len(resp)) {
+		end = int64(len(resp))
+	}
+	return resp[params.Offset:end], nil
+}
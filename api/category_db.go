@@ -0,0 +1,360 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const (
+	CategorySchemeLocal = "local"
+	CategorySchemeBISAC = "bisac"
+	CategorySchemeThema = "thema"
+)
+
+var (
+	errCategoryParentNotFound = errors.New("error parent category not found")
+	errCategoryCycle          = errors.New("error category cannot be moved below itself")
+)
+
+const categoryColumns = `id, parent_id, name, scheme, code, path, created_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanCategory(row rowScanner) (ResponseCategory, error) {
+	resp := ResponseCategory{}
+	var parentId sql.NullInt64
+	err := row.Scan(&resp.Id, &parentId, &resp.Name, &resp.Scheme, &resp.Code, &resp.Path, &resp.CreatedAt)
+	if err != nil {
+		return ResponseCategory{}, err
+	}
+	if parentId.Valid {
+		id := uint64(parentId.Int64)
+		resp.ParentId = &id
+	}
+	return resp, nil
+}
+
+func (db Query) InsertCategory(ctx context.Context, req RequestCategory) (ResponseCategory, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return ResponseCategory{}, err
+	}
+	defer tx.Rollback()
+
+	resp, err := insertCategory(tx, req)
+	if err != nil {
+		return ResponseCategory{}, err
+	}
+	return resp, tx.Commit()
+}
+
+// insertCategory creates a category below its parent. The materialised path
+// holds the ids from the root down to and including the category itself,
+// e.g. "/1/4/9/", so descendants can be matched with a prefix search.
+func insertCategory(tx *sql.Tx, req RequestCategory) (ResponseCategory, error) {
+	const query = `INSERT INTO categories
+	(parent_id, name, scheme, code)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id;`
+
+	parentPath, err := categoryParentPath(tx, req.ParentId)
+	if err != nil {
+		return ResponseCategory{}, err
+	}
+
+	var id uint64
+	err = tx.QueryRow(query, req.ParentId, strings.TrimSpace(req.Name), req.Scheme, strings.TrimSpace(req.Code)).Scan(&id)
+	if err != nil {
+		return ResponseCategory{}, err
+	}
+
+	row := tx.QueryRow(`UPDATE categories SET path = $1 WHERE id = $2 RETURNING `+categoryColumns+`;`, categoryPath(parentPath, id), id)
+	return scanCategory(row)
+}
+
+func categoryParentPath(tx *sql.Tx, parentId *uint64) (string, error) {
+	if parentId == nil {
+		return "/", nil
+	}
+	var path string
+	err := tx.QueryRow(`SELECT path FROM categories WHERE id = $1;`, *parentId).Scan(&path)
+	if err == sql.ErrNoRows {
+		return "", errCategoryParentNotFound
+	}
+	return path, err
+}
+
+func categoryPath(parentPath string, id uint64) string {
+	return parentPath + strconv.FormatUint(id, 10) + "/"
+}
+
+func (db Query) SelectAllCategories(ctx context.Context, params GetAllParams) ([]ResponseCategory, error) {
+	const query = `SELECT ` + categoryColumns + `
+	FROM categories
+	ORDER BY path
+	LIMIT $1
+	OFFSET $2;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, params.Limit, params.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resp := []ResponseCategory{}
+	for rows.Next() {
+		result, err := scanCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		resp = append(resp, result)
+	}
+	return resp, rows.Err()
+}
+
+func (db Query) SelectCategory(ctx context.Context, id uint64) (ResponseCategory, error) {
+	const query = `SELECT ` + categoryColumns + ` FROM categories WHERE id = $1;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return ResponseCategory{}, err
+	}
+	defer stmt.Close()
+
+	return scanCategory(stmt.QueryRowContext(ctx, id))
+}
+
+// UpdateCategory renames and possibly moves a category. Moving rewrites the
+// path of every descendant; a category cannot be moved below itself.
+func (db Query) UpdateCategory(ctx context.Context, id uint64, req RequestCategory) (ResponseCategory, error) {
+	const query = `UPDATE categories
+	SET parent_id = $1, name = $2, scheme = $3, code = $4, path = $5
+	WHERE id = $6
+	RETURNING ` + categoryColumns + `;`
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return ResponseCategory{}, err
+	}
+	defer tx.Rollback()
+
+	var oldPath string
+	err = tx.QueryRow(`SELECT path FROM categories WHERE id = $1 FOR UPDATE;`, id).Scan(&oldPath)
+	if err != nil {
+		return ResponseCategory{}, err
+	}
+
+	parentPath, err := categoryParentPath(tx, req.ParentId)
+	if err != nil {
+		return ResponseCategory{}, err
+	}
+	if strings.HasPrefix(parentPath, oldPath) {
+		return ResponseCategory{}, errCategoryCycle
+	}
+	newPath := categoryPath(parentPath, id)
+
+	resp, err := scanCategory(tx.QueryRow(query, req.ParentId, strings.TrimSpace(req.Name), req.Scheme, strings.TrimSpace(req.Code), newPath, id))
+	if err != nil {
+		return ResponseCategory{}, err
+	}
+
+	if newPath != oldPath {
+		_, err = tx.Exec(`UPDATE categories SET path = $1 || substr(path, $2) WHERE path LIKE $3 AND id <> $4;`,
+			newPath, len(oldPath)+1, oldPath+"%", id)
+		if err != nil {
+			return ResponseCategory{}, err
+		}
+	}
+	return resp, tx.Commit()
+}
+
+func (db Query) DeleteCategory(ctx context.Context, id uint64) error {
+	const query = `DELETE FROM categories WHERE id = $1;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (db Query) SelectBookCategories(ctx context.Context, bookId uint64) ([]ResponseCategory, error) {
+	const query = `SELECT c.id, c.parent_id, c.name, c.scheme, c.code, c.path, c.created_at
+	FROM book_categories bc
+	JOIN categories c ON c.id = bc.category_id
+	WHERE bc.book_id = $1
+	ORDER BY c.path;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, bookId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resp := []ResponseCategory{}
+	for rows.Next() {
+		result, err := scanCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		resp = append(resp, result)
+	}
+	return resp, rows.Err()
+}
+
+// SetBookCategories replaces the categories a book is assigned to.
+func (db Query) SetBookCategories(ctx context.Context, bookId uint64, categoryIds []uint64) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM book_categories WHERE book_id = $1;`, bookId)
+	if err != nil {
+		return err
+	}
+	for _, id := range categoryIds {
+		_, err = tx.Exec(`INSERT INTO book_categories (book_id, category_id) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING;`, bookId, id)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ImportCategories merges subject headings into the category tree in a single
+// transaction. Entries are matched by scheme and code first, then by name
+// below the same parent, so importing a newer code list is idempotent.
+func (db Query) ImportCategories(ctx context.Context, entries []RequestCategoryImport) (ResponseFeedIngest, error) {
+	result := ResponseFeedIngest{Errors: []string{}}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	for _, entry := range entries {
+		if _, err := importCategory(tx, entry, &result); err != nil {
+			return ResponseFeedIngest{}, err
+		}
+	}
+	return result, tx.Commit()
+}
+
+// linkBookSubjects replaces the BISAC and Thema categories of a book with the
+// given subjects, creating categories for codes not imported yet. Categories
+// from the local scheme are left untouched.
+func linkBookSubjects(tx *sql.Tx, bookId uint64, subjects []RequestCategoryImport) error {
+	const query = `DELETE FROM book_categories bc
+	USING categories c
+	WHERE c.id = bc.category_id
+	AND bc.book_id = $1
+	AND c.scheme IN ($2, $3);`
+
+	_, err := tx.Exec(query, bookId, CategorySchemeBISAC, CategorySchemeThema)
+	if err != nil {
+		return err
+	}
+
+	discard := ResponseFeedIngest{}
+	for _, subject := range subjects {
+		categoryId, err := importCategory(tx, subject, &discard)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO book_categories (book_id, category_id) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING;`, bookId, categoryId)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func importCategory(tx *sql.Tx, entry RequestCategoryImport, result *ResponseFeedIngest) (uint64, error) {
+	const selectChild = `SELECT id FROM categories
+	WHERE scheme = $1 AND parent_id IS NOT DISTINCT FROM $2 AND lower(name) = lower($3)
+	ORDER BY id
+	LIMIT 1;`
+
+	if len(entry.Path) == 0 {
+		return 0, errors.New("error category heading not found")
+	}
+
+	if entry.Code != "" {
+		var id uint64
+		err := tx.QueryRow(`UPDATE categories SET name = $1 WHERE scheme = $2 AND code = $3 RETURNING id;`,
+			entry.Path[len(entry.Path)-1], entry.Scheme, entry.Code).Scan(&id)
+		if err == nil {
+			result.Updated++
+			return id, nil
+		}
+		if err != sql.ErrNoRows {
+			return 0, err
+		}
+	}
+
+	var parentId *uint64
+	for i, name := range entry.Path {
+		last := i == len(entry.Path)-1
+
+		var id uint64
+		err := tx.QueryRow(selectChild, entry.Scheme, parentId, name).Scan(&id)
+		switch {
+		case err == sql.ErrNoRows:
+			req := RequestCategory{ParentId: parentId, Name: name, Scheme: entry.Scheme}
+			if last {
+				req.Code = entry.Code
+			}
+			category, err := insertCategory(tx, req)
+			if err != nil {
+				return 0, err
+			}
+			id = category.Id
+			result.Created++
+		case err != nil:
+			return 0, err
+		case last && entry.Code != "":
+			_, err = tx.Exec(`UPDATE categories SET code = $1 WHERE id = $2;`, entry.Code, id)
+			if err != nil {
+				return 0, err
+			}
+			result.Updated++
+		}
+		parentId = &id
+	}
+	return *parentId, nil
+}
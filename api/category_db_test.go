@@ -0,0 +1,240 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"database/sql/driver"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var categoryRowColumns = []string{"id", "parent_id", "name", "scheme", "code", "path", "created_at"}
+
+func TestInsertCategory(t *testing.T) {
+	t.Run("TestInsertCategoryShouldBuildPathFromParent", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		parentId := uint64(4)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT path FROM categories WHERE id = $1;`)).
+			WithArgs(4).
+			WillReturnRows(sqlmock.NewRows([]string{"path"}).AddRow("/1/4/"))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO categories (parent_id, name, scheme, code) VALUES ($1, $2, $3, $4) RETURNING id;`)).
+			WithArgs(4, "Space Opera", CategorySchemeLocal, "").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE categories SET path = $1 WHERE id = $2`)).
+			WithArgs("/1/4/9/", 9).
+			WillReturnRows(sqlmock.NewRows(categoryRowColumns).AddRow(9, 4, "Space Opera", CategorySchemeLocal, "", "/1/4/9/", time.Now()))
+		mock.ExpectCommit()
+
+		query := NewDB(db)
+
+		// Act
+		result, err := query.InsertCategory(context.Background(), RequestCategory{ParentId: &parentId, Name: " Space Opera ", Scheme: CategorySchemeLocal})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "/1/4/9/", result.Path)
+		assert.Equal(t, &parentId, result.ParentId)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestInsertCategoryShouldReturnParentNotFound", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		parentId := uint64(4)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT path FROM categories WHERE id = $1;`)).
+			WithArgs(4).
+			WillReturnRows(sqlmock.NewRows([]string{"path"}))
+		mock.ExpectRollback()
+
+		query := NewDB(db)
+
+		// Act
+		_, err = query.InsertCategory(context.Background(), RequestCategory{ParentId: &parentId, Name: "Space Opera", Scheme: CategorySchemeLocal})
+
+		// Assert
+		assert.Equal(t, errCategoryParentNotFound, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUpdateCategory(t *testing.T) {
+	t.Run("TestUpdateCategoryShouldMoveDescendants", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT path FROM categories WHERE id = $1 FOR UPDATE;`)).
+			WithArgs(4).
+			WillReturnRows(sqlmock.NewRows([]string{"path"}).AddRow("/1/4/"))
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE categories SET parent_id = $1, name = $2, scheme = $3, code = $4, path = $5 WHERE id = $6`)).
+			WithArgs(driver.Value(nil), "Science Fiction", CategorySchemeLocal, "", "/4/", 4).
+			WillReturnRows(sqlmock.NewRows(categoryRowColumns).AddRow(4, nil, "Science Fiction", CategorySchemeLocal, "", "/4/", time.Now()))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE categories SET path = $1 || substr(path, $2) WHERE path LIKE $3 AND id <> $4;`)).
+			WithArgs("/4/", 6, "/1/4/%", 4).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectCommit()
+
+		query := NewDB(db)
+
+		// Act
+		result, err := query.UpdateCategory(context.Background(), 4, RequestCategory{Name: "Science Fiction", Scheme: CategorySchemeLocal})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Nil(t, result.ParentId)
+		assert.Equal(t, "/4/", result.Path)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestUpdateCategoryShouldRejectMoveBelowItself", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		parentId := uint64(9)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT path FROM categories WHERE id = $1 FOR UPDATE;`)).
+			WithArgs(4).
+			WillReturnRows(sqlmock.NewRows([]string{"path"}).AddRow("/1/4/"))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT path FROM categories WHERE id = $1;`)).
+			WithArgs(9).
+			WillReturnRows(sqlmock.NewRows([]string{"path"}).AddRow("/1/4/9/"))
+		mock.ExpectRollback()
+
+		query := NewDB(db)
+
+		// Act
+		_, err = query.UpdateCategory(context.Background(), 4, RequestCategory{ParentId: &parentId, Name: "Science Fiction", Scheme: CategorySchemeLocal})
+
+		// Assert
+		assert.Equal(t, errCategoryCycle, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestImportCategories(t *testing.T) {
+	t.Run("TestImportCategoriesShouldCreateMissingLevels", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE categories SET name = $1 WHERE scheme = $2 AND code = $3 RETURNING id;`)).
+			WithArgs("Space Opera", CategorySchemeBISAC, "FIC028010").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		// FICTION already exists, Science Fiction and Space Opera are new.
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT id FROM categories WHERE scheme = $1 AND parent_id IS NOT DISTINCT FROM $2`)).
+			WithArgs(CategorySchemeBISAC, driver.Value(nil), "FICTION").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT id FROM categories WHERE scheme = $1 AND parent_id IS NOT DISTINCT FROM $2`)).
+			WithArgs(CategorySchemeBISAC, 1, "Science Fiction").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT path FROM categories WHERE id = $1;`)).
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"path"}).AddRow("/1/"))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO categories`)).
+			WithArgs(1, "Science Fiction", CategorySchemeBISAC, "").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE categories SET path = $1 WHERE id = $2`)).
+			WithArgs("/1/2/", 2).
+			WillReturnRows(sqlmock.NewRows(categoryRowColumns).AddRow(2, 1, "Science Fiction", CategorySchemeBISAC, "", "/1/2/", time.Now()))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT id FROM categories WHERE scheme = $1 AND parent_id IS NOT DISTINCT FROM $2`)).
+			WithArgs(CategorySchemeBISAC, 2, "Space Opera").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT path FROM categories WHERE id = $1;`)).
+			WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"path"}).AddRow("/1/2/"))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO categories`)).
+			WithArgs(2, "Space Opera", CategorySchemeBISAC, "FIC028010").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE categories SET path = $1 WHERE id = $2`)).
+			WithArgs("/1/2/3/", 3).
+			WillReturnRows(sqlmock.NewRows(categoryRowColumns).AddRow(3, 2, "Space Opera", CategorySchemeBISAC, "FIC028010", "/1/2/3/", time.Now()))
+		mock.ExpectCommit()
+
+		query := NewDB(db)
+
+		// Act
+		result, err := query.ImportCategories(context.Background(), []RequestCategoryImport{
+			{Scheme: CategorySchemeBISAC, Code: "FIC028010", Path: []string{"FICTION", "Science Fiction", "Space Opera"}},
+		})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, 2, result.Created)
+		assert.Equal(t, 0, result.Updated)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestImportCategoriesShouldRenameKnownCode", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE categories SET name = $1 WHERE scheme = $2 AND code = $3 RETURNING id;`)).
+			WithArgs("Modern & contemporary fiction", CategorySchemeThema, "FBA").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+		mock.ExpectCommit()
+
+		query := NewDB(db)
+
+		// Act
+		result, err := query.ImportCategories(context.Background(), []RequestCategoryImport{
+			{Scheme: CategorySchemeThema, Code: "FBA", Path: []string{"Fiction", "Modern & contemporary fiction"}},
+		})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, 1, result.Updated)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestSetBookCategories(t *testing.T) {
+	t.Run("TestSetBookCategoriesShouldReplaceAssignments", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM book_categories WHERE book_id = $1;`)).
+			WithArgs(5).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		for _, id := range []int{2, 3} {
+			mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO book_categories (book_id, category_id) VALUES ($1, $2)`)).
+				WithArgs(5, id).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+		mock.ExpectCommit()
+
+		query := NewDB(db)
+
+		// Act
+		err = query.SetBookCategories(context.Background(), 5, []uint64{2, 3})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
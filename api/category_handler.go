@@ -0,0 +1,212 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	c "github.com/paquesqueue/bookstore/common"
+)
+
+type CategoryHandlrQueries interface {
+	AddCategory(ctx context.Context, req RequestCategory) (ResponseCategory, error)
+	ListCategories(ctx context.Context, params GetAllParams) ([]ResponseCategory, error)
+	GetCategory(ctx context.Context, id uint64) (ResponseCategory, error)
+	PutCategory(ctx context.Context, id uint64, req RequestCategory) (ResponseCategory, error)
+	DeleteCategory(ctx context.Context, id uint64) error
+	ListCategoryBooks(ctx context.Context, id uint64, params GetAllParams) ([]ResponseBook, error)
+	GetBookCategories(ctx context.Context, bookId uint64) ([]ResponseCategory, error)
+	PutBookCategories(ctx context.Context, bookId uint64, req RequestBookCategories) ([]ResponseCategory, error)
+	ImportCategories(ctx context.Context, scheme string, r io.Reader) (ResponseFeedIngest, error)
+}
+
+type CategoryHandlr struct {
+	handler CategoryHandlrQueries
+	log     c.Log
+}
+
+func NewCategoryHandlr(h CategoryHandlrQueries, l c.Log) CategoryHandlr {
+	return CategoryHandlr{h, l}
+}
+
+func (h CategoryHandlr) AddCategory(ctx echo.Context) error {
+	req := RequestCategory{}
+	err := ctx.Bind(&req)
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	res, err := h.handler.AddCategory(ctx.Request().Context(), req)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error AddCategory Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusCreated, res)
+}
+
+func (h CategoryHandlr) ListCategories(ctx echo.Context) error {
+	var req RequestGetAll
+	err := ctx.Bind(&req)
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	params := GetAllParams{
+		Limit:  req.PageSize,
+		Offset: (req.PageId - 1) * req.PageSize,
+	}
+
+	res, err := h.handler.ListCategories(ctx.Request().Context(), params)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error ListCategories Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h CategoryHandlr) GetCategory(ctx echo.Context) error {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	res, err := h.handler.GetCategory(ctx.Request().Context(), uint64(id))
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error GetCategory Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h CategoryHandlr) PutCategory(ctx echo.Context) error {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	req := RequestCategory{}
+	err = ctx.Bind(&req)
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	res, err := h.handler.PutCategory(ctx.Request().Context(), uint64(id), req)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error PutCategory Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h CategoryHandlr) DeleteCategory(ctx echo.Context) error {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	err = h.handler.DeleteCategory(ctx.Request().Context(), uint64(id))
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error DeleteCategory Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, "Deleted Successfully")
+}
+
+func (h CategoryHandlr) ListCategoryBooks(ctx echo.Context) error {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	var req RequestGetAll
+	err = ctx.Bind(&req)
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	params := GetAllParams{
+		Limit:  req.PageSize,
+		Offset: (req.PageId - 1) * req.PageSize,
+	}
+
+	res, err := h.handler.ListCategoryBooks(ctx.Request().Context(), uint64(id), params)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error ListCategoryBooks Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h CategoryHandlr) GetBookCategories(ctx echo.Context) error {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	res, err := h.handler.GetBookCategories(ctx.Request().Context(), uint64(id))
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error GetBookCategories Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h CategoryHandlr) PutBookCategories(ctx echo.Context) error {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	req := RequestBookCategories{}
+	err = ctx.Bind(&req)
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	res, err := h.handler.PutBookCategories(ctx.Request().Context(), uint64(id), req)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error PutBookCategories Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, res)
+}
+
+// ImportCategories loads a BISAC or Thema code list sent as CSV rows of code
+// and heading, selected with ?scheme=bisac or ?scheme=thema.
+func (h CategoryHandlr) ImportCategories(ctx echo.Context) error {
+	res, err := h.handler.ImportCategories(ctx.Request().Context(), ctx.QueryParam("scheme"), ctx.Request().Body)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error ImportCategories Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, res)
+}
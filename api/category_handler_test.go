@@ -0,0 +1,167 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type CategoryHandlrSuccess struct {
+	CategoryHandlrError
+	scheme         string
+	body           string
+	bookCategories RequestBookCategories
+}
+
+func (h *CategoryHandlrSuccess) PutBookCategories(ctx context.Context, bookId uint64, req RequestBookCategories) ([]ResponseCategory, error) {
+	h.bookCategories = req
+	return []ResponseCategory{{Id: 2}, {Id: 3}}, nil
+}
+
+func (h *CategoryHandlrSuccess) ImportCategories(ctx context.Context, scheme string, r io.Reader) (ResponseFeedIngest, error) {
+	h.scheme = scheme
+	body, err := io.ReadAll(r)
+	h.body = string(body)
+	return ResponseFeedIngest{Created: 1, Errors: []string{}}, err
+}
+
+type CategoryHandlrError struct {
+	statusCodeError int
+}
+
+func (h *CategoryHandlrError) AddCategory(ctx context.Context, req RequestCategory) (ResponseCategory, error) {
+	return ResponseCategory{}, &c.Err{Code: h.statusCodeError}
+}
+
+func (h *CategoryHandlrError) ListCategories(ctx context.Context, params GetAllParams) ([]ResponseCategory, error) {
+	return nil, &c.Err{Code: h.statusCodeError}
+}
+
+func (h *CategoryHandlrError) GetCategory(ctx context.Context, id uint64) (ResponseCategory, error) {
+	return ResponseCategory{}, &c.Err{Code: h.statusCodeError}
+}
+
+func (h *CategoryHandlrError) PutCategory(ctx context.Context, id uint64, req RequestCategory) (ResponseCategory, error) {
+	return ResponseCategory{}, &c.Err{Code: h.statusCodeError}
+}
+
+func (h *CategoryHandlrError) DeleteCategory(ctx context.Context, id uint64) error {
+	return &c.Err{Code: h.statusCodeError}
+}
+
+func (h *CategoryHandlrError) ListCategoryBooks(ctx context.Context, id uint64, params GetAllParams) ([]ResponseBook, error) {
+	return nil, &c.Err{Code: h.statusCodeError}
+}
+
+func (h *CategoryHandlrError) GetBookCategories(ctx context.Context, bookId uint64) ([]ResponseCategory, error) {
+	return nil, &c.Err{Code: h.statusCodeError}
+}
+
+func (h *CategoryHandlrError) PutBookCategories(ctx context.Context, bookId uint64, req RequestBookCategories) ([]ResponseCategory, error) {
+	return nil, &c.Err{Code: h.statusCodeError}
+}
+
+func (h *CategoryHandlrError) ImportCategories(ctx context.Context, scheme string, r io.Reader) (ResponseFeedIngest, error) {
+	return ResponseFeedIngest{}, &c.Err{Code: h.statusCodeError}
+}
+
+func TestImportCategoriesHandler(t *testing.T) {
+	t.Run("TestImportCategoriesHandlerShouldReturnHTTPStatus200", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPost, "/categories/import?scheme=thema", strings.NewReader("F,Fiction\n"))
+		req.Header.Set(echo.HeaderContentType, "text/csv")
+		rec := httptest.NewRecorder()
+		ctx := echo.New().NewContext(req, rec)
+
+		handlrServ := &CategoryHandlrSuccess{}
+		handler := NewCategoryHandlr(handlrServ, logrus.New())
+
+		// Act
+		err := handler.ImportCategories(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, CategorySchemeThema, handlrServ.scheme)
+			assert.Equal(t, "F,Fiction\n", handlrServ.body)
+
+			res := ResponseFeedIngest{}
+			json.Unmarshal(rec.Body.Bytes(), &res)
+			assert.Equal(t, 1, res.Created)
+		}
+	})
+
+	t.Run("TestImportCategoriesHandlerShouldReturnHTTPStatus400", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPost, "/categories/import?scheme=dewey", strings.NewReader("F,Fiction\n"))
+		rec := httptest.NewRecorder()
+		ctx := echo.New().NewContext(req, rec)
+
+		handler := NewCategoryHandlr(&CategoryHandlrError{statusCodeError: http.StatusBadRequest}, logrus.New())
+
+		// Act
+		err := handler.ImportCategories(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		}
+	})
+}
+
+func TestPutBookCategoriesHandler(t *testing.T) {
+	t.Run("TestPutBookCategoriesHandlerShouldReturnHTTPStatus200", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"category_ids":[2,3]}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		ctx := echo.New().NewContext(req, rec)
+		ctx.SetPath("/books/:id/categories")
+		ctx.SetParamNames("id")
+		ctx.SetParamValues("1")
+
+		handlrServ := &CategoryHandlrSuccess{}
+		handler := NewCategoryHandlr(handlrServ, logrus.New())
+
+		// Act
+		err := handler.PutBookCategories(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, []uint64{2, 3}, handlrServ.bookCategories.CategoryIds)
+		}
+	})
+
+	t.Run("TestPutBookCategoriesHandlerShouldReturnHTTPStatus404", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"category_ids":[2]}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		ctx := echo.New().NewContext(req, rec)
+		ctx.SetPath("/books/:id/categories")
+		ctx.SetParamNames("id")
+		ctx.SetParamValues("1")
+
+		handler := NewCategoryHandlr(&CategoryHandlrError{statusCodeError: http.StatusNotFound}, logrus.New())
+
+		// Act
+		err := handler.PutBookCategories(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusNotFound, rec.Code)
+		}
+	})
+}
@@ -0,0 +1,342 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/paquesqueue/bookstore/tracing"
+)
+
+var (
+	bisacCodePattern = regexp.MustCompile(`^[A-Z]{3}[0-9]{6}$`)
+	themaCodePattern = regexp.MustCompile(`^[0-9A-Z][0-9A-Z-]*$`)
+)
+
+type CategoryQueries interface {
+	InsertCategory(ctx context.Context, req RequestCategory) (ResponseCategory, error)
+	SelectAllCategories(ctx context.Context, params GetAllParams) ([]ResponseCategory, error)
+	SelectCategory(ctx context.Context, id uint64) (ResponseCategory, error)
+	UpdateCategory(ctx context.Context, id uint64, req RequestCategory) (ResponseCategory, error)
+	DeleteCategory(ctx context.Context, id uint64) error
+	SelectFilteredBooks(ctx context.Context, filter RequestBookFilter, params GetAllParams) ([]ResponseBook, error)
+	SelectBookByID(ctx context.Context, id uint64) (*ResponseBook, error)
+	SelectBookCategories(ctx context.Context, bookId uint64) ([]ResponseCategory, error)
+	SetBookCategories(ctx context.Context, bookId uint64, categoryIds []uint64) error
+	ImportCategories(ctx context.Context, entries []RequestCategoryImport) (ResponseFeedIngest, error)
+}
+
+type CategoryServices struct {
+	query CategoryQueries
+	log   c.Log
+}
+
+func NewCategoryService(q CategoryQueries, l c.Log) CategoryServices {
+	return CategoryServices{q, l}
+}
+
+func (s CategoryServices) AddCategory(ctx context.Context, req RequestCategory) (ResponseCategory, error) {
+	ctx, span := tracing.Start(ctx, "CategoryServices.AddCategory")
+	defer span.End()
+
+	req, err := validCategory(req)
+	if err != nil {
+		return ResponseCategory{}, err
+	}
+
+	resp, err := s.query.InsertCategory(ctx, req)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error InsertCategory : %v", err)
+		return ResponseCategory{}, categoryErr(err, "Error AddCategory Service")
+	}
+	return resp, nil
+}
+
+func (s CategoryServices) ListCategories(ctx context.Context, params GetAllParams) ([]ResponseCategory, error) {
+	ctx, span := tracing.Start(ctx, "CategoryServices.ListCategories")
+	defer span.End()
+
+	resp, err := s.query.SelectAllCategories(ctx, params)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error SelectAllCategories : %v", err)
+		return nil, &c.Err{Code: http.StatusInternalServerError, Remark: "Error ListCategories Service", Original: err}
+	}
+	return resp, nil
+}
+
+func (s CategoryServices) GetCategory(ctx context.Context, id uint64) (ResponseCategory, error) {
+	ctx, span := tracing.Start(ctx, "CategoryServices.GetCategory")
+	defer span.End()
+
+	resp, err := s.query.SelectCategory(ctx, id)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error SelectCategory : %v", err)
+		return ResponseCategory{}, categoryErr(err, "Error GetCategory Service")
+	}
+	return resp, nil
+}
+
+func (s CategoryServices) PutCategory(ctx context.Context, id uint64, req RequestCategory) (ResponseCategory, error) {
+	ctx, span := tracing.Start(ctx, "CategoryServices.PutCategory")
+	defer span.End()
+
+	req, err := validCategory(req)
+	if err != nil {
+		return ResponseCategory{}, err
+	}
+
+	resp, err := s.query.UpdateCategory(ctx, id, req)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error UpdateCategory : %v", err)
+		return ResponseCategory{}, categoryErr(err, "Error PutCategory Service")
+	}
+	return resp, nil
+}
+
+func (s CategoryServices) DeleteCategory(ctx context.Context, id uint64) error {
+	ctx, span := tracing.Start(ctx, "CategoryServices.DeleteCategory")
+	defer span.End()
+
+	err := s.query.DeleteCategory(ctx, id)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error DeleteCategory : %v", err)
+		return categoryErr(err, "Error DeleteCategory Service")
+	}
+	return nil
+}
+
+// ListCategoryBooks lists the books of a category and all of its descendants.
+func (s CategoryServices) ListCategoryBooks(ctx context.Context, id uint64, params GetAllParams) ([]ResponseBook, error) {
+	ctx, span := tracing.Start(ctx, "CategoryServices.ListCategoryBooks")
+	defer span.End()
+
+	_, err := s.GetCategory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.query.SelectFilteredBooks(ctx, RequestBookFilter{CategoryId: id}, params)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error SelectFilteredBooks : %v", err)
+		return nil, &c.Err{Code: http.StatusInternalServerError, Remark: "Error ListCategoryBooks Service", Original: err}
+	}
+	return resp, nil
+}
+
+func (s CategoryServices) GetBookCategories(ctx context.Context, bookId uint64) ([]ResponseCategory, error) {
+	ctx, span := tracing.Start(ctx, "CategoryServices.GetBookCategories")
+	defer span.End()
+
+	err := s.bookExists(ctx, bookId)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.query.SelectBookCategories(ctx, bookId)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error SelectBookCategories : %v", err)
+		return nil, &c.Err{Code: http.StatusInternalServerError, Remark: "Error GetBookCategories Service", Original: err}
+	}
+	return resp, nil
+}
+
+func (s CategoryServices) PutBookCategories(ctx context.Context, bookId uint64, req RequestBookCategories) ([]ResponseCategory, error) {
+	ctx, span := tracing.Start(ctx, "CategoryServices.PutBookCategories")
+	defer span.End()
+
+	err := s.bookExists(ctx, bookId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.query.SetBookCategories(ctx, bookId, req.CategoryIds)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error SetBookCategories : %v", err)
+		if isPqError(err, pqForeignKeyViolation) {
+			return nil, &c.Err{Code: http.StatusBadRequest, Remark: "Error Category Not Found", Original: err}
+		}
+		return nil, &c.Err{Code: http.StatusInternalServerError, Remark: "Error PutBookCategories Service", Original: err}
+	}
+	return s.GetBookCategories(ctx, bookId)
+}
+
+// ImportCategories reads a subject code list as CSV rows of code and heading
+// and merges it into the category tree. BISAC headings carry their hierarchy
+// ("FICTION / Science Fiction / Space Opera"); Thema codes derive it from
+// their prefix, so "FBA" is placed below "FB" and "F".
+func (s CategoryServices) ImportCategories(ctx context.Context, scheme string, r io.Reader) (ResponseFeedIngest, error) {
+	ctx, span := tracing.Start(ctx, "CategoryServices.ImportCategories")
+	defer span.End()
+
+	if scheme != CategorySchemeBISAC && scheme != CategorySchemeThema {
+		return ResponseFeedIngest{}, &c.Err{Code: http.StatusBadRequest, Remark: "Error Unsupported Category Scheme"}
+	}
+
+	entries, rejected, err := categoryImportEntries(scheme, r)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error Read Category Codes : %v", err)
+		return ResponseFeedIngest{}, &c.Err{Code: http.StatusBadRequest, Remark: "Error Invalid Category Codes", Original: err}
+	}
+
+	result, err := s.query.ImportCategories(ctx, entries)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error ImportCategories : %v", err)
+		return ResponseFeedIngest{}, &c.Err{Code: http.StatusInternalServerError, Remark: "Error ImportCategories Service", Original: err}
+	}
+	result.Rejected = len(rejected)
+	result.Errors = append(result.Errors, rejected...)
+	return result, nil
+}
+
+func (s CategoryServices) bookExists(ctx context.Context, bookId uint64) error {
+	_, err := s.query.SelectBookByID(ctx, bookId)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error SelectBookByID : %v", err)
+		switch err {
+		case sql.ErrNoRows:
+			return &c.Err{Code: http.StatusNotFound, Remark: "Error Book Not Found", Original: err}
+		default:
+			return &c.Err{Code: http.StatusInternalServerError, Remark: "Error GetBook Service", Original: err}
+		}
+	}
+	return nil
+}
+
+func validCategory(req RequestCategory) (RequestCategory, error) {
+	if strings.TrimSpace(req.Name) == "" {
+		return req, &c.Err{Code: http.StatusBadRequest, Remark: "Error Category Name Required"}
+	}
+	switch req.Scheme {
+	case "":
+		req.Scheme = CategorySchemeLocal
+	case CategorySchemeLocal, CategorySchemeBISAC, CategorySchemeThema:
+	default:
+		return req, &c.Err{Code: http.StatusBadRequest, Remark: "Error Unsupported Category Scheme"}
+	}
+	return req, nil
+}
+
+func categoryErr(err error, remark string) *c.Err {
+	switch {
+	case err == sql.ErrNoRows:
+		return &c.Err{Code: http.StatusNotFound, Remark: "Error Category Not Found", Original: err}
+	case err == errCategoryParentNotFound:
+		return &c.Err{Code: http.StatusBadRequest, Remark: "Error Parent Category Not Found", Original: err}
+	case err == errCategoryCycle:
+		return &c.Err{Code: http.StatusConflict, Remark: "Error Category Cycle", Original: err}
+	case isPqError(err, pqUniqueViolation):
+		return &c.Err{Code: http.StatusConflict, Remark: "Error Category Code Already Exists", Original: err}
+	case isPqError(err, pqForeignKeyViolation):
+		return &c.Err{Code: http.StatusConflict, Remark: "Error Category Still Has Children", Original: err}
+	default:
+		return &c.Err{Code: http.StatusInternalServerError, Remark: remark, Original: err}
+	}
+}
+
+// categoryImportEntries parses a code list and returns the entries to import
+// in parent-first order along with the rows it rejected.
+func categoryImportEntries(scheme string, r io.Reader) ([]RequestCategoryImport, []string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	headings := map[string]string{}
+	codes := []string{}
+	rejected := []string{}
+	for n := 1; ; n++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(record) < 2 {
+			rejected = append(rejected, fmt.Sprintf("row %d : error expected code and heading", n))
+			continue
+		}
+
+		code := strings.ToUpper(strings.TrimSpace(record[0]))
+		heading := strings.TrimSpace(record[1])
+		if n == 1 && code == "CODE" {
+			continue
+		}
+		if err := validSubject(scheme, code, heading); err != nil {
+			rejected = append(rejected, fmt.Sprintf("row %d : %v", n, err))
+			continue
+		}
+		if _, ok := headings[code]; !ok {
+			codes = append(codes, code)
+		}
+		headings[code] = heading
+	}
+
+	if scheme == CategorySchemeThema {
+		sort.SliceStable(codes, func(i, j int) bool {
+			return len(codes[i]) < len(codes[j])
+		})
+	}
+
+	entries := make([]RequestCategoryImport, 0, len(codes))
+	for _, code := range codes {
+		entry := RequestCategoryImport{Scheme: scheme, Code: code}
+		switch scheme {
+		case CategorySchemeBISAC:
+			entry.Path = bisacHeadingPath(headings[code])
+		case CategorySchemeThema:
+			entry.Path = themaHeadingPath(code, headings)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rejected, nil
+}
+
+func validSubject(scheme, code, heading string) error {
+	pattern := themaCodePattern
+	if scheme == CategorySchemeBISAC {
+		pattern = bisacCodePattern
+	}
+	if !pattern.MatchString(code) {
+		return fmt.Errorf("error invalid %v code %q", scheme, code)
+	}
+	if heading == "" {
+		return fmt.Errorf("error heading not found for %q", code)
+	}
+	return nil
+}
+
+// bisacHeadingPath splits a BISAC heading such as
+// "FICTION / Science Fiction / Space Opera" into its levels.
+func bisacHeadingPath(heading string) []string {
+	path := []string{}
+	for _, level := range strings.Split(heading, "/") {
+		if level = strings.TrimSpace(level); level != "" {
+			path = append(path, level)
+		}
+	}
+	return path
+}
+
+// themaHeadingPath returns the headings from the root down to code, using
+// the codes present in headings as ancestors. Codes missing from the list
+// are skipped so the entry attaches to its nearest known ancestor.
+func themaHeadingPath(code string, headings map[string]string) []string {
+	path := []string{headings[code]}
+	for prefix := code[:len(code)-1]; prefix != ""; prefix = prefix[:len(prefix)-1] {
+		prefix = strings.TrimRight(prefix, "-")
+		if prefix == "" {
+			break
+		}
+		if heading, ok := headings[prefix]; ok {
+			path = append([]string{heading}, path...)
+		}
+	}
+	return path
+}
@@ -0,0 +1,241 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type CategoryQueriesStub struct {
+	err            error
+	bookErr        error
+	filter         RequestBookFilter
+	categoryIds    []uint64
+	imported       []RequestCategoryImport
+	insertCalled   bool
+	setCalled      bool
+	filteredCalled bool
+}
+
+func (s *CategoryQueriesStub) InsertCategory(ctx context.Context, req RequestCategory) (ResponseCategory, error) {
+	s.insertCalled = true
+	return ResponseCategory{Id: 1, Name: req.Name, Scheme: req.Scheme, Path: "/1/", CreatedAt: time.Now()}, s.err
+}
+
+func (s *CategoryQueriesStub) SelectAllCategories(ctx context.Context, params GetAllParams) ([]ResponseCategory, error) {
+	return []ResponseCategory{{Id: 1, Name: "Fiction", Path: "/1/"}}, s.err
+}
+
+func (s *CategoryQueriesStub) SelectCategory(ctx context.Context, id uint64) (ResponseCategory, error) {
+	return ResponseCategory{Id: id, Name: "Fiction", Path: "/1/"}, s.err
+}
+
+func (s *CategoryQueriesStub) UpdateCategory(ctx context.Context, id uint64, req RequestCategory) (ResponseCategory, error) {
+	return ResponseCategory{Id: id, Name: req.Name, Scheme: req.Scheme}, s.err
+}
+
+func (s *CategoryQueriesStub) DeleteCategory(ctx context.Context, id uint64) error {
+	return s.err
+}
+
+func (s *CategoryQueriesStub) SelectFilteredBooks(ctx context.Context, filter RequestBookFilter, params GetAllParams) ([]ResponseBook, error) {
+	s.filteredCalled = true
+	s.filter = filter
+	return []ResponseBook{{Id: 1, Title: "mockTitle"}}, nil
+}
+
+func (s *CategoryQueriesStub) SelectBookByID(ctx context.Context, id uint64) (*ResponseBook, error) {
+	if s.bookErr != nil {
+		return nil, s.bookErr
+	}
+	return &ResponseBook{Id: id}, nil
+}
+
+func (s *CategoryQueriesStub) SelectBookCategories(ctx context.Context, bookId uint64) ([]ResponseCategory, error) {
+	resp := []ResponseCategory{}
+	for _, id := range s.categoryIds {
+		resp = append(resp, ResponseCategory{Id: id})
+	}
+	return resp, nil
+}
+
+func (s *CategoryQueriesStub) SetBookCategories(ctx context.Context, bookId uint64, categoryIds []uint64) error {
+	s.setCalled = true
+	s.categoryIds = categoryIds
+	return s.err
+}
+
+func (s *CategoryQueriesStub) ImportCategories(ctx context.Context, entries []RequestCategoryImport) (ResponseFeedIngest, error) {
+	s.imported = entries
+	return ResponseFeedIngest{Created: len(entries), Errors: []string{}}, s.err
+}
+
+func TestAddCategoryService(t *testing.T) {
+	t.Run("TestAddCategoryServiceShouldDefaultToLocalScheme", func(t *testing.T) {
+		// Arrange
+		query := &CategoryQueriesStub{}
+		service := NewCategoryService(query, logrus.New())
+
+		// Act
+		resp, err := service.AddCategory(context.Background(), RequestCategory{Name: "Fiction"})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.True(t, query.insertCalled)
+		assert.Equal(t, CategorySchemeLocal, resp.Scheme)
+	})
+
+	t.Run("TestAddCategoryServiceShouldReturnBadRequestOnUnknownScheme", func(t *testing.T) {
+		// Arrange
+		query := &CategoryQueriesStub{}
+		service := NewCategoryService(query, logrus.New())
+
+		// Act
+		_, err := service.AddCategory(context.Background(), RequestCategory{Name: "Fiction", Scheme: "dewey"})
+
+		// Assert
+		assert.False(t, query.insertCalled)
+		assert.Equal(t, http.StatusBadRequest, err.(*c.Err).Code)
+	})
+}
+
+func TestPutCategoryService(t *testing.T) {
+	t.Run("TestPutCategoryServiceShouldReturnConflictOnCycle", func(t *testing.T) {
+		// Arrange
+		query := &CategoryQueriesStub{err: errCategoryCycle}
+		service := NewCategoryService(query, logrus.New())
+
+		// Act
+		_, err := service.PutCategory(context.Background(), 1, RequestCategory{Name: "Fiction"})
+
+		// Assert
+		assert.Equal(t, http.StatusConflict, err.(*c.Err).Code)
+	})
+}
+
+func TestListCategoryBooksService(t *testing.T) {
+	t.Run("TestListCategoryBooksServiceShouldFilterByCategory", func(t *testing.T) {
+		// Arrange
+		query := &CategoryQueriesStub{}
+		service := NewCategoryService(query, logrus.New())
+
+		// Act
+		resp, err := service.ListCategoryBooks(context.Background(), 4, GetAllParams{Limit: 10})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Len(t, resp, 1)
+		assert.Equal(t, RequestBookFilter{CategoryId: 4}, query.filter)
+	})
+
+	t.Run("TestListCategoryBooksServiceShouldReturnNotFound", func(t *testing.T) {
+		// Arrange
+		query := &CategoryQueriesStub{err: sql.ErrNoRows}
+		service := NewCategoryService(query, logrus.New())
+
+		// Act
+		_, err := service.ListCategoryBooks(context.Background(), 4, GetAllParams{Limit: 10})
+
+		// Assert
+		assert.False(t, query.filteredCalled)
+		assert.Equal(t, http.StatusNotFound, err.(*c.Err).Code)
+	})
+}
+
+func TestPutBookCategoriesService(t *testing.T) {
+	t.Run("TestPutBookCategoriesServiceShouldReturnAssignedCategories", func(t *testing.T) {
+		// Arrange
+		query := &CategoryQueriesStub{}
+		service := NewCategoryService(query, logrus.New())
+
+		// Act
+		resp, err := service.PutBookCategories(context.Background(), 1, RequestBookCategories{CategoryIds: []uint64{2, 3}})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Len(t, resp, 2)
+	})
+
+	t.Run("TestPutBookCategoriesServiceShouldReturnNotFound", func(t *testing.T) {
+		// Arrange
+		query := &CategoryQueriesStub{bookErr: sql.ErrNoRows}
+		service := NewCategoryService(query, logrus.New())
+
+		// Act
+		_, err := service.PutBookCategories(context.Background(), 1, RequestBookCategories{CategoryIds: []uint64{2}})
+
+		// Assert
+		assert.False(t, query.setCalled)
+		assert.Equal(t, http.StatusNotFound, err.(*c.Err).Code)
+	})
+}
+
+func TestImportCategoriesService(t *testing.T) {
+	t.Run("TestImportCategoriesServiceShouldSplitBisacHeadings", func(t *testing.T) {
+		// Arrange
+		query := &CategoryQueriesStub{}
+		service := NewCategoryService(query, logrus.New())
+		codes := "Code,Heading\n" +
+			"FIC000000,FICTION / General\n" +
+			"FIC028010,\"FICTION / Science Fiction / Space Opera\"\n" +
+			"FIC28,FICTION / Broken\n"
+
+		// Act
+		resp, err := service.ImportCategories(context.Background(), CategorySchemeBISAC, strings.NewReader(codes))
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, []RequestCategoryImport{
+			{Scheme: CategorySchemeBISAC, Code: "FIC000000", Path: []string{"FICTION", "General"}},
+			{Scheme: CategorySchemeBISAC, Code: "FIC028010", Path: []string{"FICTION", "Science Fiction", "Space Opera"}},
+		}, query.imported)
+		assert.Equal(t, 1, resp.Rejected)
+		assert.Len(t, resp.Errors, 1)
+	})
+
+	t.Run("TestImportCategoriesServiceShouldNestThemaCodesByPrefix", func(t *testing.T) {
+		// Arrange
+		query := &CategoryQueriesStub{}
+		service := NewCategoryService(query, logrus.New())
+		codes := "FBA,Modern & contemporary fiction\n" +
+			"F,Fiction & Related items\n" +
+			"FB,Fiction: general & literary\n" +
+			"1KBB-US-NAK,Northern California\n" +
+			"1KBB,USA\n"
+
+		// Act
+		_, err := service.ImportCategories(context.Background(), CategorySchemeThema, strings.NewReader(codes))
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, []RequestCategoryImport{
+			{Scheme: CategorySchemeThema, Code: "F", Path: []string{"Fiction & Related items"}},
+			{Scheme: CategorySchemeThema, Code: "FB", Path: []string{"Fiction & Related items", "Fiction: general & literary"}},
+			{Scheme: CategorySchemeThema, Code: "FBA", Path: []string{"Fiction & Related items", "Fiction: general & literary", "Modern & contemporary fiction"}},
+			{Scheme: CategorySchemeThema, Code: "1KBB", Path: []string{"USA"}},
+			{Scheme: CategorySchemeThema, Code: "1KBB-US-NAK", Path: []string{"USA", "Northern California"}},
+		}, query.imported)
+	})
+
+	t.Run("TestImportCategoriesServiceShouldReturnBadRequestOnUnknownScheme", func(t *testing.T) {
+		// Arrange
+		query := &CategoryQueriesStub{}
+		service := NewCategoryService(query, logrus.New())
+
+		// Act
+		_, err := service.ImportCategories(context.Background(), CategorySchemeLocal, strings.NewReader("F,Fiction\n"))
+
+		// Assert
+		assert.Nil(t, query.imported)
+		assert.Equal(t, http.StatusBadRequest, err.(*c.Err).Code)
+	})
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+func (db Query) UpsertBookCover(ctx context.Context, req RequestCover) (ResponseCover, error) {
+	const query = `INSERT INTO book_covers
+	(book_id, version, content_type, size, width, height)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	ON CONFLICT (book_id) DO UPDATE
+	SET version = EXCLUDED.version, content_type = EXCLUDED.content_type, size = EXCLUDED.size,
+		width = EXCLUDED.width, height = EXCLUDED.height, updated_at = NOW()
+	RETURNING book_id, version, content_type, size, width, height, updated_at;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return ResponseCover{}, err
+	}
+	defer stmt.Close()
+
+	return scanCover(stmt.QueryRowContext(ctx, req.BookId, req.Version, req.ContentType, req.Size, req.Width, req.Height))
+}
+
+func (db Query) SelectBookCover(ctx context.Context, bookId uint64) (ResponseCover, error) {
+	const query = `SELECT book_id, version, content_type, size, width, height, updated_at
+	FROM book_covers
+	WHERE book_id = $1;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return ResponseCover{}, err
+	}
+	defer stmt.Close()
+
+	return scanCover(stmt.QueryRowContext(ctx, bookId))
+}
+
+func (db Query) DeleteBookCover(ctx context.Context, bookId uint64) error {
+	const query = `DELETE FROM book_covers WHERE book_id = $1;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, bookId)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// SelectCoverVersions returns the current cover version of each given book
+// that has one, so listings can build cover URLs without a query per book.
+func (db Query) SelectCoverVersions(ctx context.Context, bookIds []uint64) (map[uint64]string, error) {
+	const query = `SELECT book_id, version
+	FROM book_covers
+	WHERE book_id = ANY($1);`
+
+	resp := map[uint64]string{}
+	if len(bookIds) == 0 {
+		return resp, nil
+	}
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	ids := make([]int64, len(bookIds))
+	for i, id := range bookIds {
+		ids[i] = int64(id)
+	}
+
+	rows, err := stmt.QueryContext(ctx, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bookId uint64
+		var version string
+		if err := rows.Scan(&bookId, &version); err != nil {
+			return nil, err
+		}
+		resp[bookId] = version
+	}
+	return resp, rows.Err()
+}
+
+func scanCover(row rowScanner) (ResponseCover, error) {
+	resp := ResponseCover{}
+	err := row.Scan(&resp.BookId, &resp.Version, &resp.ContentType, &resp.Size, &resp.Width, &resp.Height, &resp.UpdatedAt)
+	if err != nil {
+		return ResponseCover{}, err
+	}
+	return resp, nil
+}
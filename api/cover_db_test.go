@@ -0,0 +1,108 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpsertBookCover(t *testing.T) {
+	t.Run("TestUpsertBookCoverShouldReturnCover", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		now := time.Now()
+		mock.ExpectPrepare(regexp.QuoteMeta(`INSERT INTO book_covers (book_id, version, content_type, size, width, height) VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (book_id) DO UPDATE`)).
+			ExpectQuery().
+			WithArgs(1, "5f2b9c0d1e3a4b6c", "image/png", 2048, 600, 900).
+			WillReturnRows(sqlmock.NewRows([]string{"book_id", "version", "content_type", "size", "width", "height", "updated_at"}).
+				AddRow(1, "5f2b9c0d1e3a4b6c", "image/png", 2048, 600, 900, now))
+
+		query := NewDB(db)
+
+		// Act
+		res, err := query.UpsertBookCover(context.Background(), RequestCover{
+			BookId: 1, Version: "5f2b9c0d1e3a4b6c", ContentType: "image/png", Size: 2048, Width: 600, Height: 900,
+		})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(1), res.BookId)
+		assert.Equal(t, 900, res.Height)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDeleteBookCover(t *testing.T) {
+	t.Run("TestDeleteBookCoverShouldReturnErrNoRowsWhenMissing", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectPrepare(regexp.QuoteMeta(`DELETE FROM book_covers WHERE book_id = $1;`)).
+			ExpectExec().
+			WithArgs(9).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		query := NewDB(db)
+
+		// Act
+		err = query.DeleteBookCover(context.Background(), 9)
+
+		// Assert
+		assert.Equal(t, sql.ErrNoRows, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestSelectCoverVersions(t *testing.T) {
+	t.Run("TestSelectCoverVersionsShouldMapBooksWithCovers", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectPrepare(regexp.QuoteMeta(`SELECT book_id, version FROM book_covers WHERE book_id = ANY($1);`)).
+			ExpectQuery().
+			WithArgs(pq.Array([]int64{1, 2})).
+			WillReturnRows(sqlmock.NewRows([]string{"book_id", "version"}).AddRow(2, "abc"))
+
+		query := NewDB(db)
+
+		// Act
+		res, err := query.SelectCoverVersions(context.Background(), []uint64{1, 2})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, map[uint64]string{2: "abc"}, res)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestSelectCoverVersionsShouldSkipQueryForNoBooks", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		query := NewDB(db)
+
+		// Act
+		res, err := query.SelectCoverVersions(context.Background(), nil)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Empty(t, res)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
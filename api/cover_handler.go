@@ -0,0 +1,127 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/paquesqueue/bookstore/storage"
+)
+
+const (
+	coverFormField = "cover"
+
+	// coverCacheControl lets clients and proxies keep covers for a day; the
+	// URLs carry the cover version, so a new upload is picked up at once.
+	coverCacheControl = "public, max-age=86400"
+)
+
+type CoverHandlrQueries interface {
+	PutCover(ctx context.Context, bookId uint64, r io.Reader) (ResponseCover, error)
+	OpenCover(ctx context.Context, bookId uint64, size string) (ResponseCover, storage.Object, error)
+	DeleteCover(ctx context.Context, bookId uint64) error
+}
+
+type CoverHandlr struct {
+	handler CoverHandlrQueries
+	log     c.Log
+}
+
+func NewCoverHandlr(h CoverHandlrQueries, l c.Log) CoverHandlr {
+	return CoverHandlr{h, l}
+}
+
+// PutCover accepts the image either as the "cover" field of a multipart form
+// or as the raw request body.
+func (h CoverHandlr) PutCover(ctx echo.Context) error {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	req := ctx.Request()
+	// Leave room for the multipart framing around the image itself.
+	req.Body = http.MaxBytesReader(ctx.Response(), req.Body, coverMaxBytes+1<<20)
+
+	var body io.Reader = req.Body
+	if strings.HasPrefix(req.Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+		file, err := ctx.FormFile(coverFormField)
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				return ctx.NoContent(http.StatusRequestEntityTooLarge)
+			}
+			return ctx.NoContent(http.StatusBadRequest)
+		}
+		f, err := file.Open()
+		if err != nil {
+			c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error PutCover Handler : %v", err)
+			return ctx.NoContent(http.StatusInternalServerError)
+		}
+		defer f.Close()
+		body = f
+	}
+
+	res, err := h.handler.PutCover(ctx.Request().Context(), uint64(id), body)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error PutCover Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, res)
+}
+
+// ServeCover streams a cover size, answering conditional and range requests
+// through http.ServeContent.
+func (h CoverHandlr) ServeCover(ctx echo.Context) error {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	size := ctx.Param("size")
+	if size == "" {
+		size = CoverOriginal
+	}
+
+	cover, obj, err := h.handler.OpenCover(ctx.Request().Context(), uint64(id), size)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error ServeCover Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	defer obj.Close()
+
+	header := ctx.Response().Header()
+	header.Set(echo.HeaderContentType, obj.Info().ContentType)
+	header.Set("Cache-Control", coverCacheControl)
+	header.Set("ETag", `"`+cover.Version+"-"+size+`"`)
+	http.ServeContent(ctx.Response(), ctx.Request(), "", cover.UpdatedAt, obj)
+	return nil
+}
+
+func (h CoverHandlr) DeleteCover(ctx echo.Context) error {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	err = h.handler.DeleteCover(ctx.Request().Context(), uint64(id))
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error DeleteCover Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, "Deleted Successfully")
+}
@@ -0,0 +1,190 @@
+//go:build unit
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"image/color"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/paquesqueue/bookstore/storage"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type CoverHandlrSuccess struct {
+	CoverHandlrError
+	body []byte
+}
+
+func (h *CoverHandlrSuccess) PutCover(ctx context.Context, bookId uint64, r io.Reader) (ResponseCover, error) {
+	body, err := io.ReadAll(r)
+	h.body = body
+	return ResponseCover{BookId: bookId, Version: "abc"}, err
+}
+
+type CoverHandlrError struct {
+	statusCodeError int
+}
+
+func (h *CoverHandlrError) PutCover(ctx context.Context, bookId uint64, r io.Reader) (ResponseCover, error) {
+	return ResponseCover{}, &c.Err{Code: h.statusCodeError}
+}
+
+func (h *CoverHandlrError) OpenCover(ctx context.Context, bookId uint64, size string) (ResponseCover, storage.Object, error) {
+	return ResponseCover{}, nil, &c.Err{Code: h.statusCodeError}
+}
+
+func (h *CoverHandlrError) DeleteCover(ctx context.Context, bookId uint64) error {
+	return &c.Err{Code: h.statusCodeError}
+}
+
+func newCoverContext(req *http.Request, rec *httptest.ResponseRecorder, size string) echo.Context {
+	ctx := echo.New().NewContext(req, rec)
+	ctx.SetPath("/books/:id/cover/:size")
+	ctx.SetParamNames("id", "size")
+	ctx.SetParamValues("1", size)
+	return ctx
+}
+
+func TestPutCoverHandler(t *testing.T) {
+	t.Run("TestPutCoverHandlerShouldAcceptRawBody", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader("raw image"))
+		req.Header.Set(echo.HeaderContentType, "image/png")
+		rec := httptest.NewRecorder()
+		ctx := newCoverContext(req, rec, "")
+
+		handlrServ := &CoverHandlrSuccess{}
+		handler := NewCoverHandlr(handlrServ, logrus.New())
+
+		// Act
+		err := handler.PutCover(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, "raw image", string(handlrServ.body))
+		}
+	})
+
+	t.Run("TestPutCoverHandlerShouldAcceptMultipartField", func(t *testing.T) {
+		// Arrange
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		part, err := form.CreateFormFile("cover", "cover.png")
+		assert.NoError(t, err)
+		part.Write([]byte("multipart image"))
+		form.Close()
+
+		req := httptest.NewRequest(http.MethodPut, "/", &body)
+		req.Header.Set(echo.HeaderContentType, form.FormDataContentType())
+		rec := httptest.NewRecorder()
+		ctx := newCoverContext(req, rec, "")
+
+		handlrServ := &CoverHandlrSuccess{}
+		handler := NewCoverHandlr(handlrServ, logrus.New())
+
+		// Act
+		err = handler.PutCover(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, "multipart image", string(handlrServ.body))
+		}
+	})
+
+	t.Run("TestPutCoverHandlerShouldReturnHTTPStatus415", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader("text"))
+		rec := httptest.NewRecorder()
+		ctx := newCoverContext(req, rec, "")
+
+		handler := NewCoverHandlr(&CoverHandlrError{statusCodeError: http.StatusUnsupportedMediaType}, logrus.New())
+
+		// Act
+		err := handler.PutCover(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+		}
+	})
+}
+
+func TestServeCoverHandler(t *testing.T) {
+	t.Run("TestServeCoverHandlerShouldAnswerRangeWithCacheHeaders", func(t *testing.T) {
+		// Arrange
+		services, _, _ := newTestCoverService(t)
+		body := testCoverPNG(t, 30, 40, color.White)
+		cover, err := services.PutCover(context.Background(), 1, bytes.NewReader(body))
+		assert.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Range", "bytes=0-7")
+		rec := httptest.NewRecorder()
+		ctx := newCoverContext(req, rec, CoverOriginal)
+
+		handler := NewCoverHandlr(services, logrus.New())
+
+		// Act
+		err = handler.ServeCover(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusPartialContent, rec.Code)
+			assert.Equal(t, body[:8], rec.Body.Bytes())
+			assert.Equal(t, "image/png", rec.Header().Get(echo.HeaderContentType))
+			assert.Equal(t, coverCacheControl, rec.Header().Get("Cache-Control"))
+			assert.Equal(t, `"`+cover.Version+`-original"`, rec.Header().Get("ETag"))
+		}
+	})
+
+	t.Run("TestServeCoverHandlerShouldReturnHTTPStatus304ForMatchingETag", func(t *testing.T) {
+		// Arrange
+		services, _, _ := newTestCoverService(t)
+		cover, err := services.PutCover(context.Background(), 1, bytes.NewReader(testCoverPNG(t, 30, 40, color.White)))
+		assert.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("If-None-Match", `"`+cover.Version+`-small"`)
+		rec := httptest.NewRecorder()
+		ctx := newCoverContext(req, rec, CoverSmall)
+
+		handler := NewCoverHandlr(services, logrus.New())
+
+		// Act
+		err = handler.ServeCover(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusNotModified, rec.Code)
+		}
+	})
+
+	t.Run("TestServeCoverHandlerShouldReturnHTTPStatus404", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		ctx := newCoverContext(req, rec, CoverSmall)
+
+		handler := NewCoverHandlr(&CoverHandlrError{statusCodeError: http.StatusNotFound}, logrus.New())
+
+		// Act
+		err := handler.ServeCover(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusNotFound, rec.Code)
+		}
+	})
+}
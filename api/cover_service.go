@@ -0,0 +1,255 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/paquesqueue/bookstore/storage"
+	"github.com/paquesqueue/bookstore/thumbnail"
+	"github.com/paquesqueue/bookstore/tracing"
+)
+
+const (
+	CoverOriginal = "original"
+	CoverSmall    = "small"
+	CoverMedium   = "medium"
+	CoverLarge    = "large"
+
+	// coverMaxBytes bounds uploaded originals.
+	coverMaxBytes = 5 << 20
+)
+
+// coverSizes lists the generated thumbnails from largest to smallest; each
+// one is scaled down from the previous to keep resizing cheap.
+var coverSizes = []struct {
+	name  string
+	width int
+}{
+	{CoverLarge, 640},
+	{CoverMedium, 320},
+	{CoverSmall, 120},
+}
+
+type CoverQueries interface {
+	SelectBookByID(ctx context.Context, id uint64) (*ResponseBook, error)
+	UpsertBookCover(ctx context.Context, req RequestCover) (ResponseCover, error)
+	SelectBookCover(ctx context.Context, bookId uint64) (ResponseCover, error)
+	DeleteBookCover(ctx context.Context, bookId uint64) error
+}
+
+type CoverServices struct {
+	query CoverQueries
+	store storage.BlobStore
+	log   c.Log
+}
+
+func NewCoverService(q CoverQueries, store storage.BlobStore, l c.Log) CoverServices {
+	return CoverServices{q, store, l}
+}
+
+// PutCover validates and stores an uploaded JPEG or PNG cover together with
+// its thumbnails. Blobs are keyed by a content hash, so a replaced cover gets
+// new URLs and cached copies of the old one never go stale.
+func (s CoverServices) PutCover(ctx context.Context, bookId uint64, r io.Reader) (ResponseCover, error) {
+	ctx, span := tracing.Start(ctx, "CoverServices.PutCover")
+	defer span.End()
+
+	_, err := s.query.SelectBookByID(ctx, bookId)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error SelectBookByID : %v", err)
+		if err == sql.ErrNoRows {
+			return ResponseCover{}, &c.Err{Code: http.StatusNotFound, Remark: "Error Book Not Found", Original: err}
+		}
+		return ResponseCover{}, &c.Err{Code: http.StatusInternalServerError, Remark: "Error PutCover Service", Original: err}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r, coverMaxBytes+1))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return ResponseCover{}, &c.Err{Code: http.StatusRequestEntityTooLarge, Remark: "Error Cover Too Large", Original: err}
+		}
+		return ResponseCover{}, &c.Err{Code: http.StatusBadRequest, Remark: "Error Reading Cover", Original: err}
+	}
+	if len(body) > coverMaxBytes {
+		return ResponseCover{}, &c.Err{Code: http.StatusRequestEntityTooLarge, Remark: "Error Cover Too Large"}
+	}
+
+	contentType := http.DetectContentType(body)
+	if contentType != thumbnail.ContentTypeJPEG && contentType != thumbnail.ContentTypePNG {
+		return ResponseCover{}, &c.Err{Code: http.StatusUnsupportedMediaType, Remark: "Error Cover Must Be JPEG Or PNG"}
+	}
+
+	img, err := thumbnail.Decode(bytes.NewReader(body))
+	if err != nil {
+		if err == thumbnail.ErrTooLarge {
+			return ResponseCover{}, &c.Err{Code: http.StatusRequestEntityTooLarge, Remark: "Error Cover Too Large", Original: err}
+		}
+		return ResponseCover{}, &c.Err{Code: http.StatusBadRequest, Remark: "Error Invalid Cover Image", Original: err}
+	}
+
+	sum := sha256.Sum256(body)
+	req := RequestCover{
+		BookId:      bookId,
+		Version:     hex.EncodeToString(sum[:8]),
+		ContentType: contentType,
+		Size:        int64(len(body)),
+		Width:       img.Bounds().Dx(),
+		Height:      img.Bounds().Dy(),
+	}
+
+	original := coverKey(bookId, req.Version, CoverOriginal, contentType)
+	err = s.store.Put(ctx, original, bytes.NewReader(body), storage.Info{Size: req.Size, ContentType: contentType})
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error Put Cover : %v", err)
+		return ResponseCover{}, &c.Err{Code: http.StatusInternalServerError, Remark: "Error PutCover Service", Original: err}
+	}
+
+	src := img
+	for _, size := range coverSizes {
+		thumb := thumbnail.Resize(src, size.width)
+		var buf bytes.Buffer
+		if err := thumbnail.EncodeJPEG(&buf, thumb); err != nil {
+			c.LogFrom(ctx, s.log).Errorf("Error EncodeJPEG : %v", err)
+			return ResponseCover{}, &c.Err{Code: http.StatusInternalServerError, Remark: "Error PutCover Service", Original: err}
+		}
+		key := coverKey(bookId, req.Version, size.name, thumbnail.ContentTypeJPEG)
+		info := storage.Info{Size: int64(buf.Len()), ContentType: thumbnail.ContentTypeJPEG}
+		if err := s.store.Put(ctx, key, &buf, info); err != nil {
+			c.LogFrom(ctx, s.log).Errorf("Error Put Cover : %v", err)
+			return ResponseCover{}, &c.Err{Code: http.StatusInternalServerError, Remark: "Error PutCover Service", Original: err}
+		}
+		src = thumb
+	}
+
+	previous, err := s.query.SelectBookCover(ctx, bookId)
+	if err != nil && err != sql.ErrNoRows {
+		c.LogFrom(ctx, s.log).Errorf("Error SelectBookCover : %v", err)
+		return ResponseCover{}, &c.Err{Code: http.StatusInternalServerError, Remark: "Error PutCover Service", Original: err}
+	}
+
+	resp, err := s.query.UpsertBookCover(ctx, req)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error UpsertBookCover : %v", err)
+		return ResponseCover{}, coverErr(err, "Error PutCover Service")
+	}
+	if previous.Version != "" && previous.Version != resp.Version {
+		s.deleteBlobs(ctx, previous)
+	}
+	resp.Urls = coverUrls(bookId, resp.Version)
+	return resp, nil
+}
+
+// OpenCover opens the stored original or one of its thumbnails. The caller
+// must close the returned object.
+func (s CoverServices) OpenCover(ctx context.Context, bookId uint64, size string) (ResponseCover, storage.Object, error) {
+	ctx, span := tracing.Start(ctx, "CoverServices.OpenCover")
+	defer span.End()
+
+	if !validCoverSize(size) {
+		return ResponseCover{}, nil, &c.Err{Code: http.StatusNotFound, Remark: "Error Cover Size Not Found"}
+	}
+
+	cover, err := s.query.SelectBookCover(ctx, bookId)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error SelectBookCover : %v", err)
+		return ResponseCover{}, nil, coverErr(err, "Error GetCover Service")
+	}
+
+	obj, err := s.store.Open(ctx, coverKey(bookId, cover.Version, size, cover.ContentType))
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error Open Cover : %v", err)
+		if err == storage.ErrNotFound {
+			return ResponseCover{}, nil, &c.Err{Code: http.StatusNotFound, Remark: "Error Cover Not Found", Original: err}
+		}
+		return ResponseCover{}, nil, &c.Err{Code: http.StatusInternalServerError, Remark: "Error GetCover Service", Original: err}
+	}
+	cover.Urls = coverUrls(bookId, cover.Version)
+	return cover, obj, nil
+}
+
+func (s CoverServices) DeleteCover(ctx context.Context, bookId uint64) error {
+	ctx, span := tracing.Start(ctx, "CoverServices.DeleteCover")
+	defer span.End()
+
+	cover, err := s.query.SelectBookCover(ctx, bookId)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error SelectBookCover : %v", err)
+		return coverErr(err, "Error DeleteCover Service")
+	}
+
+	err = s.query.DeleteBookCover(ctx, bookId)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error DeleteBookCover : %v", err)
+		return coverErr(err, "Error DeleteCover Service")
+	}
+	s.deleteBlobs(ctx, cover)
+	return nil
+}
+
+// deleteBlobs removes the files of a cover version that is no longer
+// referenced. Failures only leave orphaned blobs behind, so they are logged.
+func (s CoverServices) deleteBlobs(ctx context.Context, cover ResponseCover) {
+	keys := []string{coverKey(cover.BookId, cover.Version, CoverOriginal, cover.ContentType)}
+	for _, size := range coverSizes {
+		keys = append(keys, coverKey(cover.BookId, cover.Version, size.name, thumbnail.ContentTypeJPEG))
+	}
+	for _, key := range keys {
+		if err := s.store.Delete(ctx, key); err != nil {
+			c.LogFrom(ctx, s.log).Errorf("Error Delete Cover %v : %v", key, err)
+		}
+	}
+}
+
+func validCoverSize(size string) bool {
+	if size == CoverOriginal {
+		return true
+	}
+	for _, s := range coverSizes {
+		if s.name == size {
+			return true
+		}
+	}
+	return false
+}
+
+// coverKey names the blob of one cover size. Thumbnails are always JPEG; the
+// original keeps the extension of its uploaded type.
+func coverKey(bookId uint64, version, size, contentType string) string {
+	ext := "jpg"
+	if size == CoverOriginal && contentType == thumbnail.ContentTypePNG {
+		ext = "png"
+	}
+	return fmt.Sprintf("covers/%d/%s/%s.%s", bookId, version, size, ext)
+}
+
+// coverUrls lists the public URLs of every cover size. The version query
+// parameter changes with the content, so the URLs can be cached for long.
+func coverUrls(bookId uint64, version string) map[string]string {
+	urls := map[string]string{
+		CoverOriginal: fmt.Sprintf("/books/%d/cover/%s?v=%s", bookId, CoverOriginal, version),
+	}
+	for _, size := range coverSizes {
+		urls[size.name] = fmt.Sprintf("/books/%d/cover/%s?v=%s", bookId, size.name, version)
+	}
+	return urls
+}
+
+func coverErr(err error, remark string) *c.Err {
+	switch {
+	case err == sql.ErrNoRows:
+		return &c.Err{Code: http.StatusNotFound, Remark: "Error Cover Not Found", Original: err}
+	case isPqError(err, pqForeignKeyViolation):
+		return &c.Err{Code: http.StatusNotFound, Remark: "Error Book Not Found", Original: err}
+	default:
+		return &c.Err{Code: http.StatusInternalServerError, Remark: remark, Original: err}
+	}
+}
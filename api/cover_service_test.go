@@ -0,0 +1,223 @@
+//go:build unit
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/paquesqueue/bookstore/storage"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type CoverQueriesStub struct {
+	books  map[uint64]bool
+	covers map[uint64]ResponseCover
+}
+
+func (q *CoverQueriesStub) SelectBookByID(ctx context.Context, id uint64) (*ResponseBook, error) {
+	if !q.books[id] {
+		return nil, sql.ErrNoRows
+	}
+	return &ResponseBook{Id: id}, nil
+}
+
+func (q *CoverQueriesStub) UpsertBookCover(ctx context.Context, req RequestCover) (ResponseCover, error) {
+	resp := ResponseCover{
+		BookId:      req.BookId,
+		Version:     req.Version,
+		ContentType: req.ContentType,
+		Size:        req.Size,
+		Width:       req.Width,
+		Height:      req.Height,
+		UpdatedAt:   time.Now(),
+	}
+	q.covers[req.BookId] = resp
+	return resp, nil
+}
+
+func (q *CoverQueriesStub) SelectBookCover(ctx context.Context, bookId uint64) (ResponseCover, error) {
+	cover, ok := q.covers[bookId]
+	if !ok {
+		return ResponseCover{}, sql.ErrNoRows
+	}
+	return cover, nil
+}
+
+func (q *CoverQueriesStub) DeleteBookCover(ctx context.Context, bookId uint64) error {
+	if _, ok := q.covers[bookId]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(q.covers, bookId)
+	return nil
+}
+
+func newCoverQueriesStub() *CoverQueriesStub {
+	return &CoverQueriesStub{books: map[uint64]bool{1: true}, covers: map[uint64]ResponseCover{}}
+}
+
+func testCoverPNG(t *testing.T, width, height int, fill color.Color) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, fill)
+		}
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func newTestCoverService(t *testing.T) (CoverServices, *CoverQueriesStub, storage.BlobStore) {
+	store, err := storage.NewFileStore(t.TempDir())
+	assert.NoError(t, err)
+	query := newCoverQueriesStub()
+	return NewCoverService(query, store, logrus.New()), query, store
+}
+
+func TestPutCover(t *testing.T) {
+	t.Run("TestPutCoverShouldStoreOriginalAndThumbnails", func(t *testing.T) {
+		// Arrange
+		services, query, store := newTestCoverService(t)
+		body := testCoverPNG(t, 800, 1200, color.RGBA{200, 30, 30, 255})
+
+		// Act
+		res, err := services.PutCover(context.Background(), 1, bytes.NewReader(body))
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "image/png", res.ContentType)
+		assert.Equal(t, 800, res.Width)
+		assert.Equal(t, 1200, res.Height)
+		assert.Len(t, res.Version, 16)
+		assert.Equal(t, "/books/1/cover/medium?v="+res.Version, res.Urls[CoverMedium])
+		assert.Equal(t, res.Version, query.covers[1].Version)
+
+		original, err := store.Open(context.Background(), "covers/1/"+res.Version+"/original.png")
+		assert.NoError(t, err)
+		defer original.Close()
+		assert.Equal(t, int64(len(body)), original.Info().Size)
+
+		small, err := store.Open(context.Background(), "covers/1/"+res.Version+"/small.jpg")
+		assert.NoError(t, err)
+		defer small.Close()
+		img, _, err := image.Decode(small)
+		assert.NoError(t, err)
+		assert.Equal(t, 120, img.Bounds().Dx())
+		assert.Equal(t, 180, img.Bounds().Dy())
+	})
+
+	t.Run("TestPutCoverShouldRemoveReplacedVersion", func(t *testing.T) {
+		// Arrange
+		services, _, store := newTestCoverService(t)
+		first, err := services.PutCover(context.Background(), 1, bytes.NewReader(testCoverPNG(t, 40, 60, color.White)))
+		assert.NoError(t, err)
+
+		// Act
+		second, err := services.PutCover(context.Background(), 1, bytes.NewReader(testCoverPNG(t, 40, 60, color.Black)))
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NotEqual(t, first.Version, second.Version)
+		_, err = store.Open(context.Background(), "covers/1/"+first.Version+"/large.jpg")
+		assert.Equal(t, storage.ErrNotFound, err)
+	})
+
+	t.Run("TestPutCoverShouldRejectUnsupportedType", func(t *testing.T) {
+		// Arrange
+		services, _, _ := newTestCoverService(t)
+
+		// Act
+		_, err := services.PutCover(context.Background(), 1, bytes.NewReader([]byte("GIF89a not a cover")))
+
+		// Assert
+		assert.Equal(t, http.StatusUnsupportedMediaType, err.(*c.Err).Code)
+	})
+
+	t.Run("TestPutCoverShouldRejectTooLargeUpload", func(t *testing.T) {
+		// Arrange
+		services, _, _ := newTestCoverService(t)
+		body := io.MultiReader(bytes.NewReader(testCoverPNG(t, 1, 1, color.White)), bytes.NewReader(make([]byte, coverMaxBytes)))
+
+		// Act
+		_, err := services.PutCover(context.Background(), 1, body)
+
+		// Assert
+		assert.Equal(t, http.StatusRequestEntityTooLarge, err.(*c.Err).Code)
+	})
+
+	t.Run("TestPutCoverShouldRejectCorruptImage", func(t *testing.T) {
+		// Arrange
+		services, _, _ := newTestCoverService(t)
+		body := testCoverPNG(t, 10, 10, color.White)[:40]
+
+		// Act
+		_, err := services.PutCover(context.Background(), 1, bytes.NewReader(body))
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, err.(*c.Err).Code)
+	})
+
+	t.Run("TestPutCoverShouldReturnNotFoundForMissingBook", func(t *testing.T) {
+		// Arrange
+		services, _, _ := newTestCoverService(t)
+
+		// Act
+		_, err := services.PutCover(context.Background(), 2, bytes.NewReader(testCoverPNG(t, 10, 10, color.White)))
+
+		// Assert
+		assert.Equal(t, http.StatusNotFound, err.(*c.Err).Code)
+	})
+}
+
+func TestOpenCover(t *testing.T) {
+	t.Run("TestOpenCoverShouldRejectUnknownSize", func(t *testing.T) {
+		// Arrange
+		services, _, _ := newTestCoverService(t)
+
+		// Act
+		_, _, err := services.OpenCover(context.Background(), 1, "huge")
+
+		// Assert
+		assert.Equal(t, http.StatusNotFound, err.(*c.Err).Code)
+	})
+
+	t.Run("TestOpenCoverShouldReturnNotFoundWithoutCover", func(t *testing.T) {
+		// Arrange
+		services, _, _ := newTestCoverService(t)
+
+		// Act
+		_, _, err := services.OpenCover(context.Background(), 1, CoverSmall)
+
+		// Assert
+		assert.Equal(t, http.StatusNotFound, err.(*c.Err).Code)
+	})
+}
+
+func TestDeleteCover(t *testing.T) {
+	t.Run("TestDeleteCoverShouldRemoveRowAndBlobs", func(t *testing.T) {
+		// Arrange
+		services, query, store := newTestCoverService(t)
+		cover, err := services.PutCover(context.Background(), 1, bytes.NewReader(testCoverPNG(t, 20, 30, color.White)))
+		assert.NoError(t, err)
+
+		// Act
+		err = services.DeleteCover(context.Background(), 1)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Empty(t, query.covers)
+		_, err = store.Open(context.Background(), "covers/1/"+cover.Version+"/original.png")
+		assert.Equal(t, storage.ErrNotFound, err)
+	})
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"context"
+	"time"
+)
+
+// SelectLoginLocks returns the lockouts in force for a username or an IP
+// address.
+func (db Query) SelectLoginLocks(ctx context.Context, username string, ip string) ([]LoginLock, error) {
+	const query = `SELECT scope, subject, locked_until FROM login_failures
+	WHERE ((scope = $1 AND subject = $2) OR (scope = $3 AND subject = $4)) AND locked_until > NOW();`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, LoginScopeUser, username, LoginScopeIP, ip)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	locks := []LoginLock{}
+	for rows.Next() {
+		lock := LoginLock{}
+		if err := rows.Scan(&lock.Scope, &lock.Subject, &lock.LockedUntil); err != nil {
+			return nil, err
+		}
+		locks = append(locks, lock)
+	}
+	return locks, rows.Err()
+}
+
+// RecordLoginFailure counts a failed login and returns how many there have
+// been, starting over when the last one is older than window.
+func (db Query) RecordLoginFailure(ctx context.Context, scope string, subject string, window time.Duration) (int, error) {
+	const query = `INSERT INTO login_failures (scope, subject, failures, last_failed_at)
+	VALUES ($1, $2, 1, NOW())
+	ON CONFLICT (scope, subject) DO UPDATE SET
+		failures = CASE WHEN login_failures.last_failed_at < NOW() - make_interval(secs => $3) THEN 1 ELSE login_failures.failures + 1 END,
+		last_failed_at = NOW()
+	RETURNING failures;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	var failures int
+	err = stmt.QueryRowContext(ctx, scope, subject, window.Seconds()).Scan(&failures)
+	return failures, err
+}
+
+// LockLogin refuses logins for a subject until the given time.
+func (db Query) LockLogin(ctx context.Context, scope string, subject string, until time.Time) error {
+	const query = `UPDATE login_failures SET locked_until = $3 WHERE scope = $1 AND subject = $2;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, scope, subject, until)
+	return err
+}
+
+// ClearLoginFailures forgets the failed logins of a subject, lifting any
+// lockout.
+func (db Query) ClearLoginFailures(ctx context.Context, scope string, subject string) error {
+	const query = `DELETE FROM login_failures WHERE scope = $1 AND subject = $2;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, scope, subject)
+	return err
+}
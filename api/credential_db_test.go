@@ -0,0 +1,110 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectLoginLocks(t *testing.T) {
+	t.Run("TestSelectLoginLocksShouldLookUpUserAndIP", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		lockedUntil := time.Date(2024, 1, 1, 0, 5, 0, 0, time.UTC)
+		mock.ExpectPrepare(regexp.QuoteMeta(`SELECT scope, subject, locked_until FROM login_failures WHERE ((scope = $1 AND subject = $2) OR (scope = $3 AND subject = $4)) AND locked_until > NOW();`)).
+			ExpectQuery().
+			WithArgs(LoginScopeUser, "tester", LoginScopeIP, "10.0.0.1").
+			WillReturnRows(sqlmock.NewRows([]string{"scope", "subject", "locked_until"}).
+				AddRow(LoginScopeIP, "10.0.0.1", lockedUntil))
+
+		query := NewDB(db)
+
+		// Act
+		locks, err := query.SelectLoginLocks(context.Background(), "tester", "10.0.0.1")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, []LoginLock{{Scope: LoginScopeIP, Subject: "10.0.0.1", LockedUntil: lockedUntil}}, locks)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRecordLoginFailure(t *testing.T) {
+	t.Run("TestRecordLoginFailureShouldReturnFailuresInWindow", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectPrepare(regexp.QuoteMeta(`INSERT INTO login_failures (scope, subject, failures, last_failed_at)`)).
+			ExpectQuery().
+			WithArgs(LoginScopeUser, "tester", float64(86400)).
+			WillReturnRows(sqlmock.NewRows([]string{"failures"}).AddRow(4))
+
+		query := NewDB(db)
+
+		// Act
+		failures, err := query.RecordLoginFailure(context.Background(), LoginScopeUser, "tester", 24*time.Hour)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, 4, failures)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestLockLogin(t *testing.T) {
+	t.Run("TestLockLoginShouldSetLockedUntil", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		until := time.Date(2024, 1, 1, 0, 5, 0, 0, time.UTC)
+		mock.ExpectPrepare(regexp.QuoteMeta(`UPDATE login_failures SET locked_until = $3 WHERE scope = $1 AND subject = $2;`)).
+			ExpectExec().
+			WithArgs(LoginScopeIP, "10.0.0.1", until).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		query := NewDB(db)
+
+		// Act
+		err = query.LockLogin(context.Background(), LoginScopeIP, "10.0.0.1", until)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestClearLoginFailures(t *testing.T) {
+	t.Run("TestClearLoginFailuresShouldDeleteSubject", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectPrepare(regexp.QuoteMeta(`DELETE FROM login_failures WHERE scope = $1 AND subject = $2;`)).
+			ExpectExec().
+			WithArgs(LoginScopeUser, "tester").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		query := NewDB(db)
+
+		// Act
+		err = query.ClearLoginFailures(context.Background(), LoginScopeUser, "tester")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
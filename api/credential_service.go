@@ -0,0 +1,272 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"sync"
+	"time"
+
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/paquesqueue/bookstore/tracing"
+	"github.com/paquesqueue/bookstore/utils"
+)
+
+// Failed logins are counted per account and per IP address, so both
+// guessing one password and trying many accounts from one address lead to
+// a lockout.
+const (
+	LoginScopeUser = "user"
+	LoginScopeIP   = "ip"
+)
+
+// Security events written to the security log.
+const (
+	eventLoginSucceeded  = "login_succeeded"
+	eventLoginFailed     = "login_failed"
+	eventLoginBlocked    = "login_blocked"
+	eventLockedOut       = "locked_out"
+	eventAccountUnlocked = "account_unlocked"
+	eventMFASucceeded    = "mfa_succeeded"
+	eventMFAFailed       = "mfa_failed"
+	eventMFAEnabled      = "mfa_enabled"
+	eventMFADisabled     = "mfa_disabled"
+	eventRecoveryCodes   = "recovery_codes_replaced"
+	eventReauthFailed    = "reauth_failed"
+	eventProfileChanged  = "profile_changed"
+	eventEmailChanged    = "email_changed"
+	eventPasswordChanged = "password_changed"
+	eventUsernameChanged = "username_changed"
+	eventDataExported    = "data_exported"
+	eventUserErased      = "user_erased"
+	eventAdminBootstrap  = "admin_bootstrapped"
+)
+
+// LoginLock is a lockout in force for a username or an IP address.
+type LoginLock struct {
+	Scope       string
+	Subject     string
+	LockedUntil time.Time
+}
+
+// dummyHash is checked against for unknown usernames, so they take as long
+// to reject as a wrong password. It is made on first use, after the
+// password hashing settings are applied.
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+func unknownUserHash() string {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = utils.HashPassword("not the password of any user")
+	})
+	return dummyHash
+}
+
+// VerifyCredentials checks a username and password and returns the user.
+// Unknown usernames and wrong passwords get the same answer in about the
+// same time, and count towards a lockout of the username and the IP
+// address alike. A hash made with outdated settings is upgraded.
+func (s UserServices) VerifyCredentials(ctx context.Context, req RequestLogin) (ResponseUser, error) {
+	ctx, span := tracing.Start(ctx, "UserServices.VerifyCredentials")
+	defer span.End()
+
+	if req.Username == "" || req.Password == "" {
+		return ResponseUser{}, &c.Err{Code: http.StatusBadRequest, Remark: "Error Username And Password Required"}
+	}
+
+	if err := s.lockout().checkLoginLocks(ctx, req, "Error VerifyCredentials Service"); err != nil {
+		return ResponseUser{}, err
+	}
+
+	user, err := s.query.SelectUser(ctx, req.Username)
+	hashedPassword := user.HashedPassword
+	if err != nil {
+		if err != sql.ErrNoRows {
+			c.LogFrom(ctx, s.log).Errorf("Error SelectUser : %v", err)
+			return ResponseUser{}, &c.Err{Code: http.StatusInternalServerError, Remark: "Error VerifyCredentials Service", Original: err}
+		}
+		hashedPassword = unknownUserHash()
+	}
+	if err := utils.CheckPassword(hashedPassword, req.Password); err != nil || user.Username == "" {
+		s.lockout().loginFailed(ctx, eventLoginFailed, req)
+		return ResponseUser{}, &c.Err{Code: http.StatusUnauthorized, Remark: "Error Invalid Credentials", Original: err}
+	}
+
+	if err := s.query.ClearLoginFailures(ctx, LoginScopeUser, user.Username); err != nil {
+		c.LogFrom(ctx, s.log).Warnf("Error ClearLoginFailures : %v", err)
+	}
+	securityEvent(ctx, s.security, eventLoginSucceeded, req.Username, req.IP, nil)
+	s.rehashPassword(ctx, user, req.Password)
+	return user, nil
+}
+
+// VerifySecondFactor checks the code finishing the login of user, who has
+// two-factor authentication on. Wrong codes count towards a lockout like
+// wrong passwords.
+func (s UserServices) VerifySecondFactor(ctx context.Context, user ResponseUser, req RequestLoginMFA) error {
+	ctx, span := tracing.Start(ctx, "UserServices.VerifySecondFactor")
+	defer span.End()
+
+	login := RequestLogin{Username: user.Username, IP: req.IP}
+	if err := s.lockout().checkLoginLocks(ctx, login, "Error VerifySecondFactor Service"); err != nil {
+		return err
+	}
+
+	mfa, err := s.query.SelectMFA(ctx, user.Username)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error SelectMFA : %v", err)
+		return &c.Err{Code: http.StatusInternalServerError, Remark: "Error VerifySecondFactor Service", Original: err}
+	}
+	ok, err := checkSecondFactor(ctx, s.query, s.conf, s.now(), user.Username, mfa, req.RequestMFACode)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error Check Second Factor : %v", err)
+		return &c.Err{Code: http.StatusInternalServerError, Remark: "Error VerifySecondFactor Service", Original: err}
+	}
+	if !ok {
+		s.lockout().loginFailed(ctx, eventMFAFailed, login)
+		return &c.Err{Code: http.StatusUnauthorized, Remark: "Error Invalid Code"}
+	}
+
+	if err := s.query.ClearLoginFailures(ctx, LoginScopeUser, user.Username); err != nil {
+		c.LogFrom(ctx, s.log).Warnf("Error ClearLoginFailures : %v", err)
+	}
+	securityEvent(ctx, s.security, eventMFASucceeded, user.Username, req.IP, c.Fields{"method": req.method()})
+	return nil
+}
+
+// UnlockUser lifts a lockout of username before it runs out.
+func (s UserServices) UnlockUser(ctx context.Context, username string, by string) error {
+	ctx, span := tracing.Start(ctx, "UserServices.UnlockUser")
+	defer span.End()
+
+	if err := s.query.ClearLoginFailures(ctx, LoginScopeUser, username); err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error ClearLoginFailures : %v", err)
+		return &c.Err{Code: http.StatusInternalServerError, Remark: "Error UnlockUser Service", Original: err}
+	}
+	securityEvent(ctx, s.security, eventAccountUnlocked, username, "", c.Fields{"by": by})
+	return nil
+}
+
+// loginLockQueries are what counting failed logins towards a lockout needs.
+type loginLockQueries interface {
+	SelectLoginLocks(ctx context.Context, username string, ip string) ([]LoginLock, error)
+	RecordLoginFailure(ctx context.Context, scope string, subject string, window time.Duration) (int, error)
+	LockLogin(ctx context.Context, scope string, subject string, until time.Time) error
+}
+
+// loginLockout counts failed logins, and the wrong passwords and codes of
+// users already signed in, towards the lockouts of conf.
+type loginLockout struct {
+	query    loginLockQueries
+	conf     c.AuthConfig
+	security c.Log
+	log      c.Log
+	now      func() time.Time
+}
+
+func (s UserServices) lockout() loginLockout {
+	return loginLockout{s.query, s.conf, s.security, s.log, s.now}
+}
+
+// checkLoginLocks returns a 429 error while the username or IP address of
+// req is locked out.
+func (l loginLockout) checkLoginLocks(ctx context.Context, req RequestLogin, remark string) error {
+	locks, err := l.query.SelectLoginLocks(ctx, req.Username, req.IP)
+	if err != nil {
+		c.LogFrom(ctx, l.log).Errorf("Error SelectLoginLocks : %v", err)
+		return &c.Err{Code: http.StatusInternalServerError, Remark: remark, Original: err}
+	}
+	if len(locks) > 0 {
+		securityEvent(ctx, l.security, eventLoginBlocked, req.Username, req.IP, c.Fields{"scope": locks[0].Scope, "locked_until": locks[0].LockedUntil})
+		return &c.Err{Code: http.StatusTooManyRequests, Remark: "Error Login Locked Out"}
+	}
+	return nil
+}
+
+// loginFailed counts a failed login against the username and the IP
+// address, locking out either once it reaches its threshold. Failing to
+// count is logged rather than returned, as the login fails either way.
+func (l loginLockout) loginFailed(ctx context.Context, event string, req RequestLogin) {
+	securityEvent(ctx, l.security, event, req.Username, req.IP, nil)
+
+	subjects := []struct {
+		scope, subject string
+		threshold      int
+	}{
+		{LoginScopeUser, req.Username, l.conf.LockoutThreshold},
+		{LoginScopeIP, req.IP, l.conf.LockoutIPThreshold},
+	}
+	for _, sub := range subjects {
+		if sub.subject == "" {
+			continue
+		}
+		failures, err := l.query.RecordLoginFailure(ctx, sub.scope, sub.subject, l.conf.LockoutWindow)
+		if err != nil {
+			c.LogFrom(ctx, l.log).Errorf("Error RecordLoginFailure : %v", err)
+			continue
+		}
+		d := lockoutDuration(failures, sub.threshold, l.conf.LockoutBase, l.conf.LockoutMax)
+		if d == 0 {
+			continue
+		}
+		until := l.now().Add(d)
+		if err := l.query.LockLogin(ctx, sub.scope, sub.subject, until); err != nil {
+			c.LogFrom(ctx, l.log).Errorf("Error LockLogin : %v", err)
+			continue
+		}
+		securityEvent(ctx, l.security, eventLockedOut, req.Username, req.IP, c.Fields{"scope": sub.scope, "failures": failures, "locked_until": until})
+	}
+}
+
+// lockoutDuration is base once failures reach threshold, doubling with each
+// failure after that up to max.
+func lockoutDuration(failures int, threshold int, base time.Duration, max time.Duration) time.Duration {
+	if threshold <= 0 || failures < threshold {
+		return 0
+	}
+	d := base
+	for i := threshold; i < failures && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
+
+// rehashPassword upgrades a stored hash made with an older algorithm or
+// cost, while the plain password is at hand. The old hash still works, so
+// a failure is only logged.
+func (s UserServices) rehashPassword(ctx context.Context, user ResponseUser, password string) {
+	if !utils.PasswordNeedsRehash(user.HashedPassword) {
+		return
+	}
+	rehashed, err := utils.HashPassword(password)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Warnf("Error Rehash Password : %v", err)
+		return
+	}
+	if err := s.query.UpdateUserPassword(ctx, user.Username, rehashed); err != nil {
+		c.LogFrom(ctx, s.log).Warnf("Error UpdateUserPassword : %v", err)
+	}
+}
+
+// securityEvent writes event to the security log. Logins and settings
+// changes are info; failures and lockouts are warnings.
+func securityEvent(ctx context.Context, security c.Log, event string, username string, ip string, fields c.Fields) {
+	entry := c.LogFrom(ctx, security).WithFields(c.Fields{"event": event, "username": username})
+	if ip != "" {
+		entry = entry.WithField("ip", ip)
+	}
+	if fields != nil {
+		entry = entry.WithFields(fields)
+	}
+	switch event {
+	case eventLoginFailed, eventLoginBlocked, eventLockedOut, eventMFAFailed, eventReauthFailed:
+		entry.Warn("Security Event")
+	default:
+		entry.Info("Security Event")
+	}
+}
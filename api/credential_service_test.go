@@ -0,0 +1,378 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/paquesqueue/bookstore/passwords"
+	"github.com/paquesqueue/bookstore/totp"
+	"github.com/paquesqueue/bookstore/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// LoginQueriesStub keeps failed logins and lockouts by "scope:subject".
+type LoginQueriesStub struct {
+	UserQueriesSuccess
+	users    map[string]ResponseUser
+	failures map[string]int
+	locks    map[string]time.Time
+	rehashed map[string]string
+	mfa      UserMFA
+	recovery map[string]bool
+}
+
+func newLoginQueriesStub(t *testing.T) *LoginQueriesStub {
+	hashed, err := utils.HashPassword("123456")
+	assert.NoError(t, err)
+	return &LoginQueriesStub{
+		users:    map[string]ResponseUser{"tester": {Username: "tester", HashedPassword: hashed}},
+		failures: map[string]int{},
+		locks:    map[string]time.Time{},
+		rehashed: map[string]string{},
+		recovery: map[string]bool{},
+	}
+}
+
+func (q *LoginQueriesStub) SelectUser(ctx context.Context, username string) (ResponseUser, error) {
+	user, ok := q.users[username]
+	if !ok {
+		return ResponseUser{}, sql.ErrNoRows
+	}
+	return user, nil
+}
+
+func (q *LoginQueriesStub) UpdateUserPassword(ctx context.Context, username string, hashedPassword string) error {
+	q.rehashed[username] = hashedPassword
+	return nil
+}
+
+func (q *LoginQueriesStub) SelectLoginLocks(ctx context.Context, username string, ip string) ([]LoginLock, error) {
+	locks := []LoginLock{}
+	for _, l := range []LoginLock{{Scope: LoginScopeUser, Subject: username}, {Scope: LoginScopeIP, Subject: ip}} {
+		if until, ok := q.locks[l.Scope+":"+l.Subject]; ok {
+			l.LockedUntil = until
+			locks = append(locks, l)
+		}
+	}
+	return locks, nil
+}
+
+func (q *LoginQueriesStub) RecordLoginFailure(ctx context.Context, scope string, subject string, window time.Duration) (int, error) {
+	q.failures[scope+":"+subject]++
+	return q.failures[scope+":"+subject], nil
+}
+
+func (q *LoginQueriesStub) LockLogin(ctx context.Context, scope string, subject string, until time.Time) error {
+	q.locks[scope+":"+subject] = until
+	return nil
+}
+
+func (q *LoginQueriesStub) ClearLoginFailures(ctx context.Context, scope string, subject string) error {
+	delete(q.failures, scope+":"+subject)
+	delete(q.locks, scope+":"+subject)
+	return nil
+}
+
+func (q *LoginQueriesStub) SelectMFA(ctx context.Context, username string) (UserMFA, error) {
+	return q.mfa, nil
+}
+
+func (q *LoginQueriesStub) AdvanceMFAStep(ctx context.Context, username string, step int64) error {
+	if step <= q.mfa.LastStep {
+		return sql.ErrNoRows
+	}
+	q.mfa.LastStep = step
+	return nil
+}
+
+func (q *LoginQueriesStub) UseRecoveryCode(ctx context.Context, username string, codeHash string) error {
+	if !q.recovery[codeHash] {
+		return sql.ErrNoRows
+	}
+	delete(q.recovery, codeHash)
+	return nil
+}
+
+var loginNow = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newCredentialServices(q UserQueries) (*UserServices, *test.Hook) {
+	security, hook := test.NewNullLogger()
+	s := NewUserService(q, passwords.Policy{}, &EmailVerifierStub{}, c.AuthConfig{
+		LockoutThreshold:   3,
+		LockoutIPThreshold: 5,
+		LockoutBase:        time.Minute,
+		LockoutMax:         4 * time.Minute,
+		LockoutWindow:      24 * time.Hour,
+		ErasureKey:         "erasure-key",
+	}, security, logrus.New())
+	s.now = func() time.Time { return loginNow }
+	return s, hook
+}
+
+func TestVerifyCredentials(t *testing.T) {
+	t.Run("TestVerifyCredentialsShouldReturnUserAndForgetFailures", func(t *testing.T) {
+		// Arrange
+		query := newLoginQueriesStub(t)
+		query.failures["user:tester"] = 2
+		services, hook := newCredentialServices(query)
+
+		// Act
+		user, err := services.VerifyCredentials(context.Background(), RequestLogin{Username: "tester", Password: "123456", IP: "10.0.0.1"})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "tester", user.Username)
+		assert.NotContains(t, query.failures, "user:tester")
+		if entry := hook.LastEntry(); assert.NotNil(t, entry) {
+			assert.Equal(t, eventLoginSucceeded, entry.Data["event"])
+			assert.Equal(t, "10.0.0.1", entry.Data["ip"])
+			assert.Equal(t, logrus.InfoLevel, entry.Level)
+		}
+	})
+
+	t.Run("TestVerifyCredentialsShouldLockOutWithBackoff", func(t *testing.T) {
+		// Arrange
+		query := newLoginQueriesStub(t)
+		services, hook := newCredentialServices(query)
+		req := RequestLogin{Username: "tester", Password: "654321", IP: "10.0.0.1"}
+
+		for attempt, want := range []time.Duration{0, 0, time.Minute, 2 * time.Minute, 4 * time.Minute, 4 * time.Minute} {
+			query.locks = map[string]time.Time{}
+
+			// Act
+			_, err := services.VerifyCredentials(context.Background(), req)
+
+			// Assert
+			if assert.IsType(t, &c.Err{}, err) {
+				assert.Equal(t, http.StatusUnauthorized, err.(*c.Err).Code)
+			}
+			if want == 0 {
+				assert.NotContains(t, query.locks, "user:tester", "attempt %d", attempt+1)
+				continue
+			}
+			assert.Equal(t, loginNow.Add(want), query.locks["user:tester"], "attempt %d", attempt+1)
+			if entry := hook.LastEntry(); assert.NotNil(t, entry) {
+				assert.Equal(t, eventLockedOut, entry.Data["event"])
+			}
+		}
+		assert.Equal(t, 6, query.failures["ip:10.0.0.1"])
+		assert.Equal(t, loginNow.Add(2*time.Minute), query.locks["ip:10.0.0.1"])
+	})
+
+	t.Run("TestVerifyCredentialsShouldRefuseWhileLockedOut", func(t *testing.T) {
+		for _, lock := range []string{"user:tester", "ip:10.0.0.1"} {
+			// Arrange
+			query := newLoginQueriesStub(t)
+			query.locks[lock] = loginNow.Add(time.Minute)
+			services, hook := newCredentialServices(query)
+
+			// Act
+			_, err := services.VerifyCredentials(context.Background(), RequestLogin{Username: "tester", Password: "123456", IP: "10.0.0.1"})
+
+			// Assert
+			if assert.IsType(t, &c.Err{}, err) {
+				assert.Equal(t, http.StatusTooManyRequests, err.(*c.Err).Code)
+			}
+			assert.Empty(t, query.failures)
+			if entry := hook.LastEntry(); assert.NotNil(t, entry) {
+				assert.Equal(t, eventLoginBlocked, entry.Data["event"])
+			}
+		}
+	})
+
+	t.Run("TestVerifyCredentialsShouldTreatUnknownUserAsWrongPassword", func(t *testing.T) {
+		// Arrange
+		query := newLoginQueriesStub(t)
+		services, hook := newCredentialServices(query)
+
+		// Act
+		_, err := services.VerifyCredentials(context.Background(), RequestLogin{Username: "nobody", Password: "123456", IP: "10.0.0.1"})
+
+		// Assert
+		if assert.IsType(t, &c.Err{}, err) {
+			assert.Equal(t, http.StatusUnauthorized, err.(*c.Err).Code)
+			assert.Equal(t, "Error Invalid Credentials", err.(*c.Err).Remark)
+		}
+		assert.Equal(t, map[string]int{"user:nobody": 1, "ip:10.0.0.1": 1}, query.failures)
+		if entry := hook.LastEntry(); assert.NotNil(t, entry) {
+			assert.Equal(t, eventLoginFailed, entry.Data["event"])
+			assert.Equal(t, "nobody", entry.Data["username"])
+		}
+	})
+
+	t.Run("TestVerifyCredentialsShouldRequireUsernameAndPassword", func(t *testing.T) {
+		// Arrange
+		query := newLoginQueriesStub(t)
+		services, _ := newCredentialServices(query)
+
+		// Act
+		_, err := services.VerifyCredentials(context.Background(), RequestLogin{Username: "tester"})
+
+		// Assert
+		if assert.IsType(t, &c.Err{}, err) {
+			assert.Equal(t, http.StatusBadRequest, err.(*c.Err).Code)
+		}
+		assert.Empty(t, query.failures)
+	})
+
+	t.Run("TestVerifyCredentialsShouldRehashOutdatedPassword", func(t *testing.T) {
+		// Arrange
+		query := newLoginQueriesStub(t)
+		defer utils.SetPasswordHashing(utils.PasswordHashing{Algorithm: utils.PasswordBcrypt, BcryptCost: bcrypt.DefaultCost})
+		utils.SetPasswordHashing(utils.PasswordHashing{Algorithm: utils.PasswordArgon2id, Argon2Time: 1, Argon2Memory: 64, Argon2Threads: 1})
+		services, _ := newCredentialServices(query)
+
+		// Act
+		_, err := services.VerifyCredentials(context.Background(), RequestLogin{Username: "tester", Password: "123456"})
+
+		// Assert
+		if assert.NoError(t, err) {
+			rehashed := query.rehashed["tester"]
+			assert.True(t, strings.HasPrefix(rehashed, "$argon2id$"))
+			assert.NoError(t, utils.CheckPassword(rehashed, "123456"))
+		}
+	})
+
+	t.Run("TestVerifyCredentialsShouldKeepCurrentHash", func(t *testing.T) {
+		// Arrange
+		query := newLoginQueriesStub(t)
+		services, _ := newCredentialServices(query)
+
+		// Act
+		_, err := services.VerifyCredentials(context.Background(), RequestLogin{Username: "tester", Password: "123456"})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Empty(t, query.rehashed)
+	})
+}
+
+func TestVerifySecondFactor(t *testing.T) {
+	enrolled := func(t *testing.T) (*LoginQueriesStub, string) {
+		query := newLoginQueriesStub(t)
+		secret, err := totp.NewSecret()
+		assert.NoError(t, err)
+		query.mfa = UserMFA{Secret: secret, EnabledAt: &loginNow}
+		query.recovery[hashRecoveryCode("abcde-fghjk")] = true
+		code, err := totp.Code(secret, totp.Step(loginNow))
+		assert.NoError(t, err)
+		return query, code
+	}
+	user := ResponseUser{Username: "tester"}
+
+	t.Run("TestVerifySecondFactorShouldAcceptCodeOnce", func(t *testing.T) {
+		// Arrange
+		query, code := enrolled(t)
+		services, hook := newCredentialServices(query)
+		req := RequestLoginMFA{RequestMFACode: RequestMFACode{Code: code, IP: "10.0.0.1"}}
+
+		// Act
+		err := services.VerifySecondFactor(context.Background(), user, req)
+		replay := services.VerifySecondFactor(context.Background(), user, req)
+
+		// Assert
+		assert.NoError(t, err)
+		if assert.IsType(t, &c.Err{}, replay) {
+			assert.Equal(t, http.StatusUnauthorized, replay.(*c.Err).Code)
+		}
+		assert.Equal(t, 1, query.failures["user:tester"])
+		if entry := hook.LastEntry(); assert.NotNil(t, entry) {
+			assert.Equal(t, eventMFAFailed, entry.Data["event"])
+		}
+	})
+
+	t.Run("TestVerifySecondFactorShouldAcceptRecoveryCodeOnce", func(t *testing.T) {
+		// Arrange
+		query, _ := enrolled(t)
+		services, hook := newCredentialServices(query)
+		req := RequestLoginMFA{RequestMFACode: RequestMFACode{RecoveryCode: "ABCDE FGHJK"}}
+
+		// Act
+		err := services.VerifySecondFactor(context.Background(), user, req)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Empty(t, query.recovery)
+		if entry := hook.LastEntry(); assert.NotNil(t, entry) {
+			assert.Equal(t, eventMFASucceeded, entry.Data["event"])
+			assert.Equal(t, "recovery_code", entry.Data["method"])
+		}
+	})
+
+	t.Run("TestVerifySecondFactorShouldLockOutAfterWrongCodes", func(t *testing.T) {
+		// Arrange
+		query, _ := enrolled(t)
+		services, _ := newCredentialServices(query)
+		req := RequestLoginMFA{RequestMFACode: RequestMFACode{Code: "000000"}}
+
+		for i := 0; i < 3; i++ {
+			_ = services.VerifySecondFactor(context.Background(), user, req)
+		}
+
+		// Act
+		err := services.VerifySecondFactor(context.Background(), user, req)
+
+		// Assert
+		if assert.IsType(t, &c.Err{}, err) {
+			assert.Equal(t, http.StatusTooManyRequests, err.(*c.Err).Code)
+		}
+	})
+}
+
+func TestUnlockUser(t *testing.T) {
+	t.Run("TestUnlockUserShouldLiftLockoutAndLogWho", func(t *testing.T) {
+		// Arrange
+		query := newLoginQueriesStub(t)
+		query.failures["user:tester"] = 3
+		query.locks["user:tester"] = loginNow.Add(time.Minute)
+		services, hook := newCredentialServices(query)
+
+		// Act
+		err := services.UnlockUser(context.Background(), "tester", "admin")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Empty(t, query.locks)
+		if entry := hook.LastEntry(); assert.NotNil(t, entry) {
+			assert.Equal(t, eventAccountUnlocked, entry.Data["event"])
+			assert.Equal(t, "admin", entry.Data["by"])
+		}
+	})
+
+	t.Run("TestUnlockUserShouldReturnHTTPStatus500", func(t *testing.T) {
+		// Arrange
+		services, _ := newCredentialServices(&UserQueriesError{})
+
+		// Act
+		err := services.UnlockUser(context.Background(), "tester", "admin")
+
+		// Assert
+		if assert.IsType(t, &c.Err{}, err) {
+			assert.Equal(t, http.StatusInternalServerError, err.(*c.Err).Code)
+		}
+	})
+}
+
+func TestLockoutDuration(t *testing.T) {
+	t.Run("TestLockoutDurationShouldDoubleUpToMax", func(t *testing.T) {
+		for failures, want := range map[int]time.Duration{
+			2: 0,
+			3: time.Minute,
+			4: 2 * time.Minute,
+			5: 4 * time.Minute,
+			9: 5 * time.Minute,
+		} {
+			assert.Equal(t, want, lockoutDuration(failures, 3, time.Minute, 5*time.Minute), "failures %d", failures)
+		}
+	})
+}
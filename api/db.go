@@ -1,9 +1,10 @@
 package api
 
 import (
+	"context"
 	"database/sql"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"github.com/paquesqueue/bookstore/common"
 )
 
@@ -27,17 +28,327 @@ CREATE TABLE IF NOT EXISTS users (
 	hashed_password TEXT NOT NULL,	
 	created_at TIMESTAMP DEFAULT NOW() NOT NULL
 );
+
+CREATE TABLE IF NOT EXISTS book_onix_details (
+	book_id INT PRIMARY KEY NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+	record_reference TEXT NOT NULL,
+	product_form TEXT NOT NULL DEFAULT '',
+	subtitle TEXT NOT NULL DEFAULT '',
+	language_code TEXT NOT NULL DEFAULT '',
+	page_count BIGINT NOT NULL DEFAULT 0,
+	publication_date TEXT NOT NULL DEFAULT '',
+	description TEXT NOT NULL DEFAULT '',
+	currency_code TEXT NOT NULL DEFAULT '',
+	updated_at TIMESTAMP DEFAULT NOW() NOT NULL
+);
+`
+
+const sqlContributors = `
+CREATE TABLE IF NOT EXISTS authors (
+	id SERIAL PRIMARY KEY NOT NULL,
+	name TEXT NOT NULL,
+	name_key TEXT NOT NULL UNIQUE,
+	created_at TIMESTAMP DEFAULT NOW() NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS publishers (
+	id SERIAL PRIMARY KEY NOT NULL,
+	name TEXT NOT NULL,
+	name_key TEXT NOT NULL UNIQUE,
+	created_at TIMESTAMP DEFAULT NOW() NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS book_authors (
+	book_id INT NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+	author_id INT NOT NULL REFERENCES authors(id) ON DELETE RESTRICT,
+	role TEXT NOT NULL DEFAULT 'author',
+	position INT NOT NULL,
+	PRIMARY KEY (book_id, author_id, role)
+);
+
+CREATE INDEX IF NOT EXISTS book_authors_author_id_idx ON book_authors (author_id);
+
+ALTER TABLE books ADD COLUMN IF NOT EXISTS publisher_id INT REFERENCES publishers(id) ON DELETE RESTRICT;
+
+CREATE INDEX IF NOT EXISTS books_publisher_id_idx ON books (publisher_id);
+`
+
+const sqlTaxonomy = `
+CREATE TABLE IF NOT EXISTS categories (
+	id SERIAL PRIMARY KEY NOT NULL,
+	parent_id INT REFERENCES categories(id) ON DELETE RESTRICT,
+	name TEXT NOT NULL,
+	scheme TEXT NOT NULL DEFAULT 'local',
+	code TEXT NOT NULL DEFAULT '',
+	path TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP DEFAULT NOW() NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS categories_scheme_code_key ON categories (scheme, code) WHERE code <> '';
+CREATE INDEX IF NOT EXISTS categories_parent_id_idx ON categories (parent_id);
+CREATE INDEX IF NOT EXISTS categories_path_idx ON categories (path text_pattern_ops);
+
+CREATE TABLE IF NOT EXISTS tags (
+	id SERIAL PRIMARY KEY NOT NULL,
+	name TEXT NOT NULL,
+	name_key TEXT NOT NULL UNIQUE,
+	created_at TIMESTAMP DEFAULT NOW() NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS book_categories (
+	book_id INT NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+	category_id INT NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+	PRIMARY KEY (book_id, category_id)
+);
+
+CREATE INDEX IF NOT EXISTS book_categories_category_id_idx ON book_categories (category_id);
+
+CREATE TABLE IF NOT EXISTS book_tags (
+	book_id INT NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+	tag_id INT NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+	PRIMARY KEY (book_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS book_tags_tag_id_idx ON book_tags (tag_id);
+`
+
+const sqlCovers = `
+CREATE TABLE IF NOT EXISTS book_covers (
+	book_id INT PRIMARY KEY NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+	version TEXT NOT NULL,
+	content_type TEXT NOT NULL,
+	size BIGINT NOT NULL,
+	width INT NOT NULL,
+	height INT NOT NULL,
+	updated_at TIMESTAMP DEFAULT NOW() NOT NULL
+);
+`
+
+const sqlWorks = `
+CREATE TABLE IF NOT EXISTS works (
+	id SERIAL PRIMARY KEY NOT NULL,
+	title TEXT NOT NULL,
+	authors TEXT[] NOT NULL,
+	created_at TIMESTAMP DEFAULT NOW() NOT NULL
+);
+
+ALTER TABLE books ADD COLUMN IF NOT EXISTS work_id INT REFERENCES works(id) ON DELETE RESTRICT;
+ALTER TABLE books ADD COLUMN IF NOT EXISTS format TEXT NOT NULL DEFAULT '';
+ALTER TABLE books ADD COLUMN IF NOT EXISTS publication_date DATE;
+
+CREATE INDEX IF NOT EXISTS books_work_id_idx ON books (work_id);
+`
+
+const sqlReviews = `
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'customer';
+
+CREATE TABLE IF NOT EXISTS sessions (
+	token_hash TEXT PRIMARY KEY NOT NULL,
+	username TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE,
+	created_at TIMESTAMP DEFAULT NOW() NOT NULL,
+	expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS sessions_username_idx ON sessions (username);
+
+CREATE TABLE IF NOT EXISTS reviews (
+	id SERIAL PRIMARY KEY NOT NULL,
+	book_id INT NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+	username TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE,
+	rating SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+	title TEXT NOT NULL DEFAULT '',
+	body TEXT NOT NULL DEFAULT '',
+	status TEXT NOT NULL DEFAULT 'pending',
+	moderation_note TEXT NOT NULL DEFAULT '',
+	moderated_by TEXT NOT NULL DEFAULT '',
+	moderated_at TIMESTAMP,
+	helpful_count BIGINT NOT NULL DEFAULT 0,
+	created_at TIMESTAMP DEFAULT NOW() NOT NULL,
+	UNIQUE (book_id, username)
+);
+
+CREATE INDEX IF NOT EXISTS reviews_book_id_status_idx ON reviews (book_id, status);
+CREATE INDEX IF NOT EXISTS reviews_status_idx ON reviews (status, created_at);
+
+CREATE TABLE IF NOT EXISTS review_votes (
+	review_id INT NOT NULL REFERENCES reviews(id) ON DELETE CASCADE,
+	username TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE,
+	created_at TIMESTAMP DEFAULT NOW() NOT NULL,
+	PRIMARY KEY (review_id, username)
+);
+
+ALTER TABLE books ADD COLUMN IF NOT EXISTS rating_count BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE books ADD COLUMN IF NOT EXISTS rating_sum BIGINT NOT NULL DEFAULT 0;
+`
+
+const sqlLists = `
+CREATE TABLE IF NOT EXISTS book_lists (
+	id SERIAL PRIMARY KEY NOT NULL,
+	username TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE,
+	name TEXT NOT NULL,
+	is_default BOOLEAN NOT NULL DEFAULT FALSE,
+	visibility TEXT NOT NULL DEFAULT 'private',
+	share_token TEXT UNIQUE,
+	created_at TIMESTAMP DEFAULT NOW() NOT NULL,
+	updated_at TIMESTAMP DEFAULT NOW() NOT NULL,
+	UNIQUE (username, name)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS book_lists_default_key ON book_lists (username) WHERE is_default;
+
+CREATE TABLE IF NOT EXISTS book_list_items (
+	list_id INT NOT NULL REFERENCES book_lists(id) ON DELETE CASCADE,
+	book_id INT NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+	position INT NOT NULL,
+	added_at TIMESTAMP DEFAULT NOW() NOT NULL,
+	PRIMARY KEY (list_id, book_id)
+);
+
+CREATE INDEX IF NOT EXISTS book_list_items_book_id_idx ON book_list_items (book_id);
+`
+
+const sqlSimilarities = `
+CREATE TABLE IF NOT EXISTS book_similarities (
+	book_id INT NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+	related_id INT NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+	score DOUBLE PRECISION NOT NULL,
+	content_score DOUBLE PRECISION NOT NULL,
+	co_score DOUBLE PRECISION NOT NULL,
+	computed_at TIMESTAMP DEFAULT NOW() NOT NULL,
+	PRIMARY KEY (book_id, related_id)
+);
+
+CREATE INDEX IF NOT EXISTS book_similarities_score_idx ON book_similarities (book_id, score DESC);
+`
+
+const sqlAPIKeys = `
+CREATE TABLE IF NOT EXISTS api_keys (
+	id SERIAL PRIMARY KEY NOT NULL,
+	username TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE,
+	name TEXT NOT NULL,
+	prefix TEXT NOT NULL,
+	key_hash TEXT UNIQUE NOT NULL,
+	scopes TEXT[] NOT NULL DEFAULT '{}',
+	created_at TIMESTAMP DEFAULT NOW() NOT NULL,
+	expires_at TIMESTAMP,
+	last_used_at TIMESTAMP,
+	rotated_at TIMESTAMP,
+	revoked_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS api_keys_username_idx ON api_keys (username);
+`
+
+const sqlUserTokens = `
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS user_tokens (
+	token_hash TEXT PRIMARY KEY NOT NULL,
+	username TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE,
+	purpose TEXT NOT NULL,
+	email TEXT NOT NULL,
+	created_at TIMESTAMP DEFAULT NOW() NOT NULL,
+	expires_at TIMESTAMP NOT NULL,
+	used_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS user_tokens_username_idx ON user_tokens (username, purpose);
+`
+
+const sqlLoginFailures = `
+CREATE TABLE IF NOT EXISTS login_failures (
+	scope TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	failures INT NOT NULL DEFAULT 0,
+	last_failed_at TIMESTAMP DEFAULT NOW() NOT NULL,
+	locked_until TIMESTAMP,
+	PRIMARY KEY (scope, subject)
+);
+`
+
+const sqlUserMFA = `
+ALTER TABLE users ADD COLUMN IF NOT EXISTS mfa_secret TEXT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS mfa_enabled_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS mfa_last_step BIGINT;
+
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+	username TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE,
+	code_hash TEXT NOT NULL,
+	created_at TIMESTAMP DEFAULT NOW() NOT NULL,
+	used_at TIMESTAMP,
+	PRIMARY KEY (username, code_hash)
+);
 `
 
-func InitDB(c common.Config, log common.Log) (*sql.DB, error) {
-	db, err := sql.Open(c.DriverName, c.Url)
+const sqlUserErasures = `
+ALTER TABLE users ADD COLUMN IF NOT EXISTS erased_at TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS user_erasures (
+	id SERIAL PRIMARY KEY NOT NULL,
+	username TEXT NOT NULL,
+	subject_hash TEXT NOT NULL,
+	erased_by TEXT NOT NULL,
+	reviews_anonymised INT NOT NULL DEFAULT 0,
+	lists_deleted INT NOT NULL DEFAULT 0,
+	api_keys_deleted INT NOT NULL DEFAULT 0,
+	sessions_deleted INT NOT NULL DEFAULT 0,
+	erased_at TIMESTAMP DEFAULT NOW() NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS user_erasures_subject_hash_idx ON user_erasures (subject_hash);
+`
+
+// sqlExpiryTimeZones keeps the instants the service writes from Go and
+// compares against NOW(). A plain TIMESTAMP drops the offset, so an expiry
+// written by a server outside UTC was off by that offset.
+const sqlExpiryTimeZones = `
+ALTER TABLE sessions
+	ALTER COLUMN created_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN expires_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE user_tokens
+	ALTER COLUMN created_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN expires_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN used_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE login_failures
+	ALTER COLUMN last_failed_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN locked_until TYPE TIMESTAMPTZ;
+
+ALTER TABLE api_keys
+	ALTER COLUMN created_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN expires_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN last_used_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN rotated_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN revoked_at TYPE TIMESTAMPTZ;
+`
+
+// InitDB opens the pool, waits for the database to accept connections and
+// migrates it. Startup keeps retrying with backoff until ConnectTimeout, so
+// the service can start before Postgres is ready.
+func InitDB(c common.DBConfig, m *DBMetrics, log common.Log) (*sql.DB, error) {
+	db, err := m.Open(c.Driver, c.Url)
 	if err != nil {
 		log.Errorf("Error Open Database : %v", err)
+		return nil, err
 	}
+	db.SetMaxOpenConns(c.MaxOpenConns)
+	db.SetMaxIdleConns(c.MaxIdleConns)
+	db.SetConnMaxLifetime(c.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(c.ConnMaxIdleTime)
 
-	_, err = db.Exec(sqlQuries)
+	ctx, cancel := context.WithTimeout(context.Background(), c.ConnectTimeout)
+	defer cancel()
+	err = pingWithRetry(ctx, db, newBackoff(c.RetryInitial, c.RetryMax), c.HealthTimeout, log)
 	if err != nil {
-		log.Errorf("Error Create Tables : %v", err)
+		log.Errorf("Error Connect Database : %v", err)
+		db.Close()
+		return nil, err
+	}
+
+	err = Migrate(db)
+	if err != nil {
+		log.Errorf("Error Migrate Database : %v", err)
 	}
 	return db, err
 }
@@ -49,3 +360,13 @@ type Query struct {
 func NewDB(db *sql.DB) Query {
 	return Query{db}
 }
+
+const (
+	pqUniqueViolation     = "23505"
+	pqForeignKeyViolation = "23503"
+)
+
+func isPqError(err error, code pq.ErrorCode) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == code
+}
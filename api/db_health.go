@@ -0,0 +1,154 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"math/rand"
+	"sync"
+	"time"
+
+	c "github.com/paquesqueue/bookstore/common"
+)
+
+// dbPinger is the part of *sql.DB the health checks use.
+type dbPinger interface {
+	PingContext(ctx context.Context) error
+	Stats() sql.DBStats
+}
+
+// backoff doubles the delay between attempts up to max, then picks a random
+// delay in the upper half so restarted replicas do not retry in lockstep.
+type backoff struct {
+	initial time.Duration
+	max     time.Duration
+	jitter  func(n int64) int64
+}
+
+func newBackoff(initial time.Duration, max time.Duration) backoff {
+	return backoff{initial, max, rand.Int63n}
+}
+
+func (b backoff) delay(attempt int) time.Duration {
+	d := b.initial
+	for i := 0; i < attempt && d < b.max; i++ {
+		d *= 2
+	}
+	if d > b.max {
+		d = b.max
+	}
+	half := d / 2
+	return half + time.Duration(b.jitter(int64(d-half)+1))
+}
+
+// pingWithRetry pings until the database answers or ctx ends, returning the
+// last ping error in that case.
+func pingWithRetry(ctx context.Context, db dbPinger, b backoff, timeout time.Duration, log c.Log) error {
+	for attempt := 0; ; attempt++ {
+		pingCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := db.PingContext(pingCtx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+
+		wait := b.delay(attempt)
+		log.Errorf("Error Ping Database attempt %d, retrying in %v : %v", attempt+1, wait, err)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// DBHealth pings the database periodically and remembers the outcome, so
+// callers can report it without waiting on the database.
+type DBHealth struct {
+	db      dbPinger
+	timeout time.Duration
+	log     c.Log
+	now     func() time.Time
+
+	mu        sync.RWMutex
+	err       error
+	checkedAt time.Time
+}
+
+func NewDBHealth(db dbPinger, timeout time.Duration, l c.Log) *DBHealth {
+	return &DBHealth{db: db, timeout: timeout, log: l, now: time.Now}
+}
+
+// Check pings the database once and records the result. Only changes
+// between healthy and unhealthy are logged.
+func (h *DBHealth) Check(ctx context.Context) error {
+	pingCtx, cancel := context.WithTimeout(ctx, h.timeout)
+	err := h.db.PingContext(pingCtx)
+	cancel()
+
+	h.mu.Lock()
+	firstCheck := h.checkedAt.IsZero()
+	wasHealthy := !firstCheck && h.err == nil
+	h.err = err
+	h.checkedAt = h.now()
+	h.mu.Unlock()
+
+	if err != nil && (firstCheck || wasHealthy) {
+		h.log.Errorf("Error Database Unhealthy : %v", err)
+	}
+	if err == nil && !wasHealthy {
+		h.log.Info("Success Database Healthy")
+	}
+	return err
+}
+
+// Run checks the database on every interval until ctx is done. A
+// non-positive interval disables the checks.
+func (h *DBHealth) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		_ = h.Check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Status returns the last check and the current pool statistics.
+func (h *DBHealth) Status() ResponseDBStatus {
+	h.mu.RLock()
+	err, checkedAt := h.err, h.checkedAt
+	h.mu.RUnlock()
+
+	resp := ResponseDBStatus{
+		Healthy:   err == nil && !checkedAt.IsZero(),
+		CheckedAt: checkedAt,
+		Pool:      newResponseDBStats(h.db.Stats()),
+	}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	return resp
+}
+
+func newResponseDBStats(s sql.DBStats) ResponseDBStats {
+	return ResponseDBStats{
+		MaxOpenConnections: s.MaxOpenConnections,
+		OpenConnections:    s.OpenConnections,
+		InUse:              s.InUse,
+		Idle:               s.Idle,
+		WaitCount:          s.WaitCount,
+		WaitDurationMs:     s.WaitDuration.Milliseconds(),
+		MaxIdleClosed:      s.MaxIdleClosed,
+		MaxIdleTimeClosed:  s.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  s.MaxLifetimeClosed,
+	}
+}
@@ -0,0 +1,135 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type DBPingerStub struct {
+	failures int
+	pings    int
+	stats    sql.DBStats
+}
+
+func (p *DBPingerStub) PingContext(ctx context.Context) error {
+	p.pings++
+	if p.pings <= p.failures {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func (p *DBPingerStub) Stats() sql.DBStats {
+	return p.stats
+}
+
+func TestBackoff(t *testing.T) {
+	t.Run("TestBackoffShouldDoubleUpToMax", func(t *testing.T) {
+		// Arrange
+		b := backoff{time.Second, 5 * time.Second, func(n int64) int64 { return n - 1 }}
+
+		// Act & Assert
+		assert.Equal(t, time.Second, b.delay(0))
+		assert.Equal(t, 2*time.Second, b.delay(1))
+		assert.Equal(t, 4*time.Second, b.delay(2))
+		assert.Equal(t, 5*time.Second, b.delay(3))
+		assert.Equal(t, 5*time.Second, b.delay(40))
+	})
+
+	t.Run("TestBackoffShouldKeepJitterInUpperHalf", func(t *testing.T) {
+		// Arrange
+		b := backoff{4 * time.Second, 10 * time.Second, func(n int64) int64 { return 0 }}
+
+		// Act & Assert
+		assert.Equal(t, 2*time.Second, b.delay(0))
+		assert.Equal(t, 5*time.Second, b.delay(5))
+	})
+}
+
+func TestPingWithRetry(t *testing.T) {
+	b := backoff{time.Millisecond, 2 * time.Millisecond, func(n int64) int64 { return 0 }}
+
+	t.Run("TestPingWithRetryShouldRetryUntilDatabaseAnswers", func(t *testing.T) {
+		// Arrange
+		db := &DBPingerStub{failures: 3}
+
+		// Act
+		err := pingWithRetry(context.Background(), db, b, time.Second, logrus.New())
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, 4, db.pings)
+	})
+
+	t.Run("TestPingWithRetryShouldGiveUpWhenContextEnds", func(t *testing.T) {
+		// Arrange
+		db := &DBPingerStub{failures: 1 << 30}
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		// Act
+		err := pingWithRetry(ctx, db, b, time.Second, logrus.New())
+
+		// Assert
+		assert.EqualError(t, err, "connection refused")
+		assert.Greater(t, db.pings, 1)
+	})
+}
+
+func TestDBHealth(t *testing.T) {
+	t.Run("TestDBHealthShouldBeUnhealthyBeforeFirstCheck", func(t *testing.T) {
+		// Arrange
+		health := NewDBHealth(&DBPingerStub{}, time.Second, logrus.New())
+
+		// Act
+		status := health.Status()
+
+		// Assert
+		assert.False(t, status.Healthy)
+		assert.True(t, status.CheckedAt.IsZero())
+	})
+
+	t.Run("TestDBHealthShouldReportLastCheckWithPoolStats", func(t *testing.T) {
+		// Arrange
+		db := &DBPingerStub{failures: 1, stats: sql.DBStats{
+			MaxOpenConnections: 25,
+			OpenConnections:    3,
+			InUse:              1,
+			Idle:               2,
+			WaitDuration:       1500 * time.Millisecond,
+		}}
+		health := NewDBHealth(db, time.Second, logrus.New())
+		checkedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		health.now = func() time.Time { return checkedAt }
+
+		// Act
+		failErr := health.Check(context.Background())
+		failed := health.Status()
+		okErr := health.Check(context.Background())
+		recovered := health.Status()
+
+		// Assert
+		assert.Error(t, failErr)
+		assert.False(t, failed.Healthy)
+		assert.Equal(t, "connection refused", failed.Error)
+		assert.NoError(t, okErr)
+		assert.True(t, recovered.Healthy)
+		assert.Empty(t, recovered.Error)
+		assert.Equal(t, checkedAt, recovered.CheckedAt)
+		assert.Equal(t, ResponseDBStats{
+			MaxOpenConnections: 25,
+			OpenConnections:    3,
+			InUse:              1,
+			Idle:               2,
+			WaitDurationMs:     1500,
+		}, recovered.Pool)
+	})
+}
@@ -0,0 +1,348 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/paquesqueue/bookstore/metrics"
+	"github.com/paquesqueue/bookstore/tracing"
+)
+
+// queryMethodPrefix is how runtime names the methods of Query, so a
+// statement can be attributed to the method that ran it.
+var queryMethodPrefix = reflect.TypeOf(Query{}).PkgPath() + ".Query."
+
+// DBMetrics records statement latency per Query method and the connection
+// pool statistics. Statements run inside a traced request also become spans.
+type DBMetrics struct {
+	reg     *metrics.Registry
+	queries *metrics.HistogramVec
+	system  string
+}
+
+func NewDBMetrics(reg *metrics.Registry) *DBMetrics {
+	return &DBMetrics{
+		reg: reg,
+		queries: reg.Histogram("bookstore_db_query_duration_seconds",
+			"Time spent running database statements, by Query method.", metrics.DefBuckets, "method"),
+	}
+}
+
+// Open opens the database like sql.Open, timing every statement run on it
+// and reporting its pool.
+func (m *DBMetrics) Open(driverName string, dsn string) (*sql.DB, error) {
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	drv := db.Driver()
+	db.Close()
+
+	var connector driver.Connector = dsnConnector{drv, dsn}
+	if dc, ok := drv.(driver.DriverContext); ok {
+		connector, err = dc.OpenConnector(dsn)
+		if err != nil {
+			return nil, err
+		}
+	}
+	m.system = driverName
+	if driverName == "postgres" {
+		m.system = "postgresql"
+	}
+	db = sql.OpenDB(timedConnector{connector, m})
+	m.watchPool(db)
+	return db, nil
+}
+
+func (m *DBMetrics) watchPool(db *sql.DB) {
+	gauges := []struct {
+		name  string
+		help  string
+		value func(s sql.DBStats) float64
+	}{
+		{"bookstore_db_max_open_connections", "Maximum number of open connections to the database.", func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }},
+		{"bookstore_db_open_connections", "Established connections, in use and idle.", func(s sql.DBStats) float64 { return float64(s.OpenConnections) }},
+		{"bookstore_db_in_use_connections", "Connections currently in use.", func(s sql.DBStats) float64 { return float64(s.InUse) }},
+		{"bookstore_db_idle_connections", "Idle connections.", func(s sql.DBStats) float64 { return float64(s.Idle) }},
+	}
+	for _, g := range gauges {
+		value := g.value
+		m.reg.GaugeFunc(g.name, g.help, func() float64 { return value(db.Stats()) })
+	}
+
+	counters := []struct {
+		name  string
+		help  string
+		value func(s sql.DBStats) float64
+	}{
+		{"bookstore_db_wait_count_total", "Connections waited for.", func(s sql.DBStats) float64 { return float64(s.WaitCount) }},
+		{"bookstore_db_wait_duration_seconds_total", "Time blocked waiting for a connection.", func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }},
+		{"bookstore_db_max_idle_closed_total", "Connections closed due to max_idle_conns.", func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }},
+		{"bookstore_db_max_idle_time_closed_total", "Connections closed due to conn_max_idle_time.", func(s sql.DBStats) float64 { return float64(s.MaxIdleTimeClosed) }},
+		{"bookstore_db_max_lifetime_closed_total", "Connections closed due to conn_max_lifetime.", func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }},
+	}
+	for _, c := range counters {
+		value := c.value
+		m.reg.CounterFunc(c.name, c.help, func() float64 { return value(db.Stats()) })
+	}
+}
+
+// statement starts timing query and returns the func to call with its
+// outcome. Only statements under an existing span are traced, so background
+// work does not start traces of its own.
+func (m *DBMetrics) statement(ctx context.Context, query string) func(err error) {
+	start := time.Now()
+	method := queryMethod()
+
+	var span *tracing.Span
+	if tracing.SpanFromContext(ctx) != nil {
+		_, span = tracing.Start(ctx, "Query."+method, tracing.WithKind(tracing.KindClient), tracing.WithAttributes(
+			tracing.String("db.system", m.system),
+			tracing.String("db.statement", strings.TrimSpace(query)),
+			tracing.String("code.function", method),
+		))
+	}
+
+	return func(err error) {
+		m.queries.With(method).Observe(time.Since(start).Seconds())
+		if err != nil && err != driver.ErrSkip {
+			span.RecordError(err)
+		}
+		span.End()
+	}
+}
+
+// queryMethod finds the Query method on the calling stack, or "other" for
+// statements run elsewhere, such as migrations.
+func queryMethod() string {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if method := strings.TrimPrefix(frame.Function, queryMethodPrefix); method != frame.Function {
+			if i := strings.IndexByte(method, '.'); i >= 0 {
+				method = method[:i]
+			}
+			return method
+		}
+		if !more {
+			return "other"
+		}
+	}
+}
+
+type dsnConnector struct {
+	driver driver.Driver
+	dsn    string
+}
+
+func (c dsnConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+type timedConnector struct {
+	driver.Connector
+	m *DBMetrics
+}
+
+func (c timedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &timedConn{Conn: conn, m: c.m}, nil
+}
+
+// timedConn passes every call through to the driver's connection, timing
+// the ones that run statements. Optional interfaces the driver lacks fall
+// back to what database/sql would do without them.
+//
+// database/sql runs the statements of a transaction without the context it
+// was begun with, so the connection keeps that context until the transaction
+// ends; a connection serves one transaction at a time.
+type timedConn struct {
+	driver.Conn
+	m     *DBMetrics
+	txCtx context.Context
+}
+
+// statementCtx is ctx, or the transaction's when ctx carries no span.
+func (c *timedConn) statementCtx(ctx context.Context) context.Context {
+	if c.txCtx != nil && tracing.SpanFromContext(ctx) == nil {
+		return c.txCtx
+	}
+	return ctx
+}
+
+func (c *timedConn) Prepare(query string) (driver.Stmt, error) {
+	stmt, err := c.Conn.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	return timedStmt{stmt, c, query}, nil
+}
+
+func (c *timedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	pc, ok := c.Conn.(driver.ConnPrepareContext)
+	if !ok {
+		return c.Prepare(query)
+	}
+	stmt, err := pc.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return timedStmt{stmt, c, query}, nil
+}
+
+func (c *timedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	var tx driver.Tx
+	var err error
+	if bc, ok := c.Conn.(driver.ConnBeginTx); ok {
+		tx, err = bc.BeginTx(ctx, opts)
+	} else {
+		tx, err = c.Conn.Begin()
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.txCtx = ctx
+	return timedTx{tx, c}, nil
+}
+
+func (c *timedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	ec, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	done := c.m.statement(c.statementCtx(ctx), query)
+	res, err := ec.ExecContext(ctx, query, args)
+	done(err)
+	return res, err
+}
+
+func (c *timedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	qc, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	done := c.m.statement(c.statementCtx(ctx), query)
+	rows, err := qc.QueryContext(ctx, query, args)
+	done(err)
+	return rows, err
+}
+
+func (c *timedConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *timedConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *timedConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *timedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if nc, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type timedTx struct {
+	driver.Tx
+	conn *timedConn
+}
+
+func (t timedTx) Commit() error {
+	t.conn.txCtx = nil
+	return t.Tx.Commit()
+}
+
+func (t timedTx) Rollback() error {
+	t.conn.txCtx = nil
+	return t.Tx.Rollback()
+}
+
+type timedStmt struct {
+	driver.Stmt
+	conn  *timedConn
+	query string
+}
+
+func (s timedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	done := s.conn.m.statement(s.conn.statementCtx(ctx), s.query)
+	res, err := s.exec(ctx, args)
+	done(err)
+	return res, err
+}
+
+func (s timedStmt) exec(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	if ec, ok := s.Stmt.(driver.StmtExecContext); ok {
+		return ec.ExecContext(ctx, args)
+	}
+	values, err := namedValues(args)
+	if err != nil {
+		return nil, err
+	}
+	return s.Stmt.Exec(values)
+}
+
+func (s timedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	done := s.conn.m.statement(s.conn.statementCtx(ctx), s.query)
+	rows, err := s.run(ctx, args)
+	done(err)
+	return rows, err
+}
+
+func (s timedStmt) run(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	if qc, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		return qc.QueryContext(ctx, args)
+	}
+	values, err := namedValues(args)
+	if err != nil {
+		return nil, err
+	}
+	return s.Stmt.Query(values)
+}
+
+func (s timedStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if nc, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+var errNamedArgs = errors.New("driver does not support named arguments")
+
+func namedValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, errNamedArgs
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
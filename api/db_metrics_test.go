@@ -0,0 +1,96 @@
+//go:build unit
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/paquesqueue/bookstore/metrics"
+	"github.com/paquesqueue/bookstore/tracing"
+	"github.com/stretchr/testify/assert"
+)
+
+func scrapeMetrics(t *testing.T, reg *metrics.Registry) string {
+	var out strings.Builder
+	_, err := reg.WriteTo(&out)
+	assert.NoError(t, err)
+	return out.String()
+}
+
+func TestDBMetrics(t *testing.T) {
+	t.Run("TestDBMetricsShouldTimeStatementsByQueryMethod", func(t *testing.T) {
+		// Arrange
+		mockDB, mock, err := sqlmock.NewWithDSN("TestDBMetricsShouldTimeStatementsByQueryMethod")
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		mock.ExpectPrepare(regexp.QuoteMeta(`SELECT`)).
+			ExpectQuery().
+			WillReturnRows(sqlmock.NewRows([]string{"books", "stock_value", "users"}).AddRow(3, 4500, 2))
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM sessions`)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		reg := metrics.NewRegistry()
+		db, err := NewDBMetrics(reg).Open("sqlmock", "TestDBMetricsShouldTimeStatementsByQueryMethod")
+		assert.NoError(t, err)
+
+		// Act
+		stats, err := NewDB(db).SelectStoreStats(context.Background())
+		assert.NoError(t, err)
+		_, err = db.Exec(`DELETE FROM sessions WHERE expires_at < NOW();`)
+		assert.NoError(t, err)
+		out := scrapeMetrics(t, reg)
+
+		// Assert
+		assert.Equal(t, StoreStats{Books: 3, StockValue: 4500, Users: 2}, stats)
+		assert.Contains(t, out, `bookstore_db_query_duration_seconds_count{method="SelectStoreStats"} 1`)
+		assert.Contains(t, out, `bookstore_db_query_duration_seconds_count{method="other"} 1`)
+		assert.Contains(t, out, "\nbookstore_db_open_connections 1\n")
+		assert.Contains(t, out, "# TYPE bookstore_db_wait_count_total counter\n")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("TestDBMetricsShouldTraceStatementsUnderASpan", func(t *testing.T) {
+		// Arrange
+		mockDB, mock, err := sqlmock.NewWithDSN("TestDBMetricsShouldTraceStatementsUnderASpan")
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		mock.ExpectPrepare(regexp.QuoteMeta(`SELECT`)).
+			ExpectQuery().
+			WillReturnRows(sqlmock.NewRows([]string{"books", "stock_value", "users"}).AddRow(3, 4500, 2))
+		mock.ExpectPrepare(regexp.QuoteMeta(`SELECT`)).
+			ExpectQuery().
+			WillReturnRows(sqlmock.NewRows([]string{"books", "stock_value", "users"}).AddRow(3, 4500, 2))
+
+		db, err := NewDBMetrics(metrics.NewRegistry()).Open("sqlmock", "TestDBMetricsShouldTraceStatementsUnderASpan")
+		assert.NoError(t, err)
+
+		var out bytes.Buffer
+		tracer := tracing.NewTracer(1, tracing.NewStdoutExporter(&out))
+		tracing.SetTracer(tracer)
+		defer tracing.SetTracer(tracing.NewTracer(0, nil))
+
+		// Act
+		ctx, span := tracing.Start(context.Background(), "request")
+		_, err = NewDB(db).SelectStoreStats(ctx)
+		assert.NoError(t, err)
+		span.End()
+		_, err = NewDB(db).SelectStoreStats(context.Background())
+		assert.NoError(t, err)
+		assert.NoError(t, tracer.Shutdown(context.Background()))
+
+		// Assert
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		assert.Len(t, lines, 2)
+		assert.Contains(t, lines[0], `"name":"Query.SelectStoreStats"`)
+		assert.Contains(t, lines[0], `"parent_id":"`+span.SpanContext().SpanID.String()+`"`)
+		assert.Contains(t, lines[0], `"db.system":"sqlmock"`)
+		assert.Contains(t, lines[1], `"name":"request"`)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	c "github.com/paquesqueue/bookstore/common"
+)
+
+type DBStatusQueries interface {
+	Status() ResponseDBStatus
+}
+
+type HealthHandlrQueries interface {
+	Liveness() ResponseHealth
+	Readiness(ctx context.Context) ResponseHealth
+}
+
+type HealthHandlr struct {
+	db      DBStatusQueries
+	handler HealthHandlrQueries
+	log     c.Log
+}
+
+func NewHealthHandlr(db DBStatusQueries, h HealthHandlrQueries, l c.Log) HealthHandlr {
+	return HealthHandlr{db, h, l}
+}
+
+// DBStatus reports the last database health check with the pool statistics,
+// answering 503 while the database is unhealthy.
+func (h HealthHandlr) DBStatus(ctx echo.Context) error {
+	status := h.db.Status()
+	if !status.Healthy {
+		return ctx.JSON(http.StatusServiceUnavailable, status)
+	}
+	return ctx.JSON(http.StatusOK, status)
+}
+
+func (h HealthHandlr) Healthz(ctx echo.Context) error {
+	return healthJSON(ctx, h.handler.Liveness())
+}
+
+func (h HealthHandlr) Readyz(ctx echo.Context) error {
+	return healthJSON(ctx, h.handler.Readiness(ctx.Request().Context()))
+}
+
+func healthJSON(ctx echo.Context, res ResponseHealth) error {
+	if res.Status != HealthStatusUp {
+		return ctx.JSON(http.StatusServiceUnavailable, res)
+	}
+	return ctx.JSON(http.StatusOK, res)
+}
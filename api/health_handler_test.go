@@ -0,0 +1,118 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type DBStatusStub struct {
+	status ResponseDBStatus
+}
+
+func (s DBStatusStub) Status() ResponseDBStatus {
+	return s.status
+}
+
+type HealthHandlrStub struct {
+	ready bool
+}
+
+func (h HealthHandlrStub) Liveness() ResponseHealth {
+	return newResponseHealth(map[string]ResponseComponent{"process": {Status: HealthStatusUp}})
+}
+
+func (h HealthHandlrStub) Readiness(ctx context.Context) ResponseHealth {
+	if !h.ready {
+		return newResponseHealth(map[string]ResponseComponent{"shutdown": {Status: HealthStatusDown, Detail: "draining"}})
+	}
+	return newResponseHealth(map[string]ResponseComponent{"shutdown": {Status: HealthStatusUp}})
+}
+
+func newHealthContext(target string) (echo.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	rec := httptest.NewRecorder()
+	return echo.New().NewContext(req, rec), rec
+}
+
+func TestDBStatus(t *testing.T) {
+	t.Run("TestDBStatusShouldReturnPoolStatsWhenHealthy", func(t *testing.T) {
+		// Arrange
+		h := NewHealthHandlr(DBStatusStub{ResponseDBStatus{Healthy: true, Pool: ResponseDBStats{OpenConnections: 4}}}, HealthHandlrStub{}, logrus.New())
+		ctx, rec := newHealthContext("/status/db")
+
+		// Act
+		err := h.DBStatus(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"open_connections":4`)
+	})
+
+	t.Run("TestDBStatusShouldReturnServiceUnavailableWhenUnhealthy", func(t *testing.T) {
+		// Arrange
+		h := NewHealthHandlr(DBStatusStub{ResponseDBStatus{Error: "connection refused"}}, HealthHandlrStub{}, logrus.New())
+		ctx, rec := newHealthContext("/status/db")
+
+		// Act
+		err := h.DBStatus(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Contains(t, rec.Body.String(), "connection refused")
+	})
+}
+
+func TestHealthz(t *testing.T) {
+	t.Run("TestHealthzShouldReturnOk", func(t *testing.T) {
+		// Arrange
+		h := NewHealthHandlr(DBStatusStub{}, HealthHandlrStub{}, logrus.New())
+		ctx, rec := newHealthContext("/healthz")
+
+		// Act
+		err := h.Healthz(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"status":"up","components":{"process":{"status":"up"}}}`, rec.Body.String())
+	})
+}
+
+func TestReadyz(t *testing.T) {
+	t.Run("TestReadyzShouldReturnOkWhenReady", func(t *testing.T) {
+		// Arrange
+		h := NewHealthHandlr(DBStatusStub{}, HealthHandlrStub{ready: true}, logrus.New())
+		ctx, rec := newHealthContext("/readyz")
+
+		// Act
+		err := h.Readyz(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("TestReadyzShouldReturnServiceUnavailableWhenNotReady", func(t *testing.T) {
+		// Arrange
+		h := NewHealthHandlr(DBStatusStub{}, HealthHandlrStub{}, logrus.New())
+		ctx, rec := newHealthContext("/readyz")
+
+		// Act
+		err := h.Readyz(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.JSONEq(t, `{"status":"down","components":{"shutdown":{"status":"down","detail":"draining"}}}`, rec.Body.String())
+	})
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/paquesqueue/bookstore/tracing"
+)
+
+const (
+	HealthStatusUp   = "up"
+	HealthStatusDown = "down"
+)
+
+type HealthQueries interface {
+	SelectSchemaVersion(ctx context.Context) (int, error)
+}
+
+type DBChecker interface {
+	Check(ctx context.Context) error
+}
+
+type HealthServices struct {
+	query    HealthQueries
+	db       DBChecker
+	draining atomic.Bool
+	log      c.Log
+}
+
+func NewHealthService(q HealthQueries, db DBChecker, l c.Log) *HealthServices {
+	return &HealthServices{query: q, db: db, log: l}
+}
+
+// Drain marks the service as shutting down, so readiness fails from now on
+// while in-flight requests finish.
+func (s *HealthServices) Drain() {
+	s.draining.Store(true)
+}
+
+// Liveness only says the process is serving requests; it must not depend on
+// the database, or an outage would get every replica restarted.
+func (s *HealthServices) Liveness() ResponseHealth {
+	return newResponseHealth(map[string]ResponseComponent{
+		"process": {Status: HealthStatusUp},
+	})
+}
+
+// Readiness says whether the service should receive traffic: the database
+// answers, its schema is current and the service is not shutting down.
+func (s *HealthServices) Readiness(ctx context.Context) ResponseHealth {
+	ctx, span := tracing.Start(ctx, "HealthServices.Readiness")
+	defer span.End()
+
+	components := map[string]ResponseComponent{
+		"database":   {Status: HealthStatusUp},
+		"migrations": {Status: HealthStatusUp},
+		"shutdown":   {Status: HealthStatusUp},
+	}
+
+	if err := s.db.Check(ctx); err != nil {
+		components["database"] = ResponseComponent{Status: HealthStatusDown, Detail: err.Error()}
+		components["migrations"] = ResponseComponent{Status: HealthStatusDown, Detail: "database unreachable"}
+	} else if version, err := s.query.SelectSchemaVersion(ctx); err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error SelectSchemaVersion : %v", err)
+		components["migrations"] = ResponseComponent{Status: HealthStatusDown, Detail: err.Error()}
+	} else if version < LatestSchemaVersion() {
+		components["migrations"] = ResponseComponent{
+			Status: HealthStatusDown,
+			Detail: fmt.Sprintf("schema version %d, want %d", version, LatestSchemaVersion()),
+		}
+	}
+
+	if s.draining.Load() {
+		components["shutdown"] = ResponseComponent{Status: HealthStatusDown, Detail: "draining"}
+	}
+	return newResponseHealth(components)
+}
+
+func newResponseHealth(components map[string]ResponseComponent) ResponseHealth {
+	status := HealthStatusUp
+	for _, component := range components {
+		if component.Status != HealthStatusUp {
+			status = HealthStatusDown
+		}
+	}
+	return ResponseHealth{Status: status, Components: components}
+}
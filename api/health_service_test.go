@@ -0,0 +1,95 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type HealthQueriesStub struct {
+	version int
+	err     error
+}
+
+func (q HealthQueriesStub) SelectSchemaVersion(ctx context.Context) (int, error) {
+	return q.version, q.err
+}
+
+type DBCheckerStub struct {
+	err error
+}
+
+func (d DBCheckerStub) Check(ctx context.Context) error {
+	return d.err
+}
+
+func TestLiveness(t *testing.T) {
+	t.Run("TestLivenessShouldBeUpWhileDatabaseIsDown", func(t *testing.T) {
+		// Arrange
+		s := NewHealthService(HealthQueriesStub{}, DBCheckerStub{errors.New("connection refused")}, logrus.New())
+
+		// Act
+		res := s.Liveness()
+
+		// Assert
+		assert.Equal(t, HealthStatusUp, res.Status)
+	})
+}
+
+func TestReadiness(t *testing.T) {
+	t.Run("TestReadinessShouldBeUpWhenEveryComponentIs", func(t *testing.T) {
+		// Arrange
+		s := NewHealthService(HealthQueriesStub{version: LatestSchemaVersion()}, DBCheckerStub{}, logrus.New())
+
+		// Act
+		res := s.Readiness(context.Background())
+
+		// Assert
+		assert.Equal(t, HealthStatusUp, res.Status)
+		assert.Len(t, res.Components, 3)
+	})
+
+	t.Run("TestReadinessShouldBeDownWhenDatabaseIsUnreachable", func(t *testing.T) {
+		// Arrange
+		s := NewHealthService(HealthQueriesStub{version: LatestSchemaVersion()}, DBCheckerStub{errors.New("connection refused")}, logrus.New())
+
+		// Act
+		res := s.Readiness(context.Background())
+
+		// Assert
+		assert.Equal(t, HealthStatusDown, res.Status)
+		assert.Equal(t, ResponseComponent{Status: HealthStatusDown, Detail: "connection refused"}, res.Components["database"])
+		assert.Equal(t, HealthStatusDown, res.Components["migrations"].Status)
+	})
+
+	t.Run("TestReadinessShouldBeDownWhenMigrationsArePending", func(t *testing.T) {
+		// Arrange
+		s := NewHealthService(HealthQueriesStub{version: LatestSchemaVersion() - 1}, DBCheckerStub{}, logrus.New())
+
+		// Act
+		res := s.Readiness(context.Background())
+
+		// Assert
+		assert.Equal(t, HealthStatusDown, res.Status)
+		assert.Equal(t, HealthStatusUp, res.Components["database"].Status)
+		assert.Contains(t, res.Components["migrations"].Detail, "schema version")
+	})
+
+	t.Run("TestReadinessShouldBeDownOnceDraining", func(t *testing.T) {
+		// Arrange
+		s := NewHealthService(HealthQueriesStub{version: LatestSchemaVersion()}, DBCheckerStub{}, logrus.New())
+
+		// Act
+		s.Drain()
+		res := s.Readiness(context.Background())
+
+		// Assert
+		assert.Equal(t, HealthStatusDown, res.Status)
+		assert.Equal(t, ResponseComponent{Status: HealthStatusDown, Detail: "draining"}, res.Components["shutdown"])
+	})
+}
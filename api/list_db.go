@@ -0,0 +1,349 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+var (
+	errDefaultList = errors.New("error default list cannot be deleted")
+	errListOrder   = errors.New("error list order must contain every book of the list once")
+)
+
+const listColumns = `l.id, l.username, l.name, l.is_default, l.visibility, COALESCE(l.share_token, ''), l.created_at, l.updated_at,
+	(SELECT COUNT(*) FROM book_list_items i WHERE i.list_id = l.id)`
+
+func scanBookList(row rowScanner) (ResponseBookList, error) {
+	resp := ResponseBookList{}
+	err := row.Scan(&resp.Id, &resp.Username, &resp.Name, &resp.IsDefault, &resp.Visibility, &resp.ShareToken,
+		&resp.CreatedAt, &resp.UpdatedAt, &resp.BookCount)
+	if err != nil {
+		return ResponseBookList{}, err
+	}
+	return resp, nil
+}
+
+// InsertDefaultList gives a user their wishlist if they have none yet.
+func (db Query) InsertDefaultList(ctx context.Context, username string) error {
+	const query = `INSERT INTO book_lists (username, name, is_default) VALUES ($1, $2, TRUE) ON CONFLICT DO NOTHING;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, username, defaultListName)
+	return err
+}
+
+func (db Query) InsertBookList(ctx context.Context, req RequestBookList) (ResponseBookList, error) {
+	const query = `INSERT INTO book_lists AS l (username, name, visibility, share_token)
+	VALUES ($1, $2, $3, NULLIF($4, ''))
+	RETURNING ` + listColumns + `;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return ResponseBookList{}, err
+	}
+	defer stmt.Close()
+
+	return scanBookList(stmt.QueryRowContext(ctx, req.Username, req.Name, req.Visibility, req.ShareToken))
+}
+
+// SelectBookLists returns a user's lists without their books, the wishlist
+// first. An empty visibility returns every list.
+func (db Query) SelectBookLists(ctx context.Context, username string, visibility string) ([]ResponseBookList, error) {
+	const query = `SELECT ` + listColumns + `
+	FROM book_lists l
+	WHERE l.username = $1 AND ($2 = '' OR l.visibility = $2)
+	ORDER BY l.is_default DESC, l.name, l.id;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, username, visibility)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resp := []ResponseBookList{}
+	for rows.Next() {
+		list, err := scanBookList(rows)
+		if err != nil {
+			return nil, err
+		}
+		resp = append(resp, list)
+	}
+	return resp, rows.Err()
+}
+
+// SelectBookList returns a list with its books in list order.
+func (db Query) SelectBookList(ctx context.Context, id uint64) (ResponseBookList, error) {
+	const query = `SELECT ` + listColumns + ` FROM book_lists l WHERE l.id = $1;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return ResponseBookList{}, err
+	}
+	defer stmt.Close()
+
+	resp, err := scanBookList(stmt.QueryRowContext(ctx, id))
+	if err != nil {
+		return ResponseBookList{}, err
+	}
+	resp.Books, err = db.selectListBooks(ctx, resp.Id)
+	if err != nil {
+		return ResponseBookList{}, err
+	}
+	return resp, nil
+}
+
+// SelectSharedBookList finds a list by its share token. Lists that are no
+// longer shared have no token, so old links stop working.
+func (db Query) SelectSharedBookList(ctx context.Context, token string) (ResponseBookList, error) {
+	const query = `SELECT ` + listColumns + ` FROM book_lists l WHERE l.share_token = $1 AND l.visibility = $2;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return ResponseBookList{}, err
+	}
+	defer stmt.Close()
+
+	resp, err := scanBookList(stmt.QueryRowContext(ctx, token, ListVisibilityShared))
+	if err != nil {
+		return ResponseBookList{}, err
+	}
+	resp.Books, err = db.selectListBooks(ctx, resp.Id)
+	if err != nil {
+		return ResponseBookList{}, err
+	}
+	return resp, nil
+}
+
+func (db Query) selectListBooks(ctx context.Context, listId uint64) ([]ResponseListBook, error) {
+	const query = `SELECT ` + bookColumns + `, i.position, i.added_at
+	FROM book_list_items i
+	JOIN books b ON b.id = i.book_id
+	WHERE i.list_id = $1
+	ORDER BY i.position, i.book_id;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, listId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resp := []ResponseListBook{}
+	for rows.Next() {
+		item := ResponseListBook{}
+		var ratingSum int64
+		err := rows.Scan(&item.Id, &item.Title, pq.Array(&item.Authors), &item.Publisher, &item.Isbn, &item.Price, &item.Quantity, &item.Created_by, &item.Created_at,
+			&item.WorkId, &item.Format, &item.PublicationDate, &item.RatingCount, &ratingSum, &item.Position, &item.AddedAt)
+		if err != nil {
+			return nil, err
+		}
+		item.RatingAvg = ratingAverage(ratingSum, item.RatingCount)
+		resp = append(resp, item)
+	}
+	return resp, rows.Err()
+}
+
+// UpdateBookList renames a list or changes who can see it. A list keeps its
+// share token while it stays shared and loses it otherwise.
+func (db Query) UpdateBookList(ctx context.Context, username string, id uint64, req RequestBookList) (ResponseBookList, error) {
+	const query = `UPDATE book_lists AS l
+	SET name = $1, visibility = $2,
+		share_token = CASE WHEN $2 = '` + ListVisibilityShared + `' THEN COALESCE(l.share_token, NULLIF($3, '')) END,
+		updated_at = NOW()
+	WHERE l.id = $4 AND l.username = $5
+	RETURNING ` + listColumns + `;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return ResponseBookList{}, err
+	}
+	defer stmt.Close()
+
+	return scanBookList(stmt.QueryRowContext(ctx, req.Name, req.Visibility, req.ShareToken, id, username))
+}
+
+func (db Query) DeleteBookList(ctx context.Context, username string, id uint64) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	isDefault, err := lockBookList(tx, username, id)
+	if err != nil {
+		return err
+	}
+	if isDefault {
+		return errDefaultList
+	}
+	_, err = tx.Exec(`DELETE FROM book_lists WHERE id = $1;`, id)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// lockBookList checks that a list belongs to username and holds it for the
+// rest of the transaction, so concurrent edits keep positions consistent.
+func lockBookList(tx *sql.Tx, username string, id uint64) (bool, error) {
+	var isDefault bool
+	err := tx.QueryRow(`SELECT is_default FROM book_lists WHERE id = $1 AND username = $2 FOR UPDATE;`, id, username).Scan(&isDefault)
+	return isDefault, err
+}
+
+func touchBookList(tx *sql.Tx, id uint64) error {
+	_, err := tx.Exec(`UPDATE book_lists SET updated_at = NOW() WHERE id = $1;`, id)
+	return err
+}
+
+// InsertListBook appends a book to a list. Adding a book already on the list
+// leaves it where it is.
+func (db Query) InsertListBook(ctx context.Context, username string, listId uint64, bookId uint64) error {
+	const query = `INSERT INTO book_list_items (list_id, book_id, position)
+	SELECT $1, $2, COALESCE(MAX(position), 0) + 1 FROM book_list_items WHERE list_id = $1
+	ON CONFLICT DO NOTHING;`
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := lockBookList(tx, username, listId); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(query, listId, bookId); err != nil {
+		return err
+	}
+	if err := touchBookList(tx, listId); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (db Query) DeleteListBook(ctx context.Context, username string, listId uint64, bookId uint64) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := lockBookList(tx, username, listId); err != nil {
+		return err
+	}
+	result, err := tx.Exec(`DELETE FROM book_list_items WHERE list_id = $1 AND book_id = $2;`, listId, bookId)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	if err := touchBookList(tx, listId); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ReorderListBooks numbers the books of a list in the given order, which must
+// name every book on the list exactly once.
+func (db Query) ReorderListBooks(ctx context.Context, username string, listId uint64, bookIds []uint64) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := lockBookList(tx, username, listId); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(`SELECT book_id FROM book_list_items WHERE list_id = $1;`, listId)
+	if err != nil {
+		return err
+	}
+	current := map[uint64]bool{}
+	for rows.Next() {
+		var id uint64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		current[id] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(bookIds) != len(current) {
+		return errListOrder
+	}
+	for i, id := range bookIds {
+		if !current[id] {
+			return errListOrder
+		}
+		delete(current, id)
+		_, err := tx.Exec(`UPDATE book_list_items SET position = $1 WHERE list_id = $2 AND book_id = $3;`, i+1, listId, id)
+		if err != nil {
+			return err
+		}
+	}
+	if err := touchBookList(tx, listId); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SelectBookWatchers returns the users keeping a book on any of their lists.
+func (db Query) SelectBookWatchers(ctx context.Context, bookId uint64) ([]string, error) {
+	const query = `SELECT DISTINCT l.username
+	FROM book_list_items i
+	JOIN book_lists l ON l.id = i.list_id
+	WHERE i.book_id = $1
+	ORDER BY l.username;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, bookId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resp := []string{}
+	for rows.Next() {
+		var username string
+		if err := rows.Scan(&username); err != nil {
+			return nil, err
+		}
+		resp = append(resp, username)
+	}
+	return resp, rows.Err()
+}
@@ -0,0 +1,175 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInsertListBook(t *testing.T) {
+	t.Run("TestInsertListBookShouldAppendToOwnedList", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT is_default FROM book_lists WHERE id = $1 AND username = $2 FOR UPDATE;`)).
+			WithArgs(3, "reader").
+			WillReturnRows(sqlmock.NewRows([]string{"is_default"}).AddRow(false))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO book_list_items (list_id, book_id, position) SELECT $1, $2, COALESCE(MAX(position), 0) + 1 FROM book_list_items WHERE list_id = $1 ON CONFLICT DO NOTHING;`)).
+			WithArgs(3, 7).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE book_lists SET updated_at = NOW() WHERE id = $1;`)).
+			WithArgs(3).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		query := NewDB(db)
+
+		// Act
+		err = query.InsertListBook(context.Background(), "reader", 3, 7)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestInsertListBookShouldReturnErrNoRowsForOthersList", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT is_default FROM book_lists`)).
+			WithArgs(3, "intruder").
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		query := NewDB(db)
+
+		// Act
+		err = query.InsertListBook(context.Background(), "intruder", 3, 7)
+
+		// Assert
+		assert.Equal(t, sql.ErrNoRows, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestReorderListBooks(t *testing.T) {
+	t.Run("TestReorderListBooksShouldNumberBooksInOrder", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT is_default FROM book_lists`)).
+			WithArgs(3, "reader").
+			WillReturnRows(sqlmock.NewRows([]string{"is_default"}).AddRow(true))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT book_id FROM book_list_items WHERE list_id = $1;`)).
+			WithArgs(3).
+			WillReturnRows(sqlmock.NewRows([]string{"book_id"}).AddRow(7).AddRow(9))
+		for i, id := range []int{9, 7} {
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE book_list_items SET position = $1 WHERE list_id = $2 AND book_id = $3;`)).
+				WithArgs(i+1, 3, id).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE book_lists SET updated_at = NOW()`)).
+			WithArgs(3).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		query := NewDB(db)
+
+		// Act
+		err = query.ReorderListBooks(context.Background(), "reader", 3, []uint64{9, 7})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestReorderListBooksShouldRejectDuplicates", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT is_default FROM book_lists`)).
+			WithArgs(3, "reader").
+			WillReturnRows(sqlmock.NewRows([]string{"is_default"}).AddRow(true))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT book_id FROM book_list_items`)).
+			WithArgs(3).
+			WillReturnRows(sqlmock.NewRows([]string{"book_id"}).AddRow(7).AddRow(9))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE book_list_items SET position`)).
+			WithArgs(1, 3, 7).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectRollback()
+
+		query := NewDB(db)
+
+		// Act
+		err = query.ReorderListBooks(context.Background(), "reader", 3, []uint64{7, 7})
+
+		// Assert
+		assert.Equal(t, errListOrder, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDeleteBookList(t *testing.T) {
+	t.Run("TestDeleteBookListShouldKeepWishlist", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT is_default FROM book_lists`)).
+			WithArgs(1, "reader").
+			WillReturnRows(sqlmock.NewRows([]string{"is_default"}).AddRow(true))
+		mock.ExpectRollback()
+
+		query := NewDB(db)
+
+		// Act
+		err = query.DeleteBookList(context.Background(), "reader", 1)
+
+		// Assert
+		assert.Equal(t, errDefaultList, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestSelectBookWatchers(t *testing.T) {
+	t.Run("TestSelectBookWatchersShouldReturnDistinctUsers", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectPrepare(regexp.QuoteMeta(`SELECT DISTINCT l.username FROM book_list_items i JOIN book_lists l ON l.id = i.list_id WHERE i.book_id = $1`)).
+			ExpectQuery().
+			WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"username"}).AddRow("a").AddRow("b"))
+
+		query := NewDB(db)
+
+		// Act
+		res, err := query.SelectBookWatchers(context.Background(), 7)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a", "b"}, res)
+	})
+}
@@ -0,0 +1,232 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	c "github.com/paquesqueue/bookstore/common"
+)
+
+type ListHandlrQueries interface {
+	GetLists(ctx context.Context, username string, viewer string) ([]ResponseBookList, error)
+	AddList(ctx context.Context, username string, req RequestBookList) (ResponseBookList, error)
+	GetList(ctx context.Context, username string, viewer string, id uint64) (ResponseBookList, error)
+	GetSharedList(ctx context.Context, token string) (ResponseBookList, error)
+	PutList(ctx context.Context, username string, id uint64, req RequestBookList) (ResponseBookList, error)
+	DeleteList(ctx context.Context, username string, id uint64) error
+	AddListBook(ctx context.Context, username string, id uint64, req RequestListBook) (ResponseBookList, error)
+	RemoveListBook(ctx context.Context, username string, id uint64, bookId uint64) (ResponseBookList, error)
+	ReorderListBooks(ctx context.Context, username string, id uint64, req RequestListOrder) (ResponseBookList, error)
+}
+
+type ListHandlr struct {
+	handler ListHandlrQueries
+	log     c.Log
+}
+
+func NewListHandlr(h ListHandlrQueries, l c.Log) ListHandlr {
+	return ListHandlr{h, l}
+}
+
+// listOwner returns the username in the path when the session belongs to it.
+func listOwner(ctx echo.Context) (string, bool) {
+	user, ok := CurrentUser(ctx)
+	username := ctx.Param("username")
+	return username, ok && user.Username == username
+}
+
+func (h ListHandlr) GetLists(ctx echo.Context) error {
+	viewer, _ := CurrentUser(ctx)
+
+	res, err := h.handler.GetLists(ctx.Request().Context(), ctx.Param("username"), viewer.Username)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error GetLists Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h ListHandlr) AddList(ctx echo.Context) error {
+	username, ok := listOwner(ctx)
+	if !ok {
+		return ctx.NoContent(http.StatusForbidden)
+	}
+
+	req := RequestBookList{}
+	err := ctx.Bind(&req)
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	res, err := h.handler.AddList(ctx.Request().Context(), username, req)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error AddList Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusCreated, res)
+}
+
+func (h ListHandlr) GetList(ctx echo.Context) error {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+	viewer, _ := CurrentUser(ctx)
+
+	res, err := h.handler.GetList(ctx.Request().Context(), ctx.Param("username"), viewer.Username, uint64(id))
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error GetList Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h ListHandlr) GetSharedList(ctx echo.Context) error {
+	res, err := h.handler.GetSharedList(ctx.Request().Context(), ctx.Param("token"))
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error GetSharedList Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h ListHandlr) PutList(ctx echo.Context) error {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+	username, ok := listOwner(ctx)
+	if !ok {
+		return ctx.NoContent(http.StatusForbidden)
+	}
+
+	req := RequestBookList{}
+	err = ctx.Bind(&req)
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	res, err := h.handler.PutList(ctx.Request().Context(), username, uint64(id), req)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error PutList Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h ListHandlr) DeleteList(ctx echo.Context) error {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+	username, ok := listOwner(ctx)
+	if !ok {
+		return ctx.NoContent(http.StatusForbidden)
+	}
+
+	err = h.handler.DeleteList(ctx.Request().Context(), username, uint64(id))
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error DeleteList Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, "Deleted Successfully")
+}
+
+func (h ListHandlr) AddListBook(ctx echo.Context) error {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+	username, ok := listOwner(ctx)
+	if !ok {
+		return ctx.NoContent(http.StatusForbidden)
+	}
+
+	req := RequestListBook{}
+	err = ctx.Bind(&req)
+	if err != nil || req.BookId == 0 {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	res, err := h.handler.AddListBook(ctx.Request().Context(), username, uint64(id), req)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error AddListBook Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h ListHandlr) RemoveListBook(ctx echo.Context) error {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+	bookId, err := strconv.Atoi(ctx.Param("bookId"))
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+	username, ok := listOwner(ctx)
+	if !ok {
+		return ctx.NoContent(http.StatusForbidden)
+	}
+
+	res, err := h.handler.RemoveListBook(ctx.Request().Context(), username, uint64(id), uint64(bookId))
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error RemoveListBook Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h ListHandlr) ReorderListBooks(ctx echo.Context) error {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+	username, ok := listOwner(ctx)
+	if !ok {
+		return ctx.NoContent(http.StatusForbidden)
+	}
+
+	req := RequestListOrder{}
+	err = ctx.Bind(&req)
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	res, err := h.handler.ReorderListBooks(ctx.Request().Context(), username, uint64(id), req)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error ReorderListBooks Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,170 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type ListHandlrStub struct {
+	username string
+	viewer   string
+	bookId   uint64
+	err      error
+}
+
+func (h *ListHandlrStub) GetLists(ctx context.Context, username string, viewer string) ([]ResponseBookList, error) {
+	h.username, h.viewer = username, viewer
+	return []ResponseBookList{}, h.err
+}
+
+func (h *ListHandlrStub) AddList(ctx context.Context, username string, req RequestBookList) (ResponseBookList, error) {
+	h.username = username
+	return ResponseBookList{Id: 2, Username: username, Name: req.Name}, h.err
+}
+
+func (h *ListHandlrStub) GetList(ctx context.Context, username string, viewer string, id uint64) (ResponseBookList, error) {
+	h.username, h.viewer = username, viewer
+	return ResponseBookList{Id: id}, h.err
+}
+
+func (h *ListHandlrStub) GetSharedList(ctx context.Context, token string) (ResponseBookList, error) {
+	return ResponseBookList{}, h.err
+}
+
+func (h *ListHandlrStub) PutList(ctx context.Context, username string, id uint64, req RequestBookList) (ResponseBookList, error) {
+	return ResponseBookList{Id: id}, h.err
+}
+
+func (h *ListHandlrStub) DeleteList(ctx context.Context, username string, id uint64) error {
+	return h.err
+}
+
+func (h *ListHandlrStub) AddListBook(ctx context.Context, username string, id uint64, req RequestListBook) (ResponseBookList, error) {
+	h.bookId = req.BookId
+	return ResponseBookList{Id: id}, h.err
+}
+
+func (h *ListHandlrStub) RemoveListBook(ctx context.Context, username string, id uint64, bookId uint64) (ResponseBookList, error) {
+	h.bookId = bookId
+	return ResponseBookList{Id: id}, h.err
+}
+
+func (h *ListHandlrStub) ReorderListBooks(ctx context.Context, username string, id uint64, req RequestListOrder) (ResponseBookList, error) {
+	return ResponseBookList{Id: id}, h.err
+}
+
+func newListContext(method string, body string, user *ResponseUser) (echo.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(method, "/users/reader/lists/1", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	ctx := echo.New().NewContext(req, rec)
+	ctx.SetParamNames("username", "id")
+	ctx.SetParamValues("reader", "1")
+	if user != nil {
+		ctx.Set(ctxUserKey, *user)
+	}
+	return ctx, rec
+}
+
+func TestAddListHandler(t *testing.T) {
+	t.Run("TestAddListHandlerShouldReturnHTTPStatus201", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newListContext(http.MethodPost, `{"name":"Holiday"}`, &ResponseUser{Username: "reader"})
+		stub := &ListHandlrStub{}
+		handler := NewListHandlr(stub, logrus.New())
+
+		// Act
+		err := handler.AddList(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, "reader", stub.username)
+	})
+
+	t.Run("TestAddListHandlerShouldReturnHTTPStatus403ForOtherUser", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newListContext(http.MethodPost, `{"name":"Holiday"}`, &ResponseUser{Username: "intruder"})
+		stub := &ListHandlrStub{}
+		handler := NewListHandlr(stub, logrus.New())
+
+		// Act
+		err := handler.AddList(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Empty(t, stub.username)
+	})
+}
+
+func TestGetListHandler(t *testing.T) {
+	t.Run("TestGetListHandlerShouldPassAnonymousViewer", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newListContext(http.MethodGet, "", nil)
+		stub := &ListHandlrStub{}
+		handler := NewListHandlr(stub, logrus.New())
+
+		// Act
+		err := handler.GetList(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "reader", stub.username)
+		assert.Empty(t, stub.viewer)
+	})
+
+	t.Run("TestGetListHandlerShouldReturnServiceStatus", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newListContext(http.MethodGet, "", nil)
+		handler := NewListHandlr(&ListHandlrStub{err: &c.Err{Code: http.StatusNotFound}}, logrus.New())
+
+		// Act
+		err := handler.GetList(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestAddListBookHandler(t *testing.T) {
+	t.Run("TestAddListBookHandlerShouldReturnHTTPStatus400WithoutBook", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newListContext(http.MethodPost, `{}`, &ResponseUser{Username: "reader"})
+		handler := NewListHandlr(&ListHandlrStub{}, logrus.New())
+
+		// Act
+		err := handler.AddListBook(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("TestAddListBookHandlerShouldReturnHTTPStatus200", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newListContext(http.MethodPost, `{"book_id":7}`, &ResponseUser{Username: "reader"})
+		stub := &ListHandlrStub{}
+		handler := NewListHandlr(stub, logrus.New())
+
+		// Act
+		err := handler.AddListBook(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, uint64(7), stub.bookId)
+	})
+}
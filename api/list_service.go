@@ -0,0 +1,304 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/paquesqueue/bookstore/tracing"
+	"github.com/paquesqueue/bookstore/utils"
+)
+
+// Private lists are seen by their owner only, shared lists also by anyone
+// holding the share link, and public lists by everyone.
+const (
+	ListVisibilityPrivate = "private"
+	ListVisibilityShared  = "shared"
+	ListVisibilityPublic  = "public"
+
+	defaultListName = "Wishlist"
+	listMaxNameLen  = 100
+)
+
+type ListQueries interface {
+	InsertDefaultList(ctx context.Context, username string) error
+	InsertBookList(ctx context.Context, req RequestBookList) (ResponseBookList, error)
+	SelectBookLists(ctx context.Context, username string, visibility string) ([]ResponseBookList, error)
+	SelectBookList(ctx context.Context, id uint64) (ResponseBookList, error)
+	SelectSharedBookList(ctx context.Context, token string) (ResponseBookList, error)
+	UpdateBookList(ctx context.Context, username string, id uint64, req RequestBookList) (ResponseBookList, error)
+	DeleteBookList(ctx context.Context, username string, id uint64) error
+	InsertListBook(ctx context.Context, username string, listId uint64, bookId uint64) error
+	DeleteListBook(ctx context.Context, username string, listId uint64, bookId uint64) error
+	ReorderListBooks(ctx context.Context, username string, listId uint64, bookIds []uint64) error
+}
+
+type ListServices struct {
+	query ListQueries
+	log   c.Log
+}
+
+func NewListService(q ListQueries, l c.Log) ListServices {
+	return ListServices{q, l}
+}
+
+// GetLists returns the lists of username visible to viewer. Owners always
+// have a wishlist; it is created the first time they look.
+func (s ListServices) GetLists(ctx context.Context, username string, viewer string) ([]ResponseBookList, error) {
+	ctx, span := tracing.Start(ctx, "ListServices.GetLists")
+	defer span.End()
+
+	visibility := ListVisibilityPublic
+	if viewer == username {
+		visibility = ""
+		if err := s.query.InsertDefaultList(ctx, username); err != nil {
+			c.LogFrom(ctx, s.log).Errorf("Error InsertDefaultList : %v", err)
+			return nil, listErr(err, "Error GetLists Service")
+		}
+	}
+
+	resp, err := s.query.SelectBookLists(ctx, username, visibility)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error SelectBookLists : %v", err)
+		return nil, listErr(err, "Error GetLists Service")
+	}
+	return resp, nil
+}
+
+func (s ListServices) AddList(ctx context.Context, username string, req RequestBookList) (ResponseBookList, error) {
+	ctx, span := tracing.Start(ctx, "ListServices.AddList")
+	defer span.End()
+
+	req, err := s.validList(ctx, req)
+	if err != nil {
+		return ResponseBookList{}, err
+	}
+	if req.Name == defaultListName {
+		return ResponseBookList{}, &c.Err{Code: http.StatusConflict, Remark: "Error List Name Reserved"}
+	}
+	req.Username = username
+
+	resp, err := s.query.InsertBookList(ctx, req)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error InsertBookList : %v", err)
+		return ResponseBookList{}, listErr(err, "Error AddList Service")
+	}
+	return resp, nil
+}
+
+// GetList hides lists the viewer may not see behind a 404, so their
+// existence is not revealed.
+func (s ListServices) GetList(ctx context.Context, username string, viewer string, id uint64) (ResponseBookList, error) {
+	ctx, span := tracing.Start(ctx, "ListServices.GetList")
+	defer span.End()
+
+	resp, err := s.query.SelectBookList(ctx, id)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error SelectBookList : %v", err)
+		return ResponseBookList{}, listErr(err, "Error GetList Service")
+	}
+	if resp.Username != username || (viewer != username && resp.Visibility != ListVisibilityPublic) {
+		return ResponseBookList{}, listErr(sql.ErrNoRows, "")
+	}
+	if viewer != username {
+		resp.ShareToken = ""
+	}
+	return resp, nil
+}
+
+func (s ListServices) GetSharedList(ctx context.Context, token string) (ResponseBookList, error) {
+	ctx, span := tracing.Start(ctx, "ListServices.GetSharedList")
+	defer span.End()
+
+	resp, err := s.query.SelectSharedBookList(ctx, token)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error SelectSharedBookList : %v", err)
+		return ResponseBookList{}, listErr(err, "Error GetSharedList Service")
+	}
+	resp.ShareToken = ""
+	return resp, nil
+}
+
+func (s ListServices) PutList(ctx context.Context, username string, id uint64, req RequestBookList) (ResponseBookList, error) {
+	ctx, span := tracing.Start(ctx, "ListServices.PutList")
+	defer span.End()
+
+	req, err := s.validList(ctx, req)
+	if err != nil {
+		return ResponseBookList{}, err
+	}
+
+	resp, err := s.query.UpdateBookList(ctx, username, id, req)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error UpdateBookList : %v", err)
+		return ResponseBookList{}, listErr(err, "Error PutList Service")
+	}
+	return resp, nil
+}
+
+func (s ListServices) DeleteList(ctx context.Context, username string, id uint64) error {
+	ctx, span := tracing.Start(ctx, "ListServices.DeleteList")
+	defer span.End()
+
+	err := s.query.DeleteBookList(ctx, username, id)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error DeleteBookList : %v", err)
+		return listErr(err, "Error DeleteList Service")
+	}
+	return nil
+}
+
+func (s ListServices) AddListBook(ctx context.Context, username string, id uint64, req RequestListBook) (ResponseBookList, error) {
+	ctx, span := tracing.Start(ctx, "ListServices.AddListBook")
+	defer span.End()
+
+	err := s.query.InsertListBook(ctx, username, id, req.BookId)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error InsertListBook : %v", err)
+		return ResponseBookList{}, listErr(err, "Error AddListBook Service")
+	}
+	return s.GetList(ctx, username, username, id)
+}
+
+func (s ListServices) RemoveListBook(ctx context.Context, username string, id uint64, bookId uint64) (ResponseBookList, error) {
+	ctx, span := tracing.Start(ctx, "ListServices.RemoveListBook")
+	defer span.End()
+
+	err := s.query.DeleteListBook(ctx, username, id, bookId)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error DeleteListBook : %v", err)
+		return ResponseBookList{}, listErr(err, "Error RemoveListBook Service")
+	}
+	return s.GetList(ctx, username, username, id)
+}
+
+func (s ListServices) ReorderListBooks(ctx context.Context, username string, id uint64, req RequestListOrder) (ResponseBookList, error) {
+	ctx, span := tracing.Start(ctx, "ListServices.ReorderListBooks")
+	defer span.End()
+
+	err := s.query.ReorderListBooks(ctx, username, id, req.BookIds)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error ReorderListBooks : %v", err)
+		return ResponseBookList{}, listErr(err, "Error ReorderListBooks Service")
+	}
+	return s.GetList(ctx, username, username, id)
+}
+
+// validList trims the name and gives shared lists a candidate share token;
+// the database keeps the existing token when the list was already shared.
+func (s ListServices) validList(ctx context.Context, req RequestBookList) (RequestBookList, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || utf8.RuneCountInString(req.Name) > listMaxNameLen {
+		return req, &c.Err{Code: http.StatusBadRequest, Remark: "Error List Name Required"}
+	}
+
+	switch req.Visibility {
+	case "":
+		req.Visibility = ListVisibilityPrivate
+	case ListVisibilityPrivate, ListVisibilityPublic:
+	case ListVisibilityShared:
+		token, err := utils.NewToken()
+		if err != nil {
+			c.LogFrom(ctx, s.log).Errorf("Error List New Token : %v", err)
+			return req, &c.Err{Code: http.StatusInternalServerError, Remark: "Error List Service", Original: err}
+		}
+		req.ShareToken = token
+	default:
+		return req, &c.Err{Code: http.StatusBadRequest, Remark: "Error Unknown List Visibility"}
+	}
+	return req, nil
+}
+
+func listErr(err error, remark string) *c.Err {
+	switch {
+	case err == sql.ErrNoRows:
+		return &c.Err{Code: http.StatusNotFound, Remark: "Error List Not Found", Original: err}
+	case err == errDefaultList:
+		return &c.Err{Code: http.StatusConflict, Remark: "Error Wishlist Cannot Be Deleted", Original: err}
+	case err == errListOrder:
+		return &c.Err{Code: http.StatusBadRequest, Remark: "Error List Order Does Not Match", Original: err}
+	case isPqError(err, pqUniqueViolation):
+		return &c.Err{Code: http.StatusConflict, Remark: "Error List Name Taken", Original: err}
+	case isPqError(err, pqForeignKeyViolation):
+		return &c.Err{Code: http.StatusNotFound, Remark: "Error Book Or User Not Found", Original: err}
+	default:
+		return &c.Err{Code: http.StatusInternalServerError, Remark: remark, Original: err}
+	}
+}
+
+const (
+	ListEventPriceDrop   = "price_drop"
+	ListEventBackInStock = "back_in_stock"
+)
+
+// ListEvent tells the users keeping a book on one of their lists that it got
+// cheaper or is available again.
+type ListEvent struct {
+	Kind          string
+	Book          ResponseBook
+	PreviousPrice int64
+	Usernames     []string
+}
+
+// ListNotifier delivers list events, for example by e-mail or a message
+// queue. Delivery failures are logged and never fail the book update.
+type ListNotifier interface {
+	NotifyList(ctx context.Context, event ListEvent) error
+}
+
+// LogListNotifier only writes events to the log.
+type LogListNotifier struct {
+	log c.Log
+}
+
+func NewLogListNotifier(l c.Log) LogListNotifier {
+	return LogListNotifier{l}
+}
+
+func (n LogListNotifier) NotifyList(ctx context.Context, event ListEvent) error {
+	c.LogFrom(ctx, n.log).Infof("List event %v for book %d sent to %d users", event.Kind, event.Book.Id, len(event.Usernames))
+	return nil
+}
+
+type listWatcherQueries interface {
+	SelectBookWatchers(ctx context.Context, bookId uint64) ([]string, error)
+}
+
+// listEvents compares a book before and after an update.
+func listEvents(before ResponseBook, after ResponseBook) []string {
+	events := []string{}
+	if after.Price < before.Price {
+		events = append(events, ListEventPriceDrop)
+	}
+	if before.Quantity <= 0 && after.Quantity > 0 {
+		events = append(events, ListEventBackInStock)
+	}
+	return events
+}
+
+// notifyListWatchers sends the events an update caused to the users watching
+// the book. A nil notifier disables notifications.
+func notifyListWatchers(ctx context.Context, q listWatcherQueries, n ListNotifier, log c.Log, before ResponseBook, after ResponseBook) {
+	events := listEvents(before, after)
+	if n == nil || len(events) == 0 {
+		return
+	}
+
+	usernames, err := q.SelectBookWatchers(ctx, after.Id)
+	if err != nil {
+		log.Errorf("Error SelectBookWatchers : %v", err)
+		return
+	}
+	if len(usernames) == 0 {
+		return
+	}
+	for _, kind := range events {
+		err := n.NotifyList(ctx, ListEvent{Kind: kind, Book: after, PreviousPrice: before.Price, Usernames: usernames})
+		if err != nil {
+			log.Errorf("Error NotifyList %v : %v", kind, err)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"testing"
+
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type ListQueriesStub struct {
+	lists          map[uint64]ResponseBookList
+	defaultCreated bool
+	visibility     string
+	updated        RequestBookList
+	err            error
+}
+
+func newListQueriesStub() *ListQueriesStub {
+	return &ListQueriesStub{lists: map[uint64]ResponseBookList{
+		1: {Id: 1, Username: "reader", Name: defaultListName, IsDefault: true, Visibility: ListVisibilityPrivate},
+		2: {Id: 2, Username: "reader", Name: "Holiday", Visibility: ListVisibilityPublic, ShareToken: ""},
+		3: {Id: 3, Username: "reader", Name: "Gifts", Visibility: ListVisibilityShared, ShareToken: "share"},
+	}}
+}
+
+func (q *ListQueriesStub) InsertDefaultList(ctx context.Context, username string) error {
+	q.defaultCreated = true
+	return q.err
+}
+
+func (q *ListQueriesStub) InsertBookList(ctx context.Context, req RequestBookList) (ResponseBookList, error) {
+	return ResponseBookList{Id: 4, Username: req.Username, Name: req.Name, Visibility: req.Visibility, ShareToken: req.ShareToken}, q.err
+}
+
+func (q *ListQueriesStub) SelectBookLists(ctx context.Context, username string, visibility string) ([]ResponseBookList, error) {
+	q.visibility = visibility
+	return []ResponseBookList{}, q.err
+}
+
+func (q *ListQueriesStub) SelectBookList(ctx context.Context, id uint64) (ResponseBookList, error) {
+	list, ok := q.lists[id]
+	if !ok {
+		return ResponseBookList{}, sql.ErrNoRows
+	}
+	return list, q.err
+}
+
+func (q *ListQueriesStub) SelectSharedBookList(ctx context.Context, token string) (ResponseBookList, error) {
+	for _, list := range q.lists {
+		if list.ShareToken == token && list.Visibility == ListVisibilityShared {
+			return list, nil
+		}
+	}
+	return ResponseBookList{}, sql.ErrNoRows
+}
+
+func (q *ListQueriesStub) UpdateBookList(ctx context.Context, username string, id uint64, req RequestBookList) (ResponseBookList, error) {
+	q.updated = req
+	return ResponseBookList{Id: id, Username: username, Name: req.Name, Visibility: req.Visibility}, q.err
+}
+
+func (q *ListQueriesStub) DeleteBookList(ctx context.Context, username string, id uint64) error {
+	return q.err
+}
+
+func (q *ListQueriesStub) InsertListBook(ctx context.Context, username string, listId uint64, bookId uint64) error {
+	return q.err
+}
+
+func (q *ListQueriesStub) DeleteListBook(ctx context.Context, username string, listId uint64, bookId uint64) error {
+	return q.err
+}
+
+func (q *ListQueriesStub) ReorderListBooks(ctx context.Context, username string, listId uint64, bookIds []uint64) error {
+	return q.err
+}
+
+type ListNotifierStub struct {
+	events []ListEvent
+}
+
+func (n *ListNotifierStub) NotifyList(ctx context.Context, event ListEvent) error {
+	n.events = append(n.events, event)
+	return nil
+}
+
+func TestGetLists(t *testing.T) {
+	t.Run("TestGetListsShouldCreateWishlistForOwner", func(t *testing.T) {
+		// Arrange
+		query := newListQueriesStub()
+		services := NewListService(query, logrus.New())
+
+		// Act
+		_, err := services.GetLists(context.Background(), "reader", "reader")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.True(t, query.defaultCreated)
+		assert.Equal(t, "", query.visibility)
+	})
+
+	t.Run("TestGetListsShouldShowPublicListsToOthers", func(t *testing.T) {
+		// Arrange
+		query := newListQueriesStub()
+		services := NewListService(query, logrus.New())
+
+		// Act
+		_, err := services.GetLists(context.Background(), "reader", "")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.False(t, query.defaultCreated)
+		assert.Equal(t, ListVisibilityPublic, query.visibility)
+	})
+}
+
+func TestGetList(t *testing.T) {
+	services := NewListService(newListQueriesStub(), logrus.New())
+
+	t.Run("TestGetListShouldHidePrivateListFromOthers", func(t *testing.T) {
+		// Act
+		_, err := services.GetList(context.Background(), "reader", "visitor", 1)
+
+		// Assert
+		if assert.Error(t, err) {
+			assert.Equal(t, http.StatusNotFound, err.(*c.Err).Code)
+		}
+	})
+
+	t.Run("TestGetListShouldHideSharedListWithoutLink", func(t *testing.T) {
+		// Act
+		_, err := services.GetList(context.Background(), "reader", "", 3)
+
+		// Assert
+		if assert.Error(t, err) {
+			assert.Equal(t, http.StatusNotFound, err.(*c.Err).Code)
+		}
+	})
+
+	t.Run("TestGetListShouldReturnListUnderItsOwnerOnly", func(t *testing.T) {
+		// Act
+		_, err := services.GetList(context.Background(), "someone", "someone", 2)
+
+		// Assert
+		if assert.Error(t, err) {
+			assert.Equal(t, http.StatusNotFound, err.(*c.Err).Code)
+		}
+	})
+
+	t.Run("TestGetListShouldShowShareTokenToOwner", func(t *testing.T) {
+		// Act
+		res, err := services.GetList(context.Background(), "reader", "reader", 3)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "share", res.ShareToken)
+	})
+
+	t.Run("TestGetSharedListShouldResolveToken", func(t *testing.T) {
+		// Act
+		res, err := services.GetSharedList(context.Background(), "share")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(3), res.Id)
+		assert.Empty(t, res.ShareToken)
+	})
+}
+
+func TestAddList(t *testing.T) {
+	t.Run("TestAddListShouldGiveSharedListAToken", func(t *testing.T) {
+		// Arrange
+		services := NewListService(newListQueriesStub(), logrus.New())
+
+		// Act
+		res, err := services.AddList(context.Background(), "reader", RequestBookList{Name: " Book club ", Visibility: ListVisibilityShared})
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, "Book club", res.Name)
+			assert.NotEmpty(t, res.ShareToken)
+		}
+	})
+
+	t.Run("TestAddListShouldDefaultToPrivate", func(t *testing.T) {
+		// Arrange
+		services := NewListService(newListQueriesStub(), logrus.New())
+
+		// Act
+		res, err := services.AddList(context.Background(), "reader", RequestBookList{Name: "Later"})
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, ListVisibilityPrivate, res.Visibility)
+			assert.Empty(t, res.ShareToken)
+		}
+	})
+
+	t.Run("TestAddListShouldReturnHTTPStatus409ForWishlistName", func(t *testing.T) {
+		// Arrange
+		services := NewListService(newListQueriesStub(), logrus.New())
+
+		// Act
+		_, err := services.AddList(context.Background(), "reader", RequestBookList{Name: defaultListName})
+
+		// Assert
+		if assert.Error(t, err) {
+			assert.Equal(t, http.StatusConflict, err.(*c.Err).Code)
+		}
+	})
+
+	t.Run("TestAddListShouldReturnHTTPStatus400ForUnknownVisibility", func(t *testing.T) {
+		// Arrange
+		services := NewListService(newListQueriesStub(), logrus.New())
+
+		// Act
+		_, err := services.AddList(context.Background(), "reader", RequestBookList{Name: "Later", Visibility: "friends"})
+
+		// Assert
+		if assert.Error(t, err) {
+			assert.Equal(t, http.StatusBadRequest, err.(*c.Err).Code)
+		}
+	})
+}
+
+func TestDeleteList(t *testing.T) {
+	t.Run("TestDeleteListShouldReturnHTTPStatus409ForWishlist", func(t *testing.T) {
+		// Arrange
+		query := newListQueriesStub()
+		query.err = errDefaultList
+		services := NewListService(query, logrus.New())
+
+		// Act
+		err := services.DeleteList(context.Background(), "reader", 1)
+
+		// Assert
+		if assert.Error(t, err) {
+			assert.Equal(t, http.StatusConflict, err.(*c.Err).Code)
+		}
+	})
+}
+
+func TestNotifyListWatchers(t *testing.T) {
+	t.Run("TestNotifyListWatchersShouldReportPriceDropAndRestock", func(t *testing.T) {
+		// Arrange
+		query := NewBookQueriesMemory()
+		query.watchers = map[uint64][]string{1: {"reader"}}
+		notifier := &ListNotifierStub{}
+
+		// Act
+		notifyListWatchers(context.Background(), query, notifier, logrus.New(),
+			ResponseBook{Id: 1, Price: 1000, Quantity: 0},
+			ResponseBook{Id: 1, Price: 800, Quantity: 5})
+
+		// Assert
+		if assert.Len(t, notifier.events, 2) {
+			assert.Equal(t, ListEventPriceDrop, notifier.events[0].Kind)
+			assert.Equal(t, int64(1000), notifier.events[0].PreviousPrice)
+			assert.Equal(t, []string{"reader"}, notifier.events[0].Usernames)
+			assert.Equal(t, ListEventBackInStock, notifier.events[1].Kind)
+		}
+	})
+
+	t.Run("TestNotifyListWatchersShouldIgnoreOtherChanges", func(t *testing.T) {
+		// Arrange
+		query := NewBookQueriesMemory()
+		query.watchers = map[uint64][]string{1: {"reader"}}
+		notifier := &ListNotifierStub{}
+
+		// Act
+		notifyListWatchers(context.Background(), query, notifier, logrus.New(),
+			ResponseBook{Id: 1, Price: 1000, Quantity: 2},
+			ResponseBook{Id: 1, Price: 1200, Quantity: 1})
+
+		// Assert
+		assert.Empty(t, notifier.events)
+	})
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	c "github.com/paquesqueue/bookstore/common"
+)
+
+const (
+	MIMEApplicationMarcXML = "application/marcxml+xml"
+	MIMEApplicationMarc    = "application/marc"
+)
+
+type MarcHandlrQueries interface {
+	ExportBook(ctx context.Context, id uint64, format string) ([]byte, error)
+	ExportBooks(ctx context.Context, format string, w io.Writer) error
+	ImportBooks(ctx context.Context, format string, r io.Reader) (ResponseFeedIngest, error)
+}
+
+type MarcHandlr struct {
+	handler MarcHandlrQueries
+	log     c.Log
+}
+
+func NewMarcHandlr(h MarcHandlrQueries, l c.Log) MarcHandlr {
+	return MarcHandlr{h, l}
+}
+
+func (h MarcHandlr) ExportBook(ctx echo.Context) error {
+	param := ctx.Param("id")
+	id, err := strconv.Atoi(param)
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+	format, contentType, ok := marcFormat(ctx)
+	if !ok {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	res, err := h.handler.ExportBook(ctx.Request().Context(), uint64(id), format)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error ExportBook Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.Blob(http.StatusOK, contentType, res)
+}
+
+func (h MarcHandlr) ExportBooks(ctx echo.Context) error {
+	format, contentType, ok := marcFormat(ctx)
+	if !ok {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	ctx.Response().Header().Set(echo.HeaderContentType, contentType)
+	ctx.Response().WriteHeader(http.StatusOK)
+
+	// The status line is already sent once streaming starts, so a failure
+	// part way through can only be logged and leaves a truncated export.
+	err := h.handler.ExportBooks(ctx.Request().Context(), format, ctx.Response())
+	if err != nil {
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error ExportBooks Handler : %v", err)
+	}
+	return nil
+}
+
+func (h MarcHandlr) ImportBooks(ctx echo.Context) error {
+	format, _, ok := marcFormat(ctx)
+	if !ok {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	res, err := h.handler.ImportBooks(ctx.Request().Context(), format, ctx.Request().Body)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error ImportBooks Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, res)
+}
+
+// marcFormat reads the format query parameter, defaulting to MARCXML.
+func marcFormat(ctx echo.Context) (string, string, bool) {
+	switch ctx.QueryParam("format") {
+	case "", MarcFormatXML:
+		return MarcFormatXML, MIMEApplicationMarcXML, true
+	case MarcFormatBinary:
+		return MarcFormatBinary, MIMEApplicationMarc, true
+	default:
+		return "", "", false
+	}
+}
@@ -0,0 +1,180 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type MarcHandlrSuccess struct {
+	format string
+}
+
+func (h *MarcHandlrSuccess) ExportBook(ctx context.Context, id uint64, format string) ([]byte, error) {
+	h.format = format
+	return []byte("<collection/>"), nil
+}
+
+func (h *MarcHandlrSuccess) ExportBooks(ctx context.Context, format string, w io.Writer) error {
+	h.format = format
+	_, err := io.WriteString(w, "00000")
+	return err
+}
+
+func (h *MarcHandlrSuccess) ImportBooks(ctx context.Context, format string, r io.Reader) (ResponseFeedIngest, error) {
+	h.format = format
+	return ResponseFeedIngest{Created: 2, Errors: []string{}}, nil
+}
+
+type MarcHandlrError struct {
+	statusCodeError int
+}
+
+func (h *MarcHandlrError) ExportBook(ctx context.Context, id uint64, format string) ([]byte, error) {
+	return nil, &c.Err{Code: h.statusCodeError}
+}
+
+func (h *MarcHandlrError) ExportBooks(ctx context.Context, format string, w io.Writer) error {
+	return &c.Err{Code: h.statusCodeError}
+}
+
+func (h *MarcHandlrError) ImportBooks(ctx context.Context, format string, r io.Reader) (ResponseFeedIngest, error) {
+	return ResponseFeedIngest{}, &c.Err{Code: h.statusCodeError}
+}
+
+func TestExportBookMarcHandler(t *testing.T) {
+	t.Run("TestExportBookMarcHandlerShouldDefaultToMarcXML", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/books/1/marc", nil)
+		rec := httptest.NewRecorder()
+		ctx := echo.New().NewContext(req, rec)
+		ctx.SetPath("/books/:id/marc")
+		ctx.SetParamNames("id")
+		ctx.SetParamValues("1")
+
+		handlrServ := &MarcHandlrSuccess{}
+		handler := NewMarcHandlr(handlrServ, logrus.New())
+
+		// Act
+		err := handler.ExportBook(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, MarcFormatXML, handlrServ.format)
+			assert.Equal(t, MIMEApplicationMarcXML, rec.Header().Get(echo.HeaderContentType))
+		}
+	})
+
+	t.Run("TestExportBookMarcHandlerShouldReturnHTTPStatus400OnUnknownFormat", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/books/1/marc?format=json", nil)
+		rec := httptest.NewRecorder()
+		ctx := echo.New().NewContext(req, rec)
+		ctx.SetParamNames("id")
+		ctx.SetParamValues("1")
+
+		handler := NewMarcHandlr(&MarcHandlrSuccess{}, logrus.New())
+
+		// Act
+		err := handler.ExportBook(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		}
+	})
+
+	t.Run("TestExportBookMarcHandlerShouldReturnHTTPStatus404", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/books/1/marc?format=mrc", nil)
+		rec := httptest.NewRecorder()
+		ctx := echo.New().NewContext(req, rec)
+		ctx.SetParamNames("id")
+		ctx.SetParamValues("1")
+
+		handler := NewMarcHandlr(&MarcHandlrError{statusCodeError: http.StatusNotFound}, logrus.New())
+
+		// Act
+		err := handler.ExportBook(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusNotFound, rec.Code)
+		}
+	})
+}
+
+func TestExportBooksMarcHandler(t *testing.T) {
+	t.Run("TestExportBooksMarcHandlerShouldStreamBinary", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/books/export?format=mrc", nil)
+		rec := httptest.NewRecorder()
+		ctx := echo.New().NewContext(req, rec)
+
+		handlrServ := &MarcHandlrSuccess{}
+		handler := NewMarcHandlr(handlrServ, logrus.New())
+
+		// Act
+		err := handler.ExportBooks(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, MarcFormatBinary, handlrServ.format)
+			assert.Equal(t, MIMEApplicationMarc, rec.Header().Get(echo.HeaderContentType))
+			assert.Equal(t, "00000", rec.Body.String())
+		}
+	})
+}
+
+func TestImportBooksMarcHandler(t *testing.T) {
+	t.Run("TestImportBooksMarcHandlerShouldReturnHTTPStatus200", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPost, "/books/import?format=marcxml", strings.NewReader("<collection/>"))
+		rec := httptest.NewRecorder()
+		ctx := echo.New().NewContext(req, rec)
+
+		handlrServ := &MarcHandlrSuccess{}
+		handler := NewMarcHandlr(handlrServ, logrus.New())
+
+		// Act
+		err := handler.ImportBooks(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+			res := ResponseFeedIngest{}
+			json.Unmarshal(rec.Body.Bytes(), &res)
+			assert.Equal(t, 2, res.Created)
+		}
+	})
+
+	t.Run("TestImportBooksMarcHandlerShouldReturnHTTPStatus400", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPost, "/books/import?format=mrc", strings.NewReader("garbage"))
+		rec := httptest.NewRecorder()
+		ctx := echo.New().NewContext(req, rec)
+
+		handler := NewMarcHandlr(&MarcHandlrError{statusCodeError: http.StatusBadRequest}, logrus.New())
+
+		// Act
+		err := handler.ImportBooks(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		}
+	})
+}
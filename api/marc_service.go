@@ -0,0 +1,298 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/paquesqueue/bookstore/marc"
+	"github.com/paquesqueue/bookstore/tracing"
+)
+
+const (
+	MarcFormatXML    = "marcxml"
+	MarcFormatBinary = "mrc"
+
+	marcDefaultCreator  = "marc-import"
+	marcDefaultCurrency = "THB"
+	marcExportPageSize  = 100
+	marcAuthorRelator   = "author."
+)
+
+type MarcQueries interface {
+	InsertBook(ctx context.Context, req RequestBook) (*ResponseBook, error)
+	SelectAllBooks(ctx context.Context, params GetAllParams) ([]ResponseBook, error)
+	SelectBookByID(ctx context.Context, id uint64) (*ResponseBook, error)
+	SelectBookByIsbn(ctx context.Context, isbn string) (*ResponseBook, error)
+	UpdateBook(ctx context.Context, id uint64, req RequestBook) (*ResponseBook, error)
+}
+
+type MarcServices struct {
+	query MarcQueries
+	log   c.Log
+}
+
+func NewMarcService(q MarcQueries, l c.Log) MarcServices {
+	return MarcServices{q, l}
+}
+
+type marcRecordWriter interface {
+	Write(r marc.Record) error
+}
+
+type marcRecordReader interface {
+	Next() (*marc.Record, error)
+}
+
+// ExportBook returns a single book encoded as a MARC21 record in the given format.
+func (s MarcServices) ExportBook(ctx context.Context, id uint64, format string) ([]byte, error) {
+	ctx, span := tracing.Start(ctx, "MarcServices.ExportBook")
+	defer span.End()
+
+	book, err := s.query.SelectBookByID(ctx, id)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error SelectBookByID : %v", err)
+		switch err {
+		case sql.ErrNoRows:
+			return nil, &c.Err{Code: http.StatusNotFound, Remark: "Error Book Not Found", Original: err}
+		default:
+			return nil, &c.Err{Code: http.StatusInternalServerError, Remark: "Error ExportBook Service", Original: err}
+		}
+	}
+
+	var buf bytes.Buffer
+	err = s.writeRecords(ctx, &buf, format, []ResponseBook{*book})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ExportBooks streams the whole catalog to w as MARC21 records in the given format.
+func (s MarcServices) ExportBooks(ctx context.Context, format string, w io.Writer) error {
+	ctx, span := tracing.Start(ctx, "MarcServices.ExportBooks")
+	defer span.End()
+
+	writer, closer, err := newMarcWriter(format, w)
+	if err != nil {
+		return &c.Err{Code: http.StatusBadRequest, Remark: "Error Unsupported Marc Format", Original: err}
+	}
+
+	params := GetAllParams{Limit: marcExportPageSize, Offset: 0}
+	for {
+		books, err := s.query.SelectAllBooks(ctx, params)
+		if err != nil {
+			c.LogFrom(ctx, s.log).Errorf("Error SelectAllBooks : %v", err)
+			return &c.Err{Code: http.StatusInternalServerError, Remark: "Error ExportBooks Service", Original: err}
+		}
+		for _, book := range books {
+			if err := writer.Write(marcRecordFromBook(book)); err != nil {
+				c.LogFrom(ctx, s.log).Errorf("Error Encode Marc Record : %v", err)
+				return &c.Err{Code: http.StatusInternalServerError, Remark: "Error ExportBooks Service", Original: err}
+			}
+		}
+		if int64(len(books)) < params.Limit {
+			break
+		}
+		params.Offset += params.Limit
+	}
+	return closer()
+}
+
+// ImportBooks reads MARC21 records in the given format and upserts them into
+// the catalog keyed by ISBN. Records that cannot be mapped are rejected and
+// reported; a malformed stream aborts the import.
+func (s MarcServices) ImportBooks(ctx context.Context, format string, r io.Reader) (ResponseFeedIngest, error) {
+	ctx, span := tracing.Start(ctx, "MarcServices.ImportBooks")
+	defer span.End()
+
+	var reader marcRecordReader
+	switch format {
+	case MarcFormatXML:
+		reader = marc.NewXMLReader(r)
+	case MarcFormatBinary:
+		reader = marc.NewReader(r)
+	default:
+		return ResponseFeedIngest{}, &c.Err{Code: http.StatusBadRequest, Remark: "Error Unsupported Marc Format"}
+	}
+
+	result := ResponseFeedIngest{Errors: []string{}}
+	for n := 1; ; n++ {
+		rec, err := reader.Next()
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			c.LogFrom(ctx, s.log).Errorf("Error Decode Marc Record : %v", err)
+			return result, &c.Err{Code: http.StatusBadRequest, Remark: "Error Invalid Marc Record", Original: err}
+		}
+
+		err = s.importRecord(ctx, *rec, &result)
+		if err != nil {
+			c.LogFrom(ctx, s.log).Errorf("Error Import Marc Record %d : %v", n, err)
+			result.Rejected++
+			result.Errors = append(result.Errors, fmt.Sprintf("record %d : %v", n, err))
+		}
+	}
+}
+
+func (s MarcServices) importRecord(ctx context.Context, rec marc.Record, result *ResponseFeedIngest) error {
+	req, err := marcRecordToRequestBook(rec)
+	if err != nil {
+		return err
+	}
+
+	existing, err := s.query.SelectBookByIsbn(ctx, req.Isbn)
+	switch {
+	case err == sql.ErrNoRows:
+		req.Created_by = marcDefaultCreator
+		if _, err = s.query.InsertBook(ctx, req); err != nil {
+			return err
+		}
+		result.Created++
+	case err != nil:
+		return err
+	default:
+		req.Created_by = existing.Created_by
+		if _, err = s.query.UpdateBook(ctx, existing.Id, req); err != nil {
+			return err
+		}
+		result.Updated++
+	}
+	return nil
+}
+
+func (s MarcServices) writeRecords(ctx context.Context, w io.Writer, format string, books []ResponseBook) error {
+	writer, closer, err := newMarcWriter(format, w)
+	if err != nil {
+		return &c.Err{Code: http.StatusBadRequest, Remark: "Error Unsupported Marc Format", Original: err}
+	}
+	for _, book := range books {
+		if err := writer.Write(marcRecordFromBook(book)); err != nil {
+			c.LogFrom(ctx, s.log).Errorf("Error Encode Marc Record : %v", err)
+			return &c.Err{Code: http.StatusInternalServerError, Remark: "Error ExportBook Service", Original: err}
+		}
+	}
+	return closer()
+}
+
+func newMarcWriter(format string, w io.Writer) (marcRecordWriter, func() error, error) {
+	switch format {
+	case MarcFormatXML:
+		xw := marc.NewXMLWriter(w)
+		return xw, xw.Close, nil
+	case MarcFormatBinary:
+		return marc.NewWriter(w), func() error { return nil }, nil
+	default:
+		return nil, nil, fmt.Errorf("error unsupported marc format %q", format)
+	}
+}
+
+// marcRecordFromBook maps a book to a MARC21 bibliographic record:
+// 001 control number, 020 ISBN, 100/700 authors, 245 title, 264 publisher
+// and 365 price.
+func marcRecordFromBook(b ResponseBook) marc.Record {
+	rec := marc.NewRecord()
+	rec.AddControlField("001", strconv.FormatUint(b.Id, 10))
+	if b.Isbn != "" {
+		rec.AddDataField("020", ' ', ' ', marc.Subfield{Code: 'a', Value: b.Isbn})
+	}
+
+	titleInd1 := byte('0')
+	if len(b.Authors) > 0 {
+		titleInd1 = '1'
+		rec.AddDataField("100", '1', ' ',
+			marc.Subfield{Code: 'a', Value: b.Authors[0]},
+			marc.Subfield{Code: 'e', Value: marcAuthorRelator})
+	}
+	rec.AddDataField("245", titleInd1, '0', marc.Subfield{Code: 'a', Value: b.Title})
+	if b.Publisher != "" {
+		rec.AddDataField("264", ' ', '1', marc.Subfield{Code: 'b', Value: b.Publisher})
+	}
+	rec.AddDataField("365", ' ', ' ',
+		marc.Subfield{Code: 'b', Value: strconv.FormatInt(b.Price, 10)},
+		marc.Subfield{Code: 'c', Value: marcDefaultCurrency})
+
+	for i, author := range b.Authors {
+		if i == 0 {
+			continue
+		}
+		rec.AddDataField("700", '1', ' ',
+			marc.Subfield{Code: 'a', Value: author},
+			marc.Subfield{Code: 'e', Value: marcAuthorRelator})
+	}
+	return *rec
+}
+
+func marcRecordToRequestBook(rec marc.Record) (RequestBook, error) {
+	req := RequestBook{Authors: []string{}}
+
+	for _, f := range rec.FieldsByTag("020") {
+		if isbn := marcIsbn(f.Subfield('a')); isbn != "" {
+			req.Isbn = isbn
+			break
+		}
+	}
+	if req.Isbn == "" {
+		return RequestBook{}, fmt.Errorf("error isbn not found")
+	}
+
+	for _, f := range rec.FieldsByTag("245") {
+		req.Title = marcTrimPunctuation(strings.TrimSpace(f.Subfield('a') + " " + f.Subfield('b')))
+		break
+	}
+	if req.Title == "" {
+		return RequestBook{}, fmt.Errorf("error title not found")
+	}
+
+	for _, tag := range []string{"100", "700"} {
+		for _, f := range rec.FieldsByTag(tag) {
+			if name := marcTrimPunctuation(f.Subfield('a')); name != "" {
+				req.Authors = append(req.Authors, name)
+			}
+		}
+	}
+
+	for _, tag := range []string{"264", "260"} {
+		if fields := rec.FieldsByTag(tag); len(fields) > 0 && req.Publisher == "" {
+			req.Publisher = marcTrimPunctuation(fields[0].Subfield('b'))
+		}
+	}
+
+	for _, f := range rec.FieldsByTag("365") {
+		amount := f.Subfield('b')
+		if amount == "" {
+			continue
+		}
+		price, err := strconv.ParseFloat(amount, 64)
+		if err != nil {
+			return RequestBook{}, fmt.Errorf("error invalid price amount %q", amount)
+		}
+		req.Price = int64(math.Round(price))
+		break
+	}
+	return req, nil
+}
+
+// marcIsbn returns the ISBN from a 020 $a value, which may carry a qualifier
+// such as "9780000000017 (paperback)".
+func marcIsbn(value string) string {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ReplaceAll(fields[0], "-", "")
+}
+
+// marcTrimPunctuation strips the ISBD punctuation cataloguers leave at the end
+// of subfields, e.g. "Sample Press," or "The Go Bookstore /".
+func marcTrimPunctuation(value string) string {
+	return strings.TrimSpace(strings.TrimRight(strings.TrimSpace(value), " /:;,."))
+}
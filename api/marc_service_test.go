@@ -0,0 +1,215 @@
+//go:build unit
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/paquesqueue/bookstore/marc"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func goldenMarcBook(query *BookQueriesMemory) {
+	query.nextId = 41
+	query.InsertBook(context.Background(), RequestBook{
+		Title:      "The Go Bookstore",
+		Authors:    []string{"John Author", "Jane Writer", "สมชาย ใจดี"},
+		Publisher:  "Sample Press",
+		Isbn:       "9780000000017",
+		Price:      450,
+		Quantity:   25,
+		Created_by: "Admin",
+	})
+}
+
+func TestExportBookMarc(t *testing.T) {
+	t.Run("TestExportBookMarcXMLShouldMatchGolden", func(t *testing.T) {
+		// Arrange
+		query := NewBookQueriesMemory()
+		goldenMarcBook(query)
+		services := NewMarcService(query, logrus.New())
+		golden, err := os.ReadFile("../marc/testdata/record.xml")
+		assert.NoError(t, err)
+
+		// Act
+		res, err := services.ExportBook(context.Background(), 42, MarcFormatXML)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, string(golden), string(res))
+	})
+
+	t.Run("TestExportBookMarcBinaryShouldMatchGolden", func(t *testing.T) {
+		// Arrange
+		query := NewBookQueriesMemory()
+		goldenMarcBook(query)
+		services := NewMarcService(query, logrus.New())
+		golden, err := os.ReadFile("../marc/testdata/record.mrc")
+		assert.NoError(t, err)
+
+		// Act
+		res, err := services.ExportBook(context.Background(), 42, MarcFormatBinary)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, golden, res)
+	})
+
+	t.Run("TestExportBookMarcShouldReturnNotFound", func(t *testing.T) {
+		// Arrange
+		services := NewMarcService(NewBookQueriesMemory(), logrus.New())
+
+		// Act
+		res, err := services.ExportBook(context.Background(), 1, MarcFormatXML)
+
+		// Assert
+		assert.Nil(t, res)
+		if assert.Error(t, err) {
+			assert.Equal(t, http.StatusNotFound, err.(*c.Err).Code)
+		}
+	})
+}
+
+func TestExportBooksMarc(t *testing.T) {
+	t.Run("TestExportBooksShouldWriteEveryBook", func(t *testing.T) {
+		// Arrange
+		query := NewBookQueriesMemory()
+		for i := 0; i < marcExportPageSize+5; i++ {
+			query.InsertBook(context.Background(), RequestBook{Title: "mockTitle", Isbn: "mockIsbn"})
+		}
+		services := NewMarcService(query, logrus.New())
+
+		// Act
+		var buf bytes.Buffer
+		err := services.ExportBooks(context.Background(), MarcFormatBinary, &buf)
+
+		// Assert
+		assert.NoError(t, err)
+		reader := marc.NewReader(&buf)
+		n := 0
+		for {
+			if _, err := reader.Next(); err != nil {
+				break
+			}
+			n++
+		}
+		assert.Equal(t, marcExportPageSize+5, n)
+	})
+
+	t.Run("TestExportBooksShouldRejectUnknownFormat", func(t *testing.T) {
+		// Arrange
+		services := NewMarcService(NewBookQueriesMemory(), logrus.New())
+
+		// Act
+		err := services.ExportBooks(context.Background(), "json", &bytes.Buffer{})
+
+		// Assert
+		if assert.Error(t, err) {
+			assert.Equal(t, http.StatusBadRequest, err.(*c.Err).Code)
+		}
+	})
+}
+
+func TestImportBooksMarc(t *testing.T) {
+	t.Run("TestImportBinaryGoldenShouldCreateBook", func(t *testing.T) {
+		// Arrange
+		query := NewBookQueriesMemory()
+		services := NewMarcService(query, logrus.New())
+		file, err := os.Open("../marc/testdata/record.mrc")
+		assert.NoError(t, err)
+		defer file.Close()
+
+		// Act
+		res, err := services.ImportBooks(context.Background(), MarcFormatBinary, file)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, 1, res.Created)
+		book := query.books[1]
+		assert.Equal(t, "The Go Bookstore", book.Title)
+		assert.Equal(t, []string{"John Author", "Jane Writer", "สมชาย ใจดี"}, book.Authors)
+		assert.Equal(t, "Sample Press", book.Publisher)
+		assert.Equal(t, "9780000000017", book.Isbn)
+		assert.Equal(t, int64(450), book.Price)
+		assert.Equal(t, marcDefaultCreator, book.Created_by)
+		assert.Equal(t, []uint64{1}, query.linkedIds)
+	})
+
+	t.Run("TestImportXMLGoldenShouldUpdateExistingBook", func(t *testing.T) {
+		// Arrange
+		query := NewBookQueriesMemory()
+		query.InsertBook(context.Background(), RequestBook{Title: "Old Title", Isbn: "9780000000017", Quantity: 7, Created_by: "Admin"})
+		services := NewMarcService(query, logrus.New())
+		file, err := os.Open("../marc/testdata/record.xml")
+		assert.NoError(t, err)
+		defer file.Close()
+
+		// Act
+		res, err := services.ImportBooks(context.Background(), MarcFormatXML, file)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, 1, res.Updated)
+		assert.Equal(t, "The Go Bookstore", query.books[1].Title)
+		assert.Equal(t, "Admin", query.books[1].Created_by)
+	})
+
+	t.Run("TestImportShouldStripIsbdPunctuation", func(t *testing.T) {
+		// Arrange
+		query := NewBookQueriesMemory()
+		services := NewMarcService(query, logrus.New())
+		doc := `<collection xmlns="http://www.loc.gov/MARC21/slim"><record><leader>00000nam a2200000 i 4500</leader>` +
+			`<datafield tag="020" ind1=" " ind2=" "><subfield code="a">978-0-00-000001-7 (paperback)</subfield></datafield>` +
+			`<datafield tag="100" ind1="1" ind2=" "><subfield code="a">Author, John,</subfield></datafield>` +
+			`<datafield tag="245" ind1="1" ind2="0"><subfield code="a">The Go Bookstore /</subfield></datafield>` +
+			`<datafield tag="260" ind1=" " ind2=" "><subfield code="b">Sample Press,</subfield></datafield>` +
+			`</record></collection>`
+
+		// Act
+		res, err := services.ImportBooks(context.Background(), MarcFormatXML, strings.NewReader(doc))
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, 1, res.Created)
+		book := query.books[1]
+		assert.Equal(t, "9780000000017", book.Isbn)
+		assert.Equal(t, "The Go Bookstore", book.Title)
+		assert.Equal(t, []string{"Author, John"}, book.Authors)
+		assert.Equal(t, "Sample Press", book.Publisher)
+	})
+
+	t.Run("TestImportShouldRejectRecordWithoutIsbn", func(t *testing.T) {
+		// Arrange
+		query := NewBookQueriesMemory()
+		services := NewMarcService(query, logrus.New())
+		doc := `<record><datafield tag="245" ind1="0" ind2="0"><subfield code="a">Title</subfield></datafield></record>`
+
+		// Act
+		res, err := services.ImportBooks(context.Background(), MarcFormatXML, strings.NewReader(doc))
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, 1, res.Rejected)
+		assert.Len(t, query.books, 0)
+	})
+
+	t.Run("TestImportShouldReturnBadRequestOnMalformedRecord", func(t *testing.T) {
+		// Arrange
+		services := NewMarcService(NewBookQueriesMemory(), logrus.New())
+
+		// Act
+		_, err := services.ImportBooks(context.Background(), MarcFormatBinary, strings.NewReader("00010garbage"))
+
+		// Assert
+		if assert.Error(t, err) {
+			assert.Equal(t, http.StatusBadRequest, err.(*c.Err).Code)
+		}
+	})
+}
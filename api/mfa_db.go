@@ -0,0 +1,194 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+)
+
+// SelectMFA returns a user's two-factor settings.
+func (db Query) SelectMFA(ctx context.Context, username string) (UserMFA, error) {
+	const query = `SELECT role, COALESCE(mfa_secret, ''), mfa_enabled_at, COALESCE(mfa_last_step, 0),
+		(SELECT COUNT(*) FROM mfa_recovery_codes r WHERE r.username = users.username AND r.used_at IS NULL)
+	FROM users WHERE username = $1;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return UserMFA{}, err
+	}
+	defer stmt.Close()
+
+	mfa := UserMFA{}
+	var enabledAt sql.NullTime
+	err = stmt.QueryRowContext(ctx, username).Scan(&mfa.Role, &mfa.Secret, &enabledAt, &mfa.LastStep, &mfa.RecoveryCodesLeft)
+	if err != nil {
+		return UserMFA{}, err
+	}
+	mfa.EnabledAt = nullTime(enabledAt)
+	return mfa, nil
+}
+
+// SetMFASecret stores the secret of a user starting two-factor enrolment,
+// replacing one from an earlier unfinished attempt. It returns
+// sql.ErrNoRows when two-factor authentication is already on.
+func (db Query) SetMFASecret(ctx context.Context, username string, secret string) error {
+	const query = `UPDATE users SET mfa_secret = $2, mfa_last_step = NULL WHERE username = $1 AND mfa_enabled_at IS NULL;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, username, secret)
+	if err != nil {
+		return err
+	}
+	return expectRows(res)
+}
+
+// EnableMFA turns two-factor authentication on with the recovery codes,
+// taking step as the last code used. Sessions other than the one with
+// keepSessionHash are removed, as they were opened with the password alone.
+func (db Query) EnableMFA(ctx context.Context, username string, step int64, codeHashes []string, keepSessionHash string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`UPDATE users SET mfa_enabled_at = NOW(), mfa_last_step = $2
+	WHERE username = $1 AND mfa_enabled_at IS NULL AND mfa_secret IS NOT NULL;`, username, step)
+	if err != nil {
+		return err
+	}
+	if err := expectRows(res); err != nil {
+		return err
+	}
+	if err := insertRecoveryCodes(tx, username, codeHashes); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`DELETE FROM sessions WHERE username = $1 AND token_hash <> $2;`, username, keepSessionHash)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ReplaceRecoveryCodes swaps a user's recovery codes for new ones.
+func (db Query) ReplaceRecoveryCodes(ctx context.Context, username string, codeHashes []string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertRecoveryCodes(tx, username, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertRecoveryCodes(tx *sql.Tx, username string, codeHashes []string) error {
+	_, err := tx.Exec(`DELETE FROM mfa_recovery_codes WHERE username = $1;`, username)
+	if err != nil {
+		return err
+	}
+	for _, hash := range codeHashes {
+		_, err = tx.Exec(`INSERT INTO mfa_recovery_codes (username, code_hash) VALUES ($1, $2);`, username, hash)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DisableMFA turns two-factor authentication off and drops the secret and
+// recovery codes.
+func (db Query) DisableMFA(ctx context.Context, username string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`UPDATE users SET mfa_secret = NULL, mfa_enabled_at = NULL, mfa_last_step = NULL
+	WHERE username = $1 AND mfa_secret IS NOT NULL;`, username)
+	if err != nil {
+		return err
+	}
+	if err := expectRows(res); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`DELETE FROM mfa_recovery_codes WHERE username = $1;`, username)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// AdvanceMFAStep records step as the last code used. It returns
+// sql.ErrNoRows for a step at or before the last one, so a code is only
+// accepted once.
+func (db Query) AdvanceMFAStep(ctx context.Context, username string, step int64) error {
+	const query = `UPDATE users SET mfa_last_step = $2
+	WHERE username = $1 AND (mfa_last_step IS NULL OR mfa_last_step < $2);`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, username, step)
+	if err != nil {
+		return err
+	}
+	return expectRows(res)
+}
+
+// UseRecoveryCode marks an unused recovery code as used. It returns
+// sql.ErrNoRows for any other code.
+func (db Query) UseRecoveryCode(ctx context.Context, username string, codeHash string) error {
+	const query = `UPDATE mfa_recovery_codes SET used_at = NOW()
+	WHERE username = $1 AND code_hash = $2 AND used_at IS NULL;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, username, codeHash)
+	if err != nil {
+		return err
+	}
+	return expectRows(res)
+}
+
+// UseMFAChallenge marks an unused, unexpired login challenge as used and
+// returns whose it is. It returns sql.ErrNoRows for any other token.
+func (db Query) UseMFAChallenge(ctx context.Context, tokenHash string) (string, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	username, _, err := useUserToken(tx, tokenHash, tokenPurposeMFALogin)
+	if err != nil {
+		return "", err
+	}
+	return username, tx.Commit()
+}
+
+// expectRows returns sql.ErrNoRows when res changed nothing.
+func expectRows(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
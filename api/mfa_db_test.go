@@ -0,0 +1,193 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectMFA(t *testing.T) {
+	t.Run("TestSelectMFAShouldCountUnusedRecoveryCodes", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		enabledAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		mock.ExpectPrepare(regexp.QuoteMeta(`SELECT role, COALESCE(mfa_secret, ''), mfa_enabled_at, COALESCE(mfa_last_step, 0),`)).
+			ExpectQuery().
+			WithArgs("tester").
+			WillReturnRows(sqlmock.NewRows([]string{"role", "mfa_secret", "mfa_enabled_at", "mfa_last_step", "count"}).
+				AddRow(UserRoleStaff, "SECRET", enabledAt, 42, 9))
+
+		query := NewDB(db)
+
+		// Act
+		mfa, err := query.SelectMFA(context.Background(), "tester")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, UserMFA{Role: UserRoleStaff, Secret: "SECRET", EnabledAt: &enabledAt, LastStep: 42, RecoveryCodesLeft: 9}, mfa)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestSetMFASecret(t *testing.T) {
+	t.Run("TestSetMFASecretShouldReturnErrNoRowsWhenEnabled", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectPrepare(regexp.QuoteMeta(`UPDATE users SET mfa_secret = $2, mfa_last_step = NULL WHERE username = $1 AND mfa_enabled_at IS NULL;`)).
+			ExpectExec().
+			WithArgs("tester", "SECRET").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		query := NewDB(db)
+
+		// Act
+		err = query.SetMFASecret(context.Background(), "tester", "SECRET")
+
+		// Assert
+		assert.Equal(t, sql.ErrNoRows, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestEnableMFA(t *testing.T) {
+	t.Run("TestEnableMFAShouldStoreCodesAndEndOtherSessions", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET mfa_enabled_at = NOW(), mfa_last_step = $2`)).
+			WithArgs("tester", int64(42)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM mfa_recovery_codes WHERE username = $1;`)).
+			WithArgs("tester").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		for _, hash := range []string{"hash-1", "hash-2"} {
+			mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO mfa_recovery_codes (username, code_hash) VALUES ($1, $2);`)).
+				WithArgs("tester", hash).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM sessions WHERE username = $1 AND token_hash <> $2;`)).
+			WithArgs("tester", "session-hash").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		query := NewDB(db)
+
+		// Act
+		err = query.EnableMFA(context.Background(), "tester", 42, []string{"hash-1", "hash-2"}, "session-hash")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestEnableMFAShouldRollBackWhenAlreadyEnabled", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET mfa_enabled_at = NOW(), mfa_last_step = $2`)).
+			WithArgs("tester", int64(42)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		query := NewDB(db)
+
+		// Act
+		err = query.EnableMFA(context.Background(), "tester", 42, []string{"hash-1"}, "session-hash")
+
+		// Assert
+		assert.Equal(t, sql.ErrNoRows, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDisableMFAQuery(t *testing.T) {
+	t.Run("TestDisableMFAQueryShouldDropSecretAndCodes", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET mfa_secret = NULL, mfa_enabled_at = NULL, mfa_last_step = NULL`)).
+			WithArgs("tester").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM mfa_recovery_codes WHERE username = $1;`)).
+			WithArgs("tester").
+			WillReturnResult(sqlmock.NewResult(0, 10))
+		mock.ExpectCommit()
+
+		query := NewDB(db)
+
+		// Act
+		err = query.DisableMFA(context.Background(), "tester")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestAdvanceMFAStep(t *testing.T) {
+	t.Run("TestAdvanceMFAStepShouldReturnErrNoRowsOnReplay", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectPrepare(regexp.QuoteMeta(`UPDATE users SET mfa_last_step = $2`)).
+			ExpectExec().
+			WithArgs("tester", int64(42)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		query := NewDB(db)
+
+		// Act
+		err = query.AdvanceMFAStep(context.Background(), "tester", 42)
+
+		// Assert
+		assert.Equal(t, sql.ErrNoRows, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUseRecoveryCode(t *testing.T) {
+	t.Run("TestUseRecoveryCodeShouldMarkCodeUsed", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectPrepare(regexp.QuoteMeta(`UPDATE mfa_recovery_codes SET used_at = NOW()`)).
+			ExpectExec().
+			WithArgs("tester", "hash-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		query := NewDB(db)
+
+		// Act
+		err = query.UseRecoveryCode(context.Background(), "tester", "hash-1")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	c "github.com/paquesqueue/bookstore/common"
+)
+
+type MFAHandlrQueries interface {
+	GetMFA(ctx context.Context, username string) (ResponseMFA, error)
+	StartMFA(ctx context.Context, username string) (ResponseMFASetup, error)
+	ConfirmMFA(ctx context.Context, username string, req RequestMFACode, sessionToken string) (ResponseRecoveryCodes, error)
+	ReplaceRecoveryCodes(ctx context.Context, username string, req RequestMFACode) (ResponseRecoveryCodes, error)
+	DisableMFA(ctx context.Context, username string, req RequestMFACode, by string) error
+}
+
+type MFAHandlr struct {
+	handler MFAHandlrQueries
+	log     c.Log
+}
+
+func NewMFAHandlr(h MFAHandlrQueries, l c.Log) MFAHandlr {
+	return MFAHandlr{h, l}
+}
+
+func (h MFAHandlr) GetMFA(ctx echo.Context) error {
+	username, _, ok := accountManager(ctx)
+	if !ok {
+		return ctx.NoContent(http.StatusForbidden)
+	}
+
+	res, err := h.handler.GetMFA(ctx.Request().Context(), username)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error GetMFA Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, res)
+}
+
+// StartMFA returns a new secret to add to an authenticator app.
+func (h MFAHandlr) StartMFA(ctx echo.Context) error {
+	username, ok := sessionOwner(ctx)
+	if !ok {
+		return ctx.NoContent(http.StatusForbidden)
+	}
+
+	res, err := h.handler.StartMFA(ctx.Request().Context(), username)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error StartMFA Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusCreated, res)
+}
+
+// ConfirmMFA turns two-factor authentication on with a first code and
+// returns the recovery codes.
+func (h MFAHandlr) ConfirmMFA(ctx echo.Context) error {
+	username, ok := sessionOwner(ctx)
+	if !ok {
+		return ctx.NoContent(http.StatusForbidden)
+	}
+	req := RequestMFACode{}
+	err := ctx.Bind(&req)
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+	req.IP = ctx.RealIP()
+
+	res, err := h.handler.ConfirmMFA(ctx.Request().Context(), username, req, ctx.Request().Header.Get(SessionHeader))
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error ConfirmMFA Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h MFAHandlr) ReplaceRecoveryCodes(ctx echo.Context) error {
+	username, ok := sessionOwner(ctx)
+	if !ok {
+		return ctx.NoContent(http.StatusForbidden)
+	}
+	req := RequestMFACode{}
+	err := ctx.Bind(&req)
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+	req.IP = ctx.RealIP()
+
+	res, err := h.handler.ReplaceRecoveryCodes(ctx.Request().Context(), username, req)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error ReplaceRecoveryCodes Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h MFAHandlr) DisableMFA(ctx echo.Context) error {
+	username, by, ok := accountManager(ctx)
+	if !ok {
+		return ctx.NoContent(http.StatusForbidden)
+	}
+	req := RequestMFACode{}
+	err := ctx.Bind(&req)
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+	req.IP = ctx.RealIP()
+
+	err = h.handler.DisableMFA(ctx.Request().Context(), username, req, by)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error DisableMFA Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, "Disabled Successfully")
+}
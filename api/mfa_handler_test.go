@@ -0,0 +1,217 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type MFAHandlrStub struct {
+	username     string
+	by           string
+	req          RequestMFACode
+	sessionToken string
+	err          error
+}
+
+func (h *MFAHandlrStub) GetMFA(ctx context.Context, username string) (ResponseMFA, error) {
+	h.username = username
+	return ResponseMFA{Required: true}, h.err
+}
+
+func (h *MFAHandlrStub) StartMFA(ctx context.Context, username string) (ResponseMFASetup, error) {
+	h.username = username
+	return ResponseMFASetup{Secret: "SECRET", URI: "otpauth://totp/Bookstore:" + username}, h.err
+}
+
+func (h *MFAHandlrStub) ConfirmMFA(ctx context.Context, username string, req RequestMFACode, sessionToken string) (ResponseRecoveryCodes, error) {
+	h.username, h.req, h.sessionToken = username, req, sessionToken
+	return ResponseRecoveryCodes{RecoveryCodes: []string{"abcde-fghjk"}}, h.err
+}
+
+func (h *MFAHandlrStub) ReplaceRecoveryCodes(ctx context.Context, username string, req RequestMFACode) (ResponseRecoveryCodes, error) {
+	h.username, h.req = username, req
+	return ResponseRecoveryCodes{RecoveryCodes: []string{"abcde-fghjk"}}, h.err
+}
+
+func (h *MFAHandlrStub) DisableMFA(ctx context.Context, username string, req RequestMFACode, by string) error {
+	h.username, h.req, h.by = username, req, by
+	return h.err
+}
+
+func newMFAContext(method string, body string, user *ResponseUser) (echo.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(method, "/users/reader/mfa", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(SessionHeader, "session-token")
+	rec := httptest.NewRecorder()
+	ctx := echo.New().NewContext(req, rec)
+	ctx.SetParamNames("username")
+	ctx.SetParamValues("reader")
+	if user != nil {
+		ctx.Set(ctxUserKey, *user)
+	}
+	return ctx, rec
+}
+
+func TestStartMFAHandler(t *testing.T) {
+	t.Run("TestStartMFAHandlerShouldReturnHTTPStatus201WithSecret", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newMFAContext(http.MethodPost, "", &ResponseUser{Username: "reader"})
+		stub := &MFAHandlrStub{}
+		handler := NewMFAHandlr(stub, logrus.New())
+
+		// Act
+		err := handler.StartMFA(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, "reader", stub.username)
+
+		res := ResponseMFASetup{}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		assert.Equal(t, "SECRET", res.Secret)
+	})
+
+	t.Run("TestStartMFAHandlerShouldReturnHTTPStatus403ForAdmin", func(t *testing.T) {
+		// Arrange
+		enabledAt := time.Now()
+		ctx, rec := newMFAContext(http.MethodPost, "", &ResponseUser{Username: "admin", Role: UserRoleAdmin, MFAEnabledAt: &enabledAt})
+		stub := &MFAHandlrStub{}
+		handler := NewMFAHandlr(stub, logrus.New())
+
+		// Act
+		err := handler.StartMFA(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Empty(t, stub.username)
+	})
+}
+
+func TestConfirmMFAHandler(t *testing.T) {
+	t.Run("TestConfirmMFAHandlerShouldPassSessionToken", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newMFAContext(http.MethodPost, `{"code":"123456"}`, &ResponseUser{Username: "reader"})
+		stub := &MFAHandlrStub{}
+		handler := NewMFAHandlr(stub, logrus.New())
+
+		// Act
+		err := handler.ConfirmMFA(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "123456", stub.req.Code)
+		assert.Equal(t, "session-token", stub.sessionToken)
+		assert.Equal(t, "192.0.2.1", stub.req.IP)
+	})
+
+	t.Run("TestConfirmMFAHandlerShouldReturnServiceStatus", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newMFAContext(http.MethodPost, `{"code":"000000"}`, &ResponseUser{Username: "reader"})
+		stub := &MFAHandlrStub{err: &c.Err{Code: http.StatusBadRequest}}
+		handler := NewMFAHandlr(stub, logrus.New())
+
+		// Act
+		err := handler.ConfirmMFA(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestDisableMFAHandler(t *testing.T) {
+	enabledAt := time.Now()
+
+	t.Run("TestDisableMFAHandlerShouldLetOwnerDisable", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newMFAContext(http.MethodDelete, `{"code":"123456"}`, &ResponseUser{Username: "reader"})
+		stub := &MFAHandlrStub{}
+		handler := NewMFAHandlr(stub, logrus.New())
+
+		// Act
+		err := handler.DisableMFA(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "reader", stub.username)
+		assert.Equal(t, "reader", stub.by)
+	})
+
+	t.Run("TestDisableMFAHandlerShouldLetAdminWithMFADisable", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newMFAContext(http.MethodDelete, "", &ResponseUser{Username: "admin", Role: UserRoleAdmin, MFAEnabledAt: &enabledAt})
+		stub := &MFAHandlrStub{}
+		handler := NewMFAHandlr(stub, logrus.New())
+
+		// Act
+		err := handler.DisableMFA(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "reader", stub.username)
+		assert.Equal(t, "admin", stub.by)
+	})
+
+	t.Run("TestDisableMFAHandlerShouldReturnHTTPStatus403ForAdminWithoutMFA", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newMFAContext(http.MethodDelete, "", &ResponseUser{Username: "admin", Role: UserRoleAdmin})
+		stub := &MFAHandlrStub{}
+		handler := NewMFAHandlr(stub, logrus.New())
+
+		// Act
+		err := handler.DisableMFA(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Empty(t, stub.username)
+	})
+
+	t.Run("TestDisableMFAHandlerShouldReturnHTTPStatus403ForAdminAPIKey", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newMFAContext(http.MethodDelete, "", &ResponseUser{Username: "admin", Role: UserRoleAdmin, MFAEnabledAt: &enabledAt})
+		ctx.Set(ctxAPIKeyKey, ResponseAPIKey{Id: 1, Username: "admin", Scopes: []string{"users:write"}})
+		stub := &MFAHandlrStub{}
+		handler := NewMFAHandlr(stub, logrus.New())
+
+		// Act
+		err := handler.DisableMFA(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Empty(t, stub.username)
+	})
+
+	t.Run("TestDisableMFAHandlerShouldReturnHTTPStatus403ForOtherUser", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newMFAContext(http.MethodDelete, "", &ResponseUser{Username: "intruder"})
+		stub := &MFAHandlrStub{}
+		handler := NewMFAHandlr(stub, logrus.New())
+
+		// Act
+		err := handler.DisableMFA(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Empty(t, stub.username)
+	})
+}
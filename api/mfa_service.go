@@ -0,0 +1,312 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/paquesqueue/bookstore/totp"
+	"github.com/paquesqueue/bookstore/tracing"
+	"github.com/paquesqueue/bookstore/utils"
+)
+
+const (
+	// recoveryCodeChars leaves out characters easily misread for others.
+	recoveryCodeChars = "abcdefghjkmnpqrstuvwxyz23456789"
+	recoveryCodeLen   = 10
+)
+
+// UserMFA is a user's two-factor authentication. Secret is set once
+// enrolment starts; EnabledAt once it is confirmed with a code.
+type UserMFA struct {
+	Role              string
+	Secret            string
+	EnabledAt         *time.Time
+	LastStep          int64
+	RecoveryCodesLeft int
+}
+
+// secondFactorQueries are what checkSecondFactor needs to use up a code.
+type secondFactorQueries interface {
+	AdvanceMFAStep(ctx context.Context, username string, step int64) error
+	UseRecoveryCode(ctx context.Context, username string, codeHash string) error
+}
+
+type MFAQueries interface {
+	secondFactorQueries
+	loginLockQueries
+	SelectMFA(ctx context.Context, username string) (UserMFA, error)
+	SetMFASecret(ctx context.Context, username string, secret string) error
+	EnableMFA(ctx context.Context, username string, step int64, codeHashes []string, keepSessionHash string) error
+	ReplaceRecoveryCodes(ctx context.Context, username string, codeHashes []string) error
+	DisableMFA(ctx context.Context, username string) error
+}
+
+type MFAServices struct {
+	query    MFAQueries
+	conf     c.AuthConfig
+	security c.Log
+	log      c.Log
+	now      func() time.Time
+}
+
+func NewMFAService(q MFAQueries, conf c.AuthConfig, security c.Log, l c.Log) MFAServices {
+	return MFAServices{q, conf, security, l, time.Now}
+}
+
+func (s MFAServices) lockout() loginLockout {
+	return loginLockout{s.query, s.conf, s.security, s.log, s.now}
+}
+
+func (s MFAServices) GetMFA(ctx context.Context, username string) (ResponseMFA, error) {
+	ctx, span := tracing.Start(ctx, "MFAServices.GetMFA")
+	defer span.End()
+
+	mfa, err := s.selectMFA(ctx, username, "Error GetMFA Service")
+	if err != nil {
+		return ResponseMFA{}, err
+	}
+	return ResponseMFA{
+		Enabled:           mfa.EnabledAt != nil,
+		EnabledAt:         mfa.EnabledAt,
+		Required:          mfaRequired(s.conf, mfa.Role),
+		RecoveryCodesLeft: mfa.RecoveryCodesLeft,
+	}, nil
+}
+
+// StartMFA makes a new secret for the user to add to their authenticator.
+// Two-factor authentication stays off until ConfirmMFA.
+func (s MFAServices) StartMFA(ctx context.Context, username string) (ResponseMFASetup, error) {
+	ctx, span := tracing.Start(ctx, "MFAServices.StartMFA")
+	defer span.End()
+
+	secret, err := totp.NewSecret()
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error StartMFA New Secret : %v", err)
+		return ResponseMFASetup{}, &c.Err{Code: http.StatusInternalServerError, Remark: "Error StartMFA Service", Original: err}
+	}
+	if err := s.query.SetMFASecret(ctx, username, secret); err != nil {
+		if err == sql.ErrNoRows {
+			return ResponseMFASetup{}, &c.Err{Code: http.StatusConflict, Remark: "Error MFA Already Enabled", Original: err}
+		}
+		c.LogFrom(ctx, s.log).Errorf("Error SetMFASecret : %v", err)
+		return ResponseMFASetup{}, &c.Err{Code: http.StatusInternalServerError, Remark: "Error StartMFA Service", Original: err}
+	}
+	return ResponseMFASetup{Secret: secret, URI: totp.URI(s.conf.MFAIssuer, username, secret)}, nil
+}
+
+// ConfirmMFA turns two-factor authentication on once the user shows a code
+// from the secret StartMFA made, and returns their recovery codes. The
+// user's other sessions end; sessionToken is the one to keep.
+func (s MFAServices) ConfirmMFA(ctx context.Context, username string, req RequestMFACode, sessionToken string) (ResponseRecoveryCodes, error) {
+	ctx, span := tracing.Start(ctx, "MFAServices.ConfirmMFA")
+	defer span.End()
+
+	mfa, err := s.selectMFA(ctx, username, "Error ConfirmMFA Service")
+	if err != nil {
+		return ResponseRecoveryCodes{}, err
+	}
+	if mfa.EnabledAt != nil {
+		return ResponseRecoveryCodes{}, &c.Err{Code: http.StatusConflict, Remark: "Error MFA Already Enabled"}
+	}
+	if mfa.Secret == "" {
+		return ResponseRecoveryCodes{}, &c.Err{Code: http.StatusConflict, Remark: "Error MFA Not Started"}
+	}
+	login := RequestLogin{Username: username, IP: req.IP}
+	if err := s.lockout().checkLoginLocks(ctx, login, "Error ConfirmMFA Service"); err != nil {
+		return ResponseRecoveryCodes{}, err
+	}
+	step, ok, err := totp.Verify(mfa.Secret, req.Code, s.now(), s.conf.MFASkew)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error ConfirmMFA Verify : %v", err)
+		return ResponseRecoveryCodes{}, &c.Err{Code: http.StatusInternalServerError, Remark: "Error ConfirmMFA Service", Original: err}
+	}
+	if !ok {
+		s.lockout().loginFailed(ctx, eventMFAFailed, login)
+		return ResponseRecoveryCodes{}, &c.Err{Code: http.StatusBadRequest, Remark: "Error Invalid Code"}
+	}
+
+	codes, hashes, err := newRecoveryCodes(s.conf.MFARecoveryCodes)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error ConfirmMFA Recovery Codes : %v", err)
+		return ResponseRecoveryCodes{}, &c.Err{Code: http.StatusInternalServerError, Remark: "Error ConfirmMFA Service", Original: err}
+	}
+	keep := ""
+	if sessionToken != "" {
+		keep = utils.HashToken(sessionToken)
+	}
+	if err := s.query.EnableMFA(ctx, username, step, hashes, keep); err != nil {
+		if err == sql.ErrNoRows {
+			return ResponseRecoveryCodes{}, &c.Err{Code: http.StatusConflict, Remark: "Error MFA Already Enabled", Original: err}
+		}
+		c.LogFrom(ctx, s.log).Errorf("Error EnableMFA : %v", err)
+		return ResponseRecoveryCodes{}, &c.Err{Code: http.StatusInternalServerError, Remark: "Error ConfirmMFA Service", Original: err}
+	}
+	securityEvent(ctx, s.security, eventMFAEnabled, username, "", nil)
+	return ResponseRecoveryCodes{RecoveryCodes: codes}, nil
+}
+
+// ReplaceRecoveryCodes gives the user new recovery codes in place of their
+// old ones, on a code from their authenticator or an unused recovery code.
+func (s MFAServices) ReplaceRecoveryCodes(ctx context.Context, username string, req RequestMFACode) (ResponseRecoveryCodes, error) {
+	ctx, span := tracing.Start(ctx, "MFAServices.ReplaceRecoveryCodes")
+	defer span.End()
+
+	if err := s.checkEnabled(ctx, username, req, "Error ReplaceRecoveryCodes Service"); err != nil {
+		return ResponseRecoveryCodes{}, err
+	}
+	codes, hashes, err := newRecoveryCodes(s.conf.MFARecoveryCodes)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error ReplaceRecoveryCodes New Codes : %v", err)
+		return ResponseRecoveryCodes{}, &c.Err{Code: http.StatusInternalServerError, Remark: "Error ReplaceRecoveryCodes Service", Original: err}
+	}
+	if err := s.query.ReplaceRecoveryCodes(ctx, username, hashes); err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error ReplaceRecoveryCodes : %v", err)
+		return ResponseRecoveryCodes{}, &c.Err{Code: http.StatusInternalServerError, Remark: "Error ReplaceRecoveryCodes Service", Original: err}
+	}
+	securityEvent(ctx, s.security, eventRecoveryCodes, username, "", nil)
+	return ResponseRecoveryCodes{RecoveryCodes: codes}, nil
+}
+
+// DisableMFA turns two-factor authentication off. Users turning off their
+// own need a code; an admin, by, turning off another user's, for a lost
+// authenticator, does not.
+func (s MFAServices) DisableMFA(ctx context.Context, username string, req RequestMFACode, by string) error {
+	ctx, span := tracing.Start(ctx, "MFAServices.DisableMFA")
+	defer span.End()
+
+	if by == username {
+		if err := s.checkEnabled(ctx, username, req, "Error DisableMFA Service"); err != nil {
+			return err
+		}
+	}
+	if err := s.query.DisableMFA(ctx, username); err != nil {
+		if err == sql.ErrNoRows {
+			return &c.Err{Code: http.StatusNotFound, Remark: "Error MFA Not Enabled", Original: err}
+		}
+		c.LogFrom(ctx, s.log).Errorf("Error DisableMFA : %v", err)
+		return &c.Err{Code: http.StatusInternalServerError, Remark: "Error DisableMFA Service", Original: err}
+	}
+	securityEvent(ctx, s.security, eventMFADisabled, username, "", c.Fields{"by": by})
+	return nil
+}
+
+func (s MFAServices) selectMFA(ctx context.Context, username string, remark string) (UserMFA, error) {
+	mfa, err := s.query.SelectMFA(ctx, username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return UserMFA{}, &c.Err{Code: http.StatusNotFound, Remark: "Error User Not Found", Original: err}
+		}
+		c.LogFrom(ctx, s.log).Errorf("Error SelectMFA : %v", err)
+		return UserMFA{}, &c.Err{Code: http.StatusInternalServerError, Remark: remark, Original: err}
+	}
+	return mfa, nil
+}
+
+// checkEnabled returns an error unless the user has two-factor
+// authentication on and req is one of their codes. Wrong codes count
+// towards a lockout like failed logins, so a stolen session cannot be used
+// to guess them.
+func (s MFAServices) checkEnabled(ctx context.Context, username string, req RequestMFACode, remark string) error {
+	mfa, err := s.selectMFA(ctx, username, remark)
+	if err != nil {
+		return err
+	}
+	if mfa.EnabledAt == nil {
+		return &c.Err{Code: http.StatusConflict, Remark: "Error MFA Not Enabled"}
+	}
+	login := RequestLogin{Username: username, IP: req.IP}
+	if err := s.lockout().checkLoginLocks(ctx, login, remark); err != nil {
+		return err
+	}
+	ok, err := checkSecondFactor(ctx, s.query, s.conf, s.now(), username, mfa, req)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error Check Second Factor : %v", err)
+		return &c.Err{Code: http.StatusInternalServerError, Remark: remark, Original: err}
+	}
+	if !ok {
+		s.lockout().loginFailed(ctx, eventMFAFailed, login)
+		return &c.Err{Code: http.StatusBadRequest, Remark: "Error Invalid Code"}
+	}
+	return nil
+}
+
+// checkSecondFactor reports whether req holds the current code of mfa, or
+// else one of the user's unused recovery codes, and uses it up so it is
+// not accepted again.
+func checkSecondFactor(ctx context.Context, q secondFactorQueries, conf c.AuthConfig, now time.Time, username string, mfa UserMFA, req RequestMFACode) (bool, error) {
+	if mfa.EnabledAt == nil {
+		return false, nil
+	}
+	if req.Code == "" {
+		if req.RecoveryCode == "" {
+			return false, nil
+		}
+		return usedUp(q.UseRecoveryCode(ctx, username, hashRecoveryCode(req.RecoveryCode)))
+	}
+	step, ok, err := totp.Verify(mfa.Secret, req.Code, now, conf.MFASkew)
+	if err != nil || !ok {
+		return false, err
+	}
+	return usedUp(q.AdvanceMFAStep(ctx, username, step))
+}
+
+// usedUp turns sql.ErrNoRows, a code already used, into a wrong code.
+func usedUp(err error) (bool, error) {
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// mfaRequired reports whether users holding role must use two-factor
+// authentication.
+func mfaRequired(conf c.AuthConfig, role string) bool {
+	for _, r := range conf.MFARequiredRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func (r RequestMFACode) method() string {
+	if r.Code == "" {
+		return "recovery_code"
+	}
+	return "totp"
+}
+
+// newRecoveryCodes returns n codes like "abcde-fghjk" and their hashes.
+func newRecoveryCodes(n int) ([]string, []string, error) {
+	codes := make([]string, n)
+	hashes := make([]string, n)
+	max := big.NewInt(int64(len(recoveryCodeChars)))
+	for i := range codes {
+		b := make([]byte, recoveryCodeLen)
+		for j := range b {
+			k, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return nil, nil, fmt.Errorf("error generate recovery code : %w", err)
+			}
+			b[j] = recoveryCodeChars[k.Int64()]
+		}
+		codes[i] = string(b[:recoveryCodeLen/2]) + "-" + string(b[recoveryCodeLen/2:])
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode hashes a code as typed, ignoring case, spaces and
+// dashes.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	return utils.HashToken(code)
+}
@@ -0,0 +1,361 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/paquesqueue/bookstore/totp"
+	"github.com/paquesqueue/bookstore/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+var mfaNow = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type MFAQueriesStub struct {
+	mfa         UserMFA
+	recovery    map[string]bool
+	keepSession string
+	disabled    bool
+	failures    map[string]int
+	locked      bool
+}
+
+func (q *MFAQueriesStub) SelectMFA(ctx context.Context, username string) (UserMFA, error) {
+	if username != "tester" {
+		return UserMFA{}, sql.ErrNoRows
+	}
+	return q.mfa, nil
+}
+
+func (q *MFAQueriesStub) SetMFASecret(ctx context.Context, username string, secret string) error {
+	if q.mfa.EnabledAt != nil {
+		return sql.ErrNoRows
+	}
+	q.mfa.Secret = secret
+	return nil
+}
+
+func (q *MFAQueriesStub) EnableMFA(ctx context.Context, username string, step int64, codeHashes []string, keepSessionHash string) error {
+	q.mfa.EnabledAt = &mfaNow
+	q.mfa.LastStep = step
+	q.keepSession = keepSessionHash
+	return q.ReplaceRecoveryCodes(ctx, username, codeHashes)
+}
+
+func (q *MFAQueriesStub) ReplaceRecoveryCodes(ctx context.Context, username string, codeHashes []string) error {
+	q.recovery = map[string]bool{}
+	for _, hash := range codeHashes {
+		q.recovery[hash] = true
+	}
+	return nil
+}
+
+func (q *MFAQueriesStub) DisableMFA(ctx context.Context, username string) error {
+	if q.mfa.Secret == "" {
+		return sql.ErrNoRows
+	}
+	q.mfa = UserMFA{Role: q.mfa.Role}
+	q.disabled = true
+	return nil
+}
+
+func (q *MFAQueriesStub) AdvanceMFAStep(ctx context.Context, username string, step int64) error {
+	if step <= q.mfa.LastStep {
+		return sql.ErrNoRows
+	}
+	q.mfa.LastStep = step
+	return nil
+}
+
+func (q *MFAQueriesStub) UseRecoveryCode(ctx context.Context, username string, codeHash string) error {
+	if !q.recovery[codeHash] {
+		return sql.ErrNoRows
+	}
+	delete(q.recovery, codeHash)
+	return nil
+}
+
+func (q *MFAQueriesStub) SelectLoginLocks(ctx context.Context, username string, ip string) ([]LoginLock, error) {
+	if q.locked {
+		return []LoginLock{{Scope: LoginScopeUser, Subject: username, LockedUntil: mfaNow.Add(time.Minute)}}, nil
+	}
+	return nil, nil
+}
+
+func (q *MFAQueriesStub) RecordLoginFailure(ctx context.Context, scope string, subject string, window time.Duration) (int, error) {
+	if q.failures == nil {
+		q.failures = map[string]int{}
+	}
+	q.failures[scope+":"+subject]++
+	return q.failures[scope+":"+subject], nil
+}
+
+func (q *MFAQueriesStub) LockLogin(ctx context.Context, scope string, subject string, until time.Time) error {
+	q.locked = true
+	return nil
+}
+
+func newMFAServices(q MFAQueries) (MFAServices, *test.Hook) {
+	security, hook := test.NewNullLogger()
+	s := NewMFAService(q, c.AuthConfig{
+		MFAIssuer:        "Bookstore",
+		MFARequiredRoles: []string{UserRoleStaff, UserRoleAdmin},
+		MFARecoveryCodes: 3,
+		MFASkew:          1,
+	}, security, logrus.New())
+	s.now = func() time.Time { return mfaNow }
+	return s, hook
+}
+
+// enrolledMFA returns a stub for a user with two-factor authentication on,
+// the current code having not been used yet.
+func enrolledMFA(t *testing.T) (*MFAQueriesStub, string) {
+	secret, err := totp.NewSecret()
+	assert.NoError(t, err)
+	code, err := totp.Code(secret, totp.Step(mfaNow))
+	assert.NoError(t, err)
+	return &MFAQueriesStub{
+		mfa:      UserMFA{Role: UserRoleStaff, Secret: secret, EnabledAt: &mfaNow},
+		recovery: map[string]bool{hashRecoveryCode("abcde-fghjk"): true},
+	}, code
+}
+
+func TestGetMFA(t *testing.T) {
+	t.Run("TestGetMFAShouldReportWhetherRequired", func(t *testing.T) {
+		// Arrange
+		query := &MFAQueriesStub{mfa: UserMFA{Role: UserRoleStaff}}
+		services, _ := newMFAServices(query)
+
+		// Act
+		res, err := services.GetMFA(context.Background(), "tester")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, ResponseMFA{Enabled: false, Required: true}, res)
+	})
+
+	t.Run("TestGetMFAShouldReturnHTTPStatus404ForUnknownUser", func(t *testing.T) {
+		// Arrange
+		services, _ := newMFAServices(&MFAQueriesStub{})
+
+		// Act
+		_, err := services.GetMFA(context.Background(), "nobody")
+
+		// Assert
+		if assert.IsType(t, &c.Err{}, err) {
+			assert.Equal(t, http.StatusNotFound, err.(*c.Err).Code)
+		}
+	})
+}
+
+func TestStartMFA(t *testing.T) {
+	t.Run("TestStartMFAShouldReturnSecretAndURI", func(t *testing.T) {
+		// Arrange
+		query := &MFAQueriesStub{}
+		services, _ := newMFAServices(query)
+
+		// Act
+		res, err := services.StartMFA(context.Background(), "tester")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, query.mfa.Secret, res.Secret)
+		assert.True(t, strings.HasPrefix(res.URI, "otpauth://totp/Bookstore:tester?"))
+	})
+
+	t.Run("TestStartMFAShouldReturnHTTPStatus409WhenEnabled", func(t *testing.T) {
+		// Arrange
+		query, _ := enrolledMFA(t)
+		services, _ := newMFAServices(query)
+
+		// Act
+		_, err := services.StartMFA(context.Background(), "tester")
+
+		// Assert
+		if assert.IsType(t, &c.Err{}, err) {
+			assert.Equal(t, http.StatusConflict, err.(*c.Err).Code)
+		}
+	})
+}
+
+func TestConfirmMFA(t *testing.T) {
+	started := func(t *testing.T) (*MFAQueriesStub, string) {
+		query, code := enrolledMFA(t)
+		query.mfa.EnabledAt = nil
+		query.recovery = nil
+		return query, code
+	}
+
+	t.Run("TestConfirmMFAShouldEnableAndReturnRecoveryCodes", func(t *testing.T) {
+		// Arrange
+		query, code := started(t)
+		services, hook := newMFAServices(query)
+
+		// Act
+		res, err := services.ConfirmMFA(context.Background(), "tester", RequestMFACode{Code: code}, "session-token")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NotNil(t, query.mfa.EnabledAt)
+		assert.Equal(t, totp.Step(mfaNow), query.mfa.LastStep)
+		assert.Equal(t, utils.HashToken("session-token"), query.keepSession)
+		assert.Len(t, res.RecoveryCodes, 3)
+		for _, code := range res.RecoveryCodes {
+			assert.True(t, query.recovery[hashRecoveryCode(code)])
+		}
+		if entry := hook.LastEntry(); assert.NotNil(t, entry) {
+			assert.Equal(t, eventMFAEnabled, entry.Data["event"])
+		}
+	})
+
+	t.Run("TestConfirmMFAShouldReturnHTTPStatus400ForWrongCode", func(t *testing.T) {
+		// Arrange
+		query, _ := started(t)
+		services, _ := newMFAServices(query)
+
+		// Act
+		_, err := services.ConfirmMFA(context.Background(), "tester", RequestMFACode{Code: "abcdef"}, "session-token")
+
+		// Assert
+		if assert.IsType(t, &c.Err{}, err) {
+			assert.Equal(t, http.StatusBadRequest, err.(*c.Err).Code)
+		}
+		assert.Nil(t, query.mfa.EnabledAt)
+	})
+
+	t.Run("TestConfirmMFAShouldReturnHTTPStatus409WhenNotStarted", func(t *testing.T) {
+		// Arrange
+		services, _ := newMFAServices(&MFAQueriesStub{})
+
+		// Act
+		_, err := services.ConfirmMFA(context.Background(), "tester", RequestMFACode{Code: "123456"}, "session-token")
+
+		// Assert
+		if assert.IsType(t, &c.Err{}, err) {
+			assert.Equal(t, http.StatusConflict, err.(*c.Err).Code)
+		}
+	})
+}
+
+func TestReplaceRecoveryCodes(t *testing.T) {
+	t.Run("TestReplaceRecoveryCodesShouldDropOldCodes", func(t *testing.T) {
+		// Arrange
+		query, _ := enrolledMFA(t)
+		services, _ := newMFAServices(query)
+
+		// Act
+		res, err := services.ReplaceRecoveryCodes(context.Background(), "tester", RequestMFACode{RecoveryCode: "abcde-fghjk"})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Len(t, res.RecoveryCodes, 3)
+		assert.Len(t, query.recovery, 3)
+		assert.False(t, query.recovery[hashRecoveryCode("abcde-fghjk")])
+	})
+
+	t.Run("TestReplaceRecoveryCodesShouldLockOutWrongCodes", func(t *testing.T) {
+		// Arrange
+		query, code := enrolledMFA(t)
+		services, hook := newMFAServices(query)
+		services.conf.LockoutThreshold = 2
+		services.conf.LockoutBase = time.Minute
+		services.conf.LockoutMax = time.Hour
+		ctx := context.Background()
+
+		// Act
+		_, first := services.ReplaceRecoveryCodes(ctx, "tester", RequestMFACode{Code: "000000", IP: "10.0.0.1"})
+		_, second := services.ReplaceRecoveryCodes(ctx, "tester", RequestMFACode{Code: "000000", IP: "10.0.0.1"})
+		_, locked := services.ReplaceRecoveryCodes(ctx, "tester", RequestMFACode{Code: code, IP: "10.0.0.1"})
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, first.(*c.Err).Code)
+		assert.Equal(t, http.StatusBadRequest, second.(*c.Err).Code)
+		assert.Equal(t, http.StatusTooManyRequests, locked.(*c.Err).Code)
+		assert.Equal(t, 2, query.failures[LoginScopeUser+":tester"])
+		assert.Equal(t, 2, query.failures[LoginScopeIP+":10.0.0.1"])
+		assert.Len(t, query.recovery, 1)
+
+		events := []interface{}{}
+		for _, entry := range hook.AllEntries() {
+			events = append(events, entry.Data["event"])
+		}
+		assert.Contains(t, events, eventMFAFailed)
+		assert.Contains(t, events, eventLockedOut)
+		assert.Contains(t, events, eventLoginBlocked)
+	})
+}
+
+func TestDisableMFA(t *testing.T) {
+	t.Run("TestDisableMFAShouldNeedCodeFromOwner", func(t *testing.T) {
+		// Arrange
+		query, code := enrolledMFA(t)
+		services, _ := newMFAServices(query)
+
+		// Act
+		wrong := services.DisableMFA(context.Background(), "tester", RequestMFACode{Code: "000000"}, "tester")
+		err := services.DisableMFA(context.Background(), "tester", RequestMFACode{Code: code}, "tester")
+
+		// Assert
+		if assert.IsType(t, &c.Err{}, wrong) {
+			assert.Equal(t, http.StatusBadRequest, wrong.(*c.Err).Code)
+		}
+		assert.NoError(t, err)
+		assert.True(t, query.disabled)
+	})
+
+	t.Run("TestDisableMFAShouldNotNeedCodeFromAdmin", func(t *testing.T) {
+		// Arrange
+		query, _ := enrolledMFA(t)
+		services, hook := newMFAServices(query)
+
+		// Act
+		err := services.DisableMFA(context.Background(), "tester", RequestMFACode{}, "admin")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.True(t, query.disabled)
+		if entry := hook.LastEntry(); assert.NotNil(t, entry) {
+			assert.Equal(t, eventMFADisabled, entry.Data["event"])
+			assert.Equal(t, "admin", entry.Data["by"])
+		}
+	})
+
+	t.Run("TestDisableMFAShouldReturnHTTPStatus404WhenNotEnabled", func(t *testing.T) {
+		// Arrange
+		services, _ := newMFAServices(&MFAQueriesStub{})
+
+		// Act
+		err := services.DisableMFA(context.Background(), "tester", RequestMFACode{}, "admin")
+
+		// Assert
+		if assert.IsType(t, &c.Err{}, err) {
+			assert.Equal(t, http.StatusNotFound, err.(*c.Err).Code)
+		}
+	})
+}
+
+func TestNewRecoveryCodes(t *testing.T) {
+	t.Run("TestNewRecoveryCodesShouldHashCodesAsTyped", func(t *testing.T) {
+		// Act
+		codes, hashes, err := newRecoveryCodes(2)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Len(t, codes, 2)
+		assert.NotEqual(t, codes[0], codes[1])
+		for i, code := range codes {
+			assert.Len(t, code, recoveryCodeLen+1)
+			typed := strings.ToUpper(strings.Replace(code, "-", " ", 1))
+			assert.Equal(t, hashes[i], hashRecoveryCode(typed))
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+const sqlSchemaMigrations = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INT PRIMARY KEY NOT NULL,
+	name TEXT NOT NULL,
+	applied_at TIMESTAMP DEFAULT NOW() NOT NULL
+);
+`
+
+// migration is a schema or data change applied once, in version order,
+// inside its own transaction.
+type migration struct {
+	version int
+	name    string
+	up      func(tx *sql.Tx) error
+}
+
+func execMigration(query string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		_, err := tx.Exec(query)
+		return err
+	}
+}
+
+var migrations = []migration{
+	{1, "create books, users and onix details", execMigration(sqlQuries)},
+	{2, "create authors and publishers", execMigration(sqlContributors)},
+	{3, "link existing books to authors and publishers", migrateBookContributors},
+	{4, "create categories and tags", execMigration(sqlTaxonomy)},
+	{5, "create book covers", execMigration(sqlCovers)},
+	{6, "create works and move books under them as editions", migrateBookWorks},
+	{7, "create sessions, reviews and book ratings", execMigration(sqlReviews)},
+	{8, "create book lists", execMigration(sqlLists)},
+	{9, "create book similarities", execMigration(sqlSimilarities)},
+	{10, "create api keys", execMigration(sqlAPIKeys)},
+	{11, "create user tokens and email verification", execMigration(sqlUserTokens)},
+	{12, "create login failures", execMigration(sqlLoginFailures)},
+	{13, "add user two-factor authentication", execMigration(sqlUserMFA)},
+	{14, "add user erasure", execMigration(sqlUserErasures)},
+	{15, "store session, token, lockout and api key times with time zone", execMigration(sqlExpiryTimeZones)},
+}
+
+// LatestSchemaVersion is the version the database reaches once every known
+// migration has been applied.
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// SchemaVersion returns the highest migration version applied to db.
+func SchemaVersion(db *sql.DB) (int, error) {
+	var version int
+	err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations;`).Scan(&version)
+	return version, err
+}
+
+// Migrate applies every migration newer than the current schema version.
+func Migrate(db *sql.DB) error {
+	if _, err := db.Exec(sqlSchemaMigrations); err != nil {
+		return fmt.Errorf("error create schema_migrations : %w", err)
+	}
+
+	current, err := SchemaVersion(db)
+	if err != nil {
+		return fmt.Errorf("error read schema version : %w", err)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := applyMigration(db, m); err != nil {
+			return fmt.Errorf("error apply migration %d %v : %w", m.version, m.name, err)
+		}
+	}
+	return nil
+}
+
+func applyMigration(db *sql.DB, m migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := m.up(tx); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO schema_migrations (version, name) VALUES ($1, $2);`, m.version, m.name); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (db Query) SelectSchemaVersion(ctx context.Context) (int, error) {
+	return SchemaVersion(db.DB)
+}
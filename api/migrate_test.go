@@ -0,0 +1,100 @@
+//go:build unit
+
+package api
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func withMigrations(t *testing.T, ms []migration) {
+	previous := migrations
+	migrations = ms
+	t.Cleanup(func() { migrations = previous })
+}
+
+func TestMigrate(t *testing.T) {
+	t.Run("TestMigrateShouldSkipAppliedMigrations", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		withMigrations(t, []migration{
+			{1, "create a", execMigration(`CREATE TABLE a ();`)},
+			{2, "create b", execMigration(`CREATE TABLE b ();`)},
+			{3, "create c", execMigration(`CREATE TABLE c ();`)},
+		})
+
+		mock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE IF NOT EXISTS schema_migrations`)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations;`)).
+			WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(1))
+		for i, table := range []string{"b", "c"} {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE ` + table)).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO schema_migrations (version, name) VALUES ($1, $2);`)).
+				WithArgs(i+2, "create "+table).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+		}
+
+		// Act
+		err = Migrate(db)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, 3, LatestSchemaVersion())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestMigrateShouldRollbackFailedMigration", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		withMigrations(t, []migration{
+			{1, "create a", execMigration(`CREATE TABLE a ();`)},
+		})
+
+		mock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE IF NOT EXISTS schema_migrations`)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations;`)).
+			WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(0))
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE a`)).
+			WillReturnError(assert.AnError)
+		mock.ExpectRollback()
+
+		// Act
+		err = Migrate(db)
+
+		// Assert
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestMigrateShouldDoNothingWhenUpToDate", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE IF NOT EXISTS schema_migrations`)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations;`)).
+			WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(LatestSchemaVersion()))
+
+		// Act
+		err = Migrate(db)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+// IngestOnixBook writes one ONIX product in a single transaction: the
+// edition, inserted when id is 0 and updated otherwise, its contributors,
+// its ONIX detail and its subjects. A step that fails leaves the catalog as
+// it was.
+func (db Query) IngestOnixBook(ctx context.Context, id uint64, req RequestBook, detail RequestOnixDetail, subjects []RequestCategoryImport) (*ResponseBook, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var book *ResponseBook
+	if id == 0 {
+		book, err = insertBook(tx, req)
+	} else {
+		book, err = updateBook(tx, id, req)
+	}
+	if err != nil {
+		return nil, err
+	}
+	detail.BookId = book.Id
+	err = upsertOnixDetail(tx, detail)
+	if err != nil {
+		return nil, err
+	}
+	err = linkBookSubjects(tx, book.Id, subjects)
+	if err != nil {
+		return nil, err
+	}
+	return book, tx.Commit()
+}
+
+func upsertOnixDetail(tx *sql.Tx, req RequestOnixDetail) error {
+	const query = `INSERT INTO book_onix_details 
+	(book_id, record_reference, product_form, subtitle, language_code, page_count, publication_date, description, currency_code) 
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) 
+	ON CONFLICT (book_id) DO UPDATE 
+	SET record_reference = EXCLUDED.record_reference, product_form = EXCLUDED.product_form, subtitle = EXCLUDED.subtitle, 
+	language_code = EXCLUDED.language_code, page_count = EXCLUDED.page_count, publication_date = EXCLUDED.publication_date, 
+	description = EXCLUDED.description, currency_code = EXCLUDED.currency_code, updated_at = NOW();`
+
+	_, err := tx.Exec(query, req.BookId, req.RecordReference, req.ProductForm, req.Subtitle, req.LanguageCode, req.PageCount, req.PublicationDate, req.Description, req.CurrencyCode)
+	return err
+}
+
+// SelectOnixBooks pages through books with their ONIX details. The
+// edition's own publication date wins over the one last ingested from a
+// feed, which is kept for partial dates.
+func (db Query) SelectOnixBooks(ctx context.Context, params GetAllParams) ([]ResponseOnixBook, error) {
+	const query = `SELECT b.id, b.title, b.authors, b.publisher, b.isbn, b.price, b.quantity, b.created_by, b.created_at, 
+	COALESCE(d.record_reference, ''), COALESCE(d.product_form, ''), COALESCE(d.subtitle, ''), COALESCE(d.language_code, ''), 
+	COALESCE(d.page_count, 0), COALESCE(to_char(b.publication_date, 'YYYYMMDD'), d.publication_date, ''), COALESCE(d.description, ''), COALESCE(d.currency_code, '') 
+	FROM books b 
+	LEFT JOIN book_onix_details d ON d.book_id = b.id 
+	ORDER BY b.id 
+	LIMIT $1 
+	OFFSET $2;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, params.Limit, params.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resp := []ResponseOnixBook{}
+	for rows.Next() {
+		r := ResponseOnixBook{}
+		err = rows.Scan(&r.Id, &r.Title, pq.Array(&r.Authors), &r.Publisher, &r.Isbn, &r.Price, &r.Quantity, &r.Created_by, &r.Created_at,
+			&r.RecordReference, &r.ProductForm, &r.Subtitle, &r.LanguageCode, &r.PageCount, &r.PublicationDate, &r.Description, &r.CurrencyCode)
+		if err != nil {
+			return nil, err
+		}
+		resp = append(resp, r)
+	}
+	return resp, rows.Err()
+}
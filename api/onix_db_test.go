@@ -0,0 +1,188 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIngestOnixBook(t *testing.T) {
+	const upsertQuery = `INSERT INTO book_onix_details (book_id, record_reference, product_form, subtitle, language_code, page_count, publication_date, description, currency_code) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) ON CONFLICT (book_id) DO UPDATE`
+
+	mockData := RequestBook{
+		Title:      "mockTitle",
+		Authors:    []string{},
+		Isbn:       "9780000000017",
+		Price:      450,
+		Quantity:   25,
+		Created_by: "mockSender",
+	}
+	mockDetail := RequestOnixDetail{
+		RecordReference: "com.sample.1",
+		ProductForm:     "BB",
+		Subtitle:        "mockSubtitle",
+		LanguageCode:    "eng",
+		PageCount:       320,
+		PublicationDate: "20230115",
+		Description:     "mockDescription",
+		CurrencyCode:    "THB",
+	}
+	newBookRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "title", "authors", "publisher", "isbn", "price", "quantity", "created_by", "created_at", "work_id", "format", "publication_date", "rating_count", "rating_sum"}).
+			AddRow(1, mockData.Title, pq.Array(mockData.Authors), "", mockData.Isbn, mockData.Price, mockData.Quantity, mockData.Created_by, time.Now(), 1, "", "", 0, 0)
+	}
+	expectBook := func(mock sqlmock.Sqlmock) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO works (title, authors) VALUES ($1, $2) RETURNING id;`)).
+			WithArgs(mockData.Title, pq.Array(mockData.Authors)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO books`)).
+			WillReturnRows(newBookRows())
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM book_authors WHERE book_id = $1 AND role = $2;`)).
+			WithArgs(1, RoleAuthor).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE books SET publisher_id = $1 WHERE id = $2;`)).
+			WithArgs(nil, 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+
+	t.Run("TestIngestOnixBookShouldWriteProductInOneTransaction", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		expectBook(mock)
+		mock.ExpectExec(regexp.QuoteMeta(upsertQuery)).
+			WithArgs(1, mockDetail.RecordReference, mockDetail.ProductForm, mockDetail.Subtitle, mockDetail.LanguageCode, mockDetail.PageCount, mockDetail.PublicationDate, mockDetail.Description, mockDetail.CurrencyCode).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM book_categories bc`)).
+			WithArgs(1, CategorySchemeBISAC, CategorySchemeThema).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		query := NewDB(db)
+
+		// Act
+		book, err := query.IngestOnixBook(context.Background(), 0, mockData, mockDetail, nil)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(1), book.Id)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestIngestOnixBookShouldRollbackWhenDetailFails", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		expectBook(mock)
+		mock.ExpectExec(regexp.QuoteMeta(upsertQuery)).
+			WillReturnError(&pq.Error{Message: "db connection error"})
+		mock.ExpectRollback()
+
+		query := NewDB(db)
+
+		// Act
+		_, err = query.IngestOnixBook(context.Background(), 0, mockData, mockDetail, nil)
+
+		// Assert
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestSelectOnixBooks(t *testing.T) {
+	const selectQuery = `FROM books b LEFT JOIN book_onix_details d ON d.book_id = b.id ORDER BY b.id LIMIT $1 OFFSET $2;`
+
+	t.Run("TestSelectOnixBooksShouldReturnNoError", func(t *testing.T) {
+		// Arrange
+		params := GetAllParams{Limit: 10, Offset: 0}
+
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		row := sqlmock.NewRows([]string{"id", "title", "authors", "publisher", "isbn", "price", "quantity", "created_by", "created_at",
+			"record_reference", "product_form", "subtitle", "language_code", "page_count", "publication_date", "description", "currency_code"}).
+			AddRow(1, "mockTitle", pq.Array([]string{"Author A"}), "mockPublisher", "9780000000017", 450, 25, "mockAdmin", time.Now(),
+				"com.sample.1", "BB", "mockSubtitle", "eng", 320, "20230115", "mockDescription", "THB").
+			AddRow(2, "mockTitle 2", pq.Array([]string{"Author B"}), "mockPublisher", "9780000000024", 300, 0, "mockAdmin", time.Now(),
+				"", "", "", "", 0, "", "", "")
+
+		get := mock.ExpectPrepare(regexp.QuoteMeta(selectQuery))
+		get.ExpectQuery().
+			WithArgs(params.Limit, params.Offset).
+			WillReturnRows(row)
+
+		query := NewDB(db)
+
+		// Act
+		results, err := query.SelectOnixBooks(context.Background(), params)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Len(t, results, 2)
+		assert.Equal(t, "mockSubtitle", results[0].Subtitle)
+		assert.Equal(t, int64(320), results[0].PageCount)
+		assert.Equal(t, []string{"Author B"}, results[1].Authors)
+		assert.Equal(t, "", results[1].RecordReference)
+	})
+
+	t.Run("TestSelectOnixBooksShouldPreferBookPublicationDate", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		row := sqlmock.NewRows([]string{"id", "title", "authors", "publisher", "isbn", "price", "quantity", "created_by", "created_at",
+			"record_reference", "product_form", "subtitle", "language_code", "page_count", "publication_date", "description", "currency_code"}).
+			AddRow(1, "mockTitle", pq.Array([]string{"Author A"}), "mockPublisher", "9780000000017", 450, 25, "mockAdmin", time.Now(),
+				"", "", "", "", 0, "20240301", "", "")
+		get := mock.ExpectPrepare(regexp.QuoteMeta(`COALESCE(to_char(b.publication_date, 'YYYYMMDD'), d.publication_date, '')`))
+		get.ExpectQuery().
+			WithArgs(int64(10), int64(0)).
+			WillReturnRows(row)
+
+		query := NewDB(db)
+
+		// Act
+		results, err := query.SelectOnixBooks(context.Background(), GetAllParams{Limit: 10})
+
+		// Assert
+		assert.NoError(t, err)
+		if assert.Len(t, results, 1) {
+			assert.Equal(t, "20240301", results[0].PublicationDate)
+		}
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestSelectOnixBooksShouldReturnError", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		get := mock.ExpectPrepare(regexp.QuoteMeta(selectQuery))
+		get.ExpectQuery().
+			WillReturnError(&pq.Error{Message: "db connection error"})
+
+		query := NewDB(db)
+
+		// Act
+		results, err := query.SelectOnixBooks(context.Background(), GetAllParams{Limit: 10})
+
+		// Assert
+		assert.Error(t, err)
+		assert.Nil(t, results)
+	})
+}
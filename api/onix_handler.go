@@ -0,0 +1,54 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	c "github.com/paquesqueue/bookstore/common"
+)
+
+type OnixHandlrQueries interface {
+	IngestFeed(ctx context.Context, r io.Reader) (ResponseFeedIngest, error)
+	ExportFeed(ctx context.Context, w io.Writer) error
+}
+
+type OnixHandlr struct {
+	handler OnixHandlrQueries
+	log     c.Log
+}
+
+func NewOnixHandlr(h OnixHandlrQueries, l c.Log) OnixHandlr {
+	return OnixHandlr{h, l}
+}
+
+// IngestFeed answers a malformed feed with the counts of what was applied
+// before the error, so the sender knows where to resume.
+func (h OnixHandlr) IngestFeed(ctx echo.Context) error {
+	res, err := h.handler.IngestFeed(ctx.Request().Context(), ctx.Request().Body)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			if cmErr.Code == http.StatusBadRequest {
+				return ctx.JSON(cmErr.Code, res)
+			}
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error IngestFeed Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h OnixHandlr) ExportFeed(ctx echo.Context) error {
+	ctx.Response().Header().Set(echo.HeaderContentType, echo.MIMEApplicationXMLCharsetUTF8)
+	ctx.Response().WriteHeader(http.StatusOK)
+
+	// The status line is already sent once streaming starts, so a failure
+	// part way through can only be logged and leaves a truncated document.
+	err := h.handler.ExportFeed(ctx.Request().Context(), ctx.Response())
+	if err != nil {
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error ExportFeed Handler : %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type OnixHandlrSuccess struct {
+	ingestFeedCalled bool
+	exportFeedCalled bool
+}
+
+func (h *OnixHandlrSuccess) IngestFeed(ctx context.Context, r io.Reader) (ResponseFeedIngest, error) {
+	h.ingestFeedCalled = true
+	return ResponseFeedIngest{Created: 1, Errors: []string{}}, nil
+}
+
+func (h *OnixHandlrSuccess) ExportFeed(ctx context.Context, w io.Writer) error {
+	h.exportFeedCalled = true
+	_, err := io.WriteString(w, "<ONIXMessage release=\"3.0\"></ONIXMessage>")
+	return err
+}
+
+type OnixHandlrError struct {
+	statusCodeError int
+}
+
+func (h *OnixHandlrError) IngestFeed(ctx context.Context, r io.Reader) (ResponseFeedIngest, error) {
+	return ResponseFeedIngest{Created: 1, Errors: []string{}, Aborted: "unexpected EOF"}, &c.Err{Code: h.statusCodeError}
+}
+
+func (h *OnixHandlrError) ExportFeed(ctx context.Context, w io.Writer) error {
+	return &c.Err{Code: h.statusCodeError}
+}
+
+func TestIngestFeedHandler(t *testing.T) {
+	t.Run("TestIngestFeedHandlerShouldReturnHTTPStatus200", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPost, "/feeds/onix", strings.NewReader("<ONIXMessage/>"))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationXML)
+		rec := httptest.NewRecorder()
+		ctx := echo.New().NewContext(req, rec)
+
+		handlrServ := &OnixHandlrSuccess{}
+		handler := NewOnixHandlr(handlrServ, logrus.New())
+
+		// Act
+		err := handler.IngestFeed(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.True(t, handlrServ.ingestFeedCalled)
+			assert.Equal(t, http.StatusOK, rec.Code)
+
+			res := ResponseFeedIngest{}
+			json.Unmarshal(rec.Body.Bytes(), &res)
+			assert.Equal(t, 1, res.Created)
+		}
+	})
+
+	t.Run("TestIngestFeedHandlerShouldReturnHTTPStatus400", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPost, "/feeds/onix", strings.NewReader("not xml"))
+		rec := httptest.NewRecorder()
+		ctx := echo.New().NewContext(req, rec)
+
+		handlrServ := &OnixHandlrError{statusCodeError: http.StatusBadRequest}
+		handler := NewOnixHandlr(handlrServ, logrus.New())
+
+		// Act
+		err := handler.IngestFeed(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+			res := ResponseFeedIngest{}
+			json.Unmarshal(rec.Body.Bytes(), &res)
+			assert.Equal(t, 1, res.Created)
+			assert.Equal(t, "unexpected EOF", res.Aborted)
+		}
+	})
+}
+
+func TestExportFeedHandler(t *testing.T) {
+	t.Run("TestExportFeedHandlerShouldReturnXML", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/feeds/onix", nil)
+		rec := httptest.NewRecorder()
+		ctx := echo.New().NewContext(req, rec)
+
+		handlrServ := &OnixHandlrSuccess{}
+		handler := NewOnixHandlr(handlrServ, logrus.New())
+
+		// Act
+		err := handler.ExportFeed(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.True(t, handlrServ.exportFeedCalled)
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, echo.MIMEApplicationXMLCharsetUTF8, rec.Header().Get(echo.HeaderContentType))
+			assert.Contains(t, rec.Body.String(), "ONIXMessage")
+		}
+	})
+}
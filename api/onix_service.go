@@ -0,0 +1,359 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/paquesqueue/bookstore/onix"
+	"github.com/paquesqueue/bookstore/tracing"
+)
+
+const (
+	onixSenderName      = "Go Bookstore"
+	onixDefaultCreator  = "onix-feed"
+	onixDefaultCurrency = "THB"
+	onixExportPageSize  = 100
+)
+
+type OnixQueries interface {
+	IngestOnixBook(ctx context.Context, id uint64, req RequestBook, detail RequestOnixDetail, subjects []RequestCategoryImport) (*ResponseBook, error)
+	DeleteBook(ctx context.Context, id uint64) error
+	SelectBookByIsbn(ctx context.Context, isbn string) (*ResponseBook, error)
+	SelectOnixBooks(ctx context.Context, params GetAllParams) ([]ResponseOnixBook, error)
+	SelectBookWatchers(ctx context.Context, bookId uint64) ([]string, error)
+}
+
+type OnixServices struct {
+	query    OnixQueries
+	notifier ListNotifier
+	log      c.Log
+}
+
+func NewOnixService(q OnixQueries, n ListNotifier, l c.Log) OnixServices {
+	return OnixServices{q, n, l}
+}
+
+// IngestFeed upserts every product of an ONIX 3.0 feed into the catalog keyed
+// by ISBN. Products that cannot be mapped are rejected and reported without
+// stopping the feed; a malformed document aborts it, keeping the products
+// before the error, which the returned counts still cover.
+func (s OnixServices) IngestFeed(ctx context.Context, r io.Reader) (ResponseFeedIngest, error) {
+	ctx, span := tracing.Start(ctx, "OnixServices.IngestFeed")
+	defer span.End()
+
+	result := ResponseFeedIngest{Errors: []string{}}
+	dec := onix.NewDecoder(r)
+	for {
+		product, err := dec.Next()
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			c.LogFrom(ctx, s.log).Errorf("Error Decode Onix Feed : %v", err)
+			result.Aborted = err.Error()
+			return result, &c.Err{Code: http.StatusBadRequest, Remark: "Error Invalid Onix Feed", Original: err}
+		}
+
+		creator := onixDefaultCreator
+		if h := dec.Header(); h != nil && h.Sender.SenderName != "" {
+			creator = h.Sender.SenderName
+		}
+
+		err = s.ingestProduct(ctx, *product, creator, &result)
+		if err != nil {
+			c.LogFrom(ctx, s.log).Errorf("Error Ingest Onix Product %v : %v", product.RecordReference, err)
+			result.Rejected++
+			result.Errors = append(result.Errors, fmt.Sprintf("%v : %v", product.RecordReference, err))
+		}
+	}
+}
+
+func (s OnixServices) ingestProduct(ctx context.Context, p onix.Product, creator string, result *ResponseFeedIngest) error {
+	isbn := p.Isbn()
+	if isbn == "" {
+		return fmt.Errorf("error isbn not found")
+	}
+
+	existing, err := s.query.SelectBookByIsbn(ctx, isbn)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	if p.NotificationType == onix.NotificationDelete {
+		if existing == nil {
+			return nil
+		}
+		if err := s.query.DeleteBook(ctx, existing.Id); err != nil {
+			return err
+		}
+		result.Deleted++
+		return nil
+	}
+
+	req, detail, err := onixToRequestBook(p)
+	if err != nil {
+		return err
+	}
+
+	if existing == nil {
+		req.Created_by = creator
+		if _, err := s.query.IngestOnixBook(ctx, 0, req, detail, onixSubjects(p)); err != nil {
+			return err
+		}
+		result.Created++
+		return nil
+	}
+
+	req.Created_by = existing.Created_by
+	book, err := s.query.IngestOnixBook(ctx, existing.Id, req, detail, onixSubjects(p))
+	if err != nil {
+		return err
+	}
+	result.Updated++
+	notifyListWatchers(ctx, s.query, s.notifier, s.log, *existing, *book)
+	return nil
+}
+
+// ExportFeed writes the whole catalog to w as an ONIX 3.0 message.
+func (s OnixServices) ExportFeed(ctx context.Context, w io.Writer) error {
+	ctx, span := tracing.Start(ctx, "OnixServices.ExportFeed")
+	defer span.End()
+
+	enc := onix.NewEncoder(w)
+	header := onix.Header{
+		Sender:       onix.Sender{SenderName: onixSenderName},
+		SentDateTime: time.Now().UTC().Format("20060102T1504Z"),
+	}
+	if err := enc.WriteHeader(header); err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error Encode Onix Header : %v", err)
+		return &c.Err{Code: http.StatusInternalServerError, Remark: "Error ExportFeed Service", Original: err}
+	}
+
+	params := GetAllParams{Limit: onixExportPageSize, Offset: 0}
+	for {
+		books, err := s.query.SelectOnixBooks(ctx, params)
+		if err != nil {
+			c.LogFrom(ctx, s.log).Errorf("Error SelectOnixBooks : %v", err)
+			return &c.Err{Code: http.StatusInternalServerError, Remark: "Error ExportFeed Service", Original: err}
+		}
+		for _, book := range books {
+			if err := enc.Encode(onixProductFromBook(book)); err != nil {
+				c.LogFrom(ctx, s.log).Errorf("Error Encode Onix Product : %v", err)
+				return &c.Err{Code: http.StatusInternalServerError, Remark: "Error ExportFeed Service", Original: err}
+			}
+		}
+		if int64(len(books)) < params.Limit {
+			break
+		}
+		params.Offset += params.Limit
+	}
+
+	if err := enc.Close(); err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error Close Onix Feed : %v", err)
+		return &c.Err{Code: http.StatusInternalServerError, Remark: "Error ExportFeed Service", Original: err}
+	}
+	return nil
+}
+
+func onixToRequestBook(p onix.Product) (RequestBook, RequestOnixDetail, error) {
+	title, subtitle := p.Title()
+	if title == "" {
+		return RequestBook{}, RequestOnixDetail{}, fmt.Errorf("error title not found")
+	}
+
+	var price int64
+	var currency string
+	if pr, ok := p.Price(); ok {
+		amount, err := strconv.ParseFloat(pr.PriceAmount, 64)
+		if err != nil {
+			return RequestBook{}, RequestOnixDetail{}, fmt.Errorf("error invalid price amount %q", pr.PriceAmount)
+		}
+		// Prices are whole units; rounding would change the price and
+		// would not survive an export.
+		if amount != math.Trunc(amount) {
+			return RequestBook{}, RequestOnixDetail{}, fmt.Errorf("error price amount %q is not a whole number", pr.PriceAmount)
+		}
+		price = int64(amount)
+		currency = pr.CurrencyCode
+	}
+
+	var pageCount int64
+	if pc := p.PageCount(); pc != "" {
+		n, err := strconv.ParseInt(pc, 10, 64)
+		if err != nil {
+			return RequestBook{}, RequestOnixDetail{}, fmt.Errorf("error invalid page count %q", pc)
+		}
+		pageCount = n
+	}
+
+	req := RequestBook{
+		Title:     title,
+		Authors:   p.Authors(),
+		Publisher: p.Publisher(),
+		Isbn:      p.Isbn(),
+		Price:     price,
+		Quantity:  p.OnHand(),
+
+		Format:          onixEditionFormat(p.DescriptiveDetail.ProductForm),
+		PublicationDate: onixDate(p.PublicationDate()),
+	}
+	detail := RequestOnixDetail{
+		RecordReference: p.RecordReference,
+		ProductForm:     p.DescriptiveDetail.ProductForm,
+		Subtitle:        subtitle,
+		LanguageCode:    p.Language(),
+		PageCount:       pageCount,
+		PublicationDate: p.PublicationDate(),
+		Description:     p.Description(),
+		CurrencyCode:    currency,
+	}
+	return req, detail, nil
+}
+
+// onixSubjects maps the BISAC and Thema subjects of a product to categories.
+// A subject without heading text is named after its code until the code list
+// is imported.
+func onixSubjects(p onix.Product) []RequestCategoryImport {
+	subjects := []RequestCategoryImport{}
+	for _, sb := range p.Subjects(onix.SubjectSchemeBISAC) {
+		path := bisacHeadingPath(sb.SubjectHeadingText)
+		if len(path) == 0 {
+			path = []string{sb.SubjectCode}
+		}
+		subjects = append(subjects, RequestCategoryImport{Scheme: CategorySchemeBISAC, Code: sb.SubjectCode, Path: path})
+	}
+	for _, sb := range p.Subjects(onix.SubjectSchemeThema) {
+		name := strings.TrimSpace(sb.SubjectHeadingText)
+		if name == "" {
+			name = sb.SubjectCode
+		}
+		subjects = append(subjects, RequestCategoryImport{Scheme: CategorySchemeThema, Code: sb.SubjectCode, Path: []string{name}})
+	}
+	return subjects
+}
+
+// onixEditionFormat maps an ONIX product form code (code list 150) to the
+// edition format; forms without a counterpart are left unspecified.
+func onixEditionFormat(productForm string) string {
+	switch {
+	case productForm == "BB":
+		return EditionHardcover
+	case productForm == "BC":
+		return EditionPaperback
+	case strings.HasPrefix(productForm, "E"):
+		return EditionEbook
+	case strings.HasPrefix(productForm, "A"):
+		return EditionAudiobook
+	default:
+		return EditionUnspecified
+	}
+}
+
+// onixDate converts an ONIX YYYYMMDD date to YYYY-MM-DD. Partial dates such
+// as a bare year are dropped.
+func onixDate(date string) string {
+	t, err := time.Parse("20060102", date)
+	if err != nil {
+		return ""
+	}
+	return t.Format(editionDateLayout)
+}
+
+func onixProductFromBook(b ResponseOnixBook) onix.Product {
+	recordRef := b.RecordReference
+	if recordRef == "" {
+		recordRef = fmt.Sprintf("bookstore.%d", b.Id)
+	}
+	productForm := b.ProductForm
+	if productForm == "" {
+		productForm = onix.ProductFormUndefined
+	}
+	currency := b.CurrencyCode
+	if currency == "" {
+		currency = onixDefaultCurrency
+	}
+
+	p := onix.Product{
+		RecordReference:    recordRef,
+		NotificationType:   onix.NotificationConfirmed,
+		ProductIdentifiers: []onix.ProductIdentifier{onixIdentifier(b.Isbn)},
+		DescriptiveDetail: onix.DescriptiveDetail{
+			ProductComposition: onix.ProductCompositionSingle,
+			ProductForm:        productForm,
+			TitleDetails: []onix.TitleDetail{{
+				TitleType: onix.TitleTypeDistinctive,
+				TitleElements: []onix.TitleElement{{
+					TitleElementLevel: onix.TitleElementProduct,
+					TitleText:         b.Title,
+					Subtitle:          b.Subtitle,
+				}},
+			}},
+		},
+		PublishingDetail: &onix.PublishingDetail{
+			Publishers: []onix.Publisher{{PublishingRole: onix.PublishingRolePublisher, PublisherName: b.Publisher}},
+		},
+	}
+
+	for i, author := range b.Authors {
+		p.DescriptiveDetail.Contributors = append(p.DescriptiveDetail.Contributors, onix.Contributor{
+			SequenceNumber:  i + 1,
+			ContributorRole: onix.ContributorRoleAuthor,
+			PersonName:      author,
+		})
+	}
+	if b.LanguageCode != "" {
+		p.DescriptiveDetail.Languages = []onix.Language{{LanguageRole: onix.LanguageRoleText, LanguageCode: b.LanguageCode}}
+	}
+	if b.PageCount > 0 {
+		p.DescriptiveDetail.Extents = []onix.Extent{{
+			ExtentType:  onix.ExtentTypeMainContent,
+			ExtentValue: strconv.FormatInt(b.PageCount, 10),
+			ExtentUnit:  onix.ExtentUnitPages,
+		}}
+	}
+	if b.Description != "" {
+		p.CollateralDetail = &onix.CollateralDetail{
+			TextContents: []onix.TextContent{{TextType: onix.TextTypeDescription, ContentAudience: onix.ContentAudienceAny, Text: b.Description}},
+		}
+	}
+	if b.PublicationDate != "" {
+		p.PublishingDetail.PublishingDates = []onix.PublishingDate{{PublishingDateRole: onix.PublishingDatePublished, Date: b.PublicationDate}}
+	}
+
+	availability := onix.AvailabilityOutOfStock
+	if b.Quantity > 0 {
+		availability = onix.AvailabilityInStock
+	}
+	p.ProductSupply = &onix.ProductSupply{
+		SupplyDetails: []onix.SupplyDetail{{
+			Supplier:            onix.Supplier{SupplierRole: onix.SupplierRolePublisher, SupplierName: b.Publisher},
+			ProductAvailability: availability,
+			Stock:               &onix.Stock{OnHand: b.Quantity},
+			Prices: []onix.Price{{
+				PriceType:    onix.PriceTypeRRPExcludingTax,
+				PriceAmount:  strconv.FormatInt(b.Price, 10),
+				CurrencyCode: currency,
+			}},
+		}},
+	}
+	return p
+}
+
+func onixIdentifier(isbn string) onix.ProductIdentifier {
+	switch len(isbn) {
+	case 13:
+		return onix.ProductIdentifier{ProductIDType: onix.ProductIDTypeISBN13, IDValue: isbn}
+	case 10:
+		return onix.ProductIdentifier{ProductIDType: onix.ProductIDTypeISBN10, IDValue: isbn}
+	default:
+		return onix.ProductIdentifier{ProductIDType: onix.ProductIDTypeProprietary, IDTypeName: "Bookstore ISBN", IDValue: isbn}
+	}
+}
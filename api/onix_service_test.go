@@ -0,0 +1,211 @@
+//go:build unit
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/paquesqueue/bookstore/onix"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type OnixQueriesError struct {
+	BookQueriesMemory
+}
+
+func (q *OnixQueriesError) SelectOnixBooks(ctx context.Context, params GetAllParams) ([]ResponseOnixBook, error) {
+	return nil, &c.Err{}
+}
+
+func TestIngestFeed(t *testing.T) {
+	t.Run("TestIngestFeedShouldCreateBooks", func(t *testing.T) {
+		// Arrange
+		query := NewBookQueriesMemory()
+		services := NewOnixService(query, nil, logrus.New())
+		file, err := os.Open("../onix/testdata/sample.xml")
+		assert.NoError(t, err)
+		defer file.Close()
+
+		// Act
+		res, err := services.IngestFeed(context.Background(), file)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, 1, res.Created)
+		assert.Equal(t, 0, res.Rejected)
+		assert.Len(t, query.books, 1)
+
+		book := query.books[1]
+		assert.Equal(t, "The Go Bookstore", book.Title)
+		assert.Equal(t, []string{"John Author", "Jane Writer"}, book.Authors)
+		assert.Equal(t, "Sample Press", book.Publisher)
+		assert.Equal(t, "9780000000017", book.Isbn)
+		assert.Equal(t, int64(450), book.Price)
+		assert.Equal(t, int64(25), book.Quantity)
+		assert.Equal(t, "Sample Distribution Co.", book.Created_by)
+		assert.Equal(t, EditionHardcover, book.Format)
+		assert.Equal(t, "2023-01-15", book.PublicationDate)
+		assert.Equal(t, "Building Web Services with Echo", query.details[1].Subtitle)
+		assert.Equal(t, int64(320), query.details[1].PageCount)
+		assert.Equal(t, []uint64{1}, query.linkedIds)
+		assert.Equal(t, []RequestCategoryImport{
+			{Scheme: CategorySchemeBISAC, Code: "COM051010", Path: []string{"COMPUTERS", "Programming Languages", "General"}},
+			{Scheme: CategorySchemeThema, Code: "UMX", Path: []string{"UMX"}},
+		}, query.subjects[1])
+	})
+
+	t.Run("TestIngestFeedShouldUpdateAndDeleteExistingBooks", func(t *testing.T) {
+		// Arrange
+		query := NewBookQueriesMemory()
+		query.InsertBook(context.Background(), RequestBook{Title: "Old Title", Isbn: "9780000000017", Created_by: "Admin"})
+		query.InsertBook(context.Background(), RequestBook{Title: "Withdrawn Title", Isbn: "9780000000024", Created_by: "Admin"})
+		services := NewOnixService(query, nil, logrus.New())
+		file, err := os.Open("../onix/testdata/sample.xml")
+		assert.NoError(t, err)
+		defer file.Close()
+
+		// Act
+		res, err := services.IngestFeed(context.Background(), file)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, 1, res.Updated)
+		assert.Equal(t, 1, res.Deleted)
+		assert.Len(t, query.books, 1)
+		assert.Equal(t, "The Go Bookstore", query.books[1].Title)
+		assert.Equal(t, "Admin", query.books[1].Created_by)
+	})
+
+	t.Run("TestIngestFeedShouldRejectProductWithoutIsbn", func(t *testing.T) {
+		// Arrange
+		query := NewBookQueriesMemory()
+		services := NewOnixService(query, nil, logrus.New())
+		feed := `<ONIXMessage release="3.0"><Product><RecordReference>ref-1</RecordReference><NotificationType>03</NotificationType></Product></ONIXMessage>`
+
+		// Act
+		res, err := services.IngestFeed(context.Background(), strings.NewReader(feed))
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, 1, res.Rejected)
+		assert.Len(t, res.Errors, 1)
+		assert.Len(t, query.books, 0)
+	})
+
+	t.Run("TestIngestFeedShouldRejectFractionalPrice", func(t *testing.T) {
+		// Arrange
+		query := NewBookQueriesMemory()
+		services := NewOnixService(query, nil, logrus.New())
+		file, err := os.Open("../onix/testdata/fractional_price.xml")
+		assert.NoError(t, err)
+		defer file.Close()
+
+		// Act
+		res, err := services.IngestFeed(context.Background(), file)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, 1, res.Created)
+		assert.Equal(t, 1, res.Rejected)
+		if assert.Len(t, res.Errors, 1) {
+			assert.Contains(t, res.Errors[0], "com.sample.9780000000031")
+			assert.Contains(t, res.Errors[0], "12.99")
+		}
+		if assert.Len(t, query.books, 1) {
+			assert.Equal(t, "9780000000048", query.books[1].Isbn)
+			assert.Equal(t, int64(300), query.books[1].Price)
+		}
+	})
+
+	t.Run("TestIngestFeedShouldReturnAppliedCountsWhenAborted", func(t *testing.T) {
+		// Arrange
+		query := NewBookQueriesMemory()
+		services := NewOnixService(query, nil, logrus.New())
+		feed := `<ONIXMessage release="3.0"><Product><RecordReference>ref-1</RecordReference><NotificationType>03</NotificationType>` +
+			`<ProductIdentifier><ProductIDType>15</ProductIDType><IDValue>9780000000017</IDValue></ProductIdentifier>` +
+			`<DescriptiveDetail><TitleDetail><TitleType>01</TitleType><TitleElement><TitleElementLevel>01</TitleElementLevel><TitleText>Kept</TitleText></TitleElement></TitleDetail></DescriptiveDetail>` +
+			`</Product><Product><RecordReference>ref-2</Rec`
+
+		// Act
+		res, err := services.IngestFeed(context.Background(), strings.NewReader(feed))
+
+		// Assert
+		if assert.Error(t, err) {
+			assert.Equal(t, http.StatusBadRequest, err.(*c.Err).Code)
+		}
+		assert.Equal(t, 1, res.Created)
+		assert.NotEmpty(t, res.Aborted)
+		assert.Len(t, query.books, 1)
+	})
+
+	t.Run("TestIngestFeedShouldReturnBadRequest", func(t *testing.T) {
+		// Arrange
+		services := NewOnixService(NewBookQueriesMemory(), nil, logrus.New())
+
+		// Act
+		_, err := services.IngestFeed(context.Background(), strings.NewReader("not xml"))
+
+		// Assert
+		if assert.Error(t, err) {
+			assert.Equal(t, http.StatusBadRequest, err.(*c.Err).Code)
+		}
+	})
+}
+
+func TestExportFeed(t *testing.T) {
+	t.Run("TestExportFeedShouldRoundTripSample", func(t *testing.T) {
+		// Arrange
+		query := NewBookQueriesMemory()
+		services := NewOnixService(query, nil, logrus.New())
+		file, err := os.Open("../onix/testdata/sample.xml")
+		assert.NoError(t, err)
+		defer file.Close()
+		_, err = services.IngestFeed(context.Background(), file)
+		assert.NoError(t, err)
+
+		// Act
+		var buf bytes.Buffer
+		err = services.ExportFeed(context.Background(), &buf)
+
+		// Assert
+		assert.NoError(t, err)
+		dec := onix.NewDecoder(&buf)
+		product, err := dec.Next()
+		if assert.NoError(t, err) {
+			title, subtitle := product.Title()
+			price, _ := product.Price()
+			assert.Equal(t, "com.sample.9780000000017", product.RecordReference)
+			assert.Equal(t, "9780000000017", product.Isbn())
+			assert.Equal(t, "The Go Bookstore", title)
+			assert.Equal(t, "Building Web Services with Echo", subtitle)
+			assert.Equal(t, []string{"John Author", "Jane Writer"}, product.Authors())
+			assert.Equal(t, "Sample Press", product.Publisher())
+			assert.Equal(t, "20230115", product.PublicationDate())
+			assert.Equal(t, "320", product.PageCount())
+			assert.Equal(t, int64(25), product.OnHand())
+			assert.Equal(t, "450", price.PriceAmount)
+			assert.Equal(t, "THB", price.CurrencyCode)
+		}
+		_, err = dec.Next()
+		assert.Equal(t, io.EOF, err)
+	})
+
+	t.Run("TestExportFeedShouldReturnError", func(t *testing.T) {
+		// Arrange
+		services := NewOnixService(&OnixQueriesError{}, nil, logrus.New())
+
+		// Act
+		err := services.ExportFeed(context.Background(), io.Discard)
+
+		// Assert
+		assert.Error(t, err)
+	})
+}
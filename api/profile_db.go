@@ -0,0 +1,96 @@
+package api
+
+import (
+	"context"
+)
+
+// UpdateUserFullname changes a user's full name alone.
+func (db Query) UpdateUserFullname(ctx context.Context, username string, fullname string) (ResponseUser, error) {
+	const query = `UPDATE users SET fullname = $2 WHERE username = $1 RETURNING ` + userColumns + `;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return ResponseUser{}, err
+	}
+	defer stmt.Close()
+
+	return scanUser(stmt.QueryRowContext(ctx, username, fullname))
+}
+
+// UpdateUserEmail changes a user's email address, which is unverified
+// again unless it is the same one.
+func (db Query) UpdateUserEmail(ctx context.Context, username string, email string) (ResponseUser, error) {
+	const query = `UPDATE users
+	SET email = $2, email_verified_at = CASE WHEN email = $2 THEN email_verified_at END
+	WHERE username = $1
+	RETURNING ` + userColumns + `;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return ResponseUser{}, err
+	}
+	defer stmt.Close()
+
+	return scanUser(stmt.QueryRowContext(ctx, username, email))
+}
+
+// ChangeUserPassword sets a new password hash. The user's sessions other
+// than the one with keepSessionHash, and unused reset links, are removed;
+// an empty keepSessionHash removes every session.
+func (db Query) ChangeUserPassword(ctx context.Context, username string, hashedPassword string, keepSessionHash string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`UPDATE users SET hashed_password = $2 WHERE username = $1;`, username, hashedPassword)
+	if err != nil {
+		return err
+	}
+	if err := expectRows(res); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`DELETE FROM sessions WHERE username = $1 AND token_hash <> $2;`, username, keepSessionHash)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`DELETE FROM user_tokens WHERE username = $1 AND purpose = $2 AND used_at IS NULL;`, username, tokenPurposeResetPassword)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RenameUser changes a username. Tables referencing users follow through
+// their foreign keys; the creator of books, the moderator of reviews and the
+// failed login count, which only hold the name, are updated here.
+func (db Query) RenameUser(ctx context.Context, username string, newUsername string) (ResponseUser, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return ResponseUser{}, err
+	}
+	defer tx.Rollback()
+
+	user, err := scanUser(tx.QueryRow(`UPDATE users SET username = $2 WHERE username = $1 RETURNING `+userColumns+`;`, username, newUsername))
+	if err != nil {
+		return ResponseUser{}, err
+	}
+	_, err = tx.Exec(`UPDATE books SET created_by = $2 WHERE created_by = $1;`, username, newUsername)
+	if err != nil {
+		return ResponseUser{}, err
+	}
+	_, err = tx.Exec(`UPDATE reviews SET moderated_by = $2 WHERE moderated_by = $1;`, username, newUsername)
+	if err != nil {
+		return ResponseUser{}, err
+	}
+	_, err = tx.Exec(`DELETE FROM login_failures WHERE scope = $1 AND subject = $2;`, LoginScopeUser, newUsername)
+	if err != nil {
+		return ResponseUser{}, err
+	}
+	_, err = tx.Exec(`UPDATE login_failures SET subject = $3 WHERE scope = $1 AND subject = $2;`, LoginScopeUser, username, newUsername)
+	if err != nil {
+		return ResponseUser{}, err
+	}
+	return user, tx.Commit()
+}
@@ -0,0 +1,184 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func newUserRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"username", "email", "fullname", "hashed_password", "created_at", "role", "email_verified_at", "mfa_enabled_at"})
+}
+
+func TestUpdateUserFullname(t *testing.T) {
+	t.Run("TestUpdateUserFullnameShouldOnlySetFullname", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		mock.ExpectPrepare(regexp.QuoteMeta(`UPDATE users SET fullname = $2 WHERE username = $1 RETURNING `+userColumns+`;`)).
+			ExpectQuery().
+			WithArgs("tester", "New Name").
+			WillReturnRows(newUserRows().AddRow("tester", "tester@email.com", "New Name", "hash", createdAt, UserRoleCustomer, nil, nil))
+
+		query := NewDB(db)
+
+		// Act
+		user, err := query.UpdateUserFullname(context.Background(), "tester", "New Name")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "New Name", user.Fullname)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUpdateUserEmail(t *testing.T) {
+	t.Run("TestUpdateUserEmailShouldClearVerification", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		mock.ExpectPrepare(regexp.QuoteMeta(`UPDATE users SET email = $2, email_verified_at = CASE WHEN email = $2 THEN email_verified_at END WHERE username = $1`)).
+			ExpectQuery().
+			WithArgs("tester", "new@email.com").
+			WillReturnRows(newUserRows().AddRow("tester", "new@email.com", "tester testing", "hash", createdAt, UserRoleCustomer, nil, nil))
+
+		query := NewDB(db)
+
+		// Act
+		user, err := query.UpdateUserEmail(context.Background(), "tester", "new@email.com")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "new@email.com", user.Email)
+		assert.Nil(t, user.EmailVerifiedAt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestChangeUserPassword(t *testing.T) {
+	t.Run("TestChangeUserPasswordShouldEndOtherSessions", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET hashed_password = $2 WHERE username = $1;`)).
+			WithArgs("tester", "hash").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM sessions WHERE username = $1 AND token_hash <> $2;`)).
+			WithArgs("tester", "session-hash").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM user_tokens WHERE username = $1 AND purpose = $2 AND used_at IS NULL;`)).
+			WithArgs("tester", tokenPurposeResetPassword).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		query := NewDB(db)
+
+		// Act
+		err = query.ChangeUserPassword(context.Background(), "tester", "hash", "session-hash")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestChangeUserPasswordShouldReturnErrNoRowsForUnknownUser", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET hashed_password = $2 WHERE username = $1;`)).
+			WithArgs("nobody", "hash").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		query := NewDB(db)
+
+		// Act
+		err = query.ChangeUserPassword(context.Background(), "nobody", "hash", "")
+
+		// Assert
+		assert.Equal(t, sql.ErrNoRows, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRenameUser(t *testing.T) {
+	t.Run("TestRenameUserShouldUpdateNameOnlyReferences", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE users SET username = $2 WHERE username = $1 RETURNING `+userColumns+`;`)).
+			WithArgs("tester", "renamed").
+			WillReturnRows(newUserRows().AddRow("renamed", "tester@email.com", "tester testing", "hash", createdAt, UserRoleStaff, nil, nil))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE books SET created_by = $2 WHERE created_by = $1;`)).
+			WithArgs("tester", "renamed").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE reviews SET moderated_by = $2 WHERE moderated_by = $1;`)).
+			WithArgs("tester", "renamed").
+			WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM login_failures WHERE scope = $1 AND subject = $2;`)).
+			WithArgs(LoginScopeUser, "renamed").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE login_failures SET subject = $3 WHERE scope = $1 AND subject = $2;`)).
+			WithArgs(LoginScopeUser, "tester", "renamed").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		query := NewDB(db)
+
+		// Act
+		user, err := query.RenameUser(context.Background(), "tester", "renamed")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "renamed", user.Username)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("TestRenameUserShouldRollbackWhenBooksFail", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE users SET username = $2 WHERE username = $1 RETURNING `+userColumns+`;`)).
+			WithArgs("tester", "renamed").
+			WillReturnRows(newUserRows().AddRow("renamed", "tester@email.com", "tester testing", "hash", createdAt, UserRoleStaff, nil, nil))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE books SET created_by = $2 WHERE created_by = $1;`)).
+			WithArgs("tester", "renamed").
+			WillReturnError(sql.ErrConnDone)
+		mock.ExpectRollback()
+
+		query := NewDB(db)
+
+		// Act
+		_, err = query.RenameUser(context.Background(), "tester", "renamed")
+
+		// Assert
+		assert.ErrorIs(t, err, sql.ErrConnDone)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	c "github.com/paquesqueue/bookstore/common"
+)
+
+// PutProfile lets users change their own full name.
+func (h UserHandlr) PutProfile(ctx echo.Context) error {
+	username, ok := sessionOwner(ctx)
+	if !ok {
+		return ctx.NoContent(http.StatusForbidden)
+	}
+	req := RequestProfile{}
+	err := ctx.Bind(&req)
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	resp, err := h.handler.PutProfile(ctx.Request().Context(), username, req)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error PutProfile Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// ChangeEmail lets users move to a new email address with their password.
+func (h UserHandlr) ChangeEmail(ctx echo.Context) error {
+	username, ok := sessionOwner(ctx)
+	if !ok {
+		return ctx.NoContent(http.StatusForbidden)
+	}
+	req := RequestChangeEmail{}
+	err := ctx.Bind(&req)
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+	req.IP = ctx.RealIP()
+
+	resp, err := h.handler.ChangeEmail(ctx.Request().Context(), username, req)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error ChangeEmail Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// ChangePassword lets users set a new password with their current one,
+// staying signed in on this session only.
+func (h UserHandlr) ChangePassword(ctx echo.Context) error {
+	username, ok := sessionOwner(ctx)
+	if !ok {
+		return ctx.NoContent(http.StatusForbidden)
+	}
+	req := RequestChangePassword{}
+	err := ctx.Bind(&req)
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+	req.IP = ctx.RealIP()
+
+	err = h.handler.ChangePassword(ctx.Request().Context(), username, req, ctx.Request().Header.Get(SessionHeader))
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error ChangePassword Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, "Changed Successfully")
+}
+
+// ChangeUsername lets users rename themselves with their password.
+func (h UserHandlr) ChangeUsername(ctx echo.Context) error {
+	username, ok := sessionOwner(ctx)
+	if !ok {
+		return ctx.NoContent(http.StatusForbidden)
+	}
+	req := RequestChangeUsername{}
+	err := ctx.Bind(&req)
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+	req.IP = ctx.RealIP()
+
+	resp, err := h.handler.ChangeUsername(ctx.Request().Context(), username, req)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error ChangeUsername Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// ForceEmail lets an admin change a user's email address.
+func (h UserHandlr) ForceEmail(ctx echo.Context) error {
+	admin, ok := CurrentUser(ctx)
+	if !ok {
+		return ctx.NoContent(http.StatusUnauthorized)
+	}
+	req := RequestChangeEmail{}
+	err := ctx.Bind(&req)
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	resp, err := h.handler.ForceEmail(ctx.Request().Context(), ctx.Param("username"), req, admin.Username)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error ForceEmail Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// ForcePassword lets an admin set a user's password, signing them out.
+func (h UserHandlr) ForcePassword(ctx echo.Context) error {
+	admin, ok := CurrentUser(ctx)
+	if !ok {
+		return ctx.NoContent(http.StatusUnauthorized)
+	}
+	req := RequestChangePassword{}
+	err := ctx.Bind(&req)
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	err = h.handler.ForcePassword(ctx.Request().Context(), ctx.Param("username"), req, admin.Username)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error ForcePassword Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, "Changed Successfully")
+}
+
+// ForceUsername lets an admin rename a user.
+func (h UserHandlr) ForceUsername(ctx echo.Context) error {
+	admin, ok := CurrentUser(ctx)
+	if !ok {
+		return ctx.NoContent(http.StatusUnauthorized)
+	}
+	req := RequestChangeUsername{}
+	err := ctx.Bind(&req)
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	resp, err := h.handler.ForceUsername(ctx.Request().Context(), ctx.Param("username"), req, admin.Username)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error ForceUsername Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,176 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// ProfileHandlrStub records the account changes passed on to the service.
+type ProfileHandlrStub struct {
+	UserHandlrSuccess
+	username     string
+	by           string
+	ip           string
+	sessionToken string
+	err          error
+}
+
+func (h *ProfileHandlrStub) PutProfile(ctx context.Context, username string, req RequestProfile) (ResponseUser, error) {
+	h.username = username
+	return ResponseUser{Username: username, Fullname: req.Fullname}, h.err
+}
+
+func (h *ProfileHandlrStub) ChangeEmail(ctx context.Context, username string, req RequestChangeEmail) (ResponseUser, error) {
+	h.username, h.ip = username, req.IP
+	return ResponseUser{Username: username, Email: req.Email}, h.err
+}
+
+func (h *ProfileHandlrStub) ChangePassword(ctx context.Context, username string, req RequestChangePassword, sessionToken string) error {
+	h.username, h.ip, h.sessionToken = username, req.IP, sessionToken
+	return h.err
+}
+
+func (h *ProfileHandlrStub) ForceUsername(ctx context.Context, username string, req RequestChangeUsername, by string) (ResponseUser, error) {
+	h.username, h.by = username, by
+	return ResponseUser{Username: req.Username}, h.err
+}
+
+func newProfileContext(method string, body string, user *ResponseUser) (echo.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(method, "/users/reader/password", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(echo.HeaderXRealIP, "10.0.0.1")
+	req.Header.Set(SessionHeader, "session-token")
+	rec := httptest.NewRecorder()
+	ctx := echo.New().NewContext(req, rec)
+	ctx.SetParamNames("username")
+	ctx.SetParamValues("reader")
+	if user != nil {
+		ctx.Set(ctxUserKey, *user)
+	}
+	return ctx, rec
+}
+
+func TestPutProfileHandler(t *testing.T) {
+	t.Run("TestPutProfileHandlerShouldReturnHTTPStatus403ForOtherUser", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newProfileContext(http.MethodPut, `{"fullname":"New Name"}`, &ResponseUser{Username: "intruder"})
+		stub := &ProfileHandlrStub{}
+		handler := NewUserHandler(stub, logrus.New())
+
+		// Act
+		err := handler.PutProfile(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Empty(t, stub.username)
+	})
+}
+
+func TestChangeEmailHandler(t *testing.T) {
+	t.Run("TestChangeEmailHandlerShouldPassClientIP", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newProfileContext(http.MethodPut, `{"email":"new@email.com","password":"123456"}`, &ResponseUser{Username: "reader"})
+		stub := &ProfileHandlrStub{}
+		handler := NewUserHandler(stub, logrus.New())
+
+		// Act
+		err := handler.ChangeEmail(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "reader", stub.username)
+		assert.Equal(t, "10.0.0.1", stub.ip)
+	})
+}
+
+func TestChangePasswordHandler(t *testing.T) {
+	t.Run("TestChangePasswordHandlerShouldPassSessionToken", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newProfileContext(http.MethodPut, `{"current_password":"123456","new_password":"n3w-passw0rd"}`, &ResponseUser{Username: "reader"})
+		stub := &ProfileHandlrStub{}
+		handler := NewUserHandler(stub, logrus.New())
+
+		// Act
+		err := handler.ChangePassword(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "session-token", stub.sessionToken)
+	})
+
+	t.Run("TestChangePasswordHandlerShouldReturnHTTPStatus403WhenCalledWithAPIKey", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newProfileContext(http.MethodPut, `{"current_password":"123456","new_password":"n3w-passw0rd"}`, &ResponseUser{Username: "reader"})
+		ctx.Set(ctxAPIKeyKey, ResponseAPIKey{Id: 1, Username: "reader", Scopes: []string{"users:write"}})
+		stub := &ProfileHandlrStub{}
+		handler := NewUserHandler(stub, logrus.New())
+
+		// Act
+		err := handler.ChangePassword(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Empty(t, stub.username)
+	})
+
+	t.Run("TestChangePasswordHandlerShouldReturnServiceStatus", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newProfileContext(http.MethodPut, `{"current_password":"wrong","new_password":"n3w-passw0rd"}`, &ResponseUser{Username: "reader"})
+		stub := &ProfileHandlrStub{err: &c.Err{Code: http.StatusForbidden}}
+		handler := NewUserHandler(stub, logrus.New())
+
+		// Act
+		err := handler.ChangePassword(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
+
+func TestForceUsernameHandler(t *testing.T) {
+	t.Run("TestForceUsernameHandlerShouldRenameAsCurrentUser", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newProfileContext(http.MethodPut, `{"username":"renamed"}`, &ResponseUser{Username: "admin", Role: UserRoleAdmin})
+		stub := &ProfileHandlrStub{}
+		handler := NewUserHandler(stub, logrus.New())
+
+		// Act
+		err := handler.ForceUsername(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "reader", stub.username)
+		assert.Equal(t, "admin", stub.by)
+	})
+
+	t.Run("TestForceUsernameHandlerShouldReturnHTTPStatus401WithoutUser", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newProfileContext(http.MethodPut, `{"username":"renamed"}`, nil)
+		stub := &ProfileHandlrStub{}
+		handler := NewUserHandler(stub, logrus.New())
+
+		// Act
+		err := handler.ForceUsername(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Empty(t, stub.username)
+	})
+}
@@ -0,0 +1,209 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/mail"
+	"regexp"
+	"strings"
+
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/paquesqueue/bookstore/tracing"
+	"github.com/paquesqueue/bookstore/utils"
+)
+
+// usernamePattern is what a user may be renamed to: names that are safe
+// in a URL path and in the otpauth label of an authenticator app.
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]{3,32}$`)
+
+// PutProfile changes a user's full name, leaving the rest of the account
+// alone.
+func (s UserServices) PutProfile(ctx context.Context, username string, req RequestProfile) (ResponseUser, error) {
+	ctx, span := tracing.Start(ctx, "UserServices.PutProfile")
+	defer span.End()
+
+	return s.setFullname(ctx, username, req.Fullname, username, "Error PutProfile Service")
+}
+
+// ChangeEmail moves users to a new email address once they give their
+// password. The new address must be verified again.
+func (s UserServices) ChangeEmail(ctx context.Context, username string, req RequestChangeEmail) (ResponseUser, error) {
+	ctx, span := tracing.Start(ctx, "UserServices.ChangeEmail")
+	defer span.End()
+
+	if _, err := s.reauthenticate(ctx, username, req.Password, req.IP, "Error ChangeEmail Service"); err != nil {
+		return ResponseUser{}, err
+	}
+	return s.setEmail(ctx, username, req.Email, username, "Error ChangeEmail Service")
+}
+
+// ForceEmail is ChangeEmail for an admin, by, who needs no password.
+func (s UserServices) ForceEmail(ctx context.Context, username string, req RequestChangeEmail, by string) (ResponseUser, error) {
+	ctx, span := tracing.Start(ctx, "UserServices.ForceEmail")
+	defer span.End()
+
+	return s.setEmail(ctx, username, req.Email, by, "Error ForceEmail Service")
+}
+
+// ChangePassword sets a new password once users give their current one.
+// Their other sessions end; sessionToken is the one to keep.
+func (s UserServices) ChangePassword(ctx context.Context, username string, req RequestChangePassword, sessionToken string) error {
+	ctx, span := tracing.Start(ctx, "UserServices.ChangePassword")
+	defer span.End()
+
+	user, err := s.reauthenticate(ctx, username, req.CurrentPassword, req.IP, "Error ChangePassword Service")
+	if err != nil {
+		return err
+	}
+	keep := ""
+	if sessionToken != "" {
+		keep = utils.HashToken(sessionToken)
+	}
+	return s.setPassword(ctx, user, req.NewPassword, keep, username, "Error ChangePassword Service")
+}
+
+// ForcePassword is ChangePassword for an admin, by, who needs no current
+// password. Every session of the user ends.
+func (s UserServices) ForcePassword(ctx context.Context, username string, req RequestChangePassword, by string) error {
+	ctx, span := tracing.Start(ctx, "UserServices.ForcePassword")
+	defer span.End()
+
+	user, err := s.query.SelectUser(ctx, username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return &c.Err{Code: http.StatusNotFound, Remark: "Error User Not Found", Original: err}
+		}
+		c.LogFrom(ctx, s.log).Errorf("Error SelectUser : %v", err)
+		return &c.Err{Code: http.StatusInternalServerError, Remark: "Error ForcePassword Service", Original: err}
+	}
+	return s.setPassword(ctx, user, req.NewPassword, "", by, "Error ForcePassword Service")
+}
+
+// ChangeUsername renames users once they give their password. Their
+// sessions, keys and other records follow the new name.
+func (s UserServices) ChangeUsername(ctx context.Context, username string, req RequestChangeUsername) (ResponseUser, error) {
+	ctx, span := tracing.Start(ctx, "UserServices.ChangeUsername")
+	defer span.End()
+
+	if _, err := s.reauthenticate(ctx, username, req.Password, req.IP, "Error ChangeUsername Service"); err != nil {
+		return ResponseUser{}, err
+	}
+	return s.rename(ctx, username, req.Username, username, "Error ChangeUsername Service")
+}
+
+// ForceUsername is ChangeUsername for an admin, by, who needs no password.
+func (s UserServices) ForceUsername(ctx context.Context, username string, req RequestChangeUsername, by string) (ResponseUser, error) {
+	ctx, span := tracing.Start(ctx, "UserServices.ForceUsername")
+	defer span.End()
+
+	return s.rename(ctx, username, req.Username, by, "Error ForceUsername Service")
+}
+
+func (s UserServices) setFullname(ctx context.Context, username string, fullname string, by string, remark string) (ResponseUser, error) {
+	fullname = strings.TrimSpace(fullname)
+	if fullname == "" {
+		return ResponseUser{}, &c.Err{Code: http.StatusBadRequest, Remark: "Error Fullname Required"}
+	}
+	resp, err := s.query.UpdateUserFullname(ctx, username, fullname)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ResponseUser{}, &c.Err{Code: http.StatusNotFound, Remark: "Error User Not Found", Original: err}
+		}
+		c.LogFrom(ctx, s.log).Errorf("Error UpdateUserFullname : %v", err)
+		return ResponseUser{}, &c.Err{Code: http.StatusInternalServerError, Remark: remark, Original: err}
+	}
+	securityEvent(ctx, s.security, eventProfileChanged, username, "", c.Fields{"by": by})
+	return resp, nil
+}
+
+func (s UserServices) setEmail(ctx context.Context, username string, email string, by string, remark string) (ResponseUser, error) {
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return ResponseUser{}, &c.Err{Code: http.StatusBadRequest, Remark: "Error Invalid Email", Original: err}
+	}
+	resp, err := s.query.UpdateUserEmail(ctx, username, email)
+	if err != nil {
+		switch {
+		case err == sql.ErrNoRows:
+			return ResponseUser{}, &c.Err{Code: http.StatusNotFound, Remark: "Error User Not Found", Original: err}
+		case isPqError(err, pqUniqueViolation):
+			return ResponseUser{}, &c.Err{Code: http.StatusConflict, Remark: "Error Email Taken", Original: err}
+		}
+		c.LogFrom(ctx, s.log).Errorf("Error UpdateUserEmail : %v", err)
+		return ResponseUser{}, &c.Err{Code: http.StatusInternalServerError, Remark: remark, Original: err}
+	}
+	if resp.EmailVerifiedAt == nil {
+		s.sendVerification(ctx, resp)
+	}
+	securityEvent(ctx, s.security, eventEmailChanged, username, "", c.Fields{"by": by})
+	return resp, nil
+}
+
+func (s UserServices) setPassword(ctx context.Context, user ResponseUser, password string, keepSessionHash string, by string, remark string) error {
+	if password == "" {
+		return &c.Err{Code: http.StatusBadRequest, Remark: "Error New Password Required"}
+	}
+	if err := checkPassword(ctx, s.policy, s.log, password, user.Username, user.Email, remark); err != nil {
+		return err
+	}
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error Hash Password : %v", err)
+		return &c.Err{Code: http.StatusInternalServerError, Remark: remark, Original: err}
+	}
+	if err := s.query.ChangeUserPassword(ctx, user.Username, hashedPassword, keepSessionHash); err != nil {
+		if err == sql.ErrNoRows {
+			return &c.Err{Code: http.StatusNotFound, Remark: "Error User Not Found", Original: err}
+		}
+		c.LogFrom(ctx, s.log).Errorf("Error ChangeUserPassword : %v", err)
+		return &c.Err{Code: http.StatusInternalServerError, Remark: remark, Original: err}
+	}
+	securityEvent(ctx, s.security, eventPasswordChanged, user.Username, "", c.Fields{"by": by})
+	return nil
+}
+
+func (s UserServices) rename(ctx context.Context, username string, newUsername string, by string, remark string) (ResponseUser, error) {
+	if !usernamePattern.MatchString(newUsername) {
+		return ResponseUser{}, &c.Err{Code: http.StatusBadRequest, Remark: "Error Invalid Username"}
+	}
+	resp, err := s.query.RenameUser(ctx, username, newUsername)
+	if err != nil {
+		switch {
+		case err == sql.ErrNoRows:
+			return ResponseUser{}, &c.Err{Code: http.StatusNotFound, Remark: "Error User Not Found", Original: err}
+		case isPqError(err, pqUniqueViolation):
+			return ResponseUser{}, &c.Err{Code: http.StatusConflict, Remark: "Error Username Taken", Original: err}
+		}
+		c.LogFrom(ctx, s.log).Errorf("Error RenameUser : %v", err)
+		return ResponseUser{}, &c.Err{Code: http.StatusInternalServerError, Remark: remark, Original: err}
+	}
+	securityEvent(ctx, s.security, eventUsernameChanged, newUsername, "", c.Fields{"by": by, "from": username})
+	return resp, nil
+}
+
+// reauthenticate checks the password of a signed-in user about to change
+// their account. Wrong passwords count towards a lockout like failed
+// logins, so a stolen session cannot be used to guess it.
+func (s UserServices) reauthenticate(ctx context.Context, username string, password string, ip string, remark string) (ResponseUser, error) {
+	if password == "" {
+		return ResponseUser{}, &c.Err{Code: http.StatusBadRequest, Remark: "Error Password Required"}
+	}
+	login := RequestLogin{Username: username, IP: ip}
+	if err := s.lockout().checkLoginLocks(ctx, login, remark); err != nil {
+		return ResponseUser{}, err
+	}
+	user, err := s.query.SelectUser(ctx, username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ResponseUser{}, &c.Err{Code: http.StatusNotFound, Remark: "Error User Not Found", Original: err}
+		}
+		c.LogFrom(ctx, s.log).Errorf("Error SelectUser : %v", err)
+		return ResponseUser{}, &c.Err{Code: http.StatusInternalServerError, Remark: remark, Original: err}
+	}
+	if err := utils.CheckPassword(user.HashedPassword, password); err != nil {
+		s.lockout().loginFailed(ctx, eventReauthFailed, login)
+		return ResponseUser{}, &c.Err{Code: http.StatusForbidden, Remark: "Error Invalid Password", Original: err}
+	}
+	return user, nil
+}
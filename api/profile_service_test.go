@@ -0,0 +1,286 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/lib/pq"
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/paquesqueue/bookstore/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// ProfileQueriesStub records account changes on top of the login stub,
+// whose user "tester" has the password "123456".
+type ProfileQueriesStub struct {
+	*LoginQueriesStub
+	fullname    string
+	email       string
+	password    string
+	keepSession string
+	renamed     string
+	taken       bool
+}
+
+func (q *ProfileQueriesStub) UpdateUserFullname(ctx context.Context, username string, fullname string) (ResponseUser, error) {
+	q.fullname = fullname
+	return ResponseUser{Username: username, Fullname: fullname}, nil
+}
+
+func (q *ProfileQueriesStub) UpdateUserEmail(ctx context.Context, username string, email string) (ResponseUser, error) {
+	if q.taken {
+		return ResponseUser{}, &pq.Error{Code: pqUniqueViolation}
+	}
+	q.email = email
+	return ResponseUser{Username: username, Email: email}, nil
+}
+
+func (q *ProfileQueriesStub) ChangeUserPassword(ctx context.Context, username string, hashedPassword string, keepSessionHash string) error {
+	q.password, q.keepSession = hashedPassword, keepSessionHash
+	return nil
+}
+
+func (q *ProfileQueriesStub) RenameUser(ctx context.Context, username string, newUsername string) (ResponseUser, error) {
+	if q.taken {
+		return ResponseUser{}, &pq.Error{Code: pqUniqueViolation}
+	}
+	q.renamed = newUsername
+	return ResponseUser{Username: newUsername}, nil
+}
+
+func newProfileQueriesStub(t *testing.T) *ProfileQueriesStub {
+	return &ProfileQueriesStub{LoginQueriesStub: newLoginQueriesStub(t)}
+}
+
+func TestPutProfile(t *testing.T) {
+	t.Run("TestPutProfileShouldOnlyChangeFullname", func(t *testing.T) {
+		// Arrange
+		query := newProfileQueriesStub(t)
+		services, hook := newCredentialServices(query)
+
+		// Act
+		user, err := services.PutProfile(context.Background(), "tester", RequestProfile{Fullname: "  New Name "})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "New Name", user.Fullname)
+		assert.Empty(t, query.password)
+		if entry := hook.LastEntry(); assert.NotNil(t, entry) {
+			assert.Equal(t, eventProfileChanged, entry.Data["event"])
+		}
+	})
+
+	t.Run("TestPutProfileShouldReturnHTTPStatus400WithoutFullname", func(t *testing.T) {
+		// Arrange
+		services, _ := newCredentialServices(newProfileQueriesStub(t))
+
+		// Act
+		_, err := services.PutProfile(context.Background(), "tester", RequestProfile{Fullname: " "})
+
+		// Assert
+		if assert.IsType(t, &c.Err{}, err) {
+			assert.Equal(t, http.StatusBadRequest, err.(*c.Err).Code)
+		}
+	})
+}
+
+func TestChangeEmail(t *testing.T) {
+	t.Run("TestChangeEmailShouldSendVerification", func(t *testing.T) {
+		// Arrange
+		query := newProfileQueriesStub(t)
+		services, hook := newCredentialServices(query)
+		req := RequestChangeEmail{Email: "new@email.com", Password: "123456"}
+
+		// Act
+		_, err := services.ChangeEmail(context.Background(), "tester", req)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "new@email.com", query.email)
+		assert.Len(t, services.verifier.(*EmailVerifierStub).sent, 1)
+		if entry := hook.LastEntry(); assert.NotNil(t, entry) {
+			assert.Equal(t, eventEmailChanged, entry.Data["event"])
+			assert.Equal(t, "tester", entry.Data["by"])
+		}
+	})
+
+	t.Run("TestChangeEmailShouldReturnHTTPStatus403AndCountWrongPassword", func(t *testing.T) {
+		// Arrange
+		query := newProfileQueriesStub(t)
+		services, hook := newCredentialServices(query)
+		req := RequestChangeEmail{Email: "new@email.com", Password: "wrong", IP: "10.0.0.1"}
+
+		// Act
+		_, err := services.ChangeEmail(context.Background(), "tester", req)
+
+		// Assert
+		if assert.IsType(t, &c.Err{}, err) {
+			assert.Equal(t, http.StatusForbidden, err.(*c.Err).Code)
+		}
+		assert.Empty(t, query.email)
+		assert.Equal(t, 1, query.failures["user:tester"])
+		assert.Equal(t, 1, query.failures["ip:10.0.0.1"])
+		if entry := hook.LastEntry(); assert.NotNil(t, entry) {
+			assert.Equal(t, eventReauthFailed, entry.Data["event"])
+		}
+	})
+
+	t.Run("TestChangeEmailShouldReturnHTTPStatus400ForInvalidEmail", func(t *testing.T) {
+		// Arrange
+		query := newProfileQueriesStub(t)
+		services, _ := newCredentialServices(query)
+		req := RequestChangeEmail{Email: "Tester <new@email.com>", Password: "123456"}
+
+		// Act
+		_, err := services.ChangeEmail(context.Background(), "tester", req)
+
+		// Assert
+		if assert.IsType(t, &c.Err{}, err) {
+			assert.Equal(t, http.StatusBadRequest, err.(*c.Err).Code)
+		}
+		assert.Empty(t, query.email)
+	})
+}
+
+func TestForceEmail(t *testing.T) {
+	t.Run("TestForceEmailShouldReturnHTTPStatus409ForTakenEmail", func(t *testing.T) {
+		// Arrange
+		query := newProfileQueriesStub(t)
+		query.taken = true
+		services, _ := newCredentialServices(query)
+
+		// Act
+		_, err := services.ForceEmail(context.Background(), "tester", RequestChangeEmail{Email: "taken@email.com"}, "admin")
+
+		// Assert
+		if assert.IsType(t, &c.Err{}, err) {
+			assert.Equal(t, http.StatusConflict, err.(*c.Err).Code)
+		}
+	})
+}
+
+func TestChangePassword(t *testing.T) {
+	t.Run("TestChangePasswordShouldKeepCurrentSession", func(t *testing.T) {
+		// Arrange
+		query := newProfileQueriesStub(t)
+		services, hook := newCredentialServices(query)
+		req := RequestChangePassword{CurrentPassword: "123456", NewPassword: "n3w-passw0rd"}
+
+		// Act
+		err := services.ChangePassword(context.Background(), "tester", req, "session-token")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NoError(t, utils.CheckPassword(query.password, "n3w-passw0rd"))
+		assert.Equal(t, utils.HashToken("session-token"), query.keepSession)
+		if entry := hook.LastEntry(); assert.NotNil(t, entry) {
+			assert.Equal(t, eventPasswordChanged, entry.Data["event"])
+		}
+	})
+
+	t.Run("TestChangePasswordShouldReturnHTTPStatus429WhenLockedOut", func(t *testing.T) {
+		// Arrange
+		query := newProfileQueriesStub(t)
+		query.locks["user:tester"] = loginNow
+		services, _ := newCredentialServices(query)
+		req := RequestChangePassword{CurrentPassword: "123456", NewPassword: "n3w-passw0rd"}
+
+		// Act
+		err := services.ChangePassword(context.Background(), "tester", req, "session-token")
+
+		// Assert
+		if assert.IsType(t, &c.Err{}, err) {
+			assert.Equal(t, http.StatusTooManyRequests, err.(*c.Err).Code)
+		}
+		assert.Empty(t, query.password)
+	})
+}
+
+func TestForcePassword(t *testing.T) {
+	t.Run("TestForcePasswordShouldEndEverySession", func(t *testing.T) {
+		// Arrange
+		query := newProfileQueriesStub(t)
+		services, hook := newCredentialServices(query)
+
+		// Act
+		err := services.ForcePassword(context.Background(), "tester", RequestChangePassword{NewPassword: "n3w-passw0rd"}, "admin")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NotEmpty(t, query.password)
+		assert.Empty(t, query.keepSession)
+		if entry := hook.LastEntry(); assert.NotNil(t, entry) {
+			assert.Equal(t, "admin", entry.Data["by"])
+		}
+	})
+
+	t.Run("TestForcePasswordShouldReturnHTTPStatus404ForUnknownUser", func(t *testing.T) {
+		// Arrange
+		services, _ := newCredentialServices(newProfileQueriesStub(t))
+
+		// Act
+		err := services.ForcePassword(context.Background(), "nobody", RequestChangePassword{NewPassword: "n3w-passw0rd"}, "admin")
+
+		// Assert
+		if assert.IsType(t, &c.Err{}, err) {
+			assert.Equal(t, http.StatusNotFound, err.(*c.Err).Code)
+		}
+	})
+}
+
+func TestChangeUsername(t *testing.T) {
+	t.Run("TestChangeUsernameShouldRename", func(t *testing.T) {
+		// Arrange
+		query := newProfileQueriesStub(t)
+		services, hook := newCredentialServices(query)
+
+		// Act
+		user, err := services.ChangeUsername(context.Background(), "tester", RequestChangeUsername{Username: "renamed", Password: "123456"})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "renamed", user.Username)
+		if entry := hook.LastEntry(); assert.NotNil(t, entry) {
+			assert.Equal(t, eventUsernameChanged, entry.Data["event"])
+			assert.Equal(t, "renamed", entry.Data["username"])
+			assert.Equal(t, "tester", entry.Data["from"])
+		}
+	})
+
+	t.Run("TestChangeUsernameShouldReturnHTTPStatus400ForInvalidUsername", func(t *testing.T) {
+		for _, username := range []string{"", "ab", "has space", "slash/name", "colon:name"} {
+			// Arrange
+			query := newProfileQueriesStub(t)
+			services, _ := newCredentialServices(query)
+
+			// Act
+			_, err := services.ChangeUsername(context.Background(), "tester", RequestChangeUsername{Username: username, Password: "123456"})
+
+			// Assert
+			if assert.IsType(t, &c.Err{}, err, username) {
+				assert.Equal(t, http.StatusBadRequest, err.(*c.Err).Code, username)
+			}
+			assert.Empty(t, query.renamed)
+		}
+	})
+}
+
+func TestForceUsername(t *testing.T) {
+	t.Run("TestForceUsernameShouldReturnHTTPStatus409ForTakenUsername", func(t *testing.T) {
+		// Arrange
+		query := newProfileQueriesStub(t)
+		query.taken = true
+		services, _ := newCredentialServices(query)
+
+		// Act
+		_, err := services.ForceUsername(context.Background(), "tester", RequestChangeUsername{Username: "taken"}, "admin")
+
+		// Assert
+		if assert.IsType(t, &c.Err{}, err) {
+			assert.Equal(t, http.StatusConflict, err.(*c.Err).Code)
+		}
+	})
+}
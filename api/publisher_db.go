@@ -0,0 +1,160 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+func (db Query) InsertPublisher(ctx context.Context, req RequestPublisher) (ResponsePublisher, error) {
+	const query = `INSERT INTO publishers
+	(name, name_key)
+	VALUES ($1, $2)
+	RETURNING id, name, created_at;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return ResponsePublisher{}, err
+	}
+	defer stmt.Close()
+
+	row := stmt.QueryRowContext(ctx, strings.TrimSpace(req.Name), nameKey(req.Name))
+	resp := ResponsePublisher{}
+	err = row.Scan(&resp.Id, &resp.Name, &resp.CreatedAt)
+	if err != nil {
+		return ResponsePublisher{}, err
+	}
+	return resp, nil
+}
+
+func (db Query) SelectAllPublishers(ctx context.Context, params GetAllParams) ([]ResponsePublisher, error) {
+	const query = `SELECT id, name, created_at
+	FROM publishers
+	ORDER BY name, id
+	LIMIT $1
+	OFFSET $2;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, params.Limit, params.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resp := []ResponsePublisher{}
+	for rows.Next() {
+		result := ResponsePublisher{}
+		err = rows.Scan(&result.Id, &result.Name, &result.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		resp = append(resp, result)
+	}
+	return resp, rows.Err()
+}
+
+func (db Query) SelectPublisher(ctx context.Context, id uint64) (ResponsePublisher, error) {
+	const query = `SELECT id, name, created_at FROM publishers WHERE id = $1;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return ResponsePublisher{}, err
+	}
+	defer stmt.Close()
+
+	resp := ResponsePublisher{}
+	err = stmt.QueryRowContext(ctx, id).Scan(&resp.Id, &resp.Name, &resp.CreatedAt)
+	if err != nil {
+		return ResponsePublisher{}, err
+	}
+	return resp, nil
+}
+
+// UpdatePublisher renames a publisher and the publisher column of every book
+// linked to it.
+func (db Query) UpdatePublisher(ctx context.Context, id uint64, req RequestPublisher) (ResponsePublisher, error) {
+	const query = `UPDATE publishers
+	SET name = $1, name_key = $2
+	WHERE id = $3
+	RETURNING id, name, created_at;`
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return ResponsePublisher{}, err
+	}
+	defer tx.Rollback()
+
+	resp := ResponsePublisher{}
+	err = tx.QueryRow(query, strings.TrimSpace(req.Name), nameKey(req.Name), id).Scan(&resp.Id, &resp.Name, &resp.CreatedAt)
+	if err != nil {
+		return ResponsePublisher{}, err
+	}
+
+	_, err = tx.Exec(`UPDATE books SET publisher = $1 WHERE publisher_id = $2;`, resp.Name, id)
+	if err != nil {
+		return ResponsePublisher{}, err
+	}
+	return resp, tx.Commit()
+}
+
+func (db Query) DeletePublisher(ctx context.Context, id uint64) error {
+	const query = `DELETE FROM publishers WHERE id = $1;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (db Query) SelectBooksByPublisher(ctx context.Context, id uint64, params GetAllParams) ([]ResponseBook, error) {
+	const query = `SELECT id, title, authors, publisher, isbn, price, quantity, created_by, created_at
+	FROM books
+	WHERE publisher_id = $1
+	ORDER BY id
+	LIMIT $2
+	OFFSET $3;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, id, params.Limit, params.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resp := []ResponseBook{}
+	for rows.Next() {
+		r := ResponseBook{}
+		err = rows.Scan(&r.Id, &r.Title, pq.Array(&r.Authors), &r.Publisher, &r.Isbn, &r.Price, &r.Quantity, &r.Created_by, &r.Created_at)
+		if err != nil {
+			return nil, err
+		}
+		resp = append(resp, r)
+	}
+	return resp, rows.Err()
+}
@@ -0,0 +1,115 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInsertPublisher(t *testing.T) {
+	t.Run("TestInsertPublisherShouldReturnNoError", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		row := sqlmock.NewRows([]string{"id", "name", "created_at"}).AddRow(1, "Sample Press", time.Now())
+		get := mock.ExpectPrepare(regexp.QuoteMeta(`INSERT INTO publishers (name, name_key) VALUES ($1, $2) RETURNING id, name, created_at;`))
+		get.ExpectQuery().
+			WithArgs("Sample Press", "samplepress").
+			WillReturnRows(row)
+
+		query := NewDB(db)
+
+		// Act
+		result, err := query.InsertPublisher(context.Background(), RequestPublisher{Name: "Sample Press"})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(1), result.Id)
+	})
+}
+
+func TestUpdatePublisher(t *testing.T) {
+	t.Run("TestUpdatePublisherShouldRenameBooks", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE publishers SET name = $1, name_key = $2 WHERE id = $3`)).
+			WithArgs("Sample Press Ltd", "samplepressltd", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "created_at"}).AddRow(1, "Sample Press Ltd", time.Now()))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE books SET publisher = $1 WHERE publisher_id = $2;`)).
+			WithArgs("Sample Press Ltd", 1).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		query := NewDB(db)
+
+		// Act
+		result, err := query.UpdatePublisher(context.Background(), 1, RequestPublisher{Name: "Sample Press Ltd"})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "Sample Press Ltd", result.Name)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDeletePublisher(t *testing.T) {
+	t.Run("TestDeletePublisherShouldReturnErrNoRowsWhenMissing", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		get := mock.ExpectPrepare(regexp.QuoteMeta(`DELETE FROM publishers WHERE id = $1;`))
+		get.ExpectExec().
+			WithArgs(1).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		query := NewDB(db)
+
+		// Act
+		err = query.DeletePublisher(context.Background(), 1)
+
+		// Assert
+		assert.Equal(t, sql.ErrNoRows, err)
+	})
+}
+
+func TestSelectBooksByPublisher(t *testing.T) {
+	t.Run("TestSelectBooksByPublisherShouldReturnNoError", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		rows := sqlmock.NewRows([]string{"id", "title", "authors", "publisher", "isbn", "price", "quantity", "created_by", "created_at"}).
+			AddRow(1, "mockTitle", pq.Array([]string{"mockAuthors"}), "Sample Press", "1234567890", 1000, 10, "Admin", time.Now())
+		get := mock.ExpectPrepare(regexp.QuoteMeta(`FROM books WHERE publisher_id = $1 ORDER BY id LIMIT $2 OFFSET $3;`))
+		get.ExpectQuery().
+			WithArgs(1, 10, 0).
+			WillReturnRows(rows)
+
+		query := NewDB(db)
+
+		// Act
+		results, err := query.SelectBooksByPublisher(context.Background(), 1, GetAllParams{Limit: 10})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Len(t, results, 1)
+		assert.Equal(t, "Sample Press", results[0].Publisher)
+	})
+}
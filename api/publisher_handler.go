@@ -0,0 +1,154 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	c "github.com/paquesqueue/bookstore/common"
+)
+
+type PublisherHandlrQueries interface {
+	AddPublisher(ctx context.Context, req RequestPublisher) (ResponsePublisher, error)
+	ListPublishers(ctx context.Context, params GetAllParams) ([]ResponsePublisher, error)
+	GetPublisher(ctx context.Context, id uint64) (ResponsePublisher, error)
+	PutPublisher(ctx context.Context, id uint64, req RequestPublisher) (ResponsePublisher, error)
+	DeletePublisher(ctx context.Context, id uint64) error
+	ListPublisherBooks(ctx context.Context, id uint64, params GetAllParams) ([]ResponseBook, error)
+}
+
+type PublisherHandlr struct {
+	handler PublisherHandlrQueries
+	log     c.Log
+}
+
+func NewPublisherHandlr(h PublisherHandlrQueries, l c.Log) PublisherHandlr {
+	return PublisherHandlr{h, l}
+}
+
+func (h PublisherHandlr) AddPublisher(ctx echo.Context) error {
+	req := RequestPublisher{}
+	err := ctx.Bind(&req)
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	res, err := h.handler.AddPublisher(ctx.Request().Context(), req)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error AddPublisher Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusCreated, res)
+}
+
+func (h PublisherHandlr) ListPublishers(ctx echo.Context) error {
+	var req RequestGetAll
+	err := ctx.Bind(&req)
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	params := GetAllParams{
+		Limit:  req.PageSize,
+		Offset: (req.PageId - 1) * req.PageSize,
+	}
+
+	res, err := h.handler.ListPublishers(ctx.Request().Context(), params)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error ListPublishers Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h PublisherHandlr) GetPublisher(ctx echo.Context) error {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	res, err := h.handler.GetPublisher(ctx.Request().Context(), uint64(id))
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error GetPublisher Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h PublisherHandlr) PutPublisher(ctx echo.Context) error {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	req := RequestPublisher{}
+	err = ctx.Bind(&req)
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	res, err := h.handler.PutPublisher(ctx.Request().Context(), uint64(id), req)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error PutPublisher Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h PublisherHandlr) DeletePublisher(ctx echo.Context) error {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	err = h.handler.DeletePublisher(ctx.Request().Context(), uint64(id))
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error DeletePublisher Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, "Deleted Successfully")
+}
+
+func (h PublisherHandlr) ListPublisherBooks(ctx echo.Context) error {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	var req RequestGetAll
+	err = ctx.Bind(&req)
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	params := GetAllParams{
+		Limit:  req.PageSize,
+		Offset: (req.PageId - 1) * req.PageSize,
+	}
+
+	res, err := h.handler.ListPublisherBooks(ctx.Request().Context(), uint64(id), params)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error ListPublisherBooks Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/paquesqueue/bookstore/tracing"
+)
+
+type PublisherQueries interface {
+	InsertPublisher(ctx context.Context, req RequestPublisher) (ResponsePublisher, error)
+	SelectAllPublishers(ctx context.Context, params GetAllParams) ([]ResponsePublisher, error)
+	SelectPublisher(ctx context.Context, id uint64) (ResponsePublisher, error)
+	UpdatePublisher(ctx context.Context, id uint64, req RequestPublisher) (ResponsePublisher, error)
+	DeletePublisher(ctx context.Context, id uint64) error
+	SelectBooksByPublisher(ctx context.Context, id uint64, params GetAllParams) ([]ResponseBook, error)
+}
+
+type PublisherServices struct {
+	query PublisherQueries
+	log   c.Log
+}
+
+func NewPublisherService(q PublisherQueries, l c.Log) PublisherServices {
+	return PublisherServices{q, l}
+}
+
+func (s PublisherServices) AddPublisher(ctx context.Context, req RequestPublisher) (ResponsePublisher, error) {
+	ctx, span := tracing.Start(ctx, "PublisherServices.AddPublisher")
+	defer span.End()
+
+	if nameKey(req.Name) == "" {
+		return ResponsePublisher{}, &c.Err{Code: http.StatusBadRequest, Remark: "Error Publisher Name Required"}
+	}
+
+	resp, err := s.query.InsertPublisher(ctx, req)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error InsertPublisher : %v", err)
+		return ResponsePublisher{}, publisherErr(err, "Error AddPublisher Service")
+	}
+	return resp, nil
+}
+
+func (s PublisherServices) ListPublishers(ctx context.Context, params GetAllParams) ([]ResponsePublisher, error) {
+	ctx, span := tracing.Start(ctx, "PublisherServices.ListPublishers")
+	defer span.End()
+
+	resp, err := s.query.SelectAllPublishers(ctx, params)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error SelectAllPublishers : %v", err)
+		return nil, &c.Err{Code: http.StatusInternalServerError, Remark: "Error ListPublishers Service", Original: err}
+	}
+	return resp, nil
+}
+
+func (s PublisherServices) GetPublisher(ctx context.Context, id uint64) (ResponsePublisher, error) {
+	ctx, span := tracing.Start(ctx, "PublisherServices.GetPublisher")
+	defer span.End()
+
+	resp, err := s.query.SelectPublisher(ctx, id)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error SelectPublisher : %v", err)
+		return ResponsePublisher{}, publisherErr(err, "Error GetPublisher Service")
+	}
+	return resp, nil
+}
+
+func (s PublisherServices) PutPublisher(ctx context.Context, id uint64, req RequestPublisher) (ResponsePublisher, error) {
+	ctx, span := tracing.Start(ctx, "PublisherServices.PutPublisher")
+	defer span.End()
+
+	if nameKey(req.Name) == "" {
+		return ResponsePublisher{}, &c.Err{Code: http.StatusBadRequest, Remark: "Error Publisher Name Required"}
+	}
+
+	resp, err := s.query.UpdatePublisher(ctx, id, req)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error UpdatePublisher : %v", err)
+		return ResponsePublisher{}, publisherErr(err, "Error PutPublisher Service")
+	}
+	return resp, nil
+}
+
+func (s PublisherServices) DeletePublisher(ctx context.Context, id uint64) error {
+	ctx, span := tracing.Start(ctx, "PublisherServices.DeletePublisher")
+	defer span.End()
+
+	err := s.query.DeletePublisher(ctx, id)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error DeletePublisher : %v", err)
+		return publisherErr(err, "Error DeletePublisher Service")
+	}
+	return nil
+}
+
+func (s PublisherServices) ListPublisherBooks(ctx context.Context, id uint64, params GetAllParams) ([]ResponseBook, error) {
+	ctx, span := tracing.Start(ctx, "PublisherServices.ListPublisherBooks")
+	defer span.End()
+
+	_, err := s.GetPublisher(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.query.SelectBooksByPublisher(ctx, id, params)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error SelectBooksByPublisher : %v", err)
+		return nil, &c.Err{Code: http.StatusInternalServerError, Remark: "Error ListPublisherBooks Service", Original: err}
+	}
+	return resp, nil
+}
+
+func publisherErr(err error, remark string) *c.Err {
+	switch {
+	case err == sql.ErrNoRows:
+		return &c.Err{Code: http.StatusNotFound, Remark: "Error Publisher Not Found", Original: err}
+	case isPqError(err, pqUniqueViolation):
+		return &c.Err{Code: http.StatusConflict, Remark: "Error Publisher Already Exists", Original: err}
+	case isPqError(err, pqForeignKeyViolation):
+		return &c.Err{Code: http.StatusConflict, Remark: "Error Publisher Still Has Books", Original: err}
+	default:
+		return &c.Err{Code: http.StatusInternalServerError, Remark: remark, Original: err}
+	}
+}
@@ -0,0 +1,105 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type PublisherQueriesStub struct {
+	err                          error
+	insertPublisherCalled        bool
+	selectBooksByPublisherCalled bool
+}
+
+func (s *PublisherQueriesStub) InsertPublisher(ctx context.Context, req RequestPublisher) (ResponsePublisher, error) {
+	s.insertPublisherCalled = true
+	return ResponsePublisher{Id: 1, Name: req.Name, CreatedAt: time.Now()}, s.err
+}
+
+func (s *PublisherQueriesStub) SelectAllPublishers(ctx context.Context, params GetAllParams) ([]ResponsePublisher, error) {
+	return []ResponsePublisher{{Id: 1, Name: "Sample Press"}}, s.err
+}
+
+func (s *PublisherQueriesStub) SelectPublisher(ctx context.Context, id uint64) (ResponsePublisher, error) {
+	return ResponsePublisher{Id: id, Name: "Sample Press"}, s.err
+}
+
+func (s *PublisherQueriesStub) UpdatePublisher(ctx context.Context, id uint64, req RequestPublisher) (ResponsePublisher, error) {
+	return ResponsePublisher{Id: id, Name: req.Name}, s.err
+}
+
+func (s *PublisherQueriesStub) DeletePublisher(ctx context.Context, id uint64) error {
+	return s.err
+}
+
+func (s *PublisherQueriesStub) SelectBooksByPublisher(ctx context.Context, id uint64, params GetAllParams) ([]ResponseBook, error) {
+	s.selectBooksByPublisherCalled = true
+	return []ResponseBook{{Id: 1, Title: "mockTitle", Publisher: "Sample Press"}}, s.err
+}
+
+func TestAddPublisherService(t *testing.T) {
+	t.Run("TestAddPublisherServiceShouldReturnNoError", func(t *testing.T) {
+		// Arrange
+		query := &PublisherQueriesStub{}
+		service := NewPublisherService(query, logrus.New())
+
+		// Act
+		resp, err := service.AddPublisher(context.Background(), RequestPublisher{Name: "Sample Press"})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.True(t, query.insertPublisherCalled)
+		assert.Equal(t, "Sample Press", resp.Name)
+	})
+
+	t.Run("TestAddPublisherServiceShouldReturnConflict", func(t *testing.T) {
+		// Arrange
+		query := &PublisherQueriesStub{err: &pq.Error{Code: pqUniqueViolation}}
+		service := NewPublisherService(query, logrus.New())
+
+		// Act
+		_, err := service.AddPublisher(context.Background(), RequestPublisher{Name: "Sample Press"})
+
+		// Assert
+		assert.Equal(t, http.StatusConflict, err.(*c.Err).Code)
+	})
+}
+
+func TestListPublisherBooksService(t *testing.T) {
+	t.Run("TestListPublisherBooksServiceShouldReturnNoError", func(t *testing.T) {
+		// Arrange
+		query := &PublisherQueriesStub{}
+		service := NewPublisherService(query, logrus.New())
+
+		// Act
+		resp, err := service.ListPublisherBooks(context.Background(), 1, GetAllParams{Limit: 10})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.True(t, query.selectBooksByPublisherCalled)
+		assert.Len(t, resp, 1)
+	})
+
+	t.Run("TestListPublisherBooksServiceShouldReturnNotFound", func(t *testing.T) {
+		// Arrange
+		query := &PublisherQueriesStub{err: sql.ErrNoRows}
+		service := NewPublisherService(query, logrus.New())
+
+		// Act
+		_, err := service.ListPublisherBooks(context.Background(), 1, GetAllParams{Limit: 10})
+
+		// Assert
+		assert.False(t, query.selectBooksByPublisherCalled)
+		assert.Equal(t, http.StatusNotFound, err.(*c.Err).Code)
+	})
+}
@@ -0,0 +1,190 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+	"github.com/paquesqueue/bookstore/recommend"
+)
+
+// userBooksQuery selects, per user, the books they showed interest in: the
+// books on their lists and the ones they rated four stars or more in an
+// approved review.
+const userBooksQuery = `SELECT l.username, i.book_id
+	FROM book_list_items i
+	JOIN book_lists l ON l.id = i.list_id
+	UNION
+	SELECT username, book_id FROM reviews WHERE status = '` + ReviewStatusApproved + `' AND rating >= 4`
+
+// SelectRecommendItems loads what content similarity compares for every book.
+func (db Query) SelectRecommendItems(ctx context.Context) ([]recommend.Item, error) {
+	const query = `SELECT b.id, b.title, b.authors, b.publisher,
+		COALESCE(ARRAY_AGG(c.category_id) FILTER (WHERE c.category_id IS NOT NULL), '{}')
+	FROM books b
+	LEFT JOIN book_categories c ON c.book_id = b.id
+	GROUP BY b.id
+	ORDER BY b.id;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resp := []recommend.Item{}
+	for rows.Next() {
+		item := recommend.Item{}
+		var categories []int64
+		err := rows.Scan(&item.Id, &item.Title, pq.Array(&item.Authors), &item.Publisher, pq.Array(&categories))
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range categories {
+			item.Categories = append(item.Categories, uint64(c))
+		}
+		resp = append(resp, item)
+	}
+	return resp, rows.Err()
+}
+
+// SelectRecommendBaskets returns one basket per user for item-to-item
+// collaborative filtering.
+func (db Query) SelectRecommendBaskets(ctx context.Context) ([]recommend.Basket, error) {
+	const query = `SELECT username, ARRAY_AGG(book_id ORDER BY book_id)
+	FROM (` + userBooksQuery + `) u
+	GROUP BY username
+	ORDER BY username;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resp := []recommend.Basket{}
+	for rows.Next() {
+		var username string
+		var books []int64
+		if err := rows.Scan(&username, pq.Array(&books)); err != nil {
+			return nil, err
+		}
+		basket := make(recommend.Basket, len(books))
+		for i, id := range books {
+			basket[i] = uint64(id)
+		}
+		resp = append(resp, basket)
+	}
+	return resp, rows.Err()
+}
+
+// ReplaceBookSimilarities swaps the whole similarity table in one
+// transaction, so readers see either the old or the new scores.
+func (db Query) ReplaceBookSimilarities(ctx context.Context, pairs []recommend.Pair) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM book_similarities;`); err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`INSERT INTO book_similarities (book_id, related_id, score, content_score, co_score) VALUES ($1, $2, $3, $4, $5);`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, p := range pairs {
+		_, err := stmt.ExecContext(ctx, p.BookId, p.RelatedId, p.Score, p.Content, p.CoOccurrence)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func scanRelatedBooks(rows *sql.Rows) ([]ResponseRelatedBook, error) {
+	defer rows.Close()
+
+	resp := []ResponseRelatedBook{}
+	for rows.Next() {
+		item := ResponseRelatedBook{}
+		var ratingSum int64
+		err := rows.Scan(&item.Id, &item.Title, pq.Array(&item.Authors), &item.Publisher, &item.Isbn, &item.Price, &item.Quantity, &item.Created_by, &item.Created_at,
+			&item.WorkId, &item.Format, &item.PublicationDate, &item.RatingCount, &ratingSum, &item.Score)
+		if err != nil {
+			return nil, err
+		}
+		item.RatingAvg = ratingAverage(ratingSum, item.RatingCount)
+		resp = append(resp, item)
+	}
+	return resp, rows.Err()
+}
+
+// SelectRelatedBooks lists the books most similar to a book, best first.
+func (db Query) SelectRelatedBooks(ctx context.Context, bookId uint64, params GetAllParams) ([]ResponseRelatedBook, error) {
+	const query = `SELECT ` + bookColumns + `, s.score
+	FROM book_similarities s
+	JOIN books b ON b.id = s.related_id
+	WHERE s.book_id = $1
+	ORDER BY s.score DESC, s.related_id
+	LIMIT $2
+	OFFSET $3;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, bookId, params.Limit, params.Offset)
+	if err != nil {
+		return nil, err
+	}
+	return scanRelatedBooks(rows)
+}
+
+// SelectUserRecommendations ranks the books related to the ones a user showed
+// interest in by their summed similarity, leaving out books the user already
+// has.
+func (db Query) SelectUserRecommendations(ctx context.Context, username string, params GetAllParams) ([]ResponseRelatedBook, error) {
+	const query = `WITH seeds AS (
+		SELECT book_id FROM (` + userBooksQuery + `) u WHERE username = $1
+	)
+	SELECT ` + bookColumns + `, SUM(s.score) AS total
+	FROM book_similarities s
+	JOIN books b ON b.id = s.related_id
+	WHERE s.book_id IN (SELECT book_id FROM seeds)
+		AND s.related_id NOT IN (SELECT book_id FROM seeds)
+	GROUP BY b.id
+	ORDER BY total DESC, b.id
+	LIMIT $2
+	OFFSET $3;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, username, params.Limit, params.Offset)
+	if err != nil {
+		return nil, err
+	}
+	return scanRelatedBooks(rows)
+}
@@ -0,0 +1,130 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/paquesqueue/bookstore/recommend"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectRecommendItems(t *testing.T) {
+	t.Run("TestSelectRecommendItemsShouldReturnBooksWithCategories", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectPrepare(regexp.QuoteMeta(`SELECT b.id, b.title, b.authors, b.publisher,`)).
+			ExpectQuery().
+			WillReturnRows(sqlmock.NewRows([]string{"id", "title", "authors", "publisher", "categories"}).
+				AddRow(1, "Dune", pq.Array([]string{"Frank Herbert"}), "Ace", "{3,5}").
+				AddRow(2, "Emma", pq.Array([]string{"Jane Austen"}), "", "{}"))
+
+		query := NewDB(db)
+
+		// Act
+		res, err := query.SelectRecommendItems(context.Background())
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, []recommend.Item{
+			{Id: 1, Title: "Dune", Authors: []string{"Frank Herbert"}, Publisher: "Ace", Categories: []uint64{3, 5}},
+			{Id: 2, Title: "Emma", Authors: []string{"Jane Austen"}},
+		}, res)
+	})
+}
+
+func TestSelectRecommendBaskets(t *testing.T) {
+	t.Run("TestSelectRecommendBasketsShouldReturnOneBasketPerUser", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectPrepare(regexp.QuoteMeta(`SELECT username, ARRAY_AGG(book_id ORDER BY book_id) FROM (SELECT l.username, i.book_id FROM book_list_items i`)).
+			ExpectQuery().
+			WillReturnRows(sqlmock.NewRows([]string{"username", "books"}).
+				AddRow("ann", "{1,2}").
+				AddRow("bob", "{2,7}"))
+
+		query := NewDB(db)
+
+		// Act
+		res, err := query.SelectRecommendBaskets(context.Background())
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, []recommend.Basket{{1, 2}, {2, 7}}, res)
+	})
+}
+
+func TestReplaceBookSimilarities(t *testing.T) {
+	t.Run("TestReplaceBookSimilaritiesShouldSwapTableInTransaction", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		pairs := []recommend.Pair{
+			{BookId: 1, RelatedId: 2, Content: 0.4, CoOccurrence: 0.5, Score: 0.9},
+			{BookId: 2, RelatedId: 1, Content: 0.4, CoOccurrence: 0.5, Score: 0.9},
+		}
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM book_similarities;`)).
+			WillReturnResult(sqlmock.NewResult(0, 10))
+		insert := mock.ExpectPrepare(regexp.QuoteMeta(`INSERT INTO book_similarities (book_id, related_id, score, content_score, co_score) VALUES ($1, $2, $3, $4, $5);`))
+		for _, p := range pairs {
+			insert.ExpectExec().
+				WithArgs(p.BookId, p.RelatedId, p.Score, p.Content, p.CoOccurrence).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+		mock.ExpectCommit()
+
+		query := NewDB(db)
+
+		// Act
+		err = query.ReplaceBookSimilarities(context.Background(), pairs)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestSelectRelatedBooks(t *testing.T) {
+	t.Run("TestSelectRelatedBooksShouldReturnScoredBooks", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		row := sqlmock.NewRows([]string{"id", "title", "authors", "publisher", "isbn", "price", "quantity", "created_by", "created_at", "work_id", "format", "publication_date", "rating_count", "rating_sum", "score"}).
+			AddRow(2, "Children of Dune", pq.Array([]string{"Frank Herbert"}), "Putnam", "isbn", 1000, 1, "Admin", time.Now(), 2, "", "", 2, 9, 0.75)
+
+		mock.ExpectPrepare(regexp.QuoteMeta(`SELECT id, title, authors, publisher, isbn, price, quantity, created_by, created_at, work_id, format, COALESCE(to_char(publication_date, 'YYYY-MM-DD'), ''), rating_count, rating_sum, s.score FROM book_similarities s JOIN books b ON b.id = s.related_id WHERE s.book_id = $1 ORDER BY s.score DESC, s.related_id LIMIT $2 OFFSET $3;`)).
+			ExpectQuery().
+			WithArgs(1, 10, 0).
+			WillReturnRows(row)
+
+		query := NewDB(db)
+
+		// Act
+		res, err := query.SelectRelatedBooks(context.Background(), 1, GetAllParams{Limit: 10, Offset: 0})
+
+		// Assert
+		assert.NoError(t, err)
+		if assert.Len(t, res, 1) {
+			assert.Equal(t, uint64(2), res[0].Id)
+			assert.Equal(t, 0.75, res[0].Score)
+			assert.Equal(t, 4.5, res[0].RatingAvg)
+		}
+	})
+}
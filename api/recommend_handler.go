@@ -0,0 +1,83 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	c "github.com/paquesqueue/bookstore/common"
+)
+
+type RecommendHandlrQueries interface {
+	GetRelatedBooks(ctx context.Context, bookId uint64, params GetAllParams) ([]ResponseRelatedBook, error)
+	GetRecommendations(ctx context.Context, username string, params GetAllParams) ([]ResponseRelatedBook, error)
+}
+
+type RecommendHandlr struct {
+	handler RecommendHandlrQueries
+	log     c.Log
+}
+
+func NewRecommendHandlr(h RecommendHandlrQueries, l c.Log) RecommendHandlr {
+	return RecommendHandlr{h, l}
+}
+
+func (h RecommendHandlr) GetRelatedBooks(ctx echo.Context) error {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	var req RequestGetAll
+	err = ctx.Bind(&req)
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	params := GetAllParams{
+		Limit:  req.PageSize,
+		Offset: (req.PageId - 1) * req.PageSize,
+	}
+
+	res, err := h.handler.GetRelatedBooks(ctx.Request().Context(), uint64(id), params)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error GetRelatedBooks Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, res)
+}
+
+// GetRecommendations is built from a user's private lists, so only the user
+// and staff may read it.
+func (h RecommendHandlr) GetRecommendations(ctx echo.Context) error {
+	user, _ := CurrentUser(ctx)
+	username := ctx.Param("username")
+	if user.Username != username && user.Role != UserRoleStaff && user.Role != UserRoleAdmin {
+		return ctx.NoContent(http.StatusForbidden)
+	}
+
+	var req RequestGetAll
+	err := ctx.Bind(&req)
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	params := GetAllParams{
+		Limit:  req.PageSize,
+		Offset: (req.PageId - 1) * req.PageSize,
+	}
+
+	res, err := h.handler.GetRecommendations(ctx.Request().Context(), username, params)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error GetRecommendations Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,118 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type RecommendHandlrStub struct {
+	username string
+	params   GetAllParams
+	err      error
+}
+
+func (h *RecommendHandlrStub) GetRelatedBooks(ctx context.Context, bookId uint64, params GetAllParams) ([]ResponseRelatedBook, error) {
+	h.params = params
+	return []ResponseRelatedBook{}, h.err
+}
+
+func (h *RecommendHandlrStub) GetRecommendations(ctx context.Context, username string, params GetAllParams) ([]ResponseRelatedBook, error) {
+	h.username = username
+	return []ResponseRelatedBook{}, h.err
+}
+
+func newRecommendContext(target string, name string, value string, user *ResponseUser) (echo.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	rec := httptest.NewRecorder()
+	ctx := echo.New().NewContext(req, rec)
+	ctx.SetParamNames(name)
+	ctx.SetParamValues(value)
+	if user != nil {
+		ctx.Set(ctxUserKey, *user)
+	}
+	return ctx, rec
+}
+
+func TestGetRelatedBooksHandler(t *testing.T) {
+	t.Run("TestGetRelatedBooksHandlerShouldPassPage", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newRecommendContext("/books/1/related?page_id=2&page_size=5", "id", "1", nil)
+		stub := &RecommendHandlrStub{}
+		handler := NewRecommendHandlr(stub, logrus.New())
+
+		// Act
+		err := handler.GetRelatedBooks(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, GetAllParams{Limit: 5, Offset: 5}, stub.params)
+	})
+
+	t.Run("TestGetRelatedBooksHandlerShouldReturnServiceStatus", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newRecommendContext("/books/1/related", "id", "1", nil)
+		handler := NewRecommendHandlr(&RecommendHandlrStub{err: &c.Err{Code: http.StatusNotFound}}, logrus.New())
+
+		// Act
+		err := handler.GetRelatedBooks(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestGetRecommendationsHandler(t *testing.T) {
+	t.Run("TestGetRecommendationsHandlerShouldReturnHTTPStatus200ForOwner", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newRecommendContext("/users/reader/recommendations", "username", "reader", &ResponseUser{Username: "reader", Role: UserRoleCustomer})
+		stub := &RecommendHandlrStub{}
+		handler := NewRecommendHandlr(stub, logrus.New())
+
+		// Act
+		err := handler.GetRecommendations(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "reader", stub.username)
+	})
+
+	t.Run("TestGetRecommendationsHandlerShouldAllowStaff", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newRecommendContext("/users/reader/recommendations", "username", "reader", &ResponseUser{Username: "clerk", Role: UserRoleStaff})
+		handler := NewRecommendHandlr(&RecommendHandlrStub{}, logrus.New())
+
+		// Act
+		err := handler.GetRecommendations(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("TestGetRecommendationsHandlerShouldReturnHTTPStatus403ForOtherCustomer", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newRecommendContext("/users/reader/recommendations", "username", "reader", &ResponseUser{Username: "intruder", Role: UserRoleCustomer})
+		stub := &RecommendHandlrStub{}
+		handler := NewRecommendHandlr(stub, logrus.New())
+
+		// Act
+		err := handler.GetRecommendations(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Empty(t, stub.username)
+	})
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/paquesqueue/bookstore/recommend"
+	"github.com/paquesqueue/bookstore/tracing"
+)
+
+// recommendTopN is how many related books are kept per book.
+const recommendTopN = 50
+
+type RecommendQueries interface {
+	SelectBookByID(ctx context.Context, id uint64) (*ResponseBook, error)
+	SelectRecommendItems(ctx context.Context) ([]recommend.Item, error)
+	SelectRecommendBaskets(ctx context.Context) ([]recommend.Basket, error)
+	ReplaceBookSimilarities(ctx context.Context, pairs []recommend.Pair) error
+	SelectRelatedBooks(ctx context.Context, bookId uint64, params GetAllParams) ([]ResponseRelatedBook, error)
+	SelectUserRecommendations(ctx context.Context, username string, params GetAllParams) ([]ResponseRelatedBook, error)
+}
+
+type RecommendServices struct {
+	query RecommendQueries
+	log   c.Log
+}
+
+func NewRecommendService(q RecommendQueries, l c.Log) RecommendServices {
+	return RecommendServices{q, l}
+}
+
+func (s RecommendServices) GetRelatedBooks(ctx context.Context, bookId uint64, params GetAllParams) ([]ResponseRelatedBook, error) {
+	ctx, span := tracing.Start(ctx, "RecommendServices.GetRelatedBooks")
+	defer span.End()
+
+	_, err := s.query.SelectBookByID(ctx, bookId)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error SelectBookByID : %v", err)
+		if err == sql.ErrNoRows {
+			return nil, &c.Err{Code: http.StatusNotFound, Remark: "Error Book Not Found", Original: err}
+		}
+		return nil, &c.Err{Code: http.StatusInternalServerError, Remark: "Error GetRelatedBooks Service", Original: err}
+	}
+
+	resp, err := s.query.SelectRelatedBooks(ctx, bookId, params)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error SelectRelatedBooks : %v", err)
+		return nil, &c.Err{Code: http.StatusInternalServerError, Remark: "Error GetRelatedBooks Service", Original: err}
+	}
+	return resp, nil
+}
+
+func (s RecommendServices) GetRecommendations(ctx context.Context, username string, params GetAllParams) ([]ResponseRelatedBook, error) {
+	ctx, span := tracing.Start(ctx, "RecommendServices.GetRecommendations")
+	defer span.End()
+
+	resp, err := s.query.SelectUserRecommendations(ctx, username, params)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error SelectUserRecommendations : %v", err)
+		return nil, &c.Err{Code: http.StatusInternalServerError, Remark: "Error GetRecommendations Service", Original: err}
+	}
+	return resp, nil
+}
+
+// RefreshSimilarities recomputes the similarity table from the current
+// catalog, lists and reviews.
+func (s RecommendServices) RefreshSimilarities(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "RecommendServices.RefreshSimilarities")
+	defer span.End()
+
+	items, err := s.query.SelectRecommendItems(ctx)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error SelectRecommendItems : %v", err)
+		return err
+	}
+	baskets, err := s.query.SelectRecommendBaskets(ctx)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error SelectRecommendBaskets : %v", err)
+		return err
+	}
+
+	pairs := recommend.Build(items, baskets, recommend.DefaultWeights, recommendTopN)
+	err = s.query.ReplaceBookSimilarities(ctx, pairs)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error ReplaceBookSimilarities : %v", err)
+		return err
+	}
+	c.LogFrom(ctx, s.log).Info(fmt.Sprintf("Success Book Similarities Refreshed : %d books, %d baskets, %d pairs", len(items), len(baskets), len(pairs)))
+	return nil
+}
+
+// RunRefresh refreshes the similarity table right away and then on every
+// interval until ctx is done. A failed run is logged and retried on the next
+// tick. A non-positive interval disables the job.
+func (s RecommendServices) RunRefresh(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		_ = s.RefreshSimilarities(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
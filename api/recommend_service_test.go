@@ -0,0 +1,144 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/paquesqueue/bookstore/recommend"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type RecommendQueriesStub struct {
+	books    map[uint64]bool
+	items    []recommend.Item
+	baskets  []recommend.Basket
+	replaced []recommend.Pair
+	calls    int
+	err      error
+}
+
+func (q *RecommendQueriesStub) SelectBookByID(ctx context.Context, id uint64) (*ResponseBook, error) {
+	if !q.books[id] {
+		return nil, sql.ErrNoRows
+	}
+	return &ResponseBook{Id: id}, nil
+}
+
+func (q *RecommendQueriesStub) SelectRecommendItems(ctx context.Context) ([]recommend.Item, error) {
+	q.calls++
+	return q.items, q.err
+}
+
+func (q *RecommendQueriesStub) SelectRecommendBaskets(ctx context.Context) ([]recommend.Basket, error) {
+	return q.baskets, q.err
+}
+
+func (q *RecommendQueriesStub) ReplaceBookSimilarities(ctx context.Context, pairs []recommend.Pair) error {
+	q.replaced = pairs
+	return q.err
+}
+
+func (q *RecommendQueriesStub) SelectRelatedBooks(ctx context.Context, bookId uint64, params GetAllParams) ([]ResponseRelatedBook, error) {
+	return []ResponseRelatedBook{{ResponseBook: ResponseBook{Id: bookId + 1}, Score: 0.5}}, q.err
+}
+
+func (q *RecommendQueriesStub) SelectUserRecommendations(ctx context.Context, username string, params GetAllParams) ([]ResponseRelatedBook, error) {
+	return []ResponseRelatedBook{}, q.err
+}
+
+func TestGetRelatedBooks(t *testing.T) {
+	t.Run("TestGetRelatedBooksShouldReturnHTTPStatus404ForUnknownBook", func(t *testing.T) {
+		// Arrange
+		services := NewRecommendService(&RecommendQueriesStub{}, logrus.New())
+
+		// Act
+		_, err := services.GetRelatedBooks(context.Background(), 1, GetAllParams{Limit: 10})
+
+		// Assert
+		if assert.Error(t, err) {
+			assert.Equal(t, http.StatusNotFound, err.(*c.Err).Code)
+		}
+	})
+
+	t.Run("TestGetRelatedBooksShouldReturnRelatedBooks", func(t *testing.T) {
+		// Arrange
+		services := NewRecommendService(&RecommendQueriesStub{books: map[uint64]bool{1: true}}, logrus.New())
+
+		// Act
+		res, err := services.GetRelatedBooks(context.Background(), 1, GetAllParams{Limit: 10})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Len(t, res, 1)
+	})
+}
+
+func TestRefreshSimilarities(t *testing.T) {
+	t.Run("TestRefreshSimilaritiesShouldStoreBuiltPairs", func(t *testing.T) {
+		// Arrange
+		query := &RecommendQueriesStub{
+			items: []recommend.Item{
+				{Id: 1, Title: "Dune", Authors: []string{"Frank Herbert"}},
+				{Id: 2, Title: "Children of Dune", Authors: []string{"Frank Herbert"}},
+				{Id: 3, Title: "Emma", Authors: []string{"Jane Austen"}},
+			},
+			baskets: []recommend.Basket{{1, 3}},
+		}
+		services := NewRecommendService(query, logrus.New())
+
+		// Act
+		err := services.RefreshSimilarities(context.Background())
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, recommend.Build(query.items, query.baskets, recommend.DefaultWeights, recommendTopN), query.replaced)
+		assert.NotEmpty(t, query.replaced)
+	})
+
+	t.Run("TestRefreshSimilaritiesShouldKeepTableWhenLoadFails", func(t *testing.T) {
+		// Arrange
+		query := &RecommendQueriesStub{err: errors.New("boom")}
+		services := NewRecommendService(query, logrus.New())
+
+		// Act
+		err := services.RefreshSimilarities(context.Background())
+
+		// Assert
+		assert.Error(t, err)
+		assert.Nil(t, query.replaced)
+	})
+
+	t.Run("TestRunRefreshShouldRefreshUntilCancelled", func(t *testing.T) {
+		// Arrange
+		query := &RecommendQueriesStub{}
+		services := NewRecommendService(query, logrus.New())
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// Act
+		services.RunRefresh(ctx, time.Hour)
+
+		// Assert
+		assert.Equal(t, 1, query.calls)
+	})
+
+	t.Run("TestRunRefreshShouldBeDisabledWithoutInterval", func(t *testing.T) {
+		// Arrange
+		query := &RecommendQueriesStub{}
+		services := NewRecommendService(query, logrus.New())
+
+		// Act
+		services.RunRefresh(context.Background(), 0)
+
+		// Assert
+		assert.Equal(t, 0, query.calls)
+	})
+}
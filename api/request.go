@@ -1,5 +1,7 @@
 package api
 
+import "time"
+
 type RequestBook struct {
 	Title      string   `json:"title"`
 	Authors    []string `json:"authors"`
@@ -8,6 +10,10 @@ type RequestBook struct {
 	Price      int64    `json:"price"`
 	Quantity   int64    `json:"quantity"`
 	Created_by string   `json:"created_by"`
+
+	WorkId          *uint64 `json:"work_id"`
+	Format          string  `json:"format"`
+	PublicationDate string  `json:"publication_date"`
 }
 
 type RequestUser struct {
@@ -17,12 +23,203 @@ type RequestUser struct {
 	Password string `json:"password"`
 }
 
+// RequestProfile is the part of a user's details they change freely.
+type RequestProfile struct {
+	Fullname string `json:"fullname"`
+}
+
+// RequestChangeEmail moves a user to a new email address. Password is the
+// current one, needed when users change their own.
+type RequestChangeEmail struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	// IP is the client address, set by the handler.
+	IP string `json:"-"`
+}
+
+// RequestChangePassword sets a new password. CurrentPassword is needed
+// when users change their own.
+type RequestChangePassword struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+	// IP is the client address, set by the handler.
+	IP string `json:"-"`
+}
+
+// RequestChangeUsername renames a user. Password is the current one,
+// needed when users rename themselves.
+type RequestChangeUsername struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// IP is the client address, set by the handler.
+	IP string `json:"-"`
+}
+
+// RequestErase asks for a user's personal data to be erased. Password is
+// the current one, needed when users erase themselves.
+type RequestErase struct {
+	Password string `json:"password"`
+	// IP is the client address, set by the handler.
+	IP string `json:"-"`
+}
+
 type RequestGetAll struct {
-	PageId   int64 `json:"page_id"`
-	PageSize int64 `json:"page_size"`
+	PageId   int64 `json:"page_id" query:"page_id"`
+	PageSize int64 `json:"page_size" query:"page_size"`
+}
+
+type RequestWork struct {
+	Title   string   `json:"title"`
+	Authors []string `json:"authors"`
+}
+
+type RequestBookFilter struct {
+	CategoryId uint64 `query:"category"`
+	Tag        string `query:"tag"`
 }
 
 type GetAllParams struct {
 	Limit  int64
 	Offset int64
 }
+
+type RequestOnixDetail struct {
+	BookId          uint64
+	RecordReference string
+	ProductForm     string
+	Subtitle        string
+	LanguageCode    string
+	PageCount       int64
+	PublicationDate string
+	Description     string
+	CurrencyCode    string
+}
+
+type RequestAuthor struct {
+	Name string `json:"name"`
+}
+
+type RequestPublisher struct {
+	Name string `json:"name"`
+}
+
+type RequestCategory struct {
+	ParentId *uint64 `json:"parent_id"`
+	Name     string  `json:"name"`
+	Scheme   string  `json:"scheme"`
+	Code     string  `json:"code"`
+}
+
+type RequestCategoryImport struct {
+	Scheme string
+	Code   string
+	Path   []string
+}
+
+type RequestBookCategories struct {
+	CategoryIds []uint64 `json:"category_ids"`
+}
+
+type RequestTag struct {
+	Name string `json:"name"`
+}
+
+type RequestBookTags struct {
+	Tags []string `json:"tags"`
+}
+
+type RequestCover struct {
+	BookId      uint64
+	Version     string
+	ContentType string
+	Size        int64
+	Width       int
+	Height      int
+}
+
+type RequestUserRole struct {
+	Role string `json:"role"`
+}
+
+type RequestLogin struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// IP is the client address, set by the handler.
+	IP string `json:"-"`
+}
+
+// RequestMFACode is a code from the user's authenticator, or one of their
+// recovery codes instead.
+type RequestMFACode struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+	// IP is the client address, set by the handler.
+	IP string `json:"-"`
+}
+
+// RequestLoginMFA finishes a login that returned an MFA token.
+type RequestLoginMFA struct {
+	Token string `json:"mfa_token"`
+	RequestMFACode
+}
+
+// RequestReview is the review a customer submits. The book, author and
+// moderation fields are filled in by the service, never from the body.
+type RequestReview struct {
+	Rating int64  `json:"rating"`
+	Title  string `json:"title"`
+	Body   string `json:"body"`
+
+	BookId         uint64 `json:"-"`
+	Username       string `json:"-"`
+	Status         string `json:"-"`
+	ModerationNote string `json:"-"`
+}
+
+type RequestModeration struct {
+	Status string `json:"status"`
+	Note   string `json:"note"`
+
+	ModeratedBy string `json:"-"`
+}
+
+type RequestBookList struct {
+	Name       string `json:"name"`
+	Visibility string `json:"visibility"`
+
+	Username   string `json:"-"`
+	ShareToken string `json:"-"`
+}
+
+type RequestListBook struct {
+	BookId uint64 `json:"book_id"`
+}
+
+type RequestListOrder struct {
+	BookIds []uint64 `json:"book_ids"`
+}
+
+// RequestAPIKey names a new API key and what it may do. The key itself is
+// generated by the service; only its hash is stored.
+type RequestAPIKey struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+
+	Username string `json:"-"`
+	Prefix   string `json:"-"`
+	KeyHash  string `json:"-"`
+}
+
+type RequestEmailVerification struct {
+	Token string `json:"token"`
+}
+
+type RequestForgotPassword struct {
+	Email string `json:"email"`
+}
+
+type RequestResetPassword struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
@@ -3,21 +3,250 @@ package api
 import "time"
 
 type ResponseBook struct {
-	Id         uint64    `json:"id"`
-	Title      string    `json:"title"`
-	Authors    []string  `json:"authors"`
-	Publisher  string    `json:"publisher"`
-	Isbn       string   `json:"isbn"`
-	Price      int64     `json:"price"`
-	Quantity   int64     `json:"quantity"`
-	Created_by string    `json:"created_by"`
-	Created_at time.Time `json:"created_at"`
+	Id              uint64            `json:"id"`
+	Title           string            `json:"title"`
+	Authors         []string          `json:"authors"`
+	Publisher       string            `json:"publisher"`
+	Isbn            string            `json:"isbn"`
+	Price           int64             `json:"price"`
+	Quantity        int64             `json:"quantity"`
+	Created_by      string            `json:"created_by"`
+	Created_at      time.Time         `json:"created_at"`
+	WorkId          uint64            `json:"work_id"`
+	Format          string            `json:"format"`
+	PublicationDate string            `json:"publication_date,omitempty"`
+	CoverUrls       map[string]string `json:"cover_urls,omitempty"`
+	RatingAvg       float64           `json:"rating_avg"`
+	RatingCount     int64             `json:"rating_count"`
+}
+
+// ResponseWork aggregates the editions of a work. Prices and quantity are
+// summarised over all editions.
+type ResponseWork struct {
+	Id        uint64         `json:"id"`
+	Title     string         `json:"title"`
+	Authors   []string       `json:"authors"`
+	CreatedAt time.Time      `json:"created_at"`
+	Formats   []string       `json:"formats"`
+	MinPrice  int64          `json:"min_price"`
+	MaxPrice  int64          `json:"max_price"`
+	Quantity  int64          `json:"quantity"`
+	Editions  []ResponseBook `json:"editions"`
 }
 
 type ResponseUser struct {
+	Username        string     `json:"username"`
+	Email           string     `json:"email"`
+	Fullname        string     `json:"fullname"`
+	HashedPassword  string     `json:"-"`
+	CreatedAt       time.Time  `json:"created_at"`
+	Role            string     `json:"role"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	MFAEnabledAt    *time.Time `json:"mfa_enabled_at"`
+}
+
+type ResponseOnixBook struct {
+	ResponseBook
+	RecordReference string
+	ProductForm     string
+	Subtitle        string
+	LanguageCode    string
+	PageCount       int64
+	PublicationDate string
+	Description     string
+	CurrencyCode    string
+}
+
+// ResponseFeedIngest counts what a feed changed. Aborted is why a malformed
+// feed stopped part way; the products counted before it stay applied.
+type ResponseFeedIngest struct {
+	Created  int      `json:"created"`
+	Updated  int      `json:"updated"`
+	Deleted  int      `json:"deleted"`
+	Rejected int      `json:"rejected"`
+	Errors   []string `json:"errors"`
+	Aborted  string   `json:"aborted,omitempty"`
+}
+
+type ResponseAuthor struct {
+	Id        uint64    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ResponseAuthorBook struct {
+	ResponseBook
+	Role     string `json:"role"`
+	Position int64  `json:"position"`
+}
+
+type ResponsePublisher struct {
+	Id        uint64    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ResponseCategory struct {
+	Id        uint64    `json:"id"`
+	ParentId  *uint64   `json:"parent_id"`
+	Name      string    `json:"name"`
+	Scheme    string    `json:"scheme"`
+	Code      string    `json:"code,omitempty"`
+	Path      string    `json:"path"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ResponseTag struct {
+	Id        uint64    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ResponseCover struct {
+	BookId      uint64            `json:"book_id"`
+	Version     string            `json:"version"`
+	ContentType string            `json:"content_type"`
+	Size        int64             `json:"size"`
+	Width       int               `json:"width"`
+	Height      int               `json:"height"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+	Urls        map[string]string `json:"urls"`
+}
+
+// ResponseSession is an opened session, or, for users with two-factor
+// authentication on, the MFA token to finish the login with; ExpiresAt is
+// then when the MFA token does.
+type ResponseSession struct {
+	Token       string    `json:"token,omitempty"`
+	MFARequired bool      `json:"mfa_required,omitempty"`
+	MFAToken    string    `json:"mfa_token,omitempty"`
+	Username    string    `json:"username"`
+	Role        string    `json:"role"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// ResponseMFA describes a user's two-factor authentication. Required is set
+// when their role has to use it.
+type ResponseMFA struct {
+	Enabled           bool       `json:"enabled"`
+	EnabledAt         *time.Time `json:"enabled_at"`
+	Required          bool       `json:"required"`
+	RecoveryCodesLeft int        `json:"recovery_codes_left"`
+}
+
+// ResponseMFASetup is the secret to add to an authenticator, also as the
+// otpauth:// URI to show as a QR code.
+type ResponseMFASetup struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// ResponseRecoveryCodes are shown once; only their hashes are stored.
+type ResponseRecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// ResponseErasure is the receipt of a user's erasure. Username is the
+// placeholder the account was renamed to; SubjectHash is the old username
+// hashed with auth.erasure_key, so the erasure can be confirmed without
+// keeping it. Retained is what the erasure leaves behind.
+type ResponseErasure struct {
+	Id                uint64    `json:"id"`
 	Username          string    `json:"username"`
-	Email             string    `json:"email"`
-	Fullname          string    `json:"fullname"`
-	HashedPassword    string    `json:"hashed_password"`
-	CreatedAt         time.Time `json:"created_at"`
+	SubjectHash       string    `json:"subject_hash"`
+	ErasedBy          string    `json:"erased_by"`
+	ReviewsAnonymised int64     `json:"reviews_anonymised"`
+	ListsDeleted      int64     `json:"lists_deleted"`
+	APIKeysDeleted    int64     `json:"api_keys_deleted"`
+	SessionsDeleted   int64     `json:"sessions_deleted"`
+	ErasedAt          time.Time `json:"erased_at"`
+	Retained          []string  `json:"retained"`
+}
+
+// ResponseAPIKey describes an API key. Key is only set when the key is
+// created or rotated; it cannot be read back later.
+type ResponseAPIKey struct {
+	Id         uint64     `json:"id"`
+	Username   string     `json:"username"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	Key        string     `json:"key,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RotatedAt  *time.Time `json:"rotated_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+type ResponseReview struct {
+	Id             uint64     `json:"id"`
+	BookId         uint64     `json:"book_id"`
+	Username       string     `json:"username"`
+	Rating         int64      `json:"rating"`
+	Title          string     `json:"title"`
+	Body           string     `json:"body"`
+	Status         string     `json:"status"`
+	HelpfulCount   int64      `json:"helpful_count"`
+	ModerationNote string     `json:"moderation_note,omitempty"`
+	ModeratedBy    string     `json:"moderated_by,omitempty"`
+	ModeratedAt    *time.Time `json:"moderated_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+type ResponseBookList struct {
+	Id         uint64             `json:"id"`
+	Username   string             `json:"username"`
+	Name       string             `json:"name"`
+	IsDefault  bool               `json:"is_default"`
+	Visibility string             `json:"visibility"`
+	ShareToken string             `json:"share_token,omitempty"`
+	BookCount  int64              `json:"book_count"`
+	CreatedAt  time.Time          `json:"created_at"`
+	UpdatedAt  time.Time          `json:"updated_at"`
+	Books      []ResponseListBook `json:"books,omitempty"`
+}
+
+// ResponseRelatedBook is a recommended book; a higher score is a closer match.
+type ResponseRelatedBook struct {
+	ResponseBook
+	Score float64 `json:"score"`
+}
+
+type ResponseListBook struct {
+	ResponseBook
+	Position int64     `json:"position"`
+	AddedAt  time.Time `json:"added_at"`
+}
+
+type ResponseDBStatus struct {
+	Healthy   bool            `json:"healthy"`
+	Error     string          `json:"error,omitempty"`
+	CheckedAt time.Time       `json:"checked_at"`
+	Pool      ResponseDBStats `json:"pool"`
+}
+
+// ResponseDBStats mirrors sql.DBStats.
+type ResponseDBStats struct {
+	MaxOpenConnections int   `json:"max_open_connections"`
+	OpenConnections    int   `json:"open_connections"`
+	InUse              int   `json:"in_use"`
+	Idle               int   `json:"idle"`
+	WaitCount          int64 `json:"wait_count"`
+	WaitDurationMs     int64 `json:"wait_duration_ms"`
+	MaxIdleClosed      int64 `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64 `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64 `json:"max_lifetime_closed"`
+}
+
+// ResponseHealth is the body of /healthz and /readyz; Status is down when
+// any component is.
+type ResponseHealth struct {
+	Status     string                       `json:"status"`
+	Components map[string]ResponseComponent `json:"components"`
+}
+
+type ResponseComponent struct {
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
 }
@@ -0,0 +1,203 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+var errReviewOwnVote = errors.New("error cannot vote on own review")
+
+const reviewColumns = `id, book_id, username, rating, title, body, status, helpful_count, moderation_note, moderated_by, moderated_at, created_at`
+
+// reviewOrders maps the public sort names to their ORDER BY clauses. Ties
+// fall back to the id so pages are stable.
+var reviewOrders = map[string]string{
+	ReviewSortRecent:  `created_at DESC, id DESC`,
+	ReviewSortHelpful: `helpful_count DESC, created_at DESC, id DESC`,
+}
+
+func scanReview(row rowScanner) (ResponseReview, error) {
+	resp := ResponseReview{}
+	var moderatedAt sql.NullTime
+	err := row.Scan(&resp.Id, &resp.BookId, &resp.Username, &resp.Rating, &resp.Title, &resp.Body, &resp.Status,
+		&resp.HelpfulCount, &resp.ModerationNote, &resp.ModeratedBy, &moderatedAt, &resp.CreatedAt)
+	if err != nil {
+		return ResponseReview{}, err
+	}
+	if moderatedAt.Valid {
+		resp.ModeratedAt = &moderatedAt.Time
+	}
+	return resp, nil
+}
+
+func scanReviews(rows *sql.Rows) ([]ResponseReview, error) {
+	defer rows.Close()
+
+	resp := []ResponseReview{}
+	for rows.Next() {
+		review, err := scanReview(rows)
+		if err != nil {
+			return nil, err
+		}
+		resp = append(resp, review)
+	}
+	return resp, rows.Err()
+}
+
+// InsertReview stores a review. Reviews are never inserted approved, so the
+// book's rating totals are left alone until a moderator accepts it.
+func (db Query) InsertReview(ctx context.Context, req RequestReview) (ResponseReview, error) {
+	const query = `INSERT INTO reviews
+	(book_id, username, rating, title, body, status, moderation_note)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	RETURNING ` + reviewColumns + `;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return ResponseReview{}, err
+	}
+	defer stmt.Close()
+
+	row := stmt.QueryRowContext(ctx, req.BookId, req.Username, req.Rating, req.Title, req.Body, req.Status, req.ModerationNote)
+	return scanReview(row)
+}
+
+// SelectBookReviews lists the approved reviews of a book.
+func (db Query) SelectBookReviews(ctx context.Context, bookId uint64, sort string, params GetAllParams) ([]ResponseReview, error) {
+	order, ok := reviewOrders[sort]
+	if !ok {
+		order = reviewOrders[ReviewSortRecent]
+	}
+	query := `SELECT ` + reviewColumns + `
+	FROM reviews
+	WHERE book_id = $1 AND status = $2
+	ORDER BY ` + order + `
+	LIMIT $3
+	OFFSET $4;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, bookId, ReviewStatusApproved, params.Limit, params.Offset)
+	if err != nil {
+		return nil, err
+	}
+	return scanReviews(rows)
+}
+
+// SelectReviewsByStatus lists reviews in a moderation state, oldest first so
+// the queue is worked in submission order.
+func (db Query) SelectReviewsByStatus(ctx context.Context, status string, params GetAllParams) ([]ResponseReview, error) {
+	const query = `SELECT ` + reviewColumns + `
+	FROM reviews
+	WHERE status = $1
+	ORDER BY created_at, id
+	LIMIT $2
+	OFFSET $3;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, status, params.Limit, params.Offset)
+	if err != nil {
+		return nil, err
+	}
+	return scanReviews(rows)
+}
+
+// UpdateReviewStatus records a moderation decision. Moving a review into or
+// out of the approved state adjusts the book's rating totals in the same
+// transaction, so the aggregate never needs a full recount.
+func (db Query) UpdateReviewStatus(ctx context.Context, id uint64, req RequestModeration) (ResponseReview, error) {
+	const query = `UPDATE reviews
+	SET status = $1, moderation_note = $2, moderated_by = $3, moderated_at = NOW()
+	WHERE id = $4
+	RETURNING ` + reviewColumns + `;`
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return ResponseReview{}, err
+	}
+	defer tx.Rollback()
+
+	var bookId uint64
+	var rating int64
+	var status string
+	err = tx.QueryRow(`SELECT book_id, rating, status FROM reviews WHERE id = $1 FOR UPDATE;`, id).Scan(&bookId, &rating, &status)
+	if err != nil {
+		return ResponseReview{}, err
+	}
+
+	resp, err := scanReview(tx.QueryRow(query, req.Status, req.Note, req.ModeratedBy, id))
+	if err != nil {
+		return ResponseReview{}, err
+	}
+
+	wasApproved := status == ReviewStatusApproved
+	isApproved := req.Status == ReviewStatusApproved
+	switch {
+	case !wasApproved && isApproved:
+		err = adjustBookRating(tx, bookId, 1, rating)
+	case wasApproved && !isApproved:
+		err = adjustBookRating(tx, bookId, -1, -rating)
+	}
+	if err != nil {
+		return ResponseReview{}, err
+	}
+	return resp, tx.Commit()
+}
+
+func adjustBookRating(tx *sql.Tx, bookId uint64, count int64, sum int64) error {
+	_, err := tx.Exec(`UPDATE books SET rating_count = rating_count + $1, rating_sum = rating_sum + $2 WHERE id = $3;`, count, sum, bookId)
+	return err
+}
+
+// InsertReviewVote marks an approved review helpful for a user. Voting again
+// is a no-op, and authors cannot vote on their own reviews.
+func (db Query) InsertReviewVote(ctx context.Context, reviewId uint64, username string) (ResponseReview, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return ResponseReview{}, err
+	}
+	defer tx.Rollback()
+
+	var author, status string
+	err = tx.QueryRow(`SELECT username, status FROM reviews WHERE id = $1 FOR UPDATE;`, reviewId).Scan(&author, &status)
+	if err != nil {
+		return ResponseReview{}, err
+	}
+	if status != ReviewStatusApproved {
+		return ResponseReview{}, sql.ErrNoRows
+	}
+	if author == username {
+		return ResponseReview{}, errReviewOwnVote
+	}
+
+	result, err := tx.Exec(`INSERT INTO review_votes (review_id, username) VALUES ($1, $2) ON CONFLICT DO NOTHING;`, reviewId, username)
+	if err != nil {
+		return ResponseReview{}, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return ResponseReview{}, err
+	}
+	if n > 0 {
+		_, err = tx.Exec(`UPDATE reviews SET helpful_count = helpful_count + 1 WHERE id = $1;`, reviewId)
+		if err != nil {
+			return ResponseReview{}, err
+		}
+	}
+
+	resp, err := scanReview(tx.QueryRow(`SELECT `+reviewColumns+` FROM reviews WHERE id = $1;`, reviewId))
+	if err != nil {
+		return ResponseReview{}, err
+	}
+	return resp, tx.Commit()
+}
@@ -0,0 +1,217 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var reviewColumnNames = []string{"id", "book_id", "username", "rating", "title", "body", "status", "helpful_count", "moderation_note", "moderated_by", "moderated_at", "created_at"}
+
+func TestSelectBookReviews(t *testing.T) {
+	t.Run("TestSelectBookReviewsShouldOrderByHelpfulness", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		now := time.Now()
+		mock.ExpectPrepare(regexp.QuoteMeta(`FROM reviews WHERE book_id = $1 AND status = $2 ORDER BY helpful_count DESC, created_at DESC, id DESC LIMIT $3 OFFSET $4;`)).
+			ExpectQuery().
+			WithArgs(1, ReviewStatusApproved, 10, 0).
+			WillReturnRows(sqlmock.NewRows(reviewColumnNames).
+				AddRow(2, 1, "reader", 5, "Great", "Loved it", ReviewStatusApproved, 3, "", "mod", now, now).
+				AddRow(1, 1, "critic", 2, "Meh", "", ReviewStatusApproved, 0, "", "mod", nil, now))
+
+		query := NewDB(db)
+
+		// Act
+		res, err := query.SelectBookReviews(context.Background(), 1, ReviewSortHelpful, GetAllParams{Limit: 10})
+
+		// Assert
+		assert.NoError(t, err)
+		if assert.Len(t, res, 2) {
+			assert.Equal(t, int64(3), res[0].HelpfulCount)
+			assert.NotNil(t, res[0].ModeratedAt)
+			assert.Nil(t, res[1].ModeratedAt)
+		}
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUpdateReviewStatus(t *testing.T) {
+	t.Run("TestUpdateReviewStatusShouldAddApprovedRatingToBook", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		now := time.Now()
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT book_id, rating, status FROM reviews WHERE id = $1 FOR UPDATE;`)).
+			WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"book_id", "rating", "status"}).AddRow(1, 4, ReviewStatusPending))
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE reviews SET status = $1, moderation_note = $2, moderated_by = $3, moderated_at = NOW() WHERE id = $4`)).
+			WithArgs(ReviewStatusApproved, "", "mod", 7).
+			WillReturnRows(sqlmock.NewRows(reviewColumnNames).
+				AddRow(7, 1, "reader", 4, "", "", ReviewStatusApproved, 0, "", "mod", now, now))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE books SET rating_count = rating_count + $1, rating_sum = rating_sum + $2 WHERE id = $3;`)).
+			WithArgs(1, 4, 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		query := NewDB(db)
+
+		// Act
+		res, err := query.UpdateReviewStatus(context.Background(), 7, RequestModeration{Status: ReviewStatusApproved, ModeratedBy: "mod"})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, ReviewStatusApproved, res.Status)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestUpdateReviewStatusShouldRemoveRatingWhenUnapproved", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		now := time.Now()
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT book_id, rating, status FROM reviews`)).
+			WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"book_id", "rating", "status"}).AddRow(1, 4, ReviewStatusApproved))
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE reviews SET status = $1`)).
+			WithArgs(ReviewStatusRejected, "spoilers", "mod", 7).
+			WillReturnRows(sqlmock.NewRows(reviewColumnNames).
+				AddRow(7, 1, "reader", 4, "", "", ReviewStatusRejected, 0, "spoilers", "mod", now, now))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE books SET rating_count`)).
+			WithArgs(-1, -4, 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		query := NewDB(db)
+
+		// Act
+		_, err = query.UpdateReviewStatus(context.Background(), 7, RequestModeration{Status: ReviewStatusRejected, Note: "spoilers", ModeratedBy: "mod"})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestUpdateReviewStatusShouldLeaveBookAloneWhenApprovalUnchanged", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		now := time.Now()
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT book_id, rating, status FROM reviews`)).
+			WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"book_id", "rating", "status"}).AddRow(1, 4, ReviewStatusPending))
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE reviews SET status = $1`)).
+			WithArgs(ReviewStatusRejected, "", "mod", 7).
+			WillReturnRows(sqlmock.NewRows(reviewColumnNames).
+				AddRow(7, 1, "reader", 4, "", "", ReviewStatusRejected, 0, "", "mod", now, now))
+		mock.ExpectCommit()
+
+		query := NewDB(db)
+
+		// Act
+		_, err = query.UpdateReviewStatus(context.Background(), 7, RequestModeration{Status: ReviewStatusRejected, ModeratedBy: "mod"})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestInsertReviewVote(t *testing.T) {
+	t.Run("TestInsertReviewVoteShouldCountFirstVoteOnly", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		now := time.Now()
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT username, status FROM reviews WHERE id = $1 FOR UPDATE;`)).
+			WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"username", "status"}).AddRow("reader", ReviewStatusApproved))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO review_votes (review_id, username) VALUES ($1, $2) ON CONFLICT DO NOTHING;`)).
+			WithArgs(7, "voter").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE reviews SET helpful_count = helpful_count + 1 WHERE id = $1;`)).
+			WithArgs(7).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM reviews WHERE id = $1;`)).
+			WithArgs(7).
+			WillReturnRows(sqlmock.NewRows(reviewColumnNames).
+				AddRow(7, 1, "reader", 4, "", "", ReviewStatusApproved, 1, "", "mod", now, now))
+		mock.ExpectCommit()
+
+		query := NewDB(db)
+
+		// Act
+		res, err := query.InsertReviewVote(context.Background(), 7, "voter")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), res.HelpfulCount)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestInsertReviewVoteShouldRejectOwnReview", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT username, status FROM reviews`)).
+			WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"username", "status"}).AddRow("reader", ReviewStatusApproved))
+		mock.ExpectRollback()
+
+		query := NewDB(db)
+
+		// Act
+		_, err = query.InsertReviewVote(context.Background(), 7, "reader")
+
+		// Assert
+		assert.Equal(t, errReviewOwnVote, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestInsertReviewVoteShouldHidePendingReviews", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT username, status FROM reviews`)).
+			WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"username", "status"}).AddRow("reader", ReviewStatusPending))
+		mock.ExpectRollback()
+
+		query := NewDB(db)
+
+		// Act
+		_, err = query.InsertReviewVote(context.Background(), 7, "voter")
+
+		// Assert
+		assert.Equal(t, sql.ErrNoRows, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
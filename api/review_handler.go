@@ -0,0 +1,153 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	c "github.com/paquesqueue/bookstore/common"
+)
+
+type ReviewHandlrQueries interface {
+	AddReview(ctx context.Context, bookId uint64, username string, req RequestReview) (ResponseReview, error)
+	ListBookReviews(ctx context.Context, bookId uint64, sort string, params GetAllParams) ([]ResponseReview, error)
+	ListReviews(ctx context.Context, status string, params GetAllParams) ([]ResponseReview, error)
+	ModerateReview(ctx context.Context, id uint64, moderator string, req RequestModeration) (ResponseReview, error)
+	VoteHelpful(ctx context.Context, id uint64, username string) (ResponseReview, error)
+}
+
+type ReviewHandlr struct {
+	handler ReviewHandlrQueries
+	log     c.Log
+}
+
+func NewReviewHandlr(h ReviewHandlrQueries, l c.Log) ReviewHandlr {
+	return ReviewHandlr{h, l}
+}
+
+func (h ReviewHandlr) AddReview(ctx echo.Context) error {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+	user, ok := CurrentUser(ctx)
+	if !ok {
+		return ctx.NoContent(http.StatusUnauthorized)
+	}
+
+	req := RequestReview{}
+	err = ctx.Bind(&req)
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	res, err := h.handler.AddReview(ctx.Request().Context(), uint64(id), user.Username, req)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error AddReview Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusCreated, res)
+}
+
+func (h ReviewHandlr) ListBookReviews(ctx echo.Context) error {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	var req RequestGetAll
+	err = ctx.Bind(&req)
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	params := GetAllParams{
+		Limit:  req.PageSize,
+		Offset: (req.PageId - 1) * req.PageSize,
+	}
+
+	res, err := h.handler.ListBookReviews(ctx.Request().Context(), uint64(id), ctx.QueryParam("sort"), params)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error ListBookReviews Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h ReviewHandlr) ListReviews(ctx echo.Context) error {
+	var req RequestGetAll
+	err := ctx.Bind(&req)
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	params := GetAllParams{
+		Limit:  req.PageSize,
+		Offset: (req.PageId - 1) * req.PageSize,
+	}
+
+	res, err := h.handler.ListReviews(ctx.Request().Context(), ctx.QueryParam("status"), params)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error ListReviews Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h ReviewHandlr) ModerateReview(ctx echo.Context) error {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+	user, ok := CurrentUser(ctx)
+	if !ok {
+		return ctx.NoContent(http.StatusUnauthorized)
+	}
+
+	req := RequestModeration{}
+	err = ctx.Bind(&req)
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	res, err := h.handler.ModerateReview(ctx.Request().Context(), uint64(id), user.Username, req)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error ModerateReview Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (h ReviewHandlr) VoteHelpful(ctx echo.Context) error {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+	user, ok := CurrentUser(ctx)
+	if !ok {
+		return ctx.NoContent(http.StatusUnauthorized)
+	}
+
+	res, err := h.handler.VoteHelpful(ctx.Request().Context(), uint64(id), user.Username)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error VoteHelpful Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, res)
+}
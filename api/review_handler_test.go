@@ -0,0 +1,164 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type ReviewHandlrSuccess struct {
+	ReviewHandlrError
+	username string
+	sort     string
+	review   RequestReview
+}
+
+func (h *ReviewHandlrSuccess) AddReview(ctx context.Context, bookId uint64, username string, req RequestReview) (ResponseReview, error) {
+	h.username = username
+	h.review = req
+	return ResponseReview{Id: 1, BookId: bookId, Username: username, Rating: req.Rating, Status: ReviewStatusPending}, nil
+}
+
+func (h *ReviewHandlrSuccess) ListBookReviews(ctx context.Context, bookId uint64, sort string, params GetAllParams) ([]ResponseReview, error) {
+	h.sort = sort
+	return []ResponseReview{{Id: 1, BookId: bookId}}, nil
+}
+
+func (h *ReviewHandlrSuccess) ModerateReview(ctx context.Context, id uint64, moderator string, req RequestModeration) (ResponseReview, error) {
+	h.username = moderator
+	return ResponseReview{Id: id, Status: req.Status, ModeratedBy: moderator}, nil
+}
+
+type ReviewHandlrError struct {
+	statusCodeError int
+}
+
+func (h *ReviewHandlrError) AddReview(ctx context.Context, bookId uint64, username string, req RequestReview) (ResponseReview, error) {
+	return ResponseReview{}, &c.Err{Code: h.statusCodeError}
+}
+
+func (h *ReviewHandlrError) ListBookReviews(ctx context.Context, bookId uint64, sort string, params GetAllParams) ([]ResponseReview, error) {
+	return nil, &c.Err{Code: h.statusCodeError}
+}
+
+func (h *ReviewHandlrError) ListReviews(ctx context.Context, status string, params GetAllParams) ([]ResponseReview, error) {
+	return nil, &c.Err{Code: h.statusCodeError}
+}
+
+func (h *ReviewHandlrError) ModerateReview(ctx context.Context, id uint64, moderator string, req RequestModeration) (ResponseReview, error) {
+	return ResponseReview{}, &c.Err{Code: h.statusCodeError}
+}
+
+func (h *ReviewHandlrError) VoteHelpful(ctx context.Context, id uint64, username string) (ResponseReview, error) {
+	return ResponseReview{}, &c.Err{Code: h.statusCodeError}
+}
+
+func newReviewContext(method string, target string, body string, user *ResponseUser) (echo.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	ctx := echo.New().NewContext(req, rec)
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("1")
+	if user != nil {
+		ctx.Set(ctxUserKey, *user)
+	}
+	return ctx, rec
+}
+
+func TestAddReviewHandler(t *testing.T) {
+	t.Run("TestAddReviewHandlerShouldReturnHTTPStatus201", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newReviewContext(http.MethodPost, "/", `{"rating":4,"title":"Good","body":"Worth it"}`, &ResponseUser{Username: "reader"})
+		handlrServ := &ReviewHandlrSuccess{}
+		handler := NewReviewHandlr(handlrServ, logrus.New())
+
+		// Act
+		err := handler.AddReview(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusCreated, rec.Code)
+			assert.Equal(t, "reader", handlrServ.username)
+			assert.Equal(t, int64(4), handlrServ.review.Rating)
+
+			res := ResponseReview{}
+			json.Unmarshal(rec.Body.Bytes(), &res)
+			assert.Equal(t, ReviewStatusPending, res.Status)
+		}
+	})
+
+	t.Run("TestAddReviewHandlerShouldReturnHTTPStatus401WithoutUser", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newReviewContext(http.MethodPost, "/", `{"rating":4}`, nil)
+		handler := NewReviewHandlr(&ReviewHandlrSuccess{}, logrus.New())
+
+		// Act
+		err := handler.AddReview(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		}
+	})
+
+	t.Run("TestAddReviewHandlerShouldReturnHTTPStatus409", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newReviewContext(http.MethodPost, "/", `{"rating":4}`, &ResponseUser{Username: "reader"})
+		handler := NewReviewHandlr(&ReviewHandlrError{statusCodeError: http.StatusConflict}, logrus.New())
+
+		// Act
+		err := handler.AddReview(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusConflict, rec.Code)
+		}
+	})
+}
+
+func TestListBookReviewsHandler(t *testing.T) {
+	t.Run("TestListBookReviewsHandlerShouldPassSort", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newReviewContext(http.MethodGet, "/?sort=helpful&page_id=1&page_size=10", "", nil)
+		handlrServ := &ReviewHandlrSuccess{}
+		handler := NewReviewHandlr(handlrServ, logrus.New())
+
+		// Act
+		err := handler.ListBookReviews(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, ReviewSortHelpful, handlrServ.sort)
+		}
+	})
+}
+
+func TestModerateReviewHandler(t *testing.T) {
+	t.Run("TestModerateReviewHandlerShouldReturnHTTPStatus200", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newReviewContext(http.MethodPut, "/", `{"status":"approved"}`, &ResponseUser{Username: "mod", Role: UserRoleStaff})
+		handlrServ := &ReviewHandlrSuccess{}
+		handler := NewReviewHandlr(handlrServ, logrus.New())
+
+		// Act
+		err := handler.ModerateReview(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, "mod", handlrServ.username)
+		}
+	})
+}
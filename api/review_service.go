@@ -0,0 +1,176 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/paquesqueue/bookstore/moderation"
+	"github.com/paquesqueue/bookstore/tracing"
+)
+
+const (
+	ReviewStatusPending  = "pending"
+	ReviewStatusApproved = "approved"
+	ReviewStatusRejected = "rejected"
+
+	ReviewSortRecent  = "recent"
+	ReviewSortHelpful = "helpful"
+
+	reviewMinRating    = 1
+	reviewMaxRating    = 5
+	reviewMaxTitleLen  = 200
+	reviewMaxBodyLen   = 10000
+	reviewMaxNoteLen   = 1000
+	reviewFilterPrefix = "filter: "
+)
+
+type ReviewQueries interface {
+	InsertReview(ctx context.Context, req RequestReview) (ResponseReview, error)
+	SelectBookReviews(ctx context.Context, bookId uint64, sort string, params GetAllParams) ([]ResponseReview, error)
+	SelectReviewsByStatus(ctx context.Context, status string, params GetAllParams) ([]ResponseReview, error)
+	UpdateReviewStatus(ctx context.Context, id uint64, req RequestModeration) (ResponseReview, error)
+	InsertReviewVote(ctx context.Context, reviewId uint64, username string) (ResponseReview, error)
+}
+
+type ReviewServices struct {
+	query  ReviewQueries
+	filter moderation.Filter
+	log    c.Log
+}
+
+func NewReviewService(q ReviewQueries, f moderation.Filter, l c.Log) ReviewServices {
+	return ReviewServices{q, f, l}
+}
+
+// AddReview queues a review for moderation. Reviews the filter flags are
+// rejected straight away with the filter's reason as the note; staff can
+// still approve them from the queue.
+func (s ReviewServices) AddReview(ctx context.Context, bookId uint64, username string, req RequestReview) (ResponseReview, error) {
+	ctx, span := tracing.Start(ctx, "ReviewServices.AddReview")
+	defer span.End()
+
+	req.Title = strings.TrimSpace(req.Title)
+	req.Body = strings.TrimSpace(req.Body)
+	switch {
+	case req.Rating < reviewMinRating || req.Rating > reviewMaxRating:
+		return ResponseReview{}, &c.Err{Code: http.StatusBadRequest, Remark: "Error Rating Must Be Between 1 And 5"}
+	case utf8.RuneCountInString(req.Title) > reviewMaxTitleLen || utf8.RuneCountInString(req.Body) > reviewMaxBodyLen:
+		return ResponseReview{}, &c.Err{Code: http.StatusBadRequest, Remark: "Error Review Too Long"}
+	}
+
+	req.BookId = bookId
+	req.Username = username
+	req.Status = ReviewStatusPending
+	req.ModerationNote = ""
+	if verdict := s.filter.Check(req.Title, req.Body); verdict.Flagged {
+		req.Status = ReviewStatusRejected
+		req.ModerationNote = reviewFilterPrefix + verdict.Reason
+	}
+
+	resp, err := s.query.InsertReview(ctx, req)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error InsertReview : %v", err)
+		return ResponseReview{}, reviewErr(err, "Error AddReview Service")
+	}
+	return resp, nil
+}
+
+func (s ReviewServices) ListBookReviews(ctx context.Context, bookId uint64, sort string, params GetAllParams) ([]ResponseReview, error) {
+	ctx, span := tracing.Start(ctx, "ReviewServices.ListBookReviews")
+	defer span.End()
+
+	if sort == "" {
+		sort = ReviewSortRecent
+	}
+	if _, ok := reviewOrders[sort]; !ok {
+		return nil, &c.Err{Code: http.StatusBadRequest, Remark: "Error Unknown Review Sort"}
+	}
+
+	resp, err := s.query.SelectBookReviews(ctx, bookId, sort, params)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error SelectBookReviews : %v", err)
+		return nil, reviewErr(err, "Error ListBookReviews Service")
+	}
+	return resp, nil
+}
+
+// ListReviews is the moderation queue; it shows pending reviews unless
+// another status is asked for.
+func (s ReviewServices) ListReviews(ctx context.Context, status string, params GetAllParams) ([]ResponseReview, error) {
+	ctx, span := tracing.Start(ctx, "ReviewServices.ListReviews")
+	defer span.End()
+
+	if status == "" {
+		status = ReviewStatusPending
+	}
+	if !validReviewStatus(status) {
+		return nil, &c.Err{Code: http.StatusBadRequest, Remark: "Error Unknown Review Status"}
+	}
+
+	resp, err := s.query.SelectReviewsByStatus(ctx, status, params)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error SelectReviewsByStatus : %v", err)
+		return nil, reviewErr(err, "Error ListReviews Service")
+	}
+	return resp, nil
+}
+
+func (s ReviewServices) ModerateReview(ctx context.Context, id uint64, moderator string, req RequestModeration) (ResponseReview, error) {
+	ctx, span := tracing.Start(ctx, "ReviewServices.ModerateReview")
+	defer span.End()
+
+	req.Note = strings.TrimSpace(req.Note)
+	if !validReviewStatus(req.Status) {
+		return ResponseReview{}, &c.Err{Code: http.StatusBadRequest, Remark: "Error Unknown Review Status"}
+	}
+	if utf8.RuneCountInString(req.Note) > reviewMaxNoteLen {
+		return ResponseReview{}, &c.Err{Code: http.StatusBadRequest, Remark: "Error Moderation Note Too Long"}
+	}
+	req.ModeratedBy = moderator
+
+	resp, err := s.query.UpdateReviewStatus(ctx, id, req)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error UpdateReviewStatus : %v", err)
+		return ResponseReview{}, reviewErr(err, "Error ModerateReview Service")
+	}
+	return resp, nil
+}
+
+func (s ReviewServices) VoteHelpful(ctx context.Context, id uint64, username string) (ResponseReview, error) {
+	ctx, span := tracing.Start(ctx, "ReviewServices.VoteHelpful")
+	defer span.End()
+
+	resp, err := s.query.InsertReviewVote(ctx, id, username)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error InsertReviewVote : %v", err)
+		return ResponseReview{}, reviewErr(err, "Error VoteHelpful Service")
+	}
+	return resp, nil
+}
+
+func validReviewStatus(status string) bool {
+	switch status {
+	case ReviewStatusPending, ReviewStatusApproved, ReviewStatusRejected:
+		return true
+	}
+	return false
+}
+
+func reviewErr(err error, remark string) *c.Err {
+	switch {
+	case err == sql.ErrNoRows:
+		return &c.Err{Code: http.StatusNotFound, Remark: "Error Review Not Found", Original: err}
+	case err == errReviewOwnVote:
+		return &c.Err{Code: http.StatusForbidden, Remark: "Error Cannot Vote On Own Review", Original: err}
+	case isPqError(err, pqUniqueViolation):
+		return &c.Err{Code: http.StatusConflict, Remark: "Error Book Already Reviewed", Original: err}
+	case isPqError(err, pqForeignKeyViolation):
+		return &c.Err{Code: http.StatusNotFound, Remark: "Error Book Not Found", Original: err}
+	default:
+		return &c.Err{Code: http.StatusInternalServerError, Remark: remark, Original: err}
+	}
+}
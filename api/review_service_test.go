@@ -0,0 +1,213 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"testing"
+
+	"github.com/lib/pq"
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/paquesqueue/bookstore/moderation"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type ReviewQueriesStub struct {
+	inserted  RequestReview
+	sort      string
+	status    string
+	moderated RequestModeration
+	err       error
+}
+
+func (q *ReviewQueriesStub) InsertReview(ctx context.Context, req RequestReview) (ResponseReview, error) {
+	q.inserted = req
+	return ResponseReview{Id: 1, BookId: req.BookId, Username: req.Username, Rating: req.Rating, Status: req.Status, ModerationNote: req.ModerationNote}, q.err
+}
+
+func (q *ReviewQueriesStub) SelectBookReviews(ctx context.Context, bookId uint64, sort string, params GetAllParams) ([]ResponseReview, error) {
+	q.sort = sort
+	return []ResponseReview{}, q.err
+}
+
+func (q *ReviewQueriesStub) SelectReviewsByStatus(ctx context.Context, status string, params GetAllParams) ([]ResponseReview, error) {
+	q.status = status
+	return []ResponseReview{}, q.err
+}
+
+func (q *ReviewQueriesStub) UpdateReviewStatus(ctx context.Context, id uint64, req RequestModeration) (ResponseReview, error) {
+	q.moderated = req
+	return ResponseReview{Id: id, Status: req.Status, ModeratedBy: req.ModeratedBy}, q.err
+}
+
+func (q *ReviewQueriesStub) InsertReviewVote(ctx context.Context, reviewId uint64, username string) (ResponseReview, error) {
+	return ResponseReview{Id: reviewId, HelpfulCount: 1}, q.err
+}
+
+func newTestReviewService(q *ReviewQueriesStub) ReviewServices {
+	return NewReviewService(q, moderation.NewDefaultFilter([]string{"darn"}), logrus.New())
+}
+
+func TestAddReview(t *testing.T) {
+	t.Run("TestAddReviewShouldQueueCleanReview", func(t *testing.T) {
+		// Arrange
+		query := &ReviewQueriesStub{}
+		services := newTestReviewService(query)
+
+		// Act
+		res, err := services.AddReview(context.Background(), 1, "reader", RequestReview{Rating: 5, Title: " Great ", Body: "Loved it"})
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, ReviewStatusPending, res.Status)
+			assert.Equal(t, "Great", query.inserted.Title)
+			assert.Equal(t, uint64(1), query.inserted.BookId)
+			assert.Equal(t, "reader", query.inserted.Username)
+		}
+	})
+
+	t.Run("TestAddReviewShouldRejectFlaggedReview", func(t *testing.T) {
+		// Arrange
+		query := &ReviewQueriesStub{}
+		services := newTestReviewService(query)
+
+		// Act
+		res, err := services.AddReview(context.Background(), 1, "reader", RequestReview{Rating: 1, Title: "Darn", Body: "Darn awful."})
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, ReviewStatusRejected, res.Status)
+			assert.Equal(t, "filter: "+moderation.ReasonProfanity, res.ModerationNote)
+		}
+	})
+
+	t.Run("TestAddReviewShouldReturnHTTPStatus400ForRatingOutOfRange", func(t *testing.T) {
+		// Arrange
+		services := newTestReviewService(&ReviewQueriesStub{})
+
+		// Act
+		_, err := services.AddReview(context.Background(), 1, "reader", RequestReview{Rating: 6})
+
+		// Assert
+		if assert.Error(t, err) {
+			assert.Equal(t, http.StatusBadRequest, err.(*c.Err).Code)
+		}
+	})
+
+	t.Run("TestAddReviewShouldReturnHTTPStatus409ForSecondReview", func(t *testing.T) {
+		// Arrange
+		services := newTestReviewService(&ReviewQueriesStub{err: &pq.Error{Code: pqUniqueViolation}})
+
+		// Act
+		_, err := services.AddReview(context.Background(), 1, "reader", RequestReview{Rating: 4})
+
+		// Assert
+		if assert.Error(t, err) {
+			assert.Equal(t, http.StatusConflict, err.(*c.Err).Code)
+		}
+	})
+}
+
+func TestListBookReviews(t *testing.T) {
+	t.Run("TestListBookReviewsShouldDefaultToRecent", func(t *testing.T) {
+		// Arrange
+		query := &ReviewQueriesStub{}
+		services := newTestReviewService(query)
+
+		// Act
+		_, err := services.ListBookReviews(context.Background(), 1, "", GetAllParams{Limit: 10})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, ReviewSortRecent, query.sort)
+	})
+
+	t.Run("TestListBookReviewsShouldReturnHTTPStatus400ForUnknownSort", func(t *testing.T) {
+		// Arrange
+		services := newTestReviewService(&ReviewQueriesStub{})
+
+		// Act
+		_, err := services.ListBookReviews(context.Background(), 1, "rating", GetAllParams{Limit: 10})
+
+		// Assert
+		if assert.Error(t, err) {
+			assert.Equal(t, http.StatusBadRequest, err.(*c.Err).Code)
+		}
+	})
+}
+
+func TestListReviews(t *testing.T) {
+	t.Run("TestListReviewsShouldDefaultToPending", func(t *testing.T) {
+		// Arrange
+		query := &ReviewQueriesStub{}
+		services := newTestReviewService(query)
+
+		// Act
+		_, err := services.ListReviews(context.Background(), "", GetAllParams{Limit: 10})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, ReviewStatusPending, query.status)
+	})
+}
+
+func TestModerateReview(t *testing.T) {
+	t.Run("TestModerateReviewShouldRecordModerator", func(t *testing.T) {
+		// Arrange
+		query := &ReviewQueriesStub{}
+		services := newTestReviewService(query)
+
+		// Act
+		res, err := services.ModerateReview(context.Background(), 7, "mod", RequestModeration{Status: ReviewStatusApproved, Note: " ok "})
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, "mod", res.ModeratedBy)
+			assert.Equal(t, "ok", query.moderated.Note)
+		}
+	})
+
+	t.Run("TestModerateReviewShouldReturnHTTPStatus404", func(t *testing.T) {
+		// Arrange
+		services := newTestReviewService(&ReviewQueriesStub{err: sql.ErrNoRows})
+
+		// Act
+		_, err := services.ModerateReview(context.Background(), 7, "mod", RequestModeration{Status: ReviewStatusApproved})
+
+		// Assert
+		if assert.Error(t, err) {
+			assert.Equal(t, http.StatusNotFound, err.(*c.Err).Code)
+		}
+	})
+
+	t.Run("TestModerateReviewShouldReturnHTTPStatus400ForUnknownStatus", func(t *testing.T) {
+		// Arrange
+		services := newTestReviewService(&ReviewQueriesStub{})
+
+		// Act
+		_, err := services.ModerateReview(context.Background(), 7, "mod", RequestModeration{Status: "hidden"})
+
+		// Assert
+		if assert.Error(t, err) {
+			assert.Equal(t, http.StatusBadRequest, err.(*c.Err).Code)
+		}
+	})
+}
+
+func TestVoteHelpful(t *testing.T) {
+	t.Run("TestVoteHelpfulShouldReturnHTTPStatus403ForOwnReview", func(t *testing.T) {
+		// Arrange
+		services := newTestReviewService(&ReviewQueriesStub{err: errReviewOwnVote})
+
+		// Act
+		_, err := services.VoteHelpful(context.Background(), 7, "reader")
+
+		// Assert
+		if assert.Error(t, err) {
+			assert.Equal(t, http.StatusForbidden, err.(*c.Err).Code)
+		}
+	})
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// InsertSession stores a new login and drops the user's expired sessions so
+// the table does not grow without bound.
+func (db Query) InsertSession(ctx context.Context, tokenHash string, username string, expiresAt time.Time) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM sessions WHERE username = $1 AND expires_at <= NOW();`, username)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO sessions (token_hash, username, expires_at) VALUES ($1, $2, $3);`, tokenHash, username, expiresAt)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SelectSessionUser returns the user owning an unexpired session.
+func (db Query) SelectSessionUser(ctx context.Context, tokenHash string) (ResponseUser, error) {
+	const query = `SELECT u.username, u.email, u.fullname, u.hashed_password, u.created_at, u.role, u.mfa_enabled_at
+	FROM sessions s
+	JOIN users u ON u.username = s.username
+	WHERE s.token_hash = $1 AND s.expires_at > NOW();`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return ResponseUser{}, err
+	}
+	defer stmt.Close()
+
+	row := stmt.QueryRowContext(ctx, tokenHash)
+	resp := ResponseUser{}
+	var mfaEnabledAt sql.NullTime
+	err = row.Scan(&resp.Username, &resp.Email, &resp.Fullname, &resp.HashedPassword, &resp.CreatedAt, &resp.Role, &mfaEnabledAt)
+	if err != nil {
+		return ResponseUser{}, err
+	}
+	resp.MFAEnabledAt = nullTime(mfaEnabledAt)
+	return resp, nil
+}
+
+func (db Query) DeleteSession(ctx context.Context, tokenHash string) error {
+	const query = `DELETE FROM sessions WHERE token_hash = $1;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, tokenHash)
+	return err
+}
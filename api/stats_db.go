@@ -0,0 +1,28 @@
+package api
+
+import "context"
+
+// StoreStats are catalogue-wide totals reported as business metrics.
+type StoreStats struct {
+	Books      int64
+	StockValue int64
+	Users      int64
+}
+
+func (db Query) SelectStoreStats(ctx context.Context) (StoreStats, error) {
+	const query = `
+	SELECT
+		(SELECT COUNT(*) FROM books),
+		(SELECT COALESCE(SUM(price * quantity), 0) FROM books),
+		(SELECT COUNT(*) FROM users);
+	`
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return StoreStats{}, err
+	}
+	defer stmt.Close()
+
+	var stats StoreStats
+	err = stmt.QueryRowContext(ctx).Scan(&stats.Books, &stats.StockValue, &stats.Users)
+	return stats, err
+}
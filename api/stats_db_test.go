@@ -0,0 +1,54 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectStoreStats(t *testing.T) {
+	t.Run("TestSelectStoreStatsShouldReturnTotals", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectPrepare(regexp.QuoteMeta(`SELECT (SELECT COUNT(*) FROM books), (SELECT COALESCE(SUM(price * quantity), 0) FROM books),`)).
+			ExpectQuery().
+			WillReturnRows(sqlmock.NewRows([]string{"books", "stock_value", "users"}).AddRow(12, 98000, 5))
+
+		query := NewDB(db)
+
+		// Act
+		stats, err := query.SelectStoreStats(context.Background())
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, StoreStats{Books: 12, StockValue: 98000, Users: 5}, stats)
+	})
+
+	t.Run("TestSelectStoreStatsShouldReturnError", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectPrepare(regexp.QuoteMeta(`SELECT`)).
+			ExpectQuery().
+			WillReturnError(errors.New("connection refused"))
+
+		query := NewDB(db)
+
+		// Act
+		_, err = query.SelectStoreStats(context.Background())
+
+		// Assert
+		assert.Error(t, err)
+	})
+}
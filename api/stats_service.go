@@ -0,0 +1,30 @@
+package api
+
+import (
+	"context"
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/paquesqueue/bookstore/metrics"
+)
+
+type StatsQueries interface {
+	SelectStoreStats(ctx context.Context) (StoreStats, error)
+}
+
+// RegisterStoreMetrics reports the store totals, read from the database on
+// every scrape. A failed read keeps the previous values.
+func RegisterStoreMetrics(reg *metrics.Registry, q StatsQueries, l c.Log) {
+	books := reg.Gauge("bookstore_books", "Books in the catalogue.")
+	stockValue := reg.Gauge("bookstore_stock_value", "Price times quantity summed over every book.")
+	users := reg.Gauge("bookstore_users", "Registered users.")
+
+	reg.OnScrape(func() {
+		stats, err := q.SelectStoreStats(context.Background())
+		if err != nil {
+			l.Errorf("Error SelectStoreStats : %v", err)
+			return
+		}
+		books.With().Set(float64(stats.Books))
+		stockValue.With().Set(float64(stats.StockValue))
+		users.With().Set(float64(stats.Users))
+	})
+}
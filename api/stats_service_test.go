@@ -0,0 +1,60 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/paquesqueue/bookstore/metrics"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type StatsQueriesStub struct {
+	stats []StoreStats
+	err   error
+}
+
+func (q *StatsQueriesStub) SelectStoreStats(ctx context.Context) (StoreStats, error) {
+	if q.err != nil {
+		return StoreStats{}, q.err
+	}
+	stats := q.stats[0]
+	q.stats = q.stats[1:]
+	return stats, nil
+}
+
+func TestRegisterStoreMetrics(t *testing.T) {
+	t.Run("TestStoreMetricsShouldReadTotalsOnScrape", func(t *testing.T) {
+		// Arrange
+		reg := metrics.NewRegistry()
+		q := &StatsQueriesStub{stats: []StoreStats{{Books: 1, StockValue: 100, Users: 1}, {Books: 2, StockValue: 350, Users: 4}}}
+		RegisterStoreMetrics(reg, q, logrus.New())
+
+		// Act
+		scrapeMetrics(t, reg)
+		out := scrapeMetrics(t, reg)
+
+		// Assert
+		assert.Contains(t, out, "\nbookstore_books 2\n")
+		assert.Contains(t, out, "\nbookstore_stock_value 350\n")
+		assert.Contains(t, out, "\nbookstore_users 4\n")
+	})
+
+	t.Run("TestStoreMetricsShouldKeepValuesWhenReadFails", func(t *testing.T) {
+		// Arrange
+		reg := metrics.NewRegistry()
+		q := &StatsQueriesStub{stats: []StoreStats{{Books: 7}}}
+		RegisterStoreMetrics(reg, q, logrus.New())
+		scrapeMetrics(t, reg)
+		q.err = errors.New("connection refused")
+
+		// Act
+		out := scrapeMetrics(t, reg)
+
+		// Assert
+		assert.Contains(t, out, "\nbookstore_books 7\n")
+	})
+}
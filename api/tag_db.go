@@ -0,0 +1,173 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// tagKey normalises a free-form tag for de-duplication. Unlike nameKey it
+// keeps punctuation, so "C++" and "C#" remain distinct tags.
+func tagKey(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+func (db Query) InsertTag(ctx context.Context, req RequestTag) (ResponseTag, error) {
+	const query = `INSERT INTO tags
+	(name, name_key)
+	VALUES ($1, $2)
+	RETURNING id, name, created_at;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return ResponseTag{}, err
+	}
+	defer stmt.Close()
+
+	resp := ResponseTag{}
+	err = stmt.QueryRowContext(ctx, strings.TrimSpace(req.Name), tagKey(req.Name)).Scan(&resp.Id, &resp.Name, &resp.CreatedAt)
+	if err != nil {
+		return ResponseTag{}, err
+	}
+	return resp, nil
+}
+
+func (db Query) SelectAllTags(ctx context.Context, params GetAllParams) ([]ResponseTag, error) {
+	const query = `SELECT id, name, created_at
+	FROM tags
+	ORDER BY name_key
+	LIMIT $1
+	OFFSET $2;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, params.Limit, params.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resp := []ResponseTag{}
+	for rows.Next() {
+		result := ResponseTag{}
+		err = rows.Scan(&result.Id, &result.Name, &result.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		resp = append(resp, result)
+	}
+	return resp, rows.Err()
+}
+
+func (db Query) UpdateTag(ctx context.Context, id uint64, req RequestTag) (ResponseTag, error) {
+	const query = `UPDATE tags
+	SET name = $1, name_key = $2
+	WHERE id = $3
+	RETURNING id, name, created_at;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return ResponseTag{}, err
+	}
+	defer stmt.Close()
+
+	resp := ResponseTag{}
+	err = stmt.QueryRowContext(ctx, strings.TrimSpace(req.Name), tagKey(req.Name), id).Scan(&resp.Id, &resp.Name, &resp.CreatedAt)
+	if err != nil {
+		return ResponseTag{}, err
+	}
+	return resp, nil
+}
+
+func (db Query) DeleteTag(ctx context.Context, id uint64) error {
+	const query = `DELETE FROM tags WHERE id = $1;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (db Query) SelectBookTags(ctx context.Context, bookId uint64) ([]ResponseTag, error) {
+	const query = `SELECT t.id, t.name, t.created_at
+	FROM book_tags bt
+	JOIN tags t ON t.id = bt.tag_id
+	WHERE bt.book_id = $1
+	ORDER BY t.name_key;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, bookId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resp := []ResponseTag{}
+	for rows.Next() {
+		result := ResponseTag{}
+		err = rows.Scan(&result.Id, &result.Name, &result.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		resp = append(resp, result)
+	}
+	return resp, rows.Err()
+}
+
+// SetBookTags replaces the tags of a book, creating any tag not seen before.
+func (db Query) SetBookTags(ctx context.Context, bookId uint64, tags []string) error {
+	const upsertTag = `INSERT INTO tags (name, name_key) VALUES ($1, $2)
+	ON CONFLICT (name_key) DO UPDATE SET name_key = EXCLUDED.name_key
+	RETURNING id;`
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM book_tags WHERE book_id = $1;`, bookId)
+	if err != nil {
+		return err
+	}
+	for _, name := range tags {
+		key := tagKey(name)
+		if key == "" {
+			continue
+		}
+		var tagId uint64
+		err = tx.QueryRow(upsertTag, strings.TrimSpace(name), key).Scan(&tagId)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO book_tags (book_id, tag_id) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING;`, bookId, tagId)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,50 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTagKey(t *testing.T) {
+	t.Run("TestTagKeyShouldKeepPunctuation", func(t *testing.T) {
+		// Arrange & Act & Assert
+		assert.Equal(t, "science fiction", tagKey("  Science   FICTION "))
+		assert.NotEqual(t, tagKey("C++"), tagKey("C#"))
+	})
+}
+
+func TestSetBookTags(t *testing.T) {
+	t.Run("TestSetBookTagsShouldUpsertTags", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM book_tags WHERE book_id = $1;`)).
+			WithArgs(5).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO tags (name, name_key) VALUES ($1, $2) ON CONFLICT (name_key)`)).
+			WithArgs("Space Opera", "space opera").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(8))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO book_tags (book_id, tag_id) VALUES ($1, $2)`)).
+			WithArgs(5, 8).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		query := NewDB(db)
+
+		// Act
+		err = query.SetBookTags(context.Background(), 5, []string{" Space Opera", "  "})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
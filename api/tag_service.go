@@ -0,0 +1,134 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/paquesqueue/bookstore/tracing"
+)
+
+type TagQueries interface {
+	InsertTag(ctx context.Context, req RequestTag) (ResponseTag, error)
+	SelectAllTags(ctx context.Context, params GetAllParams) ([]ResponseTag, error)
+	UpdateTag(ctx context.Context, id uint64, req RequestTag) (ResponseTag, error)
+	DeleteTag(ctx context.Context, id uint64) error
+	SelectBookByID(ctx context.Context, id uint64) (*ResponseBook, error)
+	SelectBookTags(ctx context.Context, bookId uint64) ([]ResponseTag, error)
+	SetBookTags(ctx context.Context, bookId uint64, tags []string) error
+}
+
+type TagServices struct {
+	query TagQueries
+	log   c.Log
+}
+
+func NewTagService(q TagQueries, l c.Log) TagServices {
+	return TagServices{q, l}
+}
+
+func (s TagServices) AddTag(ctx context.Context, req RequestTag) (ResponseTag, error) {
+	ctx, span := tracing.Start(ctx, "TagServices.AddTag")
+	defer span.End()
+
+	if tagKey(req.Name) == "" {
+		return ResponseTag{}, &c.Err{Code: http.StatusBadRequest, Remark: "Error Tag Name Required"}
+	}
+
+	resp, err := s.query.InsertTag(ctx, req)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error InsertTag : %v", err)
+		return ResponseTag{}, tagErr(err, "Error AddTag Service")
+	}
+	return resp, nil
+}
+
+func (s TagServices) ListTags(ctx context.Context, params GetAllParams) ([]ResponseTag, error) {
+	ctx, span := tracing.Start(ctx, "TagServices.ListTags")
+	defer span.End()
+
+	resp, err := s.query.SelectAllTags(ctx, params)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error SelectAllTags : %v", err)
+		return nil, &c.Err{Code: http.StatusInternalServerError, Remark: "Error ListTags Service", Original: err}
+	}
+	return resp, nil
+}
+
+func (s TagServices) PutTag(ctx context.Context, id uint64, req RequestTag) (ResponseTag, error) {
+	ctx, span := tracing.Start(ctx, "TagServices.PutTag")
+	defer span.End()
+
+	if tagKey(req.Name) == "" {
+		return ResponseTag{}, &c.Err{Code: http.StatusBadRequest, Remark: "Error Tag Name Required"}
+	}
+
+	resp, err := s.query.UpdateTag(ctx, id, req)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error UpdateTag : %v", err)
+		return ResponseTag{}, tagErr(err, "Error PutTag Service")
+	}
+	return resp, nil
+}
+
+func (s TagServices) DeleteTag(ctx context.Context, id uint64) error {
+	ctx, span := tracing.Start(ctx, "TagServices.DeleteTag")
+	defer span.End()
+
+	err := s.query.DeleteTag(ctx, id)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error DeleteTag : %v", err)
+		return tagErr(err, "Error DeleteTag Service")
+	}
+	return nil
+}
+
+func (s TagServices) GetBookTags(ctx context.Context, bookId uint64) ([]ResponseTag, error) {
+	ctx, span := tracing.Start(ctx, "TagServices.GetBookTags")
+	defer span.End()
+
+	_, err := s.query.SelectBookByID(ctx, bookId)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error SelectBookByID : %v", err)
+		switch err {
+		case sql.ErrNoRows:
+			return nil, &c.Err{Code: http.StatusNotFound, Remark: "Error Book Not Found", Original: err}
+		default:
+			return nil, &c.Err{Code: http.StatusInternalServerError, Remark: "Error GetBook Service", Original: err}
+		}
+	}
+
+	resp, err := s.query.SelectBookTags(ctx, bookId)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error SelectBookTags : %v", err)
+		return nil, &c.Err{Code: http.StatusInternalServerError, Remark: "Error GetBookTags Service", Original: err}
+	}
+	return resp, nil
+}
+
+func (s TagServices) PutBookTags(ctx context.Context, bookId uint64, req RequestBookTags) ([]ResponseTag, error) {
+	ctx, span := tracing.Start(ctx, "TagServices.PutBookTags")
+	defer span.End()
+
+	err := s.query.SetBookTags(ctx, bookId, req.Tags)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error SetBookTags : %v", err)
+		if isPqError(err, pqForeignKeyViolation) {
+			return nil, &c.Err{Code: http.StatusNotFound, Remark: "Error Book Not Found", Original: err}
+		}
+		return nil, &c.Err{Code: http.StatusInternalServerError, Remark: "Error PutBookTags Service", Original: err}
+	}
+	return s.GetBookTags(ctx, bookId)
+}
+
+func tagErr(err error, remark string) *c.Err {
+	switch {
+	case err == sql.ErrNoRows:
+		return &c.Err{Code: http.StatusNotFound, Remark: "Error Tag Not Found", Original: err}
+	case isPqError(err, pqUniqueViolation):
+		return &c.Err{Code: http.StatusConflict, Remark: "Error Tag Already Exists", Original: err}
+	default:
+		return &c.Err{Code: http.StatusInternalServerError, Remark: remark, Original: err}
+	}
+}
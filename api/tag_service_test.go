@@ -0,0 +1,121 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/lib/pq"
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+type TagQueriesStub struct {
+	err  error
+	tags []string
+}
+
+func (s *TagQueriesStub) InsertTag(ctx context.Context, req RequestTag) (ResponseTag, error) {
+	return ResponseTag{Id: 1, Name: req.Name}, s.err
+}
+
+func (s *TagQueriesStub) SelectAllTags(ctx context.Context, params GetAllParams) ([]ResponseTag, error) {
+	return []ResponseTag{{Id: 1, Name: "golang"}}, s.err
+}
+
+func (s *TagQueriesStub) UpdateTag(ctx context.Context, id uint64, req RequestTag) (ResponseTag, error) {
+	return ResponseTag{Id: id, Name: req.Name}, s.err
+}
+
+func (s *TagQueriesStub) DeleteTag(ctx context.Context, id uint64) error {
+	return s.err
+}
+
+func (s *TagQueriesStub) SelectBookByID(ctx context.Context, id uint64) (*ResponseBook, error) {
+	return &ResponseBook{Id: id}, nil
+}
+
+func (s *TagQueriesStub) SelectBookTags(ctx context.Context, bookId uint64) ([]ResponseTag, error) {
+	resp := []ResponseTag{}
+	for i, name := range s.tags {
+		resp = append(resp, ResponseTag{Id: uint64(i + 1), Name: name})
+	}
+	return resp, nil
+}
+
+func (s *TagQueriesStub) SetBookTags(ctx context.Context, bookId uint64, tags []string) error {
+	s.tags = tags
+	return s.err
+}
+
+func TestAddTagService(t *testing.T) {
+	t.Run("TestAddTagServiceShouldReturnBadRequestOnEmptyName", func(t *testing.T) {
+		// Arrange
+		service := NewTagService(&TagQueriesStub{}, logrus.New())
+
+		// Act
+		_, err := service.AddTag(context.Background(), RequestTag{Name: "   "})
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, err.(*c.Err).Code)
+	})
+
+	t.Run("TestAddTagServiceShouldReturnConflict", func(t *testing.T) {
+		// Arrange
+		service := NewTagService(&TagQueriesStub{err: &pq.Error{Code: pqUniqueViolation}}, logrus.New())
+
+		// Act
+		_, err := service.AddTag(context.Background(), RequestTag{Name: "golang"})
+
+		// Assert
+		assert.Equal(t, http.StatusConflict, err.(*c.Err).Code)
+	})
+
+	t.Run("TestAddTagServiceShouldLogWithRequestFields", func(t *testing.T) {
+		// Arrange
+		log, hook := test.NewNullLogger()
+		service := NewTagService(&TagQueriesStub{err: errors.New("connection reset")}, log)
+		ctx := c.WithLogFields(context.Background(), c.Fields{"request_id": "req-1", "user": "alice"})
+
+		// Act
+		_, err := service.AddTag(ctx, RequestTag{Name: "golang"})
+
+		// Assert
+		assert.Error(t, err)
+		entry := hook.LastEntry()
+		assert.Equal(t, logrus.ErrorLevel, entry.Level)
+		assert.Equal(t, "Error InsertTag : connection reset", entry.Message)
+		assert.Equal(t, "req-1", entry.Data["request_id"])
+		assert.Equal(t, "alice", entry.Data["user"])
+	})
+}
+
+func TestPutBookTagsService(t *testing.T) {
+	t.Run("TestPutBookTagsServiceShouldReturnTags", func(t *testing.T) {
+		// Arrange
+		service := NewTagService(&TagQueriesStub{}, logrus.New())
+
+		// Act
+		resp, err := service.PutBookTags(context.Background(), 1, RequestBookTags{Tags: []string{"golang", "web"}})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Len(t, resp, 2)
+	})
+
+	t.Run("TestPutBookTagsServiceShouldReturnNotFound", func(t *testing.T) {
+		// Arrange
+		service := NewTagService(&TagQueriesStub{err: &pq.Error{Code: pqForeignKeyViolation}}, logrus.New())
+
+		// Act
+		_, err := service.PutBookTags(context.Background(), 1, RequestBookTags{Tags: []string{"golang"}})
+
+		// Assert
+		assert.Equal(t, http.StatusNotFound, err.(*c.Err).Code)
+	})
+}
@@ -0,0 +1,213 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+)
+
+// SelectUserReviews returns every review a user wrote, whatever its status.
+func (db Query) SelectUserReviews(ctx context.Context, username string) ([]ResponseReview, error) {
+	const query = `SELECT ` + reviewColumns + ` FROM reviews WHERE username = $1 ORDER BY created_at, id;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	return scanReviews(rows)
+}
+
+// SelectUserVotes returns the reviews a user found helpful.
+func (db Query) SelectUserVotes(ctx context.Context, username string) ([]ExportVote, error) {
+	const query = `SELECT review_id, created_at FROM review_votes WHERE username = $1 ORDER BY created_at, review_id;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	votes := []ExportVote{}
+	for rows.Next() {
+		vote := ExportVote{}
+		if err := rows.Scan(&vote.ReviewId, &vote.CreatedAt); err != nil {
+			return nil, err
+		}
+		votes = append(votes, vote)
+	}
+	return votes, rows.Err()
+}
+
+// SelectUserSessions returns when a user's sessions were opened and run
+// out. Session tokens are only held hashed and are left out.
+func (db Query) SelectUserSessions(ctx context.Context, username string) ([]ExportSession, error) {
+	const query = `SELECT created_at, expires_at FROM sessions WHERE username = $1 ORDER BY created_at;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []ExportSession{}
+	for rows.Next() {
+		session := ExportSession{}
+		if err := rows.Scan(&session.CreatedAt, &session.ExpiresAt); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// SelectUserTokens returns the verification and password reset emails sent
+// to a user.
+func (db Query) SelectUserTokens(ctx context.Context, username string) ([]ExportAccountEmail, error) {
+	const query = `SELECT purpose, email, created_at, expires_at, used_at FROM user_tokens WHERE username = $1 ORDER BY created_at;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	emails := []ExportAccountEmail{}
+	for rows.Next() {
+		email := ExportAccountEmail{}
+		var usedAt sql.NullTime
+		if err := rows.Scan(&email.Purpose, &email.Email, &email.CreatedAt, &email.ExpiresAt, &usedAt); err != nil {
+			return nil, err
+		}
+		email.UsedAt = nullTime(usedAt)
+		emails = append(emails, email)
+	}
+	return emails, rows.Err()
+}
+
+// SelectLoginFailure returns the failed logins counted against a username,
+// or nil when there are none.
+func (db Query) SelectLoginFailure(ctx context.Context, username string) (*ExportLoginFailure, error) {
+	const query = `SELECT failures, last_failed_at, locked_until FROM login_failures WHERE scope = $1 AND subject = $2;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	failure := ExportLoginFailure{}
+	var lockedUntil sql.NullTime
+	err = stmt.QueryRowContext(ctx, LoginScopeUser, username).Scan(&failure.Failures, &failure.LastFailedAt, &lockedUntil)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	failure.LockedUntil = nullTime(lockedUntil)
+	return &failure, nil
+}
+
+// EraseUser anonymises a user under placeholder and records a receipt.
+// The users row stays, renamed, so the ratings and helpful votes the user
+// left still count towards the books and reviews they belong to; the text
+// of their reviews is cleared. Their lists, keys, sessions, tokens and
+// recovery codes are deleted, and columns holding the old name alone are
+// moved to placeholder. It returns sql.ErrNoRows for an unknown user.
+func (db Query) EraseUser(ctx context.Context, username string, placeholder string, subjectHash string, by string) (ResponseErasure, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return ResponseErasure{}, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`UPDATE users SET username = $2, email = $2 || '@erased.invalid', fullname = '', hashed_password = '',
+		role = $3, email_verified_at = NULL, mfa_secret = NULL, mfa_enabled_at = NULL, mfa_last_step = NULL, erased_at = NOW()
+	WHERE username = $1 AND erased_at IS NULL;`, username, placeholder, UserRoleCustomer)
+	if err != nil {
+		return ResponseErasure{}, err
+	}
+	if err := expectRows(res); err != nil {
+		return ResponseErasure{}, err
+	}
+
+	receipt := ResponseErasure{Username: placeholder, SubjectHash: subjectHash, ErasedBy: by}
+	counts := []struct {
+		query string
+		n     *int64
+	}{
+		{`UPDATE reviews SET title = '', body = '' WHERE username = $1;`, &receipt.ReviewsAnonymised},
+		{`DELETE FROM book_lists WHERE username = $1;`, &receipt.ListsDeleted},
+		{`DELETE FROM api_keys WHERE username = $1;`, &receipt.APIKeysDeleted},
+		{`DELETE FROM sessions WHERE username = $1;`, &receipt.SessionsDeleted},
+	}
+	for _, count := range counts {
+		*count.n, err = execCount(tx, count.query, placeholder)
+		if err != nil {
+			return ResponseErasure{}, err
+		}
+	}
+	for _, query := range []string{
+		`DELETE FROM user_tokens WHERE username = $1;`,
+		`DELETE FROM mfa_recovery_codes WHERE username = $1;`,
+	} {
+		if _, err := tx.Exec(query, placeholder); err != nil {
+			return ResponseErasure{}, err
+		}
+	}
+	for _, query := range []string{
+		`UPDATE reviews SET moderated_by = $2 WHERE moderated_by = $1;`,
+		`UPDATE books SET created_by = $2 WHERE created_by = $1;`,
+	} {
+		if _, err := tx.Exec(query, username, placeholder); err != nil {
+			return ResponseErasure{}, err
+		}
+	}
+	_, err = tx.Exec(`DELETE FROM login_failures WHERE scope = $1 AND subject = $2;`, LoginScopeUser, username)
+	if err != nil {
+		return ResponseErasure{}, err
+	}
+
+	err = tx.QueryRow(`INSERT INTO user_erasures
+	(username, subject_hash, erased_by, reviews_anonymised, lists_deleted, api_keys_deleted, sessions_deleted)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	RETURNING id, erased_at;`, placeholder, subjectHash, by,
+		receipt.ReviewsAnonymised, receipt.ListsDeleted, receipt.APIKeysDeleted, receipt.SessionsDeleted).
+		Scan(&receipt.Id, &receipt.ErasedAt)
+	if err != nil {
+		return ResponseErasure{}, err
+	}
+	return receipt, tx.Commit()
+}
+
+// execCount runs query in tx and returns how many rows it changed.
+func execCount(tx *sql.Tx, query string, args ...interface{}) (int64, error) {
+	res, err := tx.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,151 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectUserVotes(t *testing.T) {
+	t.Run("TestSelectUserVotesShouldReturnVotes", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		votedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		mock.ExpectPrepare(regexp.QuoteMeta(`SELECT review_id, created_at FROM review_votes WHERE username = $1 ORDER BY created_at, review_id;`)).
+			ExpectQuery().
+			WithArgs("tester").
+			WillReturnRows(sqlmock.NewRows([]string{"review_id", "created_at"}).AddRow(7, votedAt))
+
+		query := NewDB(db)
+
+		// Act
+		votes, err := query.SelectUserVotes(context.Background(), "tester")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, []ExportVote{{ReviewId: 7, CreatedAt: votedAt}}, votes)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestSelectLoginFailure(t *testing.T) {
+	t.Run("TestSelectLoginFailureShouldReturnNilWithoutFailures", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectPrepare(regexp.QuoteMeta(`SELECT failures, last_failed_at, locked_until FROM login_failures WHERE scope = $1 AND subject = $2;`)).
+			ExpectQuery().
+			WithArgs(LoginScopeUser, "tester").
+			WillReturnError(sql.ErrNoRows)
+
+		query := NewDB(db)
+
+		// Act
+		failure, err := query.SelectLoginFailure(context.Background(), "tester")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Nil(t, failure)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestEraseUserQuery(t *testing.T) {
+	t.Run("TestEraseUserQueryShouldAnonymiseAndRecordReceipt", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		erasedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET username = $2, email = $2 || '@erased.invalid', fullname = '', hashed_password = '',`)).
+			WithArgs("tester", "erased-x", UserRoleCustomer).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE reviews SET title = '', body = '' WHERE username = $1;`)).
+			WithArgs("erased-x").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM book_lists WHERE username = $1;`)).
+			WithArgs("erased-x").
+			WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM api_keys WHERE username = $1;`)).
+			WithArgs("erased-x").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM sessions WHERE username = $1;`)).
+			WithArgs("erased-x").
+			WillReturnResult(sqlmock.NewResult(0, 4))
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM user_tokens WHERE username = $1;`)).
+			WithArgs("erased-x").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM mfa_recovery_codes WHERE username = $1;`)).
+			WithArgs("erased-x").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE reviews SET moderated_by = $2 WHERE moderated_by = $1;`)).
+			WithArgs("tester", "erased-x").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE books SET created_by = $2 WHERE created_by = $1;`)).
+			WithArgs("tester", "erased-x").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM login_failures WHERE scope = $1 AND subject = $2;`)).
+			WithArgs(LoginScopeUser, "tester").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO user_erasures`)).
+			WithArgs("erased-x", "subject-hash", "admin", int64(2), int64(3), int64(1), int64(4)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "erased_at"}).AddRow(9, erasedAt))
+		mock.ExpectCommit()
+
+		query := NewDB(db)
+
+		// Act
+		receipt, err := query.EraseUser(context.Background(), "tester", "erased-x", "subject-hash", "admin")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, ResponseErasure{
+			Id:                9,
+			Username:          "erased-x",
+			SubjectHash:       "subject-hash",
+			ErasedBy:          "admin",
+			ReviewsAnonymised: 2,
+			ListsDeleted:      3,
+			APIKeysDeleted:    1,
+			SessionsDeleted:   4,
+			ErasedAt:          erasedAt,
+		}, receipt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestEraseUserQueryShouldReturnErrNoRowsForUnknownUser", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET username = $2`)).
+			WithArgs("nobody", "erased-x", UserRoleCustomer).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		query := NewDB(db)
+
+		// Act
+		_, err = query.EraseUser(context.Background(), "nobody", "erased-x", "subject-hash", "admin")
+
+		// Assert
+		assert.Equal(t, sql.ErrNoRows, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
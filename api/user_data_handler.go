@@ -0,0 +1,53 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	c "github.com/paquesqueue/bookstore/common"
+)
+
+// ExportUserData sends a ZIP of everything held about a user, to the user
+// or an admin.
+func (h UserHandlr) ExportUserData(ctx echo.Context) error {
+	username, by, ok := accountManager(ctx)
+	if !ok {
+		return ctx.NoContent(http.StatusForbidden)
+	}
+
+	data, err := h.handler.ExportUserData(ctx.Request().Context(), username, by)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error ExportUserData Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	ctx.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", username+"-data.zip"))
+	return ctx.Blob(http.StatusOK, "application/zip", data)
+}
+
+// EraseUser anonymises a user's personal data and returns the receipt.
+func (h UserHandlr) EraseUser(ctx echo.Context) error {
+	username, by, ok := accountManager(ctx)
+	if !ok {
+		return ctx.NoContent(http.StatusForbidden)
+	}
+	req := RequestErase{}
+	err := ctx.Bind(&req)
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+	req.IP = ctx.RealIP()
+
+	resp, err := h.handler.EraseUser(ctx.Request().Context(), username, req, by)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error EraseUser Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, resp)
+}
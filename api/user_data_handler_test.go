@@ -0,0 +1,173 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// UserDataHandlrStub records the exports and erasures passed on to the
+// service.
+type UserDataHandlrStub struct {
+	UserHandlrSuccess
+	username string
+	by       string
+	req      RequestErase
+	err      error
+}
+
+func (h *UserDataHandlrStub) ExportUserData(ctx context.Context, username string, by string) ([]byte, error) {
+	h.username, h.by = username, by
+	return []byte("PK"), h.err
+}
+
+func (h *UserDataHandlrStub) EraseUser(ctx context.Context, username string, req RequestErase, by string) (ResponseErasure, error) {
+	h.username, h.req, h.by = username, req, by
+	return ResponseErasure{Id: 1, Username: "erased-0123456789abcdef", ErasedBy: by}, h.err
+}
+
+func newUserDataContext(method string, body string, user *ResponseUser) (echo.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(method, "/users/reader/erase", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(echo.HeaderXRealIP, "10.0.0.1")
+	rec := httptest.NewRecorder()
+	ctx := echo.New().NewContext(req, rec)
+	ctx.SetParamNames("username")
+	ctx.SetParamValues("reader")
+	if user != nil {
+		ctx.Set(ctxUserKey, *user)
+	}
+	return ctx, rec
+}
+
+func TestExportUserDataHandler(t *testing.T) {
+	t.Run("TestExportUserDataHandlerShouldSendZipToOwner", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newUserDataContext(http.MethodGet, "", &ResponseUser{Username: "reader"})
+		stub := &UserDataHandlrStub{}
+		handler := NewUserHandler(stub, logrus.New())
+
+		// Act
+		err := handler.ExportUserData(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/zip", rec.Header().Get(echo.HeaderContentType))
+		assert.Equal(t, `attachment; filename="reader-data.zip"`, rec.Header().Get(echo.HeaderContentDisposition))
+		assert.Equal(t, "PK", rec.Body.String())
+		assert.Equal(t, "reader", stub.by)
+	})
+
+	t.Run("TestExportUserDataHandlerShouldReturnHTTPStatus403ForOtherUser", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newUserDataContext(http.MethodGet, "", &ResponseUser{Username: "intruder"})
+		stub := &UserDataHandlrStub{}
+		handler := NewUserHandler(stub, logrus.New())
+
+		// Act
+		err := handler.ExportUserData(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Empty(t, stub.username)
+	})
+
+	t.Run("TestExportUserDataHandlerShouldReturnHTTPStatus403ForAPIKey", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newUserDataContext(http.MethodGet, "", &ResponseUser{Username: "reader"})
+		ctx.Set(ctxAPIKeyKey, ResponseAPIKey{Id: 1, Username: "reader", Scopes: []string{"users:write"}})
+		stub := &UserDataHandlrStub{}
+		handler := NewUserHandler(stub, logrus.New())
+
+		// Act
+		err := handler.ExportUserData(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Empty(t, stub.username)
+	})
+
+	t.Run("TestExportUserDataHandlerShouldReturnServiceStatus", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newUserDataContext(http.MethodGet, "", &ResponseUser{Username: "reader"})
+		stub := &UserDataHandlrStub{err: &c.Err{Code: http.StatusNotFound}}
+		handler := NewUserHandler(stub, logrus.New())
+
+		// Act
+		err := handler.ExportUserData(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestEraseUserHandler(t *testing.T) {
+	enabledAt := time.Now()
+
+	t.Run("TestEraseUserHandlerShouldPassPasswordAndIPOfOwner", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newUserDataContext(http.MethodPost, `{"password":"123456"}`, &ResponseUser{Username: "reader"})
+		stub := &UserDataHandlrStub{}
+		handler := NewUserHandler(stub, logrus.New())
+
+		// Act
+		err := handler.EraseUser(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "123456", stub.req.Password)
+		assert.Equal(t, "10.0.0.1", stub.req.IP)
+		assert.Equal(t, "reader", stub.by)
+
+		res := ResponseErasure{}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		assert.Equal(t, "erased-0123456789abcdef", res.Username)
+	})
+
+	t.Run("TestEraseUserHandlerShouldLetAdminWithMFAErase", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newUserDataContext(http.MethodPost, `{}`, &ResponseUser{Username: "admin", Role: UserRoleAdmin, MFAEnabledAt: &enabledAt})
+		stub := &UserDataHandlrStub{}
+		handler := NewUserHandler(stub, logrus.New())
+
+		// Act
+		err := handler.EraseUser(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "reader", stub.username)
+		assert.Equal(t, "admin", stub.by)
+	})
+
+	t.Run("TestEraseUserHandlerShouldReturnHTTPStatus403ForAdminWithoutMFA", func(t *testing.T) {
+		// Arrange
+		ctx, rec := newUserDataContext(http.MethodPost, `{}`, &ResponseUser{Username: "admin", Role: UserRoleAdmin})
+		stub := &UserDataHandlrStub{}
+		handler := NewUserHandler(stub, logrus.New())
+
+		// Act
+		err := handler.EraseUser(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Empty(t, stub.username)
+	})
+}
@@ -0,0 +1,257 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/paquesqueue/bookstore/tracing"
+	"github.com/paquesqueue/bookstore/utils"
+)
+
+// erasedUsernamePrefix starts the placeholder an erased user is renamed to.
+const erasedUsernamePrefix = "erased-"
+
+// exportExcluded is what a data export leaves out, and why, as listed in
+// its manifest.
+var exportExcluded = map[string]string{
+	"credentials":  "password, secret and token hashes are never exported",
+	"security_log": "logins, lockouts and account changes are written to the security log rather than the database and kept for log.max_age; ask an admin for them",
+}
+
+// erasureRetained is what an erasure leaves behind, as listed on its
+// receipt.
+var erasureRetained = []string{
+	"security log entries from before the erasure, which name the old username until log.max_age removes them",
+}
+
+// UserDataQueries are what a user's data export and erasure need.
+type UserDataQueries interface {
+	SelectUserReviews(ctx context.Context, username string) ([]ResponseReview, error)
+	SelectUserVotes(ctx context.Context, username string) ([]ExportVote, error)
+	SelectBookLists(ctx context.Context, username string, visibility string) ([]ResponseBookList, error)
+	SelectBookList(ctx context.Context, id uint64) (ResponseBookList, error)
+	SelectAPIKeys(ctx context.Context, username string) ([]ResponseAPIKey, error)
+	SelectUserSessions(ctx context.Context, username string) ([]ExportSession, error)
+	SelectUserTokens(ctx context.Context, username string) ([]ExportAccountEmail, error)
+	SelectLoginFailure(ctx context.Context, username string) (*ExportLoginFailure, error)
+	EraseUser(ctx context.Context, username string, placeholder string, subjectHash string, by string) (ResponseErasure, error)
+}
+
+// The files of a data export.
+
+type ExportManifest struct {
+	Username    string            `json:"username"`
+	GeneratedAt time.Time         `json:"generated_at"`
+	Files       []string          `json:"files"`
+	Excluded    map[string]string `json:"excluded"`
+}
+
+type ExportProfile struct {
+	Username          string     `json:"username"`
+	Email             string     `json:"email"`
+	Fullname          string     `json:"fullname"`
+	Role              string     `json:"role"`
+	CreatedAt         time.Time  `json:"created_at"`
+	EmailVerifiedAt   *time.Time `json:"email_verified_at"`
+	MFAEnabledAt      *time.Time `json:"mfa_enabled_at"`
+	RecoveryCodesLeft int        `json:"recovery_codes_left"`
+}
+
+type ExportReviews struct {
+	Reviews      []ResponseReview `json:"reviews"`
+	HelpfulVotes []ExportVote     `json:"helpful_votes"`
+}
+
+type ExportVote struct {
+	ReviewId  uint64    `json:"review_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ExportSecurity struct {
+	APIKeys       []ResponseAPIKey     `json:"api_keys"`
+	Sessions      []ExportSession      `json:"sessions"`
+	AccountEmails []ExportAccountEmail `json:"account_emails"`
+	LoginFailures *ExportLoginFailure  `json:"login_failures"`
+}
+
+type ExportSession struct {
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ExportAccountEmail is a verification or password reset email sent to
+// the user.
+type ExportAccountEmail struct {
+	Purpose   string     `json:"purpose"`
+	Email     string     `json:"email"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+}
+
+type ExportLoginFailure struct {
+	Failures     int        `json:"failures"`
+	LastFailedAt time.Time  `json:"last_failed_at"`
+	LockedUntil  *time.Time `json:"locked_until"`
+}
+
+// ExportUserData returns a ZIP of everything held about a user: their
+// profile, reviews and votes, lists with their books, and the keys,
+// sessions, emails and failed logins of their account. Password, secret
+// and token hashes and the security log are left out, as the manifest
+// says. by is who asked, the user or an admin.
+func (s UserServices) ExportUserData(ctx context.Context, username string, by string) ([]byte, error) {
+	ctx, span := tracing.Start(ctx, "UserServices.ExportUserData")
+	defer span.End()
+
+	files, err := s.userDataFiles(ctx, username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &c.Err{Code: http.StatusNotFound, Remark: "Error User Not Found", Original: err}
+		}
+		c.LogFrom(ctx, s.log).Errorf("Error Select User Data : %v", err)
+		return nil, &c.Err{Code: http.StatusInternalServerError, Remark: "Error ExportUserData Service", Original: err}
+	}
+
+	manifest := ExportManifest{Username: username, GeneratedAt: s.now().UTC(), Excluded: exportExcluded}
+	for _, f := range files {
+		manifest.Files = append(manifest.Files, f.name)
+	}
+	files = append([]exportFile{{"export.json", manifest}}, files...)
+
+	buf := bytes.Buffer{}
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		if err := writeZipJSON(zw, f.name, f.data, manifest.GeneratedAt); err != nil {
+			c.LogFrom(ctx, s.log).Errorf("Error ExportUserData Zip : %v", err)
+			return nil, &c.Err{Code: http.StatusInternalServerError, Remark: "Error ExportUserData Service", Original: err}
+		}
+	}
+	if err := zw.Close(); err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error ExportUserData Zip : %v", err)
+		return nil, &c.Err{Code: http.StatusInternalServerError, Remark: "Error ExportUserData Service", Original: err}
+	}
+	securityEvent(ctx, s.security, eventDataExported, username, "", c.Fields{"by": by})
+	return buf.Bytes(), nil
+}
+
+// EraseUser anonymises a user's personal data and returns the receipt. The
+// account stays, renamed to a placeholder, so the ratings and votes it
+// left keep counting. Users erasing themselves need their password; an
+// admin, by, erasing another user does not. The security log is not
+// rewritten, which the receipt notes.
+func (s UserServices) EraseUser(ctx context.Context, username string, req RequestErase, by string) (ResponseErasure, error) {
+	ctx, span := tracing.Start(ctx, "UserServices.EraseUser")
+	defer span.End()
+
+	if by == username {
+		if _, err := s.reauthenticate(ctx, username, req.Password, req.IP, "Error EraseUser Service"); err != nil {
+			return ResponseErasure{}, err
+		}
+	}
+	token, err := utils.NewToken()
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error EraseUser New Token : %v", err)
+		return ResponseErasure{}, &c.Err{Code: http.StatusInternalServerError, Remark: "Error EraseUser Service", Original: err}
+	}
+	placeholder := erasedUsernamePrefix + token[:16]
+
+	receipt, err := s.query.EraseUser(ctx, username, placeholder, utils.KeyedHash(s.conf.ErasureKey, username), by)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ResponseErasure{}, &c.Err{Code: http.StatusNotFound, Remark: "Error User Not Found", Original: err}
+		}
+		c.LogFrom(ctx, s.log).Errorf("Error EraseUser : %v", err)
+		return ResponseErasure{}, &c.Err{Code: http.StatusInternalServerError, Remark: "Error EraseUser Service", Original: err}
+	}
+	receipt.Retained = erasureRetained
+	securityEvent(ctx, s.security, eventUserErased, receipt.Username, "", c.Fields{"by": by, "receipt": receipt.Id})
+	return receipt, nil
+}
+
+type exportFile struct {
+	name string
+	data interface{}
+}
+
+// userDataFiles reads what the export holds about username. It returns
+// sql.ErrNoRows for an unknown user.
+func (s UserServices) userDataFiles(ctx context.Context, username string) ([]exportFile, error) {
+	user, err := s.query.SelectUser(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	mfa, err := s.query.SelectMFA(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	profile := ExportProfile{
+		Username:          user.Username,
+		Email:             user.Email,
+		Fullname:          user.Fullname,
+		Role:              user.Role,
+		CreatedAt:         user.CreatedAt,
+		EmailVerifiedAt:   user.EmailVerifiedAt,
+		MFAEnabledAt:      user.MFAEnabledAt,
+		RecoveryCodesLeft: mfa.RecoveryCodesLeft,
+	}
+
+	reviews := ExportReviews{}
+	if reviews.Reviews, err = s.query.SelectUserReviews(ctx, username); err != nil {
+		return nil, err
+	}
+	if reviews.HelpfulVotes, err = s.query.SelectUserVotes(ctx, username); err != nil {
+		return nil, err
+	}
+
+	lists, err := s.query.SelectBookLists(ctx, username, "")
+	if err != nil {
+		return nil, err
+	}
+	for i := range lists {
+		if lists[i], err = s.query.SelectBookList(ctx, lists[i].Id); err != nil {
+			return nil, err
+		}
+	}
+
+	security := ExportSecurity{}
+	if security.APIKeys, err = s.query.SelectAPIKeys(ctx, username); err != nil {
+		return nil, err
+	}
+	if security.Sessions, err = s.query.SelectUserSessions(ctx, username); err != nil {
+		return nil, err
+	}
+	if security.AccountEmails, err = s.query.SelectUserTokens(ctx, username); err != nil {
+		return nil, err
+	}
+	if security.LoginFailures, err = s.query.SelectLoginFailure(ctx, username); err != nil {
+		return nil, err
+	}
+
+	return []exportFile{
+		{"profile.json", profile},
+		{"reviews.json", reviews},
+		{"lists.json", lists},
+		{"security.json", security},
+	}, nil
+}
+
+func writeZipJSON(zw *zip.Writer, name string, v interface{}, modified time.Time) error {
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return fmt.Errorf("error create %v : %w", name, err)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("error write %v : %w", name, err)
+	}
+	return nil
+}
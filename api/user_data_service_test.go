@@ -0,0 +1,143 @@
+//go:build unit
+
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/paquesqueue/bookstore/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// UserDataQueriesStub records erasures on top of the login stub, whose
+// user "tester" has the password "123456".
+type UserDataQueriesStub struct {
+	*LoginQueriesStub
+	erased      string
+	placeholder string
+	subjectHash string
+}
+
+func (q *UserDataQueriesStub) SelectUserReviews(ctx context.Context, username string) ([]ResponseReview, error) {
+	return []ResponseReview{{Id: 1, Username: username, Rating: 5, Title: "Great"}}, nil
+}
+
+func (q *UserDataQueriesStub) EraseUser(ctx context.Context, username string, placeholder string, subjectHash string, by string) (ResponseErasure, error) {
+	q.erased, q.placeholder, q.subjectHash = username, placeholder, subjectHash
+	return ResponseErasure{Id: 1, Username: placeholder, SubjectHash: subjectHash, ErasedBy: by}, nil
+}
+
+func newUserDataQueriesStub(t *testing.T) *UserDataQueriesStub {
+	return &UserDataQueriesStub{LoginQueriesStub: newLoginQueriesStub(t)}
+}
+
+func readZip(t *testing.T, data []byte) map[string]string {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	assert.NoError(t, err)
+	files := map[string]string{}
+	for _, f := range zr.File {
+		r, err := f.Open()
+		assert.NoError(t, err)
+		b, err := io.ReadAll(r)
+		assert.NoError(t, err)
+		r.Close()
+		files[f.Name] = string(b)
+	}
+	return files
+}
+
+func TestExportUserData(t *testing.T) {
+	t.Run("TestExportUserDataShouldZipEveryFileWithoutHashes", func(t *testing.T) {
+		// Arrange
+		query := newUserDataQueriesStub(t)
+		services, hook := newCredentialServices(query)
+
+		// Act
+		data, err := services.ExportUserData(context.Background(), "tester", "admin")
+
+		// Assert
+		assert.NoError(t, err)
+		files := readZip(t, data)
+		assert.Len(t, files, 5)
+		for _, name := range []string{"export.json", "profile.json", "reviews.json", "lists.json", "security.json"} {
+			assert.Contains(t, files, name)
+		}
+		assert.Contains(t, files["reviews.json"], `"title": "Great"`)
+		assert.Contains(t, files["export.json"], `"security_log"`)
+		for _, content := range files {
+			assert.NotContains(t, content, query.users["tester"].HashedPassword)
+		}
+		if entry := hook.LastEntry(); assert.NotNil(t, entry) {
+			assert.Equal(t, eventDataExported, entry.Data["event"])
+			assert.Equal(t, "admin", entry.Data["by"])
+		}
+	})
+
+	t.Run("TestExportUserDataShouldReturnHTTPStatus404ForUnknownUser", func(t *testing.T) {
+		// Arrange
+		services, _ := newCredentialServices(newUserDataQueriesStub(t))
+
+		// Act
+		_, err := services.ExportUserData(context.Background(), "nobody", "admin")
+
+		// Assert
+		assert.Equal(t, http.StatusNotFound, err.(*c.Err).Code)
+	})
+}
+
+func TestEraseUser(t *testing.T) {
+	t.Run("TestEraseUserShouldNeedPasswordOfSelf", func(t *testing.T) {
+		// Arrange
+		query := newUserDataQueriesStub(t)
+		services, _ := newCredentialServices(query)
+
+		// Act
+		_, err := services.EraseUser(context.Background(), "tester", RequestErase{Password: "wrong"}, "tester")
+
+		// Assert
+		assert.Equal(t, http.StatusForbidden, err.(*c.Err).Code)
+		assert.Empty(t, query.erased)
+	})
+
+	t.Run("TestEraseUserShouldEraseSelfWithPassword", func(t *testing.T) {
+		// Arrange
+		query := newUserDataQueriesStub(t)
+		services, hook := newCredentialServices(query)
+
+		// Act
+		receipt, err := services.EraseUser(context.Background(), "tester", RequestErase{Password: "123456"}, "tester")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "tester", query.erased)
+		assert.True(t, strings.HasPrefix(receipt.Username, erasedUsernamePrefix))
+		assert.Equal(t, utils.KeyedHash("erasure-key", "tester"), receipt.SubjectHash)
+		assert.NotEqual(t, utils.HashToken("tester"), receipt.SubjectHash)
+		assert.NotEmpty(t, receipt.Retained)
+		if entry := hook.LastEntry(); assert.NotNil(t, entry) {
+			assert.Equal(t, eventUserErased, entry.Data["event"])
+			assert.Equal(t, receipt.Username, entry.Data["username"])
+		}
+	})
+
+	t.Run("TestEraseUserShouldNotNeedPasswordOfAdmin", func(t *testing.T) {
+		// Arrange
+		query := newUserDataQueriesStub(t)
+		services, _ := newCredentialServices(query)
+
+		// Act
+		receipt, err := services.EraseUser(context.Background(), "tester", RequestErase{}, "admin")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "admin", receipt.ErasedBy)
+		assert.NotContains(t, query.placeholder, "tester")
+	})
+}
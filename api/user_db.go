@@ -1,77 +1,125 @@
 package api
 
-func (db Query) InsertUser(req RequestUser) (ResponseUser, error) {
+import (
+	"context"
+	"database/sql"
+)
+
+const userColumns = `username, email, fullname, hashed_password, created_at, role, email_verified_at, mfa_enabled_at`
+
+func scanUser(row rowScanner) (ResponseUser, error) {
+	resp := ResponseUser{}
+	var verifiedAt, mfaEnabledAt sql.NullTime
+	err := row.Scan(&resp.Username, &resp.Email, &resp.Fullname, &resp.HashedPassword, &resp.CreatedAt, &resp.Role, &verifiedAt, &mfaEnabledAt)
+	if err != nil {
+		return ResponseUser{}, err
+	}
+	resp.EmailVerifiedAt = nullTime(verifiedAt)
+	resp.MFAEnabledAt = nullTime(mfaEnabledAt)
+	return resp, nil
+}
+
+func (db Query) InsertUser(ctx context.Context, req RequestUser) (ResponseUser, error) {
 	const query = `INSERT INTO users 
 	(username, email, fullname, hashed_password) 
 	VALUES ($1, $2, $3, $4) 
-	RETURNING username, email, fullname, hashed_password, created_at;`
+	RETURNING ` + userColumns + `;`
 
-	stmt, err := db.Prepare(query)
+	stmt, err := db.PrepareContext(ctx, query)
 	if err != nil {
 		return ResponseUser{}, err
 	}
 	defer stmt.Close()
 
-	row := stmt.QueryRow(req.Username, req.Email, req.Fullname, req.Password)
+	return scanUser(stmt.QueryRowContext(ctx, req.Username, req.Email, req.Fullname, req.Password))
+}
 
-	resp := ResponseUser{}
-	err = row.Scan(&resp.Username, &resp.Email, &resp.Fullname, &resp.HashedPassword, &resp.CreatedAt)
+func (db Query) SelectUser(ctx context.Context, username string) (ResponseUser, error) {
+	const query = `SELECT ` + userColumns + ` FROM users WHERE username = $1;`
+	stmt, err := db.PrepareContext(ctx, query)
 	if err != nil {
 		return ResponseUser{}, err
 	}
-	return resp, nil
+	defer stmt.Close()
+
+	return scanUser(stmt.QueryRowContext(ctx, username))
 }
 
-func (db Query) SelectUser(username string) (ResponseUser, error) {
-	const query = `SELECT * FROM users WHERE username = $1;`
-	stmt, err := db.Prepare(query)
+// UpdateUserPassword replaces a user's password hash, such as when it is
+// rehashed with newer settings.
+func (db Query) UpdateUserPassword(ctx context.Context, username string, hashedPassword string) error {
+	const query = `UPDATE users SET hashed_password = $1 WHERE username = $2;`
+
+	stmt, err := db.PrepareContext(ctx, query)
 	if err != nil {
-		return ResponseUser{}, err
+		return err
 	}
 	defer stmt.Close()
 
-	row := stmt.QueryRow(username)
-	resp := ResponseUser{}
-	err = row.Scan(&resp.Username, &resp.Email, &resp.Fullname, &resp.HashedPassword, &resp.CreatedAt)
+	_, err = stmt.ExecContext(ctx, hashedPassword, username)
+	return err
+}
+
+func (db Query) UpdateUserRole(ctx context.Context, username string, role string) (ResponseUser, error) {
+	const query = `UPDATE users SET role = $1 WHERE username = $2 RETURNING ` + userColumns + `;`
+
+	stmt, err := db.PrepareContext(ctx, query)
 	if err != nil {
 		return ResponseUser{}, err
 	}
-	return resp, nil
+	defer stmt.Close()
+
+	return scanUser(stmt.QueryRowContext(ctx, role, username))
 }
 
-func (db Query) UpdateUser(username string, req RequestUser) (ResponseUser, error) {
-	const query = `UPDATE users 
-	SET username = $1, email = $2, fullname = $3, hashed_password = $4
-	WHERE username = $5
-	RETURNING username, email, fullname, hashed_password, created_at;`
+// PromoteFirstAdmin makes username an admin unless some user already is one.
+// It reports whether the user was promoted.
+func (db Query) PromoteFirstAdmin(ctx context.Context, username string) (bool, error) {
+	const query = `UPDATE users SET role = $1
+	WHERE username = $2 AND erased_at IS NULL
+	AND NOT EXISTS (SELECT 1 FROM users WHERE role = $1);`
 
-	stmt, err := db.Prepare(query)
+	stmt, err := db.PrepareContext(ctx, query)
 	if err != nil {
-		return ResponseUser{}, err
+		return false, err
 	}
 	defer stmt.Close()
 
-	row := stmt.QueryRow(req.Username, req.Email, req.Fullname, req.Password, username)
-	resp := ResponseUser{}
-	err = row.Scan(&resp.Username, &resp.Email, &resp.Fullname, &resp.HashedPassword, &resp.CreatedAt)
+	res, err := stmt.ExecContext(ctx, UserRoleAdmin, username)
 	if err != nil {
-		return ResponseUser{}, err
+		return false, err
 	}
-	return resp, nil
+	n, err := res.RowsAffected()
+	return n > 0, err
 }
 
-func (db Query) DeleteUser(username string) error {
-	const query = `DELETE FROM users WHERE username = $1`
+// DeleteUser removes a user together with their sessions, reviews and votes.
+// The user's approved reviews are taken out of the book rating totals first,
+// since the cascade that removes them does not touch the books.
+func (db Query) DeleteUser(ctx context.Context, username string) error {
+	const query = `UPDATE books b
+	SET rating_count = b.rating_count - r.n, rating_sum = b.rating_sum - r.total
+	FROM (
+		SELECT book_id, COUNT(*) AS n, SUM(rating) AS total
+		FROM reviews
+		WHERE username = $1 AND status = $2
+		GROUP BY book_id
+	) r
+	WHERE b.id = r.book_id;`
 
-	stmt, err := db.Prepare(query)
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
-	defer stmt.Close()
+	defer tx.Rollback()
 
-	_, err = stmt.Exec(username)
+	_, err = tx.Exec(query, username, ReviewStatusApproved)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`DELETE FROM users WHERE username = $1`, username)
 	if err != nil {
 		return err
 	}
-	return nil
+	return tx.Commit()
 }
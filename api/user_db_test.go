@@ -3,6 +3,7 @@
 package api
 
 import (
+	"context"
 	"regexp"
 	"testing"
 	"time"
@@ -30,11 +31,11 @@ func TestInsertUser(t *testing.T) {
 		assert.NoError(t, err)
 		defer db.Close()
 
-		row := sqlmock.NewRows([]string{"username", "email", "fullname", "hashed_password", "created_at"})
+		row := sqlmock.NewRows([]string{"username", "email", "fullname", "hashed_password", "created_at", "role", "email_verified_at", "mfa_enabled_at"})
 		mockCreated_at := time.Now()
-		row.AddRow(mockData.Username, mockData.Email, mockData.Fullname, mockData.Password, mockCreated_at)
+		row.AddRow(mockData.Username, mockData.Email, mockData.Fullname, mockData.Password, mockCreated_at, UserRoleCustomer, nil, nil)
 
-		get := mock.ExpectPrepare(regexp.QuoteMeta(`INSERT INTO users (username, email, fullname, hashed_password) VALUES ($1, $2, $3, $4) RETURNING username, email, fullname, hashed_password, created_at;`))
+		get := mock.ExpectPrepare(regexp.QuoteMeta(`INSERT INTO users (username, email, fullname, hashed_password) VALUES ($1, $2, $3, $4) RETURNING username, email, fullname, hashed_password, created_at, role, email_verified_at, mfa_enabled_at;`))
 		get.ExpectQuery().
 			WithArgs(mockData.Username, mockData.Email, mockData.Fullname, mockData.Password).
 			WillReturnRows(row)
@@ -42,7 +43,7 @@ func TestInsertUser(t *testing.T) {
 		query := NewDB(db)
 
 		// Act
-		resp, err := query.InsertUser(mockData)
+		resp, err := query.InsertUser(context.Background(), mockData)
 
 		// Assert
 		if assert.Nil(t, err) {
@@ -62,7 +63,7 @@ func TestInsertUser(t *testing.T) {
 		assert.NoError(t, err)
 		defer db.Close()
 
-		get := mock.ExpectPrepare(regexp.QuoteMeta(`INSERT INTO users (username, email, fullname, hashed_password) VALUES ($1, $2, $3, $4) RETURNING username, email, fullname, hashed_password, created_at;`))
+		get := mock.ExpectPrepare(regexp.QuoteMeta(`INSERT INTO users (username, email, fullname, hashed_password) VALUES ($1, $2, $3, $4) RETURNING username, email, fullname, hashed_password, created_at, role, email_verified_at, mfa_enabled_at;`))
 		get.ExpectQuery().
 			WithArgs(mockData).
 			WillReturnError(&pq.Error{Message: "db connection error"})
@@ -70,7 +71,7 @@ func TestInsertUser(t *testing.T) {
 		query := NewDB(db)
 
 		// Act
-		resp, err := query.InsertUser(mockData)
+		resp, err := query.InsertUser(context.Background(), mockData)
 
 		// Assert
 		if assert.NotNil(t, err) {
@@ -96,11 +97,11 @@ func TestSelectUser(t *testing.T) {
 		assert.NoError(t, err)
 		defer db.Close()
 
-		row := sqlmock.NewRows([]string{"username", "email", "fullname", "hashed_password", "created_at"})
+		row := sqlmock.NewRows([]string{"username", "email", "fullname", "hashed_password", "created_at", "role", "email_verified_at", "mfa_enabled_at"})
 		mockCreated_at := time.Now()
-		row.AddRow(mockData.Username, mockData.Email, mockData.Fullname, mockData.Password, mockCreated_at)
+		row.AddRow(mockData.Username, mockData.Email, mockData.Fullname, mockData.Password, mockCreated_at, UserRoleCustomer, nil, nil)
 
-		get := mock.ExpectPrepare(regexp.QuoteMeta(`SELECT * FROM users WHERE username = $1;`))
+		get := mock.ExpectPrepare(regexp.QuoteMeta(`SELECT username, email, fullname, hashed_password, created_at, role, email_verified_at, mfa_enabled_at FROM users WHERE username = $1;`))
 		get.ExpectQuery().
 			WithArgs(mockData.Username).
 			WillReturnRows(row)
@@ -108,7 +109,7 @@ func TestSelectUser(t *testing.T) {
 		query := NewDB(db)
 
 		// Act
-		resp, err := query.SelectUser(mockData.Username)
+		resp, err := query.SelectUser(context.Background(), mockData.Username)
 
 		// Assert
 		if assert.Nil(t, err) {
@@ -117,6 +118,7 @@ func TestSelectUser(t *testing.T) {
 			assert.Equal(t, mockData.Fullname, resp.Fullname)
 			assert.Equal(t, mockData.Password, resp.HashedPassword)
 			assert.NotEmpty(t, resp.CreatedAt)
+			assert.Equal(t, UserRoleCustomer, resp.Role)
 		}
 	})
 
@@ -136,7 +138,7 @@ func TestSelectUser(t *testing.T) {
 		assert.NoError(t, err)
 		defer db.Close()
 
-		get := mock.ExpectPrepare(regexp.QuoteMeta(`SELECT * FROM users WHERE username = $1;`))
+		get := mock.ExpectPrepare(regexp.QuoteMeta(`SELECT username, email, fullname, hashed_password, created_at, role, email_verified_at, mfa_enabled_at FROM users WHERE username = $1;`))
 		get.ExpectQuery().
 			WithArgs(mockData.Username).
 			WillReturnError(&pq.Error{Message: "not found error"})
@@ -144,7 +146,7 @@ func TestSelectUser(t *testing.T) {
 		query := NewDB(db)
 
 		// Act
-		resp, err := query.SelectUser(mockData.Username)
+		resp, err := query.SelectUser(context.Background(), mockData.Username)
 
 		// Assert
 		if assert.NotNil(t, err) {
@@ -153,75 +155,49 @@ func TestSelectUser(t *testing.T) {
 	})
 }
 
-func TestUpdateUser(t *testing.T) {
-	t.Run("TestUpdatetUserShouldReturnNoError", func(t *testing.T) {
+func TestPromoteFirstAdmin(t *testing.T) {
+	t.Run("TestPromoteFirstAdminShouldReportPromotion", func(t *testing.T) {
 		// Arrange
-		hashedPassword, err := utils.HashPassword("123456")
-		assert.NoError(t, err)
-
-		username := "tester"
-
-		mockData := RequestUser{
-			Username: "tester",
-			Password: hashedPassword,
-			Email:    "tester@email.com",
-			Fullname: "tester testing",
-		}
-
 		db, mock, err := sqlmock.New()
 		assert.NoError(t, err)
 		defer db.Close()
 
-		row := sqlmock.NewRows([]string{"username", "email", "fullname", "hashed_password", "created_at"})
-		mockCreated_at := time.Now()
-		row.AddRow(mockData.Username, mockData.Email, mockData.Fullname, mockData.Password, mockCreated_at)
-
-		get := mock.ExpectPrepare(regexp.QuoteMeta(`UPDATE users SET username = $1, email = $2, fullname = $3, hashed_password = $4 WHERE username = $5 RETURNING username, email, fullname, hashed_password, created_at;`))
-		get.ExpectQuery().
-			WithArgs(mockData.Username, mockData.Email, mockData.Fullname, mockData.Password, mockData.Username).
-			WillReturnRows(row)
+		mock.ExpectPrepare(regexp.QuoteMeta(`UPDATE users SET role = $1 WHERE username = $2 AND erased_at IS NULL AND NOT EXISTS (SELECT 1 FROM users WHERE role = $1);`)).
+			ExpectExec().
+			WithArgs(UserRoleAdmin, "tester").
+			WillReturnResult(sqlmock.NewResult(0, 1))
 
 		query := NewDB(db)
 
 		// Act
-		resp, err := query.UpdateUser(username, mockData)
+		promoted, err := query.PromoteFirstAdmin(context.Background(), "tester")
 
 		// Assert
-		if assert.Nil(t, err) {
-			assert.Equal(t, mockData.Username, resp.Username)
-			assert.Equal(t, mockData.Email, resp.Email)
-			assert.Equal(t, mockData.Fullname, resp.Fullname)
-			assert.Equal(t, mockData.Password, resp.HashedPassword)
-			assert.NotEmpty(t, resp.CreatedAt)
-		}
+		assert.Nil(t, err)
+		assert.True(t, promoted)
+		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
-	t.Run("TestUpdatetUserShouldReturnError", func(t *testing.T) {
+	t.Run("TestPromoteFirstAdminShouldNotPromoteWhenAdminExists", func(t *testing.T) {
 		// Arrange
-		mockData := RequestUser{}
-		username := "tester"
 		db, mock, err := sqlmock.New()
 		assert.NoError(t, err)
 		defer db.Close()
 
-		row := sqlmock.NewRows([]string{"username", "email", "fullname", "hashed_password", "created_at"})
-		mockCreated_at := time.Now()
-		row.AddRow(mockData.Username, mockData.Email, mockData.Fullname, mockData.Password, mockCreated_at)
-
-		get := mock.ExpectPrepare(regexp.QuoteMeta(`UPDATE users SET username = $1, email = $2, fullname = $3, hashed_password = $4 WHERE username = $5 RETURNING username, email, fullname, hashed_password, created_at;`))
-		get.ExpectQuery().
-			WithArgs(mockData.Email, mockData.Fullname, mockData.Password, mockData.Username).
-			WillReturnError(&pq.Error{Message: "db connection error"})
+		mock.ExpectPrepare(regexp.QuoteMeta(`UPDATE users SET role = $1`)).
+			ExpectExec().
+			WithArgs(UserRoleAdmin, "tester").
+			WillReturnResult(sqlmock.NewResult(0, 0))
 
 		query := NewDB(db)
 
 		// Act
-		resp, err := query.UpdateUser(username, mockData)
+		promoted, err := query.PromoteFirstAdmin(context.Background(), "tester")
 
 		// Assert
-		if assert.NotNil(t, err) {
-			assert.Empty(t, resp)
-		}
+		assert.Nil(t, err)
+		assert.False(t, promoted)
+		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
 
@@ -234,18 +210,23 @@ func TestDeleteUser(t *testing.T) {
 		assert.NoError(t, err)
 		defer db.Close()
 
-		get := mock.ExpectPrepare(regexp.QuoteMeta(`DELETE FROM users WHERE username = $1`))
-		get.ExpectExec().
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE books b SET rating_count = b.rating_count - r.n, rating_sum = b.rating_sum - r.total`)).
+			WithArgs(mockUsername, ReviewStatusApproved).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM users WHERE username = $1`)).
 			WithArgs(mockUsername).
 			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
 
 		query := NewDB(db)
 
 		// Act
-		err = query.DeleteUser(mockUsername)
+		err = query.DeleteUser(context.Background(), mockUsername)
 
 		// Assert
 		assert.Nil(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
 	t.Run("TestDeleteUserShouldReturnError", func(t *testing.T) {
@@ -256,15 +237,19 @@ func TestDeleteUser(t *testing.T) {
 		assert.NoError(t, err)
 		defer db.Close()
 
-		get := mock.ExpectPrepare(regexp.QuoteMeta(`DELETE FROM users WHERE username = $1`))
-		get.ExpectExec().
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE books b SET rating_count`)).
+			WithArgs(mockUsername, ReviewStatusApproved).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM users WHERE username = $1`)).
 			WithArgs(mockUsername).
 			WillReturnError(&pq.Error{Message: "db connection error"})
+		mock.ExpectRollback()
 
 		query := NewDB(db)
 
 		// Act
-		err = query.DeleteUser(mockUsername)
+		err = query.DeleteUser(context.Background(), mockUsername)
 
 		// Assert
 		assert.NotNil(t, err)
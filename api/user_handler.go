@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
@@ -8,10 +9,21 @@ import (
 )
 
 type UserHandlrQueries interface {
-	AddUser(req RequestUser) (ResponseUser, error)
-	GetUser(username string) (ResponseUser, error)
-	PutUser(username string, req RequestUser) (ResponseUser, error)
-	DeleteUser(username string) error
+	AddUser(ctx context.Context, req RequestUser) (ResponseUser, error)
+	GetUser(ctx context.Context, username string) (ResponseUser, error)
+	PutUser(ctx context.Context, username string, req RequestProfile, by string) (ResponseUser, error)
+	PutUserRole(ctx context.Context, username string, req RequestUserRole) (ResponseUser, error)
+	DeleteUser(ctx context.Context, username string) error
+	UnlockUser(ctx context.Context, username string, by string) error
+	PutProfile(ctx context.Context, username string, req RequestProfile) (ResponseUser, error)
+	ChangeEmail(ctx context.Context, username string, req RequestChangeEmail) (ResponseUser, error)
+	ChangePassword(ctx context.Context, username string, req RequestChangePassword, sessionToken string) error
+	ChangeUsername(ctx context.Context, username string, req RequestChangeUsername) (ResponseUser, error)
+	ForceEmail(ctx context.Context, username string, req RequestChangeEmail, by string) (ResponseUser, error)
+	ForcePassword(ctx context.Context, username string, req RequestChangePassword, by string) error
+	ForceUsername(ctx context.Context, username string, req RequestChangeUsername, by string) (ResponseUser, error)
+	ExportUserData(ctx context.Context, username string, by string) ([]byte, error)
+	EraseUser(ctx context.Context, username string, req RequestErase, by string) (ResponseErasure, error)
 }
 
 type UserHandlr struct {
@@ -31,45 +43,72 @@ func (h UserHandlr) AddUser(ctx echo.Context) error {
 		return ctx.NoContent(http.StatusBadRequest)
 	}
 
-	resp, err := h.handler.AddUser(req)
+	resp, err := h.handler.AddUser(ctx.Request().Context(), req)
 	if err != nil {
 		if cmErr, ok := err.(*c.Err); ok {
 			return ctx.NoContent(cmErr.Code)
 		}
-		h.log.Errorf("Error AddUser Handler : %v", err)
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error AddUser Handler : %v", err)
 		return ctx.NoContent(http.StatusInternalServerError)
 	}
 	return ctx.JSON(http.StatusCreated, resp)
 }
 
+// GetUser shows an account to its owner or an admin.
 func (h UserHandlr) GetUser(ctx echo.Context) error {
-	username := ctx.Param("username")
+	username, _, ok := accountManager(ctx)
+	if !ok {
+		return ctx.NoContent(http.StatusForbidden)
+	}
 
-	resp, err := h.handler.GetUser(username)
+	resp, err := h.handler.GetUser(ctx.Request().Context(), username)
 	if err != nil {
 		if cmErr, ok := err.(*c.Err); ok {
 			return ctx.NoContent(cmErr.Code)
 		}
-		h.log.Errorf("Error GetUser Handler : %v", err)
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error GetUser Handler : %v", err)
 		return ctx.NoContent(http.StatusInternalServerError)
 	}
 	return ctx.JSON(http.StatusOK, resp)
 }
 
+// PutUser lets an admin change a user's full name.
 func (h UserHandlr) PutUser(ctx echo.Context) error {
+	admin, ok := CurrentUser(ctx)
+	if !ok {
+		return ctx.NoContent(http.StatusUnauthorized)
+	}
+	req := RequestProfile{}
+	err := ctx.Bind(&req)
+	if err != nil {
+		return ctx.NoContent(http.StatusBadRequest)
+	}
+
+	resp, err := h.handler.PutUser(ctx.Request().Context(), ctx.Param("username"), req, admin.Username)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error PutUser Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func (h UserHandlr) PutUserRole(ctx echo.Context) error {
 	username := ctx.Param("username")
-	var req = RequestUser{}
+	var req = RequestUserRole{}
 	err := ctx.Bind(&req)
 	if err != nil {
 		return ctx.NoContent(http.StatusBadRequest)
 	}
 
-	resp, err := h.handler.PutUser(username, req)
+	resp, err := h.handler.PutUserRole(ctx.Request().Context(), username, req)
 	if err != nil {
 		if cmErr, ok := err.(*c.Err); ok {
 			return ctx.NoContent(cmErr.Code)
 		}
-		h.log.Errorf("Error PutUser Handler : %v", err)
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error PutUserRole Handler : %v", err)
 		return ctx.NoContent(http.StatusInternalServerError)
 	}
 	return ctx.JSON(http.StatusOK, resp)
@@ -78,13 +117,31 @@ func (h UserHandlr) PutUser(ctx echo.Context) error {
 func (h UserHandlr) DeleteUser(ctx echo.Context) error {
 	username := ctx.Param("username")
 
-	err := h.handler.DeleteUser(username)
+	err := h.handler.DeleteUser(ctx.Request().Context(), username)
 	if err != nil {
 		if cmErr, ok := err.(*c.Err); ok {
 			return ctx.NoContent(cmErr.Code)
 		}
-		h.log.Errorf("Erro DelUser Handler : %v", err)
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Erro DelUser Handler : %v", err)
 		return ctx.NoContent(http.StatusInternalServerError)
 	}
 	return ctx.JSON(http.StatusOK, "Deleted Successfully")
 }
+
+// UnlockUser lets a user locked out after failed logins try again at once.
+func (h UserHandlr) UnlockUser(ctx echo.Context) error {
+	admin, ok := CurrentUser(ctx)
+	if !ok {
+		return ctx.NoContent(http.StatusUnauthorized)
+	}
+
+	err := h.handler.UnlockUser(ctx.Request().Context(), ctx.Param("username"), admin.Username)
+	if err != nil {
+		if cmErr, ok := err.(*c.Err); ok {
+			return ctx.NoContent(cmErr.Code)
+		}
+		c.LogFrom(ctx.Request().Context(), h.log).Errorf("Error UnlockUser Handler : %v", err)
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+	return ctx.JSON(http.StatusOK, "Unlocked Successfully")
+}
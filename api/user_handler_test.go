@@ -3,6 +3,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -22,9 +23,12 @@ type UserHandlrSuccess struct {
 	getUserCalled bool
 	putUserCalled bool
 	delUserCalled bool
+	putUserBy     string
+	unlocked      string
+	unlockedBy    string
 }
 
-func (s *UserHandlrSuccess) AddUser(req RequestUser) (ResponseUser, error) {
+func (s *UserHandlrSuccess) AddUser(ctx context.Context, req RequestUser) (ResponseUser, error) {
 	s.addUserCalled = true
 
 	hashedPassword, err := utils.HashPassword(req.Password)
@@ -42,7 +46,7 @@ func (s *UserHandlrSuccess) AddUser(req RequestUser) (ResponseUser, error) {
 	}, nil
 }
 
-func (s *UserHandlrSuccess) GetUser(username string) (ResponseUser, error) {
+func (s *UserHandlrSuccess) GetUser(ctx context.Context, username string) (ResponseUser, error) {
 	s.getUserCalled = true
 
 	hashedPassword, err := utils.HashPassword("123456")
@@ -59,27 +63,68 @@ func (s *UserHandlrSuccess) GetUser(username string) (ResponseUser, error) {
 	}, nil
 }
 
-func (s *UserHandlrSuccess) PutUser(username string, req RequestUser) (ResponseUser, error) {
+func (s *UserHandlrSuccess) PutUser(ctx context.Context, username string, req RequestProfile, by string) (ResponseUser, error) {
 	s.putUserCalled = true
-	hashedPassword, err := utils.HashPassword(req.Password)
-	if err != nil {
-		return ResponseUser{}, nil
-	}
+	s.putUserBy = by
 	return ResponseUser{
-		Username:       req.Username,
-		HashedPassword: hashedPassword,
-		Email:          req.Email,
-		Fullname:       req.Fullname,
+		Username: username,
+		Email:    "tester@email.com",
+		Fullname: req.Fullname,
 
 		CreatedAt: time.Now(),
 	}, nil
 }
 
-func (s *UserHandlrSuccess) DeleteUser(username string) error {
+func (s *UserHandlrSuccess) PutUserRole(ctx context.Context, username string, req RequestUserRole) (ResponseUser, error) {
+	return ResponseUser{Username: username, Role: req.Role}, nil
+}
+
+func (s *UserHandlrSuccess) DeleteUser(ctx context.Context, username string) error {
 	s.delUserCalled = true
 	return nil
 }
 
+func (s *UserHandlrSuccess) UnlockUser(ctx context.Context, username string, by string) error {
+	s.unlocked, s.unlockedBy = username, by
+	return nil
+}
+
+func (s *UserHandlrSuccess) PutProfile(ctx context.Context, username string, req RequestProfile) (ResponseUser, error) {
+	return ResponseUser{Username: username, Fullname: req.Fullname}, nil
+}
+
+func (s *UserHandlrSuccess) ChangeEmail(ctx context.Context, username string, req RequestChangeEmail) (ResponseUser, error) {
+	return ResponseUser{Username: username, Email: req.Email}, nil
+}
+
+func (s *UserHandlrSuccess) ChangePassword(ctx context.Context, username string, req RequestChangePassword, sessionToken string) error {
+	return nil
+}
+
+func (s *UserHandlrSuccess) ChangeUsername(ctx context.Context, username string, req RequestChangeUsername) (ResponseUser, error) {
+	return ResponseUser{Username: req.Username}, nil
+}
+
+func (s *UserHandlrSuccess) ForceEmail(ctx context.Context, username string, req RequestChangeEmail, by string) (ResponseUser, error) {
+	return ResponseUser{Username: username, Email: req.Email}, nil
+}
+
+func (s *UserHandlrSuccess) ForcePassword(ctx context.Context, username string, req RequestChangePassword, by string) error {
+	return nil
+}
+
+func (s *UserHandlrSuccess) ForceUsername(ctx context.Context, username string, req RequestChangeUsername, by string) (ResponseUser, error) {
+	return ResponseUser{Username: req.Username}, nil
+}
+
+func (s *UserHandlrSuccess) ExportUserData(ctx context.Context, username string, by string) ([]byte, error) {
+	return []byte("PK"), nil
+}
+
+func (s *UserHandlrSuccess) EraseUser(ctx context.Context, username string, req RequestErase, by string) (ResponseErasure, error) {
+	return ResponseErasure{Username: "erased-user", ErasedBy: by}, nil
+}
+
 type UserHandlrError struct {
 	addUserCalled   bool
 	getUserCalled   bool
@@ -88,26 +133,70 @@ type UserHandlrError struct {
 	statusCodeError int
 }
 
-func (s *UserHandlrError) AddUser(req RequestUser) (ResponseUser, error) {
+func (s *UserHandlrError) AddUser(ctx context.Context, req RequestUser) (ResponseUser, error) {
 	s.addUserCalled = true
 	return ResponseUser{}, &c.Err{Code: s.statusCodeError}
 }
 
-func (s *UserHandlrError) GetUser(username string) (ResponseUser, error) {
+func (s *UserHandlrError) GetUser(ctx context.Context, username string) (ResponseUser, error) {
 	s.getUserCalled = true
 	return ResponseUser{}, &c.Err{Code: s.statusCodeError}
 }
 
-func (s *UserHandlrError) PutUser(username string, req RequestUser) (ResponseUser, error) {
+func (s *UserHandlrError) PutUser(ctx context.Context, username string, req RequestProfile, by string) (ResponseUser, error) {
 	s.putUserCalled = true
 	return ResponseUser{}, &c.Err{Code: s.statusCodeError}
 }
 
-func (s *UserHandlrError) DeleteUser(username string) error {
+func (s *UserHandlrError) PutUserRole(ctx context.Context, username string, req RequestUserRole) (ResponseUser, error) {
+	return ResponseUser{}, &c.Err{Code: s.statusCodeError}
+}
+
+func (s *UserHandlrError) DeleteUser(ctx context.Context, username string) error {
 	s.delUserCalled = true
 	return &c.Err{Code: s.statusCodeError}
 }
 
+func (s *UserHandlrError) UnlockUser(ctx context.Context, username string, by string) error {
+	return &c.Err{Code: s.statusCodeError}
+}
+
+func (s *UserHandlrError) PutProfile(ctx context.Context, username string, req RequestProfile) (ResponseUser, error) {
+	return ResponseUser{}, &c.Err{Code: s.statusCodeError}
+}
+
+func (s *UserHandlrError) ChangeEmail(ctx context.Context, username string, req RequestChangeEmail) (ResponseUser, error) {
+	return ResponseUser{}, &c.Err{Code: s.statusCodeError}
+}
+
+func (s *UserHandlrError) ChangePassword(ctx context.Context, username string, req RequestChangePassword, sessionToken string) error {
+	return &c.Err{Code: s.statusCodeError}
+}
+
+func (s *UserHandlrError) ChangeUsername(ctx context.Context, username string, req RequestChangeUsername) (ResponseUser, error) {
+	return ResponseUser{}, &c.Err{Code: s.statusCodeError}
+}
+
+func (s *UserHandlrError) ForceEmail(ctx context.Context, username string, req RequestChangeEmail, by string) (ResponseUser, error) {
+	return ResponseUser{}, &c.Err{Code: s.statusCodeError}
+}
+
+func (s *UserHandlrError) ForcePassword(ctx context.Context, username string, req RequestChangePassword, by string) error {
+	return &c.Err{Code: s.statusCodeError}
+}
+
+func (s *UserHandlrError) ForceUsername(ctx context.Context, username string, req RequestChangeUsername, by string) (ResponseUser, error) {
+	return ResponseUser{}, &c.Err{Code: s.statusCodeError}
+}
+
+func (s *UserHandlrError) ExportUserData(ctx context.Context, username string, by string) ([]byte, error) {
+	return nil, &c.Err{Code: s.statusCodeError}
+}
+
+func (s *UserHandlrError) EraseUser(ctx context.Context, username string, req RequestErase, by string) (ResponseErasure, error) {
+	return ResponseErasure{}, &c.Err{Code: s.statusCodeError}
+}
+
 func TestAddUserHandler(t *testing.T) {
 	t.Run("TestAddUserHandlerShouldReturnHTTPStatus201", func(t *testing.T) {
 		// Arrange
@@ -214,6 +303,7 @@ func TestGetUserHandler(t *testing.T) {
 		ctx.SetPath("/:username")
 		ctx.SetParamNames("username")
 		ctx.SetParamValues(username)
+		setCurrentUser(ctx, ResponseUser{Username: username, Role: UserRoleCustomer})
 
 		// Act
 		err := handler.GetUser(ctx)
@@ -228,7 +318,8 @@ func TestGetUserHandler(t *testing.T) {
 
 			assert.NotEmpty(t, resp.Username)
 			assert.NotEmpty(t, resp.Email)
-			assert.NotEmpty(t, resp.HashedPassword)
+			assert.Empty(t, resp.HashedPassword)
+			assert.NotContains(t, rec.Body.String(), "hashed_password")
 			assert.NotEmpty(t, resp.Fullname)
 
 			assert.NotEmpty(t, resp.CreatedAt)
@@ -247,6 +338,7 @@ func TestGetUserHandler(t *testing.T) {
 		ctx.SetPath("/:username")
 		ctx.SetParamNames("username")
 		ctx.SetParamValues(username)
+		setCurrentUser(ctx, ResponseUser{Username: username, Role: UserRoleCustomer})
 
 		handlrServ := &UserHandlrError{statusCodeError: http.StatusInternalServerError}
 		log := logrus.New()
@@ -260,34 +352,53 @@ func TestGetUserHandler(t *testing.T) {
 			assert.Equal(t, handlrServ.statusCodeError, rec.Code)
 		}
 	})
-}
 
-func TestPutUserHandler(t *testing.T) {
-	t.Run("TestPutUserhHandlerShouldReturnHTTPStatus200", func(t *testing.T) {
+	t.Run("TestGetUserHandlerShouldReturnHTTPStatus403ForOtherUser", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/users", nil)
+		rec := httptest.NewRecorder()
+
+		e := echo.New()
+		ctx := e.NewContext(req, rec)
+		ctx.SetPath("/:username")
+		ctx.SetParamNames("username")
+		ctx.SetParamValues("tester")
+		setCurrentUser(ctx, ResponseUser{Username: "intruder", Role: UserRoleStaff})
+
+		handlrServ := &UserHandlrSuccess{}
+		handler := NewUserHandler(handlrServ, logrus.New())
+
 		// Act
-		reqBody := RequestUser{
-			Username: "tester",
-			Password: "123456",
-			Email:    "tester@email.com",
-			Fullname: "tester testing",
+		err := handler.GetUser(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusForbidden, rec.Code)
+			assert.False(t, handlrServ.getUserCalled)
 		}
+	})
+}
+
+func TestPutUserHandler(t *testing.T) {
+	t.Run("TestPutUserHandlerShouldReturnHTTPStatus200", func(t *testing.T) {
+		// Arrange
+		reqBody := RequestProfile{Fullname: "tester testing"}
 
 		body, err := json.Marshal(reqBody)
 		if err != nil {
 			assert.Fail(t, "json marshal fail")
 		}
 
-		req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(string(body)))
+		req := httptest.NewRequest(http.MethodPut, "/users/tester", strings.NewReader(string(body)))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 		rec := httptest.NewRecorder()
 
 		e := echo.New()
 		ctx := e.NewContext(req, rec)
-
-		username := "tester"
-		ctx.SetPath("/:username")
+		ctx.SetPath("/users/:username")
 		ctx.SetParamNames("username")
-		ctx.SetParamValues(username)
+		ctx.SetParamValues("tester")
+		setCurrentUser(ctx, ResponseUser{Username: "admin", Role: UserRoleAdmin})
 
 		handlrServ := &UserHandlrSuccess{}
 		log := logrus.New()
@@ -299,44 +410,33 @@ func TestPutUserHandler(t *testing.T) {
 		// Assert
 		if assert.NoError(t, err) {
 			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, "admin", handlrServ.putUserBy)
 
 			resp := &ResponseUser{}
 			json.Unmarshal(rec.Body.Bytes(), &resp)
 
-			assert.Equal(t, reqBody.Username, resp.Username)
-			assert.Equal(t, reqBody.Email, resp.Email)
-			assert.NotEqual(t, reqBody.Password, resp.HashedPassword)
+			assert.Equal(t, "tester", resp.Username)
 			assert.Equal(t, reqBody.Fullname, resp.Fullname)
-
-			assert.NotEmpty(t, resp.CreatedAt)
 		}
 	})
 
 	t.Run("TestPutUserHandlerShouldReturnHTTPStatus500", func(t *testing.T) {
 		// Arrange
-		reqBody := RequestUser{
-			Username: "tester",
-			Password: "123456",
-			Email:    "tester@email.com",
-			Fullname: "tester testing",
-		}
-
-		body, err := json.Marshal(reqBody)
+		body, err := json.Marshal(RequestProfile{Fullname: "tester testing"})
 		if err != nil {
 			assert.Fail(t, "json marshal fail")
 		}
 
-		req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(string(body)))
+		req := httptest.NewRequest(http.MethodPut, "/users/tester", strings.NewReader(string(body)))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 		rec := httptest.NewRecorder()
 
 		e := echo.New()
 		ctx := e.NewContext(req, rec)
-
-		username := "tester"
-		ctx.SetPath("/:username")
+		ctx.SetPath("/users/:username")
 		ctx.SetParamNames("username")
-		ctx.SetParamValues(username)
+		ctx.SetParamValues("tester")
+		setCurrentUser(ctx, ResponseUser{Username: "admin", Role: UserRoleAdmin})
 
 		handlrServ := &UserHandlrError{statusCodeError: http.StatusInternalServerError}
 		log := logrus.New()
@@ -354,13 +454,14 @@ func TestPutUserHandler(t *testing.T) {
 	t.Run("TestPutUserHandlerShouldReturnHTTPStatus400", func(t *testing.T) {
 		// Arrange
 		e := echo.New()
-		req := httptest.NewRequest(http.MethodPut, "/users", nil)
+		req := httptest.NewRequest(http.MethodPut, "/users/tester", strings.NewReader("{"))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 		rec := httptest.NewRecorder()
 
 		ctx := e.NewContext(req, rec)
+		setCurrentUser(ctx, ResponseUser{Username: "admin", Role: UserRoleAdmin})
 
-		handlrServ := &UserHandlrError{statusCodeError: http.StatusBadRequest}
+		handlrServ := &UserHandlrSuccess{}
 		log := logrus.New()
 		handler := NewUserHandler(handlrServ, log)
 
@@ -370,6 +471,30 @@ func TestPutUserHandler(t *testing.T) {
 		// Assert
 		if assert.NoError(t, err) {
 			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.False(t, handlrServ.putUserCalled)
+		}
+	})
+
+	t.Run("TestPutUserHandlerShouldReturnHTTPStatus401WithoutUser", func(t *testing.T) {
+		// Arrange
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPut, "/users/tester", strings.NewReader(`{"fullname":"tester testing"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		ctx := e.NewContext(req, rec)
+
+		handlrServ := &UserHandlrSuccess{}
+		log := logrus.New()
+		handler := NewUserHandler(handlrServ, log)
+
+		// Act
+		err := handler.PutUser(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusUnauthorized, rec.Code)
+			assert.False(t, handlrServ.putUserCalled)
 		}
 	})
 }
@@ -449,3 +574,96 @@ func TestDeleteUserHandler(t *testing.T) {
 		}
 	})
 }
+
+func TestPutUserRoleHandler(t *testing.T) {
+	t.Run("TestPutUserRoleHandlerShouldReturnHTTPStatus200", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"role":"staff"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		ctx := echo.New().NewContext(req, rec)
+		ctx.SetPath("/users/:username/role")
+		ctx.SetParamNames("username")
+		ctx.SetParamValues("tester")
+
+		handler := NewUserHandler(&UserHandlrSuccess{}, logrus.New())
+
+		// Act
+		err := handler.PutUserRole(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+			res := ResponseUser{}
+			json.Unmarshal(rec.Body.Bytes(), &res)
+			assert.Equal(t, UserRoleStaff, res.Role)
+		}
+	})
+
+	t.Run("TestPutUserRoleHandlerShouldReturnHTTPStatus400", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"role":"owner"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		ctx := echo.New().NewContext(req, rec)
+
+		handler := NewUserHandler(&UserHandlrError{statusCodeError: http.StatusBadRequest}, logrus.New())
+
+		// Act
+		err := handler.PutUserRole(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		}
+	})
+}
+
+func TestUnlockUserHandler(t *testing.T) {
+	t.Run("TestUnlockUserHandlerShouldUnlockAsCurrentUser", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodDelete, "/users/tester/lockout", nil)
+		rec := httptest.NewRecorder()
+
+		e := echo.New()
+		ctx := e.NewContext(req, rec)
+		ctx.SetPath("/users/:username/lockout")
+		ctx.SetParamNames("username")
+		ctx.SetParamValues("tester")
+		setCurrentUser(ctx, ResponseUser{Username: "admin", Role: UserRoleAdmin})
+
+		handlrServ := &UserHandlrSuccess{}
+		handler := NewUserHandler(handlrServ, logrus.New())
+
+		// Act
+		err := handler.UnlockUser(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, "tester", handlrServ.unlocked)
+			assert.Equal(t, "admin", handlrServ.unlockedBy)
+		}
+	})
+
+	t.Run("TestUnlockUserHandlerShouldReturnHTTPStatus401WithoutUser", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodDelete, "/users/tester/lockout", nil)
+		rec := httptest.NewRecorder()
+
+		e := echo.New()
+		ctx := e.NewContext(req, rec)
+
+		handlrServ := &UserHandlrSuccess{}
+		handler := NewUserHandler(handlrServ, logrus.New())
+
+		// Act
+		err := handler.UnlockUser(ctx)
+
+		// Assert
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusUnauthorized, rec.Code)
+			assert.Empty(t, handlrServ.unlocked)
+		}
+	})
+}
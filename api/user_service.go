@@ -1,33 +1,81 @@
 package api
 
 import (
+	"context"
 	"database/sql"
 	"net/http"
+	"time"
 
 	c "github.com/paquesqueue/bookstore/common"
+	"github.com/paquesqueue/bookstore/passwords"
+	"github.com/paquesqueue/bookstore/tracing"
 	"github.com/paquesqueue/bookstore/utils"
 )
 
+// Users sign up as customers; staff can moderate reviews and admins are
+// staff that may also manage other users' roles.
+const (
+	UserRoleCustomer = "customer"
+	UserRoleStaff    = "staff"
+	UserRoleAdmin    = "admin"
+)
+
 type UserQueries interface {
-	InsertUser(req RequestUser) (ResponseUser, error)
-	SelectUser(username string) (ResponseUser, error)
-	UpdateUser(username string, req RequestUser) (ResponseUser, error)
-	DeleteUser(username string) error
+	UserDataQueries
+	loginLockQueries
+	InsertUser(ctx context.Context, req RequestUser) (ResponseUser, error)
+	SelectUser(ctx context.Context, username string) (ResponseUser, error)
+	UpdateUserRole(ctx context.Context, username string, role string) (ResponseUser, error)
+	PromoteFirstAdmin(ctx context.Context, username string) (bool, error)
+	DeleteUser(ctx context.Context, username string) error
+	UpdateUserPassword(ctx context.Context, username string, hashedPassword string) error
+	UpdateUserFullname(ctx context.Context, username string, fullname string) (ResponseUser, error)
+	UpdateUserEmail(ctx context.Context, username string, email string) (ResponseUser, error)
+	ChangeUserPassword(ctx context.Context, username string, hashedPassword string, keepSessionHash string) error
+	RenameUser(ctx context.Context, username string, newUsername string) (ResponseUser, error)
+	ClearLoginFailures(ctx context.Context, scope string, subject string) error
+	SelectMFA(ctx context.Context, username string) (UserMFA, error)
+	AdvanceMFAStep(ctx context.Context, username string, step int64) error
+	UseRecoveryCode(ctx context.Context, username string, codeHash string) error
+}
+
+// PasswordPolicy decides whether an account may use a password. It returns
+// a *passwords.Rejection for a password it refuses.
+type PasswordPolicy interface {
+	Check(password string, account passwords.Account) error
+}
+
+// EmailVerifier sends a user a link to confirm their email address.
+type EmailVerifier interface {
+	SendVerification(ctx context.Context, user ResponseUser) error
 }
 
 type UserServices struct {
-	query UserQueries
-	log   c.Log
+	query    UserQueries
+	policy   PasswordPolicy
+	verifier EmailVerifier
+	conf     c.AuthConfig
+	security c.Log
+	log      c.Log
+	now      func() time.Time
 }
 
-func NewUserService(q UserQueries, l c.Log) *UserServices {
-	return &UserServices{q, l}
+// NewUserService returns the user services. Logins and lockouts are written
+// to security as well as counted against conf's lockout settings.
+func NewUserService(q UserQueries, p PasswordPolicy, v EmailVerifier, conf c.AuthConfig, security c.Log, l c.Log) *UserServices {
+	return &UserServices{q, p, v, conf, security, l, time.Now}
 }
 
-func (s UserServices) AddUser(req RequestUser) (ResponseUser, error) {
+func (s UserServices) AddUser(ctx context.Context, req RequestUser) (ResponseUser, error) {
+	ctx, span := tracing.Start(ctx, "UserServices.AddUser")
+	defer span.End()
+
+	if err := checkPassword(ctx, s.policy, s.log, req.Password, req.Username, req.Email, "Error AddUser Service"); err != nil {
+		return ResponseUser{}, err
+	}
 	hashedPassword, err := utils.HashPassword(req.Password)
 	if err != nil {
-		s.log.Errorf("Error AddUser Hash Password : %v", err)
+		c.LogFrom(ctx, s.log).Errorf("Error AddUser Hash Password : %v", err)
 		return ResponseUser{}, &c.Err{Code: http.StatusInternalServerError, Remark: "Error AddUser Service", Original: err}
 	}
 
@@ -38,18 +86,22 @@ func (s UserServices) AddUser(req RequestUser) (ResponseUser, error) {
 		Fullname: req.Fullname,
 	}
 
-	resp, err := s.query.InsertUser(data)
+	resp, err := s.query.InsertUser(ctx, data)
 	if err != nil {
-		s.log.Errorf("Error InsertUser : %v", err)
+		c.LogFrom(ctx, s.log).Errorf("Error InsertUser : %v", err)
 		return ResponseUser{}, &c.Err{Code: http.StatusInternalServerError, Remark: "Error AddUser Serivce", Original: err}
 	}
+	s.sendVerification(ctx, resp)
 	return resp, nil
 }
 
-func (s UserServices) GetUser(username string) (ResponseUser, error) {
-	resp, err := s.query.SelectUser(username)
+func (s UserServices) GetUser(ctx context.Context, username string) (ResponseUser, error) {
+	ctx, span := tracing.Start(ctx, "UserServices.GetUser")
+	defer span.End()
+
+	resp, err := s.query.SelectUser(ctx, username)
 	if err != nil {
-		s.log.Errorf("Error SelectUser : %v", err)
+		c.LogFrom(ctx, s.log).Errorf("Error SelectUser : %v", err)
 		switch err {
 		case sql.ErrNoRows:
 			return ResponseUser{}, &c.Err{Code: http.StatusNotFound, Remark: "Error User Not Found", Original: err}
@@ -60,33 +112,93 @@ func (s UserServices) GetUser(username string) (ResponseUser, error) {
 	return resp, nil
 }
 
-func (s UserServices) PutUser(username string, req RequestUser) (ResponseUser, error) {
+// PutUser is PutProfile for an admin, by. Email addresses, passwords and
+// usernames have their own endpoints.
+func (s UserServices) PutUser(ctx context.Context, username string, req RequestProfile, by string) (ResponseUser, error) {
+	ctx, span := tracing.Start(ctx, "UserServices.PutUser")
+	defer span.End()
 
-	hashedPassword, err := utils.HashPassword(req.Password)
+	return s.setFullname(ctx, username, req.Fullname, by, "Error PutUser Service")
+}
+
+func (s UserServices) PutUserRole(ctx context.Context, username string, req RequestUserRole) (ResponseUser, error) {
+	ctx, span := tracing.Start(ctx, "UserServices.PutUserRole")
+	defer span.End()
+
+	switch req.Role {
+	case UserRoleCustomer, UserRoleStaff, UserRoleAdmin:
+	default:
+		return ResponseUser{}, &c.Err{Code: http.StatusBadRequest, Remark: "Error Unknown User Role"}
+	}
+
+	resp, err := s.query.UpdateUserRole(ctx, username, req.Role)
 	if err != nil {
-		s.log.Errorf("Error PutUser Hash Password : %v", err)
-		return ResponseUser{}, &c.Err{Code: http.StatusInternalServerError, Remark: "Error PutUser Service", Original: err}
+		c.LogFrom(ctx, s.log).Errorf("Error UpdateUserRole : %v", err)
+		switch err {
+		case sql.ErrNoRows:
+			return ResponseUser{}, &c.Err{Code: http.StatusNotFound, Remark: "Error User Not Found", Original: err}
+		default:
+			return ResponseUser{}, &c.Err{Code: http.StatusInternalServerError, Remark: "Error PutUserRole Service", Original: err}
+		}
 	}
+	return resp, nil
+}
 
-	data := RequestUser{
-		Username: req.Username,
-		Password: hashedPassword,
-		Email:    req.Email,
-		Fullname: req.Fullname,
+// BootstrapAdmin makes the auth.bootstrap_admin user an admin while the
+// store has none, which is the only way to get the first one. It does
+// nothing when the setting is empty or an admin already exists.
+func (s UserServices) BootstrapAdmin(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "UserServices.BootstrapAdmin")
+	defer span.End()
+
+	if s.conf.BootstrapAdmin == "" {
+		return nil
 	}
-	resp, err := s.query.UpdateUser(username, data)
+	promoted, err := s.query.PromoteFirstAdmin(ctx, s.conf.BootstrapAdmin)
 	if err != nil {
-		s.log.Errorf("Error UpdateUser : %v", err)
-		return ResponseUser{}, &c.Err{Code: http.StatusInternalServerError, Remark: "Error PutUser Service", Original: err}
+		c.LogFrom(ctx, s.log).Errorf("Error PromoteFirstAdmin : %v", err)
+		return &c.Err{Code: http.StatusInternalServerError, Remark: "Error BootstrapAdmin Service", Original: err}
 	}
-	return resp, nil
+	if !promoted {
+		c.LogFrom(ctx, s.log).Infof("Skip Bootstrap Admin : an admin exists or %q is not registered", s.conf.BootstrapAdmin)
+		return nil
+	}
+	securityEvent(ctx, s.security, eventAdminBootstrap, s.conf.BootstrapAdmin, "", c.Fields{"by": "auth.bootstrap_admin"})
+	return nil
 }
 
-func (s UserServices) DeleteUser(username string) error {
-	err := s.query.DeleteUser(username)
+func (s UserServices) DeleteUser(ctx context.Context, username string) error {
+	ctx, span := tracing.Start(ctx, "UserServices.DeleteUser")
+	defer span.End()
+
+	err := s.query.DeleteUser(ctx, username)
 	if err != nil {
-		s.log.Errorf("Error DeleteUser : %v", err)
+		c.LogFrom(ctx, s.log).Errorf("Error DeleteUser : %v", err)
 		return &c.Err{Code: http.StatusInternalServerError, Remark: "Error DeleteUser Service", Original: err}
 	}
 	return nil
 }
+
+// sendVerification emails user a verification link. The user is saved
+// either way and can ask for a new link, so a failure is only logged.
+func (s UserServices) sendVerification(ctx context.Context, user ResponseUser) {
+	if user.Email == "" {
+		return
+	}
+	if err := s.verifier.SendVerification(ctx, user); err != nil {
+		c.LogFrom(ctx, s.log).Warnf("Error SendVerification : %v", err)
+	}
+}
+
+// checkPassword returns a 400 error for a password the policy refuses.
+func checkPassword(ctx context.Context, policy PasswordPolicy, log c.Log, password string, username string, email string, remark string) error {
+	err := policy.Check(password, passwords.Account{Username: username, Email: email})
+	if err == nil {
+		return nil
+	}
+	if rejection, ok := err.(*passwords.Rejection); ok {
+		return &c.Err{Code: http.StatusBadRequest, Remark: "Error Password Rejected", Original: rejection}
+	}
+	c.LogFrom(ctx, log).Errorf("Error Check Password : %v", err)
+	return &c.Err{Code: http.StatusInternalServerError, Remark: remark, Original: err}
+}
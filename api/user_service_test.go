@@ -3,12 +3,17 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"net/http"
 	"testing"
 	"time"
 
 	c "github.com/paquesqueue/bookstore/common"
+	"github.com/paquesqueue/bookstore/passwords"
 	"github.com/paquesqueue/bookstore/utils"
 	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -17,9 +22,10 @@ type UserQueriesSuccess struct {
 	selectUserCalled bool
 	updateUserCalled bool
 	deleteUserCalled bool
+	promoted         string
 }
 
-func (s *UserQueriesSuccess) InsertUser(req RequestUser) (ResponseUser, error) {
+func (s *UserQueriesSuccess) InsertUser(ctx context.Context, req RequestUser) (ResponseUser, error) {
 	s.insertUserCalled = true
 	hashedPassword, err := utils.HashPassword(req.Password)
 	if err != nil {
@@ -35,7 +41,7 @@ func (s *UserQueriesSuccess) InsertUser(req RequestUser) (ResponseUser, error) {
 	}, nil
 }
 
-func (s *UserQueriesSuccess) SelectUser(username string) (ResponseUser, error) {
+func (s *UserQueriesSuccess) SelectUser(ctx context.Context, username string) (ResponseUser, error) {
 	s.selectUserCalled = true
 	hashedPassword, err := utils.HashPassword("123456")
 	if err != nil {
@@ -51,27 +57,105 @@ func (s *UserQueriesSuccess) SelectUser(username string) (ResponseUser, error) {
 	}, nil
 }
 
-func (s *UserQueriesSuccess) UpdateUser(username string, req RequestUser) (ResponseUser, error) {
+func (s *UserQueriesSuccess) UpdateUserRole(ctx context.Context, username string, role string) (ResponseUser, error) {
 	s.updateUserCalled = true
-	hashedPassword, err := utils.HashPassword(req.Password)
-	if err != nil {
-		return ResponseUser{}, nil
-	}
-	return ResponseUser{
-		Username:       "tester",
-		HashedPassword: hashedPassword,
-		Email:          "tester@email.com",
-		Fullname:       "tester testing",
+	return ResponseUser{Username: username, Role: role}, nil
+}
 
-		CreatedAt: time.Now(),
-	}, nil
+func (s *UserQueriesSuccess) PromoteFirstAdmin(ctx context.Context, username string) (bool, error) {
+	s.promoted = username
+	return true, nil
 }
 
-func (s *UserQueriesSuccess) DeleteUser(username string) error {
+func (s *UserQueriesSuccess) DeleteUser(ctx context.Context, username string) error {
 	s.deleteUserCalled = true
 	return nil
 }
 
+func (s *UserQueriesSuccess) UpdateUserPassword(ctx context.Context, username string, hashedPassword string) error {
+	return nil
+}
+
+func (s *UserQueriesSuccess) SelectLoginLocks(ctx context.Context, username string, ip string) ([]LoginLock, error) {
+	return []LoginLock{}, nil
+}
+
+func (s *UserQueriesSuccess) RecordLoginFailure(ctx context.Context, scope string, subject string, window time.Duration) (int, error) {
+	return 1, nil
+}
+
+func (s *UserQueriesSuccess) LockLogin(ctx context.Context, scope string, subject string, until time.Time) error {
+	return nil
+}
+
+func (s *UserQueriesSuccess) ClearLoginFailures(ctx context.Context, scope string, subject string) error {
+	return nil
+}
+
+func (s *UserQueriesSuccess) SelectMFA(ctx context.Context, username string) (UserMFA, error) {
+	return UserMFA{}, nil
+}
+
+func (s *UserQueriesSuccess) AdvanceMFAStep(ctx context.Context, username string, step int64) error {
+	return nil
+}
+
+func (s *UserQueriesSuccess) UseRecoveryCode(ctx context.Context, username string, codeHash string) error {
+	return nil
+}
+
+func (s *UserQueriesSuccess) UpdateUserFullname(ctx context.Context, username string, fullname string) (ResponseUser, error) {
+	return ResponseUser{Username: username, Fullname: fullname}, nil
+}
+
+func (s *UserQueriesSuccess) UpdateUserEmail(ctx context.Context, username string, email string) (ResponseUser, error) {
+	return ResponseUser{Username: username, Email: email}, nil
+}
+
+func (s *UserQueriesSuccess) ChangeUserPassword(ctx context.Context, username string, hashedPassword string, keepSessionHash string) error {
+	return nil
+}
+
+func (s *UserQueriesSuccess) RenameUser(ctx context.Context, username string, newUsername string) (ResponseUser, error) {
+	return ResponseUser{Username: newUsername}, nil
+}
+
+func (s *UserQueriesSuccess) SelectUserReviews(ctx context.Context, username string) ([]ResponseReview, error) {
+	return []ResponseReview{}, nil
+}
+
+func (s *UserQueriesSuccess) SelectUserVotes(ctx context.Context, username string) ([]ExportVote, error) {
+	return []ExportVote{}, nil
+}
+
+func (s *UserQueriesSuccess) SelectBookLists(ctx context.Context, username string, visibility string) ([]ResponseBookList, error) {
+	return []ResponseBookList{}, nil
+}
+
+func (s *UserQueriesSuccess) SelectBookList(ctx context.Context, id uint64) (ResponseBookList, error) {
+	return ResponseBookList{Id: id}, nil
+}
+
+func (s *UserQueriesSuccess) SelectAPIKeys(ctx context.Context, username string) ([]ResponseAPIKey, error) {
+	return []ResponseAPIKey{}, nil
+}
+
+func (s *UserQueriesSuccess) SelectUserSessions(ctx context.Context, username string) ([]ExportSession, error) {
+	return []ExportSession{}, nil
+}
+
+func (s *UserQueriesSuccess) SelectUserTokens(ctx context.Context, username string) ([]ExportAccountEmail, error) {
+	return []ExportAccountEmail{}, nil
+}
+
+func (s *UserQueriesSuccess) SelectLoginFailure(ctx context.Context, username string) (*ExportLoginFailure, error) {
+	return nil, nil
+}
+
+func (s *UserQueriesSuccess) EraseUser(ctx context.Context, username string, placeholder string, subjectHash string, by string) (ResponseErasure, error) {
+	return ResponseErasure{Username: placeholder, SubjectHash: subjectHash, ErasedBy: by}, nil
+}
+
 type UserQueriesError struct {
 	insertUserCalled bool
 	selectUserCalled bool
@@ -79,33 +163,131 @@ type UserQueriesError struct {
 	deleteUserCalled bool
 }
 
-func (s *UserQueriesError) InsertUser(req RequestUser) (ResponseUser, error) {
+func (s *UserQueriesError) InsertUser(ctx context.Context, req RequestUser) (ResponseUser, error) {
 	s.insertUserCalled = true
 	return ResponseUser{}, &c.Err{}
 }
 
-func (s *UserQueriesError) SelectUser(username string) (ResponseUser, error) {
+func (s *UserQueriesError) SelectUser(ctx context.Context, username string) (ResponseUser, error) {
 	s.selectUserCalled = true
 	return ResponseUser{}, &c.Err{}
 }
 
-func (s *UserQueriesError) UpdateUser(username string, req RequestUser) (ResponseUser, error) {
+func (s *UserQueriesError) UpdateUserRole(ctx context.Context, username string, role string) (ResponseUser, error) {
 	s.updateUserCalled = true
 	return ResponseUser{}, &c.Err{}
 }
 
-func (s *UserQueriesError) DeleteUser(username string) error {
+func (s *UserQueriesError) PromoteFirstAdmin(ctx context.Context, username string) (bool, error) {
+	return false, &c.Err{}
+}
+
+func (s *UserQueriesError) DeleteUser(ctx context.Context, username string) error {
 	s.deleteUserCalled = true
 	return &c.Err{}
 }
 
+func (s *UserQueriesError) UpdateUserPassword(ctx context.Context, username string, hashedPassword string) error {
+	return &c.Err{}
+}
+
+func (s *UserQueriesError) SelectLoginLocks(ctx context.Context, username string, ip string) ([]LoginLock, error) {
+	return nil, &c.Err{}
+}
+
+func (s *UserQueriesError) RecordLoginFailure(ctx context.Context, scope string, subject string, window time.Duration) (int, error) {
+	return 0, &c.Err{}
+}
+
+func (s *UserQueriesError) LockLogin(ctx context.Context, scope string, subject string, until time.Time) error {
+	return &c.Err{}
+}
+
+func (s *UserQueriesError) ClearLoginFailures(ctx context.Context, scope string, subject string) error {
+	return &c.Err{}
+}
+
+func (s *UserQueriesError) SelectMFA(ctx context.Context, username string) (UserMFA, error) {
+	return UserMFA{}, &c.Err{}
+}
+
+func (s *UserQueriesError) AdvanceMFAStep(ctx context.Context, username string, step int64) error {
+	return &c.Err{}
+}
+
+func (s *UserQueriesError) UseRecoveryCode(ctx context.Context, username string, codeHash string) error {
+	return &c.Err{}
+}
+
+func (s *UserQueriesError) UpdateUserFullname(ctx context.Context, username string, fullname string) (ResponseUser, error) {
+	return ResponseUser{}, &c.Err{}
+}
+
+func (s *UserQueriesError) UpdateUserEmail(ctx context.Context, username string, email string) (ResponseUser, error) {
+	return ResponseUser{}, &c.Err{}
+}
+
+func (s *UserQueriesError) ChangeUserPassword(ctx context.Context, username string, hashedPassword string, keepSessionHash string) error {
+	return &c.Err{}
+}
+
+func (s *UserQueriesError) RenameUser(ctx context.Context, username string, newUsername string) (ResponseUser, error) {
+	return ResponseUser{}, &c.Err{}
+}
+
+func (s *UserQueriesError) SelectUserReviews(ctx context.Context, username string) ([]ResponseReview, error) {
+	return nil, &c.Err{}
+}
+
+func (s *UserQueriesError) SelectUserVotes(ctx context.Context, username string) ([]ExportVote, error) {
+	return nil, &c.Err{}
+}
+
+func (s *UserQueriesError) SelectBookLists(ctx context.Context, username string, visibility string) ([]ResponseBookList, error) {
+	return nil, &c.Err{}
+}
+
+func (s *UserQueriesError) SelectBookList(ctx context.Context, id uint64) (ResponseBookList, error) {
+	return ResponseBookList{}, &c.Err{}
+}
+
+func (s *UserQueriesError) SelectAPIKeys(ctx context.Context, username string) ([]ResponseAPIKey, error) {
+	return nil, &c.Err{}
+}
+
+func (s *UserQueriesError) SelectUserSessions(ctx context.Context, username string) ([]ExportSession, error) {
+	return nil, &c.Err{}
+}
+
+func (s *UserQueriesError) SelectUserTokens(ctx context.Context, username string) ([]ExportAccountEmail, error) {
+	return nil, &c.Err{}
+}
+
+func (s *UserQueriesError) SelectLoginFailure(ctx context.Context, username string) (*ExportLoginFailure, error) {
+	return nil, &c.Err{}
+}
+
+func (s *UserQueriesError) EraseUser(ctx context.Context, username string, placeholder string, subjectHash string, by string) (ResponseErasure, error) {
+	return ResponseErasure{}, &c.Err{}
+}
+
+type EmailVerifierStub struct {
+	sent []ResponseUser
+	err  error
+}
+
+func (v *EmailVerifierStub) SendVerification(ctx context.Context, user ResponseUser) error {
+	v.sent = append(v.sent, user)
+	return v.err
+}
+
 func TestAddUser(t *testing.T) {
 	t.Run("TestAddUserServiceShouldReturnNoError", func(t *testing.T) {
 		// Arrange
 		query := &UserQueriesSuccess{}
 		log := logrus.New()
 
-		services := NewUserService(query, log)
+		services := NewUserService(query, passwords.Policy{}, &EmailVerifierStub{}, c.AuthConfig{}, logrus.New(), log)
 
 		mockData := RequestUser{
 			Username: "tester",
@@ -115,7 +297,7 @@ func TestAddUser(t *testing.T) {
 		}
 
 		// Act
-		resp, err := services.AddUser(mockData)
+		resp, err := services.AddUser(context.Background(), mockData)
 
 		// Assert
 		if assert.Nil(t, err) {
@@ -128,12 +310,54 @@ func TestAddUser(t *testing.T) {
 			assert.NotEmpty(t, resp.CreatedAt)
 		}
 	})
+	t.Run("TestAddUserServiceShouldSendVerification", func(t *testing.T) {
+		// Arrange
+		query := &UserQueriesSuccess{}
+		verifier := &EmailVerifierStub{}
+		services := NewUserService(query, passwords.Policy{}, verifier, c.AuthConfig{}, logrus.New(), logrus.New())
+
+		// Act
+		resp, err := services.AddUser(context.Background(), RequestUser{Username: "tester", Password: "123456", Email: "tester@email.com"})
+
+		// Assert
+		if assert.Nil(t, err) {
+			assert.Equal(t, []ResponseUser{resp}, verifier.sent)
+		}
+	})
+	t.Run("TestAddUserServiceShouldIgnoreVerificationError", func(t *testing.T) {
+		// Arrange
+		query := &UserQueriesSuccess{}
+		verifier := &EmailVerifierStub{err: errors.New("smtp down")}
+		services := NewUserService(query, passwords.Policy{}, verifier, c.AuthConfig{}, logrus.New(), logrus.New())
+
+		// Act
+		resp, err := services.AddUser(context.Background(), RequestUser{Username: "tester", Password: "123456", Email: "tester@email.com"})
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, "tester", resp.Username)
+		assert.Len(t, verifier.sent, 1)
+	})
+	t.Run("TestAddUserServiceShouldRejectPasswordAgainstPolicy", func(t *testing.T) {
+		// Arrange
+		query := &UserQueriesSuccess{}
+		services := NewUserService(query, passwords.Policy{MinLength: 8}, &EmailVerifierStub{}, c.AuthConfig{}, logrus.New(), logrus.New())
+
+		// Act
+		_, err := services.AddUser(context.Background(), RequestUser{Username: "tester", Password: "123456", Email: "tester@email.com"})
+
+		// Assert
+		if assert.IsType(t, &c.Err{}, err) {
+			assert.Equal(t, http.StatusBadRequest, err.(*c.Err).Code)
+		}
+		assert.False(t, query.insertUserCalled)
+	})
 	t.Run("TestAddUserServiceShouldReturnError", func(t *testing.T) {
 		// Arrange
 		query := &UserQueriesError{}
 		log := logrus.New()
 
-		services := NewUserService(query, log)
+		services := NewUserService(query, passwords.Policy{}, &EmailVerifierStub{}, c.AuthConfig{}, logrus.New(), log)
 
 		mockData := RequestUser{
 			Username: "tester",
@@ -143,7 +367,7 @@ func TestAddUser(t *testing.T) {
 		}
 
 		// Act
-		resp, err := services.AddUser(mockData)
+		resp, err := services.AddUser(context.Background(), mockData)
 
 		// Assert
 		if assert.NotNil(t, err) {
@@ -159,7 +383,7 @@ func TestGetUser(t *testing.T) {
 		query := &UserQueriesSuccess{}
 		log := logrus.New()
 
-		services := NewUserService(query, log)
+		services := NewUserService(query, passwords.Policy{}, &EmailVerifierStub{}, c.AuthConfig{}, logrus.New(), log)
 
 		mockData := RequestUser{
 			Username: "tester",
@@ -169,7 +393,7 @@ func TestGetUser(t *testing.T) {
 		}
 
 		// Act
-		resp, err := services.GetUser(mockData.Username)
+		resp, err := services.GetUser(context.Background(), mockData.Username)
 
 		// Assert
 		if assert.Nil(t, err) {
@@ -187,12 +411,12 @@ func TestGetUser(t *testing.T) {
 		query := &UserQueriesError{}
 		log := logrus.New()
 
-		services := NewUserService(query, log)
+		services := NewUserService(query, passwords.Policy{}, &EmailVerifierStub{}, c.AuthConfig{}, logrus.New(), log)
 
 		mockUsername := "tester"
 
 		// Act
-		resp, err := services.GetUser(mockUsername)
+		resp, err := services.GetUser(context.Background(), mockUsername)
 
 		// Assert
 		if assert.NotNil(t, err) {
@@ -203,32 +427,25 @@ func TestGetUser(t *testing.T) {
 }
 
 func TestPutUser(t *testing.T) {
-	t.Run("TestPutUserServiceShouldReturnNoError", func(t *testing.T) {
+	t.Run("TestPutUserServiceShouldOnlyChangeFullname", func(t *testing.T) {
 		// Arrange
-		query := &UserQueriesSuccess{}
-		log := logrus.New()
-
-		services := NewUserService(query, log)
-		username := "tester"
-		mockData := RequestUser{
-			Username: "tester",
-			Password: "123456",
-			Email:    "tester@email.com",
-			Fullname: "tester testing",
-		}
+		query := newProfileQueriesStub(t)
+		services, hook := newCredentialServices(query)
 
 		// Act
-		resp, err := services.PutUser(username, mockData)
+		resp, err := services.PutUser(context.Background(), "tester", RequestProfile{Fullname: " tester testing "}, "admin")
 
 		// Assert
 		if assert.Nil(t, err) {
-			assert.Equal(t, true, query.updateUserCalled)
-			assert.Equal(t, mockData.Username, resp.Username)
-			assert.Equal(t, mockData.Email, resp.Email)
-			assert.Equal(t, mockData.Fullname, resp.Fullname)
-			assert.NotEqual(t, mockData.Password, resp.HashedPassword)
-
-			assert.NotEmpty(t, resp.CreatedAt)
+			assert.Equal(t, "tester", resp.Username)
+			assert.Equal(t, "tester testing", resp.Fullname)
+			assert.Empty(t, query.email)
+			assert.Empty(t, query.password)
+			assert.Empty(t, query.renamed)
+		}
+		if entry := hook.LastEntry(); assert.NotNil(t, entry) {
+			assert.Equal(t, eventProfileChanged, entry.Data["event"])
+			assert.Equal(t, "admin", entry.Data["by"])
 		}
 	})
 	t.Run("TestPutUserServiceShouldReturnError", func(t *testing.T) {
@@ -236,21 +453,13 @@ func TestPutUser(t *testing.T) {
 		query := &UserQueriesError{}
 		log := logrus.New()
 
-		services := NewUserService(query, log)
-		username := "tester"
-		mockData := RequestUser{
-			Username: "tester",
-			Password: "123456",
-			Email:    "tester@email.com",
-			Fullname: "tester testing",
-		}
+		services := NewUserService(query, passwords.Policy{}, &EmailVerifierStub{}, c.AuthConfig{}, logrus.New(), log)
 
 		// Act
-		resp, err := services.PutUser(username, mockData)
+		resp, err := services.PutUser(context.Background(), "tester", RequestProfile{Fullname: "tester testing"}, "admin")
 
 		// Assert
 		if assert.NotNil(t, err) {
-			assert.Equal(t, true, query.updateUserCalled)
 			assert.Empty(t, resp)
 		}
 	})
@@ -262,11 +471,11 @@ func TestDelUser(t *testing.T) {
 		query := &UserQueriesSuccess{}
 		log := logrus.New()
 
-		services := NewUserService(query, log)
+		services := NewUserService(query, passwords.Policy{}, &EmailVerifierStub{}, c.AuthConfig{}, logrus.New(), log)
 		mockUsername := "tester"
 
 		// Act
-		err := services.DeleteUser(mockUsername)
+		err := services.DeleteUser(context.Background(), mockUsername)
 
 		// Assert
 		if assert.Nil(t, err) {
@@ -278,12 +487,12 @@ func TestDelUser(t *testing.T) {
 		query := &UserQueriesError{}
 		log := logrus.New()
 
-		services := NewUserService(query, log)
+		services := NewUserService(query, passwords.Policy{}, &EmailVerifierStub{}, c.AuthConfig{}, logrus.New(), log)
 
 		mockUsername := "tester"
 
 		// Act
-		err := services.DeleteUser(mockUsername)
+		err := services.DeleteUser(context.Background(), mockUsername)
 
 		// Assert
 		if assert.NotNil(t, err) {
@@ -291,3 +500,83 @@ func TestDelUser(t *testing.T) {
 		}
 	})
 }
+
+func TestPutUserRole(t *testing.T) {
+	t.Run("TestPutUserRoleServiceShouldReturnNoError", func(t *testing.T) {
+		// Arrange
+		query := &UserQueriesSuccess{}
+		services := NewUserService(query, passwords.Policy{}, &EmailVerifierStub{}, c.AuthConfig{}, logrus.New(), logrus.New())
+
+		// Act
+		resp, err := services.PutUserRole(context.Background(), "tester", RequestUserRole{Role: UserRoleStaff})
+
+		// Assert
+		if assert.Nil(t, err) {
+			assert.Equal(t, true, query.updateUserCalled)
+			assert.Equal(t, UserRoleStaff, resp.Role)
+		}
+	})
+
+	t.Run("TestPutUserRoleServiceShouldRejectUnknownRole", func(t *testing.T) {
+		// Arrange
+		query := &UserQueriesSuccess{}
+		services := NewUserService(query, passwords.Policy{}, &EmailVerifierStub{}, c.AuthConfig{}, logrus.New(), logrus.New())
+
+		// Act
+		_, err := services.PutUserRole(context.Background(), "tester", RequestUserRole{Role: "owner"})
+
+		// Assert
+		if assert.NotNil(t, err) {
+			assert.Equal(t, 400, err.(*c.Err).Code)
+			assert.Equal(t, false, query.updateUserCalled)
+		}
+	})
+}
+
+func TestBootstrapAdmin(t *testing.T) {
+	t.Run("TestBootstrapAdminShouldPromoteConfiguredUser", func(t *testing.T) {
+		// Arrange
+		query := &UserQueriesSuccess{}
+		security, hook := test.NewNullLogger()
+		services := NewUserService(query, passwords.Policy{}, &EmailVerifierStub{}, c.AuthConfig{BootstrapAdmin: "tester"}, security, logrus.New())
+
+		// Act
+		err := services.BootstrapAdmin(context.Background())
+
+		// Assert
+		if assert.Nil(t, err) {
+			assert.Equal(t, "tester", query.promoted)
+			if assert.NotNil(t, hook.LastEntry()) {
+				assert.Equal(t, eventAdminBootstrap, hook.LastEntry().Data["event"])
+				assert.Equal(t, "tester", hook.LastEntry().Data["username"])
+			}
+		}
+	})
+
+	t.Run("TestBootstrapAdminShouldDoNothingWhenUnset", func(t *testing.T) {
+		// Arrange
+		query := &UserQueriesSuccess{}
+		services := NewUserService(query, passwords.Policy{}, &EmailVerifierStub{}, c.AuthConfig{}, logrus.New(), logrus.New())
+
+		// Act
+		err := services.BootstrapAdmin(context.Background())
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, "", query.promoted)
+	})
+
+	t.Run("TestBootstrapAdminShouldReturnError", func(t *testing.T) {
+		// Arrange
+		query := &UserQueriesError{}
+		services := NewUserService(query, passwords.Policy{}, &EmailVerifierStub{}, c.AuthConfig{BootstrapAdmin: "tester"}, logrus.New(), logrus.New())
+
+		// Act
+		err := services.BootstrapAdmin(context.Background())
+
+		// Assert
+		if assert.NotNil(t, err) {
+			assert.Equal(t, http.StatusInternalServerError, err.(*c.Err).Code)
+		}
+	})
+}
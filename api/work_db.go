@@ -0,0 +1,286 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+var errWorkNotFound = errors.New("error work not found")
+
+const workColumns = `id, title, authors, created_at`
+
+func scanWork(row rowScanner) (ResponseWork, error) {
+	resp := ResponseWork{}
+	err := row.Scan(&resp.Id, &resp.Title, pq.Array(&resp.Authors), &resp.CreatedAt)
+	if err != nil {
+		return ResponseWork{}, err
+	}
+	return resp, nil
+}
+
+func (db Query) InsertWork(ctx context.Context, req RequestWork) (ResponseWork, error) {
+	const query = `INSERT INTO works
+	(title, authors)
+	VALUES ($1, $2)
+	RETURNING ` + workColumns + `;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return ResponseWork{}, err
+	}
+	defer stmt.Close()
+
+	return scanWork(stmt.QueryRowContext(ctx, strings.TrimSpace(req.Title), pq.Array(req.Authors)))
+}
+
+func insertWork(tx *sql.Tx, req RequestWork) (uint64, error) {
+	var id uint64
+	err := tx.QueryRow(`INSERT INTO works (title, authors) VALUES ($1, $2) RETURNING id;`, req.Title, pq.Array(req.Authors)).Scan(&id)
+	return id, err
+}
+
+func selectWorkTitle(tx *sql.Tx, id uint64) (string, []string, error) {
+	var title string
+	var authors []string
+	err := tx.QueryRow(`SELECT title, authors FROM works WHERE id = $1;`, id).Scan(&title, pq.Array(&authors))
+	if err == sql.ErrNoRows {
+		return "", nil, errWorkNotFound
+	}
+	return title, authors, err
+}
+
+func (db Query) SelectAllWorks(ctx context.Context, params GetAllParams) ([]ResponseWork, error) {
+	const query = `SELECT ` + workColumns + `
+	FROM works
+	ORDER BY id
+	LIMIT $1
+	OFFSET $2;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, params.Limit, params.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resp := []ResponseWork{}
+	for rows.Next() {
+		result, err := scanWork(rows)
+		if err != nil {
+			return nil, err
+		}
+		resp = append(resp, result)
+	}
+	return resp, rows.Err()
+}
+
+func (db Query) SelectWork(ctx context.Context, id uint64) (ResponseWork, error) {
+	const query = `SELECT ` + workColumns + ` FROM works WHERE id = $1;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return ResponseWork{}, err
+	}
+	defer stmt.Close()
+
+	return scanWork(stmt.QueryRowContext(ctx, id))
+}
+
+// UpdateWork changes the title and authors of a work and of every edition
+// belonging to it.
+func (db Query) UpdateWork(ctx context.Context, id uint64, req RequestWork) (ResponseWork, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return ResponseWork{}, err
+	}
+	defer tx.Rollback()
+
+	err = syncWorkEditions(tx, id, strings.TrimSpace(req.Title), req.Authors, 0)
+	if err != nil {
+		return ResponseWork{}, err
+	}
+
+	resp, err := scanWork(tx.QueryRow(`SELECT `+workColumns+` FROM works WHERE id = $1;`, id))
+	if err != nil {
+		return ResponseWork{}, err
+	}
+	return resp, tx.Commit()
+}
+
+// syncWorkEditions writes title and authors to a work and copies them to its
+// editions, except skipBookId whose row the caller has already updated. The
+// author links of each copied edition are refreshed as well.
+func syncWorkEditions(tx *sql.Tx, workId uint64, title string, authors []string, skipBookId uint64) error {
+	const updateWork = `UPDATE works SET title = $1, authors = $2 WHERE id = $3;`
+	const updateEditions = `UPDATE books
+	SET title = $1, authors = $2
+	WHERE work_id = $3 AND id <> $4
+	RETURNING id, publisher;`
+
+	res, err := tx.Exec(updateWork, title, pq.Array(authors), workId)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+
+	type edition struct {
+		id        uint64
+		publisher string
+	}
+	rows, err := tx.Query(updateEditions, title, pq.Array(authors), workId, skipBookId)
+	if err != nil {
+		return err
+	}
+	editions := []edition{}
+	for rows.Next() {
+		e := edition{}
+		if err := rows.Scan(&e.id, &e.publisher); err != nil {
+			rows.Close()
+			return err
+		}
+		editions = append(editions, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, e := range editions {
+		if err := linkBookContributors(tx, e.id, authors, e.publisher); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db Query) DeleteWork(ctx context.Context, id uint64) error {
+	const query = `DELETE FROM works WHERE id = $1;`
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// SelectWorkEditions returns the editions of the given works keyed by work
+// id, each list ordered by edition id.
+func (db Query) SelectWorkEditions(ctx context.Context, workIds []uint64) (map[uint64][]ResponseBook, error) {
+	const query = `SELECT ` + bookColumns + `
+	FROM books
+	WHERE work_id = ANY($1)
+	ORDER BY id;`
+
+	resp := map[uint64][]ResponseBook{}
+	if len(workIds) == 0 {
+		return resp, nil
+	}
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	ids := make([]int64, len(workIds))
+	for i, id := range workIds {
+		ids[i] = int64(id)
+	}
+
+	rows, err := stmt.QueryContext(ctx, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		b, err := scanBook(rows)
+		if err != nil {
+			return nil, err
+		}
+		resp[b.WorkId] = append(resp[b.WorkId], *b)
+	}
+	return resp, rows.Err()
+}
+
+// migrateBookWorks gives every book without a work a work of its own, and
+// fills in the format and publication date of ONIX-ingested books from their
+// stored ONIX details.
+func migrateBookWorks(tx *sql.Tx) error {
+	type bookWork struct {
+		id              uint64
+		title           string
+		authors         []string
+		productForm     string
+		publicationDate string
+	}
+
+	if _, err := tx.Exec(sqlWorks); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(`SELECT b.id, b.title, b.authors, COALESCE(d.product_form, ''), COALESCE(d.publication_date, '')
+	FROM books b
+	LEFT JOIN book_onix_details d ON d.book_id = b.id
+	WHERE b.work_id IS NULL
+	ORDER BY b.id;`)
+	if err != nil {
+		return err
+	}
+	books := []bookWork{}
+	for rows.Next() {
+		b := bookWork{}
+		if err := rows.Scan(&b.id, &b.title, pq.Array(&b.authors), &b.productForm, &b.publicationDate); err != nil {
+			rows.Close()
+			return err
+		}
+		books = append(books, b)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, b := range books {
+		workId, err := insertWork(tx, RequestWork{Title: b.title, Authors: b.authors})
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`UPDATE books SET work_id = $1, format = $2, publication_date = NULLIF($3, '')::date WHERE id = $4;`,
+			workId, onixEditionFormat(b.productForm), onixDate(b.publicationDate), b.id)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(`ALTER TABLE books ALTER COLUMN work_id SET NOT NULL;`)
+	return err
+}
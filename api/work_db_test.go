@@ -0,0 +1,158 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateWork(t *testing.T) {
+	t.Run("TestUpdateWorkShouldCopyTitleToEditions", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		authors := []string{"Ursula K. Le Guin"}
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE works SET title = $1, authors = $2 WHERE id = $3;`)).
+			WithArgs("The Dispossessed", pq.Array(authors), 3).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE books SET title = $1, authors = $2 WHERE work_id = $3 AND id <> $4 RETURNING id, publisher;`)).
+			WithArgs("The Dispossessed", pq.Array(authors), 3, 0).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "publisher"}).AddRow(10, ""))
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM book_authors WHERE book_id = $1 AND role = $2;`)).
+			WithArgs(10, RoleAuthor).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO authors (name, name_key) VALUES ($1, $2)`)).
+			WithArgs("Ursula K. Le Guin", nameKey("Ursula K. Le Guin")).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO book_authors (book_id, author_id, role, position) VALUES ($1, $2, $3, $4)`)).
+			WithArgs(10, 4, RoleAuthor, 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE books SET publisher_id = $1 WHERE id = $2;`)).
+			WithArgs(nil, 10).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, title, authors, created_at FROM works WHERE id = $1;`)).
+			WithArgs(3).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "title", "authors", "created_at"}).
+				AddRow(3, "The Dispossessed", pq.Array(authors), time.Now()))
+		mock.ExpectCommit()
+
+		query := NewDB(db)
+
+		// Act
+		res, err := query.UpdateWork(context.Background(), 3, RequestWork{Title: " The Dispossessed ", Authors: authors})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "The Dispossessed", res.Title)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TestUpdateWorkShouldReturnErrNoRowsWhenMissing", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE works SET title = $1, authors = $2 WHERE id = $3;`)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		query := NewDB(db)
+
+		// Act
+		_, err = query.UpdateWork(context.Background(), 3, RequestWork{Title: "Missing"})
+
+		// Assert
+		assert.Equal(t, sql.ErrNoRows, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestSelectWorkEditions(t *testing.T) {
+	t.Run("TestSelectWorkEditionsShouldGroupByWork", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		now := time.Now()
+		rows := sqlmock.NewRows([]string{"id", "title", "authors", "publisher", "isbn", "price", "quantity", "created_by", "created_at", "work_id", "format", "publication_date", "rating_count", "rating_sum"}).
+			AddRow(1, "Dune", pq.Array([]string{"Frank Herbert"}), "Chilton", "9780000000011", 1500, 2, "Admin", now, 1, EditionHardcover, "1965-08-01", 4, 18).
+			AddRow(2, "Dune", pq.Array([]string{"Frank Herbert"}), "Ace", "9780000000028", 500, 10, "Admin", now, 1, EditionPaperback, "", 0, 0).
+			AddRow(3, "Emma", pq.Array([]string{"Jane Austen"}), "Murray", "9780000000035", 300, 1, "Admin", now, 2, EditionEbook, "", 0, 0)
+		mock.ExpectPrepare(regexp.QuoteMeta(`FROM books WHERE work_id = ANY($1) ORDER BY id;`)).
+			ExpectQuery().
+			WithArgs(pq.Array([]int64{1, 2})).
+			WillReturnRows(rows)
+
+		query := NewDB(db)
+
+		// Act
+		res, err := query.SelectWorkEditions(context.Background(), []uint64{1, 2})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Len(t, res[1], 2)
+		assert.Len(t, res[2], 1)
+		assert.Equal(t, "1965-08-01", res[1][0].PublicationDate)
+		assert.Equal(t, int64(4), res[1][0].RatingCount)
+		assert.Equal(t, 4.5, res[1][0].RatingAvg)
+		assert.Equal(t, 0.0, res[1][1].RatingAvg)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestMigrateBookWorks(t *testing.T) {
+	t.Run("TestMigrateBookWorksShouldGiveEachBookAWork", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE IF NOT EXISTS works`)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT b.id, b.title, b.authors, COALESCE(d.product_form, ''), COALESCE(d.publication_date, '') FROM books b`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "title", "authors", "product_form", "publication_date"}).
+				AddRow(1, "Dune", pq.Array([]string{"Frank Herbert"}), "BB", "19650801").
+				AddRow(2, "Emma", pq.Array([]string{"Jane Austen"}), "", ""))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO works (title, authors) VALUES ($1, $2) RETURNING id;`)).
+			WithArgs("Dune", pq.Array([]string{"Frank Herbert"})).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE books SET work_id = $1, format = $2, publication_date = NULLIF($3, '')::date WHERE id = $4;`)).
+			WithArgs(1, EditionHardcover, "1965-08-01", 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO works (title, authors) VALUES ($1, $2) RETURNING id;`)).
+			WithArgs("Emma", pq.Array([]string{"Jane Austen"})).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE books SET work_id = $1`)).
+			WithArgs(2, EditionUnspecified, "", 2).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`ALTER TABLE books ALTER COLUMN work_id SET NOT NULL;`)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		tx, err := db.Begin()
+		assert.NoError(t, err)
+
+		// Act
+		err = migrateBookWorks(tx)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NoError(t, tx.Commit())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
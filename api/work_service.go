@@ -0,0 +1,221 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/paquesqueue/bookstore/tracing"
+)
+
+const (
+	EditionUnspecified = ""
+	EditionHardcover   = "hardcover"
+	EditionPaperback   = "paperback"
+	EditionEbook       = "ebook"
+	EditionAudiobook   = "audiobook"
+
+	editionDateLayout = "2006-01-02"
+
+	// GroupByWork makes GET /books list works with their editions.
+	GroupByWork = "work"
+)
+
+var editionFormats = []string{EditionUnspecified, EditionHardcover, EditionPaperback, EditionEbook, EditionAudiobook}
+
+type WorkQueries interface {
+	InsertWork(ctx context.Context, req RequestWork) (ResponseWork, error)
+	SelectAllWorks(ctx context.Context, params GetAllParams) ([]ResponseWork, error)
+	SelectWork(ctx context.Context, id uint64) (ResponseWork, error)
+	UpdateWork(ctx context.Context, id uint64, req RequestWork) (ResponseWork, error)
+	DeleteWork(ctx context.Context, id uint64) error
+	SelectWorkEditions(ctx context.Context, workIds []uint64) (map[uint64][]ResponseBook, error)
+	SelectCoverVersions(ctx context.Context, bookIds []uint64) (map[uint64]string, error)
+}
+
+type WorkServices struct {
+	query WorkQueries
+	log   c.Log
+}
+
+func NewWorkService(q WorkQueries, l c.Log) WorkServices {
+	return WorkServices{q, l}
+}
+
+func (s WorkServices) AddWork(ctx context.Context, req RequestWork) (ResponseWork, error) {
+	ctx, span := tracing.Start(ctx, "WorkServices.AddWork")
+	defer span.End()
+
+	req, err := validWork(req)
+	if err != nil {
+		return ResponseWork{}, err
+	}
+
+	resp, err := s.query.InsertWork(ctx, req)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error InsertWork : %v", err)
+		return ResponseWork{}, workErr(err, "Error AddWork Service")
+	}
+	resp.Formats = []string{}
+	resp.Editions = []ResponseBook{}
+	return resp, nil
+}
+
+func (s WorkServices) ListWorks(ctx context.Context, params GetAllParams) ([]ResponseWork, error) {
+	ctx, span := tracing.Start(ctx, "WorkServices.ListWorks")
+	defer span.End()
+
+	resp, err := s.query.SelectAllWorks(ctx, params)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error SelectAllWorks : %v", err)
+		return nil, &c.Err{Code: http.StatusInternalServerError, Remark: "Error ListWorks Service", Original: err}
+	}
+
+	err = addWorkEditions(ctx, s.query, resp)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error addWorkEditions : %v", err)
+		return nil, &c.Err{Code: http.StatusInternalServerError, Remark: "Error ListWorks Service", Original: err}
+	}
+	return resp, nil
+}
+
+func (s WorkServices) GetWork(ctx context.Context, id uint64) (ResponseWork, error) {
+	ctx, span := tracing.Start(ctx, "WorkServices.GetWork")
+	defer span.End()
+
+	resp, err := s.query.SelectWork(ctx, id)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error SelectWork : %v", err)
+		return ResponseWork{}, workErr(err, "Error GetWork Service")
+	}
+
+	works := []ResponseWork{resp}
+	err = addWorkEditions(ctx, s.query, works)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error addWorkEditions : %v", err)
+		return ResponseWork{}, &c.Err{Code: http.StatusInternalServerError, Remark: "Error GetWork Service", Original: err}
+	}
+	return works[0], nil
+}
+
+func (s WorkServices) PutWork(ctx context.Context, id uint64, req RequestWork) (ResponseWork, error) {
+	ctx, span := tracing.Start(ctx, "WorkServices.PutWork")
+	defer span.End()
+
+	req, err := validWork(req)
+	if err != nil {
+		return ResponseWork{}, err
+	}
+
+	_, err = s.query.UpdateWork(ctx, id, req)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error UpdateWork : %v", err)
+		return ResponseWork{}, workErr(err, "Error PutWork Service")
+	}
+	return s.GetWork(ctx, id)
+}
+
+func (s WorkServices) DeleteWork(ctx context.Context, id uint64) error {
+	ctx, span := tracing.Start(ctx, "WorkServices.DeleteWork")
+	defer span.End()
+
+	err := s.query.DeleteWork(ctx, id)
+	if err != nil {
+		c.LogFrom(ctx, s.log).Errorf("Error DeleteWork : %v", err)
+		return workErr(err, "Error DeleteWork Service")
+	}
+	return nil
+}
+
+type workEditionQueries interface {
+	coverVersionQueries
+	SelectWorkEditions(ctx context.Context, workIds []uint64) (map[uint64][]ResponseBook, error)
+}
+
+// addWorkEditions loads the editions of each work and summarises their
+// formats, prices and stock.
+func addWorkEditions(ctx context.Context, q workEditionQueries, works []ResponseWork) error {
+	ids := make([]uint64, len(works))
+	for i, w := range works {
+		ids[i] = w.Id
+	}
+
+	editions, err := q.SelectWorkEditions(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range works {
+		w := &works[i]
+		w.Editions = editions[w.Id]
+		if w.Editions == nil {
+			w.Editions = []ResponseBook{}
+		}
+		if err := addCoverUrls(ctx, q, w.Editions); err != nil {
+			return err
+		}
+
+		w.Formats = []string{}
+		w.MinPrice, w.MaxPrice, w.Quantity = 0, 0, 0
+		seen := map[string]bool{}
+		for j, e := range w.Editions {
+			if e.Format != EditionUnspecified && !seen[e.Format] {
+				seen[e.Format] = true
+				w.Formats = append(w.Formats, e.Format)
+			}
+			if j == 0 || e.Price < w.MinPrice {
+				w.MinPrice = e.Price
+			}
+			if j == 0 || e.Price > w.MaxPrice {
+				w.MaxPrice = e.Price
+			}
+			w.Quantity += e.Quantity
+		}
+	}
+	return nil
+}
+
+func validWork(req RequestWork) (RequestWork, error) {
+	req.Title = strings.TrimSpace(req.Title)
+	if req.Title == "" {
+		return RequestWork{}, &c.Err{Code: http.StatusBadRequest, Remark: "Error Work Title Required"}
+	}
+	if req.Authors == nil {
+		req.Authors = []string{}
+	}
+	return req, nil
+}
+
+// validEdition checks the edition-level fields of a book.
+func validEdition(req RequestBook) error {
+	known := false
+	for _, f := range editionFormats {
+		if req.Format == f {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return &c.Err{Code: http.StatusBadRequest, Remark: "Error Unknown Edition Format"}
+	}
+	if req.PublicationDate != "" {
+		if _, err := time.Parse(editionDateLayout, req.PublicationDate); err != nil {
+			return &c.Err{Code: http.StatusBadRequest, Remark: "Error Invalid Publication Date", Original: err}
+		}
+	}
+	return nil
+}
+
+func workErr(err error, remark string) *c.Err {
+	switch {
+	case err == sql.ErrNoRows:
+		return &c.Err{Code: http.StatusNotFound, Remark: "Error Work Not Found", Original: err}
+	case isPqError(err, pqForeignKeyViolation):
+		return &c.Err{Code: http.StatusConflict, Remark: "Error Work Still Has Editions", Original: err}
+	default:
+		return &c.Err{Code: http.StatusInternalServerError, Remark: remark, Original: err}
+	}
+}
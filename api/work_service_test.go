@@ -0,0 +1,181 @@
+//go:build unit
+
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"testing"
+
+	"github.com/lib/pq"
+	c "github.com/paquesqueue/bookstore/common"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type WorkQueriesStub struct {
+	works    map[uint64]ResponseWork
+	editions map[uint64][]ResponseBook
+	covers   map[uint64]string
+	err      error
+}
+
+func (q *WorkQueriesStub) InsertWork(ctx context.Context, req RequestWork) (ResponseWork, error) {
+	w := ResponseWork{Id: uint64(len(q.works) + 1), Title: req.Title, Authors: req.Authors}
+	q.works[w.Id] = w
+	return w, q.err
+}
+
+func (q *WorkQueriesStub) SelectAllWorks(ctx context.Context, params GetAllParams) ([]ResponseWork, error) {
+	resp := []ResponseWork{}
+	for id := uint64(1); id <= uint64(len(q.works)); id++ {
+		resp = append(resp, q.works[id])
+	}
+	return resp, q.err
+}
+
+func (q *WorkQueriesStub) SelectWork(ctx context.Context, id uint64) (ResponseWork, error) {
+	w, ok := q.works[id]
+	if !ok {
+		return ResponseWork{}, sql.ErrNoRows
+	}
+	return w, q.err
+}
+
+func (q *WorkQueriesStub) UpdateWork(ctx context.Context, id uint64, req RequestWork) (ResponseWork, error) {
+	if _, ok := q.works[id]; !ok {
+		return ResponseWork{}, sql.ErrNoRows
+	}
+	q.works[id] = ResponseWork{Id: id, Title: req.Title, Authors: req.Authors}
+	return q.works[id], q.err
+}
+
+func (q *WorkQueriesStub) DeleteWork(ctx context.Context, id uint64) error {
+	if len(q.editions[id]) > 0 {
+		return &pq.Error{Code: pqForeignKeyViolation}
+	}
+	delete(q.works, id)
+	return q.err
+}
+
+func (q *WorkQueriesStub) SelectWorkEditions(ctx context.Context, workIds []uint64) (map[uint64][]ResponseBook, error) {
+	return q.editions, q.err
+}
+
+func (q *WorkQueriesStub) SelectCoverVersions(ctx context.Context, bookIds []uint64) (map[uint64]string, error) {
+	return q.covers, q.err
+}
+
+func newWorkQueriesStub() *WorkQueriesStub {
+	return &WorkQueriesStub{
+		works: map[uint64]ResponseWork{
+			1: {Id: 1, Title: "Dune", Authors: []string{"Frank Herbert"}},
+		},
+		editions: map[uint64][]ResponseBook{
+			1: {
+				{Id: 10, WorkId: 1, Format: EditionHardcover, Price: 1500, Quantity: 2},
+				{Id: 11, WorkId: 1, Format: EditionPaperback, Price: 500, Quantity: 10},
+				{Id: 12, WorkId: 1, Format: EditionPaperback, Price: 450, Quantity: 1},
+			},
+		},
+		covers: map[uint64]string{11: "abc"},
+	}
+}
+
+func TestGetWork(t *testing.T) {
+	t.Run("TestGetWorkShouldAggregateEditions", func(t *testing.T) {
+		// Arrange
+		services := NewWorkService(newWorkQueriesStub(), logrus.New())
+
+		// Act
+		res, err := services.GetWork(context.Background(), 1)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Len(t, res.Editions, 3)
+		assert.Equal(t, []string{EditionHardcover, EditionPaperback}, res.Formats)
+		assert.Equal(t, int64(450), res.MinPrice)
+		assert.Equal(t, int64(1500), res.MaxPrice)
+		assert.Equal(t, int64(13), res.Quantity)
+		assert.Equal(t, "/books/11/cover/large?v=abc", res.Editions[1].CoverUrls[CoverLarge])
+		assert.Nil(t, res.Editions[0].CoverUrls)
+	})
+
+	t.Run("TestGetWorkShouldReturnNotFound", func(t *testing.T) {
+		// Arrange
+		services := NewWorkService(newWorkQueriesStub(), logrus.New())
+
+		// Act
+		_, err := services.GetWork(context.Background(), 2)
+
+		// Assert
+		assert.Equal(t, http.StatusNotFound, err.(*c.Err).Code)
+	})
+}
+
+func TestAddWork(t *testing.T) {
+	t.Run("TestAddWorkShouldReturnEmptyEditions", func(t *testing.T) {
+		// Arrange
+		services := NewWorkService(newWorkQueriesStub(), logrus.New())
+
+		// Act
+		res, err := services.AddWork(context.Background(), RequestWork{Title: " Emma "})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "Emma", res.Title)
+		assert.Equal(t, []string{}, res.Authors)
+		assert.Equal(t, []ResponseBook{}, res.Editions)
+	})
+
+	t.Run("TestAddWorkShouldRequireTitle", func(t *testing.T) {
+		// Arrange
+		services := NewWorkService(newWorkQueriesStub(), logrus.New())
+
+		// Act
+		_, err := services.AddWork(context.Background(), RequestWork{Title: "  "})
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, err.(*c.Err).Code)
+	})
+}
+
+func TestPutWork(t *testing.T) {
+	t.Run("TestPutWorkShouldReturnUpdatedWorkWithEditions", func(t *testing.T) {
+		// Arrange
+		services := NewWorkService(newWorkQueriesStub(), logrus.New())
+
+		// Act
+		res, err := services.PutWork(context.Background(), 1, RequestWork{Title: "Dune Messiah", Authors: []string{"Frank Herbert"}})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "Dune Messiah", res.Title)
+		assert.Len(t, res.Editions, 3)
+	})
+}
+
+func TestDeleteWork(t *testing.T) {
+	t.Run("TestDeleteWorkShouldReturnConflictWithEditions", func(t *testing.T) {
+		// Arrange
+		services := NewWorkService(newWorkQueriesStub(), logrus.New())
+
+		// Act
+		err := services.DeleteWork(context.Background(), 1)
+
+		// Assert
+		assert.Equal(t, http.StatusConflict, err.(*c.Err).Code)
+	})
+}
+
+func TestValidEdition(t *testing.T) {
+	t.Run("TestValidEditionShouldRejectUnknownFormatAndDate", func(t *testing.T) {
+		// Arrange & Act & Assert
+		assert.NoError(t, validEdition(RequestBook{}))
+		assert.NoError(t, validEdition(RequestBook{Format: EditionAudiobook, PublicationDate: "2020-02-29"}))
+		assert.Error(t, validEdition(RequestBook{Format: "scroll"}))
+		assert.Error(t, validEdition(RequestBook{PublicationDate: "2021-02-29"}))
+		assert.Error(t, validEdition(RequestBook{PublicationDate: "20210101"}))
+	})
+}
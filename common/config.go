@@ -1,20 +1,984 @@
 package common
 
-import "os"
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/mail"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is loaded in layers: defaults, then the YAML file named by -config
+// or CONFIG_FILE, then environment variables, then command-line flags.
 type Config struct {
-	DriverName  string
-	Port        string
-	Url         string
-	AccessToken string
+	Server    ServerConfig    `yaml:"server"`
+	DB        DBConfig        `yaml:"db"`
+	Log       LogConfig       `yaml:"log"`
+	Auth      AuthConfig      `yaml:"auth"`
+	Password  PasswordConfig  `yaml:"password"`
+	Storage   StorageConfig   `yaml:"storage"`
+	Mail      MailConfig      `yaml:"mail"`
+	Tracing   TracingConfig   `yaml:"tracing"`
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+	Features  FeatureConfig   `yaml:"features"`
+}
+
+type ServerConfig struct {
+	Port            string        `yaml:"port"`
+	ReadTimeout     time.Duration `yaml:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	DrainDelay      time.Duration `yaml:"drain_delay"`
+	// TrustedProxies are the CIDR ranges of the proxies in front of the
+	// service. Only they may set the client address with X-Forwarded-For;
+	// without any the address is the connection's.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+type DBConfig struct {
+	Driver          string        `yaml:"driver"`
+	Url             string        `yaml:"url"`
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+	ConnectTimeout  time.Duration `yaml:"connect_timeout"`
+	RetryInitial    time.Duration `yaml:"retry_initial"`
+	RetryMax        time.Duration `yaml:"retry_max"`
+	HealthInterval  time.Duration `yaml:"health_interval"`
+	HealthTimeout   time.Duration `yaml:"health_timeout"`
+}
+
+// LogConfig applies to both the application and the request logger; the
+// request logger has its own level and file.
+type LogConfig struct {
+	Level         string        `yaml:"level"`
+	RequestLevel  string        `yaml:"request_level"`
+	Format        string        `yaml:"format"`
+	Sinks         []string      `yaml:"sinks"`
+	File          string        `yaml:"file"`
+	RequestFile   string        `yaml:"request_file"`
+	SecurityFile  string        `yaml:"security_file"`
+	MaxSizeMB     int           `yaml:"max_size_mb"`
+	RotateEvery   time.Duration `yaml:"rotate_every"`
+	MaxAge        time.Duration `yaml:"max_age"`
+	MaxBackups    int           `yaml:"max_backups"`
+	Compress      bool          `yaml:"compress"`
+	SyslogNetwork string        `yaml:"syslog_network"`
+	SyslogAddress string        `yaml:"syslog_address"`
+}
+
+type AuthConfig struct {
+	AccessToken string `yaml:"access_token"`
+	// ErasureKey keys the hash of an erased user's old username on the
+	// erasure receipt, so the name cannot be guessed back from the hash.
+	ErasureKey string `yaml:"erasure_key"`
+	// AppURL is where links in account emails point, e.g.
+	// <app_url>/reset-password?token=...
+	AppURL           string        `yaml:"app_url"`
+	VerifyEmailTTL   time.Duration `yaml:"verify_email_ttl"`
+	PasswordResetTTL time.Duration `yaml:"password_reset_ttl"`
+	// An account, or an IP address, is locked out after its threshold of
+	// failed logins, for LockoutBase, doubling with each further failure
+	// up to LockoutMax. Failures older than LockoutWindow are forgotten.
+	LockoutThreshold   int           `yaml:"lockout_threshold"`
+	LockoutIPThreshold int           `yaml:"lockout_ip_threshold"`
+	LockoutBase        time.Duration `yaml:"lockout_base"`
+	LockoutMax         time.Duration `yaml:"lockout_max"`
+	LockoutWindow      time.Duration `yaml:"lockout_window"`
+	// Users holding one of MFARequiredRoles must turn on two-factor
+	// authentication before using the routes limited to their role. A
+	// login with it on is finished within MFAChallengeTTL by a code from
+	// the authenticator, accepted MFASkew periods early or late, or by one
+	// of MFARecoveryCodes single-use codes.
+	MFAIssuer        string        `yaml:"mfa_issuer"`
+	MFARequiredRoles []string      `yaml:"mfa_required_roles"`
+	MFAChallengeTTL  time.Duration `yaml:"mfa_challenge_ttl"`
+	MFARecoveryCodes int           `yaml:"mfa_recovery_codes"`
+	MFASkew          int           `yaml:"mfa_skew"`
+	// BootstrapAdmin is a registered username promoted to admin at startup
+	// while there is no admin yet. Once one exists it has no effect.
+	BootstrapAdmin string `yaml:"bootstrap_admin"`
+}
+
+// PasswordConfig is the policy new passwords must meet and how they are
+// hashed. Stored hashes made with other settings are upgraded when their
+// owner next logs in.
+type PasswordConfig struct {
+	MinLength      int  `yaml:"min_length"`
+	MaxLength      int  `yaml:"max_length"`
+	RequireClasses int  `yaml:"require_classes"`
+	RejectSimilar  bool `yaml:"reject_similar"`
+	// BreachedDir holds breached password hashes split by prefix, one
+	// <first 5 hex digits of the SHA-1>.txt file per prefix.
+	BreachedDir   string `yaml:"breached_dir"`
+	Hash          string `yaml:"hash"`
+	BcryptCost    int    `yaml:"bcrypt_cost"`
+	Argon2Time    int    `yaml:"argon2_time"`
+	Argon2Memory  int    `yaml:"argon2_memory_kib"`
+	Argon2Threads int    `yaml:"argon2_threads"`
+}
+
+type StorageConfig struct {
+	Driver      string `yaml:"driver"`
+	Dir         string `yaml:"dir"`
+	S3Endpoint  string `yaml:"s3_endpoint"`
+	S3Region    string `yaml:"s3_region"`
+	S3Bucket    string `yaml:"s3_bucket"`
+	S3AccessKey string `yaml:"s3_access_key"`
+	S3SecretKey string `yaml:"s3_secret_key"`
 }
 
-func InitConfig() Config {
+// MailConfig selects how emails are sent: logged, written to Dir as .eml
+// files, or sent through an SMTP server.
+type MailConfig struct {
+	Driver       string        `yaml:"driver"`
+	From         string        `yaml:"from"`
+	Dir          string        `yaml:"dir"`
+	SMTPHost     string        `yaml:"smtp_host"`
+	SMTPPort     int           `yaml:"smtp_port"`
+	SMTPUsername string        `yaml:"smtp_username"`
+	SMTPPassword string        `yaml:"smtp_password"`
+	SMTPTimeout  time.Duration `yaml:"smtp_timeout"`
+}
+
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter"`
+	Endpoint    string  `yaml:"endpoint"`
+	ServiceName string  `yaml:"service_name"`
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+// RateLimitConfig limits each client, by user or IP address, per route.
+// Routes are keyed like "GET /books/:id"; other routes share Default.
+type RateLimitConfig struct {
+	Enabled       bool                 `yaml:"enabled"`
+	Default       RateLimit            `yaml:"default"`
+	Routes        map[string]RateLimit `yaml:"routes"`
+	Store         string               `yaml:"store"`
+	RedisAddr     string               `yaml:"redis_addr"`
+	RedisPassword string               `yaml:"redis_password"`
+	RedisDB       int                  `yaml:"redis_db"`
+}
+
+// RateLimit allows Requests per Period, written like 120/1m. The zero
+// value, written off, does not limit.
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+}
+
+func ParseRateLimit(s string) (RateLimit, error) {
+	s = strings.TrimSpace(s)
+	if s == "off" {
+		return RateLimit{}, nil
+	}
+	requests, period, ok := strings.Cut(s, "/")
+	n, err := strconv.Atoi(requests)
+	if !ok || err != nil || n <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q, want requests/period like 120/1m or off", s)
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q, want requests/period like 120/1m or off", s)
+	}
+	return RateLimit{n, d}, nil
+}
+
+func (l RateLimit) String() string {
+	if l.Requests <= 0 || l.Period <= 0 {
+		return "off"
+	}
+	// Drop the zero units time.Duration adds, so 1m0s prints as 1m.
+	period := l.Period.String()
+	for _, zero := range []string{"0s", "0m"} {
+		p := strings.TrimSuffix(period, zero)
+		if p != period && strings.IndexAny(p[len(p)-1:], "hms") == 0 {
+			period = p
+		}
+	}
+	return fmt.Sprintf("%d/%v", l.Requests, period)
+}
+
+func (l RateLimit) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+func (l *RateLimit) UnmarshalText(text []byte) error {
+	parsed, err := ParseRateLimit(string(text))
+	if err != nil {
+		return err
+	}
+	*l = parsed
+	return nil
+}
+
+type FeatureConfig struct {
+	ReviewBlockedWords []string      `yaml:"review_blocked_words"`
+	RecommendInterval  time.Duration `yaml:"recommend_interval"`
+}
+
+const (
+	LogFormatJSON   = "json"
+	LogFormatText   = "text"
+	LogFormatLogfmt = "logfmt"
+
+	LogSinkStdout = "stdout"
+	LogSinkFile   = "file"
+	LogSinkSyslog = "syslog"
+	LogSinkNone   = "none"
+
+	TracingExporterNone   = "none"
+	TracingExporterStdout = "stdout"
+	TracingExporterOTLP   = "otlp"
+
+	PasswordHashBcrypt   = "bcrypt"
+	PasswordHashArgon2id = "argon2id"
+
+	MailDriverLog  = "log"
+	MailDriverFile = "file"
+	MailDriverSMTP = "smtp"
+
+	RateLimitStoreMemory = "memory"
+	RateLimitStoreRedis  = "redis"
+
+	redacted = "REDACTED"
+)
+
+func DefaultConfig() Config {
 	return Config{
-		DriverName:  os.Getenv("DRIVER_NAME"),
-		Port:        os.Getenv("PORT"),
-		Url:         os.Getenv("DATABASE_URL"),
-		AccessToken: os.Getenv("ACCESS_TOKEN"),
+		Server: ServerConfig{
+			ReadTimeout:     15 * time.Second,
+			WriteTimeout:    30 * time.Second,
+			IdleTimeout:     60 * time.Second,
+			ShutdownTimeout: 10 * time.Second,
+		},
+		DB: DBConfig{
+			Driver:          "postgres",
+			MaxOpenConns:    25,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+			ConnectTimeout:  time.Minute,
+			RetryInitial:    500 * time.Millisecond,
+			RetryMax:        10 * time.Second,
+			HealthInterval:  30 * time.Second,
+			HealthTimeout:   2 * time.Second,
+		},
+		Log: LogConfig{
+			Level:        "info",
+			Format:       LogFormatJSON,
+			Sinks:        []string{LogSinkStdout, LogSinkFile},
+			File:         "logs.text",
+			RequestFile:  "request-logs.text",
+			SecurityFile: "security-logs.text",
+			MaxSizeMB:    100,
+			MaxAge:       30 * 24 * time.Hour,
+			MaxBackups:   10,
+			Compress:     true,
+		},
+		Auth: AuthConfig{
+			AppURL:             "http://localhost:2565",
+			VerifyEmailTTL:     48 * time.Hour,
+			PasswordResetTTL:   time.Hour,
+			LockoutThreshold:   5,
+			LockoutIPThreshold: 20,
+			LockoutBase:        time.Minute,
+			LockoutMax:         time.Hour,
+			LockoutWindow:      24 * time.Hour,
+			MFAIssuer:          "Bookstore",
+			MFARequiredRoles:   []string{"staff", "admin"},
+			MFAChallengeTTL:    5 * time.Minute,
+			MFARecoveryCodes:   10,
+			MFASkew:            1,
+		},
+		Password: PasswordConfig{
+			MinLength:     8,
+			MaxLength:     128,
+			RejectSimilar: true,
+			Hash:          PasswordHashArgon2id,
+			BcryptCost:    10,
+			Argon2Time:    3,
+			Argon2Memory:  64 * 1024,
+			Argon2Threads: 4,
+		},
+		Mail: MailConfig{
+			Driver:      MailDriverLog,
+			From:        "Bookstore <no-reply@bookstore.local>",
+			Dir:         "outbox",
+			SMTPPort:    587,
+			SMTPTimeout: 10 * time.Second,
+		},
+		Tracing: TracingConfig{
+			Exporter:    TracingExporterNone,
+			Endpoint:    "http://localhost:4318",
+			ServiceName: "bookstore",
+			SampleRatio: 1,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Default: RateLimit{600, time.Minute},
+			Routes: map[string]RateLimit{
+				"POST /auth/login":           {10, time.Minute},
+				"POST /auth/login/mfa":       {10, time.Minute},
+				"POST /auth/password/forgot": {5, time.Minute},
+				"GET /healthz":               {},
+				"GET /readyz":                {},
+			},
+			Store: RateLimitStoreMemory,
+		},
+		Features: FeatureConfig{
+			RecommendInterval: time.Hour,
+		},
+	}
+}
+
+// ConfigError lists every problem found while loading a configuration, so
+// they can all be fixed in one go.
+type ConfigError struct {
+	Problems []string
+}
+
+func (e *ConfigError) Error() string {
+	return "error invalid config:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+// setting binds one value to its flag name, which is also its dotted key in
+// the file, and to its environment variable. Reloadable settings may change
+// while the process runs; see LiveConfig.
+type setting struct {
+	key        string
+	env        string
+	usage      string
+	value      flag.Value
+	reloadable bool
+}
+
+func (c *Config) settings() []setting {
+	return []setting{
+		{"server.port", "PORT", "port the HTTP server listens on", (*stringValue)(&c.Server.Port), false},
+		{"server.read_timeout", "SERVER_READ_TIMEOUT", "maximum time to read a request", (*durationValue)(&c.Server.ReadTimeout), false},
+		{"server.write_timeout", "SERVER_WRITE_TIMEOUT", "maximum time to write a response", (*durationValue)(&c.Server.WriteTimeout), false},
+		{"server.idle_timeout", "SERVER_IDLE_TIMEOUT", "how long idle keep-alive connections stay open", (*durationValue)(&c.Server.IdleTimeout), false},
+		{"server.shutdown_timeout", "SHUTDOWN_TIMEOUT", "how long shutdown waits for requests to finish", (*durationValue)(&c.Server.ShutdownTimeout), true},
+		{"server.drain_delay", "SERVER_DRAIN_DELAY", "how long /readyz reports draining before the server stops accepting requests", (*durationValue)(&c.Server.DrainDelay), true},
+		{"server.trusted_proxies", "SERVER_TRUSTED_PROXIES", "comma-separated CIDR ranges of proxies allowed to set X-Forwarded-For", (*listValue)(&c.Server.TrustedProxies), false},
+		{"db.driver", "DRIVER_NAME", "database/sql driver name", (*stringValue)(&c.DB.Driver), false},
+		{"db.url", "DATABASE_URL", "database connection URL", (*stringValue)(&c.DB.Url), false},
+		{"db.max_open_conns", "DB_MAX_OPEN_CONNS", "maximum open connections, 0 for unlimited", (*intValue)(&c.DB.MaxOpenConns), false},
+		{"db.max_idle_conns", "DB_MAX_IDLE_CONNS", "maximum idle connections", (*intValue)(&c.DB.MaxIdleConns), false},
+		{"db.conn_max_lifetime", "DB_CONN_MAX_LIFETIME", "maximum time a connection is reused, 0 for no limit", (*durationValue)(&c.DB.ConnMaxLifetime), false},
+		{"db.conn_max_idle_time", "DB_CONN_MAX_IDLE_TIME", "maximum time a connection stays idle, 0 for no limit", (*durationValue)(&c.DB.ConnMaxIdleTime), false},
+		{"db.connect_timeout", "DB_CONNECT_TIMEOUT", "how long startup keeps retrying the database", (*durationValue)(&c.DB.ConnectTimeout), false},
+		{"db.retry_initial", "DB_RETRY_INITIAL", "first delay between startup connection attempts", (*durationValue)(&c.DB.RetryInitial), false},
+		{"db.retry_max", "DB_RETRY_MAX", "longest delay between startup connection attempts", (*durationValue)(&c.DB.RetryMax), false},
+		{"db.health_interval", "DB_HEALTH_INTERVAL", "how often the database is pinged, 0 to disable", (*durationValue)(&c.DB.HealthInterval), false},
+		{"db.health_timeout", "DB_HEALTH_TIMEOUT", "how long one ping may take", (*durationValue)(&c.DB.HealthTimeout), false},
+		{"log.level", "LOG_LEVEL", "minimum log level: trace, debug, info, warn or error", (*stringValue)(&c.Log.Level), true},
+		{"log.request_level", "LOG_REQUEST_LEVEL", "minimum level of the request log, empty for log.level", (*stringValue)(&c.Log.RequestLevel), true},
+		{"log.format", "LOG_FORMAT", "log format: json, text or logfmt", (*stringValue)(&c.Log.Format), true},
+		{"log.sinks", "LOG_SINKS", "comma-separated log outputs: stdout, file, syslog or none", (*listValue)(&c.Log.Sinks), false},
+		{"log.file", "LOG_FILE", "application log file of the file sink", (*stringValue)(&c.Log.File), false},
+		{"log.request_file", "LOG_REQUEST_FILE", "request log file of the file sink", (*stringValue)(&c.Log.RequestFile), false},
+		{"log.security_file", "LOG_SECURITY_FILE", "security event log file of the file sink", (*stringValue)(&c.Log.SecurityFile), false},
+		{"log.max_size_mb", "LOG_MAX_SIZE_MB", "rotate a log file once it reaches this many megabytes, 0 for no limit", (*intValue)(&c.Log.MaxSizeMB), false},
+		{"log.rotate_every", "LOG_ROTATE_EVERY", "rotate log files this often, 0 to rotate by size only", (*durationValue)(&c.Log.RotateEvery), false},
+		{"log.max_age", "LOG_MAX_AGE", "delete rotated log files older than this, 0 to keep them", (*durationValue)(&c.Log.MaxAge), false},
+		{"log.max_backups", "LOG_MAX_BACKUPS", "rotated files kept per log, 0 to keep all", (*intValue)(&c.Log.MaxBackups), false},
+		{"log.compress", "LOG_COMPRESS", "gzip rotated log files", (*boolValue)(&c.Log.Compress), false},
+		{"log.syslog_network", "LOG_SYSLOG_NETWORK", "syslog network: tcp, udp, or empty for the local daemon", (*stringValue)(&c.Log.SyslogNetwork), false},
+		{"log.syslog_address", "LOG_SYSLOG_ADDRESS", "syslog server address, host:port", (*stringValue)(&c.Log.SyslogAddress), false},
+		{"auth.access_token", "ACCESS_TOKEN", "token every request must send in the Authorization header", (*stringValue)(&c.Auth.AccessToken), true},
+		{"auth.erasure_key", "AUTH_ERASURE_KEY", "secret keying the hash of an erased username on its receipt", (*stringValue)(&c.Auth.ErasureKey), false},
+		{"auth.app_url", "APP_URL", "base URL of links in account emails", (*stringValue)(&c.Auth.AppURL), false},
+		{"auth.verify_email_ttl", "AUTH_VERIFY_EMAIL_TTL", "how long an email verification link works", (*durationValue)(&c.Auth.VerifyEmailTTL), false},
+		{"auth.password_reset_ttl", "AUTH_PASSWORD_RESET_TTL", "how long a password reset link works", (*durationValue)(&c.Auth.PasswordResetTTL), false},
+		{"auth.lockout_threshold", "AUTH_LOCKOUT_THRESHOLD", "failed logins before an account is locked out", (*intValue)(&c.Auth.LockoutThreshold), false},
+		{"auth.lockout_ip_threshold", "AUTH_LOCKOUT_IP_THRESHOLD", "failed logins before an IP address is locked out", (*intValue)(&c.Auth.LockoutIPThreshold), false},
+		{"auth.lockout_base", "AUTH_LOCKOUT_BASE", "first lockout, doubled by each further failed login", (*durationValue)(&c.Auth.LockoutBase), false},
+		{"auth.lockout_max", "AUTH_LOCKOUT_MAX", "longest lockout", (*durationValue)(&c.Auth.LockoutMax), false},
+		{"auth.lockout_window", "AUTH_LOCKOUT_WINDOW", "how long failed logins are remembered", (*durationValue)(&c.Auth.LockoutWindow), false},
+		{"auth.mfa_issuer", "AUTH_MFA_ISSUER", "name authenticator apps show for the two-factor secret", (*stringValue)(&c.Auth.MFAIssuer), false},
+		{"auth.mfa_required_roles", "AUTH_MFA_REQUIRED_ROLES", "comma-separated roles that must use two-factor authentication", (*listValue)(&c.Auth.MFARequiredRoles), false},
+		{"auth.mfa_challenge_ttl", "AUTH_MFA_CHALLENGE_TTL", "time to enter the second factor after the password", (*durationValue)(&c.Auth.MFAChallengeTTL), false},
+		{"auth.mfa_recovery_codes", "AUTH_MFA_RECOVERY_CODES", "recovery codes given when two-factor authentication is turned on", (*intValue)(&c.Auth.MFARecoveryCodes), false},
+		{"auth.mfa_skew", "AUTH_MFA_SKEW", "periods a code may be early or late", (*intValue)(&c.Auth.MFASkew), false},
+		{"auth.bootstrap_admin", "AUTH_BOOTSTRAP_ADMIN", "username made admin at startup while there is no admin", (*stringValue)(&c.Auth.BootstrapAdmin), false},
+		{"password.min_length", "PASSWORD_MIN_LENGTH", "fewest characters a password may have", (*intValue)(&c.Password.MinLength), false},
+		{"password.max_length", "PASSWORD_MAX_LENGTH", "most characters a password may have", (*intValue)(&c.Password.MaxLength), false},
+		{"password.require_classes", "PASSWORD_REQUIRE_CLASSES", "how many of lowercase, uppercase, digits and symbols a password needs", (*intValue)(&c.Password.RequireClasses), false},
+		{"password.reject_similar", "PASSWORD_REJECT_SIMILAR", "reject passwords containing the username or email", (*boolValue)(&c.Password.RejectSimilar), false},
+		{"password.breached_dir", "PASSWORD_BREACHED_DIR", "directory of breached password hash prefix files, empty to skip the check", (*stringValue)(&c.Password.BreachedDir), false},
+		{"password.hash", "PASSWORD_HASH", "password hash: argon2id or bcrypt", (*stringValue)(&c.Password.Hash), false},
+		{"password.bcrypt_cost", "PASSWORD_BCRYPT_COST", "bcrypt cost", (*intValue)(&c.Password.BcryptCost), false},
+		{"password.argon2_time", "PASSWORD_ARGON2_TIME", "argon2id passes over memory", (*intValue)(&c.Password.Argon2Time), false},
+		{"password.argon2_memory_kib", "PASSWORD_ARGON2_MEMORY_KIB", "argon2id memory in KiB", (*intValue)(&c.Password.Argon2Memory), false},
+		{"password.argon2_threads", "PASSWORD_ARGON2_THREADS", "argon2id parallelism", (*intValue)(&c.Password.Argon2Threads), false},
+		{"storage.driver", "STORAGE_DRIVER", "blob store: fs or s3", (*stringValue)(&c.Storage.Driver), false},
+		{"storage.dir", "STORAGE_DIR", "directory of the fs blob store", (*stringValue)(&c.Storage.Dir), false},
+		{"storage.s3_endpoint", "S3_ENDPOINT", "S3 endpoint URL", (*stringValue)(&c.Storage.S3Endpoint), false},
+		{"storage.s3_region", "S3_REGION", "S3 region", (*stringValue)(&c.Storage.S3Region), false},
+		{"storage.s3_bucket", "S3_BUCKET", "S3 bucket", (*stringValue)(&c.Storage.S3Bucket), false},
+		{"storage.s3_access_key", "S3_ACCESS_KEY", "S3 access key", (*stringValue)(&c.Storage.S3AccessKey), false},
+		{"storage.s3_secret_key", "S3_SECRET_KEY", "S3 secret key", (*stringValue)(&c.Storage.S3SecretKey), false},
+		{"mail.driver", "MAIL_DRIVER", "how emails are sent: log, file or smtp", (*stringValue)(&c.Mail.Driver), false},
+		{"mail.from", "MAIL_FROM", "sender address of emails", (*stringValue)(&c.Mail.From), false},
+		{"mail.dir", "MAIL_DIR", "directory the file driver writes .eml files to", (*stringValue)(&c.Mail.Dir), false},
+		{"mail.smtp_host", "SMTP_HOST", "SMTP server host", (*stringValue)(&c.Mail.SMTPHost), false},
+		{"mail.smtp_port", "SMTP_PORT", "SMTP server port", (*intValue)(&c.Mail.SMTPPort), false},
+		{"mail.smtp_username", "SMTP_USERNAME", "SMTP user, empty to send without authenticating", (*stringValue)(&c.Mail.SMTPUsername), false},
+		{"mail.smtp_password", "SMTP_PASSWORD", "SMTP password", (*stringValue)(&c.Mail.SMTPPassword), false},
+		{"mail.smtp_timeout", "SMTP_TIMEOUT", "how long sending one email may take", (*durationValue)(&c.Mail.SMTPTimeout), false},
+		{"tracing.exporter", "TRACING_EXPORTER", "span exporter: none, stdout or otlp", (*stringValue)(&c.Tracing.Exporter), false},
+		{"tracing.endpoint", "OTEL_EXPORTER_OTLP_ENDPOINT", "OTLP/HTTP collector URL, spans are sent to <endpoint>/v1/traces", (*stringValue)(&c.Tracing.Endpoint), false},
+		{"tracing.service_name", "OTEL_SERVICE_NAME", "service.name reported with every span", (*stringValue)(&c.Tracing.ServiceName), false},
+		{"tracing.sample_ratio", "TRACING_SAMPLE_RATIO", "share of new traces recorded, from 0 to 1", (*floatValue)(&c.Tracing.SampleRatio), false},
+		{"rate_limit.enabled", "RATE_LIMIT_ENABLED", "limit how often each client may call the API", (*boolValue)(&c.RateLimit.Enabled), true},
+		{"rate_limit.default", "RATE_LIMIT_DEFAULT", "limit of routes without their own, like 600/1m or off", (*rateLimitValue)(&c.RateLimit.Default), true},
+		{"rate_limit.routes", "RATE_LIMIT_ROUTES", "comma-separated per-route limits, like GET /books=120/1m", (*routeLimitsValue)(&c.RateLimit.Routes), true},
+		{"rate_limit.store", "RATE_LIMIT_STORE", "where buckets are kept: memory, or redis to share them between replicas", (*stringValue)(&c.RateLimit.Store), false},
+		{"rate_limit.redis_addr", "REDIS_ADDR", "Redis host:port of the redis store", (*stringValue)(&c.RateLimit.RedisAddr), false},
+		{"rate_limit.redis_password", "REDIS_PASSWORD", "Redis password", (*stringValue)(&c.RateLimit.RedisPassword), false},
+		{"rate_limit.redis_db", "REDIS_DB", "Redis database number", (*intValue)(&c.RateLimit.RedisDB), false},
+		{"features.review_blocked_words", "REVIEW_BLOCKED_WORDS", "comma-separated words that reject a review", (*listValue)(&c.Features.ReviewBlockedWords), false},
+		{"features.recommend_interval", "RECOMMEND_INTERVAL", "how often recommendations are recomputed, 0 to disable", (*durationValue)(&c.Features.RecommendInterval), false},
 	}
+}
+
+type override struct {
+	key   string
+	value string
+}
 
+// LoadConfig builds the configuration from args, the environment and the
+// config file. Empty environment variables count as unset. The returned error is a *ConfigError when the values were
+// read but are invalid; the config is still returned so it can be printed.
+func LoadConfig(args []string, stderr io.Writer) (Config, error) {
+	fs := flag.NewFlagSet("bookstore", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	file := fs.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML config file (env CONFIG_FILE)")
+
+	overrides := []override{}
+	defaults := DefaultConfig()
+	for _, s := range defaults.settings() {
+		key := s.key
+		fs.Func(key, fmt.Sprintf("%v (env %v)", s.usage, s.env), func(v string) error {
+			overrides = append(overrides, override{key, v})
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+	if fs.NArg() > 0 {
+		return Config{}, fmt.Errorf("error unexpected argument %q", fs.Arg(0))
+	}
+
+	config := DefaultConfig()
+	if *file != "" {
+		if err := config.readFile(*file); err != nil {
+			return Config{}, err
+		}
+	}
+
+	problems := []string{}
+	settings := map[string]setting{}
+	for _, s := range config.settings() {
+		settings[s.key] = s
+		if v := os.Getenv(s.env); v != "" {
+			if err := s.value.Set(v); err != nil {
+				problems = append(problems, fmt.Sprintf("%v: %v", s.env, err))
+			}
+		}
+	}
+	for _, o := range overrides {
+		if err := settings[o.key].value.Set(o.value); err != nil {
+			problems = append(problems, fmt.Sprintf("-%v: %v", o.key, err))
+		}
+	}
+
+	problems = append(problems, config.problems()...)
+	if len(problems) > 0 {
+		return config, &ConfigError{problems}
+	}
+	return config, nil
+}
+
+// readFile overlays the keys present in a YAML file. Unknown keys are an
+// error, so a typo does not silently leave a default in place.
+func (c *Config) readFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error read config file : %w", err)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("error parse config file %v : %w", path, err)
+	}
+	return nil
+}
+
+// Validate reports every invalid value at once.
+func (c Config) Validate() error {
+	if problems := c.problems(); len(problems) > 0 {
+		return &ConfigError{problems}
+	}
+	return nil
 }
+
+func (c Config) problems() []string {
+	problems := []string{}
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if port, err := strconv.Atoi(c.Server.Port); err != nil || port < 1 || port > 65535 {
+		add("server.port: must be a port number between 1 and 65535, got %q", c.Server.Port)
+	}
+	for _, proxy := range c.Server.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil {
+			add("server.trusted_proxies: must be CIDR ranges, got %q", proxy)
+		}
+	}
+	durations := []struct {
+		key   string
+		value time.Duration
+	}{
+		{"server.read_timeout", c.Server.ReadTimeout},
+		{"server.write_timeout", c.Server.WriteTimeout},
+		{"server.idle_timeout", c.Server.IdleTimeout},
+		{"server.shutdown_timeout", c.Server.ShutdownTimeout},
+		{"server.drain_delay", c.Server.DrainDelay},
+		{"db.conn_max_lifetime", c.DB.ConnMaxLifetime},
+		{"db.conn_max_idle_time", c.DB.ConnMaxIdleTime},
+		{"db.connect_timeout", c.DB.ConnectTimeout},
+		{"db.health_interval", c.DB.HealthInterval},
+		{"log.rotate_every", c.Log.RotateEvery},
+		{"log.max_age", c.Log.MaxAge},
+		{"features.recommend_interval", c.Features.RecommendInterval},
+	}
+	for _, d := range durations {
+		if d.value < 0 {
+			add("%v: must not be negative", d.key)
+		}
+	}
+
+	if c.DB.Driver == "" {
+		add("db.driver: is required")
+	}
+	if c.DB.Url == "" {
+		add("db.url: is required")
+	}
+	if c.DB.MaxOpenConns < 0 {
+		add("db.max_open_conns: must not be negative")
+	}
+	if c.DB.MaxIdleConns < 0 {
+		add("db.max_idle_conns: must not be negative")
+	}
+	if c.DB.MaxOpenConns > 0 && c.DB.MaxIdleConns > c.DB.MaxOpenConns {
+		add("db.max_idle_conns: must not exceed db.max_open_conns")
+	}
+	if c.DB.RetryInitial <= 0 {
+		add("db.retry_initial: must be positive")
+	}
+	if c.DB.RetryMax < c.DB.RetryInitial {
+		add("db.retry_max: must not be less than db.retry_initial")
+	}
+	if c.DB.HealthTimeout <= 0 {
+		add("db.health_timeout: must be positive")
+	}
+
+	if _, err := log.ParseLevel(c.Log.Level); err != nil {
+		add("log.level: unknown level %q", c.Log.Level)
+	}
+	if _, err := log.ParseLevel(c.Log.RequestLevel); c.Log.RequestLevel != "" && err != nil {
+		add("log.request_level: unknown level %q", c.Log.RequestLevel)
+	}
+	switch c.Log.Format {
+	case LogFormatJSON, LogFormatText, LogFormatLogfmt:
+	default:
+		add("log.format: must be %v, %v or %v, got %q", LogFormatJSON, LogFormatText, LogFormatLogfmt, c.Log.Format)
+	}
+	if len(c.Log.Sinks) == 0 {
+		add("log.sinks: is required, use %v to disable logging", LogSinkNone)
+	}
+	for _, sink := range c.Log.Sinks {
+		switch sink {
+		case LogSinkStdout, LogSinkSyslog:
+		case LogSinkFile:
+			if c.Log.File == "" || c.Log.RequestFile == "" || c.Log.SecurityFile == "" {
+				add("log.file, log.request_file, log.security_file: are required for the file sink")
+			}
+		case LogSinkNone:
+			if len(c.Log.Sinks) > 1 {
+				add("log.sinks: %v cannot be combined with other sinks", LogSinkNone)
+			}
+		default:
+			add("log.sinks: must be stdout, file, syslog or none, got %q", sink)
+		}
+	}
+	if c.Log.MaxSizeMB < 0 {
+		add("log.max_size_mb: must not be negative")
+	}
+	if c.Log.MaxBackups < 0 {
+		add("log.max_backups: must not be negative")
+	}
+	switch c.Log.SyslogNetwork {
+	case "", "tcp", "udp", "unix", "unixgram":
+		if c.Log.SyslogNetwork != "" && c.Log.SyslogAddress == "" {
+			add("log.syslog_address: is required with log.syslog_network")
+		}
+	default:
+		add("log.syslog_network: must be tcp, udp, unix or unixgram, got %q", c.Log.SyslogNetwork)
+	}
+
+	if strings.TrimSpace(c.Auth.AccessToken) == "" {
+		add("auth.access_token: is required")
+	}
+	if strings.TrimSpace(c.Auth.ErasureKey) == "" {
+		add("auth.erasure_key: is required")
+	}
+	if u, err := url.Parse(c.Auth.AppURL); err != nil || u.Scheme == "" || u.Host == "" {
+		add("auth.app_url: must be an http(s) URL, got %q", c.Auth.AppURL)
+	}
+	if c.Auth.VerifyEmailTTL <= 0 {
+		add("auth.verify_email_ttl: must be positive")
+	}
+	if c.Auth.PasswordResetTTL <= 0 {
+		add("auth.password_reset_ttl: must be positive")
+	}
+
+	if c.Auth.LockoutThreshold < 1 {
+		add("auth.lockout_threshold: must be positive")
+	}
+	if c.Auth.LockoutIPThreshold < 1 {
+		add("auth.lockout_ip_threshold: must be positive")
+	}
+	if c.Auth.LockoutBase <= 0 || c.Auth.LockoutMax < c.Auth.LockoutBase {
+		add("auth.lockout_base, auth.lockout_max: must be positive, with lockout_max at least lockout_base")
+	}
+	if c.Auth.LockoutWindow < c.Auth.LockoutMax {
+		add("auth.lockout_window: must be at least auth.lockout_max")
+	}
+
+	if strings.TrimSpace(c.Auth.MFAIssuer) == "" || strings.Contains(c.Auth.MFAIssuer, ":") {
+		add("auth.mfa_issuer: is required and must not contain a colon")
+	}
+	for _, role := range c.Auth.MFARequiredRoles {
+		switch role {
+		case "customer", "staff", "admin":
+		default:
+			add("auth.mfa_required_roles: must be customer, staff or admin, got %q", role)
+		}
+	}
+	if c.Auth.MFAChallengeTTL <= 0 {
+		add("auth.mfa_challenge_ttl: must be positive")
+	}
+	if c.Auth.MFARecoveryCodes < 1 || c.Auth.MFARecoveryCodes > 100 {
+		add("auth.mfa_recovery_codes: must be between 1 and 100, got %d", c.Auth.MFARecoveryCodes)
+	}
+	if c.Auth.MFASkew < 0 || c.Auth.MFASkew > 10 {
+		add("auth.mfa_skew: must be between 0 and 10, got %d", c.Auth.MFASkew)
+	}
+
+	if c.Password.MinLength < 1 {
+		add("password.min_length: must be positive")
+	}
+	if c.Password.MaxLength < c.Password.MinLength {
+		add("password.max_length: must be at least password.min_length")
+	}
+	if c.Password.RequireClasses < 0 || c.Password.RequireClasses > 4 {
+		add("password.require_classes: must be between 0 and 4, got %d", c.Password.RequireClasses)
+	}
+	switch c.Password.Hash {
+	case PasswordHashBcrypt:
+		// bcrypt ignores everything after the 72nd byte.
+		if c.Password.MaxLength > 72 {
+			add("password.max_length: must be at most 72 with bcrypt")
+		}
+		if c.Password.BcryptCost < 10 || c.Password.BcryptCost > 31 {
+			add("password.bcrypt_cost: must be between 10 and 31, got %d", c.Password.BcryptCost)
+		}
+	case PasswordHashArgon2id:
+		if c.Password.Argon2Time < 1 {
+			add("password.argon2_time: must be positive")
+		}
+		if c.Password.Argon2Memory < 8*c.Password.Argon2Threads {
+			add("password.argon2_memory_kib: must be at least 8 per thread")
+		}
+		if c.Password.Argon2Threads < 1 || c.Password.Argon2Threads > 255 {
+			add("password.argon2_threads: must be between 1 and 255, got %d", c.Password.Argon2Threads)
+		}
+	default:
+		add("password.hash: must be argon2id or bcrypt, got %q", c.Password.Hash)
+	}
+
+	if _, err := mail.ParseAddress(c.Mail.From); err != nil {
+		add("mail.from: must be an email address, got %q", c.Mail.From)
+	}
+	switch c.Mail.Driver {
+	case MailDriverLog:
+	case MailDriverFile:
+		if c.Mail.Dir == "" {
+			add("mail.dir: is required for the file driver")
+		}
+	case MailDriverSMTP:
+		if c.Mail.SMTPHost == "" {
+			add("mail.smtp_host: is required for the smtp driver")
+		}
+		if c.Mail.SMTPPort < 1 || c.Mail.SMTPPort > 65535 {
+			add("mail.smtp_port: must be a port number between 1 and 65535, got %d", c.Mail.SMTPPort)
+		}
+	default:
+		add("mail.driver: must be log, file or smtp, got %q", c.Mail.Driver)
+	}
+
+	switch c.Tracing.Exporter {
+	case TracingExporterNone, TracingExporterStdout:
+	case TracingExporterOTLP:
+		if u, err := url.Parse(c.Tracing.Endpoint); err != nil || u.Scheme == "" || u.Host == "" {
+			add("tracing.endpoint: must be an http(s) URL, got %q", c.Tracing.Endpoint)
+		}
+	default:
+		add("tracing.exporter: must be none, stdout or otlp, got %q", c.Tracing.Exporter)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		add("tracing.sample_ratio: must be between 0 and 1, got %v", c.Tracing.SampleRatio)
+	}
+
+	switch c.RateLimit.Store {
+	case RateLimitStoreMemory:
+	case RateLimitStoreRedis:
+		if c.RateLimit.RedisAddr == "" {
+			add("rate_limit.redis_addr: is required for the redis store")
+		}
+	default:
+		add("rate_limit.store: must be memory or redis, got %q", c.RateLimit.Store)
+	}
+	for _, route := range sortedKeys(c.RateLimit.Routes) {
+		if method, path, ok := strings.Cut(route, " "); !ok || method != strings.ToUpper(method) || !strings.HasPrefix(path, "/") {
+			add("rate_limit.routes: route must be like \"GET /books/:id\", got %q", route)
+		}
+	}
+	if c.RateLimit.RedisDB < 0 {
+		add("rate_limit.redis_db: must not be negative")
+	}
+
+	switch c.Storage.Driver {
+	case "", "fs":
+	case "s3":
+		if c.Storage.S3Endpoint == "" {
+			add("storage.s3_endpoint: is required for the s3 driver")
+		}
+		if c.Storage.S3Bucket == "" {
+			add("storage.s3_bucket: is required for the s3 driver")
+		}
+	default:
+		add("storage.driver: must be fs or s3, got %q", c.Storage.Driver)
+	}
+	return problems
+}
+
+// Redacted returns a copy that is safe to print or log.
+func (c Config) Redacted() Config {
+	c.DB.Url = redactURL(c.DB.Url)
+	c.Auth.AccessToken = redact(c.Auth.AccessToken)
+	c.Auth.ErasureKey = redact(c.Auth.ErasureKey)
+	c.Storage.S3AccessKey = redact(c.Storage.S3AccessKey)
+	c.Storage.S3SecretKey = redact(c.Storage.S3SecretKey)
+	c.Mail.SMTPPassword = redact(c.Mail.SMTPPassword)
+	c.RateLimit.RedisPassword = redact(c.RateLimit.RedisPassword)
+	return c
+}
+
+// String prints the redacted config, so formatting a Config with %v never
+// leaks secrets.
+func (c Config) String() string {
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(c.Redacted()); err != nil {
+		return fmt.Sprintf("error marshal config : %v", err)
+	}
+	return out.String()
+}
+
+func redact(s string) string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+// redactURL hides only the password, so the host and database stay visible.
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return redact(s)
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), redacted)
+	}
+	return u.String()
+}
+
+// ConfigCommand runs the "config" subcommand. "config print [flags]" writes
+// the effective, redacted configuration and fails if it is invalid.
+func ConfigCommand(args []string, stdout io.Writer, stderr io.Writer) error {
+	if len(args) == 0 || args[0] != "print" {
+		return errors.New("usage: bookstore config print [flags]")
+	}
+	config, err := LoadConfig(args[1:], stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	var cfgErr *ConfigError
+	if err != nil && !errors.As(err, &cfgErr) {
+		return err
+	}
+	fmt.Fprint(stdout, config)
+	return err
+}
+
+type stringValue string
+
+func (v *stringValue) Set(s string) error {
+	*v = stringValue(s)
+	return nil
+}
+
+func (v *stringValue) String() string { return string(*v) }
+
+type intValue int
+
+func (v *intValue) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("invalid number %q", s)
+	}
+	*v = intValue(n)
+	return nil
+}
+
+func (v *intValue) String() string { return strconv.Itoa(int(*v)) }
+
+type floatValue float64
+
+func (v *floatValue) Set(s string) error {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("invalid number %q", s)
+	}
+	*v = floatValue(f)
+	return nil
+}
+
+func (v *floatValue) String() string { return strconv.FormatFloat(float64(*v), 'g', -1, 64) }
+
+type durationValue time.Duration
+
+func (v *durationValue) Set(s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q", s)
+	}
+	*v = durationValue(d)
+	return nil
+}
+
+func (v *durationValue) String() string { return time.Duration(*v).String() }
+
+type rateLimitValue RateLimit
+
+func (v *rateLimitValue) Set(s string) error {
+	l, err := ParseRateLimit(s)
+	if err != nil {
+		return err
+	}
+	*v = rateLimitValue(l)
+	return nil
+}
+
+func (v *rateLimitValue) String() string { return RateLimit(*v).String() }
+
+// routeLimitsValue is a comma-separated list of route=limit pairs.
+type routeLimitsValue map[string]RateLimit
+
+func (v *routeLimitsValue) Set(s string) error {
+	routes := map[string]RateLimit{}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		route, limit, ok := strings.Cut(item, "=")
+		if !ok {
+			return fmt.Errorf("invalid route limit %q, want route=limit", item)
+		}
+		l, err := ParseRateLimit(limit)
+		if err != nil {
+			return err
+		}
+		routes[strings.TrimSpace(route)] = l
+	}
+	*v = routes
+	return nil
+}
+
+func (v *routeLimitsValue) String() string {
+	pairs := []string{}
+	for _, route := range sortedKeys(*v) {
+		pairs = append(pairs, route+"="+(*v)[route].String())
+	}
+	return strings.Join(pairs, ",")
+}
+
+func sortedKeys(m map[string]RateLimit) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type boolValue bool
+
+func (v *boolValue) Set(s string) error {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return fmt.Errorf("invalid boolean %q", s)
+	}
+	*v = boolValue(b)
+	return nil
+}
+
+func (v *boolValue) String() string { return strconv.FormatBool(bool(*v)) }
+
+// listValue is a comma-separated list; blank entries are dropped.
+type listValue []string
+
+func (v *listValue) Set(s string) error {
+	list := []string{}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	*v = list
+	return nil
+}
+
+func (v *listValue) String() string { return strings.Join(*v, ",") }
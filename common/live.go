@@ -0,0 +1,110 @@
+package common
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// Change is one setting that differs after a reload, with redacted values.
+// Applied is false for settings that only take effect after a restart.
+type Change struct {
+	Key     string
+	Old     string
+	New     string
+	Applied bool
+}
+
+func (c Change) String() string {
+	if !c.Applied {
+		return fmt.Sprintf("%v: %q -> %q (needs restart, ignored)", c.Key, c.Old, c.New)
+	}
+	return fmt.Sprintf("%v: %q -> %q", c.Key, c.Old, c.New)
+}
+
+// LiveConfig holds the running configuration. Code that should follow a
+// reload calls Get on every use instead of keeping a copy.
+type LiveConfig struct {
+	current atomic.Pointer[Config]
+	args    []string
+
+	mu        sync.Mutex
+	listeners []func(Config)
+}
+
+// NewLiveConfig starts from c; args are the command-line flags c was loaded
+// with, so a reload keeps honouring them.
+func NewLiveConfig(c Config, args []string) *LiveConfig {
+	l := &LiveConfig{args: args}
+	l.current.Store(&c)
+	return l
+}
+
+func (l *LiveConfig) Get() Config {
+	return *l.current.Load()
+}
+
+// OnChange registers fn to run with the new config after a reload that
+// applied at least one change.
+func (l *LiveConfig) OnChange(fn func(Config)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.listeners = append(l.listeners, fn)
+}
+
+// Reload loads the configuration again. An invalid configuration is rejected
+// as a whole and the running one is kept. Otherwise the reloadable settings
+// are swapped in at once and every difference is returned.
+func (l *LiveConfig) Reload() ([]Change, error) {
+	loaded, err := LoadConfig(l.args, io.Discard)
+	if err != nil {
+		return nil, err
+	}
+	return l.apply(loaded), nil
+}
+
+func (l *LiveConfig) apply(loaded Config) []Change {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	next := l.Get()
+	changes := diffConfig(next, loaded)
+
+	applied := false
+	nextSettings, loadedSettings := next.settings(), loaded.settings()
+	for i, s := range nextSettings {
+		if s.reloadable && s.value.String() != loadedSettings[i].value.String() {
+			_ = s.value.Set(loadedSettings[i].value.String())
+			applied = true
+		}
+	}
+	if !applied {
+		return changes
+	}
+
+	l.current.Store(&next)
+	for _, fn := range l.listeners {
+		fn(next)
+	}
+	return changes
+}
+
+func diffConfig(old Config, new Config) []Change {
+	oldSettings, newSettings := old.settings(), new.settings()
+	redOld, redNew := old.Redacted(), new.Redacted()
+	oldShown, newShown := redOld.settings(), redNew.settings()
+
+	changes := []Change{}
+	for i, s := range oldSettings {
+		if s.value.String() != newSettings[i].value.String() {
+			changes = append(changes, Change{
+				Key:     s.key,
+				Old:     oldShown[i].value.String(),
+				New:     newShown[i].value.String(),
+				Applied: s.reloadable,
+			})
+		}
+	}
+	return changes
+}
@@ -1,46 +1,193 @@
 package common
 
 import (
+	"context"
+	"fmt"
 	"io"
 	"os"
 
 	log "github.com/sirupsen/logrus"
 )
 
+// Fields are the structured fields of a log entry.
+type Fields = log.Fields
+
+// Log is satisfied by both *logrus.Logger and *logrus.Entry, so a logger
+// with fields attached can stand in for the plain one.
 type Log interface {
+	Debugf(format string, args ...interface{})
 	Info(args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
 	Errorf(format string, args ...interface{})
 	Fatalf(format string, args ...interface{})
+	WithField(key string, value interface{}) *log.Entry
+	WithFields(fields Fields) *log.Entry
+	WithError(err error) *log.Entry
+	WithContext(ctx context.Context) *log.Entry
 }
 
-func InitLog() *log.Logger {
-	file, err := os.OpenFile("logs.text", os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
-	if err != nil {
-		log.Error("Error Create Log File Failed")
+// RequestIDHeader carries the id that ties together everything logged for
+// one request.
+const RequestIDHeader = "X-Request-ID"
+
+type logFieldsKey struct{}
+
+// WithLogFields returns a context whose LogFrom loggers also carry fields,
+// e.g. the request id and route set by the server middleware.
+func WithLogFields(ctx context.Context, fields Fields) context.Context {
+	merged := Fields{}
+	for k, v := range LogFields(ctx) {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
 	}
+	return context.WithValue(ctx, logFieldsKey{}, merged)
+}
 
-	logs := &log.Logger{
-		Out:       io.MultiWriter(file, os.Stdout),
-		Formatter: new(log.JSONFormatter),
-		Hooks:     make(log.LevelHooks),
-		Level:     log.InfoLevel,
-		ExitFunc:  os.Exit,
+// LogFields returns the fields added to ctx by WithLogFields.
+func LogFields(ctx context.Context) Fields {
+	fields, _ := ctx.Value(logFieldsKey{}).(Fields)
+	return fields
+}
+
+// RequestID returns the id of the request ctx belongs to, if any.
+func RequestID(ctx context.Context) string {
+	id, _ := LogFields(ctx)["request_id"].(string)
+	return id
+}
+
+// LogFrom returns l with the fields of ctx attached, for logging on behalf
+// of the request ctx belongs to.
+func LogFrom(ctx context.Context, l Log) Log {
+	entry := l.WithContext(ctx)
+	if fields := LogFields(ctx); len(fields) > 0 {
+		entry = entry.WithFields(fields)
 	}
-	return logs
+	return entry
 }
 
-func InitRequestLog() *log.Logger {
-	file, err := os.OpenFile("request-logs.text", os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+// logSettings returns the level and formatter for a logger at level; values
+// were checked by Validate, so parse errors fall back to the defaults.
+func logSettings(level string, format string) (log.Level, log.Formatter) {
+	lvl, err := log.ParseLevel(level)
 	if err != nil {
-		log.Error("Error Create Request Log File Failed")
+		lvl = log.InfoLevel
+	}
+	switch format {
+	case LogFormatText:
+		return lvl, &log.TextFormatter{FullTimestamp: true}
+	case LogFormatLogfmt:
+		return lvl, &log.TextFormatter{FullTimestamp: true, DisableColors: true, QuoteEmptyFields: true}
 	}
+	return lvl, new(log.JSONFormatter)
+}
+
+// requestLevel is the request logger's level, log.level unless set.
+func (c LogConfig) requestLevel() string {
+	if c.RequestLevel == "" {
+		return c.Level
+	}
+	return c.RequestLevel
+}
+
+// ApplyLogConfig changes the level and format of the running application
+// logger.
+func ApplyLogConfig(logs *log.Logger, c LogConfig) {
+	level, formatter := logSettings(c.Level, c.Format)
+	logs.SetLevel(level)
+	logs.SetFormatter(formatter)
+}
 
+// ApplyRequestLogConfig is ApplyLogConfig for the request logger.
+func ApplyRequestLogConfig(logs *log.Logger, c LogConfig) {
+	level, formatter := logSettings(c.requestLevel(), c.Format)
+	logs.SetLevel(level)
+	logs.SetFormatter(formatter)
+}
+
+// ApplySecurityLogConfig changes the format of the running security logger.
+func ApplySecurityLogConfig(logs *log.Logger, c LogConfig) {
+	_, formatter := logSettings(log.InfoLevel.String(), c.Format)
+	logs.SetFormatter(formatter)
+}
+
+// InitLog builds the application logger writing to the sinks of c. The
+// returned closer releases its files and syslog connection.
+func InitLog(c LogConfig) (*log.Logger, io.Closer, error) {
+	return newLogger(c, c.Level, c.File, "bookstore")
+}
+
+// InitRequestLog builds the request logger, which has its own level and file.
+func InitRequestLog(c LogConfig) (*log.Logger, io.Closer, error) {
+	return newLogger(c, c.requestLevel(), c.RequestFile, "bookstore-requests")
+}
+
+// InitSecurityLog builds the logger of security events, such as failed
+// logins and lockouts. It always logs at info level, so events are kept
+// however quiet the application log is.
+func InitSecurityLog(c LogConfig) (*log.Logger, io.Closer, error) {
+	return newLogger(c, log.InfoLevel.String(), c.SecurityFile, "bookstore-security")
+}
+
+func newLogger(c LogConfig, level string, file string, tag string) (*log.Logger, io.Closer, error) {
+	writers := []io.Writer{}
+	closers := multiCloser{}
+	hooks := make(log.LevelHooks)
+	for _, sink := range c.Sinks {
+		switch sink {
+		case LogSinkStdout:
+			writers = append(writers, os.Stdout)
+		case LogSinkFile:
+			f, err := OpenRotatingFile(file, c)
+			if err != nil {
+				closers.Close()
+				return nil, nil, fmt.Errorf("error open log file %v : %w", file, err)
+			}
+			writers = append(writers, f)
+			closers = append(closers, f)
+		case LogSinkSyslog:
+			hook, closer, err := syslogHook(c, tag)
+			if err != nil {
+				closers.Close()
+				return nil, nil, fmt.Errorf("error connect syslog : %w", err)
+			}
+			hooks.Add(hook)
+			closers = append(closers, closer)
+		}
+	}
+
+	var out io.Writer
+	switch len(writers) {
+	case 0:
+		out = io.Discard
+	case 1:
+		out = writers[0]
+	default:
+		out = io.MultiWriter(writers...)
+	}
+
+	lvl, formatter := logSettings(level, c.Format)
 	logs := &log.Logger{
-		Out:       io.MultiWriter(file, os.Stdout),
-		Formatter: new(log.JSONFormatter),
-		Hooks:     make(log.LevelHooks),
-		Level:     log.InfoLevel,
+		Out:       out,
+		Formatter: formatter,
+		Hooks:     hooks,
+		Level:     lvl,
 		ExitFunc:  os.Exit,
 	}
-	return logs
+	return logs, closers, nil
+}
+
+// multiCloser closes every sink, reporting the first error.
+type multiCloser []io.Closer
+
+func (m multiCloser) Close() error {
+	var first error
+	for _, c := range m {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
 }
@@ -0,0 +1,21 @@
+//go:build !windows && !plan9
+
+package common
+
+import (
+	"io"
+	"log/syslog"
+
+	log "github.com/sirupsen/logrus"
+	logsyslog "github.com/sirupsen/logrus/hooks/syslog"
+)
+
+// syslogHook sends entries to the syslog server of c, or the local daemon,
+// at the priority matching their level.
+func syslogHook(c LogConfig, tag string) (log.Hook, io.Closer, error) {
+	hook, err := logsyslog.NewSyslogHook(c.SyslogNetwork, c.SyslogAddress, syslog.LOG_INFO|syslog.LOG_DAEMON, tag)
+	if err != nil {
+		return nil, nil, err
+	}
+	return hook, hook.Writer, nil
+}
@@ -0,0 +1,14 @@
+//go:build windows || plan9
+
+package common
+
+import (
+	"errors"
+	"io"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func syslogHook(c LogConfig, tag string) (log.Hook, io.Closer, error) {
+	return nil, nil, errors.New("syslog is not supported on this platform")
+}
@@ -0,0 +1,160 @@
+//go:build unit
+
+package common
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogFrom(t *testing.T) {
+	t.Run("TestLogFromShouldAttachContextFields", func(t *testing.T) {
+		// Arrange
+		log, hook := test.NewNullLogger()
+		ctx := WithLogFields(context.Background(), Fields{"request_id": "req-1", "route": "/books"})
+		ctx = WithLogFields(ctx, Fields{"user": "alice"})
+
+		// Act
+		LogFrom(ctx, log).WithField("book_id", 7).Warnf("Stock low : %d", 2)
+
+		// Assert
+		entry := hook.LastEntry()
+		assert.Equal(t, logrus.WarnLevel, entry.Level)
+		assert.Equal(t, "Stock low : 2", entry.Message)
+		assert.Equal(t, logrus.Fields{"request_id": "req-1", "route": "/books", "user": "alice", "book_id": 7}, entry.Data)
+		assert.Equal(t, "req-1", RequestID(ctx))
+	})
+
+	t.Run("TestLogFromShouldNotChangeParentContext", func(t *testing.T) {
+		// Arrange
+		parent := WithLogFields(context.Background(), Fields{"request_id": "req-1"})
+
+		// Act
+		_ = WithLogFields(parent, Fields{"request_id": "req-2"})
+
+		// Assert
+		assert.Equal(t, "req-1", RequestID(parent))
+	})
+
+	t.Run("TestLogFromShouldLogPlainWithoutFields", func(t *testing.T) {
+		// Arrange
+		log, hook := test.NewNullLogger()
+
+		// Act
+		LogFrom(context.Background(), log).Info("startup")
+
+		// Assert
+		assert.Empty(t, hook.LastEntry().Data)
+		assert.Empty(t, RequestID(context.Background()))
+	})
+}
+
+func TestInitLog(t *testing.T) {
+	t.Run("TestInitLogShouldWriteLogfmtToFileSink", func(t *testing.T) {
+		// Arrange
+		dir := t.TempDir()
+		c := DefaultConfig().Log
+		c.Sinks = []string{LogSinkFile}
+		c.Format = LogFormatLogfmt
+		c.File = filepath.Join(dir, "app.log")
+		c.RequestFile = filepath.Join(dir, "requests.log")
+		c.RequestLevel = "warn"
+
+		logs, closer, err := InitLog(c)
+		assert.NoError(t, err)
+		reqLogs, reqCloser, err := InitRequestLog(c)
+		assert.NoError(t, err)
+
+		// Act
+		logs.WithField("book_id", 7).Info("Success Book Added")
+		reqLogs.Info("request")
+		reqLogs.Warn("request error")
+		assert.NoError(t, closer.Close())
+		assert.NoError(t, reqCloser.Close())
+
+		// Assert
+		app, err := os.ReadFile(c.File)
+		assert.NoError(t, err)
+		assert.Contains(t, string(app), `level=info msg="Success Book Added" book_id=7`)
+		requests, err := os.ReadFile(c.RequestFile)
+		assert.NoError(t, err)
+		assert.NotContains(t, string(requests), `msg=request `)
+		assert.Contains(t, string(requests), `msg="request error"`)
+	})
+
+	t.Run("TestInitSecurityLogShouldLogInfoWhateverTheLevel", func(t *testing.T) {
+		// Arrange
+		dir := t.TempDir()
+		c := DefaultConfig().Log
+		c.Sinks = []string{LogSinkFile}
+		c.Format = LogFormatLogfmt
+		c.Level = "error"
+		c.SecurityFile = filepath.Join(dir, "security.log")
+
+		logs, closer, err := InitSecurityLog(c)
+		assert.NoError(t, err)
+
+		// Act
+		logs.WithField("event", "login_failed").Info("Security Event")
+		assert.NoError(t, closer.Close())
+
+		// Assert
+		events, err := os.ReadFile(c.SecurityFile)
+		assert.NoError(t, err)
+		assert.Contains(t, string(events), `level=info msg="Security Event" event=login_failed`)
+	})
+
+	t.Run("TestInitLogShouldDiscardWithNoneSink", func(t *testing.T) {
+		// Arrange
+		c := DefaultConfig().Log
+		c.Sinks = []string{LogSinkNone}
+
+		// Act
+		logs, closer, err := InitLog(c)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, io.Discard, logs.Out)
+		assert.NoError(t, closer.Close())
+	})
+
+	t.Run("TestInitLogShouldReportUnopenableFile", func(t *testing.T) {
+		// Arrange
+		dir := t.TempDir()
+		blocker := filepath.Join(dir, "blocker")
+		assert.NoError(t, os.WriteFile(blocker, nil, 0644))
+		c := DefaultConfig().Log
+		c.Sinks = []string{LogSinkFile}
+		c.File = filepath.Join(blocker, "app.log")
+
+		// Act
+		_, _, err := InitLog(c)
+
+		// Assert
+		assert.Error(t, err)
+	})
+}
+
+func TestApplyLogConfig(t *testing.T) {
+	t.Run("TestApplyRequestLogConfigShouldUseRequestLevel", func(t *testing.T) {
+		// Arrange
+		logs := logrus.New()
+		c := LogConfig{Level: "debug", RequestLevel: "error", Format: LogFormatJSON}
+
+		// Act
+		ApplyLogConfig(logs, c)
+		appLevel := logs.GetLevel()
+		ApplyRequestLogConfig(logs, c)
+
+		// Assert
+		assert.Equal(t, logrus.DebugLevel, appLevel)
+		assert.Equal(t, logrus.ErrorLevel, logs.GetLevel())
+	})
+}
@@ -0,0 +1,230 @@
+package common
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rotatedTimeFormat names rotated files so they sort by age.
+const rotatedTimeFormat = "20060102T150405.000"
+
+// RotatingFile is an append-only log file that is moved aside once it grows
+// past maxSize bytes or has been written for every. Rotated files are named
+// <file>.<time>, gzipped when compress is set, and removed once older than
+// maxAge or beyond the newest maxBackups. A zero limit disables it.
+type RotatingFile struct {
+	path       string
+	maxSize    int64
+	every      time.Duration
+	maxAge     time.Duration
+	maxBackups int
+	compress   bool
+	now        func() time.Time
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+
+	// mill serialises compression and cleanup, which run in the background
+	// so a rotation does not stall the write that caused it.
+	mill sync.Mutex
+	wg   sync.WaitGroup
+}
+
+// OpenRotatingFile opens path for appending, creating it and its directory,
+// with the rotation and retention limits of c.
+func OpenRotatingFile(path string, c LogConfig) (*RotatingFile, error) {
+	f := &RotatingFile{
+		path:       path,
+		maxSize:    int64(c.MaxSizeMB) * 1024 * 1024,
+		every:      c.RotateEvery,
+		maxAge:     c.MaxAge,
+		maxBackups: c.MaxBackups,
+		compress:   c.Compress,
+		now:        time.Now,
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	f.opened = f.now()
+	return nil
+}
+
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	full := f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize
+	due := f.every > 0 && f.now().Sub(f.opened) >= f.every
+	if full || due {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Rotate moves the current file aside and starts a new one.
+func (f *RotatingFile) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return os.ErrClosed
+	}
+	return f.rotate()
+}
+
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+	rotated := f.path + "." + f.now().UTC().Format(rotatedTimeFormat)
+	if err := os.Rename(f.path, rotated); err != nil {
+		return err
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		f.mill.Lock()
+		defer f.mill.Unlock()
+		if f.compress {
+			// A failed compression leaves the plain file, which is still
+			// subject to retention.
+			_ = gzipFile(rotated)
+		}
+		f.removeOld()
+	}()
+	return nil
+}
+
+// Close closes the file once pending compression and cleanup are done.
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	var err error
+	if f.file != nil {
+		err = f.file.Close()
+		f.file = nil
+	}
+	f.mu.Unlock()
+	f.wg.Wait()
+	return err
+}
+
+// backups lists the rotated files of f, oldest first.
+func (f *RotatingFile) backups() ([]string, error) {
+	dir := filepath.Dir(f.path)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, e := range entries {
+		if _, ok := f.rotatedAt(e.Name()); ok && !e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	for i := range names {
+		names[i] = filepath.Join(dir, names[i])
+	}
+	return names, nil
+}
+
+// rotatedAt reads the rotation time from the name of one of f's backups.
+func (f *RotatingFile) rotatedAt(name string) (time.Time, bool) {
+	stamp := strings.TrimPrefix(filepath.Base(name), filepath.Base(f.path)+".")
+	if stamp == filepath.Base(name) {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(rotatedTimeFormat, strings.TrimSuffix(stamp, ".gz"))
+	return t, err == nil
+}
+
+func (f *RotatingFile) removeOld() {
+	backups, err := f.backups()
+	if err != nil {
+		return
+	}
+	if f.maxBackups > 0 && len(backups) > f.maxBackups {
+		for _, name := range backups[:len(backups)-f.maxBackups] {
+			_ = os.Remove(name)
+		}
+		backups = backups[len(backups)-f.maxBackups:]
+	}
+	if f.maxAge <= 0 {
+		return
+	}
+	cutoff := f.now().Add(-f.maxAge)
+	for _, name := range backups {
+		if rotatedAt, _ := f.rotatedAt(name); rotatedAt.Before(cutoff) {
+			_ = os.Remove(name)
+		}
+	}
+}
+
+// gzipFile replaces name with name.gz.
+func gzipFile(name string) error {
+	in, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(name+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		out.Close()
+		os.Remove(name + ".gz")
+		return fmt.Errorf("error compress %v : %w", name, err)
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		os.Remove(name + ".gz")
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(name + ".gz")
+		return err
+	}
+	return os.Remove(name)
+}
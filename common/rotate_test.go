@@ -0,0 +1,130 @@
+//go:build unit
+
+package common
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// openTestRotatingFile opens a rotating file whose clock the test moves.
+func openTestRotatingFile(t *testing.T, c LogConfig, now *time.Time) *RotatingFile {
+	f, err := OpenRotatingFile(filepath.Join(t.TempDir(), "logs", "app.log"), c)
+	assert.NoError(t, err)
+	f.now = func() time.Time { return *now }
+	f.opened = *now
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func TestRotatingFile(t *testing.T) {
+	t.Run("TestRotatingFileShouldRotateBySize", func(t *testing.T) {
+		// Arrange
+		now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		f := openTestRotatingFile(t, LogConfig{MaxSizeMB: 1}, &now)
+		line := make([]byte, 600*1024)
+
+		// Act
+		_, err := f.Write(line)
+		assert.NoError(t, err)
+		now = now.Add(time.Second)
+		_, err = f.Write(line)
+		assert.NoError(t, err)
+		assert.NoError(t, f.Close())
+
+		// Assert
+		backups, err := f.backups()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{f.path + ".20240102T030406.000"}, backups)
+		info, err := os.Stat(f.path)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(len(line)), info.Size())
+	})
+
+	t.Run("TestRotatingFileShouldRotateByTime", func(t *testing.T) {
+		// Arrange
+		now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		f := openTestRotatingFile(t, LogConfig{RotateEvery: time.Hour}, &now)
+
+		// Act
+		_, err := f.Write([]byte("first\n"))
+		assert.NoError(t, err)
+		now = now.Add(time.Hour)
+		_, err = f.Write([]byte("second\n"))
+		assert.NoError(t, err)
+		assert.NoError(t, f.Close())
+
+		// Assert
+		rotated, err := os.ReadFile(f.path + ".20240102T040405.000")
+		assert.NoError(t, err)
+		assert.Equal(t, "first\n", string(rotated))
+		current, err := os.ReadFile(f.path)
+		assert.NoError(t, err)
+		assert.Equal(t, "second\n", string(current))
+	})
+
+	t.Run("TestRotatingFileShouldCompressRotatedFiles", func(t *testing.T) {
+		// Arrange
+		now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		f := openTestRotatingFile(t, LogConfig{Compress: true}, &now)
+		_, err := f.Write([]byte("compressed\n"))
+		assert.NoError(t, err)
+
+		// Act
+		assert.NoError(t, f.Rotate())
+		assert.NoError(t, f.Close())
+
+		// Assert
+		file, err := os.Open(f.path + ".20240102T030405.000.gz")
+		assert.NoError(t, err)
+		defer file.Close()
+		zr, err := gzip.NewReader(file)
+		assert.NoError(t, err)
+		content, err := io.ReadAll(zr)
+		assert.NoError(t, err)
+		assert.Equal(t, "compressed\n", string(content))
+		assert.NoFileExists(t, f.path+".20240102T030405.000")
+	})
+
+	t.Run("TestRotatingFileShouldKeepMaxBackupsWithinMaxAge", func(t *testing.T) {
+		// Arrange
+		now := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+		f := openTestRotatingFile(t, LogConfig{MaxBackups: 2, MaxAge: 48 * time.Hour}, &now)
+		unrelated := filepath.Join(filepath.Dir(f.path), "app.log.notes")
+		assert.NoError(t, os.WriteFile(unrelated, nil, 0644))
+
+		// Act
+		for i := 0; i < 4; i++ {
+			assert.NoError(t, f.Rotate())
+			f.wg.Wait()
+			now = now.Add(24 * time.Hour)
+		}
+		assert.NoError(t, f.Rotate())
+		assert.NoError(t, f.Close())
+
+		// Assert
+		backups, err := f.backups()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{f.path + ".20240105T000000.000", f.path + ".20240106T000000.000"}, backups)
+		assert.FileExists(t, unrelated)
+	})
+
+	t.Run("TestRotatingFileShouldFailAfterClose", func(t *testing.T) {
+		// Arrange
+		now := time.Now()
+		f := openTestRotatingFile(t, LogConfig{}, &now)
+		assert.NoError(t, f.Close())
+
+		// Act
+		_, err := f.Write([]byte("late\n"))
+
+		// Assert
+		assert.ErrorIs(t, err, os.ErrClosed)
+	})
+}
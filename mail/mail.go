@@ -0,0 +1,99 @@
+// Package mail sends the emails of account flows such as sign-up
+// verification and password reset.
+package mail
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/paquesqueue/bookstore/common"
+)
+
+// Message is one email with a plain text body and, optionally, an HTML
+// alternative.
+type Message struct {
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer delivers messages. Implementations are safe for concurrent use.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// InitMailer returns the mailer selected by the mail driver, defaulting to
+// writing emails to the log.
+func InitMailer(c common.MailConfig, log common.Log) (Mailer, error) {
+	switch c.Driver {
+	case "", common.MailDriverLog:
+		return NewLogMailer(log), nil
+	case common.MailDriverFile:
+		return NewFileMailer(c.Dir, c.From)
+	case common.MailDriverSMTP:
+		return NewSMTPMailer(SMTPConfig{
+			Host:     c.SMTPHost,
+			Port:     c.SMTPPort,
+			Username: c.SMTPUsername,
+			Password: c.SMTPPassword,
+			From:     c.From,
+			Timeout:  c.SMTPTimeout,
+		})
+	default:
+		return nil, fmt.Errorf("error unsupported mail driver %q", c.Driver)
+	}
+}
+
+// LogMailer writes each message to the log instead of sending it, for
+// development.
+type LogMailer struct {
+	log common.Log
+}
+
+func NewLogMailer(l common.Log) LogMailer {
+	return LogMailer{l}
+}
+
+func (m LogMailer) Send(ctx context.Context, msg Message) error {
+	common.LogFrom(ctx, m.log).WithFields(common.Fields{
+		"to":      strings.Join(msg.To, ", "),
+		"subject": msg.Subject,
+	}).Info(msg.Text)
+	return nil
+}
+
+// FileMailer writes each message to its own .eml file, which mail clients
+// open as is.
+type FileMailer struct {
+	dir  string
+	from string
+	now  func() time.Time
+}
+
+func NewFileMailer(dir string, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("error create mail dir : %w", err)
+	}
+	return &FileMailer{dir: dir, from: from, now: time.Now}, nil
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	now := m.now()
+	data, err := msg.encode(m.from, now)
+	if err != nil {
+		return err
+	}
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	name := now.UTC().Format("20060102T150405.000") + "-" + hex.EncodeToString(suffix) + ".eml"
+	return os.WriteFile(filepath.Join(m.dir, name), data, 0644)
+}
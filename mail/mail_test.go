@@ -0,0 +1,110 @@
+//go:build unit
+
+package mail
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paquesqueue/bookstore/common"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileMailer(t *testing.T) {
+	t.Run("TestFileMailerShouldWriteOneEmlFilePerMessage", func(t *testing.T) {
+		// Arrange
+		dir := filepath.Join(t.TempDir(), "mail")
+		mailer, err := NewFileMailer(dir, "Bookstore <no-reply@bookstore.test>")
+		assert.NoError(t, err)
+		mailer.now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+		// Act
+		err = mailer.Send(context.Background(), Message{To: []string{"reader@email.com"}, Subject: "Ünïcode", Text: "Hello\n"})
+		assert.NoError(t, err)
+		err = mailer.Send(context.Background(), Message{To: []string{"reader@email.com"}, Subject: "Again", Text: "Hello\n"})
+		assert.NoError(t, err)
+
+		// Assert
+		entries, err := os.ReadDir(dir)
+		assert.NoError(t, err)
+		if assert.Len(t, entries, 2) {
+			assert.True(t, strings.HasPrefix(entries[0].Name(), "20240102T030405.000-"))
+			assert.True(t, strings.HasSuffix(entries[0].Name(), ".eml"))
+
+			data, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+			assert.NoError(t, err)
+			assert.Contains(t, string(data), "Date: Tue, 02 Jan 2024 03:04:05 +0000\r\n")
+			assert.Contains(t, string(data), "Content-Type: text/plain; charset=utf-8\r\n")
+		}
+	})
+}
+
+func TestLogMailer(t *testing.T) {
+	t.Run("TestLogMailerShouldLogRecipientsAndText", func(t *testing.T) {
+		// Arrange
+		log, hook := test.NewNullLogger()
+		mailer := NewLogMailer(log)
+
+		// Act
+		err := mailer.Send(context.Background(), Message{To: []string{"a@email.com", "b@email.com"}, Subject: "Hi", Text: "Open the link"})
+
+		// Assert
+		assert.NoError(t, err)
+		if assert.NotNil(t, hook.LastEntry()) {
+			assert.Equal(t, "Open the link", hook.LastEntry().Message)
+			assert.Equal(t, "a@email.com, b@email.com", hook.LastEntry().Data["to"])
+			assert.Equal(t, "Hi", hook.LastEntry().Data["subject"])
+		}
+	})
+}
+
+func TestInitMailer(t *testing.T) {
+	t.Run("TestInitMailerShouldSelectDriver", func(t *testing.T) {
+		// Arrange
+		c := common.DefaultConfig().Mail
+
+		// Act
+		logMailer, logErr := InitMailer(c, logrus.New())
+		c.Driver, c.SMTPHost = common.MailDriverSMTP, "localhost"
+		smtpMailer, smtpErr := InitMailer(c, logrus.New())
+		c.Driver = "pigeon"
+		_, unknownErr := InitMailer(c, logrus.New())
+
+		// Assert
+		assert.NoError(t, logErr)
+		assert.IsType(t, LogMailer{}, logMailer)
+		assert.NoError(t, smtpErr)
+		assert.IsType(t, &SMTPMailer{}, smtpMailer)
+		assert.EqualError(t, unknownErr, `error unsupported mail driver "pigeon"`)
+	})
+}
+
+func TestMessageEncode(t *testing.T) {
+	t.Run("TestMessageEncodeShouldEncodeSubjectAndBody", func(t *testing.T) {
+		// Arrange
+		msg := Message{To: []string{"reader@email.com"}, Subject: "Café", Text: "Prix : 10 €\n"}
+
+		// Act
+		data, err := msg.encode("no-reply@bookstore.test", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Contains(t, string(data), "Subject: =?utf-8?q?Caf=C3=A9?=\r\n")
+		assert.Contains(t, string(data), "Prix : 10 =E2=82=AC\r\n")
+		assert.Regexp(t, `Message-ID: <[0-9a-f]{32}@bookstore\.test>`, string(data))
+	})
+
+	t.Run("TestMessageEncodeShouldRejectInvalidRecipient", func(t *testing.T) {
+		// Act
+		_, err := Message{To: []string{"not an address"}}.encode("no-reply@bookstore.test", time.Now())
+
+		// Assert
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,101 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+const defaultSMTPTimeout = 10 * time.Second
+
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	Timeout  time.Duration
+}
+
+// SMTPMailer sends each message over its own connection, upgraded with
+// STARTTLS when the server offers it. Credentials are only sent over TLS or
+// to a server on localhost.
+type SMTPMailer struct {
+	c    SMTPConfig
+	from string
+	now  func() time.Time
+}
+
+func NewSMTPMailer(c SMTPConfig) (*SMTPMailer, error) {
+	from, err := mail.ParseAddress(c.From)
+	if err != nil {
+		return nil, fmt.Errorf("error parse mail sender : %w", err)
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = defaultSMTPTimeout
+	}
+	return &SMTPMailer{c: c, from: from.Address, now: time.Now}, nil
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	data, err := msg.encode(m.c.From, m.now())
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.c.Timeout)
+	defer cancel()
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(m.c.Host, strconv.Itoa(m.c.Port)))
+	if err != nil {
+		return fmt.Errorf("error connect smtp : %w", err)
+	}
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, m.c.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("error smtp greeting : %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.c.Host}); err != nil {
+			return fmt.Errorf("error smtp starttls : %w", err)
+		}
+	}
+	if m.c.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.c.Username, m.c.Password, m.c.Host)); err != nil {
+			return fmt.Errorf("error smtp auth : %w", err)
+		}
+	}
+	if err := client.Mail(m.from); err != nil {
+		return fmt.Errorf("error smtp sender : %w", err)
+	}
+	for _, to := range msg.To {
+		addr, err := mail.ParseAddress(to)
+		if err != nil {
+			return fmt.Errorf("error parse mail recipient : %w", err)
+		}
+		if err := client.Rcpt(addr.Address); err != nil {
+			return fmt.Errorf("error smtp recipient %v : %w", addr.Address, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("error smtp data : %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("error smtp data : %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("error smtp data : %w", err)
+	}
+	return client.Quit()
+}
@@ -0,0 +1,180 @@
+//go:build unit
+
+package mail
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSMTP is a local stand-in accepting mail like an SMTP server without
+// STARTTLS, keeping what it received.
+type fakeSMTP struct {
+	ln       net.Listener
+	username string
+	password string
+
+	mu   sync.Mutex
+	from string
+	to   []string
+	data string
+}
+
+func newFakeSMTP(t *testing.T, username string, password string) *fakeSMTP {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	s := &fakeSMTP{ln: ln, username: username, password: password}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeSMTP) config() SMTPConfig {
+	host, port, _ := net.SplitHostPort(s.ln.Addr().String())
+	n, _ := strconv.Atoi(port)
+	return SMTPConfig{Host: host, Port: n, From: "Bookstore <no-reply@bookstore.test>", Timeout: 5 * time.Second}
+}
+
+func (s *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch verb {
+		case "EHLO":
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			creds, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(line, "AUTH PLAIN "))
+			if string(creds) != "\x00"+s.username+"\x00"+s.password {
+				reply("535 5.7.8 Authentication credentials invalid")
+				continue
+			}
+			reply("235 2.7.0 Authentication successful")
+		case "MAIL":
+			s.mu.Lock()
+			s.from = line[len("MAIL FROM:"):]
+			s.mu.Unlock()
+			reply("250 OK")
+		case "RCPT":
+			s.mu.Lock()
+			s.to = append(s.to, line[len("RCPT TO:"):])
+			s.mu.Unlock()
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			s.mu.Lock()
+			s.data = data.String()
+			s.mu.Unlock()
+			reply("250 OK queued")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestSMTPMailer(t *testing.T) {
+	t.Run("TestSMTPMailerShouldDeliverMessage", func(t *testing.T) {
+		// Arrange
+		server := newFakeSMTP(t, "mailer", "secret")
+		c := server.config()
+		c.Username, c.Password = "mailer", "secret"
+		mailer, err := NewSMTPMailer(c)
+		assert.NoError(t, err)
+
+		// Act
+		err = mailer.Send(context.Background(), Message{
+			To:      []string{"Reader <reader@email.com>"},
+			Subject: "Reset your password",
+			Text:    "Open the link.\n",
+			HTML:    "<p>Open the link.</p>",
+		})
+
+		// Assert
+		assert.NoError(t, err)
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		assert.Equal(t, "<no-reply@bookstore.test>", server.from)
+		assert.Equal(t, []string{"<reader@email.com>"}, server.to)
+		assert.Contains(t, server.data, "Subject: Reset your password\r\n")
+		assert.Contains(t, server.data, "To: \"Reader\" <reader@email.com>\r\n")
+		assert.Contains(t, server.data, "Content-Type: multipart/alternative")
+		assert.Contains(t, server.data, "<p>Open the link.</p>")
+	})
+
+	t.Run("TestSMTPMailerShouldReportRejectedCredentials", func(t *testing.T) {
+		// Arrange
+		server := newFakeSMTP(t, "mailer", "secret")
+		c := server.config()
+		c.Username, c.Password = "mailer", "wrong"
+		mailer, err := NewSMTPMailer(c)
+		assert.NoError(t, err)
+
+		// Act
+		err = mailer.Send(context.Background(), Message{To: []string{"reader@email.com"}, Subject: "Hi", Text: "Hi"})
+
+		// Assert
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "error smtp auth")
+		}
+		assert.Empty(t, server.data)
+	})
+
+	t.Run("TestSMTPMailerShouldReportUnreachableServer", func(t *testing.T) {
+		// Arrange
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		host, port, _ := net.SplitHostPort(ln.Addr().String())
+		ln.Close()
+		n, _ := strconv.Atoi(port)
+		mailer, err := NewSMTPMailer(SMTPConfig{Host: host, Port: n, From: "no-reply@bookstore.test"})
+		assert.NoError(t, err)
+
+		// Act
+		err = mailer.Send(context.Background(), Message{To: []string{"reader@email.com"}, Subject: "Hi", Text: "Hi"})
+
+		// Assert
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "error connect smtp")
+		}
+	})
+}
@@ -0,0 +1,45 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	"io/fs"
+	"strings"
+	texttemplate "text/template"
+)
+
+//go:embed templates
+var templates embed.FS
+
+// Render fills the email templates named name: templates/<name>.txt, which
+// defines the subject in a "subject" block, and templates/<name>.html if it
+// exists.
+func Render(name string, data interface{}) (Message, error) {
+	text, err := texttemplate.ParseFS(templates, "templates/"+name+".txt")
+	if err != nil {
+		return Message{}, err
+	}
+	var subject, body bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := text.Execute(&body, data); err != nil {
+		return Message{}, err
+	}
+	msg := Message{Subject: strings.TrimSpace(subject.String()), Text: strings.TrimSpace(body.String()) + "\n"}
+
+	if _, err := fs.Stat(templates, "templates/"+name+".html"); err != nil {
+		return msg, nil
+	}
+	html, err := htmltemplate.ParseFS(templates, "templates/"+name+".html")
+	if err != nil {
+		return Message{}, err
+	}
+	var htmlBody bytes.Buffer
+	if err := html.Execute(&htmlBody, data); err != nil {
+		return Message{}, err
+	}
+	msg.HTML = htmlBody.String()
+	return msg, nil
+}
@@ -0,0 +1,40 @@
+//go:build unit
+
+package mail
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender(t *testing.T) {
+	t.Run("TestRenderShouldFillSubjectTextAndHTML", func(t *testing.T) {
+		// Arrange
+		data := map[string]string{
+			"Username": "reader",
+			"Fullname": "Re <Ader>",
+			"Link":     "http://localhost:2565/reset-password?token=abc&x=1",
+			"ValidFor": "1 hour",
+		}
+
+		// Act
+		msg, err := Render("password_reset", data)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "Reset your password", msg.Subject)
+		assert.Contains(t, msg.Text, "Hi Re <Ader>,")
+		assert.Contains(t, msg.Text, "http://localhost:2565/reset-password?token=abc&x=1\n")
+		assert.Contains(t, msg.HTML, "Hi Re &lt;Ader&gt;,")
+		assert.Contains(t, msg.HTML, `href="http://localhost:2565/reset-password?token=abc&amp;x=1"`)
+	})
+
+	t.Run("TestRenderShouldFailForUnknownTemplate", func(t *testing.T) {
+		// Act
+		_, err := Render("newsletter", nil)
+
+		// Assert
+		assert.Error(t, err)
+	})
+}
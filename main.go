@@ -1,39 +1,150 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"net/http"
+	"os"
 
 	"github.com/labstack/echo/v4"
 	_ "github.com/lib/pq"
 	"github.com/paquesqueue/bookstore/api"
 	"github.com/paquesqueue/bookstore/common"
+	"github.com/paquesqueue/bookstore/mail"
+	"github.com/paquesqueue/bookstore/metrics"
+	"github.com/paquesqueue/bookstore/moderation"
+	"github.com/paquesqueue/bookstore/passwords"
+	"github.com/paquesqueue/bookstore/ratelimit"
 	"github.com/paquesqueue/bookstore/server"
+	"github.com/paquesqueue/bookstore/storage"
+	"github.com/paquesqueue/bookstore/tracing"
+	"github.com/paquesqueue/bookstore/utils"
 )
 
 func main() {
-	log := common.InitLog()
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		if err := common.ConfigCommand(os.Args[2:], os.Stdout, os.Stderr); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	config, err := common.LoadConfig(os.Args[1:], os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
 
-	config := common.InitConfig()
+	log, logFiles, err := common.InitLog(config.Log)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer logFiles.Close()
+	log.AddHook(tracing.LogHook{})
 	log.Info("Success Config Loaded")
 
-	db, err := api.InitDB(config, log)
+	tracer, err := tracing.InitTracer(config.Tracing, os.Stdout)
+	if err != nil {
+		log.Fatalf("Error Tracer Init Failed : %v", err)
+	}
+	tracing.SetTracer(tracer)
+
+	reg := metrics.NewRegistry()
+
+	db, err := api.InitDB(config.DB, api.NewDBMetrics(reg), log)
 	if err != nil {
 		log.Fatal("Error Database Init Failed")
 	}
 	log.Info("Success Database Connection Initialized")
 
+	store, err := storage.InitBlobStore(config.Storage)
+	if err != nil {
+		log.Fatalf("Error Blob Store Init Failed : %v", err)
+	}
+	log.Info("Success Blob Store Initialized")
+
+	reviewFilter := moderation.NewDefaultFilter(config.Features.ReviewBlockedWords)
+
 	echo := echo.New()
 
-	reqLog := common.InitRequestLog()
-	
-	server.InitMiddleware(echo, reqLog, config)
-	server.InitRoutes(echo, db, log)
+	reqLog, reqLogFiles, err := common.InitRequestLog(config.Log)
+	if err != nil {
+		log.Fatalf("Error Request Log Init Failed : %v", err)
+	}
+	defer reqLogFiles.Close()
+	reqLog.AddHook(tracing.LogHook{})
+
+	secLog, secLogFiles, err := common.InitSecurityLog(config.Log)
+	if err != nil {
+		log.Fatalf("Error Security Log Init Failed : %v", err)
+	}
+	defer secLogFiles.Close()
+	secLog.AddHook(tracing.LogHook{})
+
+	live := common.NewLiveConfig(config, os.Args[1:])
+	live.OnChange(func(c common.Config) {
+		common.ApplyLogConfig(log, c.Log)
+		common.ApplyRequestLogConfig(reqLog, c.Log)
+		common.ApplySecurityLogConfig(secLog, c.Log)
+	})
+
+	server.InitMiddleware(echo, reqLog, live, reg)
+	jobCtx, stopJobs := context.WithCancel(context.Background())
+	dbHealth := api.NewDBHealth(db, config.DB.HealthTimeout, log)
+	go dbHealth.Run(jobCtx, config.DB.HealthInterval)
+
+	health := api.NewHealthService(api.NewDB(db), dbHealth, log)
+
+	limiter, err := ratelimit.InitLimiter(live)
+	if err != nil {
+		log.Fatalf("Error Rate Limiter Init Failed : %v", err)
+	}
+	defer limiter.Close()
+
+	mailer, err := mail.InitMailer(config.Mail, log)
+	if err != nil {
+		log.Fatalf("Error Mailer Init Failed : %v", err)
+	}
+
+	utils.SetPasswordHashing(utils.PasswordHashing{
+		Algorithm:     config.Password.Hash,
+		BcryptCost:    config.Password.BcryptCost,
+		Argon2Time:    uint32(config.Password.Argon2Time),
+		Argon2Memory:  uint32(config.Password.Argon2Memory),
+		Argon2Threads: uint8(config.Password.Argon2Threads),
+	})
+	passwordPolicy, err := passwords.InitPolicy(config.Password)
+	if err != nil {
+		log.Fatalf("Error Password Policy Init Failed : %v", err)
+	}
+
+	server.InitRoutes(echo, db, dbHealth, health, reg, limiter, store, reviewFilter, config.Auth, passwordPolicy, mailer, secLog, log)
 
 	serv := &http.Server{
-		Addr:    ":" + config.Port,
-		Handler: echo,
+		Addr:         ":" + config.Server.Port,
+		Handler:      echo,
+		ReadTimeout:  config.Server.ReadTimeout,
+		WriteTimeout: config.Server.WriteTimeout,
+		IdleTimeout:  config.Server.IdleTimeout,
 	}
 
+	recommendServ := api.NewRecommendService(api.NewDB(db), log)
+	go recommendServ.RunRefresh(jobCtx, config.Features.RecommendInterval)
+
 	server.StartServer(serv, config)
-	server.GracefulShutdown(echo, log)
+	server.GracefulShutdown(serv, log, live, health)
+	stopJobs()
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.Server.ShutdownTimeout)
+	defer cancel()
+	if err := tracer.Shutdown(ctx); err != nil {
+		log.Errorf("Error Tracer Shut Down : %v", err)
+	}
 }
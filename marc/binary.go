@@ -0,0 +1,172 @@
+package marc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// ISO 2709 delimiters.
+const (
+	subfieldDelimiter = 0x1F
+	fieldTerminator   = 0x1E
+	recordTerminator  = 0x1D
+
+	directoryEntryLength = 12
+)
+
+// Marshal encodes the record in MARC21 transmission format (ISO 2709),
+// computing the record length and base address of data in the leader.
+func Marshal(r Record) ([]byte, error) {
+	var dir, data bytes.Buffer
+	for _, f := range r.Fields {
+		if len(f.Tag) != 3 {
+			return nil, fmt.Errorf("error invalid marc tag %q", f.Tag)
+		}
+		start := data.Len()
+		if f.IsControl() {
+			data.WriteString(f.Value)
+		} else {
+			data.WriteByte(indicator(f.Ind1))
+			data.WriteByte(indicator(f.Ind2))
+			for _, sf := range f.Subfields {
+				data.WriteByte(subfieldDelimiter)
+				data.WriteByte(sf.Code)
+				data.WriteString(sf.Value)
+			}
+		}
+		data.WriteByte(fieldTerminator)
+
+		length := data.Len() - start
+		if length > 9999 || start > 99999 {
+			return nil, fmt.Errorf("error marc field %v too long", f.Tag)
+		}
+		fmt.Fprintf(&dir, "%s%04d%05d", f.Tag, length, start)
+	}
+	dir.WriteByte(fieldTerminator)
+
+	base := LeaderLength + dir.Len()
+	total := base + data.Len() + 1
+	if total > 99999 {
+		return nil, fmt.Errorf("error marc record too long")
+	}
+
+	leader := []byte(normalizeLeader(r.Leader))
+	copy(leader[0:5], fmt.Sprintf("%05d", total))
+	copy(leader[12:17], fmt.Sprintf("%05d", base))
+
+	out := make([]byte, 0, total)
+	out = append(out, leader...)
+	out = append(out, dir.Bytes()...)
+	out = append(out, data.Bytes()...)
+	out = append(out, recordTerminator)
+	return out, nil
+}
+
+// Unmarshal decodes a single ISO 2709 record.
+func Unmarshal(b []byte) (*Record, error) {
+	if len(b) < LeaderLength+1 {
+		return nil, fmt.Errorf("error marc record too short")
+	}
+	if b[len(b)-1] != recordTerminator {
+		return nil, fmt.Errorf("error marc record terminator not found")
+	}
+	base, err := strconv.Atoi(string(b[12:17]))
+	if err != nil || base <= LeaderLength || base > len(b) {
+		return nil, fmt.Errorf("error invalid marc base address %q", b[12:17])
+	}
+
+	rec := &Record{Leader: string(b[:LeaderLength])}
+	dir := b[LeaderLength : base-1]
+	if len(dir)%directoryEntryLength != 0 {
+		return nil, fmt.Errorf("error invalid marc directory length %d", len(dir))
+	}
+	data := b[base:]
+
+	for i := 0; i < len(dir); i += directoryEntryLength {
+		entry := dir[i : i+directoryEntryLength]
+		tag := string(entry[0:3])
+		length, err1 := strconv.Atoi(string(entry[3:7]))
+		start, err2 := strconv.Atoi(string(entry[7:12]))
+		if err1 != nil || err2 != nil || start+length > len(data) || length < 1 {
+			return nil, fmt.Errorf("error invalid marc directory entry %q", entry)
+		}
+		raw := data[start : start+length-1]
+
+		if IsControlTag(tag) {
+			rec.AddControlField(tag, string(raw))
+			continue
+		}
+		if len(raw) < 2 {
+			return nil, fmt.Errorf("error marc field %v missing indicators", tag)
+		}
+		f := Field{Tag: tag, Ind1: raw[0], Ind2: raw[1]}
+		for _, part := range bytes.Split(raw[2:], []byte{subfieldDelimiter}) {
+			if len(part) == 0 {
+				continue
+			}
+			f.Subfields = append(f.Subfields, Subfield{Code: part[0], Value: string(part[1:])})
+		}
+		rec.Fields = append(rec.Fields, f)
+	}
+	return rec, nil
+}
+
+// Reader reads consecutive ISO 2709 records from a stream.
+type Reader struct {
+	r *bufio.Reader
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{bufio.NewReader(r)}
+}
+
+// Next returns the next record, or io.EOF when the stream is exhausted.
+func (r *Reader) Next() (*Record, error) {
+	head := make([]byte, 5)
+	n, err := io.ReadFull(r.r, head)
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error read marc record length : %w", err)
+	}
+	total, err := strconv.Atoi(string(head[:n]))
+	if err != nil || total < LeaderLength+1 {
+		return nil, fmt.Errorf("error invalid marc record length %q", head)
+	}
+
+	buf := make([]byte, total)
+	copy(buf, head)
+	if _, err := io.ReadFull(r.r, buf[5:]); err != nil {
+		return nil, fmt.Errorf("error read marc record : %w", err)
+	}
+	return Unmarshal(buf)
+}
+
+// Writer writes records in ISO 2709 format one after another.
+type Writer struct {
+	w io.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w}
+}
+
+func (w *Writer) Write(r Record) error {
+	b, err := Marshal(r)
+	if err != nil {
+		return err
+	}
+	_, err = w.w.Write(b)
+	return err
+}
+
+func indicator(b byte) byte {
+	if b == 0 {
+		return ' '
+	}
+	return b
+}
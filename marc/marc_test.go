@@ -0,0 +1,157 @@
+//go:build unit
+
+package marc
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+func goldenRecord() Record {
+	r := NewRecord()
+	r.AddControlField("001", "42")
+	r.AddDataField("020", ' ', ' ', Subfield{'a', "9780000000017"})
+	r.AddDataField("100", '1', ' ', Subfield{'a', "John Author"}, Subfield{'e', "author."})
+	r.AddDataField("245", '1', '0', Subfield{'a', "The Go Bookstore"})
+	r.AddDataField("264", ' ', '1', Subfield{'b', "Sample Press"})
+	r.AddDataField("365", ' ', ' ', Subfield{'b', "450"}, Subfield{'c', "THB"})
+	r.AddDataField("700", '1', ' ', Subfield{'a', "Jane Writer"}, Subfield{'e', "author."})
+	r.AddDataField("700", '1', ' ', Subfield{'a', "สมชาย ใจดี"}, Subfield{'e', "author."})
+	return *r
+}
+
+func assertGolden(t *testing.T, name string, got []byte) {
+	path := filepath.Join("testdata", name)
+	if *update {
+		assert.NoError(t, os.WriteFile(path, got, 0644))
+	}
+	want, err := os.ReadFile(path)
+	if assert.NoError(t, err) {
+		assert.Equal(t, string(want), string(got))
+	}
+}
+
+func TestBinary(t *testing.T) {
+	t.Run("TestMarshalShouldMatchGolden", func(t *testing.T) {
+		// Arrange
+		rec := goldenRecord()
+
+		// Act
+		got, err := Marshal(rec)
+
+		// Assert
+		assert.NoError(t, err)
+		assertGolden(t, "record.mrc", got)
+	})
+
+	t.Run("TestReaderShouldDecodeGolden", func(t *testing.T) {
+		// Arrange
+		file, err := os.Open("testdata/record.mrc")
+		assert.NoError(t, err)
+		defer file.Close()
+		reader := NewReader(file)
+
+		// Act
+		rec, err := reader.Next()
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, goldenRecord().Fields, rec.Fields)
+		assert.Equal(t, "00286nam a2200121 i 4500", rec.Leader)
+		_, err = reader.Next()
+		assert.Equal(t, io.EOF, err)
+	})
+
+	t.Run("TestReaderShouldReturnErrorOnTruncatedRecord", func(t *testing.T) {
+		// Arrange
+		b, err := Marshal(goldenRecord())
+		assert.NoError(t, err)
+
+		// Act
+		rec, err := NewReader(bytes.NewReader(b[:len(b)-10])).Next()
+
+		// Assert
+		assert.Error(t, err)
+		assert.Nil(t, rec)
+	})
+
+	t.Run("TestMarshalShouldReturnErrorOnInvalidTag", func(t *testing.T) {
+		// Arrange
+		rec := NewRecord()
+		rec.AddControlField("1", "x")
+
+		// Act
+		_, err := Marshal(*rec)
+
+		// Assert
+		assert.Error(t, err)
+	})
+}
+
+func TestXML(t *testing.T) {
+	t.Run("TestXMLWriterShouldMatchGolden", func(t *testing.T) {
+		// Arrange
+		var buf bytes.Buffer
+		w := NewXMLWriter(&buf)
+
+		// Act
+		err := w.Write(goldenRecord())
+		assert.NoError(t, err)
+		err = w.Close()
+
+		// Assert
+		assert.NoError(t, err)
+		assertGolden(t, "record.xml", buf.Bytes())
+	})
+
+	t.Run("TestXMLReaderShouldDecodeGolden", func(t *testing.T) {
+		// Arrange
+		file, err := os.Open("testdata/record.xml")
+		assert.NoError(t, err)
+		defer file.Close()
+		reader := NewXMLReader(file)
+
+		// Act
+		rec, err := reader.Next()
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, goldenRecord(), *rec)
+		_, err = reader.Next()
+		assert.Equal(t, io.EOF, err)
+	})
+
+	t.Run("TestXMLReaderShouldReadSingleRecordDocument", func(t *testing.T) {
+		// Arrange
+		doc := `<record xmlns="http://www.loc.gov/MARC21/slim"><leader>00000nam a2200000 i 4500</leader>` +
+			`<datafield tag="245" ind1="0" ind2="0"><subfield code="a">Title</subfield></datafield></record>`
+
+		// Act
+		rec, err := NewXMLReader(bytes.NewReader([]byte(doc))).Next()
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "Title", rec.FieldsByTag("245")[0].Subfield('a'))
+	})
+
+	t.Run("TestXMLWriterShouldWriteEmptyCollection", func(t *testing.T) {
+		// Arrange
+		var buf bytes.Buffer
+
+		// Act
+		err := NewXMLWriter(&buf).Close()
+
+		// Assert
+		assert.NoError(t, err)
+		_, err = NewXMLReader(&buf).Next()
+		assert.Equal(t, io.EOF, err)
+	})
+}
@@ -0,0 +1,88 @@
+package marc
+
+import "strings"
+
+const (
+	LeaderLength = 24
+
+	// DefaultLeader describes a new, Unicode encoded, monograph language
+	// material record. Record length and base address are filled on encode.
+	DefaultLeader = "00000nam a2200000 i 4500"
+)
+
+type Record struct {
+	Leader string
+	Fields []Field
+}
+
+// Field is either a control field (tags 001-009) holding a single Value, or a
+// data field holding two indicators and its subfields.
+type Field struct {
+	Tag       string
+	Ind1      byte
+	Ind2      byte
+	Value     string
+	Subfields []Subfield
+}
+
+type Subfield struct {
+	Code  byte
+	Value string
+}
+
+func NewRecord() *Record {
+	return &Record{Leader: DefaultLeader}
+}
+
+func IsControlTag(tag string) bool {
+	return strings.HasPrefix(tag, "00")
+}
+
+func (f Field) IsControl() bool {
+	return IsControlTag(f.Tag)
+}
+
+// Subfield returns the first value of the subfield with the given code.
+func (f Field) Subfield(code byte) string {
+	for _, sf := range f.Subfields {
+		if sf.Code == code {
+			return sf.Value
+		}
+	}
+	return ""
+}
+
+func (r *Record) AddControlField(tag, value string) {
+	r.Fields = append(r.Fields, Field{Tag: tag, Value: value})
+}
+
+func (r *Record) AddDataField(tag string, ind1, ind2 byte, subfields ...Subfield) {
+	r.Fields = append(r.Fields, Field{Tag: tag, Ind1: ind1, Ind2: ind2, Subfields: subfields})
+}
+
+// FieldsByTag returns every field with the given tag in record order.
+func (r Record) FieldsByTag(tag string) []Field {
+	fields := []Field{}
+	for _, f := range r.Fields {
+		if f.Tag == tag {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+func (r Record) ControlField(tag string) string {
+	for _, f := range r.Fields {
+		if f.Tag == tag {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+func normalizeLeader(leader string) string {
+	if len(leader) >= LeaderLength {
+		return leader[:LeaderLength]
+	}
+	return leader + DefaultLeader[len(leader):]
+}
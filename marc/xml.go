@@ -0,0 +1,163 @@
+package marc
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+const XMLNamespace = "http://www.loc.gov/MARC21/slim"
+
+type xmlRecord struct {
+	XMLName       xml.Name          `xml:"record"`
+	Leader        string            `xml:"leader"`
+	ControlFields []xmlControlField `xml:"controlfield"`
+	DataFields    []xmlDataField    `xml:"datafield"`
+}
+
+type xmlControlField struct {
+	Tag   string `xml:"tag,attr"`
+	Value string `xml:",chardata"`
+}
+
+type xmlDataField struct {
+	Tag       string        `xml:"tag,attr"`
+	Ind1      string        `xml:"ind1,attr"`
+	Ind2      string        `xml:"ind2,attr"`
+	Subfields []xmlSubfield `xml:"subfield"`
+}
+
+type xmlSubfield struct {
+	Code  string `xml:"code,attr"`
+	Value string `xml:",chardata"`
+}
+
+// XMLWriter writes records as a MARCXML collection. Callers must call Close
+// to terminate the collection.
+type XMLWriter struct {
+	w       io.Writer
+	enc     *xml.Encoder
+	started bool
+}
+
+func NewXMLWriter(w io.Writer) *XMLWriter {
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return &XMLWriter{w: w, enc: enc}
+}
+
+func (w *XMLWriter) Write(r Record) error {
+	if err := w.start(); err != nil {
+		return err
+	}
+	return w.enc.Encode(toXMLRecord(r))
+}
+
+func (w *XMLWriter) Close() error {
+	if err := w.start(); err != nil {
+		return err
+	}
+	if err := w.enc.EncodeToken(collectionElement().End()); err != nil {
+		return err
+	}
+	return w.enc.Flush()
+}
+
+func (w *XMLWriter) start() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+	if _, err := io.WriteString(w.w, xml.Header); err != nil {
+		return err
+	}
+	return w.enc.EncodeToken(collectionElement())
+}
+
+// XMLReader reads records from a MARCXML collection, or from a document whose
+// root is a single record, one record at a time.
+type XMLReader struct {
+	dec *xml.Decoder
+}
+
+func NewXMLReader(r io.Reader) *XMLReader {
+	return &XMLReader{xml.NewDecoder(r)}
+}
+
+// Next returns the next record, or io.EOF when there are no more.
+func (r *XMLReader) Next() (*Record, error) {
+	for {
+		tok, err := r.dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "collection":
+			continue
+		case "record":
+			xr := xmlRecord{}
+			if err := r.dec.DecodeElement(&xr, &start); err != nil {
+				return nil, fmt.Errorf("error decode marcxml record : %w", err)
+			}
+			return fromXMLRecord(xr)
+		default:
+			return nil, fmt.Errorf("error unexpected marcxml element %q", start.Name.Local)
+		}
+	}
+}
+
+func toXMLRecord(r Record) xmlRecord {
+	xr := xmlRecord{Leader: normalizeLeader(r.Leader)}
+	for _, f := range r.Fields {
+		if f.IsControl() {
+			xr.ControlFields = append(xr.ControlFields, xmlControlField{Tag: f.Tag, Value: f.Value})
+			continue
+		}
+		df := xmlDataField{Tag: f.Tag, Ind1: string(indicator(f.Ind1)), Ind2: string(indicator(f.Ind2))}
+		for _, sf := range f.Subfields {
+			df.Subfields = append(df.Subfields, xmlSubfield{Code: string(sf.Code), Value: sf.Value})
+		}
+		xr.DataFields = append(xr.DataFields, df)
+	}
+	return xr
+}
+
+func fromXMLRecord(xr xmlRecord) (*Record, error) {
+	rec := &Record{Leader: normalizeLeader(xr.Leader)}
+	for _, cf := range xr.ControlFields {
+		rec.AddControlField(cf.Tag, cf.Value)
+	}
+	for _, df := range xr.DataFields {
+		if len(df.Tag) != 3 {
+			return nil, fmt.Errorf("error invalid marc tag %q", df.Tag)
+		}
+		f := Field{Tag: df.Tag, Ind1: xmlIndicator(df.Ind1), Ind2: xmlIndicator(df.Ind2)}
+		for _, sf := range df.Subfields {
+			if len(sf.Code) != 1 {
+				return nil, fmt.Errorf("error invalid marc subfield code %q in %v", sf.Code, df.Tag)
+			}
+			f.Subfields = append(f.Subfields, Subfield{Code: sf.Code[0], Value: sf.Value})
+		}
+		rec.Fields = append(rec.Fields, f)
+	}
+	return rec, nil
+}
+
+func xmlIndicator(s string) byte {
+	if s == "" {
+		return ' '
+	}
+	return s[0]
+}
+
+func collectionElement() xml.StartElement {
+	return xml.StartElement{
+		Name: xml.Name{Local: "collection"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: XMLNamespace}},
+	}
+}
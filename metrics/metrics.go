@@ -0,0 +1,324 @@
+// Package metrics keeps counters, gauges and histograms and writes them in
+// the Prometheus text exposition format.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefBuckets are latency buckets in seconds suited to HTTP requests and
+// database queries.
+var DefBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Registry holds the metric families of a process, written in registration
+// order.
+type Registry struct {
+	mu       sync.Mutex
+	families []*family
+	hooks    []func()
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// OnScrape registers fn to run before every write, for gauges that are
+// sampled rather than updated as things happen.
+func (r *Registry) OnScrape(fn func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hooks = append(r.hooks, fn)
+}
+
+func (r *Registry) Counter(name string, help string, labels ...string) *CounterVec {
+	return &CounterVec{r.register(name, help, "counter", nil, labels)}
+}
+
+func (r *Registry) Gauge(name string, help string, labels ...string) *GaugeVec {
+	return &GaugeVec{r.register(name, help, "gauge", nil, labels)}
+}
+
+// Histogram counts observations into buckets, which must be sorted
+// ascending; the +Inf bucket is implied.
+func (r *Registry) Histogram(name string, help string, buckets []float64, labels ...string) *HistogramVec {
+	return &HistogramVec{r.register(name, help, "histogram", buckets, labels)}
+}
+
+// GaugeFunc reports fn as a gauge, calling it on every write.
+func (r *Registry) GaugeFunc(name string, help string, fn func() float64) {
+	r.register(name, help, "gauge", nil, nil).fn = fn
+}
+
+// CounterFunc reports fn, which must never decrease, as a counter.
+func (r *Registry) CounterFunc(name string, help string, fn func() float64) {
+	r.register(name, help, "counter", nil, nil).fn = fn
+}
+
+func (r *Registry) register(name string, help string, kind string, buckets []float64, labels []string) *family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, f := range r.families {
+		if f.name == name {
+			panic(fmt.Sprintf("metrics: %v registered twice", name))
+		}
+	}
+	f := &family{name: name, help: help, kind: kind, buckets: buckets, labels: labels, series: map[string]*series{}}
+	r.families = append(r.families, f)
+	return f
+}
+
+// WriteTo runs the scrape hooks and writes every family to w.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	hooks := append([]func(){}, r.hooks...)
+	families := append([]*family{}, r.families...)
+	r.mu.Unlock()
+
+	for _, hook := range hooks {
+		hook()
+	}
+
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	for _, f := range families {
+		f.write(cw)
+	}
+	if err := cw.w.Flush(); err != nil {
+		return cw.n, err
+	}
+	return cw.n, cw.err
+}
+
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", contentType)
+	_, _ = r.WriteTo(w)
+}
+
+type CounterVec struct {
+	f *family
+}
+
+// With returns the counter for the given label values, in the order the
+// labels were registered.
+func (v *CounterVec) With(values ...string) Counter {
+	return Counter{v.f.get(values)}
+}
+
+type Counter struct {
+	s *series
+}
+
+func (c Counter) Inc() {
+	c.Add(1)
+}
+
+// Add increases the counter; negative deltas are ignored.
+func (c Counter) Add(delta float64) {
+	if delta < 0 {
+		return
+	}
+	c.s.mu.Lock()
+	c.s.value += delta
+	c.s.mu.Unlock()
+}
+
+type GaugeVec struct {
+	f *family
+}
+
+func (v *GaugeVec) With(values ...string) Gauge {
+	return Gauge{v.f.get(values)}
+}
+
+type Gauge struct {
+	s *series
+}
+
+func (g Gauge) Set(value float64) {
+	g.s.mu.Lock()
+	g.s.value = value
+	g.s.mu.Unlock()
+}
+
+func (g Gauge) Add(delta float64) {
+	g.s.mu.Lock()
+	g.s.value += delta
+	g.s.mu.Unlock()
+}
+
+func (g Gauge) Inc() {
+	g.Add(1)
+}
+
+func (g Gauge) Dec() {
+	g.Add(-1)
+}
+
+type HistogramVec struct {
+	f *family
+}
+
+func (v *HistogramVec) With(values ...string) Histogram {
+	return Histogram{v.f.get(values), v.f.buckets}
+}
+
+type Histogram struct {
+	s       *series
+	buckets []float64
+}
+
+func (h Histogram) Observe(value float64) {
+	h.s.mu.Lock()
+	defer h.s.mu.Unlock()
+	if h.s.counts == nil {
+		h.s.counts = make([]uint64, len(h.buckets))
+	}
+	for i, upper := range h.buckets {
+		if value <= upper {
+			h.s.counts[i]++
+		}
+	}
+	h.s.value += value
+	h.s.count++
+}
+
+type family struct {
+	name    string
+	help    string
+	kind    string
+	buckets []float64
+	labels  []string
+	fn      func() float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	values []string
+
+	mu     sync.Mutex
+	value  float64
+	count  uint64
+	counts []uint64
+}
+
+func (f *family) get(values []string) *series {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %v wants %d label values, got %d", f.name, len(f.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{values: append([]string{}, values...)}
+		f.series[key] = s
+	}
+	return s
+}
+
+func (f *family) write(w *countingWriter) {
+	f.mu.Lock()
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	all := make([]*series, len(keys))
+	for i, key := range keys {
+		all[i] = f.series[key]
+	}
+	f.mu.Unlock()
+
+	w.printf("# HELP %v %v\n", f.name, escapeHelp(f.help))
+	w.printf("# TYPE %v %v\n", f.name, f.kind)
+	if f.fn != nil {
+		w.printf("%v %v\n", f.name, formatFloat(f.fn()))
+		return
+	}
+	for _, s := range all {
+		s.mu.Lock()
+		if f.kind != "histogram" {
+			w.printf("%v%v %v\n", f.name, f.labelSet(s.values, "", ""), formatFloat(s.value))
+			s.mu.Unlock()
+			continue
+		}
+		for i, upper := range f.buckets {
+			var count uint64
+			if s.counts != nil {
+				count = s.counts[i]
+			}
+			w.printf("%v_bucket%v %d\n", f.name, f.labelSet(s.values, "le", formatFloat(upper)), count)
+		}
+		w.printf("%v_bucket%v %d\n", f.name, f.labelSet(s.values, "le", "+Inf"), s.count)
+		w.printf("%v_sum%v %v\n", f.name, f.labelSet(s.values, "", ""), formatFloat(s.value))
+		w.printf("%v_count%v %d\n", f.name, f.labelSet(s.values, "", ""), s.count)
+		s.mu.Unlock()
+	}
+}
+
+// labelSet renders {a="1",b="2"}, adding extra=extraValue when extra is set.
+func (f *family) labelSet(values []string, extra string, extraValue string) string {
+	if len(values) == 0 && extra == "" {
+		return ""
+	}
+	pairs := make([]string, 0, len(values)+1)
+	for i, label := range f.labels {
+		pairs = append(pairs, label+`="`+escapeLabel(values[i])+`"`)
+	}
+	if extra != "" {
+		pairs = append(pairs, extra+`="`+extraValue+`"`)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (w *countingWriter) printf(format string, args ...interface{}) {
+	if w.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(w.w, format, args...)
+	w.n += int64(n)
+	w.err = err
+}
@@ -0,0 +1,161 @@
+//go:build unit
+
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func scrape(t *testing.T, r *Registry) string {
+	var out strings.Builder
+	_, err := r.WriteTo(&out)
+	assert.NoError(t, err)
+	return out.String()
+}
+
+func TestCounter(t *testing.T) {
+	t.Run("TestCounterShouldWriteSeriesSortedByLabels", func(t *testing.T) {
+		// Arrange
+		r := NewRegistry()
+		requests := r.Counter("requests_total", "Requests served.", "method", "status")
+
+		// Act
+		requests.With("POST", "201").Inc()
+		requests.With("GET", "200").Add(2)
+		requests.With("GET", "200").Add(-5)
+
+		// Assert
+		assert.Equal(t, `# HELP requests_total Requests served.
+# TYPE requests_total counter
+requests_total{method="GET",status="200"} 2
+requests_total{method="POST",status="201"} 1
+`, scrape(t, r))
+	})
+
+	t.Run("TestCounterShouldEscapeLabelValues", func(t *testing.T) {
+		// Arrange
+		r := NewRegistry()
+		errors := r.Counter("errors_total", "Errors.", "reason")
+
+		// Act
+		errors.With("say \"hi\"\n").Inc()
+
+		// Assert
+		assert.Contains(t, scrape(t, r), `errors_total{reason="say \"hi\"\n"} 1`)
+	})
+
+	t.Run("TestCounterShouldPanicOnWrongLabelCount", func(t *testing.T) {
+		// Arrange
+		r := NewRegistry()
+		requests := r.Counter("requests_total", "Requests served.", "method")
+
+		// Act & Assert
+		assert.Panics(t, func() { requests.With("GET", "200") })
+	})
+}
+
+func TestGauge(t *testing.T) {
+	t.Run("TestGaugeShouldWriteWithoutLabels", func(t *testing.T) {
+		// Arrange
+		r := NewRegistry()
+		inFlight := r.Gauge("in_flight", "Requests in flight.")
+
+		// Act
+		inFlight.With().Inc()
+		inFlight.With().Inc()
+		inFlight.With().Dec()
+
+		// Assert
+		assert.Contains(t, scrape(t, r), "\nin_flight 1\n")
+	})
+
+	t.Run("TestGaugeShouldBeSampledOnScrape", func(t *testing.T) {
+		// Arrange
+		r := NewRegistry()
+		books := r.Gauge("books", "Books in the catalogue.")
+		count := 0
+		r.OnScrape(func() {
+			count++
+			books.With().Set(float64(count * 10))
+		})
+
+		// Act
+		scrape(t, r)
+		out := scrape(t, r)
+
+		// Assert
+		assert.Contains(t, out, "\nbooks 20\n")
+	})
+}
+
+func TestGaugeFunc(t *testing.T) {
+	t.Run("TestGaugeFuncShouldCallFuncOnEveryWrite", func(t *testing.T) {
+		// Arrange
+		r := NewRegistry()
+		open := 1.0
+		r.GaugeFunc("open_connections", "Open connections.", func() float64 { return open })
+		r.CounterFunc("waits_total", "Waits.", func() float64 { return 7 })
+
+		// Act
+		open = 4
+		out := scrape(t, r)
+
+		// Assert
+		assert.Contains(t, out, "# TYPE open_connections gauge\nopen_connections 4\n")
+		assert.Contains(t, out, "# TYPE waits_total counter\nwaits_total 7\n")
+	})
+}
+
+func TestHistogram(t *testing.T) {
+	t.Run("TestHistogramShouldWriteCumulativeBuckets", func(t *testing.T) {
+		// Arrange
+		r := NewRegistry()
+		latency := r.Histogram("latency_seconds", "Latency.", []float64{0.1, 1}, "route")
+
+		// Act
+		latency.With("/books").Observe(0.05)
+		latency.With("/books").Observe(0.5)
+		latency.With("/books").Observe(3)
+
+		// Assert
+		assert.Equal(t, `# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/books",le="0.1"} 1
+latency_seconds_bucket{route="/books",le="1"} 2
+latency_seconds_bucket{route="/books",le="+Inf"} 3
+latency_seconds_sum{route="/books"} 3.55
+latency_seconds_count{route="/books"} 3
+`, scrape(t, r))
+	})
+}
+
+func TestRegistry(t *testing.T) {
+	t.Run("TestRegistryShouldPanicOnDuplicateName", func(t *testing.T) {
+		// Arrange
+		r := NewRegistry()
+		r.Gauge("books", "Books.")
+
+		// Act & Assert
+		assert.Panics(t, func() { r.Counter("books", "Books.") })
+	})
+
+	t.Run("TestRegistryShouldServeTextFormat", func(t *testing.T) {
+		// Arrange
+		r := NewRegistry()
+		r.Gauge("books", "Books.").With().Set(3)
+		rec := httptest.NewRecorder()
+
+		// Act
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, contentType, rec.Header().Get("Content-Type"))
+		assert.Contains(t, rec.Body.String(), "\nbooks 3\n")
+	})
+}
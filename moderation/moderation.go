@@ -0,0 +1,120 @@
+// Package moderation screens user-submitted text, such as book reviews,
+// before it is shown to other customers.
+package moderation
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	ReasonProfanity = "profanity"
+	ReasonSpam      = "spam"
+)
+
+// Verdict is the outcome of screening a piece of text. Reason is a short
+// machine-readable label, empty when the text is not flagged.
+type Verdict struct {
+	Flagged bool
+	Reason  string
+}
+
+// Filter screens the title and body of a submission. Implementations must be
+// safe for concurrent use.
+type Filter interface {
+	Check(title, body string) Verdict
+}
+
+// Chain runs its filters in order and returns the first flag raised.
+type Chain []Filter
+
+func (c Chain) Check(title, body string) Verdict {
+	for _, f := range c {
+		if v := f.Check(title, body); v.Flagged {
+			return v
+		}
+	}
+	return Verdict{}
+}
+
+// WordFilter flags text containing any of its words. Matching is on whole
+// words and ignores case, so "class" does not match "ass".
+type WordFilter struct {
+	reason string
+	words  map[string]bool
+}
+
+func NewWordFilter(reason string, words []string) WordFilter {
+	f := WordFilter{reason: reason, words: map[string]bool{}}
+	for _, w := range words {
+		w = strings.ToLower(strings.TrimSpace(w))
+		if w != "" {
+			f.words[w] = true
+		}
+	}
+	return f
+}
+
+func (f WordFilter) Check(title, body string) Verdict {
+	if len(f.words) == 0 {
+		return Verdict{}
+	}
+	for _, text := range []string{title, body} {
+		for _, w := range strings.FieldsFunc(strings.ToLower(text), isSeparator) {
+			if f.words[w] {
+				return Verdict{Flagged: true, Reason: f.reason}
+			}
+		}
+	}
+	return Verdict{}
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '\''
+}
+
+// SpamFilter flags the usual shapes of spam: many links, or long runs of one
+// repeated character. A zero limit disables that check.
+type SpamFilter struct {
+	MaxLinks  int
+	MaxRepeat int
+}
+
+func (f SpamFilter) Check(title, body string) Verdict {
+	text := strings.ToLower(title + "\n" + body)
+	if f.MaxLinks > 0 {
+		links := strings.Count(text, "http://") + strings.Count(text, "https://") + strings.Count(text, "www.")
+		if links > f.MaxLinks {
+			return Verdict{Flagged: true, Reason: ReasonSpam}
+		}
+	}
+	if f.MaxRepeat > 0 && longestRun(text) > f.MaxRepeat {
+		return Verdict{Flagged: true, Reason: ReasonSpam}
+	}
+	return Verdict{}
+}
+
+func longestRun(text string) int {
+	longest, run := 0, 0
+	var prev rune
+	for i, r := range text {
+		if i > 0 && r == prev && !unicode.IsSpace(r) {
+			run++
+		} else {
+			run = 1
+		}
+		if run > longest {
+			longest = run
+		}
+		prev = r
+	}
+	return longest
+}
+
+// NewDefaultFilter screens for the given blocked words and for spam.
+func NewDefaultFilter(blockedWords []string) Filter {
+	return Chain{
+		NewWordFilter(ReasonProfanity, blockedWords),
+		SpamFilter{MaxLinks: 2, MaxRepeat: 12},
+	}
+}
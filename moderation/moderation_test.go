@@ -0,0 +1,85 @@
+//go:build unit
+
+package moderation
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWordFilter(t *testing.T) {
+	t.Run("TestWordFilterShouldFlagWholeWordsIgnoringCase", func(t *testing.T) {
+		// Arrange
+		filter := NewWordFilter(ReasonProfanity, []string{"darn", " Heck "})
+
+		// Act
+		title := filter.Check("Well, HECK!", "")
+		body := filter.Check("", "A darn good read.")
+
+		// Assert
+		assert.Equal(t, Verdict{Flagged: true, Reason: ReasonProfanity}, title)
+		assert.Equal(t, Verdict{Flagged: true, Reason: ReasonProfanity}, body)
+	})
+
+	t.Run("TestWordFilterShouldNotFlagPartialWords", func(t *testing.T) {
+		// Arrange
+		filter := NewWordFilter(ReasonProfanity, []string{"heck"})
+
+		// Act
+		verdict := filter.Check("Checked", "It was checked twice.")
+
+		// Assert
+		assert.False(t, verdict.Flagged)
+	})
+
+	t.Run("TestWordFilterShouldAllowEverythingWithoutWords", func(t *testing.T) {
+		// Act
+		verdict := NewWordFilter(ReasonProfanity, nil).Check("anything", "at all")
+
+		// Assert
+		assert.False(t, verdict.Flagged)
+	})
+}
+
+func TestSpamFilter(t *testing.T) {
+	filter := SpamFilter{MaxLinks: 2, MaxRepeat: 5}
+
+	t.Run("TestSpamFilterShouldFlagTooManyLinks", func(t *testing.T) {
+		// Act
+		verdict := filter.Check("Deals", "https://a.example http://b.example www.c.example")
+
+		// Assert
+		assert.Equal(t, Verdict{Flagged: true, Reason: ReasonSpam}, verdict)
+	})
+
+	t.Run("TestSpamFilterShouldFlagRepeatedCharacters", func(t *testing.T) {
+		// Act
+		verdict := filter.Check("", "Great"+strings.Repeat("!", 6))
+
+		// Assert
+		assert.True(t, verdict.Flagged)
+	})
+
+	t.Run("TestSpamFilterShouldAllowOrdinaryText", func(t *testing.T) {
+		// Act
+		verdict := filter.Check("Lovely", "See https://example.com for the sequel.    Really good!!")
+
+		// Assert
+		assert.False(t, verdict.Flagged)
+	})
+}
+
+func TestChain(t *testing.T) {
+	t.Run("TestChainShouldReturnFirstFlag", func(t *testing.T) {
+		// Arrange
+		chain := NewDefaultFilter([]string{"darn"})
+
+		// Act
+		verdict := chain.Check("darn", strings.Repeat("a", 20))
+
+		// Assert
+		assert.Equal(t, ReasonProfanity, verdict.Reason)
+	})
+}
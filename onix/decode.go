@@ -0,0 +1,82 @@
+package onix
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// Decoder reads an ONIX 3.0 message one Product at a time so that large
+// feeds never need to be held in memory.
+type Decoder struct {
+	dec    *xml.Decoder
+	header *Header
+	root   bool
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{dec: xml.NewDecoder(r)}
+}
+
+// Header returns the message header once it has been read, or nil when the
+// feed has no header or Next has not reached it yet.
+func (d *Decoder) Header() *Header {
+	return d.header
+}
+
+// Next returns the next Product in the feed, or io.EOF when there are no more.
+func (d *Decoder) Next() (*Product, error) {
+	for {
+		tok, err := d.dec.Token()
+		if err != nil {
+			if err == io.EOF && !d.root {
+				return nil, fmt.Errorf("error onix message not found")
+			}
+			return nil, err
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "ONIXMessage":
+			if err := checkRelease(start); err != nil {
+				return nil, err
+			}
+			d.root = true
+		case "Header":
+			header := &Header{}
+			if err := d.dec.DecodeElement(header, &start); err != nil {
+				return nil, fmt.Errorf("error decode onix header : %w", err)
+			}
+			d.header = header
+		case "Product":
+			if !d.root {
+				return nil, fmt.Errorf("error onix product outside ONIXMessage")
+			}
+			product := &Product{}
+			if err := d.dec.DecodeElement(product, &start); err != nil {
+				return nil, fmt.Errorf("error decode onix product : %w", err)
+			}
+			return product, nil
+		default:
+			if !d.root {
+				return nil, fmt.Errorf("error unexpected root element %q", start.Name.Local)
+			}
+			if err := d.dec.Skip(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func checkRelease(start xml.StartElement) error {
+	for _, attr := range start.Attr {
+		if attr.Name.Local == "release" && attr.Value != Release {
+			return fmt.Errorf("error unsupported onix release %q", attr.Value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package onix
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// Encoder writes an ONIX 3.0 message one Product at a time. Callers must call
+// Close to terminate the message.
+type Encoder struct {
+	w       io.Writer
+	enc     *xml.Encoder
+	started bool
+}
+
+func NewEncoder(w io.Writer) *Encoder {
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return &Encoder{w: w, enc: enc}
+}
+
+// WriteHeader opens the ONIXMessage element and writes its Header. It must be
+// called once, before any Product is encoded.
+func (e *Encoder) WriteHeader(h Header) error {
+	if e.started {
+		return fmt.Errorf("error onix header already written")
+	}
+	if _, err := io.WriteString(e.w, xml.Header); err != nil {
+		return err
+	}
+	if err := e.enc.EncodeToken(rootElement()); err != nil {
+		return err
+	}
+	e.started = true
+	h.XMLName = xml.Name{}
+	return e.enc.Encode(h)
+}
+
+func (e *Encoder) Encode(p Product) error {
+	if !e.started {
+		return fmt.Errorf("error onix header not written")
+	}
+	p.XMLName = xml.Name{}
+	return e.enc.Encode(p)
+}
+
+func (e *Encoder) Close() error {
+	if !e.started {
+		return fmt.Errorf("error onix header not written")
+	}
+	if err := e.enc.EncodeToken(rootElement().End()); err != nil {
+		return err
+	}
+	return e.enc.Flush()
+}
+
+func rootElement() xml.StartElement {
+	return xml.StartElement{
+		Name: xml.Name{Local: "ONIXMessage"},
+		Attr: []xml.Attr{
+			{Name: xml.Name{Local: "release"}, Value: Release},
+			{Name: xml.Name{Local: "xmlns"}, Value: Namespace},
+		},
+	}
+}
@@ -0,0 +1,323 @@
+package onix
+
+import (
+	"encoding/xml"
+	"sort"
+)
+
+const (
+	Namespace = "http://ns.editeur.org/onix/3.0/reference"
+	Release   = "3.0"
+)
+
+// Code list values used by the bookstore catalog (ONIX code lists 1, 5, 15, 17, 45, 58, 163).
+const (
+	NotificationConfirmed = "03"
+	NotificationDelete    = "05"
+
+	ProductIDTypeProprietary = "01"
+	ProductIDTypeISBN10      = "02"
+	ProductIDTypeISBN13      = "15"
+
+	ProductCompositionSingle = "00"
+	ProductFormUndefined     = "00"
+
+	TitleTypeDistinctive = "01"
+	TitleElementProduct  = "01"
+
+	ContributorRoleAuthor = "A01"
+
+	PublishingRolePublisher = "01"
+	PublishingDatePublished = "01"
+
+	TextTypeDescription = "03"
+	ContentAudienceAny  = "00"
+
+	LanguageRoleText = "01"
+
+	ExtentTypeMainContent = "00"
+	ExtentUnitPages       = "03"
+
+	SubjectSchemeBISAC = "10"
+	SubjectSchemeThema = "93"
+
+	PriceTypeRRPExcludingTax = "01"
+	SupplierRolePublisher    = "01"
+	AvailabilityInStock      = "21"
+	AvailabilityOutOfStock   = "31"
+)
+
+type Header struct {
+	XMLName      xml.Name `xml:"Header"`
+	Sender       Sender   `xml:"Sender"`
+	SentDateTime string   `xml:"SentDateTime"`
+}
+
+type Sender struct {
+	SenderName string `xml:"SenderName"`
+}
+
+type Product struct {
+	XMLName            xml.Name            `xml:"Product"`
+	RecordReference    string              `xml:"RecordReference"`
+	NotificationType   string              `xml:"NotificationType"`
+	ProductIdentifiers []ProductIdentifier `xml:"ProductIdentifier"`
+	DescriptiveDetail  DescriptiveDetail   `xml:"DescriptiveDetail"`
+	CollateralDetail   *CollateralDetail   `xml:"CollateralDetail,omitempty"`
+	PublishingDetail   *PublishingDetail   `xml:"PublishingDetail,omitempty"`
+	ProductSupply      *ProductSupply      `xml:"ProductSupply,omitempty"`
+}
+
+type ProductIdentifier struct {
+	ProductIDType string `xml:"ProductIDType"`
+	IDTypeName    string `xml:"IDTypeName,omitempty"`
+	IDValue       string `xml:"IDValue"`
+}
+
+type DescriptiveDetail struct {
+	ProductComposition string        `xml:"ProductComposition"`
+	ProductForm        string        `xml:"ProductForm"`
+	TitleDetails       []TitleDetail `xml:"TitleDetail"`
+	Contributors       []Contributor `xml:"Contributor"`
+	Languages          []Language    `xml:"Language"`
+	Extents            []Extent      `xml:"Extent"`
+	Subjects           []Subject     `xml:"Subject"`
+}
+
+type TitleDetail struct {
+	TitleType     string         `xml:"TitleType"`
+	TitleElements []TitleElement `xml:"TitleElement"`
+}
+
+type TitleElement struct {
+	TitleElementLevel string `xml:"TitleElementLevel"`
+	TitleText         string `xml:"TitleText,omitempty"`
+	Subtitle          string `xml:"Subtitle,omitempty"`
+}
+
+type Contributor struct {
+	SequenceNumber     int    `xml:"SequenceNumber,omitempty"`
+	ContributorRole    string `xml:"ContributorRole"`
+	PersonName         string `xml:"PersonName,omitempty"`
+	PersonNameInverted string `xml:"PersonNameInverted,omitempty"`
+	CorporateName      string `xml:"CorporateName,omitempty"`
+}
+
+type Language struct {
+	LanguageRole string `xml:"LanguageRole"`
+	LanguageCode string `xml:"LanguageCode"`
+}
+
+type Extent struct {
+	ExtentType  string `xml:"ExtentType"`
+	ExtentValue string `xml:"ExtentValue"`
+	ExtentUnit  string `xml:"ExtentUnit"`
+}
+
+type Subject struct {
+	SubjectSchemeIdentifier string `xml:"SubjectSchemeIdentifier"`
+	SubjectSchemeVersion    string `xml:"SubjectSchemeVersion,omitempty"`
+	SubjectCode             string `xml:"SubjectCode,omitempty"`
+	SubjectHeadingText      string `xml:"SubjectHeadingText,omitempty"`
+}
+
+type CollateralDetail struct {
+	TextContents []TextContent `xml:"TextContent"`
+}
+
+type TextContent struct {
+	TextType        string `xml:"TextType"`
+	ContentAudience string `xml:"ContentAudience"`
+	Text            string `xml:"Text"`
+}
+
+type PublishingDetail struct {
+	Publishers      []Publisher      `xml:"Publisher"`
+	PublishingDates []PublishingDate `xml:"PublishingDate"`
+}
+
+type Publisher struct {
+	PublishingRole string `xml:"PublishingRole"`
+	PublisherName  string `xml:"PublisherName"`
+}
+
+type PublishingDate struct {
+	PublishingDateRole string `xml:"PublishingDateRole"`
+	Date               string `xml:"Date"`
+}
+
+type ProductSupply struct {
+	SupplyDetails []SupplyDetail `xml:"SupplyDetail"`
+}
+
+type SupplyDetail struct {
+	Supplier            Supplier `xml:"Supplier"`
+	ProductAvailability string   `xml:"ProductAvailability"`
+	Stock               *Stock   `xml:"Stock,omitempty"`
+	Prices              []Price  `xml:"Price"`
+}
+
+type Supplier struct {
+	SupplierRole string `xml:"SupplierRole"`
+	SupplierName string `xml:"SupplierName"`
+}
+
+type Stock struct {
+	OnHand int64 `xml:"OnHand"`
+}
+
+type Price struct {
+	PriceType    string `xml:"PriceType"`
+	PriceAmount  string `xml:"PriceAmount"`
+	CurrencyCode string `xml:"CurrencyCode,omitempty"`
+}
+
+// Isbn returns the ISBN-13 of the product, falling back to the ISBN-10.
+func (p Product) Isbn() string {
+	isbn10 := ""
+	for _, id := range p.ProductIdentifiers {
+		switch id.ProductIDType {
+		case ProductIDTypeISBN13:
+			return id.IDValue
+		case ProductIDTypeISBN10:
+			isbn10 = id.IDValue
+		}
+	}
+	return isbn10
+}
+
+// Title returns the distinctive title text and subtitle of the product.
+func (p Product) Title() (string, string) {
+	for _, td := range p.DescriptiveDetail.TitleDetails {
+		if td.TitleType != TitleTypeDistinctive {
+			continue
+		}
+		for _, te := range td.TitleElements {
+			if te.TitleElementLevel == TitleElementProduct {
+				return te.TitleText, te.Subtitle
+			}
+		}
+	}
+	return "", ""
+}
+
+// Authors returns the names of the A01 contributors in sequence order.
+func (p Product) Authors() []string {
+	authors := []string{}
+	for _, ct := range sortedContributors(p.DescriptiveDetail.Contributors) {
+		if ct.ContributorRole != ContributorRoleAuthor {
+			continue
+		}
+		switch {
+		case ct.PersonName != "":
+			authors = append(authors, ct.PersonName)
+		case ct.PersonNameInverted != "":
+			authors = append(authors, ct.PersonNameInverted)
+		case ct.CorporateName != "":
+			authors = append(authors, ct.CorporateName)
+		}
+	}
+	return authors
+}
+
+func (p Product) Publisher() string {
+	if p.PublishingDetail == nil {
+		return ""
+	}
+	for _, pb := range p.PublishingDetail.Publishers {
+		if pb.PublishingRole == PublishingRolePublisher {
+			return pb.PublisherName
+		}
+	}
+	return ""
+}
+
+func (p Product) PublicationDate() string {
+	if p.PublishingDetail == nil {
+		return ""
+	}
+	for _, pd := range p.PublishingDetail.PublishingDates {
+		if pd.PublishingDateRole == PublishingDatePublished {
+			return pd.Date
+		}
+	}
+	return ""
+}
+
+func (p Product) Language() string {
+	for _, l := range p.DescriptiveDetail.Languages {
+		if l.LanguageRole == LanguageRoleText {
+			return l.LanguageCode
+		}
+	}
+	return ""
+}
+
+// PageCount returns the main content extent in pages, or "" when not given.
+func (p Product) PageCount() string {
+	for _, ex := range p.DescriptiveDetail.Extents {
+		if ex.ExtentType == ExtentTypeMainContent && ex.ExtentUnit == ExtentUnitPages {
+			return ex.ExtentValue
+		}
+	}
+	return ""
+}
+
+// Subjects returns the coded subjects of the product in the given scheme.
+func (p Product) Subjects(scheme string) []Subject {
+	subjects := []Subject{}
+	for _, sb := range p.DescriptiveDetail.Subjects {
+		if sb.SubjectSchemeIdentifier == scheme && sb.SubjectCode != "" {
+			subjects = append(subjects, sb)
+		}
+	}
+	return subjects
+}
+
+func (p Product) Description() string {
+	if p.CollateralDetail == nil {
+		return ""
+	}
+	for _, tc := range p.CollateralDetail.TextContents {
+		if tc.TextType == TextTypeDescription {
+			return tc.Text
+		}
+	}
+	return ""
+}
+
+// Price returns the first price of the first supply detail.
+func (p Product) Price() (Price, bool) {
+	if p.ProductSupply == nil {
+		return Price{}, false
+	}
+	for _, sd := range p.ProductSupply.SupplyDetails {
+		if len(sd.Prices) > 0 {
+			return sd.Prices[0], true
+		}
+	}
+	return Price{}, false
+}
+
+// OnHand returns the stock on hand summed over all supply details.
+func (p Product) OnHand() int64 {
+	if p.ProductSupply == nil {
+		return 0
+	}
+	var total int64
+	for _, sd := range p.ProductSupply.SupplyDetails {
+		if sd.Stock != nil {
+			total += sd.Stock.OnHand
+		}
+	}
+	return total
+}
+
+func sortedContributors(cts []Contributor) []Contributor {
+	sorted := make([]Contributor, len(cts))
+	copy(sorted, cts)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].SequenceNumber < sorted[j].SequenceNumber
+	})
+	return sorted
+}
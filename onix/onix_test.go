@@ -0,0 +1,132 @@
+//go:build unit
+
+package onix
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func decodeAll(t *testing.T, r io.Reader) (*Header, []Product) {
+	dec := NewDecoder(r)
+	products := []Product{}
+	for {
+		p, err := dec.Next()
+		if err == io.EOF {
+			break
+		}
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		products = append(products, *p)
+	}
+	return dec.Header(), products
+}
+
+func TestDecoder(t *testing.T) {
+	t.Run("TestDecodeSampleShouldReturnProducts", func(t *testing.T) {
+		// Arrange
+		file, err := os.Open("testdata/sample.xml")
+		assert.NoError(t, err)
+		defer file.Close()
+
+		// Act
+		header, products := decodeAll(t, file)
+
+		// Assert
+		assert.Equal(t, "Sample Distribution Co.", header.Sender.SenderName)
+		assert.Len(t, products, 2)
+
+		p := products[0]
+		title, subtitle := p.Title()
+		price, ok := p.Price()
+		assert.Equal(t, "9780000000017", p.Isbn())
+		assert.Equal(t, "The Go Bookstore", title)
+		assert.Equal(t, "Building Web Services with Echo", subtitle)
+		assert.Equal(t, []string{"John Author", "Jane Writer"}, p.Authors())
+		assert.Equal(t, "Sample Press", p.Publisher())
+		assert.Equal(t, "20230115", p.PublicationDate())
+		assert.Equal(t, "eng", p.Language())
+		assert.Equal(t, "320", p.PageCount())
+		assert.Len(t, p.Subjects(SubjectSchemeBISAC), 1)
+		assert.Equal(t, "UMX", p.Subjects(SubjectSchemeThema)[0].SubjectCode)
+		assert.Equal(t, int64(25), p.OnHand())
+		assert.True(t, ok)
+		assert.Equal(t, "450.00", price.PriceAmount)
+		assert.Equal(t, "THB", price.CurrencyCode)
+
+		assert.Equal(t, NotificationDelete, products[1].NotificationType)
+	})
+
+	t.Run("TestDecodeUnsupportedReleaseShouldReturnError", func(t *testing.T) {
+		// Arrange
+		feed := `<ONIXMessage release="2.1"><Product></Product></ONIXMessage>`
+
+		// Act
+		p, err := NewDecoder(strings.NewReader(feed)).Next()
+
+		// Assert
+		assert.Error(t, err)
+		assert.Nil(t, p)
+	})
+
+	t.Run("TestDecodeWrongRootShouldReturnError", func(t *testing.T) {
+		// Arrange
+		feed := `<rss><Product></Product></rss>`
+
+		// Act
+		p, err := NewDecoder(strings.NewReader(feed)).Next()
+
+		// Assert
+		assert.Error(t, err)
+		assert.Nil(t, p)
+	})
+}
+
+func TestRoundTrip(t *testing.T) {
+	t.Run("TestEncodeDecodedSampleShouldRoundTrip", func(t *testing.T) {
+		// Arrange
+		file, err := os.Open("testdata/sample.xml")
+		assert.NoError(t, err)
+		defer file.Close()
+		header, products := decodeAll(t, file)
+
+		// Act
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf)
+		assert.NoError(t, enc.WriteHeader(*header))
+		for _, p := range products {
+			assert.NoError(t, enc.Encode(p))
+		}
+		assert.NoError(t, enc.Close())
+		header2, products2 := decodeAll(t, bytes.NewReader(buf.Bytes()))
+
+		// Assert
+		assert.Contains(t, buf.String(), `<ONIXMessage release="3.0" xmlns="`+Namespace+`">`)
+		assert.Equal(t, header.Sender, header2.Sender)
+		assert.Equal(t, header.SentDateTime, header2.SentDateTime)
+		assert.Len(t, products2, len(products))
+		for i := range products {
+			assert.Equal(t, products[i].XMLName.Local, products2[i].XMLName.Local)
+			products[i].XMLName, products2[i].XMLName = xml.Name{}, xml.Name{}
+			assert.Equal(t, products[i], products2[i])
+		}
+	})
+
+	t.Run("TestEncodeWithoutHeaderShouldReturnError", func(t *testing.T) {
+		// Arrange
+		enc := NewEncoder(io.Discard)
+
+		// Act
+		err := enc.Encode(Product{})
+
+		// Assert
+		assert.Error(t, err)
+	})
+}
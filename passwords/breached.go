@@ -0,0 +1,65 @@
+package passwords
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// hashPrefixLen is how many hex digits of the SHA-1 name a file, as in the
+// k-anonymity range API of Have I Been Pwned.
+const hashPrefixLen = 5
+
+// BreachedList looks passwords up in a directory of SHA-1 hashes of
+// breached passwords, split by the first five hex digits of the hash: file
+// 5BAA6.txt holds lines like 1E4C9B93F3F0682250B6CF8331B7EE68FD8:3861493
+// for every hash starting 5BAA6. Only the one file a password's prefix
+// names is read, and a missing file means no hash has that prefix.
+type BreachedList struct {
+	dir string
+}
+
+func NewBreachedList(dir string) (*BreachedList, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error open breached password list : %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("error breached password list %v is not a directory", dir)
+	}
+	return &BreachedList{dir}, nil
+}
+
+// Contains reports whether the hash of password is in the list.
+func (l *BreachedList) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:hashPrefixLen], hash[hashPrefixLen:]
+
+	f, err := os.Open(filepath.Join(l.dir, prefix+".txt"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("error read breached password list : %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), ":")
+		if strings.EqualFold(strings.TrimSpace(line), suffix) {
+			return true, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("error read breached password list : %w", err)
+	}
+	return false, nil
+}
@@ -0,0 +1,134 @@
+// Package passwords decides whether a password is good enough for an
+// account, before it is hashed and stored.
+package passwords
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/paquesqueue/bookstore/common"
+)
+
+// Reasons a password is rejected, as short machine-readable labels.
+const (
+	ReasonTooShort = "too_short"
+	ReasonTooLong  = "too_long"
+	ReasonClasses  = "too_few_character_classes"
+	ReasonSimilar  = "similar_to_account"
+	ReasonBreached = "breached"
+
+	// similarMinLen keeps short usernames, like "al", from ruling out
+	// every password that happens to contain them.
+	similarMinLen = 3
+)
+
+// Account is who a password is for.
+type Account struct {
+	Username string
+	Email    string
+}
+
+// Rejection lists every reason a password was refused.
+type Rejection struct {
+	Reasons []string
+}
+
+func (r *Rejection) Error() string {
+	return "error password rejected : " + strings.Join(r.Reasons, ", ")
+}
+
+// Policy checks the length and character classes of a password, that it
+// does not contain the account's username or email, and that it is not in
+// the breached list. The zero value accepts any password.
+type Policy struct {
+	MinLength int
+	MaxLength int
+	// RequireClasses is how many of lowercase, uppercase, digits and
+	// symbols a password must mix.
+	RequireClasses int
+	RejectSimilar  bool
+	Breached       *BreachedList
+}
+
+// InitPolicy returns the policy of c, opening its breached list if set.
+func InitPolicy(c common.PasswordConfig) (Policy, error) {
+	p := Policy{
+		MinLength:      c.MinLength,
+		MaxLength:      c.MaxLength,
+		RequireClasses: c.RequireClasses,
+		RejectSimilar:  c.RejectSimilar,
+	}
+	if c.BreachedDir != "" {
+		breached, err := NewBreachedList(c.BreachedDir)
+		if err != nil {
+			return Policy{}, err
+		}
+		p.Breached = breached
+	}
+	return p, nil
+}
+
+// Check returns a *Rejection when password breaks the policy. Other errors
+// mean the breached list could not be read.
+func (p Policy) Check(password string, account Account) error {
+	var reasons []string
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		reasons = append(reasons, ReasonTooShort)
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		reasons = append(reasons, ReasonTooLong)
+	}
+	if classes(password) < p.RequireClasses {
+		reasons = append(reasons, ReasonClasses)
+	}
+	if p.RejectSimilar && similar(password, account) {
+		reasons = append(reasons, ReasonSimilar)
+	}
+	if p.Breached != nil {
+		breached, err := p.Breached.Contains(password)
+		if err != nil {
+			return err
+		}
+		if breached {
+			reasons = append(reasons, ReasonBreached)
+		}
+	}
+
+	if len(reasons) > 0 {
+		return &Rejection{reasons}
+	}
+	return nil
+}
+
+func classes(password string) int {
+	var lower, upper, digit, symbol int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+	return lower + upper + digit + symbol
+}
+
+// similar reports whether password contains the username, the email or the
+// part of the email before the @, ignoring case.
+func similar(password string, account Account) bool {
+	password = strings.ToLower(password)
+	email := strings.ToLower(account.Email)
+	local, _, _ := strings.Cut(email, "@")
+	for _, s := range []string{strings.ToLower(account.Username), email, local} {
+		if utf8.RuneCountInString(s) >= similarMinLen && strings.Contains(password, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+//go:build unit
+
+package passwords
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paquesqueue/bookstore/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy(t *testing.T) {
+	t.Run("TestPolicyShouldAcceptGoodPassword", func(t *testing.T) {
+		// Arrange
+		policy := Policy{MinLength: 8, MaxLength: 64, RequireClasses: 3, RejectSimilar: true}
+
+		// Act
+		err := policy.Check("Correct horse 9", Account{Username: "tester", Email: "tester@email.com"})
+
+		// Assert
+		assert.NoError(t, err)
+	})
+
+	t.Run("TestPolicyShouldListEveryReason", func(t *testing.T) {
+		// Arrange
+		policy := Policy{MinLength: 12, RequireClasses: 2, RejectSimilar: true}
+
+		// Act
+		err := policy.Check("xtester", Account{Username: "Tester"})
+
+		// Assert
+		assert.Equal(t, &Rejection{[]string{ReasonTooShort, ReasonClasses, ReasonSimilar}}, err)
+	})
+
+	t.Run("TestPolicyShouldCountCharactersNotBytes", func(t *testing.T) {
+		// Arrange
+		policy := Policy{MinLength: 4, MaxLength: 4}
+
+		// Act
+		short := policy.Check("ราม", Account{})
+		fits := policy.Check("รามา", Account{})
+
+		// Assert
+		assert.Equal(t, &Rejection{[]string{ReasonTooShort}}, short)
+		assert.NoError(t, fits)
+	})
+
+	t.Run("TestPolicyShouldRejectTooLong", func(t *testing.T) {
+		// Act
+		err := Policy{MaxLength: 3}.Check("abcd", Account{})
+
+		// Assert
+		assert.Equal(t, &Rejection{[]string{ReasonTooLong}}, err)
+	})
+
+	t.Run("TestPolicyShouldRejectEmailParts", func(t *testing.T) {
+		// Arrange
+		policy := Policy{RejectSimilar: true}
+		account := Account{Username: "al", Email: "Reader@Email.com"}
+
+		// Act
+		local := policy.Check("my-reader-pass", account)
+		short := policy.Check("alright then", account)
+
+		// Assert
+		assert.Equal(t, &Rejection{[]string{ReasonSimilar}}, local)
+		assert.NoError(t, short)
+	})
+
+	t.Run("TestPolicyShouldRejectBreached", func(t *testing.T) {
+		// Arrange
+		dir := t.TempDir()
+		// SHA-1 of "password" is 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8.
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "5BAA6.txt"), []byte("003D68EB55068C33ACE09247EE4C639306B:3\r\n1E4C9B93F3F0682250B6CF8331B7EE68FD8:3861493\r\n"), 0644))
+		breached, err := NewBreachedList(dir)
+		assert.NoError(t, err)
+		policy := Policy{Breached: breached}
+
+		// Act
+		err = policy.Check("password", Account{})
+
+		// Assert
+		assert.Equal(t, &Rejection{[]string{ReasonBreached}}, err)
+	})
+}
+
+func TestBreachedList(t *testing.T) {
+	t.Run("TestBreachedListShouldNotFindMissingPrefix", func(t *testing.T) {
+		// Arrange
+		list, err := NewBreachedList(t.TempDir())
+		assert.NoError(t, err)
+
+		// Act
+		found, err := list.Contains("password")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("TestBreachedListShouldMatchWholeSuffixIgnoringCase", func(t *testing.T) {
+		// Arrange
+		dir := t.TempDir()
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "5BAA6.txt"), []byte("1e4c9b93f3f0682250b6cf8331b7ee68fd8:1\n"), 0644))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "7C4A8.txt"), []byte("D09CA3762AF61E59520943DC26494F8941B\n"), 0644))
+		list, err := NewBreachedList(dir)
+		assert.NoError(t, err)
+
+		// Act
+		password, err1 := list.Contains("password")
+		digits, err2 := list.Contains("123456")
+		other, err3 := list.Contains("not in the list")
+
+		// Assert
+		assert.NoError(t, err1)
+		assert.NoError(t, err2)
+		assert.NoError(t, err3)
+		assert.True(t, password)
+		assert.True(t, digits)
+		assert.False(t, other)
+	})
+
+	t.Run("TestNewBreachedListShouldRequireDirectory", func(t *testing.T) {
+		// Arrange
+		file := filepath.Join(t.TempDir(), "list.txt")
+		assert.NoError(t, os.WriteFile(file, nil, 0644))
+
+		// Act
+		_, missing := NewBreachedList(filepath.Join(t.TempDir(), "missing"))
+		_, notDir := NewBreachedList(file)
+
+		// Assert
+		assert.Error(t, missing)
+		assert.Error(t, notDir)
+	})
+}
+
+func TestInitPolicy(t *testing.T) {
+	t.Run("TestInitPolicyShouldUseConfig", func(t *testing.T) {
+		// Arrange
+		config := common.DefaultConfig().Password
+		config.BreachedDir = t.TempDir()
+
+		// Act
+		policy, err := InitPolicy(config)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, config.MinLength, policy.MinLength)
+		assert.True(t, policy.RejectSimilar)
+		assert.NotNil(t, policy.Breached)
+	})
+}
@@ -0,0 +1,44 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepEvery is how many takes pass between removals of full buckets.
+const sweepEvery = 1024
+
+// MemoryStore keeps buckets in this process, for a single instance.
+type MemoryStore struct {
+	mu    sync.Mutex
+	tats  map[string]time.Time
+	takes int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{tats: map[string]time.Time{}}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.takes++
+	if s.takes%sweepEvery == 0 {
+		s.sweep(now)
+	}
+
+	tat, res := take(s.tats[key], limit, now)
+	s.tats[key] = tat
+	return res, nil
+}
+
+// sweep forgets buckets that have refilled, which behave like new ones.
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, tat := range s.tats {
+		if !tat.After(now) {
+			delete(s.tats, key)
+		}
+	}
+}
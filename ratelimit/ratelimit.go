@@ -0,0 +1,131 @@
+// Package ratelimit limits how often each client may call the API with
+// token buckets kept in memory or in Redis.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/paquesqueue/bookstore/common"
+)
+
+// Limit lets a client make Requests per Period, in bursts of up to Requests.
+// The zero Limit does not limit.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+func (l Limit) Unlimited() bool {
+	return l.Requests <= 0 || l.Period <= 0
+}
+
+// interval is the time it takes the bucket to regain one token.
+func (l Limit) interval() time.Duration {
+	return l.Period / time.Duration(l.Requests)
+}
+
+// Result is the state of a client's bucket after one request.
+type Result struct {
+	Allowed bool
+	// Remaining is how many more requests would be allowed right now.
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long a rejected client should wait.
+	RetryAfter time.Duration
+}
+
+// Store keeps the buckets. Take spends one token of the bucket at key.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+// take applies one request to a bucket kept as GCRA's theoretical arrival
+// time, the moment the bucket would be empty again. It returns the new
+// arrival time to store, or tat unchanged when the request is rejected.
+func take(tat time.Time, limit Limit, now time.Time) (time.Time, Result) {
+	interval := limit.interval()
+	capacity := interval * time.Duration(limit.Requests)
+	if tat.Before(now) {
+		tat = now
+	}
+
+	next := tat.Add(interval)
+	if allowAt := next.Add(-capacity); now.Before(allowAt) {
+		return tat, Result{
+			Allowed:    false,
+			Remaining:  0,
+			Reset:      tat.Sub(now),
+			RetryAfter: allowAt.Sub(now),
+		}
+	}
+	return next, Result{
+		Allowed:   true,
+		Remaining: int(math.Floor(float64(capacity-next.Sub(now)) / float64(interval))),
+		Reset:     next.Sub(now),
+	}
+}
+
+// Limiter applies the limit of each route to each client, falling back to
+// a default limit for routes without their own. The limits are read on
+// every request, so a config reload changes them at once.
+type Limiter struct {
+	store  Store
+	limits func() common.RateLimitConfig
+	now    func() time.Time
+}
+
+// NewLimiter limits routes, keyed like "GET /books/:id", with their own
+// limits from limits and every other route with its default. Nothing is
+// limited while limits is disabled.
+func NewLimiter(store Store, limits func() common.RateLimitConfig) *Limiter {
+	return &Limiter{store: store, limits: limits, now: time.Now}
+}
+
+// Allow counts one request of client on route. Routes with their own limit
+// have their own bucket; the others share the default one.
+func (l *Limiter) Allow(ctx context.Context, route string, client string) (Limit, Result, error) {
+	c := l.limits()
+	if !c.Enabled {
+		return Limit{}, Result{Allowed: true}, nil
+	}
+	limit, bucket := Limit(c.Default), "default"
+	if routeLimit, ok := c.Routes[route]; ok {
+		limit, bucket = Limit(routeLimit), route
+	}
+	if limit.Unlimited() {
+		return limit, Result{Allowed: true}, nil
+	}
+	res, err := l.store.Take(ctx, bucket+"|"+client, limit, l.now())
+	return limit, res, err
+}
+
+// Close releases the store's connections, if it holds any.
+func (l *Limiter) Close() error {
+	if closer, ok := l.store.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// InitLimiter builds the limiter with the store the config asks for, which
+// only changes on restart. Whether and how much to limit follows live.
+func InitLimiter(live *common.LiveConfig) (*Limiter, error) {
+	c := live.Get().RateLimit
+
+	var store Store
+	switch c.Store {
+	case "", common.RateLimitStoreMemory:
+		store = NewMemoryStore()
+	case common.RateLimitStoreRedis:
+		store = NewRedisStore(c.RedisAddr, c.RedisPassword, c.RedisDB)
+	default:
+		return nil, fmt.Errorf("error unsupported rate limit store %q", c.Store)
+	}
+
+	return NewLimiter(store, func() common.RateLimitConfig { return live.Get().RateLimit }), nil
+}
@@ -0,0 +1,148 @@
+//go:build unit
+
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/paquesqueue/bookstore/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTake(t *testing.T) {
+	t.Run("TestTakeShouldAllowBurstThenRefill", func(t *testing.T) {
+		// Arrange
+		limit := Limit{Requests: 3, Period: 3 * time.Second}
+		now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		var tat time.Time
+		remaining := []int{}
+
+		// Act
+		for i := 0; i < 3; i++ {
+			var res Result
+			tat, res = take(tat, limit, now)
+			assert.True(t, res.Allowed)
+			remaining = append(remaining, res.Remaining)
+		}
+		_, rejected := take(tat, limit, now)
+		_, refilled := take(tat, limit, now.Add(time.Second))
+
+		// Assert
+		assert.Equal(t, []int{2, 1, 0}, remaining)
+		assert.False(t, rejected.Allowed)
+		assert.Equal(t, time.Second, rejected.RetryAfter)
+		assert.Equal(t, 3*time.Second, rejected.Reset)
+		assert.True(t, refilled.Allowed)
+		assert.Equal(t, 0, refilled.Remaining)
+	})
+}
+
+func TestLimiter(t *testing.T) {
+	t.Run("TestLimiterShouldKeepRouteBucketsApart", func(t *testing.T) {
+		// Arrange
+		limits := common.RateLimitConfig{
+			Enabled: true,
+			Default: common.RateLimit{Requests: 1, Period: time.Minute},
+			Routes: map[string]common.RateLimit{
+				"POST /auth/login": {Requests: 2, Period: time.Minute},
+				"GET /healthz":     {},
+			},
+		}
+		limiter := NewLimiter(NewMemoryStore(), func() common.RateLimitConfig { return limits })
+		ctx := context.Background()
+
+		// Act
+		_, books, _ := limiter.Allow(ctx, "GET /books", "ip:10.0.0.1")
+		_, authors, _ := limiter.Allow(ctx, "GET /authors", "ip:10.0.0.1")
+		_, otherClient, _ := limiter.Allow(ctx, "GET /authors", "ip:10.0.0.2")
+		loginLimit, login, _ := limiter.Allow(ctx, "POST /auth/login", "ip:10.0.0.1")
+		healthLimit, health, err := limiter.Allow(ctx, "GET /healthz", "ip:10.0.0.1")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.True(t, books.Allowed)
+		assert.False(t, authors.Allowed)
+		assert.True(t, otherClient.Allowed)
+		assert.True(t, login.Allowed)
+		assert.Equal(t, 2, loginLimit.Requests)
+		assert.True(t, health.Allowed)
+		assert.True(t, healthLimit.Unlimited())
+	})
+
+	t.Run("TestLimiterShouldFollowChangedLimits", func(t *testing.T) {
+		// Arrange
+		limits := common.RateLimitConfig{Enabled: true, Default: common.RateLimit{Requests: 1, Period: time.Minute}}
+		limiter := NewLimiter(NewMemoryStore(), func() common.RateLimitConfig { return limits })
+		ctx := context.Background()
+		_, first, _ := limiter.Allow(ctx, "GET /books", "ip:10.0.0.1")
+
+		// Act
+		_, limited, _ := limiter.Allow(ctx, "GET /books", "ip:10.0.0.1")
+		limits.Enabled = false
+		disabledLimit, disabled, _ := limiter.Allow(ctx, "GET /books", "ip:10.0.0.1")
+		limits.Enabled = true
+		limits.Routes = map[string]common.RateLimit{"GET /books": {Requests: 5, Period: time.Minute}}
+		routeLimit, route, err := limiter.Allow(ctx, "GET /books", "ip:10.0.0.1")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.True(t, first.Allowed)
+		assert.False(t, limited.Allowed)
+		assert.True(t, disabled.Allowed)
+		assert.True(t, disabledLimit.Unlimited())
+		assert.True(t, route.Allowed)
+		assert.Equal(t, 5, routeLimit.Requests)
+	})
+}
+
+func TestMemoryStore(t *testing.T) {
+	t.Run("TestMemoryStoreShouldForgetRefilledBuckets", func(t *testing.T) {
+		// Arrange
+		store := NewMemoryStore()
+		limit := Limit{Requests: 1, Period: time.Second}
+		now := time.Now()
+		_, err := store.Take(context.Background(), "old", limit, now)
+		assert.NoError(t, err)
+
+		// Act
+		store.sweep(now.Add(time.Second))
+
+		// Assert
+		assert.Empty(t, store.tats)
+	})
+}
+
+func TestInitLimiter(t *testing.T) {
+	t.Run("TestInitLimiterShouldNotLimitWhenDisabled", func(t *testing.T) {
+		// Arrange
+		c := common.DefaultConfig()
+		c.RateLimit.Enabled = false
+		limiter, err := InitLimiter(common.NewLiveConfig(c, nil))
+		assert.NoError(t, err)
+
+		// Act
+		limit, res, err := limiter.Allow(context.Background(), "POST /auth/login", "ip:10.0.0.1")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.True(t, limit.Unlimited())
+	})
+
+	t.Run("TestInitLimiterShouldUseConfiguredLimits", func(t *testing.T) {
+		// Arrange
+		limiter, err := InitLimiter(common.NewLiveConfig(common.DefaultConfig(), nil))
+		assert.NoError(t, err)
+
+		// Act
+		limit, _, err := limiter.Allow(context.Background(), "POST /auth/login", "ip:10.0.0.1")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.IsType(t, &MemoryStore{}, limiter.store)
+		assert.Equal(t, Limit{Requests: 10, Period: time.Minute}, limit)
+		assert.Equal(t, common.RateLimit{Requests: 600, Period: time.Minute}, limiter.limits().Default)
+	})
+}
@@ -0,0 +1,264 @@
+package ratelimit
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+const (
+	redisKeyPrefix = "bookstore:ratelimit:"
+	redisPoolSize  = 16
+	redisTimeout   = 2 * time.Second
+	// redisRetries bounds the optimistic transaction when other replicas
+	// keep changing the same bucket.
+	redisRetries = 5
+)
+
+var (
+	errRedisNil       = errors.New("redis: nil")
+	errRedisConflict  = errors.New("redis: bucket changed by another client too often")
+	errRedisMalformed = errors.New("redis: malformed reply")
+)
+
+// RedisStore keeps buckets in Redis, or anything speaking its protocol, so
+// every replica sees the same limits. Buckets are updated with WATCH and
+// MULTI/EXEC rather than scripts.
+type RedisStore struct {
+	addr     string
+	password string
+	db       int
+	pool     chan *redisConn
+}
+
+func NewRedisStore(addr string, password string, db int) *RedisStore {
+	return &RedisStore{addr: addr, password: password, db: db, pool: make(chan *redisConn, redisPoolSize)}
+}
+
+func (s *RedisStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	conn, err := s.get(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	res, err := s.take(conn, redisKeyPrefix+key, limit, now)
+	s.put(conn, err)
+	return res, err
+}
+
+func (s *RedisStore) take(conn *redisConn, key string, limit Limit, now time.Time) (Result, error) {
+	for i := 0; i < redisRetries; i++ {
+		if _, err := conn.do("WATCH", key); err != nil {
+			return Result{}, err
+		}
+		var tat time.Time
+		reply, err := conn.do("GET", key)
+		switch {
+		case err == errRedisNil:
+		case err != nil:
+			return Result{}, err
+		default:
+			nanos, err := strconv.ParseInt(reply.(string), 10, 64)
+			if err != nil {
+				return Result{}, errRedisMalformed
+			}
+			tat = time.Unix(0, nanos)
+		}
+
+		next, res := take(tat, limit, now)
+		if !res.Allowed {
+			_, err := conn.do("UNWATCH")
+			return res, err
+		}
+
+		ttl := next.Sub(now).Milliseconds() + 1
+		replies, err := conn.pipeline(
+			[]string{"MULTI"},
+			[]string{"SET", key, strconv.FormatInt(next.UnixNano(), 10), "PX", strconv.FormatInt(ttl, 10)},
+			[]string{"EXEC"},
+		)
+		if err != nil {
+			return Result{}, err
+		}
+		if replies[2] != nil {
+			return res, nil
+		}
+	}
+	return Result{}, errRedisConflict
+}
+
+// Close closes the idle connections.
+func (s *RedisStore) Close() error {
+	for {
+		select {
+		case conn := <-s.pool:
+			conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+func (s *RedisStore) get(ctx context.Context) (*redisConn, error) {
+	select {
+	case conn := <-s.pool:
+		return conn, nil
+	default:
+	}
+
+	dialer := net.Dialer{Timeout: redisTimeout}
+	nc, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return nil, fmt.Errorf("error connect redis : %w", err)
+	}
+	conn := &redisConn{Conn: nc, r: bufio.NewReader(nc), w: bufio.NewWriter(nc)}
+	if s.password != "" {
+		if _, err := conn.do("AUTH", s.password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if s.db != 0 {
+		if _, err := conn.do("SELECT", strconv.Itoa(s.db)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// put returns conn to the pool unless the take failed, which may leave the
+// connection watching a key or out of step with the server.
+func (s *RedisStore) put(conn *redisConn, err error) {
+	if err != nil {
+		conn.Close()
+		return
+	}
+	select {
+	case s.pool <- conn:
+	default:
+		conn.Close()
+	}
+}
+
+// redisError is an error reply from the server.
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+type redisConn struct {
+	net.Conn
+	r *bufio.Reader
+	w *bufio.Writer
+}
+
+func (c *redisConn) do(args ...string) (interface{}, error) {
+	replies, err := c.pipeline(args)
+	if err != nil {
+		return nil, err
+	}
+	return replies[0], nil
+}
+
+// pipeline sends every command before reading the replies. The first error
+// reply is returned along with all replies; a nil reply is errRedisNil only
+// for a single command.
+func (c *redisConn) pipeline(cmds ...[]string) ([]interface{}, error) {
+	_ = c.SetDeadline(time.Now().Add(redisTimeout))
+	for _, args := range cmds {
+		fmt.Fprintf(c.w, "*%d\r\n", len(args))
+		for _, arg := range args {
+			fmt.Fprintf(c.w, "$%d\r\n%s\r\n", len(arg), arg)
+		}
+	}
+	if err := c.w.Flush(); err != nil {
+		return nil, err
+	}
+
+	replies := make([]interface{}, len(cmds))
+	var first error
+	for i := range cmds {
+		reply, err := readReply(c.r)
+		if err != nil {
+			var replyErr redisError
+			if !errors.As(err, &replyErr) {
+				return nil, err
+			}
+			if first == nil {
+				first = err
+			}
+		}
+		replies[i] = reply
+	}
+	if first == nil && len(cmds) == 1 && replies[0] == nil {
+		first = errRedisNil
+	}
+	return replies, first
+}
+
+// readReply reads one RESP reply: strings, integers, nil or arrays of them.
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, errRedisMalformed
+	}
+	kind, body := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, redisError(body)
+	case ':':
+		n, err := strconv.ParseInt(body, 10, 64)
+		if err != nil {
+			return nil, errRedisMalformed
+		}
+		return n, nil
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, errRedisMalformed
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, errRedisMalformed
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		// Error replies inside an array, e.g. from EXEC, are items rather
+		// than failures, so the rest of the array is still read.
+		items := make([]interface{}, n)
+		for i := range items {
+			item, err := readReply(r)
+			var replyErr redisError
+			if errors.As(err, &replyErr) {
+				items[i] = replyErr
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	}
+	return nil, errRedisMalformed
+}
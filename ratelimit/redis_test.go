@@ -0,0 +1,252 @@
+//go:build unit
+
+package ratelimit
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRedis is a local stand-in speaking enough of the Redis protocol for
+// RedisStore: AUTH, SELECT, GET, SET with PX, WATCH, UNWATCH, MULTI and EXEC.
+type fakeRedis struct {
+	ln       net.Listener
+	password string
+
+	mu       sync.Mutex
+	values   map[string]string
+	ttls     map[string]time.Duration
+	versions map[string]int
+	// interfere changes every watched key before this many EXECs, as
+	// another replica would.
+	interfere int
+	execs     int
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	r := &fakeRedis{ln: ln, password: password, values: map[string]string{}, ttls: map[string]time.Duration{}, versions: map[string]int{}}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go r.serve(conn)
+		}
+	}()
+	return r
+}
+
+func (r *fakeRedis) addr() string {
+	return r.ln.Addr().String()
+}
+
+func (r *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	rd, w := bufio.NewReader(conn), bufio.NewWriter(conn)
+	authed := r.password == ""
+	watched := map[string]int{}
+	var queued [][]string
+	inMulti := false
+
+	for {
+		args, err := readCommand(rd)
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(args[0])
+		switch {
+		case cmd == "AUTH":
+			authed = args[1] == r.password
+			if !authed {
+				w.WriteString("-WRONGPASS invalid password\r\n")
+				break
+			}
+			w.WriteString("+OK\r\n")
+		case !authed:
+			w.WriteString("-NOAUTH Authentication required.\r\n")
+		case cmd == "SELECT":
+			w.WriteString("+OK\r\n")
+		case cmd == "MULTI":
+			inMulti = true
+			w.WriteString("+OK\r\n")
+		case cmd == "EXEC":
+			w.WriteString(r.exec(watched, queued))
+			watched, queued, inMulti = map[string]int{}, nil, false
+		case inMulti:
+			queued = append(queued, args)
+			w.WriteString("+QUEUED\r\n")
+		case cmd == "WATCH":
+			r.mu.Lock()
+			watched[args[1]] = r.versions[args[1]]
+			r.mu.Unlock()
+			w.WriteString("+OK\r\n")
+		case cmd == "UNWATCH":
+			watched = map[string]int{}
+			w.WriteString("+OK\r\n")
+		default:
+			r.mu.Lock()
+			w.WriteString(r.run(args))
+			r.mu.Unlock()
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (r *fakeRedis) exec(watched map[string]int, queued [][]string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.execs++
+	if r.execs <= r.interfere {
+		for key := range watched {
+			r.versions[key]++
+		}
+	}
+	for key, version := range watched {
+		if r.versions[key] != version {
+			return "*-1\r\n"
+		}
+	}
+	out := fmt.Sprintf("*%d\r\n", len(queued))
+	for _, args := range queued {
+		out += r.run(args)
+	}
+	return out
+}
+
+func (r *fakeRedis) run(args []string) string {
+	switch strings.ToUpper(args[0]) {
+	case "GET":
+		value, ok := r.values[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+	case "SET":
+		r.values[args[1]] = args[2]
+		r.versions[args[1]]++
+		if len(args) == 5 && strings.ToUpper(args[3]) == "PX" {
+			ms, _ := strconv.Atoi(args[4])
+			r.ttls[args[1]] = time.Duration(ms) * time.Millisecond
+		}
+		return "+OK\r\n"
+	}
+	return "-ERR unknown command '" + args[0] + "'\r\n"
+}
+
+func readCommand(rd *bufio.Reader) ([]string, error) {
+	reply, err := readReply(rd)
+	if err != nil {
+		return nil, err
+	}
+	items, ok := reply.([]interface{})
+	if !ok || len(items) == 0 {
+		return nil, errRedisMalformed
+	}
+	args := make([]string, len(items))
+	for i, item := range items {
+		args[i], _ = item.(string)
+	}
+	return args, nil
+}
+
+func TestRedisStore(t *testing.T) {
+	t.Run("TestRedisStoreShouldShareBucketsBetweenReplicas", func(t *testing.T) {
+		// Arrange
+		server := newFakeRedis(t, "secret")
+		first, second := NewRedisStore(server.addr(), "secret", 1), NewRedisStore(server.addr(), "secret", 1)
+		defer first.Close()
+		defer second.Close()
+		limit := Limit{Requests: 2, Period: time.Minute}
+		now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+		// Act
+		a, err := first.Take(context.Background(), "default|ip:10.0.0.1", limit, now)
+		assert.NoError(t, err)
+		b, err := second.Take(context.Background(), "default|ip:10.0.0.1", limit, now)
+		assert.NoError(t, err)
+		c, err := first.Take(context.Background(), "default|ip:10.0.0.1", limit, now)
+		assert.NoError(t, err)
+
+		// Assert
+		assert.True(t, a.Allowed)
+		assert.Equal(t, 1, a.Remaining)
+		assert.True(t, b.Allowed)
+		assert.False(t, c.Allowed)
+		assert.Equal(t, 30*time.Second, c.RetryAfter)
+		assert.Equal(t, strconv.FormatInt(now.Add(time.Minute).UnixNano(), 10), server.values["bookstore:ratelimit:default|ip:10.0.0.1"])
+		assert.Equal(t, time.Minute+time.Millisecond, server.ttls["bookstore:ratelimit:default|ip:10.0.0.1"])
+	})
+
+	t.Run("TestRedisStoreShouldRetryWhenBucketChanges", func(t *testing.T) {
+		// Arrange
+		server := newFakeRedis(t, "")
+		server.interfere = 2
+		store := NewRedisStore(server.addr(), "", 0)
+		defer store.Close()
+
+		// Act
+		res, err := store.Take(context.Background(), "k", Limit{Requests: 5, Period: time.Minute}, time.Now())
+
+		// Assert
+		assert.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 3, server.execs)
+	})
+
+	t.Run("TestRedisStoreShouldGiveUpOnConstantConflicts", func(t *testing.T) {
+		// Arrange
+		server := newFakeRedis(t, "")
+		server.interfere = redisRetries
+		store := NewRedisStore(server.addr(), "", 0)
+		defer store.Close()
+
+		// Act
+		_, err := store.Take(context.Background(), "k", Limit{Requests: 5, Period: time.Minute}, time.Now())
+
+		// Assert
+		assert.ErrorIs(t, err, errRedisConflict)
+	})
+
+	t.Run("TestRedisStoreShouldReportWrongPassword", func(t *testing.T) {
+		// Arrange
+		server := newFakeRedis(t, "secret")
+		store := NewRedisStore(server.addr(), "wrong", 0)
+		defer store.Close()
+
+		// Act
+		_, err := store.Take(context.Background(), "k", Limit{Requests: 5, Period: time.Minute}, time.Now())
+
+		// Assert
+		assert.EqualError(t, err, "redis: WRONGPASS invalid password")
+	})
+
+	t.Run("TestRedisStoreShouldReportUnreachableServer", func(t *testing.T) {
+		// Arrange
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		addr := ln.Addr().String()
+		ln.Close()
+		store := NewRedisStore(addr, "", 0)
+
+		// Act
+		_, err = store.Take(context.Background(), "k", Limit{Requests: 5, Period: time.Minute}, time.Now())
+
+		// Assert
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,282 @@
+// Package recommend scores how closely books are related, both by what they
+// have in common and by how often customers keep them together.
+package recommend
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Item is the part of a book the content similarity looks at.
+type Item struct {
+	Id         uint64
+	Title      string
+	Authors    []string
+	Publisher  string
+	Categories []uint64
+}
+
+// Basket is a set of books one customer keeps together, such as the books on
+// their lists or in one order.
+type Basket []uint64
+
+// Weights sets how much each signal adds to a pair's score. The content
+// weights sum to one, so a content score is between 0 and 1.
+type Weights struct {
+	Authors      float64
+	Publisher    float64
+	Categories   float64
+	Title        float64
+	CoOccurrence float64
+}
+
+var DefaultWeights = Weights{
+	Authors:      0.4,
+	Publisher:    0.15,
+	Categories:   0.25,
+	Title:        0.2,
+	CoOccurrence: 1,
+}
+
+// Pair is one book related to another. Content and CoOccurrence are the raw
+// signals; Score is their weighted sum.
+type Pair struct {
+	BookId       uint64
+	RelatedId    uint64
+	Content      float64
+	CoOccurrence float64
+	Score        float64
+}
+
+const (
+	// maxFeatureBooks stops very common features, such as a big publisher,
+	// from making every pair of books a candidate.
+	maxFeatureBooks = 1000
+	// maxBasketBooks bounds the pairs a single basket contributes.
+	maxBasketBooks = 200
+	minTermLen     = 3
+)
+
+var stopWords = map[string]bool{
+	"and": true, "the": true, "for": true, "with": true, "from": true,
+	"into": true, "your": true, "our": true,
+}
+
+type features struct {
+	authors    map[string]bool
+	publisher  string
+	categories map[string]bool
+	terms      map[string]bool
+}
+
+func newFeatures(item Item) features {
+	f := features{
+		authors:    map[string]bool{},
+		publisher:  normalise(item.Publisher),
+		categories: map[string]bool{},
+		terms:      map[string]bool{},
+	}
+	for _, a := range item.Authors {
+		if key := normalise(a); key != "" {
+			f.authors[key] = true
+		}
+	}
+	for _, c := range item.Categories {
+		f.categories[strconv.FormatUint(c, 10)] = true
+	}
+	for _, term := range TitleTerms(item.Title) {
+		f.terms[term] = true
+	}
+	return f
+}
+
+// keys lists the features as index keys, prefixed by their kind so an author
+// never matches a title term.
+func (f features) keys() []string {
+	keys := []string{}
+	for a := range f.authors {
+		keys = append(keys, "a:"+a)
+	}
+	if f.publisher != "" {
+		keys = append(keys, "p:"+f.publisher)
+	}
+	for c := range f.categories {
+		keys = append(keys, "c:"+c)
+	}
+	for t := range f.terms {
+		keys = append(keys, "t:"+t)
+	}
+	return keys
+}
+
+func normalise(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+// TitleTerms splits a title into lower-case words, dropping short words and
+// stop words.
+func TitleTerms(title string) []string {
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	terms := []string{}
+	for _, w := range words {
+		if len([]rune(w)) >= minTermLen && !stopWords[w] {
+			terms = append(terms, w)
+		}
+	}
+	return terms
+}
+
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for k := range a {
+		if b[k] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+func contentScore(a, b features, w Weights) float64 {
+	score := w.Authors*jaccard(a.authors, b.authors) +
+		w.Categories*jaccard(a.categories, b.categories) +
+		w.Title*jaccard(a.terms, b.terms)
+	if a.publisher != "" && a.publisher == b.publisher {
+		score += w.Publisher
+	}
+	return score
+}
+
+// ContentScore compares two books on authors, publisher, categories and title
+// terms.
+func ContentScore(a, b Item, w Weights) float64 {
+	return contentScore(newFeatures(a), newFeatures(b), w)
+}
+
+type pairKey struct {
+	a, b uint64
+}
+
+func newPairKey(a, b uint64) pairKey {
+	if a > b {
+		a, b = b, a
+	}
+	return pairKey{a, b}
+}
+
+// coOccurrence returns, for every pair of books found in the same basket, the
+// cosine of their basket vectors: the baskets holding both divided by the
+// geometric mean of the baskets holding each.
+func coOccurrence(baskets []Basket) map[pairKey]float64 {
+	counts := map[uint64]int{}
+	together := map[pairKey]int{}
+	for _, basket := range baskets {
+		ids := distinct(basket)
+		if len(ids) > maxBasketBooks {
+			ids = ids[:maxBasketBooks]
+		}
+		for i, a := range ids {
+			counts[a]++
+			for _, b := range ids[i+1:] {
+				together[newPairKey(a, b)]++
+			}
+		}
+	}
+
+	resp := map[pairKey]float64{}
+	for key, n := range together {
+		resp[key] = float64(n) / math.Sqrt(float64(counts[key.a])*float64(counts[key.b]))
+	}
+	return resp
+}
+
+func distinct(basket Basket) []uint64 {
+	seen := map[uint64]bool{}
+	ids := []uint64{}
+	for _, id := range basket {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// Build scores every pair of books that share a feature or a basket and keeps
+// the topN best related books for each book. The result is ordered by book,
+// then best match first, and is the same for the same input.
+func Build(items []Item, baskets []Basket, w Weights, topN int) []Pair {
+	known := map[uint64]features{}
+	index := map[string][]uint64{}
+	for _, item := range items {
+		f := newFeatures(item)
+		known[item.Id] = f
+		for _, key := range f.keys() {
+			index[key] = append(index[key], item.Id)
+		}
+	}
+
+	candidates := map[pairKey]bool{}
+	for _, ids := range index {
+		if len(ids) > maxFeatureBooks {
+			continue
+		}
+		for i, a := range ids {
+			for _, b := range ids[i+1:] {
+				if a != b {
+					candidates[newPairKey(a, b)] = true
+				}
+			}
+		}
+	}
+
+	co := coOccurrence(baskets)
+	for key := range co {
+		_, okA := known[key.a]
+		_, okB := known[key.b]
+		if okA && okB {
+			candidates[key] = true
+		}
+	}
+
+	related := map[uint64][]Pair{}
+	for key := range candidates {
+		content := contentScore(known[key.a], known[key.b], w)
+		score := content + w.CoOccurrence*co[key]
+		if score <= 0 {
+			continue
+		}
+		related[key.a] = append(related[key.a], Pair{key.a, key.b, content, co[key], score})
+		related[key.b] = append(related[key.b], Pair{key.b, key.a, content, co[key], score})
+	}
+
+	bookIds := make([]uint64, 0, len(related))
+	for id := range related {
+		bookIds = append(bookIds, id)
+	}
+	sort.Slice(bookIds, func(i, j int) bool { return bookIds[i] < bookIds[j] })
+
+	resp := []Pair{}
+	for _, id := range bookIds {
+		pairs := related[id]
+		sort.Slice(pairs, func(i, j int) bool {
+			if pairs[i].Score != pairs[j].Score {
+				return pairs[i].Score > pairs[j].Score
+			}
+			return pairs[i].RelatedId < pairs[j].RelatedId
+		})
+		if len(pairs) > topN {
+			pairs = pairs[:topN]
+		}
+		resp = append(resp, pairs...)
+	}
+	return resp
+}
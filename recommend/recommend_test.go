@@ -0,0 +1,178 @@
+//go:build unit
+
+package recommend
+
+import (
+	"math"
+	"testing"
+
+	"github.com/paquesqueue/bookstore/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// testDataset builds a catalog drawn from small pools of authors,
+// publishers, categories and title words, so books overlap often, plus
+// customer baskets. The same seed always gives the same dataset.
+func testDataset(seed int64, books int, baskets int) ([]Item, []Basket) {
+	r := utils.NewRandom(seed)
+
+	pool := func(n int, size int) []string {
+		words := make([]string, n)
+		for i := range words {
+			words[i] = r.Alphabet(size)
+		}
+		return words
+	}
+	authors := pool(12, 6)
+	publishers := pool(4, 8)
+	words := pool(30, 5)
+
+	items := make([]Item, books)
+	for i := range items {
+		item := Item{Id: uint64(i + 1), Publisher: publishers[r.Intn(len(publishers))]}
+		for _, n := range r.Sample(len(authors), 1+r.Intn(2)) {
+			item.Authors = append(item.Authors, authors[n])
+		}
+		for _, n := range r.Sample(8, 1+r.Intn(3)) {
+			item.Categories = append(item.Categories, uint64(n+1))
+		}
+		for _, n := range r.Sample(len(words), 2+r.Intn(3)) {
+			item.Title += words[n] + " "
+		}
+		items[i] = item
+	}
+
+	resp := make([]Basket, baskets)
+	for i := range resp {
+		for _, n := range r.Sample(books, 2+r.Intn(5)) {
+			resp[i] = append(resp[i], uint64(n+1))
+		}
+	}
+	return items, resp
+}
+
+func TestTitleTerms(t *testing.T) {
+	t.Run("TestTitleTermsShouldDropShortAndStopWords", func(t *testing.T) {
+		// Act
+		terms := TitleTerms("The Art of Go: Programming, 2nd Edition")
+
+		// Assert
+		assert.Equal(t, []string{"art", "programming", "2nd", "edition"}, terms)
+	})
+}
+
+func TestContentScore(t *testing.T) {
+	t.Run("TestContentScoreShouldBeOneForIdenticalBooks", func(t *testing.T) {
+		// Arrange
+		book := Item{Id: 1, Title: "Gardens of the Moon", Authors: []string{"Steven Erikson"}, Publisher: "Bantam", Categories: []uint64{3}}
+
+		// Act
+		score := ContentScore(book, book, DefaultWeights)
+
+		// Assert
+		assert.InDelta(t, 1.0, score, 1e-9)
+	})
+
+	t.Run("TestContentScoreShouldIgnoreCaseAndSpacing", func(t *testing.T) {
+		// Arrange
+		a := Item{Id: 1, Authors: []string{"Ursula K. Le Guin"}}
+		b := Item{Id: 2, Authors: []string{" ursula  k. le guin"}}
+
+		// Act
+		score := ContentScore(a, b, DefaultWeights)
+
+		// Assert
+		assert.InDelta(t, DefaultWeights.Authors, score, 1e-9)
+	})
+
+	t.Run("TestContentScoreShouldBeZeroForUnrelatedBooks", func(t *testing.T) {
+		// Arrange
+		a := Item{Id: 1, Title: "Dune", Authors: []string{"Frank Herbert"}, Publisher: "Chilton", Categories: []uint64{1}}
+		b := Item{Id: 2, Title: "Emma", Authors: []string{"Jane Austen"}, Publisher: "John Murray", Categories: []uint64{2}}
+
+		// Act & Assert
+		assert.Zero(t, ContentScore(a, b, DefaultWeights))
+	})
+}
+
+func TestBuild(t *testing.T) {
+	t.Run("TestBuildShouldRankSharedAuthorAboveSharedPublisher", func(t *testing.T) {
+		// Arrange
+		items := []Item{
+			{Id: 1, Title: "Dune", Authors: []string{"Frank Herbert"}, Publisher: "Ace"},
+			{Id: 2, Title: "Children of Dune", Authors: []string{"Frank Herbert"}, Publisher: "Putnam"},
+			{Id: 3, Title: "Neuromancer", Authors: []string{"William Gibson"}, Publisher: "Ace"},
+			{Id: 4, Title: "Emma", Authors: []string{"Jane Austen"}, Publisher: "John Murray"},
+		}
+
+		// Act
+		pairs := Build(items, nil, DefaultWeights, 10)
+
+		// Assert
+		related := []uint64{}
+		for _, p := range pairs {
+			if p.BookId == 1 {
+				related = append(related, p.RelatedId)
+			}
+		}
+		assert.Equal(t, []uint64{2, 3}, related)
+	})
+
+	t.Run("TestBuildShouldRelateBooksKeptTogether", func(t *testing.T) {
+		// Arrange
+		items := []Item{{Id: 1, Title: "Dune"}, {Id: 2, Title: "Emma"}, {Id: 3, Title: "Ulysses"}}
+		baskets := []Basket{{1, 2}, {1, 2, 2}, {1, 3}, {9, 1}}
+
+		// Act
+		pairs := Build(items, baskets, DefaultWeights, 10)
+
+		// Assert
+		// Book 1 is in four baskets, book 2 in two and book 3 in one.
+		if assert.Len(t, pairs, 4) {
+			assert.Equal(t, uint64(2), pairs[0].RelatedId)
+			assert.InDelta(t, 2/math.Sqrt(4*2), pairs[0].Score, 1e-9)
+			assert.Equal(t, uint64(3), pairs[1].RelatedId)
+			assert.InDelta(t, 1/math.Sqrt(4*1), pairs[1].Score, 1e-9)
+			assert.Zero(t, pairs[0].Content)
+		}
+	})
+
+	t.Run("TestBuildShouldBeDeterministic", func(t *testing.T) {
+		// Arrange
+		items, baskets := testDataset(2024, 60, 40)
+		again, againBaskets := testDataset(2024, 60, 40)
+
+		// Act
+		first := Build(items, baskets, DefaultWeights, 5)
+		second := Build(again, againBaskets, DefaultWeights, 5)
+
+		// Assert
+		assert.NotEmpty(t, first)
+		assert.Equal(t, first, second)
+	})
+
+	t.Run("TestBuildShouldKeepTopNBestMatchesPerBook", func(t *testing.T) {
+		// Arrange
+		items, baskets := testDataset(7, 60, 40)
+
+		// Act
+		pairs := Build(items, baskets, DefaultWeights, 5)
+
+		// Assert
+		perBook := map[uint64]int{}
+		for i, p := range pairs {
+			perBook[p.BookId]++
+			assert.NotEqual(t, p.BookId, p.RelatedId)
+			assert.Greater(t, p.Score, 0.0)
+			assert.InDelta(t, p.Content+p.CoOccurrence, p.Score, 1e-9)
+			if i > 0 && pairs[i-1].BookId == p.BookId {
+				assert.GreaterOrEqual(t, pairs[i-1].Score, p.Score)
+			} else if i > 0 {
+				assert.Less(t, pairs[i-1].BookId, p.BookId)
+			}
+		}
+		for _, n := range perBook {
+			assert.LessOrEqual(t, n, 5)
+		}
+	})
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/paquesqueue/bookstore/metrics"
+)
+
+// unmatchedRoute labels requests no route matched, so scanners probing
+// random paths cannot create new series.
+const unmatchedRoute = "unmatched"
+
+// metricsMiddleware counts requests and their latency by route pattern, so
+// /books/1 and /books/2 share one series.
+func metricsMiddleware(reg *metrics.Registry) echo.MiddlewareFunc {
+	requests := reg.Counter("bookstore_http_requests_total",
+		"HTTP requests served.", "method", "route", "status")
+	latency := reg.Histogram("bookstore_http_request_duration_seconds",
+		"Time spent serving HTTP requests.", metrics.DefBuckets, "method", "route", "status")
+	inFlight := reg.Gauge("bookstore_http_requests_in_flight",
+		"HTTP requests being served.")
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			inFlight.With().Inc()
+			defer inFlight.With().Dec()
+
+			err := next(c)
+
+			status := responseStatus(c, err)
+			route := c.Path()
+			if route == "" || (route == "/*" && status == http.StatusNotFound) {
+				route = unmatchedRoute
+			}
+
+			labels := []string{c.Request().Method, route, strconv.Itoa(status)}
+			requests.With(labels...).Inc()
+			latency.With(labels...).Observe(time.Since(start).Seconds())
+			return err
+		}
+	}
+}
+
+// responseStatus is the status the client gets: the one written, or the one
+// the error handler will write for err.
+func responseStatus(c echo.Context, err error) int {
+	status := c.Response().Status
+	if err != nil && !c.Response().Committed {
+		status = http.StatusInternalServerError
+		var httpErr *echo.HTTPError
+		if errors.As(err, &httpErr) {
+			status = httpErr.Code
+		}
+	}
+	return status
+}
@@ -5,16 +5,37 @@ import (
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	api "github.com/paquesqueue/bookstore/api"
 	"github.com/paquesqueue/bookstore/common"
+	"github.com/paquesqueue/bookstore/metrics"
 	"github.com/sirupsen/logrus"
 )
 
-func InitMiddleware(e *echo.Echo, log *logrus.Logger, config common.Config) {
+// publicPaths are served without the access token so orchestrators can
+// probe them.
+var publicPaths = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+}
+
+func InitMiddleware(e *echo.Echo, log *logrus.Logger, live *common.LiveConfig, reg *metrics.Registry) {
+	e.IPExtractor = api.ClientIPExtractor(live.Get().Server.TrustedProxies)
+	e.Use(requestIDMiddleware())
+	e.Use(tracingMiddleware())
+	e.Use(metricsMiddleware(reg))
 
 	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
+			if publicPaths[c.Request().URL.Path] {
+				return next(c)
+			}
 			authToken := c.Request().Header.Values("Authorization")
-			if authToken != nil && authToken[0] == config.AccessToken {
+			if authToken != nil && authToken[0] == live.Get().Auth.AccessToken {
+				return next(c)
+			}
+			// API keys are checked against the database by
+			// AuthHandlr.APIKeyAuth, which InitRoutes adds.
+			if _, ok := api.APIKeyFromHeader(c.Request().Header.Get("Authorization")); ok {
 				return next(c)
 			}
 			return echo.NewHTTPError(http.StatusUnauthorized, "Valid credential not provided")
@@ -25,24 +46,25 @@ func InitMiddleware(e *echo.Echo, log *logrus.Logger, config common.Config) {
 		LogURI:      true,
 		LogStatus:   true,
 		LogRemoteIP: true,
-		LogMethod:   true,
 		LogHeaders:  []string{"Content-Type", "Authorization"},
 		LogLatency:  true,
 		LogError:    true,
 		LogValuesFunc: func(c echo.Context, values middleware.RequestLoggerValues) error {
+			// Access tokens and API keys must not end up in the logs.
+			if _, ok := values.Headers["Authorization"]; ok {
+				values.Headers["Authorization"] = []string{"REDACTED"}
+			}
 			if values.Error == nil {
-				log.WithFields(logrus.Fields{
+				common.LogFrom(c.Request().Context(), log).WithFields(logrus.Fields{
 					"URI":     values.URI,
 					"status":  values.Status,
-					"method":  values.Method,
 					"headers": values.Headers,
 					"latency": values.Latency,
 				}).Info("request")
 			} else {
-				log.WithFields(logrus.Fields{
+				common.LogFrom(c.Request().Context(), log).WithFields(logrus.Fields{
 					"URI":     values.URI,
 					"status":  values.Status,
-					"method":  values.Method,
 					"headers": values.Headers,
 					"latency": values.Latency,
 					"error":   values.Error,
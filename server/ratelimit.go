@@ -0,0 +1,60 @@
+package server
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/paquesqueue/bookstore/common"
+	"github.com/paquesqueue/bookstore/metrics"
+	"github.com/paquesqueue/bookstore/ratelimit"
+	"github.com/sirupsen/logrus"
+)
+
+// rateLimitMiddleware rejects clients, as named by clientKey, that exceed
+// their route's limit with 429 and tells every client its budget in the
+// RateLimit-* headers. When the store fails requests are let through, so a
+// Redis outage does not take the API down with it.
+func rateLimitMiddleware(limiter *ratelimit.Limiter, clientKey func(c echo.Context) string, reg *metrics.Registry, log *logrus.Logger) echo.MiddlewareFunc {
+	limited := reg.Counter("bookstore_http_rate_limited_total",
+		"Requests rejected by the rate limiter.", "method", "route")
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			route := req.Method + " " + c.Path()
+
+			limit, res, err := limiter.Allow(req.Context(), route, clientKey(c))
+			if err != nil {
+				common.LogFrom(req.Context(), log).Errorf("Error Rate Limit Store : %v", err)
+				return next(c)
+			}
+			if limit.Unlimited() {
+				return next(c)
+			}
+
+			h := c.Response().Header()
+			h.Set("RateLimit-Limit", strconv.Itoa(limit.Requests))
+			h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			h.Set("RateLimit-Reset", seconds(res.Reset))
+			h.Set("RateLimit-Policy", strconv.Itoa(limit.Requests)+";w="+seconds(limit.Period))
+			if !res.Allowed {
+				path := c.Path()
+				if path == "" {
+					path = unmatchedRoute
+				}
+				limited.With(req.Method, path).Inc()
+				h.Set("Retry-After", seconds(res.RetryAfter))
+				return c.NoContent(http.StatusTooManyRequests)
+			}
+			return next(c)
+		}
+	}
+}
+
+// seconds rounds d up to whole seconds, as the headers expect.
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
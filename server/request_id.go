@@ -0,0 +1,62 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/labstack/echo/v4"
+	"github.com/paquesqueue/bookstore/common"
+)
+
+// maxRequestIDLength bounds ids taken from callers, which end up in every
+// log line of the request.
+const maxRequestIDLength = 128
+
+// requestIDMiddleware gives every request an id, keeping the caller's
+// X-Request-ID when it is usable, and returns it in the response. The id,
+// method and route are added to the request context so everything logged
+// through common.LogFrom while serving it can be tied together.
+func requestIDMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			id := req.Header.Get(common.RequestIDHeader)
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+			c.Response().Header().Set(common.RequestIDHeader, id)
+
+			route := c.Path()
+			if route == "" {
+				route = unmatchedRoute
+			}
+			ctx := common.WithLogFields(req.Context(), common.Fields{
+				"request_id": id,
+				"method":     req.Method,
+				"route":      route,
+			})
+			c.SetRequest(req.WithContext(ctx))
+			return next(c)
+		}
+	}
+}
+
+// validRequestID accepts ids of printable ASCII without spaces, so a caller
+// cannot forge log lines through the header.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
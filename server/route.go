@@ -1,30 +1,202 @@
 package server
 
 import (
+	"context"
 	"database/sql"
 
 	"github.com/labstack/echo/v4"
 	api "github.com/paquesqueue/bookstore/api"
+	"github.com/paquesqueue/bookstore/common"
+	"github.com/paquesqueue/bookstore/mail"
+	"github.com/paquesqueue/bookstore/metrics"
+	"github.com/paquesqueue/bookstore/moderation"
+	"github.com/paquesqueue/bookstore/passwords"
+	"github.com/paquesqueue/bookstore/ratelimit"
+	"github.com/paquesqueue/bookstore/storage"
 	"github.com/sirupsen/logrus"
 )
 
-func InitRoutes(e *echo.Echo, dbConn *sql.DB, log *logrus.Logger) {
+func InitRoutes(e *echo.Echo, dbConn *sql.DB, dbHealth *api.DBHealth, health *api.HealthServices, reg *metrics.Registry, limiter *ratelimit.Limiter, store storage.BlobStore, reviewFilter moderation.Filter, auth common.AuthConfig, passwordPolicy passwords.Policy, mailer mail.Mailer, securityLog *logrus.Logger, log *logrus.Logger) {
 	conn := api.NewDB(dbConn)
+	listNotifier := api.NewLogListNotifier(log)
 
-	bookServ := api.NewBookService(conn, log)
+	accountServ := api.NewAccountService(conn, mailer, passwordPolicy, auth, log)
+	userServ := api.NewUserService(conn, passwordPolicy, accountServ, auth, securityLog, log)
+	if err := userServ.BootstrapAdmin(context.Background()); err != nil {
+		log.Errorf("Error Bootstrap Admin : %v", err)
+	}
+	authServ := api.NewAuthService(conn, userServ, auth, log)
+	authHandlr := api.NewAuthHandlr(authServ, log)
+	staffOnly := authHandlr.RequireRole(api.UserRoleStaff, api.UserRoleAdmin)
+
+	// Added here rather than in InitMiddleware because keys and clients are
+	// looked up in the database; both run after the access token check,
+	// which leaves API keys to APIKeyAuth.
+	e.Use(authHandlr.APIKeyAuth)
+	e.Use(rateLimitMiddleware(limiter, authHandlr.ClientKey, reg, log))
+
+	healthHandlr := api.NewHealthHandlr(dbHealth, health, log)
+
+	e.GET("/healthz", healthHandlr.Healthz)
+	e.GET("/readyz", healthHandlr.Readyz)
+	e.GET("/status/db", healthHandlr.DBStatus)
+
+	api.RegisterStoreMetrics(reg, conn, log)
+	e.GET("/metrics", echo.WrapHandler(reg))
+
+	e.POST("/auth/login", authHandlr.Login)
+	e.POST("/auth/login/mfa", authHandlr.LoginMFA)
+	e.POST("/auth/logout", authHandlr.Logout, authHandlr.RequireUser)
+
+	bookServ := api.NewBookService(conn, listNotifier, log)
 	bookHandlr := api.NewBookHandlr(bookServ, log)
 
-	e.POST("/books", bookHandlr.AddBook)
+	marcServ := api.NewMarcService(conn, log)
+	marcHandlr := api.NewMarcHandlr(marcServ, log)
+
+	e.GET("/books/export", marcHandlr.ExportBooks)
+	e.POST("/books/import", marcHandlr.ImportBooks, staffOnly)
+	e.GET("/books/:id/marc", marcHandlr.ExportBook)
+
+	e.POST("/books", bookHandlr.AddBook, staffOnly)
 	e.GET("/books", bookHandlr.ListAllBooks)
 	e.GET("/books/:id", bookHandlr.GetBookByID)
-	e.PUT("/books/:id", bookHandlr.PutBook)
-	e.DELETE("/books/:id", bookHandlr.DelBook)
+	e.PUT("/books/:id", bookHandlr.PutBook, staffOnly)
+	e.DELETE("/books/:id", bookHandlr.DelBook, staffOnly)
+
+	recommendServ := api.NewRecommendService(conn, log)
+	recommendHandlr := api.NewRecommendHandlr(recommendServ, log)
+
+	e.GET("/books/:id/related", recommendHandlr.GetRelatedBooks)
+	e.GET("/users/:username/recommendations", recommendHandlr.GetRecommendations, authHandlr.RequireUser)
+
+	workServ := api.NewWorkService(conn, log)
+	workHandlr := api.NewWorkHandlr(workServ, log)
+
+	e.POST("/works", workHandlr.AddWork, staffOnly)
+	e.GET("/works", workHandlr.ListWorks)
+	e.GET("/works/:id", workHandlr.GetWork)
+	e.PUT("/works/:id", workHandlr.PutWork, staffOnly)
+	e.DELETE("/works/:id", workHandlr.DeleteWork, staffOnly)
+
+	coverServ := api.NewCoverService(conn, store, log)
+	coverHandlr := api.NewCoverHandlr(coverServ, log)
+
+	e.PUT("/books/:id/cover", coverHandlr.PutCover, staffOnly)
+	e.DELETE("/books/:id/cover", coverHandlr.DeleteCover, staffOnly)
+	e.GET("/books/:id/cover", coverHandlr.ServeCover)
+	e.GET("/books/:id/cover/:size", coverHandlr.ServeCover)
+
+	reviewServ := api.NewReviewService(conn, reviewFilter, log)
+	reviewHandlr := api.NewReviewHandlr(reviewServ, log)
+
+	e.POST("/books/:id/reviews", reviewHandlr.AddReview, authHandlr.RequireUser)
+	e.GET("/books/:id/reviews", reviewHandlr.ListBookReviews)
+	e.POST("/reviews/:id/helpful", reviewHandlr.VoteHelpful, authHandlr.RequireUser)
+	e.GET("/reviews", reviewHandlr.ListReviews, staffOnly)
+	e.PUT("/reviews/:id/moderation", reviewHandlr.ModerateReview, staffOnly)
+
+	authorServ := api.NewAuthorService(conn, log)
+	authorHandlr := api.NewAuthorHandlr(authorServ, log)
+
+	e.POST("/authors", authorHandlr.AddAuthor, staffOnly)
+	e.GET("/authors", authorHandlr.ListAuthors)
+	e.GET("/authors/:id", authorHandlr.GetAuthor)
+	e.PUT("/authors/:id", authorHandlr.PutAuthor, staffOnly)
+	e.DELETE("/authors/:id", authorHandlr.DeleteAuthor, staffOnly)
+	e.GET("/authors/:id/books", authorHandlr.ListAuthorBooks)
+
+	publisherServ := api.NewPublisherService(conn, log)
+	publisherHandlr := api.NewPublisherHandlr(publisherServ, log)
+
+	e.POST("/publishers", publisherHandlr.AddPublisher, staffOnly)
+	e.GET("/publishers", publisherHandlr.ListPublishers)
+	e.GET("/publishers/:id", publisherHandlr.GetPublisher)
+	e.PUT("/publishers/:id", publisherHandlr.PutPublisher, staffOnly)
+	e.DELETE("/publishers/:id", publisherHandlr.DeletePublisher, staffOnly)
+	e.GET("/publishers/:id/books", publisherHandlr.ListPublisherBooks)
+
+	categoryServ := api.NewCategoryService(conn, log)
+	categoryHandlr := api.NewCategoryHandlr(categoryServ, log)
+
+	e.POST("/categories", categoryHandlr.AddCategory, staffOnly)
+	e.POST("/categories/import", categoryHandlr.ImportCategories, staffOnly)
+	e.GET("/categories", categoryHandlr.ListCategories)
+	e.GET("/categories/:id", categoryHandlr.GetCategory)
+	e.PUT("/categories/:id", categoryHandlr.PutCategory, staffOnly)
+	e.DELETE("/categories/:id", categoryHandlr.DeleteCategory, staffOnly)
+	e.GET("/categories/:id/books", categoryHandlr.ListCategoryBooks)
+	e.GET("/books/:id/categories", categoryHandlr.GetBookCategories)
+	e.PUT("/books/:id/categories", categoryHandlr.PutBookCategories, staffOnly)
+
+	tagServ := api.NewTagService(conn, log)
+	tagHandlr := api.NewTagHandlr(tagServ, log)
+
+	e.POST("/tags", tagHandlr.AddTag, staffOnly)
+	e.GET("/tags", tagHandlr.ListTags)
+	e.PUT("/tags/:id", tagHandlr.PutTag, staffOnly)
+	e.DELETE("/tags/:id", tagHandlr.DeleteTag, staffOnly)
+	e.GET("/books/:id/tags", tagHandlr.GetBookTags)
+	e.PUT("/books/:id/tags", tagHandlr.PutBookTags, staffOnly)
+
+	accountHandlr := api.NewAccountHandlr(accountServ, log)
+
+	e.POST("/auth/email/verify", accountHandlr.VerifyEmail)
+	e.POST("/auth/email/verify/resend", accountHandlr.ResendVerification, authHandlr.RequireUser)
+	e.POST("/auth/password/forgot", accountHandlr.ForgotPassword)
+	e.POST("/auth/password/reset", accountHandlr.ResetPassword)
 
-	userServ := api.NewUserService(conn, log)
 	userHandlr := api.NewUserHandler(userServ, log)
 
 	e.POST("/users", userHandlr.AddUser)
-	e.GET("/users/:username", userHandlr.GetUser)
-	e.PUT("/users/:username", userHandlr.PutUser)
-	e.DELETE("/users/:username", userHandlr.DeleteUser)
+	e.GET("/users/:username", userHandlr.GetUser, authHandlr.RequireUser)
+	e.PUT("/users/:username", userHandlr.PutUser, authHandlr.RequireRole(api.UserRoleAdmin))
+	e.PUT("/users/:username/role", userHandlr.PutUserRole, authHandlr.RequireRole(api.UserRoleAdmin))
+	e.DELETE("/users/:username", userHandlr.DeleteUser, authHandlr.RequireRole(api.UserRoleAdmin))
+	e.DELETE("/users/:username/lockout", userHandlr.UnlockUser, authHandlr.RequireRole(api.UserRoleAdmin))
+	e.PUT("/users/:username/profile", userHandlr.PutProfile, authHandlr.RequireUser)
+	e.PUT("/users/:username/email", userHandlr.ChangeEmail, authHandlr.RequireUser)
+	e.PUT("/users/:username/password", userHandlr.ChangePassword, authHandlr.RequireUser)
+	e.PUT("/users/:username/username", userHandlr.ChangeUsername, authHandlr.RequireUser)
+	e.PUT("/admin/users/:username/email", userHandlr.ForceEmail, authHandlr.RequireRole(api.UserRoleAdmin))
+	e.PUT("/admin/users/:username/password", userHandlr.ForcePassword, authHandlr.RequireRole(api.UserRoleAdmin))
+	e.PUT("/admin/users/:username/username", userHandlr.ForceUsername, authHandlr.RequireRole(api.UserRoleAdmin))
+	e.GET("/users/:username/data-export", userHandlr.ExportUserData, authHandlr.RequireUser)
+	e.POST("/users/:username/erase", userHandlr.EraseUser, authHandlr.RequireUser)
+
+	apiKeyServ := api.NewAPIKeyService(conn, log)
+	apiKeyHandlr := api.NewAPIKeyHandlr(apiKeyServ, log)
+
+	e.GET("/users/:username/api-keys", apiKeyHandlr.GetAPIKeys, authHandlr.RequireUser)
+	e.POST("/users/:username/api-keys", apiKeyHandlr.AddAPIKey, authHandlr.RequireUser)
+	e.POST("/users/:username/api-keys/:id/rotate", apiKeyHandlr.RotateAPIKey, authHandlr.RequireUser)
+	e.DELETE("/users/:username/api-keys/:id", apiKeyHandlr.RevokeAPIKey, authHandlr.RequireUser)
+
+	mfaServ := api.NewMFAService(conn, auth, securityLog, log)
+	mfaHandlr := api.NewMFAHandlr(mfaServ, log)
+
+	e.GET("/users/:username/mfa", mfaHandlr.GetMFA, authHandlr.RequireUser)
+	e.POST("/users/:username/mfa", mfaHandlr.StartMFA, authHandlr.RequireUser)
+	e.POST("/users/:username/mfa/confirm", mfaHandlr.ConfirmMFA, authHandlr.RequireUser)
+	e.POST("/users/:username/mfa/recovery-codes", mfaHandlr.ReplaceRecoveryCodes, authHandlr.RequireUser)
+	e.DELETE("/users/:username/mfa", mfaHandlr.DisableMFA, authHandlr.RequireUser)
+
+	listServ := api.NewListService(conn, log)
+	listHandlr := api.NewListHandlr(listServ, log)
+
+	e.GET("/users/:username/lists", listHandlr.GetLists, authHandlr.OptionalUser)
+	e.POST("/users/:username/lists", listHandlr.AddList, authHandlr.RequireUser)
+	e.GET("/users/:username/lists/:id", listHandlr.GetList, authHandlr.OptionalUser)
+	e.PUT("/users/:username/lists/:id", listHandlr.PutList, authHandlr.RequireUser)
+	e.DELETE("/users/:username/lists/:id", listHandlr.DeleteList, authHandlr.RequireUser)
+	e.POST("/users/:username/lists/:id/books", listHandlr.AddListBook, authHandlr.RequireUser)
+	e.PUT("/users/:username/lists/:id/books", listHandlr.ReorderListBooks, authHandlr.RequireUser)
+	e.DELETE("/users/:username/lists/:id/books/:bookId", listHandlr.RemoveListBook, authHandlr.RequireUser)
+	e.GET("/lists/shared/:token", listHandlr.GetSharedList)
+
+	onixServ := api.NewOnixService(conn, listNotifier, log)
+	onixHandlr := api.NewOnixHandlr(onixServ, log)
+
+	e.POST("/feeds/onix", onixHandlr.IngestFeed, staffOnly)
+	e.GET("/feeds/onix", onixHandlr.ExportFeed)
 }
@@ -8,14 +8,14 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/labstack/echo/v4"
 	"github.com/labstack/gommon/log"
+	"github.com/paquesqueue/bookstore/api"
 	"github.com/paquesqueue/bookstore/common"
 	"github.com/sirupsen/logrus"
 )
 
 func StartServer(s *http.Server, config common.Config) {
-	log.Infof("Server starts on port %v", config.Port)
+	log.Infof("Server starts on port %v", config.Server.Port)
 	go func() {
 		err := s.ListenAndServe()
 		if err != nil && err != http.ErrServerClosed {
@@ -24,18 +24,55 @@ func StartServer(s *http.Server, config common.Config) {
 	}()
 }
 
-func GracefulShutdown(e *echo.Echo, log *logrus.Logger) {
+// GracefulShutdown blocks until SIGINT or SIGTERM, reloading the config on
+// every SIGHUP meanwhile, then drains s. Readiness fails for the drain delay
+// before s stops accepting requests and waits for those in flight.
+func GracefulShutdown(s *http.Server, log *logrus.Logger, live *common.LiveConfig, health *api.HealthServices) {
 	signals := make(chan os.Signal, 1)
-	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
 
-	<-signals
+	for sig := range signals {
+		if sig != syscall.SIGHUP {
+			break
+		}
+		ReloadConfig(live, log)
+	}
 
 	log.Info("App is shutting down...")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	health.Drain()
+	if delay := live.Get().Server.DrainDelay; delay > 0 {
+		log.Infof("Draining for %v before shutdown", delay)
+		time.Sleep(delay)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), live.Get().Server.ShutdownTimeout)
 	defer cancel()
 
-	if err := e.Shutdown(ctx); err != nil {
+	if err := s.Shutdown(ctx); err != nil {
 		log.Fatalf("Error Server Shut Down : %s", err)
 	}
 	log.Info("Server is fully shutdown")
 }
+
+// ReloadConfig reloads the live config and logs what changed, or why the new
+// config was rejected.
+func ReloadConfig(live *common.LiveConfig, log *logrus.Logger) {
+	changes, err := live.Reload()
+	if err != nil {
+		log.Errorf("Error Config Reload Rejected : %v", err)
+		return
+	}
+	if len(changes) == 0 {
+		log.Info("Success Config Reloaded : no changes")
+		return
+	}
+	for _, change := range changes {
+		log.WithFields(logrus.Fields{
+			"key":     change.Key,
+			"old":     change.Old,
+			"new":     change.New,
+			"applied": change.Applied,
+		}).Info("config change")
+	}
+	log.Infof("Success Config Reloaded : %d changes", len(changes))
+}
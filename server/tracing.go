@@ -0,0 +1,47 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/paquesqueue/bookstore/tracing"
+)
+
+// tracingMiddleware starts a server span for every request, continuing the
+// caller's trace when it sends a traceparent header, and hands it to the
+// handlers through the request context. The response carries the trace back.
+func tracingMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			route := c.Path()
+			if route == "" {
+				route = unmatchedRoute
+			}
+
+			ctx := tracing.Extract(req.Context(), req.Header)
+			ctx, span := tracing.Start(ctx, req.Method+" "+route, tracing.WithKind(tracing.KindServer), tracing.WithAttributes(
+				tracing.String("http.method", req.Method),
+				tracing.String("http.route", route),
+				tracing.String("http.target", req.URL.RequestURI()),
+				tracing.String("net.peer.ip", c.RealIP()),
+			))
+			defer span.End()
+
+			c.SetRequest(req.WithContext(ctx))
+			tracing.Inject(ctx, c.Response().Header())
+
+			err := next(c)
+
+			status := responseStatus(c, err)
+			span.SetAttributes(tracing.Int("http.status_code", status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(tracing.StatusError, http.StatusText(status))
+			}
+			if err != nil {
+				span.RecordError(err)
+			}
+			return err
+		}
+	}
+}
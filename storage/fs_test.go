@@ -0,0 +1,67 @@
+//go:build unit
+
+package storage
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileStore(t *testing.T) {
+	t.Run("TestFileStoreShouldRoundTripBlob", func(t *testing.T) {
+		// Arrange
+		store, err := NewFileStore(t.TempDir())
+		assert.NoError(t, err)
+
+		// Act
+		err = store.Put(context.Background(), "covers/1/abc/original.png", strings.NewReader("0123456789"), Info{})
+		assert.NoError(t, err)
+		obj, err := store.Open(context.Background(), "covers/1/abc/original.png")
+
+		// Assert
+		assert.NoError(t, err)
+		defer obj.Close()
+		assert.Equal(t, int64(10), obj.Info().Size)
+		assert.Equal(t, "image/png", obj.Info().ContentType)
+
+		_, err = obj.Seek(4, io.SeekStart)
+		assert.NoError(t, err)
+		rest, err := io.ReadAll(obj)
+		assert.NoError(t, err)
+		assert.Equal(t, "456789", string(rest))
+	})
+
+	t.Run("TestFileStoreShouldDeleteBlob", func(t *testing.T) {
+		// Arrange
+		store, err := NewFileStore(t.TempDir())
+		assert.NoError(t, err)
+		assert.NoError(t, store.Put(context.Background(), "a/b.jpg", strings.NewReader("x"), Info{}))
+
+		// Act
+		err = store.Delete(context.Background(), "a/b.jpg")
+
+		// Assert
+		assert.NoError(t, err)
+		_, err = store.Open(context.Background(), "a/b.jpg")
+		assert.Equal(t, ErrNotFound, err)
+		assert.NoError(t, store.Delete(context.Background(), "a/b.jpg"))
+	})
+
+	t.Run("TestFileStoreShouldRejectEscapingKeys", func(t *testing.T) {
+		// Arrange
+		store, err := NewFileStore(t.TempDir())
+		assert.NoError(t, err)
+
+		for _, key := range []string{"../secret", "/etc/passwd", "a/../../b", "", "a//b"} {
+			// Act
+			err := store.Put(context.Background(), key, strings.NewReader("x"), Info{})
+
+			// Assert
+			assert.Error(t, err, key)
+		}
+	})
+}
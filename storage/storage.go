@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/paquesqueue/bookstore/common"
+)
+
+const (
+	DriverFS = "fs"
+	DriverS3 = "s3"
+
+	defaultDir    = "assets"
+	defaultRegion = "us-east-1"
+)
+
+var ErrNotFound = errors.New("error blob not found")
+
+// BlobStore keeps binary assets such as book covers under slash separated
+// keys, e.g. "covers/1/5f2b/original.jpg".
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, info Info) error
+	Open(ctx context.Context, key string) (Object, error)
+	Delete(ctx context.Context, key string) error
+}
+
+type Info struct {
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
+// Object is an opened blob. It is seekable so it can be served with
+// http.ServeContent, which answers range requests by seeking.
+type Object interface {
+	io.ReadSeeker
+	io.Closer
+	Info() Info
+}
+
+// InitBlobStore returns the store selected by the storage driver, defaulting
+// to the local filesystem.
+func InitBlobStore(c common.StorageConfig) (BlobStore, error) {
+	switch c.Driver {
+	case "", DriverFS:
+		dir := c.Dir
+		if dir == "" {
+			dir = defaultDir
+		}
+		return NewFileStore(dir)
+	case DriverS3:
+		region := c.S3Region
+		if region == "" {
+			region = defaultRegion
+		}
+		return NewS3Store(S3Config{
+			Endpoint:  c.S3Endpoint,
+			Region:    region,
+			Bucket:    c.S3Bucket,
+			AccessKey: c.S3AccessKey,
+			SecretKey: c.S3SecretKey,
+		})
+	default:
+		return nil, fmt.Errorf("error unsupported storage driver %q", c.Driver)
+	}
+}
+
+// cleanKey rejects keys that would escape the store, such as "../x" or "/x".
+func cleanKey(key string) (string, error) {
+	cleaned := path.Clean(key)
+	if key == "" || cleaned != key || strings.HasPrefix(cleaned, "/") || strings.HasPrefix(cleaned, "..") {
+		return "", fmt.Errorf("error invalid blob key %q", key)
+	}
+	return cleaned, nil
+}
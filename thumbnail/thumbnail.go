@@ -0,0 +1,109 @@
+// Package thumbnail scales cover images down to fixed widths using the
+// standard library only.
+package thumbnail
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+)
+
+const (
+	ContentTypeJPEG = "image/jpeg"
+	ContentTypePNG  = "image/png"
+
+	// MaxPixels bounds the decoded size of an image so a small, highly
+	// compressed upload cannot exhaust memory.
+	MaxPixels = 40_000_000
+
+	jpegQuality = 85
+)
+
+var ErrTooLarge = errors.New("error image dimensions too large")
+
+// Decode reads a JPEG or PNG image after checking its dimensions.
+func Decode(r io.ReadSeeker) (image.Image, error) {
+	cfg, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || int64(cfg.Width)*int64(cfg.Height) > MaxPixels {
+		return nil, ErrTooLarge
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(r)
+	return img, err
+}
+
+// Resize scales src to the given width keeping its aspect ratio. Each
+// destination pixel is the average of the source pixels it covers, which
+// gives clean results when shrinking. Images narrower than width are only
+// copied, never enlarged.
+func Resize(src image.Image, width int) *image.RGBA {
+	b := src.Bounds()
+	if width <= 0 || width > b.Dx() {
+		width = b.Dx()
+	}
+	height := b.Dy() * width / b.Dx()
+	if height < 1 {
+		height = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := b.Min.Y + y*b.Dy()/height
+		y1 := b.Min.Y + (y+1)*b.Dy()/height
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < width; x++ {
+			x0 := b.Min.X + x*b.Dx()/width
+			x1 := b.Min.X + (x+1)*b.Dx()/width
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+			dst.SetRGBA(x, y, average(src, x0, y0, x1, y1))
+		}
+	}
+	return dst
+}
+
+func average(src image.Image, x0, y0, x1, y1 int) color.RGBA {
+	var r, g, b, a, n uint64
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			cr, cg, cb, ca := src.At(x, y).RGBA()
+			r += uint64(cr)
+			g += uint64(cg)
+			b += uint64(cb)
+			a += uint64(ca)
+			n++
+		}
+	}
+	return color.RGBA{
+		R: uint8(r / n >> 8),
+		G: uint8(g / n >> 8),
+		B: uint8(b / n >> 8),
+		A: uint8(a / n >> 8),
+	}
+}
+
+// EncodeJPEG writes img as a JPEG. Transparent areas of PNG covers are
+// flattened onto white first, since JPEG has no alpha channel.
+func EncodeJPEG(w io.Writer, img *image.RGBA) error {
+	flat := image.NewRGBA(img.Bounds())
+	for i := 0; i < len(img.Pix); i += 4 {
+		alpha := uint32(img.Pix[i+3])
+		for c := 0; c < 3; c++ {
+			// Premultiplied colour plus white for the uncovered part.
+			flat.Pix[i+c] = uint8(uint32(img.Pix[i+c]) + 255 - alpha)
+		}
+		flat.Pix[i+3] = 255
+	}
+	return jpeg.Encode(w, flat, &jpeg.Options{Quality: jpegQuality})
+}
@@ -0,0 +1,81 @@
+//go:build unit
+
+package thumbnail
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecode(t *testing.T) {
+	t.Run("TestDecodeShouldRejectOversizedDimensions", func(t *testing.T) {
+		// Arrange
+		// Only the header is needed to read the dimensions.
+		var buf bytes.Buffer
+		assert.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, 10000, 5000))))
+
+		// Act
+		_, err := Decode(bytes.NewReader(buf.Bytes()))
+
+		// Assert
+		assert.Equal(t, ErrTooLarge, err)
+	})
+}
+
+func TestResize(t *testing.T) {
+	t.Run("TestResizeShouldKeepAspectRatioAndAverage", func(t *testing.T) {
+		// Arrange
+		src := image.NewRGBA(image.Rect(0, 0, 4, 2))
+		for y := 0; y < 2; y++ {
+			src.Set(0, y, color.RGBA{255, 0, 0, 255})
+			src.Set(1, y, color.RGBA{255, 0, 0, 255})
+			src.Set(2, y, color.RGBA{0, 0, 255, 255})
+			src.Set(3, y, color.RGBA{0, 0, 255, 255})
+		}
+
+		// Act
+		dst := Resize(src, 2)
+
+		// Assert
+		assert.Equal(t, image.Rect(0, 0, 2, 1), dst.Bounds())
+		assert.Equal(t, color.RGBA{255, 0, 0, 255}, dst.RGBAAt(0, 0))
+		assert.Equal(t, color.RGBA{0, 0, 255, 255}, dst.RGBAAt(1, 0))
+	})
+
+	t.Run("TestResizeShouldNotUpscale", func(t *testing.T) {
+		// Arrange
+		src := image.NewRGBA(image.Rect(0, 0, 50, 80))
+
+		// Act
+		dst := Resize(src, 640)
+
+		// Assert
+		assert.Equal(t, image.Rect(0, 0, 50, 80), dst.Bounds())
+	})
+}
+
+func TestEncodeJPEG(t *testing.T) {
+	t.Run("TestEncodeJPEGShouldFlattenTransparencyOntoWhite", func(t *testing.T) {
+		// Arrange
+		img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+
+		// Act
+		var buf bytes.Buffer
+		err := EncodeJPEG(&buf, img)
+
+		// Assert
+		assert.NoError(t, err)
+		decoded, err := jpeg.Decode(&buf)
+		assert.NoError(t, err)
+		r, g, b, _ := decoded.At(4, 4).RGBA()
+		assert.Greater(t, r>>8, uint32(250))
+		assert.Greater(t, g>>8, uint32(250))
+		assert.Greater(t, b>>8, uint32(250))
+	})
+}
@@ -0,0 +1,110 @@
+//go:build unit
+
+package totp
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rfcSecret is the SHA-1 key of the RFC 6238 test vectors.
+var rfcSecret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestCode(t *testing.T) {
+	t.Run("TestCodeShouldMatchRFC6238", func(t *testing.T) {
+		// The RFC lists 8 digit codes; 6 digit codes are their last 6.
+		for unix, want := range map[int64]string{
+			59:          "287082",
+			1111111109:  "081804",
+			1111111111:  "050471",
+			1234567890:  "005924",
+			2000000000:  "279037",
+			20000000000: "353130",
+		} {
+			// Act
+			code, err := Code(rfcSecret, Step(time.Unix(unix, 0)))
+
+			// Assert
+			assert.NoError(t, err)
+			assert.Equal(t, want, code, "time %d", unix)
+		}
+	})
+
+	t.Run("TestCodeShouldRejectBadSecret", func(t *testing.T) {
+		// Act
+		_, err := Code("not base32!", 1)
+
+		// Assert
+		assert.Error(t, err)
+	})
+}
+
+func TestVerify(t *testing.T) {
+	t.Run("TestVerifyShouldAcceptCodesWithinSkew", func(t *testing.T) {
+		// Arrange
+		at := time.Unix(1111111109, 0)
+		previous, err := Code(rfcSecret, Step(at)-1)
+		assert.NoError(t, err)
+
+		// Act
+		step, ok, err := Verify(rfcSecret, previous, at, 1)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, Step(at)-1, step)
+	})
+
+	t.Run("TestVerifyShouldRejectCodesOutsideSkew", func(t *testing.T) {
+		// Arrange
+		at := time.Unix(1111111109, 0)
+		old, err := Code(rfcSecret, Step(at)-2)
+		assert.NoError(t, err)
+
+		for _, code := range []string{old, "000000", "12345", ""} {
+			// Act
+			_, ok, err := Verify(rfcSecret, code, at, 1)
+
+			// Assert
+			assert.NoError(t, err)
+			assert.False(t, ok, code)
+		}
+	})
+}
+
+func TestNewSecret(t *testing.T) {
+	t.Run("TestNewSecretShouldMakeUsableSecrets", func(t *testing.T) {
+		// Act
+		secret, err := NewSecret()
+		other, _ := NewSecret()
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Len(t, secret, 32)
+		assert.NotEqual(t, other, secret)
+		_, err = Code(secret, 1)
+		assert.NoError(t, err)
+	})
+}
+
+func TestURI(t *testing.T) {
+	t.Run("TestURIShouldLabelWithIssuerAndAccount", func(t *testing.T) {
+		// Act
+		uri := URI("Bookstore", "tester", "SECRET")
+
+		// Assert
+		u, err := url.Parse(uri)
+		if assert.NoError(t, err) {
+			assert.Equal(t, "otpauth", u.Scheme)
+			assert.Equal(t, "totp", u.Host)
+			assert.Equal(t, "/Bookstore:tester", u.Path)
+			assert.Equal(t, "SECRET", u.Query().Get("secret"))
+			assert.Equal(t, "Bookstore", u.Query().Get("issuer"))
+			assert.Equal(t, "6", u.Query().Get("digits"))
+		}
+	})
+}
@@ -0,0 +1,308 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/paquesqueue/bookstore/common"
+)
+
+const (
+	batchSize     = 256
+	queueSize     = 4096
+	batchInterval = 2 * time.Second
+	exportTimeout = 10 * time.Second
+)
+
+// Exporter sends finished spans somewhere. Export is never called
+// concurrently.
+type Exporter interface {
+	Export(ctx context.Context, spans []SpanData) error
+}
+
+// batcher queues spans and exports them in batches from one goroutine.
+// Spans are dropped while the queue is full rather than slowing requests.
+type batcher struct {
+	exp   Exporter
+	queue chan SpanData
+	flush chan chan struct{}
+	done  chan struct{}
+	once  sync.Once
+}
+
+func newBatcher(exp Exporter) *batcher {
+	b := &batcher{exp: exp}
+	if exp == nil {
+		return b
+	}
+	b.queue = make(chan SpanData, queueSize)
+	b.flush = make(chan chan struct{})
+	b.done = make(chan struct{})
+	go b.run()
+	return b
+}
+
+func (b *batcher) add(data SpanData) {
+	if b.queue == nil {
+		return
+	}
+	select {
+	case b.queue <- data:
+	default:
+	}
+}
+
+func (b *batcher) run() {
+	ticker := time.NewTicker(batchInterval)
+	defer ticker.Stop()
+
+	batch := make([]SpanData, 0, batchSize)
+	export := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+		_ = b.exp.Export(ctx, batch)
+		cancel()
+		batch = make([]SpanData, 0, batchSize)
+	}
+
+	for {
+		select {
+		case data := <-b.queue:
+			batch = append(batch, data)
+			if len(batch) >= batchSize {
+				export()
+			}
+		case <-ticker.C:
+			export()
+		case flushed := <-b.flush:
+			for len(b.queue) > 0 {
+				batch = append(batch, <-b.queue)
+			}
+			export()
+			close(flushed)
+		case <-b.done:
+			return
+		}
+	}
+}
+
+func (b *batcher) shutdown(ctx context.Context) error {
+	if b.queue == nil {
+		return nil
+	}
+	flushed := make(chan struct{})
+	select {
+	case b.flush <- flushed:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-flushed:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	b.once.Do(func() { close(b.done) })
+	return nil
+}
+
+// InitTracer builds the tracer the config asks for; the stdout exporter
+// writes to w.
+func InitTracer(c common.TracingConfig, w io.Writer) (*Tracer, error) {
+	switch c.Exporter {
+	case "", common.TracingExporterNone:
+		return NewTracer(0, nil), nil
+	case common.TracingExporterStdout:
+		return NewTracer(c.SampleRatio, NewStdoutExporter(w)), nil
+	case common.TracingExporterOTLP:
+		return NewTracer(c.SampleRatio, NewOTLPExporter(c.Endpoint, c.ServiceName)), nil
+	default:
+		return nil, fmt.Errorf("error unsupported tracing exporter %q", c.Exporter)
+	}
+}
+
+// StdoutExporter writes one JSON object per span and line, for local runs
+// and tests.
+type StdoutExporter struct {
+	w io.Writer
+}
+
+func NewStdoutExporter(w io.Writer) *StdoutExporter {
+	return &StdoutExporter{w}
+}
+
+type stdoutSpan struct {
+	TraceID    string                 `json:"trace_id"`
+	SpanID     string                 `json:"span_id"`
+	ParentID   string                 `json:"parent_id,omitempty"`
+	Name       string                 `json:"name"`
+	Kind       SpanKind               `json:"kind"`
+	Start      time.Time              `json:"start"`
+	DurationMs float64                `json:"duration_ms"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+	Status     StatusCode             `json:"status,omitempty"`
+	Message    string                 `json:"status_message,omitempty"`
+}
+
+func (e *StdoutExporter) Export(ctx context.Context, spans []SpanData) error {
+	enc := json.NewEncoder(e.w)
+	for _, s := range spans {
+		out := stdoutSpan{
+			TraceID:    s.SpanContext.TraceID.String(),
+			SpanID:     s.SpanContext.SpanID.String(),
+			Name:       s.Name,
+			Kind:       s.Kind,
+			Start:      s.Start,
+			DurationMs: float64(s.End.Sub(s.Start).Microseconds()) / 1000,
+			Status:     s.StatusCode,
+			Message:    s.StatusMessage,
+		}
+		if s.Parent.IsValid() {
+			out.ParentID = s.Parent.String()
+		}
+		if len(s.Attributes) > 0 {
+			out.Attributes = map[string]interface{}{}
+			for _, a := range s.Attributes {
+				out.Attributes[a.Key] = a.Value
+			}
+		}
+		if err := enc.Encode(out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OTLPExporter posts spans to an OpenTelemetry collector with OTLP/HTTP in
+// its JSON encoding.
+type OTLPExporter struct {
+	url      string
+	resource []otlpKeyValue
+	client   *http.Client
+}
+
+// NewOTLPExporter sends to endpoint, e.g. http://localhost:4318, reporting
+// serviceName as the resource's service.name.
+func NewOTLPExporter(endpoint string, serviceName string) *OTLPExporter {
+	return &OTLPExporter{
+		url:      strings.TrimSuffix(endpoint, "/") + "/v1/traces",
+		resource: []otlpKeyValue{otlpAttribute(String("service.name", serviceName))},
+		client:   &http.Client{Timeout: exportTimeout},
+	}
+}
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              SpanKind       `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    StatusCode `json:"code,omitempty"`
+	Message string     `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+// otlpAttribute encodes a value the way OTLP JSON expects; 64-bit integers
+// are strings.
+func otlpAttribute(a Attribute) otlpKeyValue {
+	var value map[string]interface{}
+	switch v := a.Value.(type) {
+	case string:
+		value = map[string]interface{}{"stringValue": v}
+	case int64:
+		value = map[string]interface{}{"intValue": strconv.FormatInt(v, 10)}
+	case float64:
+		value = map[string]interface{}{"doubleValue": v}
+	case bool:
+		value = map[string]interface{}{"boolValue": v}
+	default:
+		value = map[string]interface{}{"stringValue": fmt.Sprint(v)}
+	}
+	return otlpKeyValue{a.Key, value}
+}
+
+func (e *OTLPExporter) Export(ctx context.Context, spans []SpanData) error {
+	out := make([]otlpSpan, len(spans))
+	for i, s := range spans {
+		out[i] = otlpSpan{
+			TraceID:           s.SpanContext.TraceID.String(),
+			SpanID:            s.SpanContext.SpanID.String(),
+			Name:              s.Name,
+			Kind:              s.Kind,
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+			Status:            otlpStatus{s.StatusCode, s.StatusMessage},
+		}
+		if s.Parent.IsValid() {
+			out[i].ParentSpanID = s.Parent.String()
+		}
+		for _, a := range s.Attributes {
+			out[i].Attributes = append(out[i].Attributes, otlpAttribute(a))
+		}
+	}
+
+	body, err := json.Marshal(otlpRequest{[]otlpResourceSpans{{
+		Resource:   otlpResource{e.resource},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{"github.com/paquesqueue/bookstore"}, Spans: out}},
+	}}})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("error otlp export : %v", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+//go:build unit
+
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paquesqueue/bookstore/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func testSpan() SpanData {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	return SpanData{
+		Name:        "Query.SelectAllBooks",
+		Kind:        KindClient,
+		SpanContext: SpanContext{TraceID: TraceID{0xab}, SpanID: SpanID{0xcd}, Sampled: true},
+		Parent:      SpanID{0xef},
+		Start:       start,
+		End:         start.Add(1500 * time.Microsecond),
+		Attributes:  []Attribute{String("db.system", "postgresql"), Int("rows", 3)},
+	}
+}
+
+func TestStdoutExporter(t *testing.T) {
+	t.Run("TestStdoutExporterShouldWriteOneLinePerSpan", func(t *testing.T) {
+		// Arrange
+		var out strings.Builder
+
+		// Act
+		err := NewStdoutExporter(&out).Export(context.Background(), []SpanData{testSpan(), testSpan()})
+
+		// Assert
+		assert.NoError(t, err)
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		assert.Len(t, lines, 2)
+		assert.JSONEq(t, `{
+			"trace_id": "ab000000000000000000000000000000",
+			"span_id": "cd00000000000000",
+			"parent_id": "ef00000000000000",
+			"name": "Query.SelectAllBooks",
+			"kind": 3,
+			"start": "2024-01-02T03:04:05Z",
+			"duration_ms": 1.5,
+			"attributes": {"db.system": "postgresql", "rows": 3}
+		}`, lines[0])
+	})
+}
+
+func TestOTLPExporter(t *testing.T) {
+	t.Run("TestOTLPExporterShouldPostSpansAsJSON", func(t *testing.T) {
+		// Arrange
+		var path string
+		var body map[string]interface{}
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+			raw, _ := io.ReadAll(r.Body)
+			_ = json.Unmarshal(raw, &body)
+		}))
+		defer srv.Close()
+
+		// Act
+		err := NewOTLPExporter(srv.URL+"/", "bookstore").Export(context.Background(), []SpanData{testSpan()})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "/v1/traces", path)
+		rs := body["resourceSpans"].([]interface{})[0].(map[string]interface{})
+		resource := rs["resource"].(map[string]interface{})["attributes"].([]interface{})[0]
+		assert.Equal(t, map[string]interface{}{"key": "service.name", "value": map[string]interface{}{"stringValue": "bookstore"}}, resource)
+		span := rs["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, "ab000000000000000000000000000000", span["traceId"])
+		assert.Equal(t, "ef00000000000000", span["parentSpanId"])
+		assert.Equal(t, "1704164645000000000", span["startTimeUnixNano"])
+		assert.Equal(t, map[string]interface{}{"key": "rows", "value": map[string]interface{}{"intValue": "3"}}, span["attributes"].([]interface{})[1])
+	})
+
+	t.Run("TestOTLPExporterShouldFailOnCollectorError", func(t *testing.T) {
+		// Arrange
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer srv.Close()
+
+		// Act
+		err := NewOTLPExporter(srv.URL, "bookstore").Export(context.Background(), []SpanData{testSpan()})
+
+		// Assert
+		assert.Error(t, err)
+	})
+}
+
+func TestInitTracer(t *testing.T) {
+	t.Run("TestInitTracerShouldRejectUnknownExporter", func(t *testing.T) {
+		// Act
+		_, err := InitTracer(common.TracingConfig{Exporter: "zipkin"}, io.Discard)
+
+		// Assert
+		assert.Error(t, err)
+	})
+
+	t.Run("TestInitTracerShouldWriteStdoutSpans", func(t *testing.T) {
+		// Arrange
+		var out strings.Builder
+		tracer, err := InitTracer(common.TracingConfig{Exporter: common.TracingExporterStdout, SampleRatio: 1}, &out)
+		assert.NoError(t, err)
+
+		// Act
+		_, span := tracer.Start(context.Background(), "GET /books")
+		span.End()
+		assert.NoError(t, tracer.Shutdown(context.Background()))
+
+		// Assert
+		assert.Contains(t, out.String(), `"name":"GET /books"`)
+	})
+}
@@ -0,0 +1,24 @@
+package tracing
+
+import "github.com/sirupsen/logrus"
+
+// LogHook adds trace_id and span_id to entries logged with a traced
+// context, e.g. log.WithContext(ctx).
+type LogHook struct{}
+
+func (LogHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (LogHook) Fire(entry *logrus.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+	sc := SpanContextFromContext(entry.Context)
+	if !sc.IsValid() {
+		return nil
+	}
+	entry.Data["trace_id"] = sc.TraceID.String()
+	entry.Data["span_id"] = sc.SpanID.String()
+	return nil
+}
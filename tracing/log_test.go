@@ -0,0 +1,34 @@
+//go:build unit
+
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogHook(t *testing.T) {
+	t.Run("TestLogHookShouldAddTraceIDs", func(t *testing.T) {
+		// Arrange
+		var out bytes.Buffer
+		log := logrus.New()
+		log.SetOutput(&out)
+		log.SetFormatter(&logrus.JSONFormatter{})
+		log.AddHook(LogHook{})
+		ctx, span := NewTracer(0, nil).Start(context.Background(), "GET /books")
+
+		// Act
+		log.WithContext(ctx).Info("request")
+		log.Info("startup")
+
+		// Assert
+		lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+		assert.Contains(t, string(lines[0]), `"trace_id":"`+span.SpanContext().TraceID.String()+`"`)
+		assert.Contains(t, string(lines[0]), `"span_id":"`+span.SpanContext().SpanID.String()+`"`)
+		assert.NotContains(t, string(lines[1]), "trace_id")
+	})
+}
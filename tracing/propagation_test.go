@@ -0,0 +1,112 @@
+//go:build unit
+
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTraceparent(t *testing.T) {
+	t.Run("TestTraceparentShouldRoundTrip", func(t *testing.T) {
+		// Arrange
+		value := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+		// Act
+		sc, ok := ParseTraceparent(value)
+
+		// Assert
+		assert.True(t, ok)
+		assert.True(t, sc.Sampled)
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String())
+		assert.Equal(t, value, sc.Traceparent())
+	})
+
+	t.Run("TestTraceparentShouldRejectMalformedValues", func(t *testing.T) {
+		for _, value := range []string{
+			"",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+			"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+			"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		} {
+			_, ok := ParseTraceparent(value)
+			assert.False(t, ok, value)
+		}
+	})
+}
+
+func TestExtract(t *testing.T) {
+	t.Run("TestExtractShouldContinueIncomingTrace", func(t *testing.T) {
+		// Arrange
+		h := http.Header{}
+		h.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		tracer := NewTracer(0, &recorder{})
+
+		// Act
+		ctx := Extract(context.Background(), h)
+		ctx, span := tracer.Start(ctx, "GET /books")
+		out := http.Header{}
+		Inject(ctx, out)
+
+		// Assert
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID.String())
+		assert.True(t, span.SpanContext().Sampled)
+		assert.Equal(t, span.SpanContext().Traceparent(), out.Get(TraceparentHeader))
+	})
+
+	t.Run("TestExtractShouldIgnoreMissingHeader", func(t *testing.T) {
+		// Act
+		ctx := Extract(context.Background(), http.Header{})
+		out := http.Header{}
+		Inject(ctx, out)
+
+		// Assert
+		assert.False(t, SpanContextFromContext(ctx).IsValid())
+		assert.Empty(t, out.Get(TraceparentHeader))
+	})
+}
+
+func TestTransport(t *testing.T) {
+	t.Run("TestTransportShouldSendTraceparentAndRecordClientSpan", func(t *testing.T) {
+		// Arrange
+		var received string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r.Header.Get(TraceparentHeader)
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer srv.Close()
+
+		rec := &recorder{}
+		tracer := NewTracer(1, rec)
+		SetTracer(tracer)
+		defer SetTracer(NewTracer(0, nil))
+		ctx, parent := Start(context.Background(), "PUT /books/:id/cover")
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/covers/1", nil)
+		assert.NoError(t, err)
+
+		// Act
+		resp, err := (&http.Client{Transport: Transport{}}).Do(req)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		parent.End()
+		assert.NoError(t, tracer.Shutdown(context.Background()))
+
+		// Assert
+		sc, ok := ParseTraceparent(received)
+		assert.True(t, ok)
+		assert.Equal(t, parent.SpanContext().TraceID, sc.TraceID)
+		assert.Len(t, rec.spans, 2)
+		assert.Equal(t, "HTTP GET", rec.spans[0].Name)
+		assert.Equal(t, KindClient, rec.spans[0].Kind)
+		assert.Equal(t, sc.SpanID, rec.spans[0].SpanContext.SpanID)
+		assert.Equal(t, StatusError, rec.spans[0].StatusCode)
+	})
+}
@@ -0,0 +1,269 @@
+// Package tracing records spans that follow a request through the handler,
+// service and database layers, propagates them with W3C trace context and
+// exports them in the OpenTelemetry format.
+package tracing
+
+import (
+	"context"
+	crand "crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type TraceID [16]byte
+
+func (t TraceID) IsValid() bool { return t != TraceID{} }
+
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+
+type SpanID [8]byte
+
+func (s SpanID) IsValid() bool { return s != SpanID{} }
+
+func (s SpanID) String() string { return hex.EncodeToString(s[:]) }
+
+// SpanContext identifies a span across process boundaries. Remote is set for
+// a parent extracted from an incoming request.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+	Remote  bool
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// SpanKind follows the OpenTelemetry numbering.
+type SpanKind int
+
+const (
+	KindInternal SpanKind = 1
+	KindServer   SpanKind = 2
+	KindClient   SpanKind = 3
+)
+
+// StatusCode follows the OpenTelemetry numbering.
+type StatusCode int
+
+const (
+	StatusUnset StatusCode = 0
+	StatusOK    StatusCode = 1
+	StatusError StatusCode = 2
+)
+
+// Attribute is a span attribute; Value is a string, int64, float64 or bool.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+func String(key string, value string) Attribute { return Attribute{key, value} }
+
+func Int(key string, value int) Attribute { return Attribute{key, int64(value)} }
+
+func Int64(key string, value int64) Attribute { return Attribute{key, value} }
+
+func Float64(key string, value float64) Attribute { return Attribute{key, value} }
+
+func Bool(key string, value bool) Attribute { return Attribute{key, value} }
+
+// SpanData is a finished span as handed to an Exporter.
+type SpanData struct {
+	Name          string
+	Kind          SpanKind
+	SpanContext   SpanContext
+	Parent        SpanID
+	Start         time.Time
+	End           time.Time
+	Attributes    []Attribute
+	StatusCode    StatusCode
+	StatusMessage string
+}
+
+// Span is one timed operation. Spans that are not sampled keep their ids
+// so they still propagate, but record nothing. A nil *Span is valid and does
+// nothing.
+type Span struct {
+	tracer *Tracer
+	sc     SpanContext
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+func (s *Span) recording() bool {
+	return s != nil && s.sc.Sampled
+}
+
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if !s.recording() {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Attributes = append(s.data.Attributes, attrs...)
+}
+
+func (s *Span) SetStatus(code StatusCode, message string) {
+	if !s.recording() {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.StatusCode = code
+	s.data.StatusMessage = message
+}
+
+// RecordError marks the span failed with err's message.
+func (s *Span) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	s.SetAttributes(String("exception.message", err.Error()))
+	s.SetStatus(StatusError, err.Error())
+}
+
+// End finishes the span and queues it for export; later calls do nothing.
+func (s *Span) End() {
+	if !s.recording() {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = s.tracer.now()
+	data := s.data
+	s.mu.Unlock()
+
+	s.tracer.batcher.add(data)
+}
+
+// StartOption configures a span being started.
+type StartOption func(d *SpanData)
+
+func WithKind(kind SpanKind) StartOption {
+	return func(d *SpanData) { d.Kind = kind }
+}
+
+func WithAttributes(attrs ...Attribute) StartOption {
+	return func(d *SpanData) { d.Attributes = append(d.Attributes, attrs...) }
+}
+
+// Tracer starts spans and hands the sampled ones to its exporter.
+type Tracer struct {
+	ratio   float64
+	batcher *batcher
+	now     func() time.Time
+
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+// NewTracer samples ratio of the traces started here; traces continued from
+// a parent keep the parent's decision. A nil exporter records nothing.
+func NewTracer(ratio float64, exp Exporter) *Tracer {
+	var seed [8]byte
+	_, _ = crand.Read(seed[:])
+	t := &Tracer{
+		ratio: ratio,
+		now:   time.Now,
+		rnd:   rand.New(rand.NewSource(int64(binary.LittleEndian.Uint64(seed[:])))),
+	}
+	if exp == nil {
+		t.ratio = 0
+	}
+	t.batcher = newBatcher(exp)
+	return t
+}
+
+// Start begins a span as a child of the span in ctx, or a new trace, and
+// returns a context carrying it.
+func (t *Tracer) Start(ctx context.Context, name string, opts ...StartOption) (context.Context, *Span) {
+	parent := SpanContextFromContext(ctx)
+
+	t.mu.Lock()
+	sc := SpanContext{TraceID: parent.TraceID, Sampled: parent.Sampled}
+	if !parent.IsValid() {
+		t.rnd.Read(sc.TraceID[:])
+		sc.Sampled = t.ratio > 0 && t.rnd.Float64() < t.ratio
+	}
+	t.rnd.Read(sc.SpanID[:])
+	t.mu.Unlock()
+
+	span := &Span{tracer: t, sc: sc}
+	span.data = SpanData{Name: name, Kind: KindInternal, SpanContext: sc, Parent: parent.SpanID, Start: t.now()}
+	for _, opt := range opts {
+		opt(&span.data)
+	}
+	return ContextWithSpan(ctx, span), span
+}
+
+// Shutdown exports the spans still queued.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	return t.batcher.shutdown(ctx)
+}
+
+var global atomic.Pointer[Tracer]
+
+func init() {
+	global.Store(NewTracer(0, nil))
+}
+
+// SetTracer makes t the tracer used by Start.
+func SetTracer(t *Tracer) {
+	global.Store(t)
+}
+
+// Start begins a span with the tracer set by SetTracer; until one is set,
+// spans only propagate ids.
+func Start(ctx context.Context, name string, opts ...StartOption) (context.Context, *Span) {
+	return global.Load().Start(ctx, name, opts...)
+}
+
+type spanKey struct{}
+
+type remoteKey struct{}
+
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// ContextWithRemoteParent returns a context whose next span continues the
+// trace sc received from another process.
+func ContextWithRemoteParent(ctx context.Context, sc SpanContext) context.Context {
+	sc.Remote = true
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// SpanFromContext returns the span started in ctx, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// SpanContextFromContext returns the current span's context, falling back to
+// a remote parent.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.SpanContext()
+	}
+	sc, _ := ctx.Value(remoteKey{}).(SpanContext)
+	return sc
+}
@@ -0,0 +1,118 @@
+//go:build unit
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder keeps exported spans in memory.
+type recorder struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+func (r *recorder) Export(ctx context.Context, spans []SpanData) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, spans...)
+	return nil
+}
+
+func TestTracer(t *testing.T) {
+	t.Run("TestTracerShouldParentSpansStartedInContext", func(t *testing.T) {
+		// Arrange
+		rec := &recorder{}
+		tracer := NewTracer(1, rec)
+
+		// Act
+		ctx, parent := tracer.Start(context.Background(), "GET /books", WithKind(KindServer))
+		_, child := tracer.Start(ctx, "BookServices.ListAllBooks", WithAttributes(Int("limit", 10)))
+		child.End()
+		parent.End()
+		assert.NoError(t, tracer.Shutdown(context.Background()))
+
+		// Assert
+		assert.Len(t, rec.spans, 2)
+		assert.Equal(t, "BookServices.ListAllBooks", rec.spans[0].Name)
+		assert.Equal(t, KindInternal, rec.spans[0].Kind)
+		assert.Equal(t, []Attribute{{"limit", int64(10)}}, rec.spans[0].Attributes)
+		assert.Equal(t, parent.SpanContext().TraceID, rec.spans[0].SpanContext.TraceID)
+		assert.Equal(t, parent.SpanContext().SpanID, rec.spans[0].Parent)
+		assert.Equal(t, KindServer, rec.spans[1].Kind)
+		assert.False(t, rec.spans[1].Parent.IsValid())
+	})
+
+	t.Run("TestTracerShouldFollowRemoteParentSampling", func(t *testing.T) {
+		// Arrange
+		rec := &recorder{}
+		tracer := NewTracer(1, rec)
+		remote := SpanContext{TraceID: TraceID{1}, SpanID: SpanID{2}, Sampled: false}
+
+		// Act
+		_, span := tracer.Start(ContextWithRemoteParent(context.Background(), remote), "GET /books")
+		span.End()
+		assert.NoError(t, tracer.Shutdown(context.Background()))
+
+		// Assert
+		assert.Equal(t, remote.TraceID, span.SpanContext().TraceID)
+		assert.NotEqual(t, remote.SpanID, span.SpanContext().SpanID)
+		assert.False(t, span.SpanContext().Sampled)
+		assert.Empty(t, rec.spans)
+	})
+
+	t.Run("TestTracerShouldRecordNothingWithoutExporter", func(t *testing.T) {
+		// Arrange
+		tracer := NewTracer(1, nil)
+
+		// Act
+		ctx, span := tracer.Start(context.Background(), "GET /books")
+		span.SetAttributes(String("a", "b"))
+		span.End()
+
+		// Assert
+		assert.True(t, span.SpanContext().IsValid())
+		assert.False(t, span.SpanContext().Sampled)
+		assert.Same(t, span, SpanFromContext(ctx))
+		assert.NoError(t, tracer.Shutdown(context.Background()))
+	})
+
+	t.Run("TestTracerShouldExportEndedSpanOnce", func(t *testing.T) {
+		// Arrange
+		rec := &recorder{}
+		tracer := NewTracer(1, rec)
+
+		// Act
+		_, span := tracer.Start(context.Background(), "Query.SelectBookByID")
+		span.RecordError(errors.New("connection reset"))
+		span.End()
+		span.End()
+		assert.NoError(t, tracer.Shutdown(context.Background()))
+
+		// Assert
+		assert.Len(t, rec.spans, 1)
+		assert.Equal(t, StatusError, rec.spans[0].StatusCode)
+		assert.Equal(t, "connection reset", rec.spans[0].StatusMessage)
+		assert.False(t, rec.spans[0].End.Before(rec.spans[0].Start))
+	})
+}
+
+func TestSpan(t *testing.T) {
+	t.Run("TestSpanShouldAllowNil", func(t *testing.T) {
+		// Arrange
+		var span *Span
+
+		// Act & Assert
+		assert.NotPanics(t, func() {
+			span.SetAttributes(String("a", "b"))
+			span.RecordError(errors.New("boom"))
+			span.End()
+		})
+		assert.False(t, span.SpanContext().IsValid())
+	})
+}
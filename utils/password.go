@@ -1,17 +1,53 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"strings"
 
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Password hash algorithms. The algorithm and its parameters are part of
+// every stored hash, so hashes made with older settings keep working.
+const (
+	PasswordBcrypt   = "bcrypt"
+	PasswordArgon2id = "argon2id"
+
+	argon2SaltLen = 16
+	argon2KeyLen  = 32
+)
+
+// PasswordHashing is how HashPassword hashes new passwords.
+type PasswordHashing struct {
+	Algorithm     string
+	BcryptCost    int
+	Argon2Time    uint32
+	Argon2Memory  uint32 // KiB
+	Argon2Threads uint8
+}
+
+var passwordHashing = PasswordHashing{Algorithm: PasswordBcrypt, BcryptCost: bcrypt.DefaultCost}
+
+// SetPasswordHashing changes how new passwords are hashed. It is meant to
+// be called once at start-up, before any password is hashed.
+func SetPasswordHashing(h PasswordHashing) {
+	passwordHashing = h
+}
+
 func HashPassword(password string) (string, error) {
 	if password == "" {
 		return "", errors.New("error password not found")
 	}
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	h := passwordHashing
+	if h.Algorithm == PasswordArgon2id {
+		return hashArgon2id(password, h)
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), h.BcryptCost)
 	if err != nil {
 		return "", fmt.Errorf("error hash password : %w", err)
 	}
@@ -19,3 +55,76 @@ func HashPassword(password string) (string, error) {
 	return result, nil
 }
 
+// CheckPassword reports whether password matches a hash made by HashPassword,
+// with any algorithm or parameters.
+func CheckPassword(hashedPassword string, password string) error {
+	if !strings.HasPrefix(hashedPassword, "$"+PasswordArgon2id+"$") {
+		return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	}
+	h, salt, key, err := parseArgon2id(hashedPassword)
+	if err != nil {
+		return err
+	}
+	other := argon2.IDKey([]byte(password), salt, h.Argon2Time, h.Argon2Memory, h.Argon2Threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return bcrypt.ErrMismatchedHashAndPassword
+	}
+	return nil
+}
+
+// PasswordNeedsRehash reports whether a hash was made with another algorithm
+// or other parameters than HashPassword uses now. Unreadable hashes are
+// left alone, since they cannot be checked either.
+func PasswordNeedsRehash(hashedPassword string) bool {
+	current := passwordHashing
+	if strings.HasPrefix(hashedPassword, "$"+PasswordArgon2id+"$") {
+		h, _, _, err := parseArgon2id(hashedPassword)
+		if err != nil {
+			return false
+		}
+		return current.Algorithm != PasswordArgon2id ||
+			h.Argon2Time != current.Argon2Time || h.Argon2Memory != current.Argon2Memory || h.Argon2Threads != current.Argon2Threads
+	}
+	cost, err := bcrypt.Cost([]byte(hashedPassword))
+	if err != nil {
+		return false
+	}
+	return current.Algorithm != PasswordBcrypt || cost != current.BcryptCost
+}
+
+// hashArgon2id encodes the hash in the PHC string format:
+// $argon2id$v=19$m=65536,t=3,p=4$<salt>$<key>.
+func hashArgon2id(password string, h PasswordHashing) (string, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("error hash password : %w", err)
+	}
+	key := argon2.IDKey([]byte(password), salt, h.Argon2Time, h.Argon2Memory, h.Argon2Threads, argon2KeyLen)
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s", PasswordArgon2id, argon2.Version,
+		h.Argon2Memory, h.Argon2Time, h.Argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func parseArgon2id(hashedPassword string) (PasswordHashing, []byte, []byte, error) {
+	h := PasswordHashing{Algorithm: PasswordArgon2id}
+	parts := strings.Split(hashedPassword, "$")
+	if len(parts) != 6 {
+		return h, nil, nil, errors.New("error malformed argon2id hash")
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return h, nil, nil, fmt.Errorf("error unsupported argon2id version %q", parts[2])
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &h.Argon2Memory, &h.Argon2Time, &h.Argon2Threads); err != nil {
+		return h, nil, nil, fmt.Errorf("error malformed argon2id parameters : %w", err)
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return h, nil, nil, fmt.Errorf("error malformed argon2id salt : %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return h, nil, nil, errors.New("error malformed argon2id key")
+	}
+	return h, salt, key, nil
+}
@@ -1,10 +1,13 @@
 //go:build unit
+
 package utils
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestPassword(t *testing.T) {
@@ -32,3 +35,90 @@ func TestPassword(t *testing.T) {
 		assert.Equal(t, "", result)
 	})
 }
+
+func TestCheckPassword(t *testing.T) {
+	t.Run("TestCheckPasswordShouldMatchHash", func(t *testing.T) {
+		// Arrange
+		hashed, err := HashPassword("123456")
+		assert.NoError(t, err)
+
+		// Act
+		err = CheckPassword(hashed, "123456")
+
+		// Assert
+		assert.NoError(t, err)
+	})
+
+	t.Run("TestCheckPasswordShouldReturnErrorOnMismatch", func(t *testing.T) {
+		// Arrange
+		hashed, err := HashPassword("123456")
+		assert.NoError(t, err)
+
+		// Act
+		err = CheckPassword(hashed, "654321")
+
+		// Assert
+		assert.Error(t, err)
+	})
+}
+
+func TestArgon2idPassword(t *testing.T) {
+	t.Run("TestArgon2idPasswordShouldMatchHash", func(t *testing.T) {
+		// Arrange
+		defer SetPasswordHashing(passwordHashing)
+		SetPasswordHashing(PasswordHashing{Algorithm: PasswordArgon2id, Argon2Time: 1, Argon2Memory: 64, Argon2Threads: 1})
+
+		// Act
+		hashed, err := HashPassword("123456")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(hashed, "$argon2id$v=19$m=64,t=1,p=1$"))
+		assert.NoError(t, CheckPassword(hashed, "123456"))
+		assert.Equal(t, bcrypt.ErrMismatchedHashAndPassword, CheckPassword(hashed, "654321"))
+	})
+
+	t.Run("TestCheckPasswordShouldRejectMalformedArgon2id", func(t *testing.T) {
+		for _, hashed := range []string{
+			"$argon2id$v=19$m=64,t=1,p=1$c2FsdA",
+			"$argon2id$v=16$m=64,t=1,p=1$c2FsdA$a2V5",
+			"$argon2id$v=19$m=64$c2FsdA$a2V5",
+			"$argon2id$v=19$m=64,t=1,p=1$c2FsdA$",
+		} {
+			assert.Error(t, CheckPassword(hashed, "123456"), hashed)
+		}
+	})
+}
+
+func TestPasswordNeedsRehash(t *testing.T) {
+	t.Run("TestPasswordNeedsRehashShouldFlagOutdatedHashes", func(t *testing.T) {
+		// Arrange
+		defer SetPasswordHashing(passwordHashing)
+		argon2id := PasswordHashing{Algorithm: PasswordArgon2id, Argon2Time: 1, Argon2Memory: 64, Argon2Threads: 1}
+		SetPasswordHashing(PasswordHashing{Algorithm: PasswordBcrypt, BcryptCost: bcrypt.MinCost})
+		oldBcrypt, err := HashPassword("123456")
+		assert.NoError(t, err)
+		SetPasswordHashing(argon2id)
+		current, err := HashPassword("123456")
+		assert.NoError(t, err)
+
+		// Act
+		SetPasswordHashing(argon2id)
+		bcryptToArgon2id := PasswordNeedsRehash(oldBcrypt)
+		upToDate := PasswordNeedsRehash(current)
+		SetPasswordHashing(PasswordHashing{Algorithm: PasswordArgon2id, Argon2Time: 2, Argon2Memory: 64, Argon2Threads: 1})
+		moreTime := PasswordNeedsRehash(current)
+		SetPasswordHashing(PasswordHashing{Algorithm: PasswordBcrypt, BcryptCost: bcrypt.MinCost + 1})
+		higherCost := PasswordNeedsRehash(oldBcrypt)
+		argon2idToBcrypt := PasswordNeedsRehash(current)
+		unreadable := PasswordNeedsRehash("plain")
+
+		// Assert
+		assert.True(t, bcryptToArgon2id)
+		assert.False(t, upToDate)
+		assert.True(t, moreTime)
+		assert.True(t, higherCost)
+		assert.True(t, argon2idToBcrypt)
+		assert.False(t, unreadable)
+	})
+}
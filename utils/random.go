@@ -9,26 +9,23 @@ import (
 const alpha = "abcdefghijklmnopqrstuvwxyz"
 const alphanum = "abcdefghijklmnopqrstuvwxyz1234567890"
 
-func RandomAlphabet(n int) string {
+func randomString(intn func(int) int, chars string, n int) string {
 	var sb strings.Builder
-	l := len(alpha)
+	l := len(chars)
 
 	for i := 0; i < n; i++ {
-		c := alpha[rand.Intn(l)]
+		c := chars[intn(l)]
 		sb.WriteByte(c)
 	}
 	return sb.String()
 }
 
-func RandomAlphanum(n int) string {
-	var sb strings.Builder
-	l := len(alphanum)
+func RandomAlphabet(n int) string {
+	return randomString(rand.Intn, alpha, n)
+}
 
-	for i := 0; i < n; i++ {
-		c := alphanum[rand.Intn(l)]
-		sb.WriteByte(c)
-	}
-	return sb.String()
+func RandomAlphanum(n int) string {
+	return randomString(rand.Intn, alphanum, n)
 }
 
 func RandomUsername() string {
@@ -49,3 +46,31 @@ func RandomFullname() string {
 	surname := RandomAlphabet(6)
 	return fmt.Sprintf("%v %v", firstName, surname)
 }
+
+// Random is a seeded generator for test data that has to be the same on
+// every run. It is not safe for concurrent use.
+type Random struct {
+	rand *rand.Rand
+}
+
+func NewRandom(seed int64) *Random {
+	return &Random{rand.New(rand.NewSource(seed))}
+}
+
+func (r *Random) Alphabet(n int) string {
+	return randomString(r.rand.Intn, alpha, n)
+}
+
+func (r *Random) Intn(n int) int {
+	return r.rand.Intn(n)
+}
+
+// Sample returns k distinct numbers from [0, n) in random order, or all of
+// them when k >= n.
+func (r *Random) Sample(n int, k int) []int {
+	perm := r.rand.Perm(n)
+	if k < n {
+		perm = perm[:k]
+	}
+	return perm
+}
@@ -0,0 +1,41 @@
+//go:build unit
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRandom(t *testing.T) {
+	t.Run("TestRandomShouldRepeatForSameSeed", func(t *testing.T) {
+		// Arrange
+		first := NewRandom(42)
+		second := NewRandom(42)
+
+		// Act & Assert
+		assert.Equal(t, first.Alphabet(12), second.Alphabet(12))
+		assert.Equal(t, first.Intn(1000), second.Intn(1000))
+		assert.Equal(t, first.Sample(20, 5), second.Sample(20, 5))
+	})
+
+	t.Run("TestSampleShouldReturnDistinctNumbers", func(t *testing.T) {
+		// Act
+		sample := NewRandom(7).Sample(10, 4)
+
+		// Assert
+		assert.Len(t, sample, 4)
+		seen := map[int]bool{}
+		for _, n := range sample {
+			assert.True(t, n >= 0 && n < 10)
+			assert.False(t, seen[n])
+			seen[n] = true
+		}
+	})
+
+	t.Run("TestSampleShouldCapAtRange", func(t *testing.T) {
+		// Act & Assert
+		assert.Len(t, NewRandom(7).Sample(3, 10), 3)
+	})
+}
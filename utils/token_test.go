@@ -0,0 +1,41 @@
+//go:build unit
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToken(t *testing.T) {
+	t.Run("TestNewTokenShouldReturnDistinctTokens", func(t *testing.T) {
+		// Act
+		first, err1 := NewToken()
+		second, err2 := NewToken()
+
+		// Assert
+		assert.NoError(t, err1)
+		assert.NoError(t, err2)
+		assert.Len(t, first, 43)
+		assert.NotEqual(t, first, second)
+	})
+
+	t.Run("TestHashTokenShouldBeStable", func(t *testing.T) {
+		// Act
+		hashed := HashToken("abc")
+
+		// Assert
+		assert.Equal(t, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad", hashed)
+		assert.Equal(t, hashed, HashToken("abc"))
+	})
+	t.Run("TestKeyedHashShouldDependOnKey", func(t *testing.T) {
+		// Act
+		hashed := KeyedHash("key", "The quick brown fox jumps over the lazy dog")
+
+		// Assert
+		assert.Equal(t, "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8", hashed)
+		assert.NotEqual(t, hashed, KeyedHash("other", "The quick brown fox jumps over the lazy dog"))
+		assert.NotEqual(t, HashToken("The quick brown fox jumps over the lazy dog"), hashed)
+	})
+}